@@ -17,6 +17,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/journal"
 	"github.com/thoughtrealm/bumblebee/keypairs"
 	"github.com/thoughtrealm/bumblebee/keystore"
 	"github.com/thoughtrealm/bumblebee/logger"
@@ -25,6 +26,9 @@ import (
 type BootstrapLoader struct {
 	ActiveProfile   *helpers.Profile
 	KeyPairStoreKey []byte
+
+	// Journal records the changes to both stores.  It is not read until a change is recorded.
+	Journal *journal.Journal
 }
 
 func Run(loadKeystore, loadKeypairStore bool) error {
@@ -39,6 +43,10 @@ func Run(loadKeystore, loadKeypairStore bool) error {
 		return err
 	}
 
+	if loader.ActiveProfile != nil {
+		loader.Journal = journal.New(loader.ActiveProfile.JournalPath())
+	}
+
 	// we load the keypair store first, because it is needed to decrypt the key store
 	if loadKeypairStore {
 		err := loader.checkKeyPairStoreKey()
@@ -96,12 +104,13 @@ func (bsl *BootstrapLoader) checkKeyPairStoreKey() error {
 func (bsl *BootstrapLoader) loadGlobalKeyStore() error {
 	keystorePath := bsl.ActiveProfile.KeyStorePath
 
-	var err error
-	keystore.GlobalKeyStore, err = keystore.NewFromFile(bsl.KeyPairStoreKey, keystorePath)
+	store, err := keystore.NewFromFile(bsl.KeyPairStoreKey, keystorePath)
 	if err != nil {
 		return fmt.Errorf("unable to load keystore file: %w", err)
 	}
 
+	keystore.GlobalKeyStore = journal.NewJournaledKeyStore(store, bsl.Journal)
+
 	return nil
 }
 
@@ -111,11 +120,12 @@ func (bsl *BootstrapLoader) loadGlobalKeypairStore() error {
 	// for now, we will assume that we are using the same key for both stores.
 	// so we won't check for it again, since it should have been gathered
 	// when loading the keystore
-	var err error
-	keypairs.GlobalKeyPairStore, err = keypairs.NewKeypairStoreFromFile(bsl.KeyPairStoreKey, keypairStorePath)
+	store, err := keypairs.NewKeypairStoreFromFile(bsl.KeyPairStoreKey, keypairStorePath)
 	if err != nil {
 		return fmt.Errorf("unable to load keypair store file: %s", err)
 	}
 
+	keypairs.GlobalKeyPairStore = journal.NewJournaledKeyPairStore(store, bsl.Journal)
+
 	return nil
 }
@@ -14,11 +14,14 @@
 package cipher
 
 import (
+	"bytes"
 	"crypto/cipher"
 	cryptorand "crypto/rand"
+	"crypto/sha256"
 	"fmt"
 	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/chacha20poly1305"
+	"hash"
 	"io"
 	"strconv"
 )
@@ -66,6 +69,18 @@ type ChachaCipher struct {
 	chacha       cipher.AEAD
 	BytesWritten int
 	BytesRead    int
+
+	// AssociatedData is mixed into the AD of every chunk, ahead of the chunk counter.
+	// When empty, only the chunk counter is used, which is compatible with older payloads.
+	AssociatedData []byte
+
+	// KeyCommitment is the expected key commitment tag.  When set, Decrypt verifies it against the derived key
+	// before any chunk is decrypted.
+	KeyCommitment []byte
+
+	// ChunkCount and payloadHash track the encrypted chunks processed by the last Encrypt or Decrypt call.
+	ChunkCount  int
+	payloadHash hash.Hash
 }
 
 func NewChaChaCipherRandomSalt(key []byte, chunkSize int) (*ChachaCipher, error) {
@@ -109,6 +124,47 @@ func (c *ChachaCipher) GetSalt() []byte {
 	return c.Salt
 }
 
+func (c *ChachaCipher) SetAssociatedData(ad []byte) {
+	c.AssociatedData = bytes.Clone(ad)
+}
+
+func (c *ChachaCipher) GetChunkCount() int {
+	return c.ChunkCount
+}
+
+// GetKeyCommitment returns the key commitment tag for the cipher's derived key and salt
+func (c *ChachaCipher) GetKeyCommitment() ([]byte, error) {
+	return DeriveKeyCommitment(c.DerivedKey, c.Salt)
+}
+
+// SetKeyCommitment sets the expected key commitment tag that Decrypt will verify
+func (c *ChachaCipher) SetKeyCommitment(tag []byte) {
+	c.KeyCommitment = bytes.Clone(tag)
+}
+
+// GetPayloadHash returns the running SHA-256 hash of the encrypted chunks processed by the last
+// Encrypt or Decrypt call.  It returns nil if no chunks have been processed.
+func (c *ChachaCipher) GetPayloadHash() []byte {
+	if c.payloadHash == nil {
+		return nil
+	}
+
+	return c.payloadHash.Sum(nil)
+}
+
+// chunkAD builds the associated data for a chunk.  The chunk counter is always included, so that chunks
+// cannot be reordered, and the AssociatedData prefix binds the chunks to a specific payload.
+func (c *ChachaCipher) chunkAD(chunkCount int) []byte {
+	chunkCountBytes := []byte(strconv.Itoa(chunkCount))
+	if len(c.AssociatedData) == 0 {
+		return chunkCountBytes
+	}
+
+	ad := make([]byte, 0, len(c.AssociatedData)+len(chunkCountBytes))
+	ad = append(ad, c.AssociatedData...)
+	return append(ad, chunkCountBytes...)
+}
+
 func (c *ChachaCipher) deriveKey(keyIn, saltIn []byte) {
 	c.Salt = make([]byte, len(saltIn))
 	copy(c.Salt, saltIn)
@@ -116,9 +172,18 @@ func (c *ChachaCipher) deriveKey(keyIn, saltIn []byte) {
 }
 
 func (c *ChachaCipher) Decrypt(r io.Reader, w io.Writer) (int, error) {
+	if len(c.KeyCommitment) != 0 {
+		err := VerifyKeyCommitment(c.DerivedKey, c.Salt, c.KeyCommitment)
+		if err != nil {
+			return 0, fmt.Errorf("decrypt failed for stream: %w", err)
+		}
+	}
+
 	buffSize := c.chacha.NonceSize() + c.ChunkSize + c.chacha.Overhead()
 	buf := make([]byte, buffSize)
 	chunkCount := 1 // Used for error messages and as the AD value.  Starting at 1 is clearer in error messages.
+	c.ChunkCount = 0
+	c.payloadHash = sha256.New()
 	for {
 		bytesRead, readErr := r.Read(buf)
 		if bytesRead > 0 {
@@ -133,10 +198,13 @@ func (c *ChachaCipher) Decrypt(r io.Reader, w io.Writer) (int, error) {
 				)
 			}
 
+			c.payloadHash.Write(chunkBytes)
+			c.ChunkCount = chunkCount
+
 			nonce, msgBytesEncrypted := chunkBytes[:c.chacha.NonceSize()], chunkBytes[c.chacha.NonceSize():]
 
 			// Decrypt and validate
-			msgBytesDecrypted, err := c.chacha.Open(nil, nonce, msgBytesEncrypted, []byte(strconv.Itoa(chunkCount)))
+			msgBytesDecrypted, err := c.chacha.Open(nil, nonce, msgBytesEncrypted, c.chunkAD(chunkCount))
 			if err != nil {
 				return c.BytesWritten, fmt.Errorf("decrypt failed for stream in chunk %d: %w", chunkCount, err)
 			}
@@ -175,6 +243,8 @@ func (c *ChachaCipher) Decrypt(r io.Reader, w io.Writer) (int, error) {
 func (c *ChachaCipher) Encrypt(r io.Reader, w io.Writer) (int, error) {
 	buf := make([]byte, c.ChunkSize)
 	chunkCount := 1 // Used for error messages and as the AD value.  Starting at 1 is clearer in error messages.
+	c.ChunkCount = 0
+	c.payloadHash = sha256.New()
 
 	for {
 		bytesRead, readErr := r.Read(buf)
@@ -190,7 +260,10 @@ func (c *ChachaCipher) Encrypt(r io.Reader, w io.Writer) (int, error) {
 			msgBytesInput := buf[:bytesRead]
 
 			// Encrypt message and append the ciphertext to the nonce.
-			msgBytesEncypted := c.chacha.Seal(nonce, nonce, msgBytesInput, []byte(strconv.Itoa(chunkCount)))
+			msgBytesEncypted := c.chacha.Seal(nonce, nonce, msgBytesInput, c.chunkAD(chunkCount))
+			c.payloadHash.Write(msgBytesEncypted)
+			c.ChunkCount = chunkCount
+
 			outputBytesWritten, outputErr := w.Write(msgBytesEncypted)
 			if outputErr != nil {
 				return c.BytesWritten, fmt.Errorf("error writing chunk %d to output: %s", chunkCount, outputErr)
@@ -220,3 +293,29 @@ func (c *ChachaCipher) Encrypt(r io.Reader, w io.Writer) (int, error) {
 
 	return c.BytesWritten, nil
 }
+
+// ComputePayloadCommitment reads an encrypted payload stream produced by ChachaCipher.Encrypt and returns the
+// chunk count and running hash, without decrypting it.  The values will match GetChunkCount() and
+// GetPayloadHash() from the cipher that encrypted the stream.
+func ComputePayloadCommitment(r io.Reader, chunkSize int) (chunkCount int, payloadHash []byte, err error) {
+	buffSize := chacha20poly1305.NonceSizeX + chunkSize + chacha20poly1305.Overhead
+	buf := make([]byte, buffSize)
+	h := sha256.New()
+	for {
+		bytesRead, readErr := io.ReadFull(r, buf)
+		if bytesRead > 0 {
+			chunkCount += 1
+			h.Write(buf[:bytesRead])
+		}
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+
+		if readErr != nil {
+			return chunkCount, nil, fmt.Errorf("error reading chunk %d: %w", chunkCount+1, readErr)
+		}
+	}
+
+	return chunkCount, h.Sum(nil), nil
+}
@@ -94,3 +94,47 @@ func TestChachaCipherBigSecret(t *testing.T) {
 
 	assert.Equal(t, secretBytes, decryptWriteBuffer.Bytes())
 }
+
+// TestChachaCipherAssociatedData verifies that a payload only decrypts with the associated data used to encrypt it,
+// and that the payload commitment can be computed without decrypting.
+func TestChachaCipherAssociatedData(t *testing.T) {
+	chachaEncrypter, err := NewChaChaCipherRandomSalt([]byte("verifyme"), 32000)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	chachaEncrypter.SetAssociatedData([]byte("bundle-1"))
+	encryptWriteBuffer := bytes.NewBuffer(nil)
+	_, err = chachaEncrypter.Encrypt(bytes.NewBuffer(werner_bytes), encryptWriteBuffer)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	chunkCount, payloadHash, err := ComputePayloadCommitment(bytes.NewReader(encryptWriteBuffer.Bytes()), 32000)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, chachaEncrypter.GetChunkCount(), chunkCount)
+	assert.Equal(t, chachaEncrypter.GetPayloadHash(), payloadHash)
+
+	chachaDecrypter, err := NewChaChaCipherFromSalt([]byte("verifyme"), chachaEncrypter.GetSalt(), 32000)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	chachaDecrypter.SetAssociatedData([]byte("bundle-2"))
+	_, err = chachaDecrypter.Decrypt(bytes.NewReader(encryptWriteBuffer.Bytes()), bytes.NewBuffer(nil))
+	assert.NotNil(t, err)
+
+	chachaDecrypter.SetAssociatedData([]byte("bundle-1"))
+	decryptWriteBuffer := bytes.NewBuffer(nil)
+	_, err = chachaDecrypter.Decrypt(bytes.NewReader(encryptWriteBuffer.Bytes()), decryptWriteBuffer)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, werner_bytes, decryptWriteBuffer.Bytes())
+	assert.Equal(t, chunkCount, chachaDecrypter.GetChunkCount())
+	assert.Equal(t, payloadHash, chachaDecrypter.GetPayloadHash())
+}
@@ -21,6 +21,11 @@ type Cipher interface {
 	GetChunkSize() int
 	GetDerivedKey() []byte
 	GetSalt() []byte
+	GetChunkCount() int
+	GetKeyCommitment() ([]byte, error)
+	SetKeyCommitment(tag []byte)
+	GetPayloadHash() []byte
+	SetAssociatedData(ad []byte)
 	Decrypt(r io.Reader, w io.Writer) (int, error)
 	Encrypt(r io.Reader, w io.Writer) (int, error)
 }
@@ -0,0 +1,68 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cipher
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/hkdf"
+	"io"
+)
+
+// KeyCommitmentInfo is the HKDF info value used when deriving key commitment tags
+const KeyCommitmentInfo = "bumblebee key commitment v1"
+
+// KeyCommitmentLen is the size of a key commitment tag
+const KeyCommitmentLen = 32
+
+var ErrKeyCommitmentMismatch = errors.New("key commitment does not match")
+
+// HKDFSHA256 derives length bytes of output keying material with RFC 5869 HKDF, using SHA-256
+func HKDFSHA256(secret, salt, info []byte, length int) ([]byte, error) {
+	output := make([]byte, length)
+	_, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), output)
+	if err != nil {
+		return nil, fmt.Errorf("hkdf: %w", err)
+	}
+
+	return output, nil
+}
+
+// DeriveKeyCommitment derives a commitment tag for the derived key.  Poly1305 based AEADs are not
+// key-committing, so a crafted ciphertext could validate under more than one key.  Checking this tag
+// before decrypting ensures that a ciphertext can only be opened with the key it was created with.
+func DeriveKeyCommitment(derivedKey, salt []byte) ([]byte, error) {
+	if len(derivedKey) == 0 {
+		return nil, errors.New("derived key is empty")
+	}
+
+	return HKDFSHA256(derivedKey, salt, []byte(KeyCommitmentInfo), KeyCommitmentLen)
+}
+
+// VerifyKeyCommitment compares the commitment derived from the derived key with the expected tag, using a
+// constant time comparison.
+func VerifyKeyCommitment(derivedKey, salt, expected []byte) error {
+	actual, err := DeriveKeyCommitment(derivedKey, salt)
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal(actual, expected) {
+		return ErrKeyCommitmentMismatch
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cipher
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestHKDFSHA256 validates HKDFSHA256 against RFC 5869 test case 1
+func TestHKDFSHA256(t *testing.T) {
+	ikm, _ := hex.DecodeString("0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b")
+	salt, _ := hex.DecodeString("000102030405060708090a0b0c")
+	info, _ := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9")
+	expected := "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865"
+
+	okm, err := HKDFSHA256(ikm, salt, info, 42)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, expected, hex.EncodeToString(okm))
+}
+
+// TestChachaCipherKeyCommitment verifies that Decrypt rejects a mismatched key commitment before decrypting
+func TestChachaCipherKeyCommitment(t *testing.T) {
+	chachaEncrypter, err := NewChaChaCipherRandomSalt([]byte("verifyme"), 32000)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	encryptWriteBuffer := bytes.NewBuffer(nil)
+	_, err = chachaEncrypter.Encrypt(bytes.NewBuffer(werner_bytes), encryptWriteBuffer)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	keyCommitment, err := chachaEncrypter.GetKeyCommitment()
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Len(t, keyCommitment, KeyCommitmentLen)
+
+	wrongKeyDecrypter, err := NewChaChaCipherFromSalt([]byte("wrongkey"), chachaEncrypter.GetSalt(), 32000)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	wrongKeyDecrypter.SetKeyCommitment(keyCommitment)
+	_, err = wrongKeyDecrypter.Decrypt(bytes.NewReader(encryptWriteBuffer.Bytes()), bytes.NewBuffer(nil))
+	assert.True(t, errors.Is(err, ErrKeyCommitmentMismatch))
+	assert.Equal(t, 0, wrongKeyDecrypter.BytesRead)
+
+	chachaDecrypter, err := NewChaChaCipherFromSalt([]byte("verifyme"), chachaEncrypter.GetSalt(), 32000)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	chachaDecrypter.SetKeyCommitment(keyCommitment)
+	decryptWriteBuffer := bytes.NewBuffer(nil)
+	_, err = chachaDecrypter.Decrypt(bytes.NewReader(encryptWriteBuffer.Bytes()), decryptWriteBuffer)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, werner_bytes, decryptWriteBuffer.Bytes())
+}
@@ -15,14 +15,17 @@ package io
 
 import (
 	cryptorand "crypto/rand"
+	"encoding/hex"
 	"github.com/thoughtrealm/bumblebee/security"
+	"strconv"
 	"time"
 )
 
 const (
-	BundleHeaderVersion = "2"
-	BundleDataVersion   = "2"
+	BundleHeaderVersion = "5"
+	BundleDataVersion   = "4"
 	DEFAULT_CHUNK_SIZE  = 64000
+	BundleIDSize        = 16
 )
 
 type BundleInputSource int
@@ -70,6 +73,16 @@ type BundleInfo struct {
 	HdrVer string
 	// PayloadVer identifies the version of the Bumblebee functionality that built the payload
 	PayloadVer string
+	// BundleID is a random value identifying this bundle.  It is mixed into the associated data of each payload chunk.
+	BundleID []byte `msgpack:",omitempty"`
+	// PayloadChunkCount is the number of encrypted payload chunks.  Only populated for split bundles.
+	PayloadChunkCount int `msgpack:",omitempty"`
+	// PayloadHash is a running SHA-256 hash over the encrypted payload chunks.  Only populated for split bundles.
+	PayloadHash []byte `msgpack:",omitempty"`
+	// KeyCommitment is an HKDF derived tag that commits the payload to its derived key.  Required from header version 4.
+	KeyCommitment []byte `msgpack:",omitempty"`
+	// SelfSlot indicates that a second copy of the header, wrapped to one of the sender's own keypairs, follows this header
+	SelfSlot bool `msgpack:",omitempty"`
 }
 
 // NewBundle returns a BundleInfo that is pre-populated with a random symmetric key
@@ -90,9 +103,41 @@ func NewBundle() (*BundleInfo, error) {
 		return nil, err
 	}
 
+	newBundle.BundleID = make([]byte, BundleIDSize)
+	_, err = cryptorand.Read(newBundle.BundleID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set the version and variant bits, so the ID is a valid random (version 4) UUID
+	newBundle.BundleID[6] = (newBundle.BundleID[6] & 0x0f) | 0x40
+	newBundle.BundleID[8] = (newBundle.BundleID[8] & 0x3f) | 0x80
+
 	return newBundle, nil
 }
 
+// BundleIDToText returns the bundle ID in the standard UUID text form.  Bundles from versions
+// prior to BundleID support return an empty string.
+func (bundle *BundleInfo) BundleIDToText() string {
+	if len(bundle.BundleID) != BundleIDSize {
+		return ""
+	}
+
+	hexID := hex.EncodeToString(bundle.BundleID)
+	return hexID[0:8] + "-" + hexID[8:12] + "-" + hexID[12:16] + "-" + hexID[16:20] + "-" + hexID[20:32]
+}
+
+// RequiresKeyCommitment indicates whether the bundle's header version requires a key commitment
+func (bundle *BundleInfo) RequiresKeyCommitment() bool {
+	hdrVer, err := strconv.Atoi(bundle.HdrVer)
+	if err != nil {
+		// Unknown versions are treated as current
+		return true
+	}
+
+	return hdrVer >= 4
+}
+
 func (bundle *BundleInfo) Wipe() {
 	if len(bundle.SymmetricKey) != 0 {
 		security.Wipe(bundle.SymmetricKey)
@@ -5,9 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"github.com/thoughtrealm/bumblebee/cipher"
+	"github.com/thoughtrealm/bumblebee/helpers"
 	"github.com/thoughtrealm/bumblebee/security"
 	"io"
-	"os"
 )
 
 type ExportWriter struct {
@@ -23,9 +23,9 @@ func (ew *ExportWriter) Wipe() {
 }
 
 // WriteExportKeyInfoToFile writes security.ExportKeyInfo data to a file.  It will simply open the file,
-// then pass it to WriteKeyInfoToStream().
+// then pass it to WriteKeyInfoToStream().  Files for exports with private keys are only readable by the current user.
 func (ew *ExportWriter) WriteExportKeyInfoToFile(eki *security.ExportKeyInfo, password []byte, filePath string) error {
-	outputFile, err := os.Create(filePath)
+	outputFile, err := helpers.CreateOutputFile(filePath, eki.HasPrivateKeys())
 	if err != nil {
 		return fmt.Errorf("unable to create output file: %w", err)
 	}
@@ -46,7 +46,7 @@ func (ew *ExportWriter) WriteExportKeyInfoToFile(eki *security.ExportKeyInfo, pa
 // KeyInfo, then the password is optional, since that only contains public keys.  If it is
 // type KeyPairInfo, then the password is required and an error is returned if it is nil.
 func (ew *ExportWriter) WriteExportKeyInfoToStream(eki *security.ExportKeyInfo, password []byte, w io.Writer) error {
-	if eki.DataType == security.ExportDataTypeKeyPairInfo && len(password) == 0 {
+	if eki.HasPrivateKeys() && len(password) == 0 {
 		return errors.New("cannot export: no password provided and password is required for exporting keypair data")
 	}
 
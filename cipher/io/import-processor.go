@@ -11,11 +11,15 @@ import (
 type ProcessorAcquirePasswordFunc func() (password []byte, err error)
 
 type ImportProcessor struct {
-	password            []byte
-	acquirePasswordFunc ProcessorAcquirePasswordFunc
-	importedUser        *security.KeyInfo
-	importedKeyPair     *security.KeyPairInfo
-	importDataType      security.ExportDataType
+	password              []byte
+	acquirePasswordFunc   ProcessorAcquirePasswordFunc
+	importedUser          *security.KeyInfo
+	importedUsers         []*security.KeyInfo
+	importedKeyPair       *security.KeyPairInfo
+	importedSuccession    *security.SuccessionStatement
+	importedRevocation    *security.RevocationCertificate
+	importedCertification *security.Certification
+	importDataType        security.ExportDataType
 }
 
 func NewImportProcessor(passwordFunc ProcessorAcquirePasswordFunc) *ImportProcessor {
@@ -30,10 +34,34 @@ func (ip *ImportProcessor) ImportedUser() *security.KeyInfo {
 	return ip.importedUser
 }
 
+// ImportedUsers returns the users for ExportDataTypeKeyInfoList, or the single user for ExportDataTypeKeyInfo
+func (ip *ImportProcessor) ImportedUsers() []*security.KeyInfo {
+	if len(ip.importedUsers) == 0 && ip.importedUser != nil {
+		return []*security.KeyInfo{ip.importedUser}
+	}
+
+	return ip.importedUsers
+}
+
 func (ip *ImportProcessor) ImportedKeyPair() *security.KeyPairInfo {
 	return ip.importedKeyPair
 }
 
+// ImportedSuccession returns the succession statement, which has already been checked for both signatures
+func (ip *ImportProcessor) ImportedSuccession() *security.SuccessionStatement {
+	return ip.importedSuccession
+}
+
+// ImportedRevocation returns the revocation certificate, which has already been checked for its signature
+func (ip *ImportProcessor) ImportedRevocation() *security.RevocationCertificate {
+	return ip.importedRevocation
+}
+
+// ImportedCertification returns the certification, which has already been checked for its signature
+func (ip *ImportProcessor) ImportedCertification() *security.Certification {
+	return ip.importedCertification
+}
+
 func (ip *ImportProcessor) Wipe() {
 	security.Wipe(ip.password)
 }
@@ -45,6 +73,10 @@ func (ip *ImportProcessor) ProcessImportData(data []byte) (err error) {
 		return errors.New("import data is nil")
 	}
 
+	if security.IsJWKData(data) {
+		return ip.processJWKData(data)
+	}
+
 	var useBytes []byte
 	if data[0] != 0 {
 		// this indicates that a password is required
@@ -67,8 +99,55 @@ func (ip *ImportProcessor) ProcessImportData(data []byte) (err error) {
 	switch eki.DataType {
 	case security.ExportDataTypeKeyInfo:
 		ip.importedUser, _ = security.NewKeyInfo(eki.Name, eki.CipherPubKey, eki.SigningPubKey)
+
+		// subkeys are only accepted when certified by the imported signing key
+		_, err = ip.importedUser.MergeSubkeys(eki.Subkeys)
+		if err != nil {
+			return fmt.Errorf("imported subkeys failed verification: %w", err)
+		}
 	case security.ExportDataTypeKeyPairInfo:
 		ip.importedKeyPair = security.NewKeyPairInfoFromSeeds(eki.Name, eki.CipherSeed, eki.SigningSeed)
+		ip.importedKeyPair.Subkeys = eki.KeyPairSubkeys
+	case security.ExportDataTypeGroupKey:
+		ip.importedKeyPair, err = eki.GroupKeyPairInfo()
+		if err != nil {
+			return fmt.Errorf("imported group key is invalid: %w", err)
+		}
+	case security.ExportDataTypeSuccession:
+		if eki.Succession == nil {
+			return errors.New("imported succession data has no succession statement")
+		}
+
+		err = eki.Succession.Verify()
+		if err != nil {
+			return fmt.Errorf("imported succession statement failed verification: %w", err)
+		}
+
+		ip.importedSuccession = eki.Succession
+	case security.ExportDataTypeRevocation:
+		if eki.Revocation == nil {
+			return errors.New("imported revocation data has no revocation certificate")
+		}
+
+		err = eki.Revocation.Verify()
+		if err != nil {
+			return fmt.Errorf("imported revocation certificate failed verification: %w", err)
+		}
+
+		ip.importedRevocation = eki.Revocation
+	case security.ExportDataTypeCertification:
+		if eki.Certification == nil {
+			return errors.New("imported certification data has no certification")
+		}
+
+		err = eki.Certification.Verify()
+		if err != nil {
+			return fmt.Errorf("imported certification failed verification: %w", err)
+		}
+
+		ip.importedCertification = eki.Certification
+	case security.ExportDataTypeRecoveryShare:
+		return errors.New("imported data is a recovery share, which is restored with \"recovery restore\"")
 	case security.ExportDataTypeUnknown:
 		return errors.New("imported data has a date type of UNKNOWN")
 	default:
@@ -78,6 +157,31 @@ func (ip *ImportProcessor) ProcessImportData(data []byte) (err error) {
 	return nil
 }
 
+// processJWKData converts a JWK set to users.  A set with a single user is handled the same as a user export.
+func (ip *ImportProcessor) processJWKData(data []byte) error {
+	kis, err := security.ParseJWKData(data)
+	if err != nil {
+		return err
+	}
+
+	for _, ki := range kis {
+		err = security.ValidatePublicKeys(ki.CipherPubKey, ki.SigningPubKey)
+		if err != nil {
+			return fmt.Errorf("kid \"%s\": %w", ki.Name, err)
+		}
+	}
+
+	if len(kis) == 1 {
+		ip.importDataType = security.ExportDataTypeKeyInfo
+		ip.importedUser = kis[0]
+		return nil
+	}
+
+	ip.importDataType = security.ExportDataTypeKeyInfoList
+	ip.importedUsers = kis
+	return nil
+}
+
 func (ip *ImportProcessor) decryptData(data []byte) (decryptedData []byte, err error) {
 	// need to get a key first
 	if ip.acquirePasswordFunc == nil {
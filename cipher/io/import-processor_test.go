@@ -0,0 +1,112 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/thoughtrealm/bumblebee/security"
+	"testing"
+)
+
+func TestImportProcessor_ProcessJWKData(t *testing.T) {
+	_, aliceKI := newSignedTestKeys(t)
+	aliceKI.Name = "alice"
+	_, bobKI := newSignedTestKeys(t)
+	bobKI.Name = "bob"
+
+	set, err := security.NewJWKSetFromKeyInfos([]*security.KeyInfo{aliceKI})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	data, err := set.Marshal()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	importProcessor := NewImportProcessor(nil)
+	err = importProcessor.ProcessImportData(data)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, security.ExportDataTypeKeyInfo, importProcessor.DataType())
+	assert.Equal(t, "alice", importProcessor.ImportedUser().Name)
+	assert.Equal(t, aliceKI.CipherPubKey, importProcessor.ImportedUser().CipherPubKey)
+	assert.Equal(t, aliceKI.SigningPubKey, importProcessor.ImportedUser().SigningPubKey)
+
+	set, err = security.NewJWKSetFromKeyInfos([]*security.KeyInfo{aliceKI, bobKI})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	data, err = set.Marshal()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	importProcessor = NewImportProcessor(nil)
+	err = importProcessor.ProcessImportData(data)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, security.ExportDataTypeKeyInfoList, importProcessor.DataType())
+	if assert.Len(t, importProcessor.ImportedUsers(), 2) {
+		assert.Equal(t, "bob", importProcessor.ImportedUsers()[1].Name)
+		assert.Equal(t, bobKI.SigningPubKey, importProcessor.ImportedUsers()[1].SigningPubKey)
+	}
+}
+
+func TestImportProcessor_ProcessKeyPairExport(t *testing.T) {
+	kpi, err := security.NewKeyPairInfoWithSeeds("alice")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	eki, err := security.NewExportKeyInfoFromKeyPairInfo(kpi)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	password := []byte("export password")
+	exportWriter := NewExportWriter(password)
+	defer exportWriter.Wipe()
+
+	// keypair exports always require a password
+	exportBuffer := bytes.NewBuffer(nil)
+	err = exportWriter.WriteExportKeyInfoToStream(eki, nil, exportBuffer)
+	assert.NotNil(t, err)
+
+	err = exportWriter.WriteExportKeyInfoToStream(eki, password, exportBuffer)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	importProcessor := NewImportProcessor(func() ([]byte, error) { return []byte("wrong password"), nil })
+	assert.NotNil(t, importProcessor.ProcessImportData(exportBuffer.Bytes()))
+
+	importProcessor = NewImportProcessor(func() ([]byte, error) { return bytes.Clone(password), nil })
+	err = importProcessor.ProcessImportData(exportBuffer.Bytes())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, security.ExportDataTypeKeyPairInfo, importProcessor.DataType())
+	importedKPI := importProcessor.ImportedKeyPair()
+	assert.Equal(t, "alice", importedKPI.Name)
+	assert.Equal(t, kpi.CipherSeed, importedKPI.CipherSeed)
+	assert.Equal(t, kpi.SigningSeed, importedKPI.SigningSeed)
+}
@@ -15,10 +15,13 @@ package io
 
 import (
 	"bytes"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"github.com/thoughtrealm/bumblebee/helpers"
 	"github.com/thoughtrealm/bumblebee/security"
+	"golang.org/x/crypto/chacha20poly1305"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
@@ -184,6 +187,117 @@ func (s *CipherIOTestSuite) TestCipherFileWriter_WriteToSplitFilesFromReader() {
 	assert.Equal(s.T(), secretBytes, decryptedBytes)
 }
 
+func (s *CipherIOTestSuite) TestCipherFileReader_ReadSplitFilesWithMismatchedHeader() {
+	var encryptedFile1 = filepath.Join(test_path, "mismatch1.bhdr")
+	var encryptedFile2 = filepath.Join(test_path, "mismatch2.bhdr")
+	var decryptedFile = filepath.Join(test_path, "mismatch.decrypted")
+
+	defer func() {
+		for _, filePath := range []string{
+			encryptedFile1,
+			helpers.ReplaceFileExt(encryptedFile1, ".bdata"),
+			encryptedFile2,
+			helpers.ReplaceFileExt(encryptedFile2, ".bdata"),
+			decryptedFile,
+		} {
+			if helpers.FileExists(filePath) {
+				_ = os.Remove(filePath)
+			}
+		}
+	}()
+
+	receiverKPI, _ := security.NewKeyPairInfoWithSeeds("receiverKPI")
+	receiverCipherPublicKey, receiverSigningPublicKey, err := receiverKPI.PublicKeys()
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	receiverKI, _ := security.NewKeyInfo("receiverKI", receiverCipherPublicKey, receiverSigningPublicKey)
+
+	senderKPI, _ := security.NewKeyPairInfoWithSeeds("senderKPI")
+	senderCipherPublicKey, senderSigningPublicKey, err := senderKPI.PublicKeys()
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	senderKI, _ := security.NewKeyInfo("senderKI", senderCipherPublicKey, senderSigningPublicKey)
+
+	// Write two bundles using the same keys, then pair the header of one with the data of the other
+	for _, encryptedFile := range []string{encryptedFile1, encryptedFile2} {
+		cfw, err := NewCipherWriter(receiverKI, senderKPI)
+		if !assert.Nil(s.T(), err) {
+			return
+		}
+
+		_, err = cfw.WriteToSplitFilesFromReader(encryptedFile, bytes.NewBuffer(werner_bytes))
+		if !assert.Nil(s.T(), err) {
+			return
+		}
+	}
+
+	cfr, err := NewCipherFileReader(receiverKPI, senderKI)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+
+	_, err = cfr.ReadSplitFilesToFile(encryptedFile1, helpers.ReplaceFileExt(encryptedFile2, ".bdata"), decryptedFile)
+	if assert.NotNil(s.T(), err) {
+		assert.True(s.T(), errors.Is(err, ErrHeaderDataMismatch))
+		assert.Contains(s.T(), err.Error(), "header does not belong to this data file")
+	}
+
+	decryptedBuff := bytes.NewBuffer(nil)
+	_, err = cfr.ReadSplitFilesToWriter(encryptedFile2, helpers.ReplaceFileExt(encryptedFile1, ".bdata"), decryptedBuff)
+	assert.True(s.T(), errors.Is(err, ErrHeaderDataMismatch))
+	assert.Equal(s.T(), 0, decryptedBuff.Len())
+}
+
+func (s *CipherIOTestSuite) TestCipherFileReader_ReadTruncatedSplitData() {
+	secretBytes, err := helpers.GetRandomBytes(DEFAULT_CHUNK_SIZE * 3)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+
+	receiverKPI, _ := security.NewKeyPairInfoWithSeeds("receiverKPI")
+	receiverCipherPublicKey, receiverSigningPublicKey, _ := receiverKPI.PublicKeys()
+	receiverKI, _ := security.NewKeyInfo("receiverKI", receiverCipherPublicKey, receiverSigningPublicKey)
+
+	senderKPI, _ := security.NewKeyPairInfoWithSeeds("senderKPI")
+	senderCipherPublicKey, senderSigningPublicKey, _ := senderKPI.PublicKeys()
+	senderKI, _ := security.NewKeyInfo("senderKI", senderCipherPublicKey, senderSigningPublicKey)
+
+	cfw, err := NewCipherWriter(receiverKI, senderKPI)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+
+	encryptedBuffHdr := bytes.NewBuffer(nil)
+	encryptedBuffData := bytes.NewBuffer(nil)
+	_, err = cfw.WriteToSplitStreamsFromReader(bytes.NewBuffer(secretBytes), encryptedBuffHdr, encryptedBuffData, nil, nil)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+
+	cfr, err := NewCipherFileReader(receiverKPI, senderKI)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+
+	bundleInfo, err := cfr.readBundleHeaderFrom(encryptedBuffHdr, false)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+
+	// Data truncated at a chunk boundary decrypts without error, so only the payload commitment catches it
+	chunkLen := chacha20poly1305.NonceSizeX + DEFAULT_CHUNK_SIZE + chacha20poly1305.Overhead
+	_, err = cfr.readBundleDataTo(bundleInfo, bytes.NewReader(encryptedBuffData.Bytes()[:chunkLen*2]), io.Discard)
+	assert.True(s.T(), errors.Is(err, ErrHeaderDataMismatch))
+
+	decryptedBuff := bytes.NewBuffer(nil)
+	_, err = cfr.readBundleDataTo(bundleInfo, bytes.NewReader(encryptedBuffData.Bytes()), decryptedBuff)
+	if assert.Nil(s.T(), err) {
+		assert.Equal(s.T(), secretBytes, decryptedBuff.Bytes())
+	}
+}
+
 func (s *CipherIOTestSuite) TestCipherFileWriter_WriteToCombinedStreamFromReader() {
 	secretBytes := werner_bytes
 	readerBuff := bytes.NewBuffer(secretBytes)
@@ -230,6 +344,292 @@ func (s *CipherIOTestSuite) TestCipherFileWriter_WriteToCombinedStreamFromReader
 	assert.Equal(s.T(), secretBytes, decryptedBytes)
 }
 
+func (s *CipherIOTestSuite) TestCipherFileWriter_WriteToCombinedStreamWithSelfSlot() {
+	secretBytes := werner_bytes
+	readerBuff := bytes.NewBuffer(secretBytes)
+	encryptedBuff := bytes.NewBuffer(nil)
+
+	receiverKPI, _ := security.NewKeyPairInfoWithSeeds("receiverKPI")
+	receiverCipherPublicKey, receiverSigningPublicKey, err := receiverKPI.PublicKeys()
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	receiverKI, _ := security.NewKeyInfo("receiverKI", receiverCipherPublicKey, receiverSigningPublicKey)
+
+	senderKPI, _ := security.NewKeyPairInfoWithSeeds("senderKPI")
+	senderCipherPublicKey, senderSigningPublicKey, err := senderKPI.PublicKeys()
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	senderKI, _ := security.NewKeyInfo("senderKI", senderCipherPublicKey, senderSigningPublicKey)
+
+	selfKPI, _ := security.NewKeyPairInfoWithSeeds("selfKPI")
+	selfCipherPublicKey, selfSigningPublicKey, err := selfKPI.PublicKeys()
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	selfKI, _ := security.NewKeyInfo("selfKI", selfCipherPublicKey, selfSigningPublicKey)
+
+	cfw, err := NewCipherWriter(receiverKI, senderKPI)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+
+	err = cfw.SetSelfRecipient(selfKI)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+
+	_, err = cfw.WriteToCombinedStreamFromReader(readerBuff, encryptedBuff, nil)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	encryptedBytes := encryptedBuff.Bytes()
+
+	// The receiver opens the bundle normally, skipping the self slot
+	cfr, err := NewCipherFileReader(receiverKPI, senderKI)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+
+	decryptedBuff := bytes.NewBuffer(nil)
+	_, err = cfr.ReadCombinedStreamToWriter(bytes.NewReader(encryptedBytes), decryptedBuff)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	assert.Equal(s.T(), secretBytes, decryptedBuff.Bytes())
+	assert.False(s.T(), cfr.OpenedViaSelfSlot)
+
+	// The sender opens the bundle via the self slot
+	cfrSelf, err := NewCipherFileReader(selfKPI, senderKI)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+
+	decryptedBuff = bytes.NewBuffer(nil)
+	_, err = cfrSelf.ReadCombinedStreamToWriter(bytes.NewReader(encryptedBytes), decryptedBuff)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	assert.Equal(s.T(), secretBytes, decryptedBuff.Bytes())
+	assert.True(s.T(), cfrSelf.OpenedViaSelfSlot)
+
+	// Any other keypair can open neither slot
+	otherKPI, _ := security.NewKeyPairInfoWithSeeds("otherKPI")
+	cfrOther, err := NewCipherFileReader(otherKPI, senderKI)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+
+	_, err = cfrOther.ReadCombinedStreamToWriter(bytes.NewReader(encryptedBytes), bytes.NewBuffer(nil))
+	assert.NotNil(s.T(), err)
+}
+
+func (s *CipherIOTestSuite) TestCipherFileWriter_WriteToCombinedStreamWithSubkey() {
+	secretBytes := werner_bytes
+	encryptedBuff := bytes.NewBuffer(nil)
+
+	receiverKPI, _ := security.NewKeyPairInfoWithSeeds("receiverKPI")
+	subkey, err := receiverKPI.AddSubkey(security.SubkeyDefaultValidity)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+
+	receiverCipherPublicKey, receiverSigningPublicKey, err := receiverKPI.PublicKeys()
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	receiverKI, _ := security.NewKeyInfo("receiverKI", receiverCipherPublicKey, receiverSigningPublicKey)
+	receiverKI.Subkeys = receiverKPI.SubkeyCertificates()
+
+	senderKPI, _ := security.NewKeyPairInfoWithSeeds("senderKPI")
+	senderCipherPublicKey, senderSigningPublicKey, err := senderKPI.PublicKeys()
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	senderKI, _ := security.NewKeyInfo("senderKI", senderCipherPublicKey, senderSigningPublicKey)
+
+	cfw, err := NewCipherWriter(receiverKI, senderKPI)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	assert.Equal(s.T(), subkey.Certificate.CipherPubKey, cfw.ReceiverCipherPublicKey)
+
+	_, err = cfw.WriteToCombinedStreamFromReader(bytes.NewBuffer(secretBytes), encryptedBuff, nil)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	encryptedBytes := encryptedBuff.Bytes()
+
+	cfr, err := NewCipherFileReader(receiverKPI, senderKI)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+
+	decryptedBuff := bytes.NewBuffer(nil)
+	_, err = cfr.ReadCombinedStreamToWriter(bytes.NewReader(encryptedBytes), decryptedBuff)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	assert.Equal(s.T(), secretBytes, decryptedBuff.Bytes())
+	assert.True(s.T(), cfr.OpenedWithSubkey)
+
+	// Without the subkey, the primary key alone can not open the bundle
+	primaryOnlyKPI := receiverKPI.Clone()
+	primaryOnlyKPI.Subkeys = nil
+	cfrPrimary, err := NewCipherFileReader(primaryOnlyKPI, senderKI)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+
+	_, err = cfrPrimary.ReadCombinedStreamToWriter(bytes.NewReader(encryptedBytes), bytes.NewBuffer(nil))
+	assert.NotNil(s.T(), err)
+}
+
+func (s *CipherIOTestSuite) TestCipherFileReader_ReadWithPriorSenderKey() {
+	secretBytes := werner_bytes
+	encryptedBuff := bytes.NewBuffer(nil)
+
+	receiverKPI, _ := security.NewKeyPairInfoWithSeeds("receiverKPI")
+	receiverCipherPublicKey, receiverSigningPublicKey, err := receiverKPI.PublicKeys()
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	receiverKI, _ := security.NewKeyInfo("receiverKI", receiverCipherPublicKey, receiverSigningPublicKey)
+
+	// The bundle is created with the sender's old keys
+	oldSenderKPI, _ := security.NewKeyPairInfoWithSeeds("oldSenderKPI")
+	oldCipherPublicKey, oldSigningPublicKey, err := oldSenderKPI.PublicKeys()
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	oldSenderKI, _ := security.NewKeyInfo("senderKI", oldCipherPublicKey, oldSigningPublicKey)
+
+	cfw, err := NewCipherWriter(receiverKI, oldSenderKPI)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+
+	_, err = cfw.WriteToCombinedStreamFromReader(bytes.NewBuffer(secretBytes), encryptedBuff, nil)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	encryptedBytes := encryptedBuff.Bytes()
+
+	// The receiver now has the sender's new keys
+	newSenderKPI, _ := security.NewKeyPairInfoWithSeeds("newSenderKPI")
+	newCipherPublicKey, newSigningPublicKey, err := newSenderKPI.PublicKeys()
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	newSenderKI, _ := security.NewKeyInfo("senderKI", newCipherPublicKey, newSigningPublicKey)
+
+	cfr, err := NewCipherFileReader(receiverKPI, newSenderKI)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+
+	// Without the prior keys, the bundle can not be opened
+	_, err = cfr.ReadCombinedStreamToWriter(bytes.NewReader(encryptedBytes), bytes.NewBuffer(nil))
+	assert.NotNil(s.T(), err)
+
+	var validatedSigningPubKey string
+	cfr.SenderPriorKeys = []*security.KeyInfo{oldSenderKI}
+	cfr.HeaderValidator = func(bundleInfo *BundleInfo, senderSigningPubKey string) error {
+		validatedSigningPubKey = senderSigningPubKey
+		return nil
+	}
+
+	decryptedBuff := bytes.NewBuffer(nil)
+	_, err = cfr.ReadCombinedStreamToWriter(bytes.NewReader(encryptedBytes), decryptedBuff)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	assert.Equal(s.T(), secretBytes, decryptedBuff.Bytes())
+	assert.True(s.T(), cfr.OpenedWithPriorSenderKey)
+	assert.Equal(s.T(), oldSigningPublicKey, validatedSigningPubKey)
+}
+
+func (s *CipherIOTestSuite) TestCipherFileReader_ReadWithPriorGroupEpoch() {
+	secretBytes := werner_bytes
+	encryptedBuff := bytes.NewBuffer(nil)
+
+	groupKPI, err := security.NewGroupKeyPairInfo("groupKPI", nil)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	groupCipherPublicKey, groupSigningPublicKey, err := groupKPI.PublicKeys()
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	groupKI, _ := security.NewKeyInfo("groupKI", groupCipherPublicKey, groupSigningPublicKey)
+
+	senderKPI, _ := security.NewKeyPairInfoWithSeeds("senderKPI")
+	senderCipherPublicKey, senderSigningPublicKey, err := senderKPI.PublicKeys()
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	senderKI, _ := security.NewKeyInfo("senderKI", senderCipherPublicKey, senderSigningPublicKey)
+
+	// The bundle is sent to the group's epoch 1 keys
+	cfw, err := NewCipherWriter(groupKI, senderKPI)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+
+	_, err = cfw.WriteToCombinedStreamFromReader(bytes.NewBuffer(secretBytes), encryptedBuff, nil)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	encryptedBytes := encryptedBuff.Bytes()
+
+	// The group is rekeyed twice before the bundle is opened
+	if !assert.Nil(s.T(), groupKPI.Rekey()) || !assert.Nil(s.T(), groupKPI.Rekey()) {
+		return
+	}
+	assert.Equal(s.T(), 3, groupKPI.GroupEpoch)
+
+	cfr, err := NewCipherFileReader(groupKPI, senderKI)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	assert.Equal(s.T(), 2, len(cfr.ReceiverEpochCipherKPs))
+
+	decryptedBuff := bytes.NewBuffer(nil)
+	_, err = cfr.ReadCombinedStreamToWriter(bytes.NewReader(encryptedBytes), decryptedBuff)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+	assert.Equal(s.T(), secretBytes, decryptedBuff.Bytes())
+	assert.Equal(s.T(), 1, cfr.OpenedWithGroupEpoch)
+	assert.False(s.T(), cfr.OpenedWithSubkey)
+
+	// A member that joined at epoch 3 only holds the current keys, so it can not open the bundle
+	joinedKPI := security.NewKeyPairInfoFromSeeds("groupKPI", groupKPI.CipherSeed, groupKPI.SigningSeed)
+	joinedKPI.GroupEpoch = groupKPI.GroupEpoch
+	cfrJoined, err := NewCipherFileReader(joinedKPI, senderKI)
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+
+	_, err = cfrJoined.ReadCombinedStreamToWriter(bytes.NewReader(encryptedBytes), bytes.NewBuffer(nil))
+	assert.NotNil(s.T(), err)
+}
+
+func (s *CipherIOTestSuite) TestBundleInfo_BundleIDToText() {
+	bundleInfo, err := NewBundle()
+	if !assert.Nil(s.T(), err) {
+		return
+	}
+
+	bundleIDText := bundleInfo.BundleIDToText()
+	assert.Regexp(s.T(), "^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$", bundleIDText)
+
+	// Bundles from versions prior to bundle IDs have no ID text
+	bundleInfo.BundleID = nil
+	assert.Equal(s.T(), "", bundleInfo.BundleIDToText())
+}
+
 func (s *CipherIOTestSuite) TestCipherFileWriter_WriteToCombinedStreamFromReader_LargeStream() {
 	secretBytes, err := helpers.GetRandomBytes(10000000)
 
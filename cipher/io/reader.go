@@ -27,19 +27,61 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 const DEFAULT_OUTPUT_FILE_NAME = "bee.output"
 
+// ErrHeaderDataMismatch is returned when a split bundle header's payload commitment does not match the data provided
+var ErrHeaderDataMismatch = errors.New("header does not belong to this data file")
+
 type CipherReader struct {
-	ReceiverCipherKP    nkeys.KeyPair
+	ReceiverCipherKP nkeys.KeyPair
+
+	// ReceiverSubkeyCipherKPs are the receiver's encryption subkeys, newest first.  These are tried
+	// after ReceiverCipherKP when decrypting a header.
+	ReceiverSubkeyCipherKPs []nkeys.KeyPair
+
+	// ReceiverEpochCipherKPs are the cipher keys of the receiver's prior group epochs, newest first.  These are
+	// tried after the subkeys, so bundles sent to a group before it was rekeyed can still be opened.
+	ReceiverEpochCipherKPs []*EpochCipherKP
+
 	SenderCipherPubKey  string
 	SenderSigningPubKey string
 	CombinedFilePath    string
 	BundleFilePath      string
 	DataFilePath        string
+
+	// SenderPriorKeys are the sender's superseded public keys, newest first.  These are tried after the
+	// sender's current keys, so bundles created before a key change can still be opened.
+	SenderPriorKeys []*security.KeyInfo
+
+	// OpenedViaSelfSlot is set when the last header read was opened using the sender's self slot
+	OpenedViaSelfSlot bool
+
+	// OpenedWithSubkey is set when the last header read was opened using one of the receiver's subkeys
+	OpenedWithSubkey bool
+
+	// OpenedWithPriorSenderKey is set when the last header read was created with one of the sender's prior keys
+	OpenedWithPriorSenderKey bool
+
+	// OpenedWithGroupEpoch is set to the prior group epoch whose key opened the last header read, or 0 otherwise
+	OpenedWithGroupEpoch int
+
+	// HeaderValidator is optional.  It is called once a header is decrypted and its signature is validated, but before
+	// any payload is decrypted.  If it returns an error, the read is aborted with that error.
+	HeaderValidator HeaderValidatorFunc
+}
+
+// EpochCipherKP is the cipher keypair of a prior group epoch
+type EpochCipherKP struct {
+	Epoch    int
+	CipherKP nkeys.KeyPair
 }
 
+// HeaderValidatorFunc allows callers to inspect and reject a bundle header before the payload is decrypted
+type HeaderValidatorFunc func(bundleInfo *BundleInfo, senderSigningPubKey string) error
+
 func NewCipherFileReader(receiverKPI *security.KeyPairInfo, senderKI *security.KeyInfo) (*CipherReader, error) {
 	if receiverKPI == nil {
 		return nil, errors.New("receiver key is nil")
@@ -54,11 +96,35 @@ func NewCipherFileReader(receiverKPI *security.KeyPairInfo, senderKI *security.K
 		return nil, fmt.Errorf("error transforming receiver cipher seed: %w", err)
 	}
 
-	return &CipherReader{
+	cfr := &CipherReader{
 		ReceiverCipherKP:    ReceiverKP,
 		SenderCipherPubKey:  senderKI.CipherPubKey,
 		SenderSigningPubKey: senderKI.SigningPubKey,
-	}, nil
+	}
+
+	for _, subkeySeed := range receiverKPI.OpenCipherSeeds(time.Now()) {
+		subkeyKP, err := nkeys.FromCurveSeed(subkeySeed)
+		security.Wipe(subkeySeed)
+		if err != nil {
+			cfr.Wipe()
+			return nil, fmt.Errorf("error transforming receiver subkey seed: %w", err)
+		}
+
+		cfr.ReceiverSubkeyCipherKPs = append(cfr.ReceiverSubkeyCipherKPs, subkeyKP)
+	}
+
+	for _, epochKey := range receiverKPI.PriorGroupEpochKeys() {
+		epochKP, err := nkeys.FromCurveSeed(epochKey.CipherSeed)
+		epochKey.Wipe()
+		if err != nil {
+			cfr.Wipe()
+			return nil, fmt.Errorf("error transforming receiver group epoch %d seed: %w", epochKey.Epoch, err)
+		}
+
+		cfr.ReceiverEpochCipherKPs = append(cfr.ReceiverEpochCipherKPs, &EpochCipherKP{Epoch: epochKey.Epoch, CipherKP: epochKP})
+	}
+
+	return cfr, nil
 }
 
 func (cfr *CipherReader) ReadCombinedFileToBytes(combinedFilePath string) ([]byte, error) {
@@ -92,9 +158,86 @@ func (cfr *CipherReader) GetBundleDetailsFromReader(r io.Reader) (*BundleInfo, e
 }
 
 func (cfr *CipherReader) readBundleHeaderFrom(r io.Reader, allowMultiDir bool) (*BundleInfo, error) {
+	cfr.OpenedViaSelfSlot = false
+	cfr.OpenedWithSubkey = false
+	cfr.OpenedWithPriorSenderKey = false
+	cfr.OpenedWithGroupEpoch = 0
+
+	encryptedBundleBytes, err := readBundleHeaderSlot(r)
+	if err != nil {
+		return nil, err
+	}
+
+	bundleInfo, senderKI, err := cfr.decryptBundleHeaderSlot(encryptedBundleBytes)
+	if err != nil {
+		// The header may be wrapped to someone else, with a self slot for us following it.  If the next slot does not
+		// open either, we report the original error, since this was most likely not our bundle or not a self slot.
+		selfSlotBytes, slotErr := readBundleHeaderSlot(r)
+		if slotErr != nil {
+			return nil, err
+		}
+
+		var slotBundleInfo *BundleInfo
+		slotBundleInfo, senderKI, slotErr = cfr.decryptBundleHeaderSlot(selfSlotBytes)
+		if slotErr != nil {
+			return nil, err
+		}
+
+		if !slotBundleInfo.SelfSlot {
+			slotBundleInfo.Wipe()
+			return nil, err
+		}
+
+		logger.Debug("Bundle header opened via the self slot")
+		bundleInfo = slotBundleInfo
+		cfr.OpenedViaSelfSlot = true
+	} else if bundleInfo.SelfSlot {
+		// We are the receiver, so just skip past the sender's self slot
+		_, err = readBundleHeaderSlot(r)
+		if err != nil {
+			bundleInfo.Wipe()
+			return nil, fmt.Errorf("failed reading self slot: %w", err)
+		}
+	}
+
+	logger.Debug("Validating bundle signature")
+	if senderKI.SigningPubKey != cfr.SenderSigningPubKey {
+		logger.Debug("Bundle header was created with a prior sender key")
+		cfr.OpenedWithPriorSenderKey = true
+	}
+
+	isValid, err := senderKI.VerifyRandomSignature(bundleInfo.SenderSig)
+	if err != nil {
+		logger.Debugfln("Sender identity validation failed: %s", err)
+		return nil, fmt.Errorf("Sender identity validation failed: %w", err)
+	}
+
+	if !isValid {
+		// Todo: Validate sender sig... we may want a warning flag so you can override this hard error
+		logger.Debug("Bundle signature does not match sender identity")
+		return nil, errors.New("bundle signature does not match sender identity")
+	}
+
+	if !allowMultiDir && bundleInfo.InputSource == BundleInputSourceMultiDir {
+		return nil, errors.New("this bundle access request does not support multi-directory bundles")
+	}
+
+	if cfr.HeaderValidator != nil {
+		err = cfr.HeaderValidator(bundleInfo, senderKI.SigningPubKey)
+		if err != nil {
+			bundleInfo.Wipe()
+			return nil, err
+		}
+	}
+
+	return bundleInfo, nil
+}
+
+// readBundleHeaderSlot reads a single length prefixed, encrypted header from r
+func readBundleHeaderSlot(r io.Reader) ([]byte, error) {
 	// Get the bundle len first
 	bundleLenBytes := make([]byte, 2)
-	bytesRead, err := r.Read(bundleLenBytes)
+	bytesRead, err := io.ReadFull(r, bundleLenBytes)
 	if err != nil {
 		return nil, fmt.Errorf("failed reading bundle length from input: %w", err)
 	}
@@ -112,7 +255,7 @@ func (cfr *CipherReader) readBundleHeaderFrom(r io.Reader, allowMultiDir bool) (
 
 	// Now, read in the encrypted bundle info
 	encryptedBundleBytes := make([]byte, bundleLen)
-	bytesRead, err = r.Read(encryptedBundleBytes)
+	bytesRead, err = io.ReadFull(r, encryptedBundleBytes)
 	if err != nil {
 		return nil, fmt.Errorf("failed reading bundle data from input: %w", err)
 	}
@@ -123,13 +266,79 @@ func (cfr *CipherReader) readBundleHeaderFrom(r io.Reader, allowMultiDir bool) (
 		)
 	}
 
-	receiverSeed, err := cfr.ReceiverCipherKP.Seed()
+	return encryptedBundleBytes, nil
+}
+
+// decryptBundleHeaderSlot decrypts and deserializes an encrypted header using the receiver and sender keys.
+// The sender's current keys are tried first, then its prior keys.  For each sender key, the receiver's primary
+// cipher key is tried first, then each of its subkeys.  The sender keys that opened the header are returned.
+func (cfr *CipherReader) decryptBundleHeaderSlot(encryptedBundleBytes []byte) (*BundleInfo, *security.KeyInfo, error) {
+	senderKI := &security.KeyInfo{
+		Name:          "verify-sender",
+		CipherPubKey:  cfr.SenderCipherPubKey,
+		SigningPubKey: cfr.SenderSigningPubKey,
+	}
+
+	bundleInfo, err := cfr.decryptBundleHeaderSlotFromSender(senderKI.CipherPubKey, encryptedBundleBytes)
+	if err == nil {
+		return bundleInfo, senderKI, nil
+	}
+
+	for _, priorKI := range cfr.SenderPriorKeys {
+		var priorErr error
+		bundleInfo, priorErr = cfr.decryptBundleHeaderSlotFromSender(priorKI.CipherPubKey, encryptedBundleBytes)
+		if priorErr == nil {
+			return bundleInfo, priorKI, nil
+		}
+	}
+
+	// report the error from the current sender keys
+	return nil, nil, err
+}
+
+// decryptBundleHeaderSlotFromSender tries the receiver's primary cipher key, then each of its subkeys, then the
+// keys of its prior group epochs
+func (cfr *CipherReader) decryptBundleHeaderSlotFromSender(senderCipherPubKey string, encryptedBundleBytes []byte) (*BundleInfo, error) {
+	cfr.OpenedWithGroupEpoch = 0
+	bundleInfo, err := decryptBundleHeaderSlotWithKP(cfr.ReceiverCipherKP, senderCipherPubKey, encryptedBundleBytes)
+	if err == nil {
+		cfr.OpenedWithSubkey = false
+		return bundleInfo, nil
+	}
+
+	for _, subkeyKP := range cfr.ReceiverSubkeyCipherKPs {
+		var subkeyErr error
+		bundleInfo, subkeyErr = decryptBundleHeaderSlotWithKP(subkeyKP, senderCipherPubKey, encryptedBundleBytes)
+		if subkeyErr == nil {
+			logger.Debug("Bundle header opened with a receiver subkey")
+			cfr.OpenedWithSubkey = true
+			return bundleInfo, nil
+		}
+	}
+
+	for _, epochKP := range cfr.ReceiverEpochCipherKPs {
+		var epochErr error
+		bundleInfo, epochErr = decryptBundleHeaderSlotWithKP(epochKP.CipherKP, senderCipherPubKey, encryptedBundleBytes)
+		if epochErr == nil {
+			logger.Debugfln("Bundle header opened with the key of group epoch %d", epochKP.Epoch)
+			cfr.OpenedWithSubkey = false
+			cfr.OpenedWithGroupEpoch = epochKP.Epoch
+			return bundleInfo, nil
+		}
+	}
+
+	// report the error from the primary key
+	return nil, err
+}
+
+func decryptBundleHeaderSlotWithKP(receiverKP nkeys.KeyPair, senderCipherPubKey string, encryptedBundleBytes []byte) (*BundleInfo, error) {
+	receiverSeed, err := receiverKP.Seed()
 	if err != nil {
 		return nil, fmt.Errorf("failed extracting seed from receiver kp: %w", err)
 	}
 	defer security.Wipe(receiverSeed)
 
-	nc, err := cipher.NewNKeysCipherDecrypter(receiverSeed, cfr.SenderCipherPubKey)
+	nc, err := cipher.NewNKeysCipherDecrypter(receiverSeed, senderCipherPubKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed creating nkeys cipher: %w", err)
 	}
@@ -144,41 +353,76 @@ func (cfr *CipherReader) readBundleHeaderFrom(r io.Reader, allowMultiDir bool) (
 	}
 
 	bundleDecrytedBytes := bundleDecryptWriter.Bytes()
+	defer security.Wipe(bundleDecrytedBytes)
+
 	bundleInfo := &BundleInfo{}
 	err = msgpack.Unmarshal(bundleDecrytedBytes, bundleInfo)
 	if err != nil {
 		return nil, fmt.Errorf("failed transforming bundle header: %w", err)
 	}
 
-	logger.Debug("Validating bundle signature")
-	verifyKI, _ := security.NewKeyInfo("verify-sender", cfr.SenderCipherPubKey, cfr.SenderSigningPubKey)
-	isValid, err := verifyKI.VerifyRandomSignature(bundleInfo.SenderSig)
+	return bundleInfo, nil
+}
+
+func (cfr *CipherReader) readBundleDataTo(bundleInfo *BundleInfo, r io.Reader, w io.Writer) (int, error) {
+	sc, err := cipher.NewSymmetricCipherFromSalt(bundleInfo.SymmetricKey, bundleInfo.Salt, DEFAULT_CHUNK_SIZE)
 	if err != nil {
-		logger.Debugfln("Sender identity validation failed: %s", err)
-		return nil, fmt.Errorf("Sender identity validation failed: %w", err)
+		return 0, fmt.Errorf("failed creating symmetric cipher: %w", err)
 	}
 
-	if !isValid {
-		// Todo: Validate sender sig... we may want a warning flag so you can override this hard error
-		logger.Debug("Bundle signature does not match sender identity")
-		return nil, errors.New("bundle signature does not match sender identity")
+	if len(bundleInfo.KeyCommitment) != 0 {
+		sc.SetKeyCommitment(bundleInfo.KeyCommitment)
+	} else if bundleInfo.RequiresKeyCommitment() {
+		return 0, errors.New("bundle header is missing the key commitment")
 	}
 
-	if !allowMultiDir && bundleInfo.InputSource == BundleInputSourceMultiDir {
-		return nil, errors.New("this bundle access request does not support multi-directory bundles")
+	sc.SetAssociatedData(bundleInfo.BundleID)
+	bytesWritten, err := sc.Decrypt(r, w)
+	if err != nil {
+		return bytesWritten, err
 	}
 
-	return bundleInfo, nil
+	// The data may have changed since it was verified, such as a file truncated at a chunk boundary, so the
+	// chunks that were decrypted are checked against the header's payload commitment as well
+	if len(bundleInfo.PayloadHash) != 0 &&
+		(sc.GetChunkCount() != bundleInfo.PayloadChunkCount || !bytes.Equal(sc.GetPayloadHash(), bundleInfo.PayloadHash)) {
+		return bytesWritten, ErrHeaderDataMismatch
+	}
+
+	return bytesWritten, nil
 }
 
-func (cfr *CipherReader) readBundleDataTo(bundleInfo *BundleInfo, r io.Reader, w io.Writer) (int, error) {
-	sc, err := cipher.NewSymmetricCipherFromSalt(bundleInfo.SymmetricKey, bundleInfo.Salt, DEFAULT_CHUNK_SIZE)
+// verifyPayloadCommitment confirms that the payload in r matches the chunk count and hash recorded in
+// the bundle header.  Headers from older versions do not carry a commitment, so they are not checked.
+func verifyPayloadCommitment(bundleInfo *BundleInfo, r io.Reader) error {
+	if len(bundleInfo.PayloadHash) == 0 {
+		return nil
+	}
+
+	chunkCount, payloadHash, err := cipher.ComputePayloadCommitment(r, DEFAULT_CHUNK_SIZE)
 	if err != nil {
-		return 0, fmt.Errorf("failed creating symmetric cipher: %w", err)
+		return fmt.Errorf("failed reading bundle data: %w", err)
 	}
 
-	bytesWritten, err := sc.Decrypt(r, w)
-	return bytesWritten, err
+	if chunkCount != bundleInfo.PayloadChunkCount || !bytes.Equal(payloadHash, bundleInfo.PayloadHash) {
+		return ErrHeaderDataMismatch
+	}
+
+	return nil
+}
+
+// verifySplitDataFile checks the data file against the header's payload commitment before any decryption occurs
+func verifySplitDataFile(bundleInfo *BundleInfo, bundleDataFilePath string) error {
+	fileDataIn, err := os.Open(bundleDataFilePath)
+	if err != nil {
+		return fmt.Errorf("failed opening bundle data file: %s", err)
+	}
+
+	defer func() {
+		_ = fileDataIn.Close()
+	}()
+
+	return verifyPayloadCommitment(bundleInfo, fileDataIn)
 }
 
 // ReadCombinedFileToWriter assumes the input file path provided has been validated
@@ -372,6 +616,11 @@ func (cfr *CipherReader) ReadSplitFilesToWriter(bundleHeaderFilePath, bundleData
 	}
 	defer bundleInfo.Wipe()
 
+	err = verifySplitDataFile(bundleInfo, bundleDataFilePath)
+	if err != nil {
+		return 0, err
+	}
+
 	fileDataIn, err := os.Open(bundleDataFilePath)
 	if err != nil {
 		return 0, fmt.Errorf("failed opening bundle header file: %s", err)
@@ -406,6 +655,11 @@ func (cfr *CipherReader) ReadSplitFilesToPath(bundleHeaderFilePath, bundleDataFi
 	}
 	defer bundleInfo.Wipe()
 
+	err = verifySplitDataFile(bundleInfo, bundleDataFilePath)
+	if err != nil {
+		return 0, err
+	}
+
 	var outputWriter io.Writer
 	var mdsw streams.StreamWriter
 	if bundleInfo.InputSource == BundleInputSourceMultiDir {
@@ -482,6 +736,11 @@ func (cfr *CipherReader) ReadSplitFilesToFile(bundleHeaderFilePath, bundleDataFi
 	}
 	defer bundleInfo.Wipe()
 
+	err = verifySplitDataFile(bundleInfo, bundleDataFilePath)
+	if err != nil {
+		return 0, err
+	}
+
 	fileOut, err := os.Create(outputFilePath)
 	if err != nil {
 		return 0, fmt.Errorf("unable to open output file: %s", err)
@@ -530,7 +789,18 @@ func (cfr *CipherReader) ReadSplitStreamsToWriter(readerHdr io.Reader, readerDat
 	}
 	defer bundleInfo.Wipe()
 
-	bytesWritten, err := cfr.readBundleDataTo(bundleInfo, readerData, w)
+	// Split streams come from the console or clipboard, so buffering the payload to verify it first is acceptable
+	dataBytes, err := io.ReadAll(readerData)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read bundle data from input: %w", err)
+	}
+
+	err = verifyPayloadCommitment(bundleInfo, bytes.NewReader(dataBytes))
+	if err != nil {
+		return 0, err
+	}
+
+	bytesWritten, err := cfr.readBundleDataTo(bundleInfo, bytes.NewReader(dataBytes), w)
 	if err != nil {
 		return bytesWritten, fmt.Errorf("unable to write bundle data: %w", err)
 	}
@@ -557,4 +827,12 @@ func (cfr *CipherReader) Wipe() {
 	if cfr.ReceiverCipherKP != nil {
 		cfr.ReceiverCipherKP.Wipe()
 	}
+
+	for _, subkeyKP := range cfr.ReceiverSubkeyCipherKPs {
+		subkeyKP.Wipe()
+	}
+
+	for _, epochKP := range cfr.ReceiverEpochCipherKPs {
+		epochKP.CipherKP.Wipe()
+	}
 }
@@ -0,0 +1,460 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"bufio"
+	"bytes"
+	cryptorand "crypto/rand"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nkeys"
+	"github.com/thoughtrealm/bumblebee/security"
+	"github.com/vmihailenco/msgpack/v5"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Signed messages are plaintext payloads with a signature trailer.  They are always emitted as armored
+// text, using the same marker line style as the bundle text outputs.  The signature is an ed25519
+// signature over a SHA-512 digest of the signed header and payload, which is accumulated while the
+// payload streams through the writer.
+const (
+	SignedMessageVersion = "1"
+
+	SignedHeaderMarker = ":start :signed-header"
+	SignedTextMarker   = ":start :signed-text"
+	SignedDataMarker   = ":start :signed-data"
+	SignatureMarker    = ":start :signature"
+	SignedEndMarker    = ":end"
+
+	signedMessageDomain = "bumblebee signed message v1"
+	signedLineWidth     = 32
+	signedMarkerWidth   = signedLineWidth * 2
+	signedNonceSize     = 16
+	signedTextEscape    = "- "
+	textDetectionSize   = 8000
+)
+
+var ErrSignatureInvalid = errors.New("signature does not match sender identity")
+
+type SignedPayloadEncoding int
+
+const (
+	SignedPayloadEncodingText SignedPayloadEncoding = 0
+	SignedPayloadEncodingHex  SignedPayloadEncoding = 1
+)
+
+func SignedPayloadEncodingToText(spe SignedPayloadEncoding) string {
+	switch spe {
+	case SignedPayloadEncodingText:
+		return "Text"
+	case SignedPayloadEncodingHex:
+		return "Hex"
+	default:
+		return "Unknown"
+	}
+}
+
+type SignedMessageInfo struct {
+	// Version identifies the version of the Bumblebee functionality that built the signed message
+	Version string
+	// FromName indicates the name of the keypair that signed the message
+	FromName string
+	// The date the message was signed
+	CreateDate string // RFC3339
+	// InputSource records the source type of the data provided for signing
+	InputSource BundleInputSource
+	// OriginalFileName records the file name of the source file, IF the source was a file
+	OriginalFileName string
+	// OriginalFileDate records the date stamp of the source file, IF the source was a file
+	OriginalFileDate string // RFC3339
+	// PayloadEncoding indicates whether the payload is emitted as escaped text lines or hex
+	PayloadEncoding SignedPayloadEncoding
+	// Nonce is a random value, so that signing identical payloads never produces identical messages
+	Nonce []byte
+}
+
+type SignedWriter struct {
+	SenderSigningKeyPair nkeys.KeyPair
+	OutputInfo           *SignedMessageInfo
+}
+
+func NewSignedWriter(senderKPI *security.KeyPairInfo) (*SignedWriter, error) {
+	if senderKPI == nil {
+		return nil, errors.New("sender key is nil")
+	}
+
+	senderSigningKP, err := senderKPI.GetSigningKeyPair()
+	if err != nil {
+		return nil, fmt.Errorf("error transforming sender key seed: %w", err)
+	}
+
+	info := &SignedMessageInfo{
+		Version:    SignedMessageVersion,
+		FromName:   senderKPI.Name,
+		CreateDate: time.Now().Format(time.RFC3339),
+		Nonce:      make([]byte, signedNonceSize),
+	}
+
+	_, err = cryptorand.Read(info.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed generating nonce: %w", err)
+	}
+
+	return &SignedWriter{
+		SenderSigningKeyPair: senderSigningKP,
+		OutputInfo:           info,
+	}, nil
+}
+
+// WriteSignedTextFromReader emits the armored signed message to w, while streaming the payload from r.
+// The payload encoding is chosen from the first bytes of the payload.  Text payloads remain readable, while
+// binary payloads are hex encoded.
+func (sw *SignedWriter) WriteSignedTextFromReader(r io.Reader, w io.Writer) (int, error) {
+	br := bufio.NewReaderSize(r, textDetectionSize)
+	peekBytes, err := br.Peek(textDetectionSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return 0, fmt.Errorf("failed reading payload: %w", err)
+	}
+
+	if looksLikeText(peekBytes) {
+		sw.OutputInfo.PayloadEncoding = SignedPayloadEncodingText
+	} else {
+		sw.OutputInfo.PayloadEncoding = SignedPayloadEncodingHex
+	}
+
+	infoBytes, err := msgpack.Marshal(sw.OutputInfo)
+	if err != nil {
+		return 0, fmt.Errorf("failed serializing signed message info: %w", err)
+	}
+
+	digest := newSignedMessageDigest(infoBytes)
+	aw := &armorWriter{w: w}
+
+	aw.writeMarkerLine(SignedHeaderMarker)
+	aw.writeHexLines(infoBytes)
+	aw.writeMarkerLine(SignedEndMarker)
+	if aw.err != nil {
+		return aw.bytesWritten, fmt.Errorf("failed writing signed message header: %w", aw.err)
+	}
+
+	var payloadWriter interface {
+		io.Writer
+		flush()
+	}
+
+	if sw.OutputInfo.PayloadEncoding == SignedPayloadEncodingText {
+		aw.writeMarkerLine(SignedTextMarker)
+		payloadWriter = &armorTextPayloadWriter{aw: aw}
+	} else {
+		aw.writeMarkerLine(SignedDataMarker)
+		payloadWriter = &armorHexPayloadWriter{aw: aw}
+	}
+
+	_, err = io.Copy(io.MultiWriter(digest, payloadWriter), br)
+	if err != nil {
+		return aw.bytesWritten, fmt.Errorf("failed writing signed message payload: %w", err)
+	}
+
+	payloadWriter.flush()
+	aw.writeMarkerLine(SignedEndMarker)
+	if aw.err != nil {
+		return aw.bytesWritten, fmt.Errorf("failed writing signed message payload: %w", aw.err)
+	}
+
+	signature, err := sw.SenderSigningKeyPair.Sign(digest.Sum(nil))
+	if err != nil {
+		return aw.bytesWritten, fmt.Errorf("failed signing message: %w", err)
+	}
+
+	aw.writeMarkerLine(SignatureMarker)
+	aw.writeHexLines(signature)
+	aw.writeMarkerLine(SignedEndMarker)
+	if aw.err != nil {
+		return aw.bytesWritten, fmt.Errorf("failed writing signature: %w", aw.err)
+	}
+
+	return aw.bytesWritten, nil
+}
+
+func (sw *SignedWriter) Wipe() {
+	if sw.SenderSigningKeyPair != nil {
+		sw.SenderSigningKeyPair.Wipe()
+	}
+}
+
+// SignedMessage is a parsed armored signed message.  The payload must not be trusted until Verify succeeds.
+type SignedMessage struct {
+	Info      *SignedMessageInfo
+	Payload   []byte
+	infoBytes []byte
+	signature []byte
+}
+
+// IsSignedMessage returns true if data contains an armored signed message
+func IsSignedMessage(data []byte) bool {
+	return bytes.Contains(data, []byte(SignedHeaderMarker))
+}
+
+// ParseSignedMessage parses armored signed message text.  It does not verify the signature.
+func ParseSignedMessage(data []byte) (*SignedMessage, error) {
+	type parseMode int
+	const (
+		parseModeUnknown parseMode = iota
+		parseModeHeader
+		parseModeText
+		parseModeHex
+		parseModeSignature
+	)
+
+	var (
+		mode         parseMode
+		headerHex    strings.Builder
+		dataHex      strings.Builder
+		signatureHex strings.Builder
+		textLines    []string
+		foundText    bool
+	)
+
+	// If the armor was converted to CRLF line endings, such as by a clipboard, the text lines must be
+	// restored to LF so that the payload matches what was signed.
+	lines := strings.Split(string(data), "\n")
+	isCRLF := false
+	for _, line := range lines {
+		if strings.HasPrefix(line, SignedHeaderMarker) {
+			isCRLF = strings.HasSuffix(line, "\r")
+			break
+		}
+	}
+
+	for _, line := range lines {
+		if isCRLF {
+			line = strings.TrimSuffix(line, "\r")
+		}
+
+		if mode == parseModeText {
+			if strings.HasPrefix(line, SignedEndMarker) {
+				mode = parseModeUnknown
+				continue
+			}
+
+			textLines = append(textLines, strings.TrimPrefix(line, signedTextEscape))
+			continue
+		}
+
+		trimmedLine := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmedLine, SignedHeaderMarker):
+			mode = parseModeHeader
+		case strings.HasPrefix(trimmedLine, SignedTextMarker):
+			mode = parseModeText
+			foundText = true
+		case strings.HasPrefix(trimmedLine, SignedDataMarker):
+			mode = parseModeHex
+		case strings.HasPrefix(trimmedLine, SignatureMarker):
+			mode = parseModeSignature
+		case strings.HasPrefix(trimmedLine, SignedEndMarker):
+			mode = parseModeUnknown
+		case mode == parseModeHeader:
+			headerHex.WriteString(trimmedLine)
+		case mode == parseModeHex:
+			dataHex.WriteString(trimmedLine)
+		case mode == parseModeSignature:
+			signatureHex.WriteString(trimmedLine)
+		}
+	}
+
+	if headerHex.Len() == 0 {
+		return nil, errors.New("signed message header not found")
+	}
+
+	if signatureHex.Len() == 0 {
+		return nil, errors.New("signature not found")
+	}
+
+	sm := &SignedMessage{Info: &SignedMessageInfo{}}
+
+	var err error
+	sm.infoBytes, err = hex.DecodeString(headerHex.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed decoding signed message header: %w", err)
+	}
+
+	err = msgpack.Unmarshal(sm.infoBytes, sm.Info)
+	if err != nil {
+		return nil, fmt.Errorf("failed transforming signed message header: %w", err)
+	}
+
+	sm.signature, err = hex.DecodeString(signatureHex.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed decoding signature: %w", err)
+	}
+
+	switch sm.Info.PayloadEncoding {
+	case SignedPayloadEncodingText:
+		if !foundText {
+			return nil, errors.New("signed text payload not found")
+		}
+
+		sm.Payload = []byte(strings.Join(textLines, "\n"))
+	case SignedPayloadEncodingHex:
+		sm.Payload, err = hex.DecodeString(dataHex.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding signed payload: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown payload encoding: %d", int(sm.Info.PayloadEncoding))
+	}
+
+	return sm, nil
+}
+
+// Verify validates the message signature against the sender's signing public key
+func (sm *SignedMessage) Verify(senderKI *security.KeyInfo) error {
+	if senderKI == nil {
+		return errors.New("sender key is nil")
+	}
+
+	digest := newSignedMessageDigest(sm.infoBytes)
+	digest.Write(sm.Payload)
+
+	isValid, err := senderKI.Verify(digest.Sum(nil), sm.signature)
+	if err != nil || !isValid {
+		return ErrSignatureInvalid
+	}
+
+	return nil
+}
+
+func newSignedMessageDigest(infoBytes []byte) interface {
+	io.Writer
+	Sum(b []byte) []byte
+} {
+	digest := sha512.New()
+	digest.Write([]byte(signedMessageDomain))
+	digest.Write(IntToUint16Bytes(len(infoBytes)))
+	digest.Write(infoBytes)
+	return digest
+}
+
+// looksLikeText returns true if the sample is valid UTF-8 with no NUL bytes.  A rune may be cut off at the
+// end of the sample, so up to 3 trailing bytes are allowed to be incomplete.
+func looksLikeText(sample []byte) bool {
+	if bytes.IndexByte(sample, 0) != -1 {
+		return false
+	}
+
+	for i := 0; i < utf8.UTFMax && len(sample) > 0 && !utf8.Valid(sample); i++ {
+		sample = sample[:len(sample)-1]
+	}
+
+	return utf8.Valid(sample)
+}
+
+// armorWriter emits armored lines to w, retaining the first error encountered
+type armorWriter struct {
+	w            io.Writer
+	bytesWritten int
+	err          error
+}
+
+func (aw *armorWriter) writeLine(line string) {
+	if aw.err != nil {
+		return
+	}
+
+	n, err := io.WriteString(aw.w, line+"\n")
+	aw.bytesWritten += n
+	aw.err = err
+}
+
+func (aw *armorWriter) writeMarkerLine(marker string) {
+	aw.writeLine(marker + " " + strings.Repeat("=", signedMarkerWidth-(len(marker)+1)))
+}
+
+func (aw *armorWriter) writeHexLines(data []byte) {
+	for len(data) > 0 {
+		lineLen := signedLineWidth
+		if len(data) < lineLen {
+			lineLen = len(data)
+		}
+
+		aw.writeLine(hex.EncodeToString(data[:lineLen]))
+		data = data[lineLen:]
+	}
+}
+
+// armorTextPayloadWriter emits the payload as text lines.  Lines that could be mistaken for markers or that
+// already begin with the escape sequence are prefixed with the escape sequence.  The final line is always
+// emitted, even when empty, so that the payload can be rebuilt exactly by joining the lines with "\n".
+type armorTextPayloadWriter struct {
+	aw          *armorWriter
+	partialLine []byte
+}
+
+func (tpw *armorTextPayloadWriter) Write(p []byte) (int, error) {
+	data := append(tpw.partialLine, p...)
+	for {
+		index := bytes.IndexByte(data, '\n')
+		if index == -1 {
+			break
+		}
+
+		tpw.writeTextLine(string(data[:index]))
+		data = data[index+1:]
+	}
+
+	tpw.partialLine = bytes.Clone(data)
+	return len(p), tpw.aw.err
+}
+
+func (tpw *armorTextPayloadWriter) writeTextLine(line string) {
+	if strings.HasPrefix(line, ":") || strings.HasPrefix(line, signedTextEscape) {
+		line = signedTextEscape + line
+	}
+
+	tpw.aw.writeLine(line)
+}
+
+func (tpw *armorTextPayloadWriter) flush() {
+	tpw.writeTextLine(string(tpw.partialLine))
+	tpw.partialLine = nil
+}
+
+// armorHexPayloadWriter emits the payload as hex lines
+type armorHexPayloadWriter struct {
+	aw          *armorWriter
+	partialLine []byte
+}
+
+func (hpw *armorHexPayloadWriter) Write(p []byte) (int, error) {
+	data := append(hpw.partialLine, p...)
+	for len(data) >= signedLineWidth {
+		hpw.aw.writeHexLines(data[:signedLineWidth])
+		data = data[signedLineWidth:]
+	}
+
+	hpw.partialLine = bytes.Clone(data)
+	return len(p), hpw.aw.err
+}
+
+func (hpw *armorHexPayloadWriter) flush() {
+	hpw.aw.writeHexLines(hpw.partialLine)
+	hpw.partialLine = nil
+}
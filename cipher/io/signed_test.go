@@ -0,0 +1,130 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/security"
+	"strings"
+	"testing"
+)
+
+func newSignedTestKeys(t *testing.T) (*security.KeyPairInfo, *security.KeyInfo) {
+	senderKPI, err := security.NewKeyPairInfoWithSeeds("senderKPI")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	senderCipherPublicKey, senderSigningPublicKey, err := senderKPI.PublicKeys()
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	senderKI, _ := security.NewKeyInfo("senderKI", senderCipherPublicKey, senderSigningPublicKey)
+	return senderKPI, senderKI
+}
+
+func TestSignedWriter_RoundTrip(t *testing.T) {
+	binaryPayload, err := helpers.GetRandomBytes(100)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	type test struct {
+		name             string
+		payload          []byte
+		expectedEncoding SignedPayloadEncoding
+	}
+
+	tests := []test{
+		{name: "Text", payload: werner_bytes, expectedEncoding: SignedPayloadEncodingText},
+		{name: "Text with trailing newline", payload: []byte("line one\nline two\n"), expectedEncoding: SignedPayloadEncodingText},
+		{name: "Text with markers", payload: []byte(":end ====\n- dashed\n:start :signature\n"), expectedEncoding: SignedPayloadEncodingText},
+		{name: "Empty", payload: []byte{}, expectedEncoding: SignedPayloadEncodingText},
+		{name: "Binary", payload: append([]byte{0}, binaryPayload...), expectedEncoding: SignedPayloadEncodingHex},
+	}
+
+	senderKPI, senderKI := newSignedTestKeys(t)
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sw, err := NewSignedWriter(senderKPI)
+			if !assert.Nil(t, err) {
+				return
+			}
+
+			armorBuff := bytes.NewBuffer(nil)
+			_, err = sw.WriteSignedTextFromReader(bytes.NewReader(tc.payload), armorBuff)
+			if !assert.Nil(t, err) {
+				return
+			}
+
+			armorBytes := armorBuff.Bytes()
+			assert.True(t, IsSignedMessage(armorBytes))
+
+			sm, err := ParseSignedMessage(armorBytes)
+			if !assert.Nil(t, err) {
+				return
+			}
+
+			assert.Equal(t, tc.expectedEncoding, sm.Info.PayloadEncoding)
+			assert.Equal(t, "senderKPI", sm.Info.FromName)
+			assert.Equal(t, tc.payload, sm.Payload)
+			assert.Nil(t, sm.Verify(senderKI))
+
+			// Clipboards may convert line endings, which must not break verification
+			crlfBytes := []byte(strings.ReplaceAll(string(armorBytes), "\n", "\r\n"))
+			sm, err = ParseSignedMessage(crlfBytes)
+			if !assert.Nil(t, err) {
+				return
+			}
+
+			assert.Equal(t, tc.payload, sm.Payload)
+			assert.Nil(t, sm.Verify(senderKI))
+		})
+	}
+}
+
+func TestSignedMessage_VerifyRejectsTampering(t *testing.T) {
+	senderKPI, senderKI := newSignedTestKeys(t)
+	_, otherKI := newSignedTestKeys(t)
+
+	sw, err := NewSignedWriter(senderKPI)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	armorBuff := bytes.NewBuffer(nil)
+	_, err = sw.WriteSignedTextFromReader(bytes.NewReader(werner_bytes), armorBuff)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	sm, err := ParseSignedMessage(armorBuff.Bytes())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, ErrSignatureInvalid, sm.Verify(otherKI))
+
+	tamperedBytes := []byte(strings.Replace(armorBuff.String(), "Werner", "Warner", 1))
+	sm, err = ParseSignedMessage(tamperedBytes)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, ErrSignatureInvalid, sm.Verify(senderKI))
+}
@@ -26,6 +26,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 type CipherFileWriterIntf interface {
@@ -33,6 +34,7 @@ type CipherFileWriterIntf interface {
 
 type CipherWriter struct {
 	ReceiverCipherPublicKey string
+	SelfCipherPublicKey     string
 	SenderCipherKeyPair     nkeys.KeyPair
 	SenderSigningKeyPair    nkeys.KeyPair
 	CombinedFilePath        string
@@ -77,7 +79,7 @@ func NewCipherWriter(receiverKI *security.KeyInfo, senderKPI *security.KeyPairIn
 	return &CipherWriter{
 		SenderCipherKeyPair:     SenderCipherKeyPair,
 		SenderSigningKeyPair:    SenderSigningKP,
-		ReceiverCipherPublicKey: receiverKI.CipherPubKey,
+		ReceiverCipherPublicKey: receiverKI.CurrentCipherPubKey(time.Now()),
 		OutputBundleInfo:        bundleInfo,
 	}, nil
 }
@@ -132,14 +134,18 @@ func (cfw *CipherWriter) WriteToSplitFilesFromReader(combinedFilePath string, r
 		_ = bdataFile.Close()
 	}()
 
-	headerBytesWritten, err := cfw.WriteBundleHeader(bhdrFile)
+	// The data is written first, so that the payload commitment can be recorded in the header.  This binds
+	// the header to this specific data file.
+	dataBytesWritten, err := cfw.WriteBundleData(r, bdataFile)
 	if err != nil {
-		return headerBytesWritten, fmt.Errorf("unable to write bundle header: %w", err)
+		return dataBytesWritten, fmt.Errorf("unable to write bundle data: %w", err)
 	}
 
-	dataBytesWritten, err := cfw.WriteBundleData(r, bdataFile)
+	cfw.setPayloadCommitment()
+
+	headerBytesWritten, err := cfw.WriteBundleHeader(bhdrFile)
 	if err != nil {
-		return dataBytesWritten, fmt.Errorf("unable to write bundle data: %w", err)
+		return headerBytesWritten, fmt.Errorf("unable to write bundle header: %w", err)
 	}
 
 	return headerBytesWritten + dataBytesWritten, nil
@@ -165,7 +171,18 @@ func (cfw *CipherWriter) WriteToCombinedStreamFromReader(r io.Reader, w io.Write
 	return headerBytesWritten + dataBytesWritten, nil
 }
 
+// WriteToSplitStreamsFromReader encrypts the payload to a buffer first, so that the payload commitment can be
+// recorded in the header before the header is emitted.  Split streams are used for console and clipboard
+// output, so the payload is expected to be reasonably small.
 func (cfw *CipherWriter) WriteToSplitStreamsFromReader(r io.Reader, wHdr io.Writer, wData io.Writer, completeFuncHdr, completeFuncData StreamCompleteFunc) (int, error) {
+	dataBuff := bytes.NewBuffer(nil)
+	_, err := cfw.WriteBundleData(r, dataBuff)
+	if err != nil {
+		return 0, fmt.Errorf("unable to write bundle data: %w", err)
+	}
+
+	cfw.setPayloadCommitment()
+
 	headerBytesWritten, err := cfw.WriteBundleHeader(wHdr)
 	if err != nil {
 		return headerBytesWritten, fmt.Errorf("unable to write bundle header: %w", err)
@@ -178,7 +195,7 @@ func (cfw *CipherWriter) WriteToSplitStreamsFromReader(r io.Reader, wHdr io.Writ
 		}
 	}
 
-	dataBytesWritten, err := cfw.WriteBundleData(r, wData)
+	dataBytesWritten, err := WriteBytesToWriter(dataBuff.Bytes(), wData)
 	if err != nil {
 		return headerBytesWritten + dataBytesWritten, fmt.Errorf("unable to write bundle data: %w", err)
 	}
@@ -190,14 +207,52 @@ func (cfw *CipherWriter) WriteToSplitStreamsFromReader(r io.Reader, wHdr io.Writ
 	return headerBytesWritten + dataBytesWritten, nil
 }
 
-func (cfw *CipherWriter) WriteBundleHeader(writer io.Writer) (int, error) {
+// initSymmetricCipher creates the payload cipher if needed.  The payload may be written before the header
+// for split bundles, so both WriteBundleHeader and WriteBundleData depend on this.
+func (cfw *CipherWriter) initSymmetricCipher() error {
+	if cfw.SymmetricCipher != nil {
+		return nil
+	}
+
 	var err error
 	cfw.SymmetricCipher, err = beecipher.NewSymmetricCipher(cfw.OutputBundleInfo.SymmetricKey, DEFAULT_CHUNK_SIZE)
 	if err != nil {
-		return 0, fmt.Errorf("failed generating symmetric sc: %s", err)
+		return fmt.Errorf("failed generating symmetric sc: %s", err)
 	}
 
+	cfw.SymmetricCipher.SetAssociatedData(cfw.OutputBundleInfo.BundleID)
 	cfw.OutputBundleInfo.Salt = cfw.SymmetricCipher.GetSalt()
+	cfw.OutputBundleInfo.KeyCommitment, err = cfw.SymmetricCipher.GetKeyCommitment()
+	if err != nil {
+		return fmt.Errorf("failed generating key commitment: %w", err)
+	}
+
+	return nil
+}
+
+// setPayloadCommitment records the chunk count and running hash of the written payload in the header
+func (cfw *CipherWriter) setPayloadCommitment() {
+	cfw.OutputBundleInfo.PayloadChunkCount = cfw.SymmetricCipher.GetChunkCount()
+	cfw.OutputBundleInfo.PayloadHash = cfw.SymmetricCipher.GetPayloadHash()
+}
+
+// SetSelfRecipient requests that the header also be wrapped to selfKI, which should be one of the sender's
+// own keypairs.  This allows the sender to reopen the bundle later via the self slot.
+func (cfw *CipherWriter) SetSelfRecipient(selfKI *security.KeyInfo) error {
+	if selfKI == nil {
+		return errors.New("self key is nil")
+	}
+
+	cfw.SelfCipherPublicKey = selfKI.CipherPubKey
+	cfw.OutputBundleInfo.SelfSlot = true
+	return nil
+}
+
+func (cfw *CipherWriter) WriteBundleHeader(writer io.Writer) (int, error) {
+	err := cfw.initSymmetricCipher()
+	if err != nil {
+		return 0, err
+	}
 
 	bundleBytes, err := msgpack.Marshal(cfw.OutputBundleInfo)
 	if err != nil {
@@ -212,9 +267,29 @@ func (cfw *CipherWriter) WriteBundleHeader(writer io.Writer) (int, error) {
 	}
 	defer security.Wipe(senderCipherSeed)
 
+	totalBytesWritten, err := writeBundleHeaderSlot(bundleBytes, cfw.ReceiverCipherPublicKey, senderCipherSeed, writer)
+	if err != nil {
+		return totalBytesWritten, err
+	}
+
+	if !cfw.OutputBundleInfo.SelfSlot {
+		return totalBytesWritten, nil
+	}
+
+	// The self slot is an identical copy of the header, wrapped to the sender's own keypair
+	selfBytesWritten, err := writeBundleHeaderSlot(bundleBytes, cfw.SelfCipherPublicKey, senderCipherSeed, writer)
+	if err != nil {
+		return totalBytesWritten + selfBytesWritten, fmt.Errorf("failed writing self slot: %w", err)
+	}
+
+	return totalBytesWritten + selfBytesWritten, nil
+}
+
+// writeBundleHeaderSlot encrypts the serialized bundle header for the recipient and writes it with its length marker
+func writeBundleHeaderSlot(bundleBytes []byte, recipientCipherPublicKey string, senderCipherSeed []byte, writer io.Writer) (int, error) {
 	bundleWriterBuff := bytes.NewBuffer(nil)
 	bundleReaderBuff := bytes.NewBuffer(bundleBytes)
-	nc, err := beecipher.NewNKeysCipherEncrypter(cfw.ReceiverCipherPublicKey, senderCipherSeed)
+	nc, err := beecipher.NewNKeysCipherEncrypter(recipientCipherPublicKey, senderCipherSeed)
 	if err != nil {
 		return 0, fmt.Errorf("failed creating new nkeys sc encrypter: %s", err)
 	}
@@ -258,10 +333,27 @@ func (cfw *CipherWriter) WriteBundleHeader(writer io.Writer) (int, error) {
 }
 
 func (cfw *CipherWriter) WriteBundleData(r io.Reader, w io.Writer) (int, error) {
+	err := cfw.initSymmetricCipher()
+	if err != nil {
+		return 0, err
+	}
+
 	// encode the data stream to the file
 	return cfw.SymmetricCipher.Encrypt(r, w)
 }
 
+func WriteBytesToWriter(data []byte, w io.Writer) (int, error) {
+	n, err := w.Write(data)
+	if err != nil {
+		return n, fmt.Errorf("error writing bytes data: %s", err)
+	}
+	if n != len(data) {
+		return n, fmt.Errorf("error writing bytes data: write count wrong: wrote %d bytes, expectd %d", n, len(data))
+	}
+
+	return n, nil
+}
+
 type LenMarkerSize int
 
 const (
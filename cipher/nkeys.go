@@ -91,6 +91,25 @@ func (nk *NKeysCipher) GetSalt() []byte {
 	return nil
 }
 
+func (nk *NKeysCipher) GetChunkCount() int {
+	return 0
+}
+
+// GetKeyCommitment returns nil for NKeysCipher.  Key commitment only applies to symmetric ciphers.
+func (nk *NKeysCipher) GetKeyCommitment() ([]byte, error) {
+	return nil, nil
+}
+
+// SetKeyCommitment is a no-op for NKeysCipher
+func (nk *NKeysCipher) SetKeyCommitment(tag []byte) {}
+
+func (nk *NKeysCipher) GetPayloadHash() []byte {
+	return nil
+}
+
+// SetAssociatedData is a no-op for NKeysCipher, which does not support associated data.
+func (nk *NKeysCipher) SetAssociatedData(ad []byte) {}
+
 func (nk *NKeysCipher) Decrypt(r io.Reader, w io.Writer) (int, error) {
 	// First, read in the bytes to decrypt
 	var encryptedBytes []byte
@@ -0,0 +1,68 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keystore"
+)
+
+type addGroupCommandVals struct {
+	members []string
+}
+
+var localAddGroupCommandVals = &addGroupCommandVals{}
+
+// addGroupCmd represents the add group command
+var addGroupCmd = &cobra.Command{
+	Use:   "group <name> [--members user1,user2]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Adds a named group of users to the keystore",
+	Long: `Adds a named group of users to the keystore.  Members must already exist in the keystore.
+A group can be used as a bundle recipient with "bundle --to @<name>", which creates one bundle per member.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		addGroup(keystore.GroupNameFromReference(args[0]))
+	},
+}
+
+func init() {
+	addCmd.AddCommand(addGroupCmd)
+	addGroupCmd.Flags().StringSliceVarP(&localAddGroupCommandVals.members, "members", "m", nil, "The users to add to the group. May be repeated or comma separated.")
+}
+
+func addGroup(groupName string) {
+	if keystore.GlobalKeyStore == nil {
+		fmt.Println("Unable to add group: keystore not loaded")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	err := keystore.GlobalKeyStore.AddGroup(groupName, localAddGroupCommandVals.members)
+	if err != nil {
+		fmt.Printf("Unable to add group: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	fmt.Printf("Group \"%s\" added with %d member(s) and keystore file changes committed.\n",
+		groupName, len(keystore.GlobalKeyStore.GetGroup(groupName).Members))
+}
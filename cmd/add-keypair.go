@@ -18,16 +18,26 @@ import (
 	"fmt"
 	"github.com/thoughtrealm/bumblebee/helpers"
 	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/logger"
+	"github.com/thoughtrealm/bumblebee/security"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 // addKeypairCmd represents the keypair command
 var addKeypairCmd = &cobra.Command{
-	Use:   "keypair <name>",
+	Use:   "keypair <name> [--mnemonic | --recover]",
 	Args:  cobra.MaximumNArgs(1),
 	Short: "Adds a new keypair identity",
-	Long:  "Adds a new keypair identity",
+	Long: `Adds a new keypair identity.
+
+With --mnemonic, the keypair is derived from a new 24 word recovery phrase, which is displayed once.  An optional
+passphrase can be added to the phrase.  Write down the phrase and keep it safe.  If the keypair store is lost, the
+same phrase and passphrase will recreate the keypair with --recover, so bundles sent to it can still be opened.
+
+With --recover, you are prompted for the recovery phrase and passphrase, and the keypair is recreated from them.
+Phrases are checked against the BIP-39 word list and checksum.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		err := startBootStrap(false, true)
 		if err != nil {
@@ -40,13 +50,31 @@ var addKeypairCmd = &cobra.Command{
 			return
 		}
 
-		addNewKeyPair(args[0])
+		switch {
+		case localAddKeypairCommandVals.mnemonic && localAddKeypairCommandVals.recover:
+			logger.Errorln("Only one of --mnemonic or --recover can be provided")
+			helpers.ExitCode = helpers.ExitCodeInvalidInput
+		case localAddKeypairCommandVals.mnemonic:
+			addNewMnemonicKeyPair(args[0])
+		case localAddKeypairCommandVals.recover:
+			recoverMnemonicKeyPair(args[0])
+		default:
+			addNewKeyPair(args[0])
+		}
 	},
 }
 
+type addKeypairCommandVals struct {
+	mnemonic bool
+	recover  bool
+}
+
+var localAddKeypairCommandVals = &addKeypairCommandVals{}
+
 func init() {
 	addCmd.AddCommand(addKeypairCmd)
-
+	addKeypairCmd.Flags().BoolVarP(&localAddKeypairCommandVals.mnemonic, "mnemonic", "", false, "Derives the keypair from a new recovery phrase, which is displayed for you to write down")
+	addKeypairCmd.Flags().BoolVarP(&localAddKeypairCommandVals.recover, "recover", "", false, "Recreates a keypair from its recovery phrase and passphrase")
 }
 
 func addNewKeyPair(keypairName string) {
@@ -95,3 +123,131 @@ func addNewKeyPair(keypairName string) {
 
 	fmt.Println("New keypair stored to file")
 }
+
+// addNewMnemonicKeyPair creates a new recovery phrase, displays it, and adds the keypair derived from it
+func addNewMnemonicKeyPair(keypairName string) {
+	if keypairs.GlobalKeyPairStore.GetKeyPairInfo(keypairName) != nil {
+		logger.Errorfln("A keypair already exists by the name \"%s\"", keypairName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	mnemonic, err := security.NewMnemonic()
+	if err != nil {
+		logger.Errorfln("Unable to create recovery phrase: %s", err)
+		helpers.ExitCode = helpers.ExitCodeCipherError
+		return
+	}
+
+	logger.Println("You can add an optional passphrase to the recovery phrase.  The passphrase is required to recover")
+	logger.Println("the keypair, and is not displayed with the phrase, so it must be remembered or stored separately.")
+	logger.Println("")
+	logger.Printf("Enter an optional passphrase or leave empty for no passphrase: ")
+
+	passphraseBytes, err := helpers.GetPasswordWithConfirm("passphrase")
+	logger.Println("")
+	if err != nil {
+		logger.Errorfln("Failed trying to obtain the passphrase: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+	defer security.Wipe(passphraseBytes)
+
+	kpi, err := security.NewKeyPairInfoFromMnemonic(keypairName, mnemonic, string(passphraseBytes))
+	if err != nil {
+		logger.Errorfln("Unable to derive keypair from recovery phrase: %s", err)
+		helpers.ExitCode = helpers.ExitCodeCipherError
+		return
+	}
+	defer kpi.Wipe()
+
+	if !saveMnemonicKeyPair(kpi) {
+		return
+	}
+
+	logger.Println("")
+	logger.Println("Recovery phrase.  Write it down in order and keep it safe.  Anyone with the phrase and passphrase")
+	logger.Println("has the private keys for this keypair.  It will not be displayed again.")
+	logger.Println("")
+	for idx, word := range strings.Fields(mnemonic) {
+		logger.Printf("%2d. %-10s", idx+1, word)
+		if (idx+1)%4 == 0 {
+			logger.Println("")
+		}
+	}
+	logger.Println("")
+}
+
+// recoverMnemonicKeyPair recreates a keypair from its recovery phrase and passphrase
+func recoverMnemonicKeyPair(keypairName string) {
+	if keypairs.GlobalKeyPairStore.GetKeyPairInfo(keypairName) != nil {
+		logger.Errorfln("A keypair already exists by the name \"%s\"", keypairName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	mnemonic, err := helpers.GetConsoleRequiredInputLine("Enter the recovery phrase, with the words separated by spaces", "Recovery phrase")
+	logger.Println("")
+	if err != nil {
+		logger.Errorfln("Unable to get input for the recovery phrase: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	_, err = security.MnemonicEntropy(mnemonic)
+	if err != nil {
+		logger.Errorfln("Unable to recover keypair: %s", err)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	logger.Printf("Enter the passphrase, or leave empty if the phrase has no passphrase: ")
+	passphraseBytes, err := helpers.GetPassword()
+	logger.Println("")
+	if err != nil {
+		logger.Errorfln("Failed trying to obtain the passphrase: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+	defer security.Wipe(passphraseBytes)
+
+	kpi, err := security.NewKeyPairInfoFromMnemonic(keypairName, mnemonic, string(passphraseBytes))
+	if err != nil {
+		logger.Errorfln("Unable to recover keypair: %s", err)
+		helpers.ExitCode = helpers.ExitCodeCipherError
+		return
+	}
+	defer kpi.Wipe()
+
+	// A different passphrase derives a different keypair without any error, so the keys are displayed for checking
+	logger.Println("Check that the public keys below match the keypair you are recovering.  If they do not, the")
+	logger.Println("passphrase may be wrong.")
+	logger.Println("")
+	saveMnemonicKeyPair(kpi)
+}
+
+func saveMnemonicKeyPair(kpi *security.KeyPairInfo) (saved bool) {
+	err := keypairs.GlobalKeyPairStore.ImportKeyPair(kpi)
+	if err != nil {
+		logger.Errorfln("Unable to add keypair: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return false
+	}
+
+	err = keypairs.GlobalKeyPairStore.SaveKeyPairStoreToOrigin(nil)
+	if err != nil {
+		logger.Errorfln("Unable to save keypair info: %s", err)
+		logger.Errorln("New keypair was not saved to file")
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return false
+	}
+
+	err = kpi.Print("New Key Pair Info", false)
+	if err != nil {
+		logger.Errorfln("Unable to print keypair info: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+	}
+
+	logger.Printfln("Keypair \"%s\" stored to file", kpi.Name)
+	return true
+}
@@ -0,0 +1,139 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/logger"
+	"github.com/thoughtrealm/bumblebee/security"
+	"strings"
+	"time"
+)
+
+type addSubkeyCommandVals struct {
+	validDays int
+}
+
+var localAddSubkeyCommandVals = &addSubkeyCommandVals{}
+
+// addSubkeyCmd represents the add subkey command
+var addSubkeyCmd = &cobra.Command{
+	Use:   "subkey <keypair name>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Adds a new expiring encryption subkey to a keypair",
+	Long: `Adds a new encryption subkey to a keypair. The subkey is certified by the keypair's signing key,
+which remains your long-term identity. Once you export your keypair as a user again, contacts that
+import it will accept the new subkey without any prompts and will encrypt to the newest valid subkey.
+Expired subkeys are still tried for a while when opening bundles.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		addSubkey(args[0])
+	},
+}
+
+func init() {
+	addCmd.AddCommand(addSubkeyCmd)
+	addSubkeyCmd.Flags().IntVarP(&localAddSubkeyCommandVals.validDays, "valid-days", "", int(security.SubkeyDefaultValidity/(24*time.Hour)), "The number of days the new subkey is valid for")
+}
+
+func addSubkey(keypairName string) {
+	if strings.EqualFold(keypairName, helpers.KeyPairNameForKeyStoreReads) ||
+		strings.EqualFold(keypairName, helpers.KeyPairNameForKeyStoreWrites) {
+		logger.Errorfln("Adding subkeys to the system keypair \"%s\" is not allowed.", keypairName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	if localAddSubkeyCommandVals.validDays <= 0 {
+		logger.Errorln("The value for --valid-days must be greater than zero.")
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	if keypairs.GlobalKeyPairStore == nil {
+		logger.Errorln("Unable to add subkey: keypair store not loaded")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	validFor := time.Duration(localAddSubkeyCommandVals.validDays) * 24 * time.Hour
+	subkey, err := keypairs.GlobalKeyPairStore.AddSubkey(keypairName, validFor)
+	if err != nil {
+		logger.Errorfln("Unable to add subkey: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+	defer security.Wipe(subkey.CipherSeed)
+
+	err = keypairs.GlobalKeyPairStore.SaveKeyPairStoreToOrigin(nil)
+	if err != nil {
+		logger.Errorfln("Unable to add subkey: keypair store could not update the file: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	logger.Printfln("Subkey added to keypair \"%s\" and keypair store file changes committed.", keypairName)
+	logger.Printfln("Subkey Public Key : %s", subkey.Certificate.CipherPubKey)
+	logger.Printfln("Expires           : %s", subkey.Certificate.ExpiryDate)
+	logger.Println("")
+
+	updateLocalUsersWithSubkey(keypairName, subkey.Certificate)
+
+	logger.Printfln("Export your keypair with \"export user --from-keypair %s\" and send it to your contacts.", keypairName)
+}
+
+// updateLocalUsersWithSubkey adds the subkey to any local users with the keypair's identity, like an entry for yourself
+func updateLocalUsersWithSubkey(keypairName string, certificate *security.SubkeyCertificate) {
+	if keystore.GlobalKeyStore == nil {
+		return
+	}
+
+	kpi := keypairs.GlobalKeyPairStore.GetKeyPairInfo(keypairName)
+	if kpi == nil {
+		return
+	}
+	defer kpi.Wipe()
+
+	cipherPubKey, signingPubKey, err := kpi.PublicKeys()
+	if err != nil {
+		return
+	}
+
+	var userNames []string
+	walkInfo := keystore.NewWalkInfo("", true, nil, func(entity *security.Entity) {
+		if entity.PublicKeys.SigningPubKey == signingPubKey && entity.PublicKeys.CipherPubKey == cipherPubKey {
+			userNames = append(userNames, entity.Name)
+		}
+	})
+	_ = keystore.GlobalKeyStore.Walk(walkInfo)
+
+	for _, userName := range userNames {
+		_, _, err = keystore.GlobalKeyStore.UpdateSubkeys(userName, []*security.SubkeyCertificate{certificate})
+		if err != nil {
+			logger.Errorfln("Unable to update local user \"%s\" with the new subkey: %s", userName, err)
+			continue
+		}
+
+		logger.Printfln("Local user \"%s\" updated with the new subkey.", userName)
+		logger.Println("")
+	}
+}
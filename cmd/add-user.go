@@ -17,12 +17,22 @@ import (
 	"errors"
 	"fmt"
 	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
 	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/security"
+	"os"
+	"strings"
 )
 
 type addUserSubcommandVals struct {
 	cipherPublicKey  string
 	signingPublicKey string
+	sshKey           string
+	displayName      string
+	emails           []string
+	organization     string
+	notes            string
+	tags             []string
 }
 
 var localAddUserSubcommandVals = &addUserSubcommandVals{}
@@ -30,10 +40,13 @@ var localAddUserSubcommandVals = &addUserSubcommandVals{}
 // addUserCmd represents the key sub-command for the add command.
 // We maintain the public keys for external users.
 var addUserCmd = &cobra.Command{
-	Use:   "user <userName> --cipher <key> --signing <key>",
+	Use:   "user <userName> --cipher <key> --signing <key> | --ssh-key <file|line>",
 	Args:  cobra.ExactArgs(1),
 	Short: "Adds a new user using the provided cipher and signing public keys",
-	Long:  "Adds a new user using the provided cipher and signing public keys",
+	Long: `Adds a new user using the provided cipher and signing public keys.
+
+Alternatively, --ssh-key adds the user from an ssh-ed25519 public key, provided as a .pub file or as the key
+line itself.  The ed25519 key becomes the signing key, and the matching X25519 key is derived for the cipher key.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		err := startBootStrap(true, true)
 		if err != nil {
@@ -41,6 +54,17 @@ var addUserCmd = &cobra.Command{
 			return
 		}
 
+		if localAddUserSubcommandVals.sshKey != "" {
+			if localAddUserSubcommandVals.cipherPublicKey != "" || localAddUserSubcommandVals.signingPublicKey != "" {
+				fmt.Println("The --ssh-key flag can not be used with the --cipher and --signing flags.")
+				helpers.ExitCode = helpers.ExitCodeInvalidInput
+				return
+			}
+
+			addNewKey(args[0])
+			return
+		}
+
 		// Exact args are set by Args property above. Only need to check for cipher and signing keys
 		if localAddUserSubcommandVals.cipherPublicKey == "" {
 			fmt.Println("No cipher public key provided.  Cipher public key is required.")
@@ -60,19 +84,92 @@ func init() {
 	addCmd.AddCommand(addUserCmd)
 	addUserCmd.Flags().StringVarP(&localAddUserSubcommandVals.cipherPublicKey, "cipher", "c", "", "The value for the public cipher key")
 	addUserCmd.Flags().StringVarP(&localAddUserSubcommandVals.signingPublicKey, "signing", "s", "", "The value for the public signing key")
+	addUserCmd.Flags().StringVarP(&localAddUserSubcommandVals.sshKey, "ssh-key", "", "", "An ssh-ed25519 public key file, or the public key line, to derive the user's keys from")
+	addUserCmd.Flags().StringVarP(&localAddUserSubcommandVals.displayName, "display-name", "", "", "An optional display name for the user")
+	addUserCmd.Flags().StringSliceVarP(&localAddUserSubcommandVals.emails, "email", "e", nil, "An optional email address for the user. May be repeated or comma separated.")
+	addUserCmd.Flags().StringVarP(&localAddUserSubcommandVals.organization, "org", "", "", "An optional organization for the user")
+	addUserCmd.Flags().StringVarP(&localAddUserSubcommandVals.notes, "notes", "", "", "Optional free-form notes for the user")
+	addUserCmd.Flags().StringSliceVarP(&localAddUserSubcommandVals.tags, "tag", "", nil, "An optional tag for the user. May be repeated or comma separated.")
 }
 
 func addNewKey(userName string) {
 	var err error
+	var newKeyInfo *security.KeyInfo
+	keySource := security.KeySourceManual
+	keySourceDetails := ""
+
+	if localAddUserSubcommandVals.sshKey != "" {
+		var sshPublicKey *security.SSHPublicKey
+		sshPublicKey, err = readAddUserSSHKey(localAddUserSubcommandVals.sshKey)
+		if err == nil {
+			newKeyInfo, err = sshPublicKey.KeyInfo(userName)
+			keySource = security.KeySourceSSH
+			keySourceDetails = sshPublicKey.SourceDetails()
+		}
+	} else {
+		newKeyInfo, err = security.NewKeyInfo(
+			userName,
+			localAddUserSubcommandVals.cipherPublicKey,
+			localAddUserSubcommandVals.signingPublicKey,
+		)
+	}
+	if err != nil {
+		fmt.Printf("Unable to add new user: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	contact := &security.ContactInfo{
+		DisplayName:  localAddUserSubcommandVals.displayName,
+		Emails:       localAddUserSubcommandVals.emails,
+		Organization: localAddUserSubcommandVals.organization,
+		Notes:        localAddUserSubcommandVals.notes,
+	}
+	contact.AddTags(localAddUserSubcommandVals.tags)
+
+	newEntity := &security.Entity{
+		Name:             userName,
+		PublicKeys:       newKeyInfo,
+		KeySource:        keySource,
+		KeySourceDetails: keySourceDetails,
+	}
 
-	err = keystore.GlobalKeyStore.AddKey(
-		userName,
-		localAddUserSubcommandVals.cipherPublicKey,
-		localAddUserSubcommandVals.signingPublicKey,
-	)
+	if !contact.IsEmpty() {
+		newEntity.Contact = contact
+	}
+
+	err = keystore.GlobalKeyStore.AddKeyWithDetails(newEntity)
 	if err != nil {
 		fmt.Printf("Unable to add new user: %v\n", errors.Unwrap(err))
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
 	}
 
 	fmt.Println("New user stored to file")
 }
+
+// readAddUserSSHKey parses the --ssh-key value, which is either a public key line or a file holding one key
+func readAddUserSSHKey(sshKey string) (*security.SSHPublicKey, error) {
+	if strings.Contains(sshKey, security.SSHKeyTypeEd25519+" ") {
+		return security.ParseSSHPublicKey(sshKey)
+	}
+
+	fileBytes, err := os.ReadFile(sshKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read ssh key file: %w", err)
+	}
+
+	var keyLines []string
+	for _, line := range strings.Split(string(fileBytes), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			keyLines = append(keyLines, line)
+		}
+	}
+
+	if len(keyLines) != 1 {
+		return nil, fmt.Errorf("the ssh key file holds %d keys, use \"import --format authorized_keys\" for files with more than one key", len(keyLines))
+	}
+
+	return security.ParseSSHPublicKey(keyLines[0])
+}
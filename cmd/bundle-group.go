@@ -0,0 +1,196 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	cipherio "github.com/thoughtrealm/bumblebee/cipher/io"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/security"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// groupBundleResult is the outcome of bundling to one member of a group
+type groupBundleResult struct {
+	memberName   string
+	outputFile   string
+	bytesWritten int
+	skipReason   string
+	err          error
+}
+
+// getGroupMembersForBundle returns the member entities of the group referenced by --to, such as "@support"
+func getGroupMembersForBundle() (members []*security.Entity, err error) {
+	if keystore.GlobalKeyStore == nil {
+		return nil, errors.New("keystore is not loaded")
+	}
+
+	groupName := keystore.GroupNameFromReference(localBundleCommandVals.toName)
+	group := keystore.GlobalKeyStore.GetGroup(groupName)
+	if group == nil {
+		return nil, fmt.Errorf("group not located for name \"%s\"", groupName)
+	}
+
+	if len(group.Members) == 0 {
+		return nil, fmt.Errorf("group \"%s\" has no members", group.Name)
+	}
+
+	for _, memberName := range group.Members {
+		entity := keystore.GlobalKeyStore.GetKey(memberName)
+		if entity == nil {
+			return nil, fmt.Errorf("member \"%s\" of group \"%s\" was not found in the keystore", memberName, group.Name)
+		}
+
+		members = append(members, entity)
+	}
+
+	return members, nil
+}
+
+// bundleToGroup writes a separate bundle for each group member.  The input is read once per member, so only file
+// and dirs input sources are supported.  Members that are revoked, or refused by the trust policy, are skipped.
+func bundleToGroup() (err error) {
+	if localBundleCommandVals.inputSource != keystore.InputSourceFile &&
+		localBundleCommandVals.inputSource != keystore.InputSourceDirs {
+		return errors.New("group bundles require an input source of file or dirs, since the input is read once per member")
+	}
+
+	var baseOutputFile string
+	switch localBundleCommandVals.outputTarget {
+	case keystore.OutputTargetFile:
+		baseOutputFile = localBundleCommandVals.outputFile
+	case keystore.OutputTargetPath:
+		_, inputFilename := filepath.Split(localBundleCommandVals.inputFilePath)
+		baseOutputFile = filepath.Join(localBundleCommandVals.outputPath, helpers.ReplaceFileExt(inputFilename, ".ext"))
+	default:
+		return errors.New("group bundles require an output target of file or path, since one output is written per member")
+	}
+
+	results := make([]*groupBundleResult, 0, len(localBundleSettings.groupMembers))
+	for _, member := range localBundleSettings.groupMembers {
+		result := &groupBundleResult{memberName: member.Name}
+		results = append(results, result)
+
+		if member.Trust == security.TrustLevelRevoked {
+			result.skipReason = fmt.Sprintf("revoked on %s", member.RevokedDate)
+			continue
+		}
+
+		trustErr := checkEntityTrust(member, "receiver")
+		if trustErr != nil {
+			result.skipReason = trustErr.Error()
+			continue
+		}
+
+		result.outputFile = getGroupMemberOutputFile(baseOutputFile, member.Name)
+		result.bytesWritten, result.err = writeGroupMemberBundle(member, result.outputFile)
+	}
+
+	return printGroupBundleReport(results)
+}
+
+// writeGroupMemberBundle writes one bundle to the member, using the sender keys and input settings of the request
+func writeGroupMemberBundle(member *security.Entity, outputFile string) (int, error) {
+	var err error
+	localBundleSettings.cipherWriter, err = cipherio.NewCipherWriter(member.PublicKeys, localBundleSettings.senderKPI)
+	if err != nil {
+		return 0, fmt.Errorf("unable to create cipher writer: %w", err)
+	}
+	defer localBundleSettings.cipherWriter.Wipe()
+
+	err = setSelfRecipientForBundle()
+	if err != nil {
+		return 0, fmt.Errorf("unable to add self slot: %w", err)
+	}
+
+	reader, err := getInputReader()
+	if err != nil {
+		return 0, fmt.Errorf("unable to initiate input stream: %w", err)
+	}
+
+	defer func() {
+		if localBundleSettings.inputFile != nil {
+			_ = localBundleSettings.inputFile.Close()
+			localBundleSettings.inputFile = nil
+		}
+	}()
+
+	localBundleCommandVals.outputFile = outputFile
+	localBundleSettings.totalBytesWritten = 0
+	err = writeToFile(reader)
+	return localBundleSettings.totalBytesWritten, err
+}
+
+// getGroupMemberOutputFile adds the member name to the output file name, such as "report.bob.bcomb"
+func getGroupMemberOutputFile(baseOutputFile, memberName string) string {
+	ext := filepath.Ext(baseOutputFile)
+	if localBundleCommandVals.bundleType == keystore.BundleTypeCombined &&
+		(ext == "" || strings.ToLower(ext) == ".ext") {
+		ext = ".bcomb"
+	}
+
+	safeName := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+
+		return '_'
+	}, memberName)
+
+	return strings.TrimSuffix(baseOutputFile, filepath.Ext(baseOutputFile)) + "." + safeName + ext
+}
+
+// printGroupBundleReport prints the outcome for each member.  An error is returned if no bundles were written.
+func printGroupBundleReport(results []*groupBundleResult) error {
+	writtenCount := 0
+	totalBytesWritten := 0
+
+	fmt.Println("")
+	fmt.Printf("Group Bundle Report for %s\n", localBundleCommandVals.toName)
+	fmt.Println("=========================================================")
+	for _, result := range results {
+		switch {
+		case result.skipReason != "":
+			fmt.Printf("%-18s : SKIPPED  %s\n", result.memberName, result.skipReason)
+		case result.err != nil:
+			fmt.Printf("%-18s : FAILED   %s\n", result.memberName, result.err)
+		default:
+			writtenCount++
+			totalBytesWritten += result.bytesWritten
+			outputFile := result.outputFile
+			if localBundleCommandVals.bundleType == keystore.BundleTypeSplit {
+				outputFile = helpers.ReplaceFileExt(outputFile, ".bhdr")
+			}
+
+			fmt.Printf("%-18s : WRITTEN  %s (%d bytes)\n", result.memberName, outputFile, result.bytesWritten)
+		}
+	}
+	fmt.Println("")
+
+	localBundleSettings.totalBytesWritten = totalBytesWritten
+	fmt.Printf("Bundles written for %d of %d member(s).\n", writtenCount, len(results))
+	if writtenCount < len(results) {
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+	}
+
+	if writtenCount == 0 {
+		return errors.New("no bundles were written for the group")
+	}
+
+	return nil
+}
@@ -46,6 +46,7 @@ var bundleCmd = &cobra.Command{
 			return
 		}
 
+		localBundleCommandVals.selfProvided = cmd.Flags().Changed("self")
 		bundleData()
 	},
 }
@@ -92,6 +93,19 @@ type bundleCommandVals struct {
 
 	// bundleType is transformed from bundleTypeText
 	bundleType keystore.BundleType
+
+	// signOnly emits the plaintext input with a signature trailer, instead of encrypting it
+	signOnly bool
+
+	// self also wraps the payload key to one of the sender's own keypairs, so the sender can reopen the bundle.
+	// If not provided, the profile's encryptToSelf setting is used.
+	self bool
+
+	// selfProvided is true if --self was explicitly provided, which overrides the profile setting
+	selfProvided bool
+
+	// selfKeypairName is the keypair to wrap the self slot to.  If empty, uses the profile setting or keystore_read.
+	selfKeypairName string
 }
 
 var localBundleCommandVals = &bundleCommandVals{}
@@ -101,8 +115,11 @@ type bundleSettings struct {
 	senderKPI         *security.KeyPairInfo
 	inputFile         *os.File
 	cipherWriter      *cipherio.CipherWriter
+	signedWriter      *cipherio.SignedWriter
 	totalBytesWritten int
 	mdsr              streams.StreamReader
+	// groupMembers are the receivers when --to refers to a group, such as "@support"
+	groupMembers []*security.Entity
 	// pipeBuffer        *bytes.Buffer
 }
 
@@ -110,7 +127,7 @@ var localBundleSettings = &bundleSettings{}
 
 func init() {
 	rootCmd.AddCommand(bundleCmd)
-	bundleCmd.Flags().StringVarP(&localBundleCommandVals.toName, "to", "t", "", "The name of the key to use for the receiver's key data.  Not necessary if using local-keys.")
+	bundleCmd.Flags().StringVarP(&localBundleCommandVals.toName, "to", "t", "", "The name of the key to use for the receiver's key data, or @group to write a bundle for each group member.  Not necessary if using local-keys.")
 	bundleCmd.Flags().StringVarP(&localBundleCommandVals.fromName, "from", "r", "", "The name of the keypair to use for the sender's key data.  If empty, uses the default keypair for the profile. Not necessary if using local-keys.")
 	bundleCmd.Flags().BoolVarP(&localBundleCommandVals.localKeys, "local-keys", "l", false, "If true, will use the local store keys to write the bundle data.")
 	bundleCmd.Flags().StringVarP(&localBundleCommandVals.inputSourceText, "input-source", "i", "", "The type of the input source.  Should be one of: console, clipboard, file or dirs.")
@@ -121,6 +138,9 @@ func init() {
 	bundleCmd.Flags().StringVarP(&localBundleCommandVals.outputFile, "output-file", "y", "", "The file name to use for output. Only relevant if output-target is FILE.")
 	bundleCmd.Flags().StringVarP(&localBundleCommandVals.outputPath, "output-path", "p", "", "The path name to use for output. Only relevant if output-target is PATH.")
 	bundleCmd.Flags().StringVarP(&localBundleCommandVals.bundleTypeText, "bundle-type", "b", "combined", "The type of bundle to build.  Should be one of: combined or split.")
+	bundleCmd.Flags().BoolVarP(&localBundleCommandVals.signOnly, "sign-only", "", false, "If true, emits the plaintext input with a signature trailer instead of encrypting it. The --to value is not used.")
+	bundleCmd.Flags().BoolVarP(&localBundleCommandVals.self, "self", "", false, "If true, also wraps the payload key to one of your own keypairs, so you can reopen the bundle later. Defaults to the profile's encryptToSelf setting.")
+	bundleCmd.Flags().StringVarP(&localBundleCommandVals.selfKeypairName, "self-keypair", "", "", "The name of the keypair to use for the self slot. If empty, uses the profile's selfKeypairName or keystore_read.")
 }
 
 func bundleData() {
@@ -155,7 +175,16 @@ func bundleData() {
 		localBundleCommandVals.inputSourceText = "piped"
 	}
 
-	localBundleSettings.receiverKI, localBundleSettings.senderKPI, err = getKeysForBundle()
+	if localBundleCommandVals.signOnly {
+		localBundleSettings.senderKPI, err = getSenderKeyPairForBundle()
+	} else if keystore.IsGroupReference(localBundleCommandVals.toName) && !localBundleCommandVals.localKeys {
+		localBundleSettings.groupMembers, err = getGroupMembersForBundle()
+		if err == nil {
+			localBundleSettings.senderKPI, err = getSenderKeyPairForBundle()
+		}
+	} else {
+		localBundleSettings.receiverKI, localBundleSettings.senderKPI, err = getKeysForBundle()
+	}
 	if err != nil {
 		fmt.Printf("Unable to acquire keys for bundle: %s\n", err)
 		// Todo: need to update exit code for all fails
@@ -186,6 +215,12 @@ func bundleData() {
 		}
 	}
 
+	if localBundleCommandVals.signOnly && localBundleCommandVals.inputSource == keystore.InputSourceDirs {
+		fmt.Println("Input source DIRS is not supported with --sign-only.")
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
 	if localBundleCommandVals.inputSource == keystore.InputSourceDirs {
 		err = validateInputDirs()
 		if err != nil {
@@ -264,15 +299,45 @@ func bundleData() {
 		}
 	}()
 
-	localBundleSettings.cipherWriter, err = cipherio.NewCipherWriter(
-		localBundleSettings.receiverKI,
-		localBundleSettings.senderKPI)
-	if err != nil {
-		fmt.Printf("Unable to create cipher writer: %s", err)
-		helpers.ExitCode = helpers.ExitCodeCipherError
+	if len(localBundleSettings.groupMembers) > 0 {
+		fmt.Printf("Starting BUNDLE request for group %s...\n", localBundleCommandVals.toName)
+		startTime := time.Now()
+		err = bundleToGroup()
+		if err != nil {
+			fmt.Printf("Unable to write group bundles: %s\n", err)
+			helpers.ExitCode = helpers.ExitCodeRequestFailed
+		}
+
+		totalTime = time.Now().Sub(startTime)
 		return
 	}
-	defer localBundleSettings.cipherWriter.Wipe()
+
+	if localBundleCommandVals.signOnly {
+		localBundleSettings.signedWriter, err = cipherio.NewSignedWriter(localBundleSettings.senderKPI)
+		if err != nil {
+			fmt.Printf("Unable to create signed writer: %s", err)
+			helpers.ExitCode = helpers.ExitCodeCipherError
+			return
+		}
+		defer localBundleSettings.signedWriter.Wipe()
+	} else {
+		localBundleSettings.cipherWriter, err = cipherio.NewCipherWriter(
+			localBundleSettings.receiverKI,
+			localBundleSettings.senderKPI)
+		if err != nil {
+			fmt.Printf("Unable to create cipher writer: %s", err)
+			helpers.ExitCode = helpers.ExitCodeCipherError
+			return
+		}
+		defer localBundleSettings.cipherWriter.Wipe()
+
+		err = setSelfRecipientForBundle()
+		if err != nil {
+			fmt.Printf("Unable to add self slot: %s\n", err)
+			helpers.ExitCode = helpers.ExitCodeInvalidInput
+			return
+		}
+	}
 
 	reader, err := getInputReader()
 	if err != nil {
@@ -284,6 +349,24 @@ func bundleData() {
 	fmt.Println("Starting BUNDLE request...")
 	startTime := time.Now()
 
+	switch {
+	case localBundleCommandVals.signOnly:
+		err = writeSigned(reader)
+	default:
+		err = writeBundle(reader)
+	}
+
+	if err != nil {
+		fmt.Printf("Unable to write to output stream: %s\n", err)
+	}
+
+	endTime := time.Now()
+	totalTime = endTime.Sub(startTime)
+
+	return
+}
+
+func writeBundle(reader io.Reader) (err error) {
 	switch localBundleCommandVals.outputTarget {
 	case keystore.OutputTargetConsole:
 		err = writeToConsole(reader)
@@ -298,14 +381,7 @@ func bundleData() {
 		fmt.Println("Unknown output target in output writer call")
 	}
 
-	if err != nil {
-		fmt.Printf("Unable to write to output stream: %s\n", err)
-	}
-
-	endTime := time.Now()
-	totalTime = endTime.Sub(startTime)
-
-	return
+	return err
 }
 
 func getKeysForBundle() (receiverKeyInfo *security.KeyInfo, senderKeyPairInfo *security.KeyPairInfo, err error) {
@@ -327,6 +403,36 @@ func getKeysForBundle() (receiverKeyInfo *security.KeyInfo, senderKeyPairInfo *s
 	}
 
 	// First, get the sender's keypair info
+	senderKeyPairInfo, err = getSenderKeyPairForBundle()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	receiverEntity := keystore.GlobalKeyStore.GetKey(localBundleCommandVals.toName)
+	if receiverEntity == nil {
+		return nil, nil, fmt.Errorf("receiver key not located for name \"%s\"", localBundleCommandVals.toName)
+	}
+
+	if receiverEntity.Trust == security.TrustLevelRevoked {
+		return nil, nil, fmt.Errorf("the receiver \"%s\" was revoked on %s.  Bundles cannot be sent to revoked users", receiverEntity.Name, receiverEntity.RevokedDate)
+	}
+
+	err = checkEntityTrust(receiverEntity, "receiver")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The returned Entity and encapsulated keys are cloned during the GetKey() call, so ok to own them
+	// here and just return them without cloning again.  Maybe a bit of an optimization and mem cost savings.
+	return receiverEntity.PublicKeys, senderKeyPairInfo, nil
+}
+
+// getSenderKeyPairForBundle returns the keypair named by --from, or the profile's default keypair
+func getSenderKeyPairForBundle() (senderKeyPairInfo *security.KeyPairInfo, err error) {
+	if keypairs.GlobalKeyPairStore == nil {
+		return nil, errors.New("keypair store is not loaded")
+	}
+
 	var useSenderName = "default"
 	if localBundleCommandVals.fromName != "" {
 		useSenderName = localBundleCommandVals.fromName
@@ -336,7 +442,7 @@ func getKeysForBundle() (receiverKeyInfo *security.KeyInfo, senderKeyPairInfo *s
 	// since we are passing it back to the caller.
 	senderKeyPairInfo = keypairs.GlobalKeyPairStore.GetKeyPairInfo(useSenderName)
 	if senderKeyPairInfo == nil {
-		return nil, nil, fmt.Errorf("Unable to locate sender's keypair for name \"%s\"\n", useSenderName)
+		return nil, fmt.Errorf("Unable to locate sender's keypair for name \"%s\"\n", useSenderName)
 	}
 
 	if strings.ToLower(senderKeyPairInfo.Name) == "default" {
@@ -350,14 +456,61 @@ func getKeysForBundle() (receiverKeyInfo *security.KeyInfo, senderKeyPairInfo *s
 		}
 	}
 
-	receiverEntity := keystore.GlobalKeyStore.GetKey(localBundleCommandVals.toName)
-	if receiverEntity == nil {
-		return nil, nil, fmt.Errorf("receiver key not located for name \"%s\"", localBundleCommandVals.toName)
+	return senderKeyPairInfo, nil
+}
+
+// setSelfRecipientForBundle adds a self slot to the bundle if requested by the --self flags or the profile settings
+func setSelfRecipientForBundle() error {
+	if localBundleCommandVals.localKeys {
+		// Local key bundles are already readable with keystore_read
+		return nil
 	}
 
-	// The returned Entity and encapsulated keys are cloned during the GetKey() call, so ok to own them
-	// here and just return them without cloning again.  Maybe a bit of an optimization and mem cost savings.
-	return receiverEntity.PublicKeys, senderKeyPairInfo, nil
+	profile := helpers.GlobalConfig.GetCurrentProfile()
+
+	useSelf := localBundleCommandVals.selfKeypairName != ""
+	if localBundleCommandVals.selfProvided {
+		useSelf = localBundleCommandVals.self
+	} else if profile != nil && profile.EncryptToSelf {
+		useSelf = true
+	}
+
+	if !useSelf {
+		return nil
+	}
+
+	selfKeypairName := localBundleCommandVals.selfKeypairName
+	if selfKeypairName == "" && profile != nil {
+		selfKeypairName = profile.SelfKeypairName
+	}
+
+	if selfKeypairName == "" {
+		selfKeypairName = helpers.KeyPairNameForKeyStoreReads
+	}
+
+	selfKeyInfo, err := getSelfKeyInfo(selfKeypairName)
+	if err != nil {
+		return err
+	}
+
+	logger.Debugfln("Adding self slot for keypair \"%s\"", selfKeypairName)
+	return localBundleSettings.cipherWriter.SetSelfRecipient(selfKeyInfo)
+}
+
+// getSelfKeyInfo returns the public keys for one of the profile's own keypairs
+func getSelfKeyInfo(keypairName string) (*security.KeyInfo, error) {
+	selfKeyPairInfo := keypairs.GlobalKeyPairStore.GetKeyPairInfo(keypairName)
+	if selfKeyPairInfo == nil {
+		return nil, fmt.Errorf("unable to locate self keypair for name \"%s\"", keypairName)
+	}
+	defer selfKeyPairInfo.Wipe()
+
+	selfCipherPublicKey, selfSigningPublicKey, err := selfKeyPairInfo.PublicKeys()
+	if err != nil {
+		return nil, fmt.Errorf("unable to obtain public keys from self keypair: %w", err)
+	}
+
+	return security.NewKeyInfo(keypairName, selfCipherPublicKey, selfSigningPublicKey)
 }
 
 // getLocalKeysForBundleWrite will return a set of keys using the default read and write keypairs in the profile's keypair store
@@ -569,6 +722,28 @@ func validateOutputPath() error {
 	return nil
 }
 
+// setBundleInputSource records the input source in whichever writer is in use
+func setBundleInputSource(inputSource cipherio.BundleInputSource) {
+	if localBundleSettings.signedWriter != nil {
+		localBundleSettings.signedWriter.OutputInfo.InputSource = inputSource
+		return
+	}
+
+	localBundleSettings.cipherWriter.OutputBundleInfo.InputSource = inputSource
+}
+
+// setBundleOriginalFile records the source file details in whichever writer is in use
+func setBundleOriginalFile(fileName, fileDate string) {
+	if localBundleSettings.signedWriter != nil {
+		localBundleSettings.signedWriter.OutputInfo.OriginalFileName = fileName
+		localBundleSettings.signedWriter.OutputInfo.OriginalFileDate = fileDate
+		return
+	}
+
+	localBundleSettings.cipherWriter.OutputBundleInfo.OriginalFileName = fileName
+	localBundleSettings.cipherWriter.OutputBundleInfo.OriginalFileDate = fileDate
+}
+
 func getInputReader() (io.Reader, error) {
 	switch localBundleCommandVals.inputSource {
 	case keystore.InputSourceConsole:
@@ -599,7 +774,7 @@ func getConsoleReader() (io.Reader, error) {
 		localBundleCommandVals.outputFile = filepath.Join(localBundleCommandVals.outputPath, "bee.console.ext")
 	}
 
-	localBundleSettings.cipherWriter.OutputBundleInfo.InputSource = cipherio.BundleInputSourceDirect
+	setBundleInputSource(cipherio.BundleInputSourceDirect)
 	inputBytes := []byte(strings.Join(inputLines, "\n"))
 	inputBuff := bytes.NewBuffer(inputBytes)
 	return inputBuff, nil
@@ -623,7 +798,7 @@ func getClipboardReaderForBundle() (io.Reader, error) {
 		localBundleCommandVals.outputFile = filepath.Join(localBundleCommandVals.outputPath, "bee.clipboard.ext")
 	}
 
-	localBundleSettings.cipherWriter.OutputBundleInfo.InputSource = cipherio.BundleInputSourceDirect
+	setBundleInputSource(cipherio.BundleInputSourceDirect)
 	return reader, nil
 }
 
@@ -646,7 +821,7 @@ func getPipedReaderForBundle() (io.Reader, error) {
 		localBundleCommandVals.outputFile = filepath.Join(localBundleCommandVals.outputPath, "bee.piped.ext")
 	}
 
-	localBundleSettings.cipherWriter.OutputBundleInfo.InputSource = cipherio.BundleInputSourceDirect
+	setBundleInputSource(cipherio.BundleInputSourceDirect)
 	return reader, nil
 }
 
@@ -656,11 +831,9 @@ func getFileReader() (io.Reader, error) {
 		return nil, fmt.Errorf("unable to get details from file: %s", err)
 	}
 
-	localBundleSettings.cipherWriter.OutputBundleInfo.InputSource = cipherio.BundleInputSourceFile
-	localBundleSettings.cipherWriter.OutputBundleInfo.OriginalFileDate = f.ModTime().Format(time.RFC3339)
-
 	_, name := filepath.Split(localBundleCommandVals.inputFilePath)
-	localBundleSettings.cipherWriter.OutputBundleInfo.OriginalFileName = name
+	setBundleInputSource(cipherio.BundleInputSourceFile)
+	setBundleOriginalFile(name, f.ModTime().Format(time.RFC3339))
 
 	file, err := os.Open(localBundleCommandVals.inputFilePath)
 	if err != nil {
@@ -678,7 +851,7 @@ func getDirsReader() (io.Reader, error) {
 		return nil, fmt.Errorf("unable to create multi directory input stream: %w", err)
 	}
 
-	localBundleSettings.cipherWriter.OutputBundleInfo.InputSource = cipherio.BundleInputSourceMultiDir
+	setBundleInputSource(cipherio.BundleInputSourceMultiDir)
 
 	if localBundleCommandVals.inputDir != "" {
 		// Everything has already been validated, no need to do checks again, we'll assume all is good
@@ -934,3 +1107,56 @@ func writeToPath(reader io.Reader) error {
 
 	return nil
 }
+
+// writeSigned emits the signed plaintext message to the requested output target.  Signed messages are always
+// armored text, so the bundle type does not apply.
+func writeSigned(reader io.Reader) error {
+	var err error
+	switch localBundleCommandVals.outputTarget {
+	case keystore.OutputTargetConsole:
+		fmt.Println("")
+		localBundleSettings.totalBytesWritten, err = localBundleSettings.signedWriter.WriteSignedTextFromReader(reader, os.Stdout)
+		if err == nil {
+			fmt.Println("")
+		}
+	case keystore.OutputTargetClipboard:
+		signedBuff := bytes.NewBuffer(nil)
+		localBundleSettings.totalBytesWritten, err = localBundleSettings.signedWriter.WriteSignedTextFromReader(reader, signedBuff)
+		if err == nil {
+			err = helpers.WriteToClipboard(signedBuff.Bytes())
+		}
+	case keystore.OutputTargetFile:
+		localBundleSettings.totalBytesWritten, err = writeSignedToFile(localBundleCommandVals.outputFile, reader)
+	case keystore.OutputTargetPath:
+		_, inputFilename := filepath.Split(localBundleCommandVals.inputFilePath)
+		localBundleSettings.totalBytesWritten, err = writeSignedToFile(
+			filepath.Join(localBundleCommandVals.outputPath, helpers.ReplaceFileExt(inputFilename, ".ext")),
+			reader)
+	default:
+		return errors.New("unknown output target")
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed writing signed message: %w", err)
+	}
+
+	return nil
+}
+
+func writeSignedToFile(outputFilePath string, reader io.Reader) (int, error) {
+	ext := strings.ToLower(filepath.Ext(outputFilePath))
+	if ext == "" || ext == ".ext" {
+		outputFilePath = helpers.ReplaceFileExt(outputFilePath, ".bsig")
+	}
+
+	signedFile, err := os.Create(outputFilePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed creating signed output file: %w", err)
+	}
+
+	defer func() {
+		_ = signedFile.Close()
+	}()
+
+	return localBundleSettings.signedWriter.WriteSignedTextFromReader(reader, signedFile)
+}
@@ -0,0 +1,180 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/logger"
+	"github.com/thoughtrealm/bumblebee/security"
+	"strings"
+)
+
+type certifyCommandVals struct {
+	keypairName   string
+	certifierName string
+}
+
+var localCertifyCommandVals = &certifyCommandVals{}
+
+// certifyCmd represents the certify command
+var certifyCmd = &cobra.Command{
+	Use:   "certify <user>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Signs a verified user's public keys and emits a certification for others to import",
+	Long: `Signs a user's public keys with one of your keypairs, stating that you have verified them.
+The user must already be set to verified with "set trust <user> verified".  The certification is stored
+with the user and emitted using the same output targets as export.  Contacts that import it will see
+you listed as a certifier of the user, and profiles that list you as a trusted introducer will accept
+the user's keys as verified.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		certifyUser(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(certifyCmd)
+	certifyCmd.Flags().StringVarP(&localCertifyCommandVals.keypairName, "from", "r", "default", "The name of your keypair to sign the certification with")
+	certifyCmd.Flags().StringVarP(&localCertifyCommandVals.certifierName, "name", "n", "", "Your name to put in the certification. Defaults to the keypair name, or the profile's default keypair name for \"default\".")
+	registerPublicStatementOutputFlags(certifyCmd)
+}
+
+func certifyUser(userName string) {
+	keypairName := localCertifyCommandVals.keypairName
+	if strings.EqualFold(keypairName, helpers.KeyPairNameForKeyStoreReads) ||
+		strings.EqualFold(keypairName, helpers.KeyPairNameForKeyStoreWrites) {
+		logger.Errorfln("Certifying with the system keypair \"%s\" is not allowed.", keypairName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	if keystore.GlobalKeyStore == nil || keypairs.GlobalKeyPairStore == nil {
+		logger.Errorln("Unable to certify user: keystore or keypair store not loaded")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	entity := keystore.GlobalKeyStore.GetKey(userName)
+	if entity == nil {
+		logger.Errorfln("No user with the name \"%s\" was found.", userName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	if !entity.IsVerified() {
+		logger.Errorfln("User \"%s\" is not verified (trust level: %s).", entity.Name, entity.TrustText())
+		logger.Errorfln("Verify their fingerprint and use \"set trust %s verified\" before certifying them.", entity.Name)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	kpi := keypairs.GlobalKeyPairStore.GetKeyPairInfo(keypairName)
+	if kpi == nil {
+		logger.Errorfln("Keypair \"%s\" not found.", keypairName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+	defer kpi.Wipe()
+
+	certifierName := getPublicStatementName(keypairName, localCertifyCommandVals.certifierName)
+	certification, err := security.NewCertification(entity.PublicKeys, certifierName, kpi)
+	if err != nil {
+		logger.Errorfln("Unable to create certification: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	_, err = keystore.GlobalKeyStore.AddCertification(certification)
+	if err != nil {
+		logger.Errorfln("Unable to store certification: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	fp := entity.PublicKeys.Fingerprint()
+	logger.Printfln("User \"%s\" certified by \"%s\".", entity.Name, certifierName)
+	logger.Printfln("Fingerprint : %s", fp.Hex())
+	logger.Println("")
+
+	eki, err := security.NewExportKeyInfoFromCertification(certification)
+	if err == nil {
+		logger.Println("Send the following certification to your contacts for import.")
+		err = exportPublicStatement(eki, "certification")
+	}
+
+	if err != nil {
+		logger.Errorfln("Unable to export certification: %s", err)
+		helpers.ExitCode = helpers.ExitCodeOutputError
+		return
+	}
+}
+
+// getCertifierNames returns a description of each certifier of the entity.  Certifiers are listed by their
+// local user name when the certifier's signing key is in the keystore, or else by the name in the certification.
+func getCertifierNames(entity *security.Entity) []string {
+	profile := helpers.GlobalConfig.GetCurrentProfile()
+
+	names := []string{}
+	for _, certification := range entity.Certifications {
+		certifier := keystore.GlobalKeyStore.GetKeyBySigningPubKey(certification.CertifierSigningPubKey)
+		if certifier == nil {
+			names = append(names, fmt.Sprintf("%s (unknown key)", certification.CertifierName))
+			continue
+		}
+
+		if profile != nil && profile.IsTrustedIntroducer(certifier.Name) {
+			names = append(names, fmt.Sprintf("%s (trusted introducer)", certifier.Name))
+			continue
+		}
+
+		names = append(names, certifier.Name)
+	}
+
+	return names
+}
+
+// getTrustedIntroducerFor returns the name of a trusted introducer that certified the entity's current keys,
+// or an empty string if there is none.  The introducer must still be verified in the keystore.
+func getTrustedIntroducerFor(entity *security.Entity) string {
+	profile := helpers.GlobalConfig.GetCurrentProfile()
+	if profile == nil || len(profile.TrustedIntroducers) == 0 || keystore.GlobalKeyStore == nil {
+		return ""
+	}
+
+	if entity.Trust == security.TrustLevelRevoked {
+		return ""
+	}
+
+	for _, certification := range entity.Certifications {
+		if !certification.Certifies(entity.PublicKeys) || certification.Verify() != nil {
+			continue
+		}
+
+		certifier := keystore.GlobalKeyStore.GetKeyBySigningPubKey(certification.CertifierSigningPubKey)
+		if certifier != nil && certifier.IsVerified() && profile.IsTrustedIntroducer(certifier.Name) {
+			return certifier.Name
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,282 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	cipherio "github.com/thoughtrealm/bumblebee/cipher/io"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/logger"
+	"github.com/thoughtrealm/bumblebee/security"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type exportKeypairCommandVals struct {
+	// includeSystem allows exporting the keystore_read and keystore_write keypairs
+	includeSystem bool
+
+	// paper writes a printable paper key to a PDF or text file
+	paper bool
+}
+
+var localExportKeypairCommandVals = &exportKeypairCommandVals{}
+
+// exportKeypairCmd represents the export keypair subcommand
+var exportKeypairCmd = &cobra.Command{
+	Use:   "keypair <name> [--include-system] [--paper]",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Exports a keypair, including its private keys, for moving it to another machine or profile",
+	Long: `Exports a keypair, including its private keys, so the identity can be moved to another machine or
+profile with "import".  Keypair exports are always encrypted with a password, which is strengthened with Argon2id.
+The same password must be provided when importing it.
+
+The output can be written to the console, clipboard, a file or a QR code.  QR codes use the text encoding,
+so a scanned code can be imported from the clipboard.
+
+With --paper, a printable paper key is written to --output-file, as a PDF if the file name ends with ".pdf",
+or as text if it ends with ".txt".  The export is rendered as numbered lines of base32 with a checksum on each
+line, along with a QR code and the keypair's fingerprint.  Restore it with "import --paper", which verifies each
+line as it is typed in.
+
+The keystore_read and keystore_write system keypairs protect this profile's keystore, so they are only
+exported when --include-system is provided.`,
+	Example: `  -- Write a paper key for the default keypair to a PDF for printing
+  bumblebee export keypair --paper default --output-file default-key.pdf`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			_ = cmd.Help()
+			return
+		}
+
+		err := startBootStrap(true, true)
+		if err != nil {
+			// bootstrap will print its own messages
+			return
+		}
+
+		exportKeypair(args[0])
+	},
+}
+
+func init() {
+	exportCmd.AddCommand(exportKeypairCmd)
+	exportKeypairCmd.Flags().BoolVarP(&localExportKeypairCommandVals.includeSystem, "include-system", "", false, "Allows exporting the keystore_read and keystore_write system keypairs.")
+	exportKeypairCmd.Flags().BoolVarP(&localExportKeypairCommandVals.paper, "paper", "", false, "Writes a printable paper key to --output-file, which must end with \".pdf\" or \".txt\".")
+}
+
+func exportKeypair(keypairName string) {
+	if !localExportKeypairCommandVals.includeSystem &&
+		(strings.EqualFold(keypairName, helpers.KeyPairNameForKeyStoreReads) ||
+			strings.EqualFold(keypairName, helpers.KeyPairNameForKeyStoreWrites)) {
+		logger.Errorfln("Exporting the system keypair \"%s\" requires --include-system.", keypairName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	if keypairs.GlobalKeyPairStore == nil {
+		logger.Errorln("Unable to export keypair: keypair store not loaded")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	kpi := keypairs.GlobalKeyPairStore.GetKeyPairInfo(keypairName)
+	if kpi == nil {
+		logger.Errorfln("No keypair with the name \"%s\" was found.", keypairName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+	defer kpi.Wipe()
+
+	if kpi.IsGroup() {
+		logger.Errorfln("Keypair \"%s\" is a group identity.  Use \"group add-member %s <user>\" to share it instead.", keypairName, keypairName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	sharedProcessExportFlags()
+	if sharedExportCommandVals.exportOutputTarget == helpers.ExportOutputTargetUnknown {
+		logger.Errorfln("Unknown export output target: %s", sharedExportCommandVals.exportOutputTargetText)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	passwordBytes, err := getExportKeypairPassword()
+	if err != nil {
+		logger.Errorfln("Unable to export keypair: %s", err)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+	defer security.Wipe(passwordBytes)
+
+	eki, err := security.NewExportKeyInfoFromKeyPairInfo(kpi)
+	if err != nil {
+		logger.Errorfln("Unable to export keypair: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	if localExportKeypairCommandVals.paper {
+		err = writeExportKeypairPaper(passwordBytes, eki)
+	} else {
+		err = writeExportKeypair(passwordBytes, eki)
+	}
+
+	if err != nil {
+		logger.Errorfln("Export failed: %s", err)
+		helpers.ExitCode = helpers.ExitCodeOutputError
+		return
+	}
+}
+
+// getExportKeypairPassword returns the --password value, or prompts for a confirmed password.  Unlike user
+// exports, the password is not optional.
+func getExportKeypairPassword() ([]byte, error) {
+	if sharedExportCommandVals.exportPassword != "" {
+		return []byte(sharedExportCommandVals.exportPassword), nil
+	}
+
+	logger.Println("Keypair exports include private keys, so a password is required to protect the export.")
+	logger.Println("You will need to provide the password when importing the keypair.")
+	logger.Println("")
+	logger.Printf("Enter a password for the export: ")
+
+	passwordBytes, err := helpers.GetPasswordWithConfirm("password")
+	logger.Println("")
+	if err != nil {
+		return nil, fmt.Errorf("failed trying to obtain password for export: %w", err)
+	}
+
+	if len(passwordBytes) == 0 {
+		return nil, errors.New("a password is required for keypair exports")
+	}
+
+	return passwordBytes, nil
+}
+
+func writeExportKeypair(password []byte, eki *security.ExportKeyInfo) error {
+	if sharedExportCommandVals.exportOutputFilePath != "" && sharedExportCommandVals.exportOutputTarget != helpers.ExportOutputTargetQR {
+		sharedExportCommandVals.exportOutputTarget = helpers.ExportOutputTargetFile
+	}
+
+	exportWriter := cipherio.NewExportWriter(password)
+	defer exportWriter.Wipe()
+
+	switch sharedExportCommandVals.exportOutputTarget {
+	case helpers.ExportOutputTargetConsole:
+		return exportUserInfoToConsole(exportWriter, password, eki)
+	case helpers.ExportOutputTargetClipboard:
+		return exportUserInfoToClipboard(exportWriter, password, eki)
+	case helpers.ExportOutputTargetQR:
+		return exportUserInfoToQR(exportWriter, password, eki)
+	case helpers.ExportOutputTargetFile:
+		if sharedExportCommandVals.exportOutputFilePath == "" {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("unable to determine the current working directory: %w", err)
+			}
+
+			ext := ".bexp-txt"
+			if sharedExportCommandVals.exportOutputEncoding == helpers.ExportOutputEncodingRaw {
+				ext = ".bexp-dat"
+			}
+
+			sharedExportCommandVals.exportOutputFilePath = filepath.Join(
+				cwd,
+				helpers.GetFileSafeName(eki.Name)+"-keypair"+ext)
+		}
+
+		return exportUserInfoToFile(exportWriter, password, eki)
+	default:
+		return fmt.Errorf("unknown export output target: %s", sharedExportCommandVals.exportOutputTargetText)
+	}
+}
+
+// writeExportKeypairPaper writes a printable paper key.  The paper key holds the same password protected export
+// data as the other targets, so it is restored with the same password.
+func writeExportKeypairPaper(password []byte, eki *security.ExportKeyInfo) error {
+	outputFilePath := sharedExportCommandVals.exportOutputFilePath
+	if outputFilePath == "" {
+		outputFilePath = helpers.GetFileSafeName(eki.Name) + "-keypair-paper.pdf"
+	}
+
+	outputExt := strings.ToLower(filepath.Ext(outputFilePath))
+	if outputExt != ".pdf" && outputExt != ".txt" {
+		return fmt.Errorf("paper key output file must end with \".pdf\" or \".txt\": %s", outputFilePath)
+	}
+
+	exportWriter := cipherio.NewExportWriter(password)
+	defer exportWriter.Wipe()
+
+	exportBuffer := bytes.NewBuffer(nil)
+	err := exportWriter.WriteExportKeyInfoToStream(eki, password, exportBuffer)
+	if err != nil {
+		return fmt.Errorf("unable to write info to stream buffer: %w", err)
+	}
+
+	fingerprint := security.NewFingerprint(eki.CipherPubKey, eki.SigningPubKey)
+	lines := helpers.EncodePaperKeyLines(exportBuffer.Bytes())
+	paperKey := &helpers.PaperKeyDocument{
+		Title: "Bumblebee Paper Key",
+		Details: [][2]string{
+			{"Keypair", eki.Name},
+			{"Fingerprint", fingerprint.Hex()},
+			{"Words", fingerprint.Words()},
+			{"Created", time.Now().Format("2006-01-02 15:04 MST")},
+			{"Lines", fmt.Sprintf("%d", len(lines))},
+		},
+		Instructions: []string{
+			"This paper key holds the private keys of the keypair, encrypted with the export password.",
+			"Keep it in a safe place, and keep the password somewhere else.",
+			"To restore, type in the lines below with \"bumblebee import --paper\".  Each line ends with a checksum, " +
+				"so a mistyped line is reported by its line number.  Or, scan the QR code and import it with " +
+				"\"bumblebee import --input-source clipboard\".",
+		},
+		Lines:  lines,
+		QRData: helpers.QRCodeExportData(exportBuffer.Bytes()),
+	}
+
+	// Keypairs with many subkeys may be too large for a QR code, but the lines are enough to restore them
+	_, err = helpers.QRCodeToText(paperKey.QRData)
+	if err != nil {
+		logger.Printfln("The paper key will not include a QR code: %s", err)
+		paperKey.QRData = nil
+	}
+
+	var paperBytes []byte
+	if outputExt == ".pdf" {
+		paperBytes, err = paperKey.ToPDF()
+	} else {
+		paperBytes, err = paperKey.ToText()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(outputFilePath, paperBytes, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to write paper key: %w", err)
+	}
+
+	logger.Printfln("Paper key with %d lines written to %s", len(lines), outputFilePath)
+	logger.Printfln("Fingerprint : %s", fingerprint.Hex())
+	return nil
+}
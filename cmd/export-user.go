@@ -17,6 +17,7 @@ package cmd
 // it's ok to do everything in memory, using byte buffers, []byte, etc.
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"github.com/spf13/cobra"
@@ -33,10 +34,17 @@ import (
 
 // exportUserCmd represents the user export subcommand
 var exportUserCmd = &cobra.Command{
-	Use:   "user [name]",
+	Use:   "user [name] [--format bumblebee|jwk|vcard]",
 	Args:  cobra.MaximumNArgs(1),
 	Short: "Exports user info for adding to another profile or system",
-	Long:  "Exports user info for adding to another profile or system",
+	Long: `Exports user info for adding to another profile or system.
+
+With --format jwk, the public keys are written as a JSON Web Key Set for JOSE libraries, with RFC 8037 OKP keys.
+The signing key is an Ed25519 key and the cipher key is an X25519 key, both with the user name as the kid.
+With --format vcard, the user is written as a vCard contact card, with the keys and fingerprint in
+X-BUMBLEBEE-* properties.  The card is all a recipient needs to import the user and start sending bundles.
+
+JWK and vCard output is not password protected.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) == 0 {
 			_ = cmd.Help()
@@ -57,9 +65,11 @@ var exportUserCmd = &cobra.Command{
 const textOutputHeader = ":start  :export-user  :hex"
 
 var exportUserFromKeypair bool
+var exportUserFormat string
 
 func init() {
 	exportCmd.AddCommand(exportUserCmd)
+	exportUserCmd.Flags().StringVarP(&exportUserFormat, "format", "", "bumblebee", "The export format.  Should be one of: bumblebee, jwk or vcard.")
 	exportUserCmd.Flags().BoolVarP(
 		&exportUserFromKeypair, "from-keypair", "", false,
 		`Extracts only public keys from a keypair and exports as a user,
@@ -93,6 +103,20 @@ func exportUser(userName string) {
 		}
 	}
 
+	switch strings.ToLower(strings.TrimSpace(exportUserFormat)) {
+	case "", "bumblebee":
+	case "jwk", "jwks":
+		exportUserJWK(entity)
+		return
+	case "vcard", "vcf":
+		exportUserVCard(entity)
+		return
+	default:
+		logger.Errorfln("Unknown export format: %s", exportUserFormat)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
 	var passwordBytes []byte
 	if sharedExportCommandVals.exportPassword != "" {
 		passwordBytes = []byte(sharedExportCommandVals.exportPassword)
@@ -121,7 +145,7 @@ func exportUser(userName string) {
 
 	sharedProcessExportFlags()
 
-	if sharedExportCommandVals.exportOutputFilePath != "" {
+	if sharedExportCommandVals.exportOutputFilePath != "" && sharedExportCommandVals.exportOutputTarget != helpers.ExportOutputTargetQR {
 		sharedExportCommandVals.exportOutputTarget = helpers.ExportOutputTargetFile
 	}
 
@@ -135,6 +159,8 @@ func exportUser(userName string) {
 		err = exportUserInfoToClipboard(exportWriter, passwordBytes, eki)
 	case helpers.ExportOutputTargetFile:
 		err = exportUserInfoToFile(exportWriter, passwordBytes, eki)
+	case helpers.ExportOutputTargetQR:
+		err = exportUserInfoToQR(exportWriter, passwordBytes, eki)
 	case helpers.ExportOutputTargetUnknown:
 		err = fmt.Errorf("unknown export output target: %s", sharedExportCommandVals.exportOutputTargetText)
 	}
@@ -146,6 +172,47 @@ func exportUser(userName string) {
 	}
 }
 
+// exportUserJWK writes the entity's public keys as a JWK set
+func exportUserJWK(entity *security.Entity) {
+	set, err := security.NewJWKSetFromKeyInfos([]*security.KeyInfo{entity.PublicKeys})
+	var setBytes []byte
+	if err == nil {
+		setBytes, err = set.Marshal()
+	}
+
+	if err != nil {
+		logger.Errorfln("Export failed: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	sharedProcessExportFlags()
+	err = writeExportDocument(setBytes, "JWK set", helpers.GetFileSafeName(entity.Name)+".jwks.json")
+	if err != nil {
+		logger.Errorfln("Export failed: %s", err)
+		helpers.ExitCode = helpers.ExitCodeOutputError
+	}
+}
+
+// exportUserVCard writes the entity as a vCard contact card
+func exportUserVCard(entity *security.Entity) {
+	cardBytes, err := keystore.EncodeUserRecords(
+		[]*keystore.UserRecord{keystore.NewUserRecordFromEntity(entity)},
+		keystore.UserListFormatVCard)
+	if err != nil {
+		logger.Errorfln("Export failed: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	sharedProcessExportFlags()
+	err = writeExportDocument(cardBytes, "vCard", helpers.GetFileSafeName(entity.Name)+".vcf")
+	if err != nil {
+		logger.Errorfln("Export failed: %s", err)
+		helpers.ExitCode = helpers.ExitCodeOutputError
+	}
+}
+
 func getExportEntityFromKeyPair(userName string) (entity *security.Entity, err error) {
 	logger.Debugfln("Building export key info from keypair using username: %s", userName)
 
@@ -202,6 +269,8 @@ func getExportEntityFromKeyPair(userName string) (entity *security.Entity, err e
 		signingPubKey,
 	)
 
+	ki.Subkeys = kpi.SubkeyCertificates()
+
 	entityOut := &security.Entity{
 		Name:       tempUserName,
 		PublicKeys: ki,
@@ -311,7 +380,8 @@ func exportUserInfoToFileWithRawEncoding(exportWriter *cipherio.ExportWriter, pa
 	return nil
 }
 
-func exportUserInfoToFileWithTextEncoding(exportWriter *cipherio.ExportWriter, password []byte, eki *security.ExportKeyInfo) error {
+// exportUserInfoToTextBytes returns the text encoded export, the same as is written to the console
+func exportUserInfoToTextBytes(exportWriter *cipherio.ExportWriter, password []byte, eki *security.ExportKeyInfo) ([]byte, error) {
 	textWriter := helpers.NewTextWriter(
 		helpers.TextWriterTargetBuffered,
 		32,
@@ -322,17 +392,39 @@ func exportUserInfoToFileWithTextEncoding(exportWriter *cipherio.ExportWriter, p
 
 	err := exportWriter.WriteExportKeyInfoToStream(eki, password, textWriter)
 	if err != nil {
-		return fmt.Errorf("unable to write info to stream buffer: %w", err)
+		return nil, fmt.Errorf("unable to write info to stream buffer: %w", err)
 	}
 
 	_, err = textWriter.Flush()
 	if err != nil {
-		return fmt.Errorf("failed finalizing stream output to buffer: %w", err)
+		return nil, fmt.Errorf("failed finalizing stream output to buffer: %w", err)
 	}
 
-	exportBytes := textWriter.PostFlushOutputBuffer()
+	return textWriter.PostFlushOutputBuffer(), nil
+}
+
+// exportUserInfoToQR writes the export as a QR code, which holds the export as a raw base64 text block
+func exportUserInfoToQR(exportWriter *cipherio.ExportWriter, password []byte, eki *security.ExportKeyInfo) error {
+	if sharedExportCommandVals.exportOutputEncoding == helpers.ExportOutputEncodingRaw {
+		return errors.New("output encoding \"RAW\" is not compatible with output target \"QR\"")
+	}
+
+	exportBuffer := bytes.NewBuffer(nil)
+	err := exportWriter.WriteExportKeyInfoToStream(eki, password, exportBuffer)
+	if err != nil {
+		return fmt.Errorf("unable to write info to stream buffer: %w", err)
+	}
+
+	return writeExportQRCode(helpers.QRCodeExportData(exportBuffer.Bytes()), "Export")
+}
+
+func exportUserInfoToFileWithTextEncoding(exportWriter *cipherio.ExportWriter, password []byte, eki *security.ExportKeyInfo) error {
+	exportBytes, err := exportUserInfoToTextBytes(exportWriter, password, eki)
+	if err != nil {
+		return err
+	}
 
-	outputFile, err := os.Create(sharedExportCommandVals.exportOutputFilePath)
+	outputFile, err := helpers.CreateOutputFile(sharedExportCommandVals.exportOutputFilePath, eki.HasPrivateKeys())
 	if err != nil {
 		return fmt.Errorf("unable to create output file: %w", err)
 	}
@@ -0,0 +1,202 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/spf13/cobra"
+	cipherio "github.com/thoughtrealm/bumblebee/cipher/io"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/logger"
+	"github.com/thoughtrealm/bumblebee/security"
+	"os"
+	"path/filepath"
+)
+
+type exportUsersCommandVals struct {
+	formatText      string
+	signWith        string
+	nameMatchFilter string
+}
+
+var localExportUsersCommandVals = &exportUsersCommandVals{}
+
+// exportUsersCmd represents the export users command
+var exportUsersCmd = &cobra.Command{
+	Use:   "users [--format csv|json|yaml|jwks|vcard] [--sign-with <keypair>]",
+	Args:  cobra.NoArgs,
+	Short: "Exports the address book as a CSV, JSON, YAML, JWKS or vCard user list",
+	Long: `Exports the address book as a CSV, JSON, YAML, JWKS or vCard user list, which can be loaded with "import users".
+The list only contains public keys and contact details, so it is not password protected.
+
+The jwks format is a JSON Web Key Set for JOSE libraries, with RFC 8037 OKP keys.  Each user has an Ed25519
+key for their signing key and an X25519 key for their cipher key, both with the user name as the kid.  Contact
+details are not included.
+
+The vcard format writes a vCard contact card for each user, for CardDAV and other address books.  The user name,
+keys and fingerprint are kept in X-BUMBLEBEE-* properties.
+
+If --sign-with is provided, the list is signed with that keypair, so recipients can verify where it came from
+by importing it with "import users --from <signer>".`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// bootstrap will print its own messages
+			return
+		}
+
+		exportUsers()
+	},
+}
+
+func init() {
+	exportCmd.AddCommand(exportUsersCmd)
+	exportUsersCmd.Flags().StringVarP(&localExportUsersCommandVals.formatText, "format", "", "json", "The format of the user list.  Should be one of: csv, json, yaml, jwks or vcard.")
+	exportUsersCmd.Flags().StringVarP(&localExportUsersCommandVals.signWith, "sign-with", "", "", "The keypair used to sign the user list.  If not provided, the list is not signed.")
+	exportUsersCmd.Flags().StringVarP(&localExportUsersCommandVals.nameMatchFilter, "match", "m", "", "Only users with names matching this filter are exported.  Supports * and ? wildcards.")
+}
+
+func exportUsers() {
+	if keystore.GlobalKeyStore == nil {
+		logger.Errorln("Unable to export users: keystore not loaded")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	format := keystore.TextToUserListFormat(localExportUsersCommandVals.formatText)
+	if format == keystore.UserListFormatUnknown {
+		logger.Errorfln("Unknown user list format: %s", localExportUsersCommandVals.formatText)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	var records []*keystore.UserRecord
+	err := keystore.GlobalKeyStore.Walk(keystore.NewWalkInfo(
+		localExportUsersCommandVals.nameMatchFilter,
+		true,
+		nil,
+		func(entity *security.Entity) {
+			records = append(records, keystore.NewUserRecordFromEntity(entity))
+		}))
+	if err != nil {
+		logger.Errorfln("Unable to read the keystore: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	if len(records) == 0 {
+		logger.Errorln("No users found to export")
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	listBytes, err := keystore.EncodeUserRecords(records, format)
+	if err != nil {
+		logger.Errorfln("Unable to encode the user list: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	if localExportUsersCommandVals.signWith != "" {
+		listBytes, err = signUserList(listBytes, localExportUsersCommandVals.signWith)
+		if err != nil {
+			logger.Errorfln("Unable to sign the user list: %s", err)
+			helpers.ExitCode = helpers.ExitCodeCipherError
+			return
+		}
+	}
+
+	err = writeExportDocument(listBytes, "User list", "users"+keystore.UserListFormatExt(format))
+	if err != nil {
+		logger.Errorfln("Unable to write the user list: %s", err)
+		helpers.ExitCode = helpers.ExitCodeOutputError
+		return
+	}
+
+	logger.Printfln("Exported %d user(s)", len(records))
+}
+
+func signUserList(listBytes []byte, keypairName string) ([]byte, error) {
+	if keypairs.GlobalKeyPairStore == nil {
+		return nil, fmt.Errorf("keypair store not loaded")
+	}
+
+	signerKPI := keypairs.GlobalKeyPairStore.GetKeyPairInfo(keypairName)
+	if signerKPI == nil {
+		return nil, fmt.Errorf("keypair not found: %s", keypairName)
+	}
+	defer signerKPI.Wipe()
+
+	signedWriter, err := cipherio.NewSignedWriter(signerKPI)
+	if err != nil {
+		return nil, err
+	}
+	defer signedWriter.Wipe()
+
+	signedBuffer := bytes.NewBuffer(nil)
+	_, err = signedWriter.WriteSignedTextFromReader(bytes.NewReader(listBytes), signedBuffer)
+	if err != nil {
+		return nil, err
+	}
+
+	return signedBuffer.Bytes(), nil
+}
+
+// writeExportDocument writes text documents, such as user lists, using the export output targets.  The
+// documentName is used in messages, and the defaultFileName is used for file output when no file is provided.
+func writeExportDocument(documentBytes []byte, documentName, defaultFileName string) error {
+	sharedProcessExportFlags()
+
+	if sharedExportCommandVals.exportOutputFilePath != "" && sharedExportCommandVals.exportOutputTarget != helpers.ExportOutputTargetQR {
+		sharedExportCommandVals.exportOutputTarget = helpers.ExportOutputTargetFile
+	}
+
+	switch sharedExportCommandVals.exportOutputTarget {
+	case helpers.ExportOutputTargetConsole:
+		fmt.Println(string(documentBytes))
+		return nil
+	case helpers.ExportOutputTargetClipboard:
+		err := helpers.WriteToClipboard(documentBytes)
+		if err != nil {
+			return err
+		}
+
+		logger.Printfln("%s written to clipboard", documentName)
+		return nil
+	case helpers.ExportOutputTargetQR:
+		return writeExportQRCode(documentBytes, documentName)
+	case helpers.ExportOutputTargetFile:
+		if sharedExportCommandVals.exportOutputFilePath == "" {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("unable to determine the current working directory: %w", err)
+			}
+
+			sharedExportCommandVals.exportOutputFilePath = filepath.Join(cwd, defaultFileName)
+		}
+
+		err := os.WriteFile(sharedExportCommandVals.exportOutputFilePath, documentBytes, 0600)
+		if err != nil {
+			return err
+		}
+
+		logger.Printfln("%s written to %s", documentName, sharedExportCommandVals.exportOutputFilePath)
+		return nil
+	default:
+		return fmt.Errorf("unknown export output target: %s", sharedExportCommandVals.exportOutputTargetText)
+	}
+}
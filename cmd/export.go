@@ -14,8 +14,14 @@
 package cmd
 
 import (
+	"fmt"
 	"github.com/spf13/cobra"
+	cipherio "github.com/thoughtrealm/bumblebee/cipher/io"
 	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/logger"
+	"github.com/thoughtrealm/bumblebee/security"
+	"os"
+	"path/filepath"
 )
 
 type exportCommandVals struct {
@@ -41,8 +47,8 @@ var exportCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(exportCmd)
-	exportCmd.PersistentFlags().StringVarP(&sharedExportCommandVals.exportOutputTargetText, "output-target", "t", "console", "The output target.  Should be one of: console, clipboard or file.")
-	exportCmd.PersistentFlags().StringVarP(&sharedExportCommandVals.exportOutputFilePath, "output-file", "f", "", "The file name to use for output. Only relevant if output-target is FILE.")
+	exportCmd.PersistentFlags().StringVarP(&sharedExportCommandVals.exportOutputTargetText, "output-target", "t", "console", "The output target.  Should be one of: console, clipboard, file or qr.")
+	exportCmd.PersistentFlags().StringVarP(&sharedExportCommandVals.exportOutputFilePath, "output-file", "f", "", "The file name to use for output. Only relevant if output-target is FILE or QR.\nFor QR, a PNG image of the QR code is written to the file.")
 	exportCmd.PersistentFlags().StringVarP(&sharedExportCommandVals.exportOutputEncodingText, "output-encoding", "e", "text", "The encoding for the output.  Should be \"text\" or \"raw\".\nText is human readable and can be copied, printed, pasted into an email, texted, etc.\nRaw is not readable or printable to console, text docs, emails, etc.")
 	exportCmd.PersistentFlags().StringVarP(&sharedExportCommandVals.exportPassword,
 		"password", "", "",
@@ -63,3 +69,82 @@ func sharedProcessExportFlags() {
 	sharedExportCommandVals.exportOutputEncoding = helpers.TextToExportOutputEncoding(
 		sharedExportCommandVals.exportOutputEncodingText)
 }
+
+// registerPublicStatementOutputFlags adds the export output flags to commands that emit signed statements,
+// such as succession statements and revocation certificates.
+func registerPublicStatementOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&sharedExportCommandVals.exportOutputTargetText, "output-target", "t", "console", "The output target.  Should be one of: console, clipboard, file or qr.")
+	cmd.Flags().StringVarP(&sharedExportCommandVals.exportOutputFilePath, "output-file", "f", "", "The file name to use for output. Only relevant if output-target is FILE or QR.\nFor QR, a PNG image of the QR code is written to the file.")
+	cmd.Flags().StringVarP(&sharedExportCommandVals.exportOutputEncodingText, "output-encoding", "e", "text", "The encoding for the output.  Should be \"text\" or \"raw\".")
+}
+
+// exportPublicStatement writes a signed statement using the export output targets.  Statements only contain
+// public keys and signatures, so they are not password protected.  The fileSuffix is added to default file names.
+func exportPublicStatement(eki *security.ExportKeyInfo, fileSuffix string) error {
+	sharedProcessExportFlags()
+
+	if sharedExportCommandVals.exportOutputFilePath != "" && sharedExportCommandVals.exportOutputTarget != helpers.ExportOutputTargetQR {
+		sharedExportCommandVals.exportOutputTarget = helpers.ExportOutputTargetFile
+	}
+
+	exportWriter := cipherio.NewExportWriter(nil)
+	defer exportWriter.Wipe()
+
+	switch sharedExportCommandVals.exportOutputTarget {
+	case helpers.ExportOutputTargetConsole:
+		return exportUserInfoToConsole(exportWriter, nil, eki)
+	case helpers.ExportOutputTargetClipboard:
+		return exportUserInfoToClipboard(exportWriter, nil, eki)
+	case helpers.ExportOutputTargetQR:
+		return exportUserInfoToQR(exportWriter, nil, eki)
+	case helpers.ExportOutputTargetFile:
+		if sharedExportCommandVals.exportOutputFilePath == "" {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("unable to determine the current working directory: %s", err)
+			}
+
+			ext := ".bexp-txt"
+			if sharedExportCommandVals.exportOutputEncoding == helpers.ExportOutputEncodingRaw {
+				ext = ".bexp-dat"
+			}
+
+			sharedExportCommandVals.exportOutputFilePath = filepath.Join(
+				cwd,
+				helpers.GetFileSafeName(eki.Name)+"-"+fileSuffix+ext)
+		}
+
+		return exportUserInfoToFile(exportWriter, nil, eki)
+	default:
+		return fmt.Errorf("unknown export output target: %s", sharedExportCommandVals.exportOutputTargetText)
+	}
+}
+
+// writeExportQRCode writes the text output as a QR code.  If an output file is provided, the QR code is written
+// to it as a PNG image.  Otherwise, it is printed to the console.
+func writeExportQRCode(textBytes []byte, documentName string) error {
+	if sharedExportCommandVals.exportOutputFilePath == "" {
+		qrText, err := helpers.QRCodeToText(textBytes)
+		if err != nil {
+			return err
+		}
+
+		fmt.Print(qrText)
+		logger.Println("")
+		logger.Printfln("%s QR code complete", documentName)
+		return nil
+	}
+
+	pngBytes, err := helpers.QRCodeToPNG(textBytes)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(sharedExportCommandVals.exportOutputFilePath, pngBytes, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to write QR code image: %w", err)
+	}
+
+	logger.Printfln("%s QR code image written to %s", documentName, sharedExportCommandVals.exportOutputFilePath)
+	return nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/security"
+	"strings"
+	"unicode"
+)
+
+// fingerprintCompareCmd represents the compare subcommand for "fingerprint" command
+var fingerprintCompareCmd = &cobra.Command{
+	Use:   "compare <user> [fingerprint or safety number]",
+	Args:  cobra.RangeArgs(1, 2),
+	Short: "Displays or compares a user's fingerprint and your safety number with them",
+	Long: "Displays a user's fingerprint and the safety number shared between you and the user.  " +
+		"If a fingerprint or safety number is provided, it is compared to the stored keys for the user.",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		var compareValue string
+		if len(args) > 1 {
+			compareValue = args[1]
+		}
+
+		compareFingerprint(args[0], compareValue)
+	},
+}
+
+type fingerprintCompareCommandVals struct {
+	// keypairName is the local keypair used for the safety number.  If empty, uses the default keypair.
+	keypairName string
+}
+
+var localFingerprintCompareCommandVals = &fingerprintCompareCommandVals{}
+
+func init() {
+	fingerprintCmd.AddCommand(fingerprintCompareCmd)
+	fingerprintCompareCmd.Flags().StringVarP(&localFingerprintCompareCommandVals.keypairName, "keypair", "k", "", "The name of your keypair to use for the safety number. If empty, uses the default keypair.")
+}
+
+func compareFingerprint(userName, compareValue string) {
+	entity := keystore.GlobalKeyStore.GetKey(userName)
+	if entity == nil {
+		fmt.Printf("No user with the name \"%s\" was found.\n", userName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	useKeypairName := localFingerprintCompareCommandVals.keypairName
+	if useKeypairName == "" {
+		useKeypairName = "default"
+	}
+
+	userFP := entity.PublicKeys.Fingerprint()
+	ownFP, err := getKeyPairFingerprint(useKeypairName)
+	if err != nil {
+		fmt.Printf("Unable to build your fingerprint: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+	safetyNumber := security.SafetyNumber(ownFP, userFP)
+
+	fmt.Printf("User                   : %s\n", entity.Name)
+	fmt.Printf("User Fingerprint       : %s\n", userFP.Hex())
+	fmt.Printf("User Fingerprint Words : %s\n", userFP.Words())
+	fmt.Printf("Your Keypair           : %s\n", useKeypairName)
+	fmt.Printf("Your Fingerprint       : %s\n", ownFP.Hex())
+	fmt.Printf("Your Fingerprint Words : %s\n", ownFP.Words())
+	fmt.Printf("Safety Number          : %s\n", safetyNumber)
+	fmt.Println("")
+
+	if compareValue == "" {
+		fmt.Println("Confirm the safety number with the user, or compare their fingerprint, over a trusted channel.")
+		return
+	}
+
+	if isSafetyNumberText(compareValue) {
+		if normalizeSafetyNumber(compareValue) == normalizeSafetyNumber(safetyNumber) {
+			fmt.Println("MATCH: The provided safety number matches.")
+			return
+		}
+
+		fmt.Println("MISMATCH: The provided safety number does NOT match.  Do not trust these keys until this is resolved.")
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	compareFP, err := security.ParseFingerprint(compareValue)
+	if err != nil {
+		fmt.Printf("Unable to read the provided value: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	if compareFP.Equal(userFP) {
+		fmt.Printf("MATCH: The provided fingerprint matches the stored keys for \"%s\".\n", entity.Name)
+		return
+	}
+
+	fmt.Printf("MISMATCH: The provided fingerprint does NOT match the stored keys for \"%s\".  Do not trust these keys until this is resolved.\n", entity.Name)
+	helpers.ExitCode = helpers.ExitCodeRequestFailed
+}
+
+// getKeyPairFingerprint returns the fingerprint of one of the profile's keypairs
+func getKeyPairFingerprint(keypairName string) (security.Fingerprint, error) {
+	if keypairs.GlobalKeyPairStore == nil {
+		return nil, errors.New("keypair store is not loaded")
+	}
+
+	kpi := keypairs.GlobalKeyPairStore.GetKeyPairInfo(keypairName)
+	if kpi == nil {
+		return nil, fmt.Errorf("keypair \"%s\" not found", keypairName)
+	}
+	defer kpi.Wipe()
+
+	return kpi.Fingerprint()
+}
+
+// isSafetyNumberText returns true if the value only contains digits and separators, with the digit count of a safety number
+func isSafetyNumberText(value string) bool {
+	digits := normalizeSafetyNumber(value)
+	if len(digits) != security.SafetyNumberGroups*5 {
+		return false
+	}
+
+	for _, r := range value {
+		if !unicode.IsDigit(r) && !unicode.IsSpace(r) && r != '-' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func normalizeSafetyNumber(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+
+		return -1
+	}, value)
+}
@@ -0,0 +1,32 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// fingerprintCmd represents the fingerprint command
+var fingerprintCmd = &cobra.Command{
+	Use:   "fingerprint",
+	Short: "Compares key fingerprints and safety numbers for out-of-band verification",
+	Long:  "Compares key fingerprints and safety numbers for out-of-band verification",
+	Run: func(cmd *cobra.Command, args []string) {
+		_ = cmd.Help()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fingerprintCmd)
+}
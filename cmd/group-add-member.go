@@ -0,0 +1,65 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/logger"
+)
+
+// groupAddMemberCmd represents the group add-member command
+var groupAddMemberCmd = &cobra.Command{
+	Use:   "add-member <group> <user> [user...]",
+	Args:  cobra.MinimumNArgs(2),
+	Short: "Adds members to a group identity, which moves the group to a new epoch",
+	Long: `Adds members to a group identity managed by this profile.  The group moves to a new epoch with new keys,
+which are distributed to all members.  New members can not open bundles sent to the group in prior epochs.
+A succession statement is emitted for contacts that send bundles to the group.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		addGroupMembers(args[0], args[1:])
+	},
+}
+
+func init() {
+	groupCmd.AddCommand(groupAddMemberCmd)
+	registerGroupDistributionFlags(groupAddMemberCmd)
+	registerPublicStatementOutputFlags(groupAddMemberCmd)
+}
+
+func addGroupMembers(groupName string, memberNames []string) {
+	kpi, err := getManagedGroupKeyPair(groupName)
+	if err != nil {
+		logger.Errorfln("Unable to add members: %s", err)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+	defer kpi.Wipe()
+
+	for _, memberName := range memberNames {
+		if kpi.HasGroupMember(memberName) {
+			logger.Errorfln("Unable to add members: \"%s\" is already a member of group \"%s\"", memberName, kpi.Name)
+			helpers.ExitCode = helpers.ExitCodeInvalidInput
+			return
+		}
+	}
+
+	rekeyGroup(kpi.Name, mergeMemberNames(kpi.GroupMembers, memberNames, nil))
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/logger"
+	"github.com/thoughtrealm/bumblebee/security"
+	"strings"
+)
+
+type groupCreateCommandVals struct {
+	members []string
+}
+
+var localGroupCreateCommandVals = &groupCreateCommandVals{}
+
+// groupCreateCmd represents the group create command
+var groupCreateCmd = &cobra.Command{
+	Use:   "create <name> [--members user1,user2]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Creates a shared group identity and distributes its keys to the members",
+	Long: `Creates a shared group identity at epoch 1, as a keypair managed by this profile.  Members must already
+exist in the keystore.  A key distribution bundle is written for each member.  Share the group's public keys
+with "export user <name> --from-keypair", so that others can send bundles to the group.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		createGroup(args[0])
+	},
+}
+
+func init() {
+	groupCmd.AddCommand(groupCreateCmd)
+	groupCreateCmd.Flags().StringSliceVarP(&localGroupCreateCommandVals.members, "members", "m", nil, "The users to distribute the group keys to. May be repeated or comma separated.")
+	registerGroupDistributionFlags(groupCreateCmd)
+}
+
+func createGroup(groupName string) {
+	if strings.EqualFold(groupName, helpers.KeyPairNameForKeyStoreReads) ||
+		strings.EqualFold(groupName, helpers.KeyPairNameForKeyStoreWrites) ||
+		strings.EqualFold(groupName, "default") {
+		logger.Errorfln("The name \"%s\" is reserved and can not be used for a group.", groupName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	if keypairs.GlobalKeyPairStore == nil {
+		logger.Errorln("Unable to create group: keypair store not loaded")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	members, err := getGroupMemberEntities(localGroupCreateCommandVals.members)
+	if err != nil {
+		logger.Errorfln("Unable to create group: %s", err)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	kpi, err := keypairs.GlobalKeyPairStore.CreateGroupKeyPair(groupName, entityNames(members))
+	if err != nil {
+		logger.Errorfln("Unable to create group: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+	defer kpi.Wipe()
+
+	err = keypairs.GlobalKeyPairStore.SaveKeyPairStoreToOrigin(nil)
+	if err != nil {
+		logger.Errorfln("Unable to create group: keypair store could not update the file: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	cipherPubKey, signingPubKey, err := kpi.PublicKeys()
+	if err == nil {
+		logger.Printfln("Group \"%s\" created at epoch %d and keypair store file changes committed.", kpi.Name, kpi.GroupEpoch)
+		logger.Printfln("Fingerprint : %s", security.NewFingerprint(cipherPubKey, signingPubKey).Hex())
+		logger.Println("")
+	}
+
+	err = distributeGroupKey(kpi, members)
+	if err != nil {
+		logger.Errorfln("Unable to distribute group keys: %s", err)
+		helpers.ExitCode = helpers.ExitCodeOutputError
+		return
+	}
+}
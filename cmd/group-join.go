@@ -0,0 +1,165 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	cipherio "github.com/thoughtrealm/bumblebee/cipher/io"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/logger"
+	"github.com/thoughtrealm/bumblebee/security"
+)
+
+type groupJoinCommandVals struct {
+	fromName string
+	toName   string
+}
+
+var localGroupJoinCommandVals = &groupJoinCommandVals{}
+
+// groupJoinCmd represents the group join command
+var groupJoinCmd = &cobra.Command{
+	Use:   "join <bundle-file> --from <user>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Stores the group keys from a key distribution bundle",
+	Long: `Opens a key distribution bundle from the manager of a group identity and stores the group keys as a keypair.
+The sender is recorded as the group's manager.  If the group is already known, the bundle must be for a newer epoch
+and must be sent by the same manager.  The keys of prior epochs are kept, so bundles
+sent to the group in those epochs can still be opened with "open --to <group>".  The bundle is read in memory, so
+the group keys are not written to disk unencrypted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		joinGroup(args[0])
+	},
+}
+
+func init() {
+	groupCmd.AddCommand(groupJoinCmd)
+	groupJoinCmd.Flags().StringVarP(&localGroupJoinCommandVals.fromName, "from", "r", "", "The name of the user that manages the group and sent the bundle.")
+	groupJoinCmd.Flags().StringVarP(&localGroupJoinCommandVals.toName, "to", "t", "default", "The name of the keypair the bundle was sent to.")
+}
+
+func joinGroup(bundleFilePath string) {
+	if localGroupJoinCommandVals.fromName == "" {
+		logger.Errorln("The --from user is required to join a group")
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	if keypairs.GlobalKeyPairStore == nil || keystore.GlobalKeyStore == nil {
+		logger.Errorln("Unable to join group: keystore or keypair store not loaded")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	sender := keystore.GlobalKeyStore.GetKey(localGroupJoinCommandVals.fromName)
+	if sender == nil {
+		logger.Errorfln("Unable to join group: user \"%s\" was not found in the keystore", localGroupJoinCommandVals.fromName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	if sender.Trust == security.TrustLevelRevoked {
+		logger.Errorfln("Unable to join group: user \"%s\" has been revoked", sender.Name)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	err := checkEntityTrust(sender, "sender")
+	if err != nil {
+		logger.Errorfln("Unable to join group: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	groupKPI, err := readGroupKeyBundle(bundleFilePath, sender)
+	if err != nil {
+		logger.Errorfln("Unable to join group: %s", err)
+		helpers.ExitCode = helpers.ExitCodeCipherError
+		return
+	}
+	defer groupKPI.Wipe()
+
+	kpi, added, err := keypairs.GlobalKeyPairStore.MergeGroupKey(groupKPI, sender.PublicKeys.SigningPubKey)
+	if errors.Is(err, security.ErrStaleGroupEpoch) {
+		logger.Printfln("Group \"%s\" is already at the same or a newer epoch: %s", groupKPI.Name, err)
+		return
+	}
+
+	if errors.Is(err, security.ErrGroupManagerMismatch) {
+		logger.Errorfln("Unable to join group: user \"%s\" is not the manager of group \"%s\": %s", sender.Name, groupKPI.Name, err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	if err != nil {
+		logger.Errorfln("Unable to join group: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+	defer kpi.Wipe()
+
+	err = keypairs.GlobalKeyPairStore.SaveKeyPairStoreToOrigin(nil)
+	if err != nil {
+		logger.Errorfln("Unable to join group: keypair store could not update the file: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	if added {
+		logger.Printfln("Joined group \"%s\" at epoch %d and keypair store file changes committed.", kpi.Name, kpi.GroupEpoch)
+	} else {
+		logger.Printfln("Group \"%s\" updated to epoch %d and keypair store file changes committed.", kpi.Name, kpi.GroupEpoch)
+	}
+}
+
+// readGroupKeyBundle opens a key distribution bundle in memory and returns the group keys it holds
+func readGroupKeyBundle(bundleFilePath string, sender *security.Entity) (*security.KeyPairInfo, error) {
+	receiverKPI := keypairs.GlobalKeyPairStore.GetKeyPairInfo(localGroupJoinCommandVals.toName)
+	if receiverKPI == nil {
+		return nil, fmt.Errorf("no keypair exists by the name \"%s\"", localGroupJoinCommandVals.toName)
+	}
+	defer receiverKPI.Wipe()
+
+	cipherReader, err := cipherio.NewCipherFileReader(receiverKPI, sender.PublicKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer cipherReader.Wipe()
+
+	ekiBytes, err := cipherReader.ReadCombinedFileToBytes(bundleFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer security.Wipe(ekiBytes)
+
+	eki, err := security.NewExportKeyInfoFromBytes(ekiBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	groupKPI, err := eki.GroupKeyPairInfo()
+	security.Wipe(eki.CipherSeed)
+	security.Wipe(eki.SigningSeed)
+	return groupKPI, err
+}
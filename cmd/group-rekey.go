@@ -0,0 +1,57 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/logger"
+)
+
+// groupRekeyCmd represents the group rekey command
+var groupRekeyCmd = &cobra.Command{
+	Use:   "rekey <group>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Moves a group identity to a new epoch and distributes the new keys to its members",
+	Long: `Moves a group identity managed by this profile to a new epoch with new keys, without changing its members.
+This is useful if the group keys may have been exposed.  The new keys are distributed to all members, and a
+succession statement is emitted for contacts that send bundles to the group.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		rekeyGroupWithMembers(args[0])
+	},
+}
+
+func init() {
+	groupCmd.AddCommand(groupRekeyCmd)
+	registerGroupDistributionFlags(groupRekeyCmd)
+	registerPublicStatementOutputFlags(groupRekeyCmd)
+}
+
+func rekeyGroupWithMembers(groupName string) {
+	kpi, err := getManagedGroupKeyPair(groupName)
+	if err != nil {
+		logger.Errorfln("Unable to rekey group: %s", err)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+	defer kpi.Wipe()
+
+	rekeyGroup(kpi.Name, kpi.GroupMembers)
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/logger"
+)
+
+// groupRemoveMemberCmd represents the group remove-member command
+var groupRemoveMemberCmd = &cobra.Command{
+	Use:   "remove-member <group> <user> [user...]",
+	Args:  cobra.MinimumNArgs(2),
+	Short: "Removes members from a group identity, which moves the group to a new epoch",
+	Long: `Removes members from a group identity managed by this profile.  The group moves to a new epoch with new
+keys, which are distributed to the remaining members.  Removed members keep the keys of prior epochs, so they can
+still open bundles sent to the group before they were removed, but not after.
+A succession statement is emitted for contacts that send bundles to the group.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		removeGroupMembers(args[0], args[1:])
+	},
+}
+
+func init() {
+	groupCmd.AddCommand(groupRemoveMemberCmd)
+	registerGroupDistributionFlags(groupRemoveMemberCmd)
+	registerPublicStatementOutputFlags(groupRemoveMemberCmd)
+}
+
+func removeGroupMembers(groupName string, memberNames []string) {
+	kpi, err := getManagedGroupKeyPair(groupName)
+	if err != nil {
+		logger.Errorfln("Unable to remove members: %s", err)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+	defer kpi.Wipe()
+
+	for _, memberName := range memberNames {
+		if !kpi.HasGroupMember(memberName) {
+			logger.Errorfln("Unable to remove members: \"%s\" is not a member of group \"%s\"", memberName, kpi.Name)
+			helpers.ExitCode = helpers.ExitCodeInvalidInput
+			return
+		}
+	}
+
+	rekeyGroup(kpi.Name, mergeMemberNames(kpi.GroupMembers, nil, memberNames))
+}
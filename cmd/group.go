@@ -0,0 +1,307 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	cipherio "github.com/thoughtrealm/bumblebee/cipher/io"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/logger"
+	"github.com/thoughtrealm/bumblebee/security"
+	"path/filepath"
+	"strings"
+)
+
+type groupCommandVals struct {
+	fromName   string
+	outputPath string
+}
+
+// sharedGroupCommandVals holds the key distribution flags shared by the group subcommands
+var sharedGroupCommandVals = &groupCommandVals{}
+
+// groupCmd represents the group command
+var groupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "Manages shared group identities and distributes their keys to members",
+	Long: `Manages shared group identities.  A group identity is a keypair whose keys are shared with its members,
+so anyone can send a bundle to the group and each member can open it.  The keys are distributed to each
+member in a bundle.  Whenever the membership changes, the group moves to a new epoch with new keys, so removed
+members can not open bundles sent after they were removed, and new members can not open earlier bundles.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		_ = cmd.Help()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(groupCmd)
+}
+
+// registerGroupDistributionFlags adds the flags for writing the key distribution bundles
+func registerGroupDistributionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&sharedGroupCommandVals.fromName, "from", "r", "default", "The name of the keypair to send the key distribution bundles from.")
+	cmd.Flags().StringVarP(&sharedGroupCommandVals.outputPath, "output-path", "p", "", "The path to write the key distribution bundles to. Defaults to the current directory.")
+}
+
+// getGroupMemberEntities returns the keystore users for the member names.  Members must exist and must not be revoked.
+func getGroupMemberEntities(memberNames []string) (members []*security.Entity, err error) {
+	if keystore.GlobalKeyStore == nil {
+		return nil, errors.New("keystore is not loaded")
+	}
+
+	for _, memberName := range memberNames {
+		entity := keystore.GlobalKeyStore.GetKey(memberName)
+		if entity == nil {
+			return nil, fmt.Errorf("user \"%s\" was not found in the keystore", memberName)
+		}
+
+		if entity.Trust == security.TrustLevelRevoked {
+			return nil, fmt.Errorf("user \"%s\" has been revoked", entity.Name)
+		}
+
+		members = append(members, entity)
+	}
+
+	return members, nil
+}
+
+// getManagedGroupKeyPair returns the group identity, which must be managed by this profile
+func getManagedGroupKeyPair(groupName string) (*security.KeyPairInfo, error) {
+	if keypairs.GlobalKeyPairStore == nil {
+		return nil, errors.New("keypair store not loaded")
+	}
+
+	kpi := keypairs.GlobalKeyPairStore.GetKeyPairInfo(groupName)
+	if kpi == nil {
+		return nil, fmt.Errorf("no keypair exists by the name \"%s\"", groupName)
+	}
+
+	if !kpi.IsGroup() {
+		kpi.Wipe()
+		return nil, fmt.Errorf("keypair \"%s\" is not a group identity", groupName)
+	}
+
+	if !kpi.GroupManaged {
+		kpi.Wipe()
+		return nil, fmt.Errorf("group \"%s\" is not managed by this profile", groupName)
+	}
+
+	return kpi, nil
+}
+
+// rekeyGroup moves the group to a new epoch with the provided members.  A succession statement is emitted for
+// contacts that send to the group, then the new keys are distributed to the members.
+func rekeyGroup(groupName string, memberNames []string) {
+	members, err := getGroupMemberEntities(memberNames)
+	if err != nil {
+		logger.Errorfln("Unable to rekey group: %s", err)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	oldKPI, newKPI, err := keypairs.GlobalKeyPairStore.RekeyGroup(groupName, entityNames(members))
+	if err != nil {
+		logger.Errorfln("Unable to rekey group: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+	defer oldKPI.Wipe()
+	defer newKPI.Wipe()
+
+	statement, err := security.NewSuccessionStatement(newKPI.Name, oldKPI, newKPI)
+	if err != nil {
+		logger.Errorfln("Unable to create succession statement: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	err = keypairs.GlobalKeyPairStore.SaveKeyPairStoreToOrigin(nil)
+	if err != nil {
+		logger.Errorfln("Unable to rekey group: keypair store could not update the file: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	logger.Printfln("Group \"%s\" moved from epoch %d to epoch %d and keypair store file changes committed.",
+		newKPI.Name, oldKPI.GroupEpoch, newKPI.GroupEpoch)
+	logger.Printfln("New Fingerprint : %s", statement.NewKeyInfo().Fingerprint().Hex())
+	logger.Println("")
+
+	// A local user for the group, used to send to it, is moved to the new keys as well
+	if keystore.GlobalKeyStore != nil {
+		userName, err := keystore.GlobalKeyStore.ApplyKeySuccession(statement)
+		if err != nil && !errors.Is(err, keystore.ErrNoSuccessionMatch) {
+			logger.Errorfln("Unable to update local user \"%s\" with the new keys: %s", userName, err)
+		} else if err == nil {
+			logger.Printfln("Local user \"%s\" updated with the new keys.", userName)
+			logger.Println("")
+		}
+	}
+
+	err = distributeGroupKey(newKPI, members)
+	if err != nil {
+		logger.Errorfln("Unable to distribute group keys: %s", err)
+		helpers.ExitCode = helpers.ExitCodeOutputError
+		return
+	}
+
+	eki, err := security.NewExportKeyInfoFromSuccession(statement)
+	if err == nil {
+		logger.Println("")
+		logger.Println("Send the following succession statement to contacts that send bundles to the group.")
+		err = exportPublicStatement(eki, "succession")
+	}
+
+	if err != nil {
+		logger.Errorfln("Unable to export succession statement: %s", err)
+		helpers.ExitCode = helpers.ExitCodeOutputError
+		return
+	}
+}
+
+func entityNames(entities []*security.Entity) []string {
+	names := make([]string, 0, len(entities))
+	for _, entity := range entities {
+		names = append(names, entity.Name)
+	}
+
+	return names
+}
+
+// distributeGroupKey writes a bundle with the current epoch keys of the group to each member.  Members that are
+// refused by the trust policy are skipped.  An error is returned if a bundle could not be written for any member.
+func distributeGroupKey(groupKPI *security.KeyPairInfo, members []*security.Entity) error {
+	if len(members) == 0 {
+		logger.Println("The group has no members, so no key distribution bundles were written.")
+		return nil
+	}
+
+	senderKPI := keypairs.GlobalKeyPairStore.GetKeyPairInfo(sharedGroupCommandVals.fromName)
+	if senderKPI == nil {
+		return fmt.Errorf("no keypair exists by the name \"%s\"", sharedGroupCommandVals.fromName)
+	}
+	defer senderKPI.Wipe()
+
+	if senderKPI.IsGroup() {
+		return fmt.Errorf("key distribution bundles can not be sent from the group identity \"%s\"", senderKPI.Name)
+	}
+
+	eki, err := security.NewExportKeyInfoFromGroupKey(groupKPI)
+	if err != nil {
+		return err
+	}
+
+	ekiBytes, err := eki.ToBytes()
+	if err != nil {
+		return fmt.Errorf("unable to serialize group key: %w", err)
+	}
+	defer security.Wipe(ekiBytes)
+
+	results := make([]*groupBundleResult, 0, len(members))
+	for _, member := range members {
+		result := &groupBundleResult{memberName: member.Name}
+		results = append(results, result)
+
+		trustErr := checkEntityTrust(member, "member")
+		if trustErr != nil {
+			result.skipReason = trustErr.Error()
+			continue
+		}
+
+		result.outputFile = getGroupKeyOutputFile(groupKPI, member.Name)
+		result.bytesWritten, result.err = writeGroupKeyBundle(groupKPI, member, senderKPI, ekiBytes, result.outputFile)
+	}
+
+	return printGroupKeyReport(groupKPI, results)
+}
+
+// writeGroupKeyBundle writes one key distribution bundle to the member
+func writeGroupKeyBundle(groupKPI *security.KeyPairInfo, member *security.Entity, senderKPI *security.KeyPairInfo, ekiBytes []byte, outputFile string) (int, error) {
+	cipherWriter, err := cipherio.NewCipherWriter(member.PublicKeys, senderKPI)
+	if err != nil {
+		return 0, fmt.Errorf("unable to create cipher writer: %w", err)
+	}
+	defer cipherWriter.Wipe()
+
+	cipherWriter.OutputBundleInfo.InputSource = cipherio.BundleInputSourceDirect
+	cipherWriter.OutputBundleInfo.OriginalFileName = fmt.Sprintf("%s.epoch%d.groupkey", groupKPI.Name, groupKPI.GroupEpoch)
+	return cipherWriter.WriteToCombinedFileFromReader(outputFile, bytes.NewReader(ekiBytes))
+}
+
+// getGroupKeyOutputFile returns the file name for a member's key distribution bundle, such as "team.bob.epoch2.bcomb"
+func getGroupKeyOutputFile(groupKPI *security.KeyPairInfo, memberName string) string {
+	fileName := fmt.Sprintf("%s.%s.epoch%d.bcomb",
+		helpers.GetFileSafeName(groupKPI.Name), helpers.GetFileSafeName(memberName), groupKPI.GroupEpoch)
+
+	return filepath.Join(sharedGroupCommandVals.outputPath, fileName)
+}
+
+// printGroupKeyReport prints the outcome for each member.  An error is returned if any bundle failed.
+func printGroupKeyReport(groupKPI *security.KeyPairInfo, results []*groupBundleResult) error {
+	writtenCount := 0
+	failedCount := 0
+
+	fmt.Printf("Key Distribution for Group %s, Epoch %d\n", groupKPI.Name, groupKPI.GroupEpoch)
+	fmt.Println("=========================================================")
+	for _, result := range results {
+		switch {
+		case result.skipReason != "":
+			fmt.Printf("%-18s : SKIPPED  %s\n", result.memberName, result.skipReason)
+		case result.err != nil:
+			failedCount++
+			fmt.Printf("%-18s : FAILED   %s\n", result.memberName, result.err)
+		default:
+			writtenCount++
+			fmt.Printf("%-18s : WRITTEN  %s (%d bytes)\n", result.memberName, result.outputFile, result.bytesWritten)
+		}
+	}
+	fmt.Println("")
+
+	fmt.Printf("Key bundles written for %d of %d member(s).  Members join with \"group join <bundle> --from <your user name>\".\n",
+		writtenCount, len(results))
+	if writtenCount < len(results) {
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+	}
+
+	if failedCount > 0 {
+		return fmt.Errorf("key bundles failed for %d member(s)", failedCount)
+	}
+
+	return nil
+}
+
+// mergeMemberNames returns the current members with the added names, less the removed names
+func mergeMemberNames(current, add, remove []string) []string {
+	var merged []string
+	for _, name := range append(append([]string{}, current...), add...) {
+		removed := false
+		for _, removeName := range remove {
+			if strings.EqualFold(name, removeName) {
+				removed = true
+				break
+			}
+		}
+
+		if !removed {
+			merged = append(merged, name)
+		}
+	}
+
+	return merged
+}
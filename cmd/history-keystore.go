@@ -0,0 +1,97 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/journal"
+)
+
+// historyKeystoreCmd represents the keystore subcommand for the history command
+var historyKeystoreCmd = &cobra.Command{
+	Use:   "keystore",
+	Args:  cobra.NoArgs,
+	Short: "Displays the changes made to the keystore and keypairs of the current profile",
+	Long: "Displays the changes made to the keystore and keypairs of the current profile, from the profile's " +
+		"keystore journal.  Changes that have not been undone can be reverted with the undo command.",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		showKeystoreHistory()
+	},
+}
+
+func init() {
+	historyCmd.AddCommand(historyKeystoreCmd)
+}
+
+func showKeystoreHistory() {
+	profile := helpers.GlobalConfig.GetCurrentProfile()
+	if profile == nil {
+		fmt.Println("Unable to retrieve current profile config")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	profileJournal, err := journal.ReadFromFile(profile.JournalPath())
+	if err != nil {
+		fmt.Printf("Unable to load the keystore journal: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	if profileJournal.Count() == 0 {
+		fmt.Println("No keystore changes have been recorded")
+		return
+	}
+
+	fmt.Println("")
+	fmt.Printf("Using profile   : %s\n", profile.Name)
+	fmt.Printf("Journal Entries : %d\n", profileJournal.Count())
+	fmt.Println("======================================================")
+
+	profileJournal.Walk(func(entry *journal.Entry, undone bool) {
+		fmt.Printf("Entry              : %d\n", entry.Sequence)
+		fmt.Printf("Date               : %s\n", entry.Date)
+		fmt.Printf("Operation          : %s\n", entry.Operation)
+		if entry.UndoOf != 0 {
+			fmt.Printf("Undid Entry        : %d\n", entry.UndoOf)
+		}
+
+		for _, change := range entry.Changes {
+			fmt.Printf("Change             : %s\n", change.Describe())
+			if change.PreviousKeyPair != nil {
+				fmt.Printf("Prior Signing Key  : %s\n", change.PreviousKeyPair.SigningPubKey)
+			}
+		}
+
+		switch {
+		case entry.Operation == journal.OperationUndo:
+		case undone:
+			fmt.Println("Status             : Undone")
+		case entry.NotUndoable:
+			fmt.Println("Status             : Cannot be undone")
+		default:
+			fmt.Println("Status             : Active")
+		}
+
+		fmt.Println("")
+	})
+}
@@ -0,0 +1,78 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/ledger"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Displays the bundles opened by the current profile",
+	Long:  "Displays the bundles opened by the current profile, from the profile's opened bundles ledger",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		showOpenedBundlesHistory()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+}
+
+func showOpenedBundlesHistory() {
+	profile := helpers.GlobalConfig.GetCurrentProfile()
+	if profile == nil {
+		fmt.Println("Unable to retrieve current profile config")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	openedLedger, err := ledger.ReadFromFile(profile.OpenedLedgerPath())
+	if err != nil {
+		fmt.Printf("Unable to load the opened bundles ledger: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	if openedLedger.Count() == 0 {
+		fmt.Println("No bundles have been opened")
+		return
+	}
+
+	fmt.Println("")
+	fmt.Printf("Using profile  : %s\n", profile.Name)
+	fmt.Printf("Bundles Opened : %d\n", openedLedger.Count())
+	fmt.Println("======================================================")
+
+	openedLedger.Walk(func(entry *ledger.OpenedBundle) {
+		fmt.Printf("Bundle ID          : %s\n", entry.BundleID)
+		fmt.Printf("From Name          : %s\n", entry.SenderName)
+		fmt.Printf("To Name            : %s\n", entry.ToName)
+		fmt.Printf("Date Created       : %s\n", entry.CreateDate)
+		fmt.Printf("First Opened       : %s\n", entry.FirstOpenedDate)
+		fmt.Printf("Last Opened        : %s\n", entry.LastOpenedDate)
+		fmt.Printf("Open Count         : %d\n", entry.OpenCount)
+		fmt.Println("")
+	})
+}
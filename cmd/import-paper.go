@@ -0,0 +1,92 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/logger"
+	"strings"
+)
+
+// getImportPaperConsoleInput reads a paper key typed in at the console.  Each line is verified as it is entered,
+// and a line that fails is asked for again.
+func getImportPaperConsoleInput() error {
+	logger.Println("Type in each line of the paper key, including the line number at the start and the checksum")
+	logger.Println("at the end.  Spaces and case are not significant.  Enter an empty line to cancel.")
+	logger.Println("")
+
+	var lines []*helpers.PaperKeyLine
+	for {
+		prompt := "Line 1"
+		if len(lines) > 0 {
+			missing := helpers.MissingPaperKeyLines(lines)
+			if len(missing) == 0 {
+				break
+			}
+
+			prompt = fmt.Sprintf("Line %d of %d", missing[0], lines[0].Total)
+		}
+
+		lineText, err := helpers.GetConsoleInputLine(prompt)
+		if err != nil {
+			logger.Errorfln("Unable to read paper key line: %s", err)
+			return err
+		}
+
+		if strings.TrimSpace(lineText) == "" {
+			logger.Errorln("Paper key input canceled")
+			return errors.New("paper key input canceled")
+		}
+
+		line, err := helpers.ParsePaperKeyLine(lineText)
+		if err == nil && len(lines) > 0 && line.Total != lines[0].Total {
+			err = fmt.Errorf("line %d is from a paper key with %d lines, but this paper key has %d lines", line.Number, line.Total, lines[0].Total)
+		}
+
+		if err != nil {
+			logger.Errorfln("%s.  Please enter the line again.", err)
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	data, err := helpers.DecodePaperKeyLines(lines)
+	if err != nil {
+		logger.Errorfln("Unable to read paper key: %s", err)
+		return err
+	}
+
+	logger.Println("")
+	sharedImportCommandVals.importedBytes = data
+	return nil
+}
+
+// decodePaperKeyInput reads a paper key from a file, clipboard or piped input.  Every line that fails
+// verification is printed.
+func decodePaperKeyInput(inputBytes []byte) ([]byte, error) {
+	data, err := helpers.DecodePaperKey(inputBytes)
+	if err != nil {
+		logger.Errorln("Unable to read paper key...")
+		for _, errLine := range strings.Split(err.Error(), "\n") {
+			logger.Errorfln("  %s", errLine)
+		}
+
+		return nil, fmt.Errorf("failed reading paper key: %w", err)
+	}
+
+	return data, nil
+}
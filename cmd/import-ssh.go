@@ -0,0 +1,158 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/logger"
+	"github.com/thoughtrealm/bumblebee/security"
+	"strings"
+)
+
+// importFormattedItem handles "import --format", for input that is not a bumblebee export.  User lists are
+// imported the same as "import users", using its skip policy.
+func importFormattedItem() {
+	if inputsAreOk := validateImportInputs(); !inputsAreOk {
+		// validateImportInputs() will have already printed error messages as needed
+		return
+	}
+
+	localImportUsersCommandVals.inputSourceText = sharedImportCommandVals.inputSourceText
+	localImportUsersCommandVals.inputFilePath = sharedImportCommandVals.inputFilePath
+	localImportUsersCommandVals.formatText = sharedImportCommandVals.formatText
+	localImportUsersCommandVals.nameOverride = sharedImportCommandVals.nameOverride
+	localImportUsersCommandVals.confirmKeyChange = sharedImportCommandVals.confirmKeyChange
+	localImportUsersCommandVals.dryRun = sharedImportCommandVals.detailsOnly
+
+	if strings.EqualFold(strings.TrimSpace(sharedImportCommandVals.formatText), "openssh") {
+		importSSHKeyPair()
+		return
+	}
+
+	switch keystore.TextToUserListFormat(sharedImportCommandVals.formatText) {
+	case keystore.UserListFormatVCard:
+		importVCards()
+		return
+	case keystore.UserListFormatUnknown:
+		logger.Errorfln("Unknown import format: %s", sharedImportCommandVals.formatText)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	importUsers()
+}
+
+// importSSHKeyPair stores an OpenSSH ed25519 private key as a keypair, so that bundles sent to the SSH
+// identity's public key can be opened.
+func importSSHKeyPair() {
+	if keypairs.GlobalKeyPairStore == nil {
+		logger.Errorln("Unable to import keypair: keypair store not loaded")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	inputBytes, err := readImportUsersInput()
+	if err != nil {
+		logger.Errorfln("Unable to read input: %s", err)
+		helpers.ExitCode = helpers.ExitCodeInputError
+		return
+	}
+	defer security.Wipe(inputBytes)
+
+	sshPrivateKey, err := security.ParseSSHPrivateKey(inputBytes)
+	if err != nil {
+		logger.Errorfln("Unable to read the SSH private key: %s", err)
+		helpers.ExitCode = helpers.ExitCodeInputError
+		return
+	}
+	defer sshPrivateKey.Wipe()
+
+	keypairName := sharedImportCommandVals.nameOverride
+	if keypairName == "" && sshPrivateKey.PublicKey.Comment != "" {
+		keypairName = keystore.SSHCommentToUserName(sshPrivateKey.PublicKey.Comment, 1)
+	}
+
+	if keypairName == "" {
+		logger.Errorln("The SSH key has no comment to name the keypair.  Provide a name with --name.")
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	kpi, err := sshPrivateKey.KeyPairInfo(keypairName)
+	if err != nil {
+		logger.Errorfln("Unable to convert the SSH private key: %s", err)
+		helpers.ExitCode = helpers.ExitCodeCipherError
+		return
+	}
+	defer kpi.Wipe()
+
+	logger.Printfln("Input type        : OpenSSH Private Key")
+	logger.Printfln("SSH Key           : %s", sshPrivateKey.PublicKey.SourceDetails())
+	logger.Printfln("Keypair Name      : %s", keypairName)
+	logger.Println("")
+
+	if sharedImportCommandVals.detailsOnly {
+		return
+	}
+
+	if keypairs.GlobalKeyPairStore.GetKeyPairInfo(keypairName) != nil {
+		logger.Errorfln("A keypair already exists by the name \"%s\".  Provide a different name with --name.", keypairName)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	if !sharedImportCommandVals.ignoreConfirm {
+		response, err := helpers.GetYesNoInput(
+			fmt.Sprintf("Import the SSH key as keypair \"%s\"? ", keypairName),
+			helpers.InputResponseValNo)
+		logger.Println("")
+
+		if err != nil {
+			logger.Errorfln("Error getting confirmation to import the keypair: %s", err)
+			helpers.ExitCode = helpers.ExitCodeRequestFailed
+			return
+		}
+
+		if response == helpers.InputResponseValNo {
+			logger.Errorln("User declined import")
+			helpers.ExitCode = helpers.ExitCodeRequestFailed
+			return
+		}
+	}
+
+	err = keypairs.GlobalKeyPairStore.ImportKeyPair(kpi)
+	if err != nil {
+		logger.Errorfln("Unable to import keypair: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	err = keypairs.GlobalKeyPairStore.SaveKeyPairStoreToOrigin(nil)
+	if err != nil {
+		logger.Errorfln("Unable to save keypair store: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	err = kpi.Print("Imported Key Pair Info", false)
+	if err != nil {
+		logger.Errorfln("Unable to print keypair info: %s", err)
+	}
+
+	logger.Println("")
+	logger.Printfln("Bundles sent to this SSH key can be opened with: bumblebee open --to %s", keypairName)
+}
@@ -0,0 +1,366 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	cipherio "github.com/thoughtrealm/bumblebee/cipher/io"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/logger"
+	"github.com/thoughtrealm/bumblebee/security"
+	"os"
+	"path/filepath"
+)
+
+type importUsersCommandVals struct {
+	inputSourceText  string
+	inputFilePath    string
+	formatText       string
+	policyText       string
+	fromName         string
+	nameOverride     string
+	dryRun           bool
+	confirmKeyChange bool
+}
+
+var localImportUsersCommandVals = &importUsersCommandVals{}
+
+// importUsersCmd represents the import users command
+var importUsersCmd = &cobra.Command{
+	Use:   "users --input-file <file> [--format csv|json|yaml|jwks|vcard|authorized_keys] [--policy skip|merge|overwrite] [--dry-run]",
+	Short: "Imports a list of users from a CSV, JSON, YAML or authorized_keys file",
+	Long: `Imports a list of users from a CSV, JSON or YAML file, such as one written by "export users".
+OpenSSH authorized_keys files, vCard files and JWK sets, such as one written by "export users --format jwks",
+are also supported.  Each ssh-ed25519 key is converted to a signing key, with the
+matching X25519 cipher key, and the user name is taken from the key comment.
+Each user's public keys are validated, as is the fingerprint if one is provided.  Duplicate users in the file,
+and keys that already belong to another stored user, are reported and not imported.
+
+The policy decides how users that already exist are handled...
+  skip      : Existing users are left unchanged.  This is the default.
+  merge     : Contact details are added to existing users with the same keys.  Key changes are reported as conflicts.
+  overwrite : The keys and contact details of existing users are replaced.  Key changes show the old and new
+              fingerprints and require confirmation, unless --confirm-key-change is provided.
+
+Use --dry-run to see the report without changing the keystore.  If the list was signed with "export users --sign-with",
+provide the signer with --from, and the signature is verified before anything is imported.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		importUsers()
+	},
+}
+
+func init() {
+	importCmd.AddCommand(importUsersCmd)
+	importUsersCmd.Flags().StringVarP(&localImportUsersCommandVals.inputSourceText, "input-source", "t", "", "The input source.  Should be one of: pipe, clipboard or file.")
+	importUsersCmd.Flags().StringVarP(&localImportUsersCommandVals.inputFilePath, "input-file", "f", "", "The file name to use for input. Only relevant if input-source is FILE.")
+	importUsersCmd.Flags().StringVarP(&localImportUsersCommandVals.formatText, "format", "", "", "The format of the user list.  Should be one of: csv, json, yaml, jwks, vcard or authorized_keys.\nDefaults to the input file extension.")
+	importUsersCmd.Flags().StringVarP(&localImportUsersCommandVals.policyText, "policy", "", "skip", "How users that already exist are handled.  Should be one of: skip, merge or overwrite.")
+	importUsersCmd.Flags().StringVarP(&localImportUsersCommandVals.fromName, "from", "r", "", "The user that signed the list.  Required for signed lists.")
+	importUsersCmd.Flags().StringVarP(&localImportUsersCommandVals.nameOverride, "name", "n", "", "Overrides the user name.  Only relevant if the list holds a single user.")
+	importUsersCmd.Flags().BoolVarP(&localImportUsersCommandVals.dryRun, "dry-run", "", false, "If set, the import report is displayed, but the keystore is not changed.")
+	importUsersCmd.Flags().BoolVarP(&localImportUsersCommandVals.confirmKeyChange, "confirm-key-change", "", false, "If set, key changes for existing users are accepted without prompting.\nThe old and new fingerprints are still displayed.")
+}
+
+func importUsers() {
+	if keystore.GlobalKeyStore == nil {
+		logger.Errorln("Unable to import users: keystore not loaded")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	policy := keystore.TextToUserImportPolicy(localImportUsersCommandVals.policyText)
+	if policy == keystore.UserImportPolicyUnknown {
+		logger.Errorfln("Unknown import policy: %s", localImportUsersCommandVals.policyText)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	format := getImportUsersFormat()
+	if format == keystore.UserListFormatUnknown {
+		logger.Errorln("Unable to determine the user list format.  Provide --format with one of: csv, json, yaml, jwks, vcard or authorized_keys.")
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	inputBytes, err := readImportUsersInput()
+	if err != nil {
+		logger.Errorfln("Unable to read input: %s", err)
+		helpers.ExitCode = helpers.ExitCodeInputError
+		return
+	}
+
+	listBytes, err := getVerifiedUserListBytes(inputBytes)
+	if err != nil {
+		logger.Errorfln("Unable to import users: %s", err)
+		helpers.ExitCode = helpers.ExitCodeCipherError
+		return
+	}
+
+	records, err := keystore.DecodeUserRecords(listBytes, format)
+	if err != nil {
+		logger.Errorfln("Unable to import users: %s", err)
+		helpers.ExitCode = helpers.ExitCodeInputError
+		return
+	}
+
+	importUserRecords(records, policy)
+}
+
+// importUserRecords plans and applies the import of the records, then prints the report
+func importUserRecords(records []*keystore.UserRecord, policy keystore.UserImportPolicy) {
+	if len(records) == 0 {
+		logger.Errorln("No users were found in the input")
+		helpers.ExitCode = helpers.ExitCodeInputError
+		return
+	}
+
+	if localImportUsersCommandVals.nameOverride != "" {
+		if len(records) != 1 {
+			logger.Errorfln("A name can only be provided when the input holds a single user, but it holds %d", len(records))
+			helpers.ExitCode = helpers.ExitCodeInvalidInput
+			return
+		}
+
+		records[0].Name = localImportUsersCommandVals.nameOverride
+	}
+
+	items := keystore.PlanUserImport(keystore.GlobalKeyStore, records, policy)
+	if !localImportUsersCommandVals.dryRun {
+		for _, item := range items {
+			applyUserImportItem(item)
+		}
+	}
+
+	printUserImportReport(items)
+}
+
+// getImportUsersFormat returns the format from --format, or from the input file extension
+func getImportUsersFormat() keystore.UserListFormat {
+	if localImportUsersCommandVals.formatText != "" {
+		return keystore.TextToUserListFormat(localImportUsersCommandVals.formatText)
+	}
+
+	if filepath.Base(localImportUsersCommandVals.inputFilePath) == "authorized_keys" {
+		return keystore.UserListFormatAuthorizedKeys
+	}
+
+	ext := filepath.Ext(localImportUsersCommandVals.inputFilePath)
+	if ext == ".pub" {
+		return keystore.UserListFormatAuthorizedKeys
+	}
+
+	if len(ext) > 1 {
+		return keystore.TextToUserListFormat(ext[1:])
+	}
+
+	return keystore.UserListFormatUnknown
+}
+
+func readImportUsersInput() ([]byte, error) {
+	inputSourceText := localImportUsersCommandVals.inputSourceText
+	if inputSourceText == "" && localImportUsersCommandVals.inputFilePath != "" {
+		inputSourceText = "file"
+	}
+
+	if inputSourceText == "" && helpers.CheckIsPiped() {
+		inputSourceText = "piped"
+	}
+
+	switch helpers.TextToImportInputSource(inputSourceText) {
+	case helpers.ImportInputSourceFile:
+		if localImportUsersCommandVals.inputFilePath == "" {
+			return nil, errors.New("input source is FILE and no input path is provided")
+		}
+
+		return os.ReadFile(localImportUsersCommandVals.inputFilePath)
+	case helpers.ImportInputSourceClipboard:
+		return helpers.ReadFromClipboard()
+	case helpers.ImportInputSourcePiped:
+		pipeBuffer := bytes.NewBuffer(nil)
+		_, err := pipeBuffer.ReadFrom(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read piped input from stdin: %w", err)
+		}
+
+		return pipeBuffer.Bytes(), nil
+	}
+
+	return nil, errors.New("no input file or input source is provided")
+}
+
+// getVerifiedUserListBytes returns the user list from the input.  A signed list is verified against the --from
+// user, and its payload is returned.  An unsigned list is refused if --from was provided.
+func getVerifiedUserListBytes(inputBytes []byte) ([]byte, error) {
+	if !cipherio.IsSignedMessage(inputBytes) {
+		if localImportUsersCommandVals.fromName != "" {
+			return nil, fmt.Errorf("the user list is not signed, so it can not be verified against \"%s\"", localImportUsersCommandVals.fromName)
+		}
+
+		return inputBytes, nil
+	}
+
+	if localImportUsersCommandVals.fromName == "" {
+		return nil, errors.New("the user list is signed.  Provide the signer with --from to verify it")
+	}
+
+	signer := keystore.GlobalKeyStore.GetKey(localImportUsersCommandVals.fromName)
+	if signer == nil {
+		return nil, fmt.Errorf("signer not located for name \"%s\"", localImportUsersCommandVals.fromName)
+	}
+
+	signedMessage, signerKI, err := parseAndVerifySignedMessage(inputBytes, signer.PublicKeys, signer.PriorKeyInfos())
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkSenderRevocation(signer, signedMessage.Info.CreateDate)
+	if err == nil {
+		err = checkSenderKeyHistory(signer, signedMessage.Info.CreateDate, signerKI.SigningPubKey)
+	}
+
+	if err == nil {
+		err = checkEntityTrust(signer, "signer")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Printfln("User list signature is VALID for signer \"%s\", signed %s", signer.Name, signedMessage.Info.CreateDate)
+	logger.Println("")
+	return signedMessage.Payload, nil
+}
+
+// applyUserImportItem makes the planned change to the keystore.  Failures and declined key changes are recorded
+// in the item, so they are included in the report.
+func applyUserImportItem(item *keystore.UserImportItem) {
+	record := item.Record
+	var err error
+	switch item.Action {
+	case keystore.UserImportActionAdd:
+		entity := record.Entity()
+		entity.KeySource, entity.KeySourceDetails = getImportUsersKeySource(record)
+		err = keystore.GlobalKeyStore.AddKeyWithDetails(entity)
+	case keystore.UserImportActionMerge:
+		_, err = keystore.GlobalKeyStore.UpdateContactInfo(item.Existing.Name, item.Contact)
+	case keystore.UserImportActionOverwrite:
+		if item.KeysChanged {
+			var confirmed bool
+			confirmed, err = confirmKeyChange(item.Existing, record.CipherPubKey, record.SigningPubKey, localImportUsersCommandVals.confirmKeyChange)
+			if err == nil && !confirmed {
+				item.Action = keystore.UserImportActionSkip
+				item.Reason = "key change declined"
+				return
+			}
+
+			if err == nil {
+				keySource, keySourceDetails := getImportUsersKeySource(record)
+				_, err = keystore.GlobalKeyStore.UpdatePublicKeysWithSource(
+					item.Existing.Name,
+					record.CipherPubKey,
+					record.SigningPubKey,
+					keySource,
+					keySourceDetails)
+			}
+		}
+
+		if err == nil {
+			_, err = keystore.GlobalKeyStore.UpdateContactInfo(item.Existing.Name, item.Contact)
+		}
+	default:
+		return
+	}
+
+	if err != nil {
+		item.Action = keystore.UserImportActionConflict
+		item.Reason = fmt.Sprintf("failed: %s", err)
+	}
+}
+
+// getImportUsersKeySource returns the key source for a record.  Keys derived from SSH keys keep the SSH key
+// details, so the entity is marked as SSH-derived.
+func getImportUsersKeySource(record *keystore.UserRecord) (security.KeySource, string) {
+	if record.KeySource != security.KeySourceUnknown {
+		return record.KeySource, record.KeySourceDetails
+	}
+
+	return security.KeySourceImport, getImportUsersSourceDetails()
+}
+
+func getImportUsersSourceDetails() string {
+	if localImportUsersCommandVals.inputFilePath == "" {
+		return "bulk import"
+	}
+
+	filePath, err := filepath.Abs(localImportUsersCommandVals.inputFilePath)
+	if err != nil {
+		filePath = localImportUsersCommandVals.inputFilePath
+	}
+
+	return "bulk import file: " + filePath
+}
+
+// printUserImportReport lists the outcome for each record, then the totals for each action
+func printUserImportReport(items []*keystore.UserImportItem) {
+	counts := map[keystore.UserImportAction]int{}
+	errorCount := 0
+
+	fmt.Println("")
+	if localImportUsersCommandVals.dryRun {
+		fmt.Println("User Import Report (dry run, no changes made)")
+	} else {
+		fmt.Println("User Import Report")
+	}
+	fmt.Println("=========================================================")
+	for _, item := range items {
+		counts[item.Action]++
+		if item.Action.IsError() {
+			errorCount++
+		}
+
+		name := item.Record.Name
+		if name == "" {
+			name = "(no name)"
+		}
+
+		reason := ""
+		if item.Reason != "" {
+			reason = "  " + item.Reason
+		}
+
+		fmt.Printf("Line %-4d %-18s : %-9s%s\n", item.Record.Line, name, keystore.UserImportActionToText(item.Action), reason)
+	}
+	fmt.Println("")
+
+	for action := keystore.UserImportActionAdd; action <= keystore.UserImportActionConflict; action++ {
+		if counts[action] > 0 {
+			fmt.Printf("%-9s : %d\n", keystore.UserImportActionToText(action), counts[action])
+		}
+	}
+
+	if errorCount > 0 {
+		fmt.Printf("\n%d of %d user(s) were not imported due to errors.\n", errorCount, len(items))
+		helpers.ExitCode = helpers.ExitCodeInputError
+	}
+}
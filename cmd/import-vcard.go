@@ -0,0 +1,87 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/logger"
+)
+
+// importVCards imports each card with X-BUMBLEBEE keys.  Each card goes through the same name, update and key
+// change confirmations as a user export, and the card's contact details are stored with the user.
+func importVCards() {
+	if keystore.GlobalKeyStore == nil {
+		logger.Errorln("Unable to import users: keystore not loaded")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	inputBytes, err := readImportUsersInput()
+	if err != nil {
+		logger.Errorfln("Unable to read input: %s", err)
+		helpers.ExitCode = helpers.ExitCodeInputError
+		return
+	}
+
+	records, err := keystore.DecodeUserRecords(inputBytes, keystore.UserListFormatVCard)
+	if err != nil {
+		logger.Errorfln("Unable to read vCard input: %s", err)
+		helpers.ExitCode = helpers.ExitCodeInputError
+		return
+	}
+
+	if len(records) == 0 {
+		logger.Errorln("No cards with bumblebee keys were found in the input")
+		helpers.ExitCode = helpers.ExitCodeInputError
+		return
+	}
+
+	if sharedImportCommandVals.nameOverride != "" && len(records) != 1 {
+		logger.Errorfln("A name can only be provided when the input holds a single card, but it holds %d", len(records))
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	failedCount := 0
+	for _, record := range records {
+		logger.Printfln("Card on line %d: %s", record.Line, record.Name)
+		logger.Println("")
+
+		err = record.Validate()
+		if err != nil {
+			logger.Errorfln("Card on line %d is invalid: %s", record.Line, err)
+			logger.Println("")
+			failedCount++
+			continue
+		}
+
+		err = handleImportedUser(record.Entity().PublicKeys, record.Contact())
+		if err != nil {
+			failedCount++
+		}
+	}
+
+	if failedCount > 0 {
+		logger.Errorfln("%d of %d card(s) were not imported", failedCount, len(records))
+		helpers.ExitCode = helpers.ExitCodeInputError
+		return
+	}
+
+	if sharedImportCommandVals.detailsOnly {
+		logger.Println("Import details complete")
+	} else {
+		logger.Println("Import complete")
+	}
+}
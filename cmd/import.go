@@ -20,28 +20,34 @@ import (
 	"github.com/spf13/cobra"
 	cipherio "github.com/thoughtrealm/bumblebee/cipher/io"
 	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keypairs"
 	"github.com/thoughtrealm/bumblebee/keystore"
 	"github.com/thoughtrealm/bumblebee/logger"
 	"github.com/thoughtrealm/bumblebee/security"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 type importCommandVals struct {
-	inputSourceText string
-	inputSource     helpers.ImportInputSource
-	inputFilePath   string
-	password        string
-	importedBytes   []byte
-	nameOverride    string
-	ignoreConfirm   bool
-	detailsOnly     bool
+	inputSourceText  string
+	inputSource      helpers.ImportInputSource
+	inputFilePath    string
+	password         string
+	importedBytes    []byte
+	nameOverride     string
+	ignoreConfirm    bool
+	confirmKeyChange bool
+	detailsOnly      bool
+	formatText       string
+	paper            bool
 }
 
 var sharedImportCommandVals = &importCommandVals{}
 
 // importCmd represents the import command
 var importCmd = &cobra.Command{
-	Use:   "import [--password] [--input-source] [--input-file]",
+	Use:   "import [--password] [--input-source] [--input-file] [--format] [--paper]",
 	Short: "Imports users and keypairs from files, clipboard or piped input",
 	Long:  "Imports users and keypairs from files, clipboard or piped input",
 	Run: func(cmd *cobra.Command, args []string) {
@@ -61,7 +67,17 @@ func init() {
 	importCmd.Flags().StringVarP(&sharedImportCommandVals.inputFilePath, "input-file", "f", "", "The file name to use for input. Only relevant if input-source is FILE.")
 	importCmd.Flags().StringVarP(&sharedImportCommandVals.nameOverride, "name", "n", "", "Overrides the name in the export package. If not provided,\nuser is prompted for name confirmation before adding to store.")
 	importCmd.Flags().BoolVarP(&sharedImportCommandVals.ignoreConfirm, "ignore-confirm", "i", false, "If set, user will not be prompted to confirm the import")
+	importCmd.Flags().BoolVarP(&sharedImportCommandVals.confirmKeyChange, "confirm-key-change", "", false, "If set, an imported key change for an existing user is accepted without prompting.\nThe old and new fingerprints are still displayed.")
 	importCmd.Flags().BoolVarP(&sharedImportCommandVals.detailsOnly, "details-only", "", false, "If set, the input will not be imported, instead just the details will be displayed.\nThis allows you to validate the file before importing it.")
+	importCmd.Flags().StringVarP(&sharedImportCommandVals.formatText, "format", "", "", `The format of the input, if it is not a bumblebee export.  Should be one of...
+  authorized_keys : An OpenSSH authorized_keys file or ssh-ed25519 public key, imported as users.
+  openssh         : An OpenSSH ed25519 private key file, imported as a keypair.
+  vcard           : A vCard file.  Each card with X-BUMBLEBEE keys is imported with the same
+                    confirmations as a user export.
+  csv, json, yaml : A user list, the same as "import users".`)
+	importCmd.Flags().BoolVarP(&sharedImportCommandVals.paper, "paper", "", false, `The input is a paper key from "export keypair --paper".  Each line's checksum is verified,
+so a mistyped line is reported by its line number.  If no input file or source is provided,
+the lines are typed in at the console.`)
 	importCmd.Flags().StringVarP(&sharedImportCommandVals.password,
 		"password", "", "",
 		`A password if required for the input stream.
@@ -82,6 +98,17 @@ use this flag to provide passwords for input streams as needed.`)
 //   - Add or update related item in keystore or keypair store.
 //   - Save store to persist item change.
 func importItem() {
+	if sharedImportCommandVals.paper && sharedImportCommandVals.formatText != "" {
+		logger.Errorln("The --paper and --format flags can not be used together.")
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	if sharedImportCommandVals.formatText != "" {
+		importFormattedItem()
+		return
+	}
+
 	if inputsAreOk := validateImportInputs(); !inputsAreOk {
 		// validateImportInputs() will have already printed error messages as needed
 		// and set the ExitCode value.
@@ -97,6 +124,8 @@ func importItem() {
 		err = getImportPipedInput()
 	case helpers.ImportInputSourceFile:
 		err = getImportFileInput()
+	case helpers.ImportInputSourceConsole:
+		err = getImportPaperConsoleInput()
 	default:
 		// this should never happen, but in case we forget to add support for a new input source,
 		// we'll add an error output here
@@ -132,8 +161,18 @@ func importItem() {
 	switch importProcessor.DataType() {
 	case security.ExportDataTypeKeyInfo:
 		err = handleUserImport(importProcessor)
+	case security.ExportDataTypeKeyInfoList:
+		err = handleUserListImport(importProcessor)
 	case security.ExportDataTypeKeyPairInfo:
 		err = handleKeyPairImport(importProcessor)
+	case security.ExportDataTypeSuccession:
+		err = handleSuccessionImport(importProcessor)
+	case security.ExportDataTypeRevocation:
+		err = handleRevocationImport(importProcessor)
+	case security.ExportDataTypeCertification:
+		err = handleCertificationImport(importProcessor)
+	case security.ExportDataTypeGroupKey:
+		err = handleGroupKeyImport(importProcessor)
 	case security.ExportDataTypeUnknown:
 		logger.Errorln("Unknown exported data type in import data")
 		helpers.ExitCode = helpers.ExitCodeRequestFailed
@@ -163,6 +202,12 @@ func validateImportInputs() (inputsAreOk bool) {
 		sharedImportCommandVals.inputSourceText = "piped"
 	}
 
+	// Paper keys are typed in at the console if no other input is provided
+	if sharedImportCommandVals.paper && sharedImportCommandVals.inputFilePath == "" && sharedImportCommandVals.inputSourceText == "" {
+		sharedImportCommandVals.inputSource = helpers.ImportInputSourceConsole
+		return true
+	}
+
 	// If not piped, one of the flags --input-file or --input-source must be provided
 	if sharedImportCommandVals.inputFilePath == "" && sharedImportCommandVals.inputSourceText == "" {
 		logger.Errorln("No input file or input source is provided.  At least one must be provided.")
@@ -276,6 +321,15 @@ func getImportFileInput() error {
 }
 
 func decodeImportedBytes(encodedBytes []byte) (decodedBytes []byte, err error) {
+	if sharedImportCommandVals.paper {
+		return decodePaperKeyInput(encodedBytes)
+	}
+
+	// JWK sets are plain JSON, so they are passed to the import processor as they are
+	if security.IsJWKData(encodedBytes) {
+		return encodedBytes, nil
+	}
+
 	var textScanner *helpers.TextScanner
 	textScanner, err = helpers.NewTextScanner(encodedBytes)
 	if err != nil {
@@ -306,19 +360,45 @@ func handleGetPasswordRequest() (password []byte, err error) {
 }
 
 func handleUserImport(importProcessor *cipherio.ImportProcessor) error {
+	return handleImportedUser(importProcessor.ImportedUser(), nil)
+}
+
+// handleImportedUser adds or updates the user, confirming the name, updates and key changes.  The contact is
+// optional.  For existing users, it is merged with the stored contact details.
+func handleImportedUser(ki *security.KeyInfo, contact *security.ContactInfo) error {
 	var importName string
 	var err error
 
-	ki := importProcessor.ImportedUser()
 	if sharedImportCommandVals.detailsOnly {
 		logger.Printfln("Input type        : User Public Keys")
 		logger.Printfln("User Name         : %s", ki.Name)
 		logger.Printfln("Cipher Public Key : %s", ki.CipherPubKey)
 		logger.Printfln("Signing Public Key: %s", ki.SigningPubKey)
+		fp := ki.Fingerprint()
+		logger.Printfln("Fingerprint       : %s", fp.Hex())
+		logger.Printfln("Fingerprint Words : %s", fp.Words())
+		for _, certificate := range ki.Subkeys {
+			logger.Printfln("Subkey            : %s (expires %s)", certificate.CipherPubKey, certificate.ExpiryDate)
+		}
+
+		if contact != nil {
+			logger.Printfln("Display Name      : %s", contact.DisplayName)
+			logger.Printfln("Emails            : %s", strings.Join(contact.Emails, ", "))
+			logger.Printfln("Organization      : %s", contact.Organization)
+		}
+
 		logger.Println("")
 		return nil
 	}
 
+	// If we already have this identity, new certified subkeys are accepted without any prompts
+	if len(ki.Subkeys) > 0 {
+		handled, err := handleUserSubkeyImport(ki)
+		if handled || err != nil {
+			return err
+		}
+	}
+
 	if sharedImportCommandVals.nameOverride != "" {
 		importName = sharedImportCommandVals.nameOverride
 	} else {
@@ -342,7 +422,23 @@ func handleUserImport(importProcessor *cipherio.ImportProcessor) error {
 
 	kiStore := keystore.GlobalKeyStore.GetKey(importName)
 	if kiStore != nil {
-		if !sharedImportCommandVals.ignoreConfirm {
+		keysChanged := kiStore.PublicKeys.CipherPubKey != ki.CipherPubKey || kiStore.PublicKeys.SigningPubKey != ki.SigningPubKey
+		if keysChanged {
+			// key changes always show the fingerprints, and ignore-confirm does not bypass them
+			confirmed, err := confirmKeyChange(kiStore, ki.CipherPubKey, ki.SigningPubKey, sharedImportCommandVals.confirmKeyChange)
+			if err != nil {
+				logger.Errorfln("Error getting confirmation for the key change: %s", err)
+				helpers.ExitCode = helpers.ExitCodeRequestFailed
+				return err
+			}
+
+			if !confirmed {
+				logger.Errorln("User declined key change")
+				logger.Println("")
+				helpers.ExitCode = helpers.ExitCodeRequestFailed
+				return nil
+			}
+		} else if !sharedImportCommandVals.ignoreConfirm {
 			// confirm updating the current user
 			logger.Printfln("A user with the name \"%s\" already exists.", importName)
 			logger.Println("")
@@ -365,18 +461,56 @@ func handleUserImport(importProcessor *cipherio.ImportProcessor) error {
 			}
 		}
 
-		_, err = keystore.GlobalKeyStore.UpdatePublicKeys(importName, ki.CipherPubKey, ki.SigningPubKey)
+		_, err = keystore.GlobalKeyStore.UpdatePublicKeysWithSource(
+			importName,
+			ki.CipherPubKey,
+			ki.SigningPubKey,
+			security.KeySourceImport,
+			getImportSourceDetails())
 		if err != nil {
 			logger.Errorfln("Unable to update keystore: %s", err)
 			helpers.ExitCode = helpers.ExitCodeRequestFailed
 			return err
 		}
 
+		if len(ki.Subkeys) > 0 {
+			_, _, err = keystore.GlobalKeyStore.UpdateSubkeys(importName, ki.Subkeys)
+			if err != nil {
+				logger.Errorfln("Unable to update keystore subkeys: %s", err)
+				helpers.ExitCode = helpers.ExitCodeRequestFailed
+				return err
+			}
+		}
+
+		if contact != nil {
+			_, err = keystore.GlobalKeyStore.UpdateContactInfo(importName, kiStore.Contact.Merge(contact))
+			if err != nil {
+				logger.Errorfln("Unable to update keystore contact details: %s", err)
+				helpers.ExitCode = helpers.ExitCodeRequestFailed
+				return err
+			}
+		}
+
 		logger.Printfln("User \"%s\" updated.", importName)
 		return nil
 	}
 
-	err = keystore.GlobalKeyStore.AddKey(importName, ki.CipherPubKey, ki.SigningPubKey)
+	newKeyInfo, err := security.NewKeyInfo(importName, ki.CipherPubKey, ki.SigningPubKey)
+	if err != nil {
+		logger.Errorfln("Error adding new user info to store: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return err
+	}
+
+	newKeyInfo.Subkeys = security.CloneSubkeyCertificates(ki.Subkeys)
+
+	err = keystore.GlobalKeyStore.AddKeyWithDetails(&security.Entity{
+		Name:             importName,
+		PublicKeys:       newKeyInfo,
+		Contact:          contact,
+		KeySource:        security.KeySourceImport,
+		KeySourceDetails: getImportSourceDetails(),
+	})
 	if err != nil {
 		logger.Errorfln("Error adding new user info to store: %s", err)
 		helpers.ExitCode = helpers.ExitCodeRequestFailed
@@ -389,6 +523,347 @@ func handleUserImport(importProcessor *cipherio.ImportProcessor) error {
 	return nil
 }
 
+// handleUserListImport imports the users of a JWK set with more than one user, the same as "import users"
+// with the skip policy.  Each user is reported, so no error is returned for individual users.
+func handleUserListImport(importProcessor *cipherio.ImportProcessor) error {
+	if sharedImportCommandVals.nameOverride != "" {
+		logger.Errorln("A name can only be provided when the input holds a single user")
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return errors.New("name provided for a list of users")
+	}
+
+	var records []*keystore.UserRecord
+	for i, ki := range importProcessor.ImportedUsers() {
+		records = append(records, &keystore.UserRecord{
+			Name:          ki.Name,
+			CipherPubKey:  ki.CipherPubKey,
+			SigningPubKey: ki.SigningPubKey,
+			Line:          i + 1,
+		})
+	}
+
+	localImportUsersCommandVals.inputFilePath = sharedImportCommandVals.inputFilePath
+	localImportUsersCommandVals.confirmKeyChange = sharedImportCommandVals.confirmKeyChange
+	localImportUsersCommandVals.dryRun = sharedImportCommandVals.detailsOnly
+	importUserRecords(records, keystore.UserImportPolicySkip)
+	return nil
+}
+
+// handleUserSubkeyImport merges the imported subkeys into a user with the same identity keys.  The import
+// processor has already verified that the subkeys are certified by the imported signing key.  If no user
+// has the same identity keys, handled is false and the import continues as a regular user import.
+func handleUserSubkeyImport(ki *security.KeyInfo) (handled bool, err error) {
+	var matchedName string
+	walkInfo := keystore.NewWalkInfo("", false, nil, func(entity *security.Entity) {
+		if matchedName == "" &&
+			entity.PublicKeys.SigningPubKey == ki.SigningPubKey &&
+			entity.PublicKeys.CipherPubKey == ki.CipherPubKey {
+			matchedName = entity.Name
+		}
+	})
+
+	err = keystore.GlobalKeyStore.Walk(walkInfo)
+	if err != nil {
+		logger.Errorfln("Unable to search keystore for existing user: %s", err)
+		return true, err
+	}
+
+	if matchedName == "" {
+		return false, nil
+	}
+
+	_, added, err := keystore.GlobalKeyStore.UpdateSubkeys(matchedName, ki.Subkeys)
+	if err != nil {
+		logger.Errorfln("Unable to update subkeys for user \"%s\": %s", matchedName, err)
+		return true, err
+	}
+
+	if added == 0 {
+		logger.Printfln("User \"%s\" already has the imported subkeys.", matchedName)
+	} else {
+		logger.Printfln("User \"%s\" updated with %d new certified subkey(s).", matchedName, added)
+	}
+
+	logger.Println("")
+	return true, nil
+}
+
+// getImportSourceDetails describes where the import data came from, for an entity's key source details
+func getImportSourceDetails() string {
+	switch sharedImportCommandVals.inputSource {
+	case helpers.ImportInputSourceFile:
+		filePath, err := filepath.Abs(sharedImportCommandVals.inputFilePath)
+		if err != nil {
+			filePath = sharedImportCommandVals.inputFilePath
+		}
+
+		return "file: " + filePath
+	case helpers.ImportInputSourceClipboard:
+		return "clipboard"
+	case helpers.ImportInputSourcePiped:
+		return "piped input"
+	default:
+		return ""
+	}
+}
+
+// handleSuccessionImport moves the user with the statement's old keys to the new keys.  The import
+// processor has already checked both signatures, and the keystore checks the old signing key.
+func handleSuccessionImport(importProcessor *cipherio.ImportProcessor) error {
+	statement := importProcessor.ImportedSuccession()
+	oldFP := statement.OldKeyInfo().Fingerprint()
+	newFP := statement.NewKeyInfo().Fingerprint()
+
+	if sharedImportCommandVals.detailsOnly {
+		logger.Printfln("Input type           : Key Succession Statement")
+		logger.Printfln("User Name            : %s", statement.Name)
+		logger.Printfln("Rotated Date         : %s", statement.RotatedDate)
+		logger.Printfln("Old Signing Key      : %s", statement.OldSigningPubKey)
+		logger.Printfln("Old Fingerprint      : %s", oldFP.Hex())
+		logger.Printfln("New Cipher Key       : %s", statement.NewCipherPubKey)
+		logger.Printfln("New Signing Key      : %s", statement.NewSigningPubKey)
+		logger.Printfln("New Fingerprint      : %s", newFP.Hex())
+		logger.Printfln("New Fingerprint Words: %s", newFP.Words())
+		logger.Println("")
+		return nil
+	}
+
+	userName, err := keystore.GlobalKeyStore.ApplyKeySuccession(statement)
+	if errors.Is(err, keystore.ErrNoSuccessionMatch) {
+		logger.Errorfln("No user in the keystore has the old keys for \"%s\" with fingerprint %s.", statement.Name, oldFP.Hex())
+		logger.Errorln("A succession can only be applied to a user that already has the old keys.")
+		logger.Println("")
+		return err
+	}
+
+	if err != nil {
+		logger.Errorfln("Unable to apply succession statement: %s", err)
+		return err
+	}
+
+	logger.Printfln("User \"%s\" moved to new keys from a verified succession statement.", userName)
+	logger.Printfln("Old Fingerprint : %s", oldFP.Hex())
+	logger.Printfln("New Fingerprint : %s", newFP.Hex())
+	logger.Println("")
+
+	return nil
+}
+
+// handleRevocationImport adds the certificate to the keystore's revocation list, which marks any user with
+// the revoked keys as revoked.  The import processor has already checked the certificate's signature.
+func handleRevocationImport(importProcessor *cipherio.ImportProcessor) error {
+	certificate := importProcessor.ImportedRevocation()
+	fp := certificate.KeyInfo().Fingerprint()
+
+	effectiveDate := certificate.EffectiveDate
+	if effectiveDate == "" {
+		effectiveDate = "when imported"
+	}
+
+	if sharedImportCommandVals.detailsOnly {
+		logger.Printfln("Input type           : Revocation Certificate")
+		logger.Printfln("User Name            : %s", certificate.Name)
+		logger.Printfln("Cipher Public Key    : %s", certificate.CipherPubKey)
+		logger.Printfln("Signing Public Key   : %s", certificate.SigningPubKey)
+		logger.Printfln("Fingerprint          : %s", fp.Hex())
+		logger.Printfln("Reason               : %s", certificate.Reason)
+		logger.Printfln("Created Date         : %s", certificate.CreatedDate)
+		logger.Printfln("Effective Date       : %s", effectiveDate)
+		logger.Println("")
+		return nil
+	}
+
+	entry, userName, err := keystore.GlobalKeyStore.ApplyRevocation(certificate)
+	if err != nil {
+		logger.Errorfln("Unable to apply revocation certificate: %s", err)
+		return err
+	}
+
+	if userName != "" {
+		logger.Printfln("User \"%s\" is revoked as of %s (%s).", userName, entry.RevokedDate, entry.Certificate.Reason)
+	} else {
+		logger.Printfln("No user currently has the keys for \"%s\" with fingerprint %s.", certificate.Name, fp.Hex())
+		logger.Println("The certificate was added to the revocation list, so these keys will be revoked if they are added later.")
+	}
+	logger.Println("")
+
+	return nil
+}
+
+// handleKeyPairImport adds a keypair written by "export keypair".  Existing keypairs are never replaced, since
+// that would lose their private keys.
 func handleKeyPairImport(importProcessor *cipherio.ImportProcessor) error {
-	return errors.New("handleKeyPairImport() not implemented")
+	kpi := importProcessor.ImportedKeyPair()
+	defer kpi.Wipe()
+
+	cipherPubKey, signingPubKey, err := kpi.PublicKeys()
+	if err != nil {
+		logger.Errorfln("Imported keypair is invalid: %s", err)
+		return err
+	}
+
+	if sharedImportCommandVals.detailsOnly {
+		fp := security.NewFingerprint(cipherPubKey, signingPubKey)
+		logger.Printfln("Input type        : Keypair")
+		logger.Printfln("Keypair Name      : %s", kpi.Name)
+		logger.Printfln("Cipher Public Key : %s", cipherPubKey)
+		logger.Printfln("Signing Public Key: %s", signingPubKey)
+		logger.Printfln("Fingerprint       : %s", fp.Hex())
+		logger.Printfln("Fingerprint Words : %s", fp.Words())
+		for _, subkey := range kpi.Subkeys {
+			logger.Printfln("Subkey            : %s (expires %s)", subkey.Certificate.CipherPubKey, subkey.Certificate.ExpiryDate)
+		}
+
+		logger.Println("")
+		return nil
+	}
+
+	importName := sharedImportCommandVals.nameOverride
+	if importName == "" {
+		logger.Printfln("The imported keypair has the name \"%s\".", kpi.Name)
+		logger.Println("")
+		logger.Println("Enter your preferred name for the imported keypair.  Leave blank to use the name from the imported data.")
+		logger.Println("")
+
+		importName, err = helpers.GetConsoleInputLine("Preferred import name (CTRL-C to cancel)")
+		logger.Println("")
+		if err != nil {
+			logger.Errorfln("Error getting preferred import name from user: %s", err)
+			return err
+		}
+
+		if importName == "" {
+			importName = kpi.Name
+		}
+	}
+
+	existingKPI := keypairs.GlobalKeyPairStore.GetKeyPairInfo(importName)
+	if existingKPI != nil {
+		existingKPI.Wipe()
+		logger.Errorfln("A keypair already exists by the name \"%s\".  Provide a different name with --name.", importName)
+		return fmt.Errorf("keypair \"%s\" already exists", importName)
+	}
+
+	if !sharedImportCommandVals.ignoreConfirm {
+		response, err := helpers.GetYesNoInput(
+			fmt.Sprintf("Import keypair \"%s\" with signing key %s? ", importName, signingPubKey),
+			helpers.InputResponseValNo)
+		logger.Println("")
+
+		if err != nil {
+			logger.Errorfln("Error getting confirmation to import the keypair: %s", err)
+			return err
+		}
+
+		if response == helpers.InputResponseValNo {
+			logger.Errorln("User declined import")
+			return errors.New("user declined import")
+		}
+	}
+
+	kpi.Name = importName
+	err = keypairs.GlobalKeyPairStore.ImportKeyPair(kpi)
+	if err != nil {
+		logger.Errorfln("Unable to import keypair: %s", err)
+		return err
+	}
+
+	err = keypairs.GlobalKeyPairStore.SaveKeyPairStoreToOrigin(nil)
+	if err != nil {
+		logger.Errorfln("Unable to import keypair: keypair store could not update the file: %s", err)
+		return err
+	}
+
+	logger.Printfln("Keypair \"%s\" added and keypair store file changes committed.", importName)
+	logger.Println("")
+	return nil
+}
+
+// handleGroupKeyImport stores group identity keys, the same as "group join" does for a key distribution bundle.
+// The export is not signed, so the sender is unknown.  New groups are added without a recorded manager, and the
+// keys of a group that is already known are not replaced.
+func handleGroupKeyImport(importProcessor *cipherio.ImportProcessor) error {
+	groupKPI := importProcessor.ImportedKeyPair()
+
+	if sharedImportCommandVals.detailsOnly {
+		logger.Printfln("Input type           : Group Key")
+		logger.Printfln("Group Name           : %s", groupKPI.Name)
+		logger.Printfln("Group Epoch          : %d", groupKPI.GroupEpoch)
+		logger.Printfln("Epoch Date           : %s", groupKPI.GroupEpochDate)
+		logger.Println("")
+		return nil
+	}
+
+	kpi, added, err := keypairs.GlobalKeyPairStore.MergeGroupKey(groupKPI, "")
+	if errors.Is(err, security.ErrGroupManagerMismatch) {
+		logger.Errorfln("Group \"%s\" already exists.  Use \"group join\" with a key distribution bundle from the group's manager to update it.", groupKPI.Name)
+		return err
+	}
+
+	if err != nil {
+		logger.Errorfln("Unable to import group key: %s", err)
+		return err
+	}
+	defer kpi.Wipe()
+
+	err = keypairs.GlobalKeyPairStore.SaveKeyPairStoreToOrigin(nil)
+	if err != nil {
+		logger.Errorfln("Unable to import group key: keypair store could not update the file: %s", err)
+		return err
+	}
+
+	if added {
+		logger.Printfln("Group \"%s\" added at epoch %d and keypair store file changes committed.", kpi.Name, kpi.GroupEpoch)
+	} else {
+		logger.Printfln("Group \"%s\" updated to epoch %d and keypair store file changes committed.", kpi.Name, kpi.GroupEpoch)
+	}
+
+	return nil
+}
+
+// handleCertificationImport stores the certification with the user that has the certified keys.  The import
+// processor has already checked the certification's signature.
+func handleCertificationImport(importProcessor *cipherio.ImportProcessor) error {
+	certification := importProcessor.ImportedCertification()
+	fp := certification.SubjectKeyInfo().Fingerprint()
+
+	if sharedImportCommandVals.detailsOnly {
+		logger.Printfln("Input type           : Certification")
+		logger.Printfln("User Name            : %s", certification.SubjectName)
+		logger.Printfln("Cipher Public Key    : %s", certification.SubjectCipherPubKey)
+		logger.Printfln("Signing Public Key   : %s", certification.SubjectSigningPubKey)
+		logger.Printfln("Fingerprint          : %s", fp.Hex())
+		logger.Printfln("Certifier Name       : %s", certification.CertifierName)
+		logger.Printfln("Certifier Signing Key: %s", certification.CertifierSigningPubKey)
+		logger.Printfln("Certified Date       : %s", certification.CertifiedDate)
+		logger.Println("")
+		return nil
+	}
+
+	userName, err := keystore.GlobalKeyStore.AddCertification(certification)
+	if errors.Is(err, keystore.ErrNoCertificationMatch) {
+		logger.Errorfln("No user in the keystore has the certified keys for \"%s\" with fingerprint %s.", certification.SubjectName, fp.Hex())
+		logger.Errorln("Import the user's public keys first, then import the certification.")
+		logger.Println("")
+		return err
+	}
+
+	if err != nil {
+		logger.Errorfln("Unable to store certification: %s", err)
+		return err
+	}
+
+	certifierText := certification.CertifierName + " (unknown key)"
+	certifier := keystore.GlobalKeyStore.GetKeyBySigningPubKey(certification.CertifierSigningPubKey)
+	if certifier != nil {
+		certifierText = certifier.Name
+	}
+
+	logger.Printfln("Certification of user \"%s\" by %s stored.", userName, certifierText)
+	if introducerName := getTrustedIntroducerFor(keystore.GlobalKeyStore.GetKey(userName)); introducerName != "" {
+		logger.Printfln("User \"%s\" is accepted as verified by the trusted introducer \"%s\".", userName, introducerName)
+	}
+
+	logger.Println("")
+	return nil
 }
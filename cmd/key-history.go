@@ -0,0 +1,124 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/logger"
+	"github.com/thoughtrealm/bumblebee/security"
+)
+
+// confirmKeyChange shows the current and new fingerprints for a user and asks the user to confirm the change.
+// If the keys are unchanged or the change was already confirmed with a flag, no prompt is shown.
+func confirmKeyChange(entity *security.Entity, newCipherPubKey, newSigningPubKey string, preConfirmed bool) (confirmed bool, err error) {
+	if entity.PublicKeys.CipherPubKey == newCipherPubKey && entity.PublicKeys.SigningPubKey == newSigningPubKey {
+		return true, nil
+	}
+
+	oldFP := entity.PublicKeys.Fingerprint()
+	newFP := security.NewFingerprint(newCipherPubKey, newSigningPubKey)
+
+	logger.Printfln("The public keys for user \"%s\" are changing.", entity.Name)
+	logger.Printfln("Old Fingerprint      : %s", oldFP.Hex())
+	logger.Printfln("                       %s", oldFP.Words())
+	logger.Printfln("New Fingerprint      : %s", newFP.Hex())
+	logger.Printfln("                       %s", newFP.Words())
+	logger.Println("")
+
+	if preConfirmed {
+		return true, nil
+	}
+
+	logger.Println("Confirm the new fingerprint with the user out-of-band before accepting it.")
+	logger.Println("")
+	response, err := helpers.GetYesNoInput(
+		fmt.Sprintf("Accept the new keys for user \"%s\"? ", entity.Name),
+		helpers.InputResponseValNo)
+	logger.Println("")
+	if err != nil {
+		return false, err
+	}
+
+	return response == helpers.InputResponseValYes, nil
+}
+
+// printKeyHistory lists an entity's prior keys, most recent first
+func printKeyHistory(entity *security.Entity) {
+	fmt.Println("Key History")
+	fmt.Println("=========================================================")
+	if entity.KeysDate != "" {
+		fmt.Printf("Current Keys Since : %s\n", entity.KeysDate)
+	}
+
+	if len(entity.KeyHistory) == 0 {
+		fmt.Println("No prior keys")
+		fmt.Println()
+		return
+	}
+
+	for i := len(entity.KeyHistory) - 1; i >= 0; i-- {
+		entry := entity.KeyHistory[i]
+		fmt.Println()
+		fmt.Printf("Fingerprint        : %s\n", entry.KeyInfo(entity.Name).Fingerprint().Hex())
+		fmt.Printf("Signing Public Key : %s\n", entry.SigningPubKey)
+		if entry.FirstSeenDate != "" {
+			fmt.Printf("First Seen         : %s\n", entry.FirstSeenDate)
+		}
+
+		fmt.Printf("Replaced           : %s\n", entry.ReplacedDate)
+		fmt.Printf("Replaced By        : %s\n", entry.ChangeSourceText())
+	}
+
+	fmt.Println()
+}
+
+// checkSenderKeyHistory is called when an item was signed with one of the sender's prior keys.  Items dated before
+// the keys were replaced are only noted.  Items dated on or after that, or signed by revoked keys, print a warning,
+// or are refused when the profile trust policy is refuse.
+func checkSenderKeyHistory(sender *security.Entity, createDate, signingPubKey string) error {
+	if sender == nil || signingPubKey == sender.PublicKeys.SigningPubKey {
+		return nil
+	}
+
+	entry := sender.FindKeyHistory(signingPubKey)
+	if entry == nil {
+		return fmt.Errorf("the signing key is not a current or prior key for \"%s\"", sender.Name)
+	}
+
+	var reason string
+	if revocation := keystore.GlobalKeyStore.GetRevocation(signingPubKey); revocation != nil {
+		reason = fmt.Sprintf(
+			"this item from \"%s\" was signed with a prior key that was revoked on %s",
+			sender.Name, revocation.RevokedDate)
+	} else if entry.IsReplacedAt(createDate) {
+		reason = fmt.Sprintf(
+			"this item from \"%s\" is dated %s, but was signed with a prior key that was replaced on %s",
+			sender.Name, createDate, entry.ReplacedDate)
+	} else {
+		fmt.Printf(
+			"NOTE: This item from \"%s\" was signed with a prior key, replaced on %s.  The item is dated %s, before the change.\n",
+			sender.Name, entry.ReplacedDate, createDate)
+		return nil
+	}
+
+	profile := helpers.GlobalConfig.GetCurrentProfile()
+	if profile != nil && profile.GetTrustPolicy() == helpers.TrustPolicyRefuse {
+		return fmt.Errorf("%s.  The profile trust policy refuses it", reason)
+	}
+
+	fmt.Printf("WARNING: %s.  It may have been created by someone else holding the old keys.\n", reason)
+	return nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"strings"
+)
+
+// listGroupsCmd represents the groups command
+var listGroupsCmd = &cobra.Command{
+	Use:   "groups",
+	Short: "Displays the keystore's groups and their members",
+	Long:  "Displays the keystore's groups and their members",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		showGroupsList()
+	},
+}
+
+func init() {
+	listCmd.AddCommand(listGroupsCmd)
+}
+
+func showGroupsList() {
+	groups := keystore.GlobalKeyStore.ListGroups()
+	if len(groups) == 0 {
+		fmt.Println("No groups have been added")
+		return
+	}
+
+	fmt.Println("")
+	fmt.Printf("Using profile : %s\n", helpers.GlobalConfig.GetCurrentProfile().Name)
+	fmt.Printf("Groups Loaded : %d\n", len(groups))
+	fmt.Println("======================================================")
+	for _, group := range groups {
+		fmt.Printf("%s%s (%d): %s\n", keystore.GroupPrefix, group.Name, len(group.Members), strings.Join(group.Members, ", "))
+	}
+	fmt.Println()
+}
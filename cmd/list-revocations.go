@@ -0,0 +1,64 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keystore"
+)
+
+// listRevocationsCmd represents the revocations command
+var listRevocationsCmd = &cobra.Command{
+	Use:   "revocations",
+	Short: "Displays the keystore's list of imported revocation certificates",
+	Long:  "Displays the keystore's list of imported revocation certificates",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		showRevocationsList()
+	},
+}
+
+func init() {
+	listCmd.AddCommand(listRevocationsCmd)
+}
+
+func showRevocationsList() {
+	entries := keystore.GlobalKeyStore.ListRevocations()
+	if len(entries) == 0 {
+		fmt.Println("No revocation certificates have been imported")
+		return
+	}
+
+	fmt.Println("")
+	fmt.Printf("Using profile       : %s\n", helpers.GlobalConfig.GetCurrentProfile().Name)
+	fmt.Printf("Revocations Loaded  : %d\n", len(entries))
+	fmt.Println("======================================================")
+	for _, entry := range entries {
+		fp := entry.Certificate.KeyInfo().Fingerprint()
+		fmt.Printf("Name                : %s\n", entry.Certificate.Name)
+		fmt.Printf("Signing Public Key  : %s\n", entry.Certificate.SigningPubKey)
+		fmt.Printf("Fingerprint         : %s\n", fp.Hex())
+		fmt.Printf("Reason              : %s\n", entry.Certificate.Reason)
+		fmt.Printf("Revoked Date        : %s\n", entry.RevokedDate)
+		fmt.Printf("Imported Date       : %s\n", entry.ImportedDate)
+		fmt.Println()
+	}
+}
@@ -19,13 +19,15 @@ import (
 	"github.com/thoughtrealm/bumblebee/helpers"
 	"github.com/thoughtrealm/bumblebee/keystore"
 	"github.com/thoughtrealm/bumblebee/security"
+	"strings"
 )
 
 // listUsersCmd represents the keys command
 var listUsersCmd = &cobra.Command{
 	Use:   "users",
 	Short: "Displays a list of users",
-	Long:  "Displays a list of users",
+	Long: "Displays a list of users. The match pattern may be prefixed with a field to match on, " +
+		"one of name:, display:, email:, org: or tag:. For example, --match tag:work.",
 	Run: func(cmd *cobra.Command, args []string) {
 		err := startBootStrap(true, true)
 		if err != nil {
@@ -48,8 +50,10 @@ func showKeysList() {
 		return
 	}
 
+	nameMatchFilter, walkFilterFunc := getUserMatchFilters(globalListSubCommandVals.match)
+
 	// First, walk the map to get a count of matching items
-	walkCount, err := keystore.GlobalKeyStore.WalkCount(globalListSubCommandVals.match, nil)
+	walkCount, err := keystore.GlobalKeyStore.WalkCount(nameMatchFilter, walkFilterFunc)
 	if err != nil {
 		fmt.Printf("Failed on pass 1 entity count: %s\n", err)
 	}
@@ -64,7 +68,46 @@ func showKeysList() {
 		entity.Print()
 	}
 
-	walkInfo := keystore.NewWalkInfo(globalListSubCommandVals.match, globalListSubCommandVals.sort, nil, walkFunc)
+	walkInfo := keystore.NewWalkInfo(nameMatchFilter, globalListSubCommandVals.sort, walkFilterFunc, walkFunc)
 
 	_ = keystore.GlobalKeyStore.Walk(walkInfo)
 }
+
+// getUserMatchFilters splits a match value into a name filter or a contact field filter.
+// Patterns without a known field prefix are treated as name patterns.
+func getUserMatchFilters(match string) (nameMatchFilter string, walkFilterFunc keystore.KeyStoreWalkFilterFunc) {
+	field, pattern, found := strings.Cut(match, ":")
+	if !found {
+		return match, nil
+	}
+
+	var getValues func(contact *security.ContactInfo) []string
+	switch strings.ToLower(field) {
+	case "name":
+		return pattern, nil
+	case "display":
+		getValues = func(contact *security.ContactInfo) []string { return []string{contact.DisplayName} }
+	case "email":
+		getValues = func(contact *security.ContactInfo) []string { return contact.Emails }
+	case "org":
+		getValues = func(contact *security.ContactInfo) []string { return []string{contact.Organization} }
+	case "tag":
+		getValues = func(contact *security.ContactInfo) []string { return contact.Tags }
+	default:
+		return match, nil
+	}
+
+	return "", func(entity *security.Entity) bool {
+		if entity.Contact == nil {
+			return false
+		}
+
+		for _, value := range getValues(entity.Contact) {
+			if value != "" && helpers.MatchesFilter(value, pattern) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
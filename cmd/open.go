@@ -23,6 +23,8 @@ import (
 	"github.com/thoughtrealm/bumblebee/helpers"
 	"github.com/thoughtrealm/bumblebee/keypairs"
 	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/ledger"
+	"github.com/thoughtrealm/bumblebee/logger"
 	"github.com/thoughtrealm/bumblebee/security"
 	"golang.org/x/text/language"
 	"golang.org/x/text/message"
@@ -91,6 +93,9 @@ type openCommandVals struct {
 
 	// showAll true will display the payload key and salt when using the detailsOnly flag
 	showAll bool
+
+	// noReplay refuses to open a bundle that was already opened, instead of warning
+	noReplay bool
 }
 
 var localOpenCommandVals = &openCommandVals{}
@@ -102,6 +107,11 @@ type openSettings struct {
 	cipherReader      *cipherio.CipherReader
 	textWriter        *helpers.TextWriter
 	totalBytesWritten int
+	openedLedger      *ledger.Ledger
+	openedBundle      *ledger.OpenedBundle
+	previousOpen      *ledger.OpenedBundle
+	revokedSender     *security.Entity
+	senderEntity      *security.Entity
 }
 
 var localOpenSettings = &openSettings{}
@@ -119,6 +129,7 @@ func init() {
 	openCmd.Flags().StringVarP(&localOpenCommandVals.bundleTypeText, "bundle-type", "b", "combined", "The type of bundle to build.  Should be one of: combined or split.")
 	openCmd.Flags().BoolVarP(&localOpenCommandVals.detailsOnly, "details-only", "d", false, "Will display the bundle details only and quit. Does not extract or open the file.")
 	openCmd.Flags().BoolVarP(&localOpenCommandVals.showAll, "show-all", "s", false, "True will display payload password and salt when using the details-only flag.")
+	openCmd.Flags().BoolVarP(&localOpenCommandVals.noReplay, "no-replay", "", false, "If true, refuses to open a bundle that was already opened, instead of displaying a warning.")
 }
 
 func openBundle() {
@@ -229,6 +240,22 @@ func openBundle() {
 	}
 	defer localOpenSettings.cipherReader.Wipe()
 
+	if localOpenSettings.senderEntity != nil {
+		localOpenSettings.cipherReader.SenderPriorKeys = localOpenSettings.senderEntity.PriorKeyInfos()
+	}
+
+	ledgerErr := loadLedgerForOpen()
+	if ledgerErr != nil {
+		if localOpenCommandVals.noReplay {
+			fmt.Printf("Unable to load the opened bundles ledger: %s\n", ledgerErr)
+			helpers.ExitCode = helpers.ExitCodeStartupFailure
+			return
+		}
+
+		fmt.Printf("WARNING: Unable to load the opened bundles ledger.  Replays will not be detected: %s\n", ledgerErr)
+	}
+	localOpenSettings.cipherReader.HeaderValidator = validateOpenBundleHeader
+
 	var (
 		writerErr    error
 		outputWriter io.Writer
@@ -256,6 +283,12 @@ func openBundle() {
 		}
 
 		if err == nil {
+			recordBundleOpen()
+
+			if localOpenSettings.cipherReader.OpenedViaSelfSlot {
+				fmt.Println("You are the sender of this bundle. It was opened via the self slot.")
+			}
+
 			p := message.NewPrinter(language.English)
 			if localOpenCommandVals.detailsOnly {
 				_, _ = p.Printf(
@@ -329,6 +362,12 @@ func getKeysForOpen() (receiverKeyPairInfo *security.KeyPairInfo, senderKeyInfo
 		return nil, nil, errors.New("sender key name not supplied")
 	}
 
+	// If the sender is one of our own keypairs, the bundle is opened via the self slot
+	ownKeyPairName := getOwnKeyPairNameForSender(localOpenCommandVals.fromName)
+	if ownKeyPairName != "" {
+		return getSelfKeysForOpen(ownKeyPairName)
+	}
+
 	// First, get the receiver's keypair info
 	var useReceiverName = "default"
 	if localOpenCommandVals.toName != "" {
@@ -344,8 +383,179 @@ func getKeysForOpen() (receiverKeyPairInfo *security.KeyPairInfo, senderKeyInfo
 	if senderEntity == nil {
 		return nil, nil, fmt.Errorf("sender key not located for name \"%s\"", localOpenCommandVals.fromName)
 	}
+
+	// Revoked senders are checked against the bundle date once the header is read
+	if senderEntity.Trust == security.TrustLevelRevoked {
+		localOpenSettings.revokedSender = senderEntity
+	} else {
+		err = checkEntityTrust(senderEntity, "sender")
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 	senderKeyInfo = senderEntity.PublicKeys
+	localOpenSettings.senderEntity = senderEntity
+
+	return receiverKeyPairInfo, senderKeyInfo, nil
+}
+
+// loadLedgerForOpen loads the current profile's ledger of opened bundles
+func loadLedgerForOpen() error {
+	profile := helpers.GlobalConfig.GetCurrentProfile()
+	if profile == nil {
+		return errors.New("unable to retrieve current profile config")
+	}
+
+	var err error
+	localOpenSettings.openedLedger, err = ledger.ReadFromFile(profile.OpenedLedgerPath())
+	return err
+}
+
+// validateOpenBundleHeader is called by the cipher reader once the bundle header is validated
+func validateOpenBundleHeader(bundleInfo *cipherio.BundleInfo, senderSigningPubKey string) error {
+	if !localOpenCommandVals.detailsOnly {
+		err := checkSenderRevocation(localOpenSettings.revokedSender, bundleInfo.CreateDate)
+		if err != nil {
+			return err
+		}
+
+		err = checkSenderKeyHistory(localOpenSettings.senderEntity, bundleInfo.CreateDate, senderSigningPubKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	return checkBundleReplay(bundleInfo, senderSigningPubKey)
+}
+
+// checkBundleReplay is called by the cipher reader once the bundle header is validated.  It warns about, or refuses,
+// bundles from the same sender that are already in the opened bundles ledger.
+func checkBundleReplay(bundleInfo *cipherio.BundleInfo, senderSigningPubKey string) error {
+	bundleID := bundleInfo.BundleIDToText()
+	if bundleID == "" {
+		logger.Debug("Bundle has no ID, so it cannot be checked for replays")
+		return nil
+	}
+
+	senderName := localOpenCommandVals.fromName
+	if senderName == "" {
+		senderName = bundleInfo.FromName
+	}
+
+	localOpenSettings.openedBundle = &ledger.OpenedBundle{
+		BundleID:            bundleID,
+		SenderName:          senderName,
+		SenderSigningPubKey: senderSigningPubKey,
+		ToName:              bundleInfo.ToName,
+		CreateDate:          bundleInfo.CreateDate,
+	}
+
+	if localOpenSettings.openedLedger == nil {
+		return nil
+	}
+
+	localOpenSettings.previousOpen = localOpenSettings.openedLedger.Find(bundleID, senderSigningPubKey)
+	if localOpenSettings.previousOpen == nil || localOpenCommandVals.detailsOnly {
+		return nil
+	}
+
+	if localOpenCommandVals.noReplay {
+		return fmt.Errorf(
+			"bundle %s from \"%s\" was already opened %d time(s), last on %s",
+			bundleID,
+			senderName,
+			localOpenSettings.previousOpen.OpenCount,
+			localOpenSettings.previousOpen.LastOpenedDate,
+		)
+	}
+
+	fmt.Printf(
+		"WARNING: Bundle %s from \"%s\" was already opened %d time(s), last on %s\n",
+		bundleID,
+		senderName,
+		localOpenSettings.previousOpen.OpenCount,
+		localOpenSettings.previousOpen.LastOpenedDate,
+	)
+
+	return nil
+}
+
+// recordBundleOpen adds a successfully opened bundle to the opened bundles ledger
+func recordBundleOpen() {
+	if localOpenCommandVals.detailsOnly || localOpenSettings.openedBundle == nil || localOpenSettings.openedLedger == nil {
+		return
+	}
+
+	localOpenSettings.openedLedger.RecordOpen(localOpenSettings.openedBundle)
+	err := localOpenSettings.openedLedger.WriteToFile("")
+	if err != nil {
+		fmt.Printf("WARNING: Unable to update the opened bundles ledger: %s\n", err)
+	}
+}
+
+// getOwnKeyPairNameForSender returns the name of the local keypair that matches the sender name, if the sender is
+// this profile.  The sender matches if it is the profile's DefaultKeypairName, a keypair name with no matching user in
+// the keystore, or a user whose public keys are one of our own keypairs.  Returns an empty string otherwise.
+func getOwnKeyPairNameForSender(senderName string) string {
+	profile := helpers.GlobalConfig.GetCurrentProfile()
+	if profile != nil && profile.DefaultKeypairName != "" && strings.EqualFold(profile.DefaultKeypairName, senderName) {
+		return "default"
+	}
+
+	senderEntity := keystore.GlobalKeyStore.GetKey(senderName)
+	if senderEntity == nil {
+		kpi := keypairs.GlobalKeyPairStore.GetKeyPairInfo(senderName)
+		if kpi == nil {
+			return ""
+		}
+
+		defer kpi.Wipe()
+		return kpi.Name
+	}
+
+	var ownKeyPairName string
+	keypairs.GlobalKeyPairStore.Walk(false, func(kpi *security.KeyPairInfo) {
+		if ownKeyPairName != "" {
+			return
+		}
 
+		cipherPubKey, signingPubKey, err := kpi.PublicKeys()
+		if err != nil {
+			return
+		}
+
+		if cipherPubKey == senderEntity.PublicKeys.CipherPubKey && signingPubKey == senderEntity.PublicKeys.SigningPubKey {
+			ownKeyPairName = kpi.Name
+		}
+	})
+
+	return ownKeyPairName
+}
+
+// getSelfKeysForOpen returns the keys for opening a bundle that we sent, using the self slot.  The sender is our own
+// keypair and the receiver is the self keypair, which is --to, the profile's selfKeypairName or keystore_read.
+func getSelfKeysForOpen(senderKeyPairName string) (receiverKeyPairInfo *security.KeyPairInfo, senderKeyInfo *security.KeyInfo, err error) {
+	senderKeyInfo, err = getSelfKeyInfo(senderKeyPairName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to build sender key info: %w", err)
+	}
+
+	useReceiverName := localOpenCommandVals.toName
+	if useReceiverName == "" {
+		profile := helpers.GlobalConfig.GetCurrentProfile()
+		if profile != nil && profile.SelfKeypairName != "" {
+			useReceiverName = profile.SelfKeypairName
+		} else {
+			useReceiverName = helpers.KeyPairNameForKeyStoreReads
+		}
+	}
+
+	receiverKeyPairInfo = keypairs.GlobalKeyPairStore.GetKeyPairInfo(useReceiverName)
+	if receiverKeyPairInfo == nil {
+		return nil, nil, fmt.Errorf("Unable to locate self keypair for name \"%s\"\n", useReceiverName)
+	}
+
+	logger.Debugfln("Sender is keypair \"%s\", opening with self keypair \"%s\"", senderKeyPairName, useReceiverName)
 	return receiverKeyPairInfo, senderKeyInfo, nil
 }
 
@@ -610,6 +820,15 @@ func getFileWriter() (io.Writer, error) {
 }
 
 func decryptFile(writer io.Writer) error {
+	if isSignedMessageFile(localOpenCommandVals.inputFilePath) {
+		fileBytes, err := os.ReadFile(localOpenCommandVals.inputFilePath)
+		if err != nil {
+			return fmt.Errorf("unable to read the input file: %w", err)
+		}
+
+		return openSignedMessage(fileBytes, writer)
+	}
+
 	if localOpenCommandVals.detailsOnly {
 		return getBundleDetailsFromFile()
 	}
@@ -679,10 +898,6 @@ func getBundleDetailsFromFile() error {
 }
 
 func decryptClipboard(writer io.Writer) error {
-	if localOpenCommandVals.detailsOnly {
-		return getBundleDetailsFromClipboard()
-	}
-
 	cbBytes, err := helpers.ReadFromClipboard()
 	if err != nil {
 		return fmt.Errorf("unable to retrieve clipboard data: %w", err)
@@ -692,6 +907,14 @@ func decryptClipboard(writer io.Writer) error {
 		return errors.New("no data retrieved from clipboard")
 	}
 
+	if cipherio.IsSignedMessage(cbBytes) {
+		return openSignedMessage(cbBytes, writer)
+	}
+
+	if localOpenCommandVals.detailsOnly {
+		return getBundleDetailsFromClipboard()
+	}
+
 	reader, err := helpers.NewTextScanner(cbBytes)
 	if err != nil {
 		return fmt.Errorf("unable to initialize text scanner from clipboard input: %s", err)
@@ -732,6 +955,10 @@ func decryptPipe(writer io.Writer) error {
 		return errors.New("no data returned from input pipe")
 	}
 
+	if cipherio.IsSignedMessage(pbBytes) {
+		return openSignedMessage(pbBytes, writer)
+	}
+
 	reader, err := helpers.NewTextScanner(pbBytes)
 	if err != nil {
 		return fmt.Errorf("unable to initialize text scanner from pipe input: %s", err)
@@ -801,7 +1028,25 @@ func getBundleDetailsFromReader(r io.Reader, sourceSize int64) error {
 	fmt.Printf("Original File Name    : %s\n", bundleInfo.OriginalFileName)
 	fmt.Printf("To Name               : %s\n", bundleInfo.ToName)
 	fmt.Printf("From Name             : %s\n", bundleInfo.FromName)
+	fmt.Printf("Bundle ID             : %s\n", bundleInfo.BundleIDToText())
 	fmt.Printf("Input Source          : %s\n", cipherio.BundleInputSourceToText(bundleInfo.InputSource))
+	fmt.Printf("Has Self Slot         : %t\n", bundleInfo.SelfSlot)
+	fmt.Printf("Opened Via Self Slot  : %t\n", localOpenSettings.cipherReader.OpenedViaSelfSlot)
+	fmt.Printf("Opened With Subkey    : %t\n", localOpenSettings.cipherReader.OpenedWithSubkey)
+	fmt.Printf("Prior Sender Key      : %t\n", localOpenSettings.cipherReader.OpenedWithPriorSenderKey)
+	if localOpenSettings.cipherReader.OpenedWithGroupEpoch > 0 {
+		fmt.Printf("Prior Group Epoch     : %d\n", localOpenSettings.cipherReader.OpenedWithGroupEpoch)
+	}
+	if localOpenSettings.previousOpen != nil {
+		fmt.Printf("Previously Opened     : %d time(s), last on %s\n", localOpenSettings.previousOpen.OpenCount, localOpenSettings.previousOpen.LastOpenedDate)
+	} else {
+		fmt.Println("Previously Opened     : No")
+	}
+
+	if localOpenSettings.revokedSender != nil {
+		fmt.Printf("Sender Revoked        : %s\n", localOpenSettings.revokedSender.TrustText())
+		fmt.Printf("Dated After Revocation: %t\n", localOpenSettings.revokedSender.IsRevokedAt(bundleInfo.CreateDate))
+	}
 
 	if localOpenCommandVals.showAll {
 
@@ -812,3 +1057,66 @@ func getBundleDetailsFromReader(r io.Reader, sourceSize int64) error {
 	fmt.Println("")
 	return nil
 }
+
+// openSignedMessage validates a signed plaintext message against the sender's keys, then emits the payload
+// to the output target.  Nothing is emitted unless the signature is valid.
+func openSignedMessage(data []byte, writer io.Writer) error {
+	var priorKIs []*security.KeyInfo
+	if localOpenSettings.senderEntity != nil {
+		priorKIs = localOpenSettings.senderEntity.PriorKeyInfos()
+	}
+
+	signedMessage, signerKI, err := parseAndVerifySignedMessage(data, localOpenSettings.senderKey, priorKIs)
+	if err != nil {
+		return err
+	}
+
+	if localOpenCommandVals.detailsOnly {
+		printSignedMessageDetails(signedMessage, int64(len(data)))
+		return nil
+	}
+
+	err = checkSenderRevocation(localOpenSettings.revokedSender, signedMessage.Info.CreateDate)
+	if err != nil {
+		return err
+	}
+
+	err = checkSenderKeyHistory(localOpenSettings.senderEntity, signedMessage.Info.CreateDate, signerKI.SigningPubKey)
+	if err != nil {
+		return err
+	}
+
+	var outputFilePath string
+	switch localOpenCommandVals.outputTarget {
+	case keystore.OutputTargetFile:
+		outputFilePath = localOpenCommandVals.outputFile
+	case keystore.OutputTargetPath:
+		// The original file name comes from the message, so only its base name is used
+		originalFileName := helpers.BaseFileName(signedMessage.Info.OriginalFileName)
+		if originalFileName != "" {
+			outputFilePath = filepath.Join(localOpenCommandVals.outputPath, originalFileName)
+		} else if localOpenCommandVals.inputFilePath != "" {
+			_, fileName := filepath.Split(localOpenCommandVals.inputFilePath)
+			outputFilePath = filepath.Join(localOpenCommandVals.outputPath, helpers.ReplaceFileExt(fileName, ".verified"))
+		} else {
+			outputFilePath = filepath.Join(localOpenCommandVals.outputPath, cipherio.DEFAULT_OUTPUT_FILE_NAME+".verified")
+		}
+	}
+
+	if outputFilePath == "" {
+		localOpenSettings.totalBytesWritten, err = writer.Write(signedMessage.Payload)
+		if err != nil {
+			return fmt.Errorf("failed writing signed message payload: %w", err)
+		}
+
+		return nil
+	}
+
+	err = os.WriteFile(outputFilePath, signedMessage.Payload, 0666)
+	if err != nil {
+		return fmt.Errorf("failed writing signed message payload: %w", err)
+	}
+
+	localOpenSettings.totalBytesWritten = len(signedMessage.Payload)
+	return nil
+}
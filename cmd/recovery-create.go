@@ -0,0 +1,202 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/logger"
+	"github.com/thoughtrealm/bumblebee/security"
+	"path/filepath"
+	"strings"
+)
+
+type recoveryCreateCommandVals struct {
+	threshold  int
+	toNames    []string
+	fromName   string
+	outputPath string
+}
+
+var localRecoveryCreateCommandVals = &recoveryCreateCommandVals{}
+
+type recoveryShareResult struct {
+	contactName  string
+	shareIndex   int
+	outputFile   string
+	bytesWritten int
+	err          error
+}
+
+// recoveryCreateCmd represents the recovery create command
+var recoveryCreateCmd = &cobra.Command{
+	Use:   "create [<keypair>] --threshold <k> --to <user1,user2,...>",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Splits a keypair into shares and bundles one share to each contact",
+	Long: `Splits a keypair into one share for each contact in --to, any --threshold of which can restore it.  The
+keypair defaults to "default".  Each share is bundled to its contact, and the shares are only held in memory,
+so they are not written to disk unencrypted.  Contacts must already exist in the keystore, and all of them must
+be accepted by the trust policy, since a skipped contact would leave fewer shares than intended.
+
+The keypair's subkeys are included.  Run create again after adding subkeys or to change the contacts.  Shares
+from different runs can not be combined.`,
+	Example: `  -- Split the default keypair into 3 shares, any 2 of which restore it
+  bumblebee recovery create --threshold 2 --to alice,bob,carol`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		keypairName := "default"
+		if len(args) == 1 {
+			keypairName = args[0]
+		}
+
+		createRecoveryShares(keypairName)
+	},
+}
+
+func init() {
+	recoveryCmd.AddCommand(recoveryCreateCmd)
+	recoveryCreateCmd.Flags().IntVarP(&localRecoveryCreateCommandVals.threshold, "threshold", "k", 0, "The number of shares required to restore the keypair. Must be at least 2.")
+	recoveryCreateCmd.Flags().StringSliceVarP(&localRecoveryCreateCommandVals.toNames, "to", "t", nil, "The contacts to bundle a share to, one share each. May be repeated or comma separated.")
+	recoveryCreateCmd.Flags().StringVarP(&localRecoveryCreateCommandVals.fromName, "from", "r", "default", "The name of the keypair to send the share bundles from.")
+	recoveryCreateCmd.Flags().StringVarP(&localRecoveryCreateCommandVals.outputPath, "output-path", "p", "", "The path to write the share bundles to. Defaults to the current directory.")
+}
+
+func createRecoveryShares(keypairName string) {
+	if strings.EqualFold(keypairName, helpers.KeyPairNameForKeyStoreReads) ||
+		strings.EqualFold(keypairName, helpers.KeyPairNameForKeyStoreWrites) {
+		logger.Errorfln("The system keypair \"%s\" can not be split for recovery.", keypairName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	contacts, err := getRecoveryContacts(localRecoveryCreateCommandVals.toNames, localRecoveryCreateCommandVals.threshold)
+	if err != nil {
+		logger.Errorfln("Unable to create recovery shares: %s", err)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	kpi, err := getRecoveryKeyPair(keypairName)
+	if err != nil {
+		logger.Errorfln("Unable to create recovery shares: %s", err)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+	defer kpi.Wipe()
+
+	senderKPI, err := getRecoveryKeyPair(localRecoveryCreateCommandVals.fromName)
+	if err != nil {
+		logger.Errorfln("Unable to create recovery shares: %s", err)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+	defer senderKPI.Wipe()
+
+	shares, err := security.NewRecoveryShares(kpi, localRecoveryCreateCommandVals.threshold, len(contacts))
+	if err != nil {
+		logger.Errorfln("Unable to create recovery shares: %s", err)
+		helpers.ExitCode = helpers.ExitCodeCipherError
+		return
+	}
+
+	results := make([]*recoveryShareResult, 0, len(shares))
+	for idx, share := range shares {
+		result := &recoveryShareResult{contactName: contacts[idx].Name, shareIndex: share.Index}
+		results = append(results, result)
+
+		fileName := fmt.Sprintf("%s.%s.share%d.bcomb",
+			helpers.GetFileSafeName(share.Name), helpers.GetFileSafeName(contacts[idx].Name), share.Index)
+		result.outputFile = filepath.Join(localRecoveryCreateCommandVals.outputPath, fileName)
+		result.bytesWritten, result.err = writeRecoveryShareBundle(share, contacts[idx], senderKPI, result.outputFile)
+		share.Wipe()
+	}
+
+	printRecoveryShareReport(shares[0], results)
+}
+
+// getRecoveryContacts returns the keystore users for the contact names.  Contacts must be unique, there must be at
+// least threshold of them, and each must be accepted by the trust policy.
+func getRecoveryContacts(contactNames []string, threshold int) ([]*security.Entity, error) {
+	if threshold < 2 {
+		return nil, fmt.Errorf("--threshold must be at least 2, but is %d", threshold)
+	}
+
+	if len(contactNames) < threshold {
+		return nil, fmt.Errorf("%d contact(s) provided in --to, but the threshold requires at least %d", len(contactNames), threshold)
+	}
+
+	if len(contactNames) > security.MaxSecretShares {
+		return nil, fmt.Errorf("%d contacts provided in --to, but the maximum is %d", len(contactNames), security.MaxSecretShares)
+	}
+
+	contacts, err := getGroupMemberEntities(contactNames)
+	if err != nil {
+		return nil, err
+	}
+
+	for idx, contact := range contacts {
+		for _, prior := range contacts[:idx] {
+			if strings.EqualFold(prior.Name, contact.Name) {
+				return nil, fmt.Errorf("contact \"%s\" was provided more than once", contact.Name)
+			}
+		}
+
+		err = checkEntityTrust(contact, "contact")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return contacts, nil
+}
+
+// printRecoveryShareReport prints the outcome for each contact
+func printRecoveryShareReport(share *security.RecoveryShare, results []*recoveryShareResult) {
+	writtenCount := 0
+
+	fmt.Printf("Recovery Shares for Keypair %s\n", share.Name)
+	fmt.Println("=========================================================")
+	for _, result := range results {
+		if result.err != nil {
+			fmt.Printf("%-18s : FAILED   share %d: %s\n", result.contactName, result.shareIndex, result.err)
+			continue
+		}
+
+		writtenCount++
+		fmt.Printf("%-18s : WRITTEN  share %d to %s (%d bytes)\n", result.contactName, result.shareIndex, result.outputFile, result.bytesWritten)
+	}
+	fmt.Println("")
+
+	fmt.Printf("Fingerprint : %s\n", share.Fingerprint().Hex())
+	fmt.Printf("Set ID      : %s\n", share.SetID)
+	fmt.Println("")
+
+	if writtenCount < len(results) {
+		helpers.ExitCode = helpers.ExitCodeOutputError
+	}
+
+	if writtenCount < share.Threshold {
+		fmt.Printf("Only %d share bundle(s) were written, but %d are required to restore.  Run create again.\n", writtenCount, share.Threshold)
+		return
+	}
+
+	fmt.Printf("Send each bundle to its contact.  Any %d of the %d contacts can help restore \"%s\".\n", share.Threshold, share.ShareCount, share.Name)
+	fmt.Println("Keep a note of who holds the shares, since you will need to contact them to restore.")
+}
@@ -0,0 +1,291 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/logger"
+	"github.com/thoughtrealm/bumblebee/security"
+	"strings"
+)
+
+type recoveryRestoreCommandVals struct {
+	fromNames     []string
+	toName        string
+	keypairName   string
+	replace       bool
+	ignoreConfirm bool
+}
+
+var localRecoveryRestoreCommandVals = &recoveryRestoreCommandVals{}
+
+type recoveryRestoreResult struct {
+	bundleFile  string
+	contactName string
+	share       *security.RecoveryShare
+	skipReason  string
+	err         error
+}
+
+// recoveryRestoreCmd represents the recovery restore command
+var recoveryRestoreCmd = &cobra.Command{
+	Use:   "restore <share-bundle> <share-bundle> ... [--from <user1,user2,...>]",
+	Args:  cobra.MinimumNArgs(1),
+	Short: "Restores a keypair from the share bundles returned by contacts",
+	Long: `Opens the share bundles returned by your contacts with "recovery return", and once there are enough shares,
+restores the keypair into this profile under its original name.  Bundles are opened with the --to keypair, and
+each bundle is tried with every user in --from, or with every user in the keystore if --from is not provided.
+
+A fresh profile already has a "default" keypair.  To restore a keypair named "default" into it, either use
+--name to restore it under another name, or --replace to replace the existing keypair.`,
+	Example: `  -- Restore from the bundles returned by 2 contacts, replacing the fresh profile's default keypair
+  bumblebee recovery restore default.share1.bcomb default.share3.bcomb --replace`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		restoreFromRecoveryShares(args)
+	},
+}
+
+func init() {
+	recoveryCmd.AddCommand(recoveryRestoreCmd)
+	recoveryRestoreCmd.Flags().StringSliceVarP(&localRecoveryRestoreCommandVals.fromNames, "from", "r", nil, "The contacts that returned the bundles. May be repeated or comma separated. Defaults to all users in the keystore.")
+	recoveryRestoreCmd.Flags().StringVarP(&localRecoveryRestoreCommandVals.toName, "to", "t", "default", "The name of the keypair the bundles were returned to.")
+	recoveryRestoreCmd.Flags().StringVarP(&localRecoveryRestoreCommandVals.keypairName, "name", "n", "", "The name to restore the keypair as. Defaults to the keypair's original name.")
+	recoveryRestoreCmd.Flags().BoolVarP(&localRecoveryRestoreCommandVals.replace, "replace", "", false, "If set, an existing keypair with the same name is replaced by the restored keypair.")
+	recoveryRestoreCmd.Flags().BoolVarP(&localRecoveryRestoreCommandVals.ignoreConfirm, "ignore-confirm", "i", false, "If set, user will not be prompted to confirm replacing a keypair")
+}
+
+func restoreFromRecoveryShares(bundleFilePaths []string) {
+	if keypairs.GlobalKeyPairStore == nil || keystore.GlobalKeyStore == nil {
+		logger.Errorln("Unable to restore: keystore or keypair store not loaded")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	contacts, err := getRecoveryRestoreContacts()
+	if err != nil {
+		logger.Errorfln("Unable to restore: %s", err)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	receiverKPI, err := getRecoveryKeyPair(localRecoveryRestoreCommandVals.toName)
+	if err != nil {
+		logger.Errorfln("Unable to restore: %s", err)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+	defer receiverKPI.Wipe()
+
+	results := make([]*recoveryRestoreResult, 0, len(bundleFilePaths))
+	for _, bundleFilePath := range bundleFilePaths {
+		results = append(results, readReturnedShareBundle(bundleFilePath, receiverKPI, contacts))
+	}
+
+	shares := printRecoveryRestoreReport(results)
+	defer func() {
+		for _, result := range results {
+			if result.share != nil {
+				result.share.Wipe()
+			}
+		}
+	}()
+
+	if len(shares) == 0 {
+		logger.Errorln("Unable to restore: no shares could be read")
+		helpers.ExitCode = helpers.ExitCodeCipherError
+		return
+	}
+
+	if len(shares) < shares[0].Threshold {
+		logger.Errorfln("Unable to restore: %d share(s) read, but %d are required.  Ask more of your contacts to return their share.",
+			len(shares), shares[0].Threshold)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	kpi, err := security.CombineRecoveryShares(shares)
+	if err != nil {
+		logger.Errorfln("Unable to restore: %s", err)
+		helpers.ExitCode = helpers.ExitCodeCipherError
+		return
+	}
+	defer kpi.Wipe()
+
+	importRestoredKeyPair(kpi, shares[0])
+}
+
+// getRecoveryRestoreContacts returns the users to try as the sender of each bundle
+func getRecoveryRestoreContacts() ([]*security.Entity, error) {
+	if len(localRecoveryRestoreCommandVals.fromNames) > 0 {
+		return getGroupMemberEntities(localRecoveryRestoreCommandVals.fromNames)
+	}
+
+	var contacts []*security.Entity
+	err := keystore.GlobalKeyStore.Walk(keystore.NewWalkInfo("", true, nil, func(entity *security.Entity) {
+		if entity.Trust != security.TrustLevelRevoked {
+			contacts = append(contacts, entity)
+		}
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(contacts) == 0 {
+		return nil, errors.New("the keystore has no users.  Add the public keys of your contacts before restoring")
+	}
+
+	return contacts, nil
+}
+
+// readReturnedShareBundle opens the bundle with the first contact it was sent from.  A bundle that no contact
+// opens is reported as failed.
+func readReturnedShareBundle(bundleFilePath string, receiverKPI *security.KeyPairInfo, contacts []*security.Entity) *recoveryRestoreResult {
+	result := &recoveryRestoreResult{bundleFile: bundleFilePath}
+	for _, contact := range contacts {
+		share, err := readRecoveryShareBundle(bundleFilePath, receiverKPI, contact)
+		if err != nil {
+			logger.Debugfln("Bundle %s was not opened as sent from \"%s\": %s", bundleFilePath, contact.Name, err)
+			continue
+		}
+
+		result.contactName = contact.Name
+		result.share = share
+
+		trustErr := checkEntityTrust(contact, "contact")
+		if trustErr != nil {
+			result.skipReason = trustErr.Error()
+		}
+
+		return result
+	}
+
+	result.err = errors.New("not a share bundle returned to this keypair by a known contact")
+	return result
+}
+
+// printRecoveryRestoreReport prints the outcome for each bundle and returns the shares that can be combined.
+// Shares that were returned more than once are only used once.
+func printRecoveryRestoreReport(results []*recoveryRestoreResult) []*security.RecoveryShare {
+	var shares []*security.RecoveryShare
+
+	fmt.Println("Returned Recovery Shares")
+	fmt.Println("=========================================================")
+	for _, result := range results {
+		switch {
+		case result.err != nil:
+			fmt.Printf("%s : FAILED   %s\n", result.bundleFile, result.err)
+		case result.skipReason != "":
+			fmt.Printf("%s : SKIPPED  %s\n", result.bundleFile, result.skipReason)
+		case len(shares) > 0 && shares[0].SetID != result.share.SetID:
+			fmt.Printf("%s : SKIPPED  share %d from \"%s\" is for a different recovery set (%s)\n",
+				result.bundleFile, result.share.Index, result.contactName, result.share.SetID)
+		case hasRecoveryShareIndex(shares, result.share.Index):
+			fmt.Printf("%s : SKIPPED  share %d from \"%s\" was already read\n", result.bundleFile, result.share.Index, result.contactName)
+		default:
+			shares = append(shares, result.share)
+			fmt.Printf("%s : READ     share %d of %d from \"%s\"\n", result.bundleFile, result.share.Index, result.share.ShareCount, result.contactName)
+		}
+	}
+	fmt.Println("")
+
+	return shares
+}
+
+func hasRecoveryShareIndex(shares []*security.RecoveryShare, index int) bool {
+	for _, share := range shares {
+		if share.Index == index {
+			return true
+		}
+	}
+
+	return false
+}
+
+// importRestoredKeyPair adds the restored keypair to the keypair store, replacing an existing keypair if requested
+func importRestoredKeyPair(kpi *security.KeyPairInfo, share *security.RecoveryShare) {
+	if localRecoveryRestoreCommandVals.keypairName != "" {
+		kpi.Name = localRecoveryRestoreCommandVals.keypairName
+	}
+
+	if strings.EqualFold(kpi.Name, helpers.KeyPairNameForKeyStoreReads) ||
+		strings.EqualFold(kpi.Name, helpers.KeyPairNameForKeyStoreWrites) {
+		logger.Errorfln("The name \"%s\" is reserved for a system keypair.  Use --name to restore under another name.", kpi.Name)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	printRecoveryShareDetails(share)
+	fmt.Println("")
+
+	existingKPI := keypairs.GlobalKeyPairStore.GetKeyPairInfo(kpi.Name)
+	if existingKPI != nil {
+		existingKPI.Wipe()
+		if !localRecoveryRestoreCommandVals.replace {
+			logger.Errorfln("A keypair named \"%s\" already exists.  Use --name to restore under another name, or --replace to replace it.", kpi.Name)
+			helpers.ExitCode = helpers.ExitCodeInvalidInput
+			return
+		}
+
+		if !localRecoveryRestoreCommandVals.ignoreConfirm {
+			response, err := helpers.GetYesNoInput(
+				fmt.Sprintf("Are you sure you wish to replace the keypair \"%s\" with the restored keypair?", kpi.Name),
+				helpers.InputResponseValNo)
+			if err != nil {
+				logger.Errorfln("Unable to confirm replacing keypair: %s", err)
+				helpers.ExitCode = helpers.ExitCodeRequestFailed
+				return
+			}
+
+			if response != helpers.InputResponseValYes {
+				logger.Println("User aborted restore request")
+				helpers.ExitCode = helpers.ExitCodeInputError
+				return
+			}
+		}
+
+		_, err := keypairs.GlobalKeyPairStore.RemoveKeyPair(kpi.Name)
+		if err != nil {
+			logger.Errorfln("Unable to replace keypair \"%s\": %s", kpi.Name, err)
+			helpers.ExitCode = helpers.ExitCodeRequestFailed
+			return
+		}
+	}
+
+	err := keypairs.GlobalKeyPairStore.ImportKeyPair(kpi)
+	if err != nil {
+		logger.Errorfln("Unable to restore keypair: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	err = keypairs.GlobalKeyPairStore.SaveKeyPairStoreToOrigin(nil)
+	if err != nil {
+		logger.Errorfln("Unable to restore keypair: keypair store could not update the file: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	logger.Printfln("Keypair \"%s\" restored and keypair store file changes committed.", kpi.Name)
+}
@@ -0,0 +1,136 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/logger"
+	"path/filepath"
+)
+
+type recoveryReturnCommandVals struct {
+	fromName    string
+	toName      string
+	keypairName string
+	outputPath  string
+}
+
+var localRecoveryReturnCommandVals = &recoveryReturnCommandVals{}
+
+// recoveryReturnCmd represents the recovery return command
+var recoveryReturnCmd = &cobra.Command{
+	Use:   "return <share-bundle> --from <owner> --to <owner-new-keys>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Returns a recovery share you hold to the owner's new keys",
+	Long: `Opens a share bundle you were sent by --from, and bundles the share to the --to user, which holds the
+public keys of the owner's fresh profile.  The share is only held in memory, so it is not written to disk
+unencrypted.
+
+Before returning a share, confirm with the owner in person or by phone that the --to keys are theirs, such as
+with "fingerprint compare".  Anyone holding enough returned shares can restore the owner's keypair.`,
+	Example: `  -- Return the share bob holds for alice to the keys of her fresh profile, imported as "alice-new"
+  bumblebee recovery return default.bob.share2.bcomb --from alice --to alice-new`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		returnRecoveryShare(args[0])
+	},
+}
+
+func init() {
+	recoveryCmd.AddCommand(recoveryReturnCmd)
+	recoveryReturnCmd.Flags().StringVarP(&localRecoveryReturnCommandVals.fromName, "from", "r", "", "The name of the user that created the share and sent you the bundle.")
+	recoveryReturnCmd.Flags().StringVarP(&localRecoveryReturnCommandVals.toName, "to", "t", "", "The name of the user with the owner's new keys to return the share to.")
+	recoveryReturnCmd.Flags().StringVarP(&localRecoveryReturnCommandVals.keypairName, "keypair", "k", "default", "The name of your keypair the share bundle was sent to, which also sends the returned bundle.")
+	recoveryReturnCmd.Flags().StringVarP(&localRecoveryReturnCommandVals.outputPath, "output-path", "p", "", "The path to write the returned share bundle to. Defaults to the current directory.")
+}
+
+func returnRecoveryShare(bundleFilePath string) {
+	if localRecoveryReturnCommandVals.fromName == "" || localRecoveryReturnCommandVals.toName == "" {
+		logger.Errorln("The --from and --to users are required to return a share")
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	if keystore.GlobalKeyStore == nil {
+		logger.Errorln("Unable to return share: keystore not loaded")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	// The owner may have revoked the keys the share was sent from after losing them, so the sender is not
+	// checked for revocation.  The recipient is who matters, and must pass the trust policy.
+	sender := keystore.GlobalKeyStore.GetKey(localRecoveryReturnCommandVals.fromName)
+	if sender == nil {
+		logger.Errorfln("Unable to return share: user \"%s\" was not found in the keystore", localRecoveryReturnCommandVals.fromName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	recipients, err := getGroupMemberEntities([]string{localRecoveryReturnCommandVals.toName})
+	if err == nil {
+		err = checkEntityTrust(recipients[0], "recipient")
+	}
+
+	if err != nil {
+		logger.Errorfln("Unable to return share: %s", err)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+	recipient := recipients[0]
+
+	kpi, err := getRecoveryKeyPair(localRecoveryReturnCommandVals.keypairName)
+	if err != nil {
+		logger.Errorfln("Unable to return share: %s", err)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+	defer kpi.Wipe()
+
+	share, err := readRecoveryShareBundle(bundleFilePath, kpi, sender)
+	if err != nil {
+		logger.Errorfln("Unable to open share bundle: %s", err)
+		helpers.ExitCode = helpers.ExitCodeCipherError
+		return
+	}
+	defer share.Wipe()
+
+	if recipient.PublicKeys.Fingerprint().Equal(share.Fingerprint()) {
+		logger.Errorfln("Unable to return share: user \"%s\" has the keys the share was split from, not the owner's new keys", recipient.Name)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	printRecoveryShareDetails(share)
+	fmt.Println("")
+
+	fileName := fmt.Sprintf("%s.share%d.bcomb", helpers.GetFileSafeName(share.Name), share.Index)
+	outputFile := filepath.Join(localRecoveryReturnCommandVals.outputPath, fileName)
+	bytesWritten, err := writeRecoveryShareBundle(share, recipient, kpi, outputFile)
+	if err != nil {
+		logger.Errorfln("Unable to write returned share bundle: %s", err)
+		helpers.ExitCode = helpers.ExitCodeOutputError
+		return
+	}
+
+	logger.Printfln("Share %d returned to \"%s\" in %s (%d bytes).", share.Index, recipient.Name, outputFile, bytesWritten)
+	logger.Println("Send the bundle to the owner.")
+}
@@ -0,0 +1,118 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	cipherio "github.com/thoughtrealm/bumblebee/cipher/io"
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/security"
+)
+
+// recoveryCmd represents the recovery command
+var recoveryCmd = &cobra.Command{
+	Use:   "recovery",
+	Short: "Splits a keypair into shares held by trusted contacts, and restores it from them",
+	Long: `Social recovery splits a keypair into shares with Shamir's secret sharing.  Each share is bundled to one
+trusted contact, and any threshold number of the shares can restore the keypair.  Fewer shares reveal nothing
+about the keys.
+
+To restore, create a fresh profile and add your contacts' public keys to it, then send the public keys of the
+fresh profile to your contacts.  Each contact returns their share with "recovery return", and the returned
+bundles are combined with "recovery restore".`,
+	Run: func(cmd *cobra.Command, args []string) {
+		_ = cmd.Help()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(recoveryCmd)
+}
+
+// getRecoveryKeyPair returns the keypair used to open or send share bundles
+func getRecoveryKeyPair(keypairName string) (*security.KeyPairInfo, error) {
+	if keypairs.GlobalKeyPairStore == nil {
+		return nil, errors.New("keypair store not loaded")
+	}
+
+	kpi := keypairs.GlobalKeyPairStore.GetKeyPairInfo(keypairName)
+	if kpi == nil {
+		return nil, fmt.Errorf("no keypair exists by the name \"%s\"", keypairName)
+	}
+
+	if kpi.IsGroup() {
+		kpi.Wipe()
+		return nil, fmt.Errorf("share bundles can not be sent or opened with the group identity \"%s\"", keypairName)
+	}
+
+	return kpi, nil
+}
+
+// writeRecoveryShareBundle writes one share in a bundle to the recipient
+func writeRecoveryShareBundle(share *security.RecoveryShare, recipient *security.Entity, senderKPI *security.KeyPairInfo, outputFile string) (int, error) {
+	eki, err := security.NewExportKeyInfoFromRecoveryShare(share)
+	if err != nil {
+		return 0, err
+	}
+
+	ekiBytes, err := eki.ToBytes()
+	if err != nil {
+		return 0, fmt.Errorf("unable to serialize recovery share: %w", err)
+	}
+	defer security.Wipe(ekiBytes)
+
+	cipherWriter, err := cipherio.NewCipherWriter(recipient.PublicKeys, senderKPI)
+	if err != nil {
+		return 0, fmt.Errorf("unable to create cipher writer: %w", err)
+	}
+	defer cipherWriter.Wipe()
+
+	cipherWriter.OutputBundleInfo.InputSource = cipherio.BundleInputSourceDirect
+	cipherWriter.OutputBundleInfo.OriginalFileName = fmt.Sprintf("%s.share%d.recovery", share.Name, share.Index)
+	return cipherWriter.WriteToCombinedFileFromReader(outputFile, bytes.NewReader(ekiBytes))
+}
+
+// readRecoveryShareBundle opens a share bundle in memory and returns the share it holds
+func readRecoveryShareBundle(bundleFilePath string, receiverKPI *security.KeyPairInfo, sender *security.Entity) (*security.RecoveryShare, error) {
+	cipherReader, err := cipherio.NewCipherFileReader(receiverKPI, sender.PublicKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer cipherReader.Wipe()
+
+	ekiBytes, err := cipherReader.ReadCombinedFileToBytes(bundleFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer security.Wipe(ekiBytes)
+
+	eki, err := security.NewExportKeyInfoFromBytes(ekiBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return eki.GetRecoveryShare()
+}
+
+// printRecoveryShareDetails prints which keypair a share belongs to and where it sits in its set
+func printRecoveryShareDetails(share *security.RecoveryShare) {
+	fmt.Printf("Keypair     : %s\n", share.Name)
+	fmt.Printf("Fingerprint : %s\n", share.Fingerprint().Hex())
+	fmt.Printf("Share       : %d of %d, any %d restore the keypair\n", share.Index, share.ShareCount, share.Threshold)
+	fmt.Printf("Created     : %s\n", share.CreatedDate)
+	fmt.Printf("Set ID      : %s\n", share.SetID)
+}
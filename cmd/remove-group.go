@@ -0,0 +1,86 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keystore"
+)
+
+// removeGroupCmd represents the remove group command
+var removeGroupCmd = &cobra.Command{
+	Use:   "group <name>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Will remove the referenced group from the keystore",
+	Long:  "Will remove the referenced group from the keystore.  The member users are not removed.",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// bootstrap will print its own messages
+			return
+		}
+
+		removeGroup(keystore.GroupNameFromReference(args[0]))
+	},
+}
+
+func init() {
+	removeCmd.AddCommand(removeGroupCmd)
+}
+
+func removeGroup(groupName string) {
+	if keystore.GlobalKeyStore == nil {
+		fmt.Println("Unable to remove group: keystore not loaded")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	group := keystore.GlobalKeyStore.GetGroup(groupName)
+	if group == nil {
+		fmt.Printf("No group was found with name \"%s\"\n", groupName)
+		return
+	}
+
+	printGroup(group)
+	response, err := helpers.GetYesNoInput(fmt.Sprintf("Are you sure you wish to remove the group \"%s\"?", group.Name), helpers.InputResponseValNo)
+	fmt.Println("")
+	if err != nil {
+		fmt.Printf("Unable to confirm removal of group: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	if response != helpers.InputResponseValYes {
+		fmt.Println("User aborted removal request")
+		helpers.ExitCode = helpers.ExitCodeInputError
+		return
+	}
+
+	found, err := keystore.GlobalKeyStore.RemoveGroup(groupName)
+	if !found {
+		fmt.Printf("Was unable to locate the group during removal: \"%s\"\n", groupName)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	if err != nil {
+		fmt.Printf("Unable to remove group named \"%s\": %s\n", groupName, err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	fmt.Println("Group removed.")
+}
@@ -73,6 +73,7 @@ func removeKeypair(keypairName string) {
 		return
 	}
 	fmt.Println()
+	fmt.Println("The private keys are not kept once removed, so the removal cannot be undone.")
 
 	response, err := helpers.GetYesNoInput(fmt.Sprintf("Are you sure you wish to remove the keypair \"%s\"?", keypairName), helpers.InputResponseValNo)
 	if err != nil {
@@ -94,4 +94,5 @@ func removeUser(userName string) {
 	}
 
 	fmt.Println("User removed.")
+	updateTrustedIntroducerName(userName, "")
 }
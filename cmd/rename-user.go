@@ -73,4 +73,5 @@ func renameUser(oldUserName, newUserName string) {
 	}
 
 	fmt.Println("User renamed and keystore file changes committed.")
+	updateTrustedIntroducerName(oldUserName, newUserName)
 }
@@ -437,6 +437,10 @@ func backupExecuteRestore() error {
 			KeyPairStorePath:      profileKeypairStorePath,
 			KeyPairStoreEncrypted: profileFromBackup.KeyPairStoreEncrypted,
 			DefaultKeypairName:    profileFromBackup.DefaultKeypairName,
+			EncryptToSelf:         profileFromBackup.EncryptToSelf,
+			SelfKeypairName:       profileFromBackup.SelfKeypairName,
+			TrustPolicy:           profileFromBackup.TrustPolicy,
+			TrustedIntroducers:    profileFromBackup.TrustedIntroducers,
 		}
 
 		configHelper := helpers.NewConfigHelper()
@@ -0,0 +1,158 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/logger"
+	"github.com/thoughtrealm/bumblebee/security"
+	"strings"
+	"time"
+)
+
+type revokeKeypairCommandVals struct {
+	reason        string
+	effectiveDate string
+	statementName string
+}
+
+var localRevokeKeypairCommandVals = &revokeKeypairCommandVals{}
+
+// revokeKeypairCmd represents the revoke keypair subcommand
+var revokeKeypairCmd = &cobra.Command{
+	Use:   "keypair <name>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Creates a signed revocation certificate for a keypair",
+	Long: `Creates a revocation certificate for a keypair, signed by the keypair's signing key.
+The keypair itself is not changed, so the certificate can be created ahead of time and stored offline.
+If the keypair is lost or stolen, send the certificate to your contacts.  When they import it,
+bundles to you are refused and bundles from you after the revocation date are flagged.
+
+Anyone with the certificate can revoke the keypair, so store it somewhere safe.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(false, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		revokeKeypair(args[0])
+	},
+}
+
+func init() {
+	revokeCmd.AddCommand(revokeKeypairCmd)
+	revokeKeypairCmd.Flags().StringVarP(&localRevokeKeypairCommandVals.reason, "reason", "r", security.RevocationReasonUnspecified, "The reason for the revocation. One of: unspecified, compromised, superseded or retired.")
+	revokeKeypairCmd.Flags().StringVarP(&localRevokeKeypairCommandVals.effectiveDate, "effective-date", "d", "",
+		`The date the keys stop being valid, as "now", a date like 2024-01-31, or an RFC3339 date and time.
+If not provided, the revocation takes effect when each contact imports it, which suits
+certificates created ahead of time.`)
+	revokeKeypairCmd.Flags().StringVarP(&localRevokeKeypairCommandVals.statementName, "name", "n", "", "The user name to put in the certificate. Defaults to the keypair name, or the profile's default keypair name for \"default\".")
+	registerPublicStatementOutputFlags(revokeKeypairCmd)
+}
+
+func revokeKeypair(keypairName string) {
+	if strings.EqualFold(keypairName, helpers.KeyPairNameForKeyStoreReads) ||
+		strings.EqualFold(keypairName, helpers.KeyPairNameForKeyStoreWrites) {
+		logger.Errorfln("Revoking the system keypair \"%s\" is not allowed.", keypairName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	if !security.IsValidRevocationReason(localRevokeKeypairCommandVals.reason) {
+		logger.Errorfln("Unknown revocation reason \"%s\".  Expected one of: unspecified, compromised, superseded or retired.", localRevokeKeypairCommandVals.reason)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	effectiveDate, err := parseRevocationEffectiveDate(localRevokeKeypairCommandVals.effectiveDate)
+	if err != nil {
+		logger.Errorfln("Invalid effective date: %s", err)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	if keypairs.GlobalKeyPairStore == nil {
+		logger.Errorln("Unable to revoke keypair: keypair store not loaded")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	kpi := keypairs.GlobalKeyPairStore.GetKeyPairInfo(keypairName)
+	if kpi == nil {
+		logger.Errorfln("Unable to revoke keypair: keypair not found with name \"%s\"", keypairName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+	defer kpi.Wipe()
+
+	certificate, err := security.NewRevocationCertificate(
+		getPublicStatementName(keypairName, localRevokeKeypairCommandVals.statementName),
+		kpi,
+		localRevokeKeypairCommandVals.reason,
+		effectiveDate)
+	if err != nil {
+		logger.Errorfln("Unable to create revocation certificate: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	fp := certificate.KeyInfo().Fingerprint()
+	logger.Printfln("Revocation certificate created for keypair \"%s\".", keypairName)
+	logger.Printfln("Fingerprint    : %s", fp.Hex())
+	logger.Printfln("Reason         : %s", certificate.Reason)
+	if certificate.EffectiveDate != "" {
+		logger.Printfln("Effective Date : %s", certificate.EffectiveDate)
+	} else {
+		logger.Println("Effective Date : when imported")
+	}
+	logger.Println("")
+	logger.Println("Anyone with this certificate can revoke the keypair.  Store it somewhere safe until it is needed.")
+	logger.Println("")
+
+	eki, err := security.NewExportKeyInfoFromRevocation(certificate)
+	if err == nil {
+		err = exportPublicStatement(eki, "revocation")
+	}
+
+	if err != nil {
+		logger.Errorfln("Unable to export revocation certificate: %s", err)
+		helpers.ExitCode = helpers.ExitCodeOutputError
+		return
+	}
+}
+
+// parseRevocationEffectiveDate converts the effective date flag to RFC3339.  An empty value is returned as empty.
+func parseRevocationEffectiveDate(dateText string) (string, error) {
+	switch {
+	case dateText == "":
+		return "", nil
+	case strings.EqualFold(dateText, "now"):
+		return time.Now().UTC().Format(time.RFC3339), nil
+	}
+
+	if dateTime, err := time.Parse(time.RFC3339, dateText); err == nil {
+		return dateTime.UTC().Format(time.RFC3339), nil
+	}
+
+	dateTime, err := time.ParseInLocation(time.DateOnly, dateText, time.Local)
+	if err != nil {
+		return "", fmt.Errorf("expected \"now\", a date like 2024-01-31 or an RFC3339 date and time: %s", dateText)
+	}
+
+	return dateTime.UTC().Format(time.RFC3339), nil
+}
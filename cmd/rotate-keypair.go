@@ -0,0 +1,162 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/logger"
+	"github.com/thoughtrealm/bumblebee/security"
+	"strings"
+	"time"
+)
+
+type rotateKeypairCommandVals struct {
+	discardOld    bool
+	statementName string
+}
+
+var localRotateKeypairCommandVals = &rotateKeypairCommandVals{}
+
+// rotateKeypairCmd represents the rotate keypair subcommand
+var rotateKeypairCmd = &cobra.Command{
+	Use:   "keypair <name>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Replaces a keypair with new keys and emits a succession statement for your contacts",
+	Long: `Replaces a keypair with new cipher and signing keys. A succession statement is emitted that is
+signed by both the old and the new signing keys. Contacts can import the statement to move their
+stored user to the new keys, which is verified against the old signing key they already have.
+The old keys are kept under a retired keypair name, so older bundles can still be opened with --to.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		rotateKeypair(args[0])
+	},
+}
+
+func init() {
+	rotateCmd.AddCommand(rotateKeypairCmd)
+	rotateKeypairCmd.Flags().BoolVarP(&localRotateKeypairCommandVals.discardOld, "discard-old", "", false, "Does not keep the old keys as a retired keypair. Older bundles sent to this keypair can no longer be opened.")
+	rotateKeypairCmd.Flags().StringVarP(&localRotateKeypairCommandVals.statementName, "name", "n", "", "The user name to put in the succession statement. Defaults to the keypair name, or the profile's default keypair name for \"default\".")
+	registerPublicStatementOutputFlags(rotateKeypairCmd)
+}
+
+func rotateKeypair(keypairName string) {
+	if strings.EqualFold(keypairName, helpers.KeyPairNameForKeyStoreReads) ||
+		strings.EqualFold(keypairName, helpers.KeyPairNameForKeyStoreWrites) {
+		logger.Errorfln("Rotating the system keypair \"%s\" is not allowed.", keypairName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	if keypairs.GlobalKeyPairStore == nil {
+		logger.Errorln("Unable to rotate keypair: keypair store not loaded")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	kpi := keypairs.GlobalKeyPairStore.GetKeyPairInfo(keypairName)
+	if kpi != nil && kpi.IsGroup() {
+		kpi.Wipe()
+		logger.Errorfln("Keypair \"%s\" is a group identity.  Use \"group rekey %s\" instead.", keypairName, keypairName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	var retiredName string
+	if !localRotateKeypairCommandVals.discardOld {
+		retiredName = fmt.Sprintf("%s-retired-%s", keypairName, time.Now().Format("20060102150405"))
+	}
+
+	oldKPI, newKPI, err := keypairs.GlobalKeyPairStore.RotateKeyPair(keypairName, retiredName)
+	if err != nil {
+		logger.Errorfln("Unable to rotate keypair: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+	defer oldKPI.Wipe()
+	defer newKPI.Wipe()
+
+	statement, err := security.NewSuccessionStatement(getPublicStatementName(keypairName, localRotateKeypairCommandVals.statementName), oldKPI, newKPI)
+	if err != nil {
+		logger.Errorfln("Unable to create succession statement: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	err = keypairs.GlobalKeyPairStore.SaveKeyPairStoreToOrigin(nil)
+	if err != nil {
+		logger.Errorfln("Unable to rotate keypair: keypair store could not update the file: %s", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	oldFP := statement.OldKeyInfo().Fingerprint()
+	newFP := statement.NewKeyInfo().Fingerprint()
+	logger.Printfln("Keypair \"%s\" rotated and keypair store file changes committed.", keypairName)
+	logger.Printfln("Old Fingerprint : %s", oldFP.Hex())
+	logger.Printfln("New Fingerprint : %s", newFP.Hex())
+	if retiredName != "" {
+		logger.Printfln("Old keys kept as keypair \"%s\"", retiredName)
+	} else {
+		logger.Println("Old keys discarded.  The rotation cannot be undone.")
+	}
+	logger.Println("")
+
+	// Any local users with the old keys, like an entry for yourself, are moved to the new keys as well
+	if keystore.GlobalKeyStore != nil {
+		userName, err := keystore.GlobalKeyStore.ApplyKeySuccession(statement)
+		if err != nil && !errors.Is(err, keystore.ErrNoSuccessionMatch) {
+			logger.Errorfln("Unable to update local user \"%s\" with the new keys: %s", userName, err)
+		} else if err == nil {
+			logger.Printfln("Local user \"%s\" updated with the new keys.", userName)
+			logger.Println("")
+		}
+	}
+
+	eki, err := security.NewExportKeyInfoFromSuccession(statement)
+	if err == nil {
+		logger.Println("Send the following succession statement to your contacts for import.")
+		err = exportPublicStatement(eki, "succession")
+	}
+
+	if err != nil {
+		logger.Errorfln("Unable to export succession statement: %s", err)
+		helpers.ExitCode = helpers.ExitCodeOutputError
+		return
+	}
+}
+
+// getPublicStatementName returns the user name for a statement about a keypair.  For the "default" keypair,
+// this is the profile's default keypair name, which is the name contacts are most likely to know.
+func getPublicStatementName(keypairName, nameOverride string) string {
+	if nameOverride != "" {
+		return nameOverride
+	}
+
+	profile := helpers.GlobalConfig.GetCurrentProfile()
+	if strings.EqualFold(keypairName, "default") && profile != nil && profile.DefaultKeypairName != "" {
+		return profile.DefaultKeypairName
+	}
+
+	return keypairName
+}
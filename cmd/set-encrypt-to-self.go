@@ -0,0 +1,106 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"strings"
+)
+
+// encryptToSelfCmd represents the encrypt-to-self subcommand for "set" command
+var encryptToSelfCmd = &cobra.Command{
+	Use:   "encrypt-to-self <on|off>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Sets whether bundles are also wrapped to one of your own keypairs",
+	Long:  "Sets whether bundles for the current profile also wrap the payload key to one of your own keypairs, so you can reopen bundles you have sent",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(false, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		setEncryptToSelf(args[0])
+	},
+}
+
+type encryptToSelfCommandVals struct {
+	// keypairName is the keypair to wrap the self slot to.  If empty, keystore_read is used.
+	keypairName string
+}
+
+var localEncryptToSelfCommandVals = &encryptToSelfCommandVals{}
+
+func init() {
+	setCmd.AddCommand(encryptToSelfCmd)
+	encryptToSelfCmd.Flags().StringVarP(&localEncryptToSelfCommandVals.keypairName, "keypair", "k", "", "The name of the keypair to wrap the self slot to. If empty, keystore_read is used.")
+}
+
+func setEncryptToSelf(stateText string) {
+	var encryptToSelf bool
+	switch strings.ToLower(stateText) {
+	case "on", "true", "yes":
+		encryptToSelf = true
+	case "off", "false", "no":
+		encryptToSelf = false
+	default:
+		fmt.Printf("Unknown value \"%s\".  Should be one of: on or off.\n", stateText)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	if localEncryptToSelfCommandVals.keypairName != "" {
+		kpi := keypairs.GlobalKeyPairStore.GetKeyPairInfo(localEncryptToSelfCommandVals.keypairName)
+		if kpi == nil {
+			fmt.Printf("Keypair \"%s\" not found\n", localEncryptToSelfCommandVals.keypairName)
+			helpers.ExitCode = helpers.ExitCodeInvalidInput
+			return
+		}
+		kpi.Wipe()
+	}
+
+	profile := helpers.GlobalConfig.GetCurrentProfile()
+	if profile == nil {
+		fmt.Println("Unable to retrieve current profile config")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	profile.EncryptToSelf = encryptToSelf
+	if localEncryptToSelfCommandVals.keypairName != "" {
+		profile.SelfKeypairName = localEncryptToSelfCommandVals.keypairName
+	}
+
+	err := helpers.GlobalConfig.WriteConfig()
+	if err != nil {
+		fmt.Printf("Unable to write updated config metadata: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeOutputError
+		return
+	}
+
+	selfKeypairName := profile.SelfKeypairName
+	if selfKeypairName == "" {
+		selfKeypairName = helpers.KeyPairNameForKeyStoreReads
+	}
+
+	if encryptToSelf {
+		fmt.Printf("Encrypt-to-self enabled for profile \"%s\" using keypair \"%s\"\n", profile.Name, selfKeypairName)
+		return
+	}
+
+	fmt.Printf("Encrypt-to-self disabled for profile \"%s\"\n", profile.Name)
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keystore"
+)
+
+// keystoreFormatCmd represents the keystore-format subcommand for "set" command
+var keystoreFormatCmd = &cobra.Command{
+	Use:   "keystore-format <simple|btree>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Converts the keystore of the current profile to the simple or btree format",
+	Long: "Converts the keystore of the current profile to the simple or btree format.  The simple format is a " +
+		"single encrypted file that is read and written as a whole.  The btree format encrypts each user, group and " +
+		"revocation as a separate record, and only reads and writes the records that are needed, which is much faster " +
+		"for keystores with thousands of users.  The converted keystore is verified before it replaces the current one.",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(false, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		setKeystoreFormat(args[0])
+	},
+}
+
+func init() {
+	setCmd.AddCommand(keystoreFormatCmd)
+}
+
+func setKeystoreFormat(formatText string) {
+	format := keystore.TextToStoreFormat(formatText)
+	if format == keystore.StoreFormatUnknown {
+		fmt.Printf("Unknown keystore format \"%s\".  Should be one of: simple or btree.\n", formatText)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	profile := helpers.GlobalConfig.GetCurrentProfile()
+	if profile == nil {
+		fmt.Println("Unable to retrieve current profile config")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	currentFormat, err := keystore.DetectStoreFormat(profile.KeyStorePath)
+	if err != nil {
+		fmt.Printf("Unable to read the keystore for profile \"%s\": %s\n", profile.Name, err)
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	if currentFormat == format {
+		fmt.Printf("The keystore for profile \"%s\" is already in the %s format\n", profile.Name, keystore.StoreFormatToText(format))
+		return
+	}
+
+	count, err := keystore.ConvertStoreFile(profile.KeyStorePath, format)
+	if err != nil {
+		fmt.Printf("Unable to convert the keystore: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	fmt.Printf(
+		"Keystore for profile \"%s\" converted from the %s format to the %s format with %d user(s)\n",
+		profile.Name,
+		keystore.StoreFormatToText(currentFormat),
+		keystore.StoreFormatToText(format),
+		count)
+}
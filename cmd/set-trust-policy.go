@@ -0,0 +1,135 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/logger"
+	"github.com/thoughtrealm/bumblebee/security"
+	"strings"
+)
+
+// trustPolicyCmd represents the trust-policy subcommand for "set" command
+var trustPolicyCmd = &cobra.Command{
+	Use:   "trust-policy <off|warn|refuse>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Sets how bundle and open respond to unverified users",
+	Long:  "Sets how bundle and open respond to unverified users for the current profile.  The default is warn.",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(false, false)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		setTrustPolicy(args[0])
+	},
+}
+
+func init() {
+	setCmd.AddCommand(trustPolicyCmd)
+}
+
+func setTrustPolicy(policyText string) {
+	policy := strings.ToLower(strings.TrimSpace(policyText))
+	switch policy {
+	case helpers.TrustPolicyOff, helpers.TrustPolicyWarn, helpers.TrustPolicyRefuse:
+	default:
+		fmt.Printf("Unknown trust policy \"%s\".  Should be one of: off, warn or refuse.\n", policyText)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	profile := helpers.GlobalConfig.GetCurrentProfile()
+	if profile == nil {
+		fmt.Println("Unable to retrieve current profile config")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	profile.TrustPolicy = policy
+	err := helpers.GlobalConfig.WriteConfig()
+	if err != nil {
+		fmt.Printf("Unable to write updated config metadata: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeOutputError
+		return
+	}
+
+	fmt.Printf("Trust policy for profile \"%s\" set to %s\n", profile.Name, policy)
+}
+
+// checkEntityTrust applies the profile's trust policy to the counterparty of a bundle or open request.
+// It prints a warning for unverified users, or returns an error if the policy is refuse.
+func checkEntityTrust(entity *security.Entity, role string) error {
+	policy := helpers.TrustPolicyWarn
+	profile := helpers.GlobalConfig.GetCurrentProfile()
+	if profile != nil {
+		policy = profile.GetTrustPolicy()
+	}
+
+	if policy == helpers.TrustPolicyOff || entity.IsVerified() {
+		return nil
+	}
+
+	introducerName := getTrustedIntroducerFor(entity)
+	if introducerName != "" {
+		logger.Debugfln("The %s \"%s\" is accepted as verified by the trusted introducer \"%s\"", role, entity.Name, introducerName)
+		return nil
+	}
+
+	var reason string
+	if entity.Trust == security.TrustLevelRevoked {
+		reason = fmt.Sprintf("the %s \"%s\" has been revoked", role, entity.Name)
+	} else {
+		reason = fmt.Sprintf("the %s \"%s\" is not verified (trust level: %s)", role, entity.Name, security.TrustLevelToText(entity.Trust))
+	}
+
+	if policy == helpers.TrustPolicyRefuse {
+		return fmt.Errorf("%s.  The profile trust policy refuses unverified users", reason)
+	}
+
+	fmt.Printf("WARNING: %s.  Use \"set trust %s verified\" once you have verified their fingerprint.\n", reason, entity.Name)
+	return nil
+}
+
+// checkSenderRevocation flags items from a revoked sender that were created on or after the revocation date.
+// The creation date is set by the sender, so items dated before the revocation are only noted.  The item is
+// refused when the profile trust policy is refuse.
+func checkSenderRevocation(sender *security.Entity, createDate string) error {
+	if sender == nil || sender.Trust != security.TrustLevelRevoked {
+		return nil
+	}
+
+	if !sender.IsRevokedAt(createDate) {
+		fmt.Printf(
+			"NOTE: The sender \"%s\" was revoked on %s.  This item is dated %s, before the revocation.  "+
+				"The date is set by the sender, so confirm it with them if the keys may have been compromised.\n",
+			sender.Name, sender.RevokedDate, createDate)
+		return nil
+	}
+
+	reason := fmt.Sprintf(
+		"the sender \"%s\" was revoked on %s (%s), and this item is dated %s, on or after the revocation",
+		sender.Name, sender.RevokedDate, sender.RevocationReason, createDate)
+
+	profile := helpers.GlobalConfig.GetCurrentProfile()
+	if profile != nil && profile.GetTrustPolicy() == helpers.TrustPolicyRefuse {
+		return fmt.Errorf("%s.  The profile trust policy refuses it", reason)
+	}
+
+	fmt.Printf("WARNING: %s.  It may have been created by someone else holding the revoked keys.\n", reason)
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/security"
+)
+
+// trustCmd represents the trust subcommand for "set" command
+var trustCmd = &cobra.Command{
+	Use:   "trust <user> <unknown|tofu|verified|revoked>",
+	Args:  cobra.ExactArgs(2),
+	Short: "Sets the trust level for a user",
+	Long:  "Sets the trust level for a user.  Use \"verified\" once you have confirmed the user's fingerprint or safety number out-of-band.",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		setTrust(args[0], args[1])
+	},
+}
+
+type trustCommandVals struct {
+	// method describes how the user was verified.  Only relevant for the verified level.
+	method string
+}
+
+var localTrustCommandVals = &trustCommandVals{}
+
+func init() {
+	setCmd.AddCommand(trustCmd)
+	trustCmd.Flags().StringVarP(&localTrustCommandVals.method, "method", "m", "manual", "How the user was verified, such as in-person, phone or safety-number. Only relevant for verified.")
+}
+
+func setTrust(userName, trustText string) {
+	trust, ok := security.TextToTrustLevel(trustText)
+	if !ok {
+		fmt.Printf("Unknown trust level \"%s\".  Should be one of: unknown, tofu, verified or revoked.\n", trustText)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	found, err := keystore.GlobalKeyStore.SetEntityTrust(userName, trust, localTrustCommandVals.method)
+	if !found {
+		fmt.Printf("No user with the name \"%s\" was found.\n", userName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	if err != nil {
+		fmt.Printf("Unable to update trust level: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	fmt.Printf("Trust level for \"%s\" set to %s\n", userName, security.TrustLevelToText(trust))
+}
@@ -0,0 +1,160 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"strings"
+)
+
+type trustedIntroducersCommandVals struct {
+	addNames    []string
+	removeNames []string
+	clear       bool
+}
+
+var localTrustedIntroducersCommandVals = &trustedIntroducersCommandVals{}
+
+// trustedIntroducersCmd represents the trusted-introducers subcommand for "set" command
+var trustedIntroducersCmd = &cobra.Command{
+	Use:   "trusted-introducers",
+	Args:  cobra.NoArgs,
+	Short: "Sets the users whose certifications are accepted as verification",
+	Long: `Sets the users whose certifications are accepted as verification of other users' keys for the current profile.
+Users certified by a trusted introducer are treated as verified by the trust policy.  Introducers must be verified
+users in the keystore.  With no flags, the current trusted introducers are listed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		setTrustedIntroducers()
+	},
+}
+
+func init() {
+	setCmd.AddCommand(trustedIntroducersCmd)
+	trustedIntroducersCmd.Flags().StringSliceVarP(&localTrustedIntroducersCommandVals.addNames, "add", "a", nil, "User names to add as trusted introducers")
+	trustedIntroducersCmd.Flags().StringSliceVarP(&localTrustedIntroducersCommandVals.removeNames, "remove", "r", nil, "User names to remove from the trusted introducers")
+	trustedIntroducersCmd.Flags().BoolVarP(&localTrustedIntroducersCommandVals.clear, "clear", "", false, "Removes all trusted introducers")
+}
+
+func setTrustedIntroducers() {
+	profile := helpers.GlobalConfig.GetCurrentProfile()
+	if profile == nil {
+		fmt.Println("Unable to retrieve current profile config")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	vals := localTrustedIntroducersCommandVals
+	if !vals.clear && len(vals.addNames) == 0 && len(vals.removeNames) == 0 {
+		printTrustedIntroducers(profile)
+		return
+	}
+
+	introducers := profile.TrustedIntroducers
+	if vals.clear {
+		introducers = nil
+	}
+
+	for _, name := range vals.removeNames {
+		introducers = removeTrustedIntroducerName(introducers, name)
+	}
+
+	for _, name := range vals.addNames {
+		entity := keystore.GlobalKeyStore.GetKey(name)
+		if entity == nil {
+			fmt.Printf("No user with the name \"%s\" was found.\n", name)
+			helpers.ExitCode = helpers.ExitCodeInvalidInput
+			return
+		}
+
+		if !entity.IsVerified() {
+			fmt.Printf("User \"%s\" is not verified.  Use \"set trust %s verified\" before adding them as a trusted introducer.\n", entity.Name, entity.Name)
+			helpers.ExitCode = helpers.ExitCodeInvalidInput
+			return
+		}
+
+		// use the stored name, so the list matches the keystore's casing
+		introducers = append(removeTrustedIntroducerName(introducers, entity.Name), entity.Name)
+	}
+
+	profile.TrustedIntroducers = introducers
+	err := helpers.GlobalConfig.WriteConfig()
+	if err != nil {
+		fmt.Printf("Unable to write updated config metadata: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeOutputError
+		return
+	}
+
+	printTrustedIntroducers(profile)
+}
+
+func printTrustedIntroducers(profile *helpers.Profile) {
+	if len(profile.TrustedIntroducers) == 0 {
+		fmt.Printf("Profile \"%s\" has no trusted introducers\n", profile.Name)
+		return
+	}
+
+	fmt.Printf("Trusted introducers for profile \"%s\": %s\n", profile.Name, strings.Join(profile.TrustedIntroducers, ", "))
+}
+
+func removeTrustedIntroducerName(introducers []string, name string) []string {
+	introducersOut := []string{}
+	for _, introducer := range introducers {
+		if !strings.EqualFold(introducer, name) {
+			introducersOut = append(introducersOut, introducer)
+		}
+	}
+
+	if len(introducersOut) == 0 {
+		return nil
+	}
+
+	return introducersOut
+}
+
+// updateTrustedIntroducerName keeps the profile's trusted introducers consistent when a user is renamed or removed.
+// An empty newName removes the user from the list.
+func updateTrustedIntroducerName(oldName, newName string) {
+	profile := helpers.GlobalConfig.GetCurrentProfile()
+	if profile == nil || !profile.IsTrustedIntroducer(oldName) {
+		return
+	}
+
+	introducers := removeTrustedIntroducerName(profile.TrustedIntroducers, oldName)
+	if newName != "" {
+		introducers = append(introducers, newName)
+	}
+
+	profile.TrustedIntroducers = introducers
+	err := helpers.GlobalConfig.WriteConfig()
+	if err != nil {
+		fmt.Printf("Unable to update the profile's trusted introducers: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeOutputError
+		return
+	}
+
+	if newName == "" {
+		fmt.Printf("Removed \"%s\" from the profile's trusted introducers.\n", oldName)
+	} else {
+		fmt.Printf("Renamed \"%s\" to \"%s\" in the profile's trusted introducers.\n", oldName, newName)
+	}
+}
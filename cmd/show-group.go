@@ -0,0 +1,88 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/security"
+)
+
+// showGroupCmd represents the group subcommand
+var showGroupCmd = &cobra.Command{
+	Use:   "group [name]",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Will display the referenced group and its members",
+	Long:  "Will display the referenced group and its members",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// bootstrap will print its own messages
+			return
+		}
+
+		if len(args) == 0 {
+			_ = cmd.Help()
+			return
+		}
+
+		showGroup(keystore.GroupNameFromReference(args[0]))
+	},
+}
+
+func init() {
+	showCmd.AddCommand(showGroupCmd)
+}
+
+func showGroup(groupName string) {
+	if keystore.GlobalKeyStore == nil {
+		fmt.Println("Unable to show group info: Key Store not loaded.")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	group := keystore.GlobalKeyStore.GetGroup(groupName)
+	if group == nil {
+		fmt.Printf("No group with the name \"%s\" was found.\n", groupName)
+		return
+	}
+
+	fmt.Printf("Using profile: %s\n", helpers.GlobalConfig.GetCurrentProfile().Name)
+	printGroup(group)
+}
+
+// printGroup prints the group and the trust level and fingerprint of each member
+func printGroup(group *keystore.Group) {
+	fmt.Println("")
+	fmt.Printf("Group              : %s%s\n", keystore.GroupPrefix, group.Name)
+	fmt.Printf("Members            : %d\n", len(group.Members))
+	fmt.Printf("Created            : %s\n", group.CreatedDate)
+	fmt.Printf("Updated            : %s\n", group.UpdatedDate)
+	fmt.Println("=========================================================")
+	for _, memberName := range group.Members {
+		entity := keystore.GlobalKeyStore.GetKey(memberName)
+		if entity == nil {
+			fmt.Printf("%-18s : not found in keystore\n", memberName)
+			continue
+		}
+
+		fmt.Printf("%-18s : %s  %s\n",
+			entity.Name,
+			entity.PublicKeys.Fingerprint().Hex(),
+			security.TrustLevelToText(entity.Trust))
+	}
+	fmt.Println()
+}
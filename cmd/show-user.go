@@ -18,11 +18,20 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/thoughtrealm/bumblebee/helpers"
 	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/logger"
+	"github.com/thoughtrealm/bumblebee/security"
+	"strings"
 )
 
+type showUserCommandVals struct {
+	history bool
+}
+
+var localShowUserCommandVals = &showUserCommandVals{}
+
 // showUserCmd represents the user subcommand
 var showUserCmd = &cobra.Command{
-	Use:   "user [name]",
+	Use:   "user [name] [--history]",
 	Args:  cobra.MaximumNArgs(1),
 	Short: "Will display the referenced user info",
 	Long:  "Will display the referenced user info",
@@ -47,6 +56,7 @@ var showUserCmd = &cobra.Command{
 
 func init() {
 	showCmd.AddCommand(showUserCmd)
+	showUserCmd.Flags().BoolVarP(&localShowUserCommandVals.history, "history", "", false, "If set, the user's prior public keys are also listed")
 }
 
 func showUser(userName string) {
@@ -64,4 +74,24 @@ func showUser(userName string) {
 
 	fmt.Printf("Using profile: %s\n", helpers.GlobalConfig.GetCurrentProfile().Name)
 	entity.Print()
+
+	certifierNames := getCertifierNames(entity)
+	if len(certifierNames) > 0 {
+		fmt.Printf("Certified By       : %s\n", strings.Join(certifierNames, ", "))
+		fmt.Println()
+	}
+
+	if localShowUserCommandVals.history {
+		printKeyHistory(entity)
+	}
+
+	ownFP, err := getKeyPairFingerprint("default")
+	if err != nil {
+		logger.Debugfln("Unable to build safety number: %s", err)
+		return
+	}
+
+	fmt.Printf("Safety Number      : %s\n", security.SafetyNumber(ownFP, entity.PublicKeys.Fingerprint()))
+	fmt.Println("                     (with your default keypair)")
+	fmt.Println()
 }
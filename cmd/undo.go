@@ -0,0 +1,123 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/journal"
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"strconv"
+)
+
+// undoCmd represents the undo command
+var undoCmd = &cobra.Command{
+	Use:   "undo [n]",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Reverts the last n changes to the keystore and keypairs of the current profile",
+	Long: "Reverts the last n changes to the keystore and keypairs of the current profile, from the profile's " +
+		"keystore journal.  If n is not provided, the last change is reverted.  Changes that were already undone are " +
+		"skipped.  Revocations cannot be undone.  Private keys are not recorded, so keypair removals and other keypair " +
+		"changes that discard keys cannot be undone either.  Keypair adds and rotations cannot be undone, since that " +
+		"would destroy private keys.  Use \"history keystore\" to see the changes.",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		undoCount := 1
+		if len(args) == 1 {
+			undoCount, err = strconv.Atoi(args[0])
+			if err != nil || undoCount < 1 {
+				fmt.Printf("Invalid count of changes to undo: \"%s\".  Should be a number of 1 or more.\n", args[0])
+				helpers.ExitCode = helpers.ExitCodeInvalidInput
+				return
+			}
+		}
+
+		undoChanges(undoCount)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+}
+
+func undoChanges(undoCount int) {
+	if keystore.GlobalKeyStore == nil || keypairs.GlobalKeyPairStore == nil {
+		fmt.Println("Unable to undo changes: keystore not loaded")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	profile := helpers.GlobalConfig.GetCurrentProfile()
+	if profile == nil {
+		fmt.Println("Unable to retrieve current profile config")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	profileJournal, err := journal.ReadFromFile(profile.JournalPath())
+	if err != nil {
+		fmt.Printf("Unable to load the keystore journal: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	entries := profileJournal.UndoCandidates(undoCount)
+	if len(entries) == 0 {
+		fmt.Println("There are no changes to undo")
+		return
+	}
+
+	if len(entries) < undoCount {
+		fmt.Printf("Only %d change(s) can be undone\n", len(entries))
+	}
+
+	fmt.Println("The following changes will be undone, newest first...")
+	for _, entry := range entries {
+		fmt.Printf("  %d. %s, %s\n", entry.Sequence, entry.Operation, entry.Date)
+		for _, change := range entry.Changes {
+			fmt.Printf("       %s\n", change.Describe())
+		}
+	}
+
+	fmt.Println("")
+	response, err := helpers.GetYesNoInput("Are you sure you wish to undo these changes?", helpers.InputResponseValNo)
+	fmt.Println("")
+	if err != nil {
+		fmt.Printf("Unable to confirm undo: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	if response != helpers.InputResponseValYes {
+		fmt.Println("User aborted undo request")
+		helpers.ExitCode = helpers.ExitCodeInputError
+		return
+	}
+
+	count, err := profileJournal.Undo(entries, keystore.GlobalKeyStore, keypairs.GlobalKeyPairStore)
+	if err != nil {
+		fmt.Printf("Undid %d of %d change(s).  %s\n", count, len(entries), err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	fmt.Printf("Undid %d change(s).\n", count)
+}
@@ -0,0 +1,84 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keystore"
+)
+
+type updateGroupCommandVals struct {
+	addMembers    []string
+	removeMembers []string
+}
+
+var localUpdateGroupCommandVals = &updateGroupCommandVals{}
+
+// updateGroupCmd represents the update group command
+var updateGroupCmd = &cobra.Command{
+	Use:   "group <name> [--add user1,user2] [--remove user3]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Will add or remove members of a group",
+	Long:  "Will add or remove members of a group.  Added members must already exist in the keystore.",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		if len(localUpdateGroupCommandVals.addMembers) == 0 && len(localUpdateGroupCommandVals.removeMembers) == 0 {
+			fmt.Println("Nothing to update.  Expected at least one of \"--add\" or \"--remove\"")
+			helpers.ExitCode = helpers.ExitCodeInvalidInput
+			return
+		}
+
+		updateGroup(keystore.GroupNameFromReference(args[0]))
+	},
+}
+
+func init() {
+	updateCmd.AddCommand(updateGroupCmd)
+	updateGroupCmd.Flags().StringSliceVarP(&localUpdateGroupCommandVals.addMembers, "add", "a", nil, "The users to add to the group. May be repeated or comma separated.")
+	updateGroupCmd.Flags().StringSliceVarP(&localUpdateGroupCommandVals.removeMembers, "remove", "r", nil, "The users to remove from the group. May be repeated or comma separated.")
+}
+
+func updateGroup(groupName string) {
+	if keystore.GlobalKeyStore == nil {
+		fmt.Println("Unable to update group: keystore not loaded")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	found, err := keystore.GlobalKeyStore.UpdateGroupMembers(
+		groupName,
+		localUpdateGroupCommandVals.addMembers,
+		localUpdateGroupCommandVals.removeMembers)
+	if !found {
+		fmt.Printf("Unable to update group: group not found with name \"%s\"\n", groupName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	if err != nil {
+		fmt.Printf("Unable to update group: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	fmt.Println("Group updated and keystore file changes committed.")
+	printGroup(keystore.GlobalKeyStore.GetGroup(groupName))
+}
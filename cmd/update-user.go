@@ -18,21 +18,33 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/thoughtrealm/bumblebee/helpers"
 	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/security"
+	"strings"
 )
 
 type updateUserSubcommandVals struct {
 	cipherPublicKey  string
 	signingPublicKey string
+	displayName      string
+	emails           []string
+	organization     string
+	notes            string
+	addTags          []string
+	removeTags       []string
+	confirmKeyChange bool
+
+	// contactChanged is captured in Run, since the contact flags may be cleared with empty values
+	contactChanged bool
 }
 
 var localUpdateUserSubcommandVals = &updateUserSubcommandVals{}
 
 // updateUserCmd represents the key command
 var updateUserCmd = &cobra.Command{
-	Use:   "user <name> --cipher=cipherKey --signing=signingKey",
+	Use:   "user <name> [--cipher=cipherKey] [--signing=signingKey] [contact flags]",
 	Args:  cobra.ExactArgs(1),
-	Short: "Will update a user's public keys",
-	Long:  "Will update a user's public keys",
+	Short: "Will update a user's public keys or contact details",
+	Long:  "Will update a user's public keys and/or contact details, such as display name, emails, organization, notes and tags",
 	Run: func(cmd *cobra.Command, args []string) {
 		err := startBootStrap(true, true)
 		if err != nil {
@@ -43,16 +55,34 @@ var updateUserCmd = &cobra.Command{
 		// Max args is set by Args property above. Only need to check for 2 or less args
 		var userName string
 
-		if localUpdateUserSubcommandVals.cipherPublicKey == "" &&
-			localUpdateUserSubcommandVals.signingPublicKey == "" {
+		localUpdateUserSubcommandVals.contactChanged = false
+		for _, flagName := range []string{"display-name", "email", "org", "notes", "tag", "remove-tag"} {
+			if cmd.Flags().Changed(flagName) {
+				localUpdateUserSubcommandVals.contactChanged = true
+				break
+			}
+		}
+
+		keysProvided := localUpdateUserSubcommandVals.cipherPublicKey != "" ||
+			localUpdateUserSubcommandVals.signingPublicKey != ""
 
-			fmt.Println("No keys provided.  Expected at least one of \"--cipher\" or \"signing\"", err)
+		if !keysProvided && !localUpdateUserSubcommandVals.contactChanged {
+			fmt.Println("Nothing to update.  Expected at least one of \"--cipher\", \"--signing\" or a contact flag")
+			helpers.ExitCode = helpers.ExitCodeInvalidInput
 			return
 		}
 
 		userName = args[0]
 
-		updateUserPublicKeys(userName)
+		if keysProvided {
+			if !updateUserPublicKeys(userName) {
+				return
+			}
+		}
+
+		if localUpdateUserSubcommandVals.contactChanged {
+			updateUserContactInfo(userName, cmd)
+		}
 	},
 }
 
@@ -60,16 +90,54 @@ func init() {
 	updateCmd.AddCommand(updateUserCmd)
 	updateUserCmd.Flags().StringVarP(&localUpdateUserSubcommandVals.cipherPublicKey, "cipher", "c", "", "The value for the public cipher key")
 	updateUserCmd.Flags().StringVarP(&localUpdateUserSubcommandVals.signingPublicKey, "signing", "s", "", "The value for the public signing key")
+	updateUserCmd.Flags().StringVarP(&localUpdateUserSubcommandVals.displayName, "display-name", "", "", "The display name for the user. An empty value clears it.")
+	updateUserCmd.Flags().StringSliceVarP(&localUpdateUserSubcommandVals.emails, "email", "e", nil, "Replaces the user's email addresses. May be repeated or comma separated. An empty value clears them.")
+	updateUserCmd.Flags().StringVarP(&localUpdateUserSubcommandVals.organization, "org", "", "", "The organization for the user. An empty value clears it.")
+	updateUserCmd.Flags().StringVarP(&localUpdateUserSubcommandVals.notes, "notes", "", "", "Free-form notes for the user. An empty value clears them.")
+	updateUserCmd.Flags().StringSliceVarP(&localUpdateUserSubcommandVals.addTags, "tag", "", nil, "A tag to add to the user. May be repeated or comma separated.")
+	updateUserCmd.Flags().StringSliceVarP(&localUpdateUserSubcommandVals.removeTags, "remove-tag", "", nil, "A tag to remove from the user. May be repeated or comma separated.")
+	updateUserCmd.Flags().BoolVarP(&localUpdateUserSubcommandVals.confirmKeyChange, "confirm-key-change", "", false, "If set, key changes are accepted without prompting. The old and new fingerprints are still displayed.")
 }
 
-func updateUserPublicKeys(userName string) {
+// updateUserPublicKeys returns true if the keys were updated
+func updateUserPublicKeys(userName string) bool {
 	var err error
 	var found bool
 
 	if keystore.GlobalKeyStore == nil {
 		fmt.Println("Unable to update key: keystore not loaded")
 		helpers.ExitCode = helpers.ExitCodeStartupFailure
-		return
+		return false
+	}
+
+	entity := keystore.GlobalKeyStore.GetKey(userName)
+	if entity == nil {
+		fmt.Printf("Unable to update keys: key not found with name \"%s\"\n", userName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return false
+	}
+
+	newCipherPubKey := entity.PublicKeys.CipherPubKey
+	if localUpdateUserSubcommandVals.cipherPublicKey != "" {
+		newCipherPubKey = localUpdateUserSubcommandVals.cipherPublicKey
+	}
+
+	newSigningPubKey := entity.PublicKeys.SigningPubKey
+	if localUpdateUserSubcommandVals.signingPublicKey != "" {
+		newSigningPubKey = localUpdateUserSubcommandVals.signingPublicKey
+	}
+
+	confirmed, err := confirmKeyChange(entity, newCipherPubKey, newSigningPubKey, localUpdateUserSubcommandVals.confirmKeyChange)
+	if err != nil {
+		fmt.Printf("Unable to get confirmation for the key change: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return false
+	}
+
+	if !confirmed {
+		fmt.Println("Key change declined.  No changes were made.")
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return false
 	}
 
 	// the user may submit both keys, or just the cipher key, or just the signing key
@@ -83,7 +151,7 @@ func updateUserPublicKeys(userName string) {
 		if !found {
 			fmt.Printf("Unable to update keys: key not found with name \"%s\"\n", userName)
 			helpers.ExitCode = helpers.ExitCodeInvalidInput
-			return
+			return false
 		}
 	} else if localUpdateUserSubcommandVals.cipherPublicKey != "" {
 		// user provided only the cipher key
@@ -93,7 +161,7 @@ func updateUserPublicKeys(userName string) {
 		if !found {
 			fmt.Printf("Unable to update cipher key: key not found with name \"%s\"\n", userName)
 			helpers.ExitCode = helpers.ExitCodeInvalidInput
-			return
+			return false
 		}
 	} else {
 		// user provided only the signing key
@@ -103,15 +171,70 @@ func updateUserPublicKeys(userName string) {
 		if !found {
 			fmt.Printf("Unable to update signing key: key not found with name \"%s\"\n", userName)
 			helpers.ExitCode = helpers.ExitCodeInvalidInput
-			return
+			return false
 		}
 	}
 
 	if err != nil {
 		fmt.Printf("Unable to update key(s): %s\n", err)
 		helpers.ExitCode = helpers.ExitCodeRequestFailed
-		return
+		return false
 	}
 
 	fmt.Println("Key updated and keystore file changes committed.")
+	return true
+}
+
+func updateUserContactInfo(userName string, cmd *cobra.Command) {
+	if keystore.GlobalKeyStore == nil {
+		fmt.Println("Unable to update contact details: keystore not loaded")
+		helpers.ExitCode = helpers.ExitCodeStartupFailure
+		return
+	}
+
+	entity := keystore.GlobalKeyStore.GetKey(userName)
+	if entity == nil {
+		fmt.Printf("Unable to update contact details: user not found with name \"%s\"\n", userName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	contact := entity.Contact.Clone()
+	if contact == nil {
+		contact = &security.ContactInfo{}
+	}
+
+	if cmd.Flags().Changed("display-name") {
+		contact.DisplayName = localUpdateUserSubcommandVals.displayName
+	}
+
+	if cmd.Flags().Changed("email") {
+		contact.Emails = nil
+		for _, email := range localUpdateUserSubcommandVals.emails {
+			email = strings.TrimSpace(email)
+			if email != "" {
+				contact.Emails = append(contact.Emails, email)
+			}
+		}
+	}
+
+	if cmd.Flags().Changed("org") {
+		contact.Organization = localUpdateUserSubcommandVals.organization
+	}
+
+	if cmd.Flags().Changed("notes") {
+		contact.Notes = localUpdateUserSubcommandVals.notes
+	}
+
+	contact.RemoveTags(localUpdateUserSubcommandVals.removeTags)
+	contact.AddTags(localUpdateUserSubcommandVals.addTags)
+
+	_, err := keystore.GlobalKeyStore.UpdateContactInfo(userName, contact)
+	if err != nil {
+		fmt.Printf("Unable to update contact details: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeRequestFailed
+		return
+	}
+
+	fmt.Println("Contact details updated and keystore file changes committed.")
 }
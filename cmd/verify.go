@@ -0,0 +1,204 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	cipherio "github.com/thoughtrealm/bumblebee/cipher/io"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/security"
+	"io"
+	"os"
+)
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verifies the signature of a signed plaintext message",
+	Long:  "Verifies the signature of a signed plaintext message created with \"bundle --sign-only\"",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startBootStrap(true, true)
+		if err != nil {
+			// startBootstrap prints messages, so nothing to print here, just bail
+			return
+		}
+
+		verifySignedMessage()
+	},
+}
+
+type verifyCommandVals struct {
+	// The name of the key in the keystore to verify the sender's signature with
+	fromName string
+
+	// inputSourceText should be clipboard, piped or file
+	inputSourceText string
+
+	// inputFilePath is the name of a file to use as input.  Only relevant for inputSourceText=file.
+	inputFilePath string
+}
+
+var localVerifyCommandVals = &verifyCommandVals{}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+	verifyCmd.Flags().StringVarP(&localVerifyCommandVals.fromName, "from", "r", "", "The name of the key to use for the sender's key data.")
+	verifyCmd.Flags().StringVarP(&localVerifyCommandVals.inputSourceText, "input-source", "i", "", "The type of the input source.  Should be one of: clipboard, piped or file.")
+	verifyCmd.Flags().StringVarP(&localVerifyCommandVals.inputFilePath, "input-file", "f", "", "The name of a file to use for input. Only relevant if input-source is file.")
+}
+
+func verifySignedMessage() {
+	if localVerifyCommandVals.inputSourceText == "" && localVerifyCommandVals.inputFilePath != "" {
+		localVerifyCommandVals.inputSourceText = "file"
+	}
+
+	if localVerifyCommandVals.inputSourceText == "" && helpers.CheckIsPiped() {
+		localVerifyCommandVals.inputSourceText = "piped"
+	}
+
+	if localVerifyCommandVals.fromName == "" {
+		fmt.Println("No sender provided.  --from is required.")
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	if localVerifyCommandVals.inputSourceText == "" {
+		fmt.Println("No input-source provided.  --input-source is required.")
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	senderEntity := keystore.GlobalKeyStore.GetKey(localVerifyCommandVals.fromName)
+	if senderEntity == nil {
+		fmt.Printf("Sender key not located for name \"%s\"\n", localVerifyCommandVals.fromName)
+		helpers.ExitCode = helpers.ExitCodeInvalidInput
+		return
+	}
+
+	inputBytes, err := readVerifyInput()
+	if err != nil {
+		fmt.Printf("Unable to read input: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeInputError
+		return
+	}
+
+	signedMessage, signerKI, err := parseAndVerifySignedMessage(inputBytes, senderEntity.PublicKeys, senderEntity.PriorKeyInfos())
+	if err != nil {
+		fmt.Printf("Unable to verify signed message: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeCipherError
+		return
+	}
+
+	printSignedMessageDetails(signedMessage, int64(len(inputBytes)))
+	fmt.Printf("Signature is VALID for sender \"%s\"\n", senderEntity.Name)
+
+	err = checkSenderRevocation(senderEntity, signedMessage.Info.CreateDate)
+	if err != nil {
+		fmt.Printf("Refusing signed message: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeCipherError
+		return
+	}
+
+	err = checkSenderKeyHistory(senderEntity, signedMessage.Info.CreateDate, signerKI.SigningPubKey)
+	if err != nil {
+		fmt.Printf("Refusing signed message: %s\n", err)
+		helpers.ExitCode = helpers.ExitCodeCipherError
+		return
+	}
+}
+
+func readVerifyInput() ([]byte, error) {
+	switch keystore.TextToInputSource(localVerifyCommandVals.inputSourceText) {
+	case keystore.InputSourceClipboard:
+		return helpers.ReadFromClipboard()
+	case keystore.InputSourceFile:
+		if localVerifyCommandVals.inputFilePath == "" {
+			return nil, errors.New("input source is FILE and no input path is provided")
+		}
+
+		return os.ReadFile(localVerifyCommandVals.inputFilePath)
+	case keystore.InputSourcePiped:
+		pipeBuffer := bytes.NewBuffer(nil)
+		_, err := pipeBuffer.ReadFrom(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read piped input from stdin: %w", err)
+		}
+
+		return pipeBuffer.Bytes(), nil
+	}
+
+	return nil, fmt.Errorf("unsupported input-source: \"%s\"", localVerifyCommandVals.inputSourceText)
+}
+
+// isSignedMessageFile checks the start of the file for the signed message marker, so that large
+// bundle files are not read into memory.
+func isSignedMessageFile(filePath string) bool {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return false
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	headBytes := make([]byte, 512)
+	n, _ := io.ReadFull(file, headBytes)
+	return cipherio.IsSignedMessage(headBytes[:n])
+}
+
+// parseAndVerifySignedMessage validates the message with the sender's current keys, then with each of the
+// sender's prior keys.  The keys that validated the message are returned.
+func parseAndVerifySignedMessage(
+	data []byte,
+	senderKI *security.KeyInfo,
+	priorKIs []*security.KeyInfo) (*cipherio.SignedMessage, *security.KeyInfo, error) {
+
+	signedMessage, err := cipherio.ParseSignedMessage(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to parse signed message: %w", err)
+	}
+
+	err = signedMessage.Verify(senderKI)
+	if err == nil {
+		return signedMessage, senderKI, nil
+	}
+
+	for _, priorKI := range priorKIs {
+		if signedMessage.Verify(priorKI) == nil {
+			return signedMessage, priorKI, nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("signed message validation failed: %w", err)
+}
+
+func printSignedMessageDetails(signedMessage *cipherio.SignedMessage, sourceSize int64) {
+	fmt.Println("")
+	fmt.Println("Signed Message Details")
+	fmt.Println("=========================================================")
+	fmt.Printf("Total Message Size    : %d bytes\n", sourceSize)
+	fmt.Printf("Payload Size          : %d bytes\n", len(signedMessage.Payload))
+	fmt.Printf("Payload Encoding      : %s\n", cipherio.SignedPayloadEncodingToText(signedMessage.Info.PayloadEncoding))
+	fmt.Printf("Date Signed           : %s\n", signedMessage.Info.CreateDate)
+	fmt.Printf("Original File Date    : %s\n", signedMessage.Info.OriginalFileDate)
+	fmt.Printf("Original File Name    : %s\n", signedMessage.Info.OriginalFileName)
+	fmt.Printf("From Name             : %s\n", signedMessage.Info.FromName)
+	fmt.Printf("Input Source          : %s\n", cipherio.BundleInputSourceToText(signedMessage.Info.InputSource))
+	fmt.Println("")
+}
@@ -16,9 +16,11 @@ module github.com/thoughtrealm/bumblebee
 go 1.20
 
 require (
+	github.com/jung-kurt/gofpdf v1.16.2
 	github.com/kirsle/configdir v0.0.0-20170128060238-e45d2f54772f
 	github.com/klauspost/compress v1.17.4
 	github.com/nats-io/nkeys v0.4.6
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	github.com/spf13/cobra v1.7.0
 	github.com/stretchr/testify v1.6.1
 	github.com/vmihailenco/msgpack/v5 v5.3.5
@@ -27,8 +27,16 @@ import (
 var GlobalUseProfile string
 
 const (
-	BBGLobalFolderName = "Bumblebee"
-	BBConfigFileName   = "config.yaml"
+	BBGLobalFolderName     = "Bumblebee"
+	BBConfigFileName       = "config.yaml"
+	BBOpenedLedgerFileName = "opened.ledger"
+	BBJournalFileName      = "keystore.journal"
+)
+
+const (
+	TrustPolicyOff    = "off"
+	TrustPolicyWarn   = "warn"
+	TrustPolicyRefuse = "refuse"
 )
 
 var GlobalConfig *ConfigHelper
@@ -42,6 +50,18 @@ type Profile struct {
 
 	// DefaultKeypairName is optional and is the name to use as the sender when using the default key for this profile
 	DefaultKeypairName string `yaml:"defaultKeypairName"`
+
+	// EncryptToSelf indicates that bundles should also wrap the payload key to one of this profile's own keypairs
+	EncryptToSelf bool `yaml:"encryptToSelf"`
+
+	// SelfKeypairName is optional and is the keypair that the self slot is wrapped to.  If empty, keystore_read is used.
+	SelfKeypairName string `yaml:"selfKeypairName"`
+
+	// TrustPolicy is how bundle and open respond to unverified users.  One of off, warn or refuse.  If empty, warn is used.
+	TrustPolicy string `yaml:"trustPolicy"`
+
+	// TrustedIntroducers are user names whose certifications are accepted as verification of other users' keys
+	TrustedIntroducers []string `yaml:"trustedIntroducers"`
 }
 
 func (p *Profile) Clone() *Profile {
@@ -52,9 +72,54 @@ func (p *Profile) Clone() *Profile {
 		KeyPairStorePath:      p.KeyPairStorePath,
 		KeyPairStoreEncrypted: p.KeyPairStoreEncrypted,
 		DefaultKeypairName:    p.DefaultKeypairName,
+		EncryptToSelf:         p.EncryptToSelf,
+		SelfKeypairName:       p.SelfKeypairName,
+		TrustPolicy:           p.TrustPolicy,
+		TrustedIntroducers:    cloneStrings(p.TrustedIntroducers),
+	}
+}
+
+// GetTrustPolicy returns the profile's trust policy, defaulting to warn
+func (p *Profile) GetTrustPolicy() string {
+	switch strings.ToLower(p.TrustPolicy) {
+	case TrustPolicyOff:
+		return TrustPolicyOff
+	case TrustPolicyRefuse:
+		return TrustPolicyRefuse
+	default:
+		return TrustPolicyWarn
 	}
 }
 
+// IsTrustedIntroducer returns true if the user name is one of the profile's trusted introducers
+func (p *Profile) IsTrustedIntroducer(name string) bool {
+	for _, introducer := range p.TrustedIntroducers {
+		if strings.EqualFold(introducer, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func cloneStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+
+	return append([]string{}, values...)
+}
+
+// OpenedLedgerPath returns the path of the profile's ledger of opened bundles
+func (p *Profile) OpenedLedgerPath() string {
+	return filepath.Join(p.Path, BBOpenedLedgerFileName)
+}
+
+// JournalPath returns the path of the profile's journal of keystore and keypair store changes
+func (p *Profile) JournalPath() string {
+	return filepath.Join(p.Path, BBJournalFileName)
+}
+
 type ConfigInfo struct {
 	Profiles       []*Profile `yaml:"profiles"`
 	CurrentProfile string     `yaml:"currentProfile"`
@@ -88,6 +153,10 @@ func (configIn *ConfigInfo) Clone() (configOut *ConfigInfo) {
 			KeyPairStorePath:      profile.KeyPairStorePath,
 			KeyPairStoreEncrypted: profile.KeyPairStoreEncrypted,
 			DefaultKeypairName:    profile.DefaultKeypairName,
+			EncryptToSelf:         profile.EncryptToSelf,
+			SelfKeypairName:       profile.SelfKeypairName,
+			TrustPolicy:           profile.TrustPolicy,
+			TrustedIntroducers:    cloneStrings(profile.TrustedIntroducers),
 		}
 
 		configOut.Profiles = append(configOut.Profiles, newProfile)
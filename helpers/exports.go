@@ -9,6 +9,9 @@ const (
 	ImportInputSourceClipboard
 	ImportInputSourceFile
 	ImportInputSourceUnknown
+
+	// ImportInputSourceConsole is for paper keys typed in at the console.  It is not selectable with --input-source.
+	ImportInputSourceConsole
 )
 
 func TextToImportInputSource(text string) ImportInputSource {
@@ -30,6 +33,7 @@ const (
 	ExportOutputTargetConsole ExportOutputTarget = iota
 	ExportOutputTargetClipboard
 	ExportOutputTargetFile
+	ExportOutputTargetQR
 	ExportOutputTargetUnknown
 )
 
@@ -41,6 +45,8 @@ func TextToExportOutputTarget(text string) ExportOutputTarget {
 		return ExportOutputTargetClipboard
 	case "FILE":
 		return ExportOutputTargetFile
+	case "QR":
+		return ExportOutputTargetQR
 	default:
 		return ExportOutputTargetUnknown
 	}
@@ -17,6 +17,7 @@ import (
 	"errors"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -44,6 +45,18 @@ func ReplaceFileExt(filePath, newExt string) string {
 	return filePath[:len(filePath)-len(ext)] + newExtPeriod + newExt
 }
 
+// BaseFileName returns the last element of a file name received from another party, such as the original file
+// name in a bundle, so that it cannot refer to a location outside the output path.  Both forward and back slashes
+// are treated as separators.  An empty string is returned if there is no usable name, such as for "..".
+func BaseFileName(inputName string) string {
+	baseName := path.Base(strings.ReplaceAll(inputName, "\\", "/"))
+	if baseName == "." || baseName == ".." || baseName == "/" {
+		return ""
+	}
+
+	return baseName
+}
+
 func FileExistsWithDetails(filePath string) (isFound, isDir bool, err error) {
 	info, err := os.Stat(filePath)
 	if err != nil {
@@ -174,3 +187,13 @@ func GetEnvSafeName(inputName string) (outputName string) {
 func ForcePath(p string) error {
 	return os.MkdirAll(p, os.ModePerm)
 }
+
+// CreateOutputFile creates or truncates the file, the same as os.Create.  If isPrivate is true, such as for files
+// that hold private keys, new files are only readable by the current user.
+func CreateOutputFile(filePath string, isPrivate bool) (*os.File, error) {
+	if !isPrivate {
+		return os.Create(filePath)
+	}
+
+	return os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+}
@@ -15,6 +15,9 @@ package helpers
 
 import (
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 )
 
@@ -72,3 +75,40 @@ func TestReplaceFileExt(t *testing.T) {
 		})
 	}
 }
+
+func TestBaseFileName(t *testing.T) {
+	tests := map[string]string{
+		"file.txt":               "file.txt",
+		"dir/file.txt":           "file.txt",
+		"../../.bashrc":          ".bashrc",
+		"/etc/passwd":            "passwd",
+		"..\\..\\windows\\a.dll": "a.dll",
+		"C:\\temp\\b.txt":        "b.txt",
+		"..":                     "",
+		"../..":                  "",
+		"/":                      "",
+		"":                       "",
+	}
+
+	for inputName, expects := range tests {
+		assert.Equal(t, expects, BaseFileName(inputName), "input name: %q", inputName)
+	}
+}
+
+func TestCreateOutputFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permission bits are not supported on windows")
+	}
+
+	privateFilePath := filepath.Join(t.TempDir(), "private.bexp-txt")
+	privateFile, err := CreateOutputFile(privateFilePath, true)
+	if !assert.Nil(t, err) {
+		return
+	}
+	_ = privateFile.Close()
+
+	fileInfo, err := os.Stat(privateFilePath)
+	if assert.Nil(t, err) {
+		assert.Equal(t, os.FileMode(0600), fileInfo.Mode().Perm())
+	}
+}
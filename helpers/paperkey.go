@@ -0,0 +1,428 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+// Paper keys render export data as numbered lines of grouped base32 for printing.  Each line ends with a short
+// checksum over the line number and data, so a line typed back in with a mistake is reported by its number.
+// Lines look like...
+//
+//	01/14  ABCD EFGH IJKL MNOP QRST UVWX YZ23 4567  K7
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"github.com/jung-kurt/gofpdf"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	PaperKeyBeginMarker = "-----BEGIN BUMBLEBEE PAPER KEY-----"
+	PaperKeyEndMarker   = "-----END BUMBLEBEE PAPER KEY-----"
+
+	paperKeyGroupSize     = 4
+	paperKeyGroupsPerLine = 8
+	paperKeyLineSize      = paperKeyGroupSize * paperKeyGroupsPerLine
+	paperKeyChecksumSize  = 2
+	paperKeyChecksumSalt  = "bumblebee paper key v1"
+	paperKeyAlphabet      = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+	paperKeyTextWidth     = 100
+)
+
+var (
+	paperKeyEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	// paperKeyLineRegex matches the line number and total, such as "01/14", and the rest of the line
+	paperKeyLineRegex = regexp.MustCompile(`^(\d+)\s*/\s*(\d+)[:.]?\s+(.*)$`)
+
+	// paperKeyLineStartRegex detects lines that are meant to be paper key lines, even if they are not valid
+	paperKeyLineStartRegex = regexp.MustCompile(`^\d+\s*/`)
+)
+
+// PaperKeyLine is one verified line of a paper key
+type PaperKeyLine struct {
+	Number int
+	Total  int
+	Data   string
+}
+
+// PaperKeyLineError reports a problem with one line of a paper key.  Line is 0 if the line number is unreadable.
+type PaperKeyLineError struct {
+	Line   int
+	Reason string
+}
+
+func (e *PaperKeyLineError) Error() string {
+	if e.Line == 0 {
+		return e.Reason
+	}
+
+	return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+}
+
+// EncodePaperKeyLines returns the data as paper key lines
+func EncodePaperKeyLines(data []byte) []string {
+	encoded := paperKeyEncoding.EncodeToString(data)
+	total := (len(encoded) + paperKeyLineSize - 1) / paperKeyLineSize
+	numberWidth := len(strconv.Itoa(total))
+	if numberWidth < 2 {
+		numberWidth = 2
+	}
+
+	lines := make([]string, 0, total)
+	for number := 1; number <= total; number++ {
+		lineData := encoded[(number-1)*paperKeyLineSize:]
+		if len(lineData) > paperKeyLineSize {
+			lineData = lineData[:paperKeyLineSize]
+		}
+
+		var groups []string
+		for remaining := lineData; len(remaining) > 0; {
+			groupSize := paperKeyGroupSize
+			if len(remaining) < groupSize {
+				groupSize = len(remaining)
+			}
+
+			groups = append(groups, remaining[:groupSize])
+			remaining = remaining[groupSize:]
+		}
+
+		lines = append(lines, fmt.Sprintf("%0*d/%0*d  %-*s  %s",
+			numberWidth, number, numberWidth, total,
+			paperKeyLineSize+paperKeyGroupsPerLine-1, strings.Join(groups, " "),
+			paperKeyLineChecksum(number, total, lineData)))
+	}
+
+	return lines
+}
+
+// IsPaperKeyLine returns true if the text starts like a paper key line, with a line number and total
+func IsPaperKeyLine(text string) bool {
+	return paperKeyLineStartRegex.MatchString(strings.TrimSpace(text))
+}
+
+// ParsePaperKeyLine reads one paper key line and verifies its checksum.  Spacing and case are not significant,
+// except that the checksum must be separated from the data.
+func ParsePaperKeyLine(text string) (*PaperKeyLine, error) {
+	matches := paperKeyLineRegex.FindStringSubmatch(strings.TrimSpace(text))
+	if matches == nil {
+		return nil, &PaperKeyLineError{Reason: fmt.Sprintf("\"%s\" does not start with a line number such as \"01/14\"", text)}
+	}
+
+	number, _ := strconv.Atoi(matches[1])
+	total, _ := strconv.Atoi(matches[2])
+	if number < 1 || total < 1 || number > total {
+		return nil, &PaperKeyLineError{Line: number, Reason: fmt.Sprintf("line number %d/%d is not valid", number, total)}
+	}
+
+	fields := strings.Fields(strings.ToUpper(matches[3]))
+	if len(fields) < 2 {
+		return nil, &PaperKeyLineError{Line: number, Reason: "the data or checksum is missing"}
+	}
+
+	checksum := fields[len(fields)-1]
+	lineData := strings.Join(fields[:len(fields)-1], "")
+	if len(checksum) != paperKeyChecksumSize {
+		return nil, &PaperKeyLineError{Line: number, Reason: fmt.Sprintf("the checksum \"%s\" should be %d characters", checksum, paperKeyChecksumSize)}
+	}
+
+	err := checkPaperKeyCharacters(lineData + checksum)
+	if err != nil {
+		return nil, &PaperKeyLineError{Line: number, Reason: err.Error()}
+	}
+
+	if (number < total && len(lineData) != paperKeyLineSize) || len(lineData) > paperKeyLineSize {
+		return nil, &PaperKeyLineError{Line: number, Reason: fmt.Sprintf("has %d data characters, but should have %d", len(lineData), paperKeyLineSize)}
+	}
+
+	if checksum != paperKeyLineChecksum(number, total, lineData) {
+		return nil, &PaperKeyLineError{Line: number, Reason: "the checksum does not match, check the line for typos"}
+	}
+
+	return &PaperKeyLine{Number: number, Total: total, Data: lineData}, nil
+}
+
+// DecodePaperKeyLines returns the data from a complete set of verified lines, in any order
+func DecodePaperKeyLines(lines []*PaperKeyLine) ([]byte, error) {
+	if len(lines) == 0 {
+		return nil, errors.New("no paper key lines were provided")
+	}
+
+	total := lines[0].Total
+	lineData := make(map[int]string, total)
+	for _, line := range lines {
+		if line.Total != total {
+			return nil, &PaperKeyLineError{Line: line.Number, Reason: fmt.Sprintf("is from a paper key with %d lines, but line %d is from one with %d lines",
+				line.Total, lines[0].Number, total)}
+		}
+
+		existing, found := lineData[line.Number]
+		if found && existing != line.Data {
+			return nil, &PaperKeyLineError{Line: line.Number, Reason: "was provided more than once with different data"}
+		}
+
+		lineData[line.Number] = line.Data
+	}
+
+	missing := MissingPaperKeyLines(lines)
+	if len(missing) > 0 {
+		missingText := make([]string, 0, len(missing))
+		for _, number := range missing {
+			missingText = append(missingText, strconv.Itoa(number))
+		}
+
+		return nil, fmt.Errorf("line(s) %s of %d are missing", strings.Join(missingText, ", "), total)
+	}
+
+	encoded := strings.Builder{}
+	for number := 1; number <= total; number++ {
+		encoded.WriteString(lineData[number])
+	}
+
+	data, err := paperKeyEncoding.DecodeString(encoded.String())
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode paper key data: %w", err)
+	}
+
+	return data, nil
+}
+
+// MissingPaperKeyLines returns the line numbers that are not in lines, based on the total of the first line
+func MissingPaperKeyLines(lines []*PaperKeyLine) []int {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	found := make(map[int]bool, len(lines))
+	for _, line := range lines {
+		found[line.Number] = true
+	}
+
+	var missing []int
+	for number := 1; number <= lines[0].Total; number++ {
+		if !found[number] {
+			missing = append(missing, number)
+		}
+	}
+
+	return missing
+}
+
+// DecodePaperKey reads the paper key lines from text, such as a paper key text file or typed lines.  If the text
+// has the begin and end markers, only the lines between them are read.  Other lines that do not start with a
+// line number are ignored.  All invalid lines are reported in the error.
+func DecodePaperKey(text []byte) ([]byte, error) {
+	textLines := strings.Split(strings.ReplaceAll(string(text), "\r\n", "\n"), "\n")
+	if strings.Contains(string(text), PaperKeyBeginMarker) {
+		inKey := false
+		var keyLines []string
+		for _, textLine := range textLines {
+			switch strings.TrimSpace(textLine) {
+			case PaperKeyBeginMarker:
+				inKey = true
+			case PaperKeyEndMarker:
+				inKey = false
+			default:
+				if inKey {
+					keyLines = append(keyLines, textLine)
+				}
+			}
+		}
+
+		textLines = keyLines
+	}
+
+	var lines []*PaperKeyLine
+	var lineErrs []error
+	for _, textLine := range textLines {
+		if !IsPaperKeyLine(textLine) {
+			continue
+		}
+
+		line, err := ParsePaperKeyLine(textLine)
+		if err != nil {
+			lineErrs = append(lineErrs, err)
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	if len(lineErrs) > 0 {
+		return nil, errors.Join(lineErrs...)
+	}
+
+	if len(lines) == 0 {
+		return nil, errors.New("no paper key lines were found")
+	}
+
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i].Number < lines[j].Number
+	})
+
+	return DecodePaperKeyLines(lines)
+}
+
+func paperKeyLineChecksum(number, total int, lineData string) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s %d/%d %s", paperKeyChecksumSalt, number, total, lineData)))
+	return paperKeyEncoding.EncodeToString(hash[:2])[:paperKeyChecksumSize]
+}
+
+// checkPaperKeyCharacters reports the first character that is not in the base32 alphabet.  Digits that are not
+// used are often misread letters, so those are suggested.
+func checkPaperKeyCharacters(text string) error {
+	for idx, char := range text {
+		if strings.ContainsRune(paperKeyAlphabet, char) {
+			continue
+		}
+
+		hint := ""
+		switch char {
+		case '0':
+			hint = ", it may be the letter O"
+		case '1':
+			hint = ", it may be the letter I or L"
+		case '8':
+			hint = ", it may be the letter B"
+		case '9':
+			hint = ", it may be the letter G"
+		}
+
+		return fmt.Errorf("character %d, \"%c\", is not valid.  Only A-Z and 2-7 are used%s", idx+1, char, hint)
+	}
+
+	return nil
+}
+
+// PaperKeyDocument is the content of a printable paper key
+type PaperKeyDocument struct {
+	Title        string
+	Details      [][2]string
+	Instructions []string
+	Lines        []string
+	QRData       []byte
+}
+
+// ToText renders the document as text.  The QR code is drawn with block characters for printing, with dark
+// modules drawn as blocks.
+func (pkd *PaperKeyDocument) ToText() ([]byte, error) {
+	buffer := bytes.NewBuffer(nil)
+	buffer.WriteString(pkd.Title + "\n")
+	buffer.WriteString(strings.Repeat("=", len(pkd.Title)) + "\n")
+	for _, detail := range pkd.Details {
+		buffer.WriteString(fmt.Sprintf("%-12s : %s\n", detail[0], detail[1]))
+	}
+
+	buffer.WriteString("\n")
+	for _, instruction := range pkd.Instructions {
+		buffer.WriteString(wrapPaperKeyText(instruction, paperKeyTextWidth) + "\n")
+	}
+
+	buffer.WriteString("\n" + PaperKeyBeginMarker + "\n")
+	for _, line := range pkd.Lines {
+		buffer.WriteString(line + "\n")
+	}
+	buffer.WriteString(PaperKeyEndMarker + "\n")
+
+	if len(pkd.QRData) > 0 {
+		qrCode, err := newQRCode(pkd.QRData)
+		if err != nil {
+			return nil, err
+		}
+
+		buffer.WriteString("\n")
+		buffer.WriteString(qrCode.ToSmallString(true))
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// ToPDF renders the document as an A4 PDF for printing
+func (pkd *PaperKeyDocument) ToPDF() ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(pkd.Title, false)
+	pdf.SetCreator("bumblebee", false)
+	pdf.SetMargins(20, 20, 20)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, pkd.Title, "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+
+	pdf.SetFont("Helvetica", "", 10)
+	for _, detail := range pkd.Details {
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(28, 5.5, detail[0], "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.MultiCell(0, 5.5, detail[1], "", "L", false)
+	}
+	pdf.Ln(3)
+
+	for _, instruction := range pkd.Instructions {
+		pdf.MultiCell(0, 5, instruction, "", "L", false)
+	}
+	pdf.Ln(4)
+
+	pdf.SetFont("Courier", "", 11)
+	pdf.CellFormat(0, 5.5, PaperKeyBeginMarker, "", 1, "L", false, 0, "")
+	for _, line := range pkd.Lines {
+		pdf.CellFormat(0, 5.5, line, "", 1, "L", false, 0, "")
+	}
+	pdf.CellFormat(0, 5.5, PaperKeyEndMarker, "", 1, "L", false, 0, "")
+
+	if len(pkd.QRData) > 0 {
+		pngBytes, err := QRCodeToPNG(pkd.QRData)
+		if err != nil {
+			return nil, err
+		}
+
+		pdf.Ln(6)
+		imageOptions := gofpdf.ImageOptions{ImageType: "PNG"}
+		pdf.RegisterImageOptionsReader("qrcode", imageOptions, bytes.NewReader(pngBytes))
+		pdf.ImageOptions("qrcode", pdf.GetX(), pdf.GetY(), 55, 0, true, imageOptions, 0, "")
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	err := pdf.Output(buffer)
+	if err != nil {
+		return nil, fmt.Errorf("unable to render the paper key PDF: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// wrapPaperKeyText wraps the text at spaces, so that lines are no longer than width unless a word is
+func wrapPaperKeyText(text string, width int) string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+
+	return strings.Join(append(lines, line), "\n")
+}
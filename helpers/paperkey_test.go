@@ -0,0 +1,140 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestEncodePaperKeyLines_Decode(t *testing.T) {
+	for _, size := range []int{1, 19, 20, 21, 300} {
+		data := make([]byte, size)
+		_, _ = rand.Read(data)
+
+		lines := EncodePaperKeyLines(data)
+		if !assert.NotEmpty(t, lines) {
+			return
+		}
+
+		for _, line := range lines {
+			assert.True(t, IsPaperKeyLine(line))
+		}
+
+		decoded, err := DecodePaperKey([]byte(strings.Join(lines, "\n")))
+		if !assert.Nil(t, err, "size %d", size) {
+			return
+		}
+		assert.Equal(t, data, decoded)
+	}
+}
+
+func TestEncodePaperKeyLines_Format(t *testing.T) {
+	lines := EncodePaperKeyLines(bytes.Repeat([]byte{0}, 25))
+	if !assert.Len(t, lines, 2) {
+		return
+	}
+
+	assert.Equal(t, "01/02  AAAA AAAA AAAA AAAA AAAA AAAA AAAA AAAA  ", lines[0][:len(lines[0])-2])
+	assert.True(t, strings.HasPrefix(lines[1], "02/02  AAAA AAAA   "))
+	assert.Len(t, lines[1], len(lines[0]))
+}
+
+func TestParsePaperKeyLine_Typos(t *testing.T) {
+	data := make([]byte, 100)
+	_, _ = rand.Read(data)
+	lines := EncodePaperKeyLines(data)
+
+	// case and spacing do not matter
+	line, err := ParsePaperKeyLine("  " + strings.ToLower(lines[2][:7]+strings.Replace(lines[2][7:], " ", "", 4)))
+	if assert.Nil(t, err) {
+		assert.Equal(t, 3, line.Number)
+		assert.Equal(t, len(lines), line.Total)
+	}
+
+	// a changed character is reported with its line number
+	typo := []byte(lines[2])
+	if typo[7] == 'A' {
+		typo[7] = 'B'
+	} else {
+		typo[7] = 'A'
+	}
+
+	_, err = ParsePaperKeyLine(string(typo))
+	var lineErr *PaperKeyLineError
+	if assert.True(t, errors.As(err, &lineErr)) {
+		assert.Equal(t, 3, lineErr.Line)
+		assert.Contains(t, err.Error(), "checksum")
+	}
+
+	typo[7] = '0'
+	_, err = ParsePaperKeyLine(string(typo))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "letter O")
+	}
+
+	_, err = ParsePaperKeyLine(strings.Replace(lines[2], " ", "", -1)[:20] + " AA")
+	assert.NotNil(t, err)
+
+	_, err = ParsePaperKeyLine("Keypair : default")
+	assert.NotNil(t, err)
+}
+
+func TestDecodePaperKey_Document(t *testing.T) {
+	data := make([]byte, 100)
+	_, _ = rand.Read(data)
+
+	doc := &PaperKeyDocument{
+		Title:        "Paper Key",
+		Details:      [][2]string{{"Keypair", "default"}, {"Created", "2024/01/02 03:04"}},
+		Instructions: []string{"Keep this in a safe place."},
+		Lines:        EncodePaperKeyLines(data),
+		QRData:       []byte("qr data"),
+	}
+
+	text, err := doc.ToText()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	decoded, err := DecodePaperKey(text)
+	if assert.Nil(t, err) {
+		assert.Equal(t, data, decoded)
+	}
+
+	// a missing line is reported by its number
+	missingText := strings.Replace(string(text), doc.Lines[1]+"\n", "", 1)
+	_, err = DecodePaperKey([]byte(missingText))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "line(s) 2 of")
+	}
+
+	// every line with a typo is reported
+	typoText := strings.Replace(string(text), doc.Lines[0][7:11], "ZZZZ", 1)
+	typoText = strings.Replace(typoText, doc.Lines[3][7:11], "ZZZZ", 1)
+	_, err = DecodePaperKey([]byte(typoText))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "line 1:")
+		assert.Contains(t, err.Error(), "line 4:")
+	}
+
+	pdfBytes, err := doc.ToPDF()
+	if assert.Nil(t, err) {
+		assert.True(t, bytes.HasPrefix(pdfBytes, []byte("%PDF-")))
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"encoding/base64"
+	"fmt"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// qrCodePNGModuleSize is the number of pixels for each module of QR code images.  Negative sizes are
+// module sizes for the qrcode package, rather than the image width.
+const qrCodePNGModuleSize = -8
+
+// QRCodeExportData frames export bytes as a raw base64 text block for a QR code.  The block is smaller than the
+// hex text encoding, and scanned codes can be imported from the clipboard, the same as text exports.
+func QRCodeExportData(exportBytes []byte) []byte {
+	return []byte(":start  :raw  :base64\n" + base64.RawStdEncoding.EncodeToString(exportBytes) + "\n:end\n")
+}
+
+func newQRCode(data []byte) (*qrcode.QRCode, error) {
+	qrCode, err := qrcode.New(string(data), qrcode.Medium)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode %d bytes as a QR code: %w", len(data), err)
+	}
+
+	return qrCode, nil
+}
+
+// QRCodeToText renders the data as a QR code for console output.  Each line holds two rows of modules.  Light
+// modules are drawn with block characters, so the code scans from terminals with a dark background.
+func QRCodeToText(data []byte) (string, error) {
+	qrCode, err := newQRCode(data)
+	if err != nil {
+		return "", err
+	}
+
+	return qrCode.ToSmallString(false), nil
+}
+
+// QRCodeToPNG renders the data as a QR code PNG image
+func QRCodeToPNG(data []byte) ([]byte, error) {
+	qrCode, err := newQRCode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	pngBytes, err := qrCode.PNG(qrCodePNGModuleSize)
+	if err != nil {
+		return nil, fmt.Errorf("unable to write the QR code image: %w", err)
+	}
+
+	return pngBytes, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestQRCodeToText(t *testing.T) {
+	text, err := QRCodeToText([]byte(":start  :export-user  :hex\n0102030405\n:end\n"))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	width := utf8.RuneCountInString(lines[0])
+	for _, line := range lines {
+		assert.Equal(t, width, utf8.RuneCountInString(line))
+	}
+
+	// Each line holds two rows of modules
+	assert.Equal(t, (width+1)/2, len(lines))
+}
+
+func TestQRCodeToPNG(t *testing.T) {
+	pngBytes, err := QRCodeToPNG([]byte("bumblebee"))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	image, err := png.Decode(bytes.NewReader(pngBytes))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	bounds := image.Bounds()
+	assert.Equal(t, bounds.Dx(), bounds.Dy())
+	assert.Equal(t, 0, bounds.Dx()%-qrCodePNGModuleSize)
+}
+
+func TestQRCodeExportData(t *testing.T) {
+	exportBytes := []byte{0, 1, 2, 3, 250, 251, 252, 253}
+	scanner, err := NewTextScanner(QRCodeExportData(exportBytes))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	scannedBytes, err := io.ReadAll(scanner)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, exportBytes, scannedBytes)
+}
+
+func TestQRCodeTooLarge(t *testing.T) {
+	_, err := QRCodeToText(bytes.Repeat([]byte{'x'}, 4000))
+	assert.NotNil(t, err)
+}
@@ -35,3 +35,21 @@ func FormatDuration(totalTime time.Duration) string {
 	milliseconds := (totalTime - (seconds * time.Second)) / time.Millisecond
 	return fmt.Sprintf("%d.%03d secs", seconds, milliseconds)
 }
+
+// DateTextBefore reports whether the RFC3339 date a is before b.  The dates are compared as times, so dates
+// recorded in different zones are ordered correctly.  Dates that do not parse are compared as text, after any
+// dates that do.
+func DateTextBefore(a, b string) bool {
+	aTime, aErr := time.Parse(time.RFC3339, a)
+	bTime, bErr := time.Parse(time.RFC3339, b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return aTime.Before(bTime)
+	case aErr == nil:
+		return true
+	case bErr == nil:
+		return false
+	}
+
+	return a < b
+}
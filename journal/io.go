@@ -0,0 +1,267 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	beecipher "github.com/thoughtrealm/bumblebee/cipher"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/security"
+	"github.com/vmihailenco/msgpack/v5"
+	"golang.org/x/crypto/chacha20poly1305"
+	"io"
+	"os"
+	"time"
+)
+
+// The journal file is a header, followed by the entries.  Each entry is its length and the entry encrypted
+// with XChaCha20-Poly1305.  The entry's sequence is the associated data, so entries cannot be reordered.
+const (
+	journalMagic     = "BBJOURNL"
+	journalVersion   = 1
+	journalSaltLen   = 32
+	journalHeaderLen = len(journalMagic) + 1 + journalSaltLen
+
+	// journalMaxEntryLen is a sanity limit, so a damaged length does not allocate an unreasonable buffer
+	journalMaxEntryLen = 64 * 1024 * 1024
+)
+
+// ReadFromFile loads the journal from filePath, which is decrypted with keys derived from the keystore system keys.
+// If the file does not exist yet, an empty journal is returned that will be written to filePath.
+func ReadFromFile(filePath string) (*Journal, error) {
+	newJournal := New(filePath)
+	err := newJournal.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return newJournal, nil
+}
+
+func (j *Journal) load() error {
+	j.Entries = nil
+	j.validLength = 0
+
+	journalBytes, err := os.ReadFile(j.SourceFilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		j.isLoaded = true
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to read journal file: %w", err)
+	}
+
+	salt, err := decodeJournalHeader(journalBytes)
+	if err != nil {
+		return err
+	}
+
+	aead, err := newJournalCipher(salt)
+	if err != nil {
+		return err
+	}
+
+	position := journalHeaderLen
+	for position < len(journalBytes) {
+		// An incomplete entry at the end of the file is from an interrupted append, so it is ignored.
+		// The next append overwrites it.
+		if len(journalBytes)-position < 4 {
+			break
+		}
+
+		entryLen := int(binary.BigEndian.Uint32(journalBytes[position:]))
+		if entryLen > journalMaxEntryLen {
+			return fmt.Errorf("journal entry %d has an invalid length", len(j.Entries)+1)
+		}
+
+		if len(journalBytes)-position-4 < entryLen {
+			break
+		}
+
+		sequence := len(j.Entries) + 1
+		entry, err := openJournalEntry(aead, sequence, journalBytes[position+4:position+4+entryLen])
+		if err != nil {
+			return err
+		}
+
+		j.Entries = append(j.Entries, entry)
+		position += 4 + entryLen
+	}
+
+	j.validLength = int64(position)
+	j.isLoaded = true
+	return nil
+}
+
+// Append records the entry at the end of the journal file.  The entry's sequence and date are set here.
+func (j *Journal) Append(entry *Entry) error {
+	if !j.isLoaded {
+		err := j.load()
+		if err != nil {
+			return err
+		}
+	}
+
+	journalFile, err := os.OpenFile(j.SourceFilePath, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to open journal file: %w", err)
+	}
+	defer func() {
+		_ = journalFile.Close()
+	}()
+
+	var salt []byte
+	if j.validLength == 0 {
+		salt, err = helpers.GetRandomBytes(journalSaltLen)
+		if err != nil {
+			return fmt.Errorf("unable to generate journal salt: %w", err)
+		}
+
+		header := append([]byte(journalMagic), journalVersion)
+		header = append(header, salt...)
+		_, err = journalFile.WriteAt(header, 0)
+		if err != nil {
+			return fmt.Errorf("unable to write journal header: %w", err)
+		}
+
+		j.validLength = int64(len(header))
+	} else {
+		header := make([]byte, journalHeaderLen)
+		_, err = io.ReadFull(journalFile, header)
+		if err != nil {
+			return fmt.Errorf("unable to read journal header: %w", err)
+		}
+
+		salt, err = decodeJournalHeader(header)
+		if err != nil {
+			return err
+		}
+	}
+
+	aead, err := newJournalCipher(salt)
+	if err != nil {
+		return err
+	}
+
+	entry.Sequence = len(j.Entries) + 1
+	entry.Date = time.Now().Format(time.RFC3339)
+	sealedEntry, err := sealJournalEntry(aead, entry)
+	if err != nil {
+		return err
+	}
+
+	record := binary.BigEndian.AppendUint32(nil, uint32(len(sealedEntry)))
+	record = append(record, sealedEntry...)
+
+	// Anything after the last complete entry is from an interrupted append, so it is truncated first
+	err = journalFile.Truncate(j.validLength)
+	if err == nil {
+		_, err = journalFile.WriteAt(record, j.validLength)
+	}
+
+	if err == nil {
+		err = journalFile.Sync()
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to write journal entry: %w", err)
+	}
+
+	j.Entries = append(j.Entries, entry)
+	j.validLength += int64(len(record))
+	return nil
+}
+
+func decodeJournalHeader(header []byte) (salt []byte, err error) {
+	if len(header) < journalHeaderLen || !bytes.Equal(header[:len(journalMagic)], []byte(journalMagic)) {
+		return nil, errors.New("file is not a bumblebee journal")
+	}
+
+	if header[len(journalMagic)] != journalVersion {
+		return nil, fmt.Errorf("unsupported journal version: %d", header[len(journalMagic)])
+	}
+
+	return header[len(journalMagic)+1 : journalHeaderLen], nil
+}
+
+// newJournalCipher returns the entry cipher.  The key is derived with HKDF-SHA256 from the keystore keypair
+// seeds and the file's salt.
+func newJournalCipher(salt []byte) (cipher.AEAD, error) {
+	secret, err := keypairs.KeyStoreSeedSecret()
+	if err != nil {
+		return nil, err
+	}
+	defer security.Wipe(secret)
+
+	entryKey, err := beecipher.HKDFSHA256(secret, salt, []byte("bumblebee journal entry key"), chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	defer security.Wipe(entryKey)
+
+	aead, err := chacha20poly1305.NewX(entryKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize the journal cipher: %w", err)
+	}
+
+	return aead, nil
+}
+
+func sealJournalEntry(aead cipher.AEAD, entry *Entry) ([]byte, error) {
+	plainEntry, err := msgpack.Marshal(entry)
+	if err != nil {
+		return nil, fmt.Errorf("unable to serialize journal entry: %w", err)
+	}
+	defer security.Wipe(plainEntry)
+
+	nonce, err := helpers.GetRandomBytes(aead.NonceSize())
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate journal entry nonce: %w", err)
+	}
+
+	return aead.Seal(nonce, nonce, plainEntry, binary.BigEndian.AppendUint64(nil, uint64(entry.Sequence))), nil
+}
+
+func openJournalEntry(aead cipher.AEAD, sequence int, sealedEntry []byte) (*Entry, error) {
+	nonceSize := aead.NonceSize()
+	if len(sealedEntry) < nonceSize {
+		return nil, fmt.Errorf("journal entry %d is truncated", sequence)
+	}
+
+	plainEntry, err := aead.Open(
+		nil,
+		sealedEntry[:nonceSize],
+		sealedEntry[nonceSize:],
+		binary.BigEndian.AppendUint64(nil, uint64(sequence)))
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to decrypt journal entry %d.  The keystore keypairs may not be the ones for this journal",
+			sequence)
+	}
+	defer security.Wipe(plainEntry)
+
+	entry := &Entry{}
+	err = msgpack.Unmarshal(plainEntry, entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed interpreting journal entry %d: %w", sequence, err)
+	}
+
+	return entry, nil
+}
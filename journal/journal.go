@@ -0,0 +1,171 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package journal contains the keystore change journal.  The journal is an append-only, encrypted file
+per profile that records each change to the keystore and the keypair store, along with the values
+the change replaced, so that changes can be reviewed and undone.
+*/
+package journal
+
+import (
+	"fmt"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/security"
+)
+
+// The kinds of items a change applies to
+const (
+	ItemKindUser    = "user"
+	ItemKindGroup   = "group"
+	ItemKindKeyPair = "keypair"
+)
+
+// The actions a change makes to an item
+const (
+	ActionAdd    = "add"
+	ActionRename = "rename"
+	ActionUpdate = "update"
+	ActionRemove = "remove"
+	// ActionRetire is a keypair rotation that kept the old keys under NewName
+	ActionRetire = "retire"
+)
+
+// OperationUndo is the operation of the entries that record an undo
+const OperationUndo = "undo"
+
+// Change is a single item changed by an operation.  The previous value is set for updates and removes.
+// For users and groups, it is what the item is restored to when the change is undone.
+type Change struct {
+	ItemKind string
+	Action   string
+	Name     string
+	// NewName is set for renames and retires
+	NewName         string           `msgpack:",omitempty"`
+	PreviousEntity  *security.Entity `msgpack:",omitempty"`
+	PreviousGroup   *keystore.Group  `msgpack:",omitempty"`
+	PreviousKeyPair *KeyPairSummary  `msgpack:",omitempty"`
+}
+
+// KeyPairSummary is the public part of a keypair, recorded for keypair changes.  Private keys are never
+// written to the journal, so keypair changes that would need them to be reverted are not undoable.
+type KeyPairSummary struct {
+	Name          string
+	CipherPubKey  string
+	SigningPubKey string
+	GroupEpoch    int `msgpack:",omitempty"`
+}
+
+func newKeyPairSummary(kpi *security.KeyPairInfo) *KeyPairSummary {
+	summary := &KeyPairSummary{Name: kpi.Name, GroupEpoch: kpi.GroupEpoch}
+	summary.CipherPubKey, summary.SigningPubKey, _ = kpi.PublicKeys()
+	return summary
+}
+
+// Describe returns a short description of the change, such as: rename user "bob" to "robert"
+func (c *Change) Describe() string {
+	if c.NewName != "" {
+		return fmt.Sprintf("%s %s \"%s\" to \"%s\"", c.Action, c.ItemKind, c.Name, c.NewName)
+	}
+
+	return fmt.Sprintf("%s %s \"%s\"", c.Action, c.ItemKind, c.Name)
+}
+
+// Entry is one operation recorded in the journal, such as a "remove user" command
+type Entry struct {
+	// Sequence is the entry's position in the journal, starting at 1
+	Sequence int
+	// Date is the date the entry was recorded, in RFC3339
+	Date string
+	// Operation describes the keystore or keypair store call, such as "set user trust"
+	Operation string
+	Changes   []*Change `msgpack:",omitempty"`
+	// UndoOf is set for undo entries, to the sequence of the entry that was undone
+	UndoOf int `msgpack:",omitempty"`
+	// NotUndoable is set for operations that should not be reverted, such as revocations, or that can not be
+	// reverted without private keys, such as keypair removals, or that would destroy private keys if reverted,
+	// such as keypair adds and rotations
+	NotUndoable bool `msgpack:",omitempty"`
+}
+
+func newEntry(operation string, changes ...*Change) *Entry {
+	return &Entry{
+		Operation: operation,
+		Changes:   changes,
+	}
+}
+
+type JournalWalkFunc func(entry *Entry, undone bool)
+
+// Journal is the list of operations recorded for a profile.  Entries are only ever appended.
+type Journal struct {
+	Entries []*Entry
+
+	// SourceFilePath is the file the journal is read from and appended to
+	SourceFilePath string
+
+	// isLoaded is false until the entries in SourceFilePath have been read
+	isLoaded bool
+	// validLength is the length of the file up to the end of the last complete entry
+	validLength int64
+}
+
+// New returns a journal for filePath.  The file is not read until the journal is first appended to.
+func New(filePath string) *Journal {
+	return &Journal{SourceFilePath: filePath}
+}
+
+func (j *Journal) Count() int {
+	return len(j.Entries)
+}
+
+// undoneSequences returns the sequences of the entries that have been undone
+func (j *Journal) undoneSequences() map[int]bool {
+	undone := make(map[int]bool)
+	for _, entry := range j.Entries {
+		if entry.UndoOf != 0 {
+			undone[entry.UndoOf] = true
+		}
+	}
+
+	return undone
+}
+
+// Walk calls walkFunc for each entry, in the order they were recorded
+func (j *Journal) Walk(walkFunc JournalWalkFunc) {
+	if walkFunc == nil {
+		panic("walkFunc is nil")
+	}
+
+	undone := j.undoneSequences()
+	for _, entry := range j.Entries {
+		walkFunc(entry, undone[entry.Sequence])
+	}
+}
+
+// UndoCandidates returns the last count entries that have not been undone, newest first.  Undo entries
+// are not included, so undo is not undone itself.  Fewer entries are returned if there are not enough.
+func (j *Journal) UndoCandidates(count int) []*Entry {
+	undone := j.undoneSequences()
+	candidates := []*Entry{}
+	for index := len(j.Entries) - 1; index >= 0 && len(candidates) < count; index-- {
+		entry := j.Entries[index]
+		if entry.Operation == OperationUndo || undone[entry.Sequence] {
+			continue
+		}
+
+		candidates = append(candidates, entry)
+	}
+
+	return candidates
+}
@@ -0,0 +1,211 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/security"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func useTestKeyStoreKeyPairs(t *testing.T) keypairs.KeyPairStore {
+	kps := keypairs.NewKeypairStore()
+	_, _ = kps.CreateNewKeyPair(helpers.KeyPairNameForKeyStoreReads)
+	_, _ = kps.CreateNewKeyPair(helpers.KeyPairNameForKeyStoreWrites)
+	keypairStorePath := filepath.Join(t.TempDir(), "keypairs.store")
+	require.Nil(t, kps.SaveKeyPairStore(nil, keypairStorePath))
+
+	// Loading the store from its file sets the origin path, which keypair changes are saved to
+	kps, err := keypairs.NewKeypairStoreFromFile(nil, keypairStorePath)
+	require.Nil(t, err)
+	keypairs.GlobalKeyPairStore = kps
+	return kps
+}
+
+func addTestUser(t *testing.T, store keystore.KeyStore, name string) {
+	kpi, err := security.NewKeyPairInfoWithSeeds(name)
+	require.Nil(t, err)
+	cipherPubKey, signingPubKey, err := kpi.PublicKeys()
+	require.Nil(t, err)
+	require.Nil(t, store.AddKey(name, cipherPubKey, signingPubKey))
+}
+
+func TestJournal_AppendReadCycle(t *testing.T) {
+	useTestKeyStoreKeyPairs(t)
+	journalPath := filepath.Join(t.TempDir(), "keystore.journal")
+
+	testJournal := New(journalPath)
+	require.Nil(t, testJournal.Append(newEntry("add user", &Change{ItemKind: ItemKindUser, Action: ActionAdd, Name: "bob"})))
+	require.Nil(t, testJournal.Append(newEntry("rename user", &Change{ItemKind: ItemKindUser, Action: ActionRename, Name: "bob", NewName: "robert"})))
+
+	// An interrupted append leaves an incomplete entry, which is ignored and then overwritten
+	journalFile, err := os.OpenFile(journalPath, os.O_APPEND|os.O_WRONLY, 0600)
+	require.Nil(t, err)
+	_, err = journalFile.Write([]byte{0, 0, 1, 0, 9, 9})
+	require.Nil(t, err)
+	require.Nil(t, journalFile.Close())
+
+	readJournal, err := ReadFromFile(journalPath)
+	require.Nil(t, err)
+	require.Equal(t, 2, readJournal.Count())
+	assert.Equal(t, 2, readJournal.Entries[1].Sequence)
+	assert.Equal(t, "rename user \"bob\" to \"robert\"", readJournal.Entries[1].Changes[0].Describe())
+
+	require.Nil(t, readJournal.Append(newEntry("remove user", &Change{ItemKind: ItemKindUser, Action: ActionRemove, Name: "robert"})))
+
+	readJournal, err = ReadFromFile(journalPath)
+	require.Nil(t, err)
+	assert.Equal(t, 3, readJournal.Count())
+
+	// The journal is encrypted with the keystore keypairs, so other keypairs cannot read it
+	useTestKeyStoreKeyPairs(t)
+	_, err = ReadFromFile(journalPath)
+	assert.NotNil(t, err)
+}
+
+func TestJournal_UndoKeyStoreChanges(t *testing.T) {
+	useTestKeyStoreKeyPairs(t)
+	tempPath := t.TempDir()
+
+	keystorePath := filepath.Join(tempPath, "keystore.store")
+	require.Nil(t, keystore.New("local", "local", true).WriteToFile(keystorePath))
+	simpleStore, err := keystore.NewFromFile(nil, keystorePath)
+	require.Nil(t, err)
+
+	testJournal := New(filepath.Join(tempPath, "keystore.journal"))
+	store := NewJournaledKeyStore(simpleStore, testJournal)
+
+	addTestUser(t, store, "bob")
+	addTestUser(t, store, "alice")
+	require.Nil(t, store.AddGroup("team", []string{"bob", "alice"}))
+	_, err = store.SetEntityTrust("bob", security.TrustLevelVerified, "in person")
+	require.Nil(t, err)
+	_, err = store.RemoveEntity("bob")
+	require.Nil(t, err)
+	assert.Equal(t, 5, testJournal.Count())
+
+	// Undoing the removal restores the user and the group membership
+	count, err := testJournal.Undo(testJournal.UndoCandidates(1), store, nil)
+	require.Nil(t, err)
+	assert.Equal(t, 1, count)
+
+	bob := store.GetKey("bob")
+	require.NotNil(t, bob)
+	assert.Equal(t, security.TrustLevelVerified, bob.Trust)
+	assert.True(t, store.GetGroup("team").HasMember("bob"))
+
+	// The undo is recorded, but is not an undo candidate itself
+	assert.Equal(t, 6, testJournal.Count())
+	candidates := testJournal.UndoCandidates(1)
+	require.Len(t, candidates, 1)
+	assert.Equal(t, "set user trust", candidates[0].Operation)
+
+	count, err = testJournal.Undo(testJournal.UndoCandidates(2), store, nil)
+	require.Nil(t, err)
+	assert.Equal(t, 2, count)
+	assert.NotEqual(t, security.TrustLevelVerified, store.GetKey("bob").Trust)
+	assert.Nil(t, store.GetGroup("team"))
+
+	readJournal, err := ReadFromFile(testJournal.SourceFilePath)
+	require.Nil(t, err)
+	candidates = readJournal.UndoCandidates(10)
+	require.Len(t, candidates, 2)
+	assert.Equal(t, "alice", candidates[0].Changes[0].Name)
+}
+
+func TestJournal_UndoKeyPairChanges(t *testing.T) {
+	kps := useTestKeyStoreKeyPairs(t)
+
+	testJournal := New(filepath.Join(t.TempDir(), "keystore.journal"))
+	store := NewJournaledKeyPairStore(kps, testJournal)
+
+	_, err := store.CreateNewKeyPair("work")
+	require.Nil(t, err)
+
+	// Keypair changes are recorded when the store is saved
+	assert.Equal(t, 0, testJournal.Count())
+	require.Nil(t, store.SaveKeyPairStoreToOrigin(nil))
+	assert.Equal(t, 1, testJournal.Count())
+
+	_, originalSigningPubKey, err := store.GetKeyPairInfo("work").PublicKeys()
+	require.Nil(t, err)
+	_, _, err = store.RotateKeyPair("work", "work-retired")
+	require.Nil(t, err)
+	require.Nil(t, store.SaveKeyPairStoreToOrigin(nil))
+	assert.Equal(t, 2, testJournal.Count())
+
+	// Only the public keys are recorded
+	rotateChange := testJournal.Entries[1].Changes[0]
+	assert.Equal(t, ActionRetire, rotateChange.Action)
+	assert.Equal(t, originalSigningPubKey, rotateChange.PreviousKeyPair.SigningPubKey)
+
+	// Renames can be undone
+	_, err = store.RenameKeyPair("work-retired", "old-work")
+	require.Nil(t, err)
+	require.Nil(t, store.SaveKeyPairStoreToOrigin(nil))
+	assert.Equal(t, 3, testJournal.Count())
+
+	count, err := testJournal.Undo(testJournal.UndoCandidates(1), nil, store)
+	require.Nil(t, err)
+	assert.Equal(t, 1, count)
+	assert.NotNil(t, store.GetKeyPairInfo("work-retired"))
+	assert.Nil(t, store.GetKeyPairInfo("old-work"))
+
+	// Undo changes are not recorded as new keypair changes
+	assert.Equal(t, 4, testJournal.Count())
+
+	// Adds and rotations can not be undone, since that would destroy private keys.  Removals and rotations that
+	// discard the old keys can not be undone, since the private keys are not recorded.
+	_, _, err = store.RotateKeyPair("work", "")
+	require.Nil(t, err)
+	require.Nil(t, store.SaveKeyPairStoreToOrigin(nil))
+	_, err = store.RemoveKeyPair("work")
+	require.Nil(t, err)
+	assert.Equal(t, 6, testJournal.Count())
+
+	candidates := testJournal.UndoCandidates(10)
+	require.Len(t, candidates, 4)
+	for _, entry := range candidates {
+		assert.True(t, entry.NotUndoable, entry.Operation)
+	}
+
+	count, err = testJournal.Undo(candidates[len(candidates)-1:], nil, store)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, count)
+	assert.NotNil(t, store.GetKeyPairInfo("work-retired"))
+	assert.Nil(t, store.GetKeyPairInfo("work"))
+
+	// Keypair adds recorded before they were marked not undoable are still refused
+	addChange := &Change{ItemKind: ItemKindKeyPair, Action: ActionAdd, Name: "work-retired"}
+	assert.NotNil(t, undoKeyPairChange(addChange, store))
+	assert.NotNil(t, store.GetKeyPairInfo("work-retired"))
+}
+
+func TestJournal_RevocationsCannotBeUndone(t *testing.T) {
+	testJournal := &Journal{isLoaded: true}
+	testJournal.Entries = []*Entry{
+		{Sequence: 1, Operation: "add user", Changes: []*Change{{ItemKind: ItemKindUser, Action: ActionAdd, Name: "bob"}}},
+		{Sequence: 2, Operation: "revoke user", NotUndoable: true},
+	}
+
+	count, err := testJournal.Undo(testJournal.UndoCandidates(2), nil, nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, count)
+}
@@ -0,0 +1,466 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/logger"
+	"github.com/thoughtrealm/bumblebee/security"
+	"strings"
+	"time"
+)
+
+// JournaledKeyStore is a keystore that records its changes in a journal.  Reads go straight to the
+// wrapped store.  A change is recorded after the wrapped store has made it.
+type JournaledKeyStore struct {
+	keystore.KeyStore
+
+	journal *Journal
+
+	// pending are changes that the wrapped store does not write until WriteToFile is called, such as renames
+	pending []*Entry
+}
+
+// NewJournaledKeyStore returns store wrapped so that its changes are recorded in journal
+func NewJournaledKeyStore(store keystore.KeyStore, journal *Journal) *JournaledKeyStore {
+	return &JournaledKeyStore{
+		KeyStore: store,
+		journal:  journal,
+	}
+}
+
+// Unwrap returns the wrapped store, whose changes are not recorded
+func (jks *JournaledKeyStore) Unwrap() keystore.KeyStore {
+	return jks.KeyStore
+}
+
+// record appends the entry to the journal.  The change has already been made, so failures are reported
+// as warnings instead of failing the change.
+func (jks *JournaledKeyStore) record(entry *Entry) {
+	err := jks.journal.Append(entry)
+	if err != nil {
+		logger.Errorfln("WARNING: Unable to record \"%s\" in the keystore journal: %s", entry.Operation, err)
+	}
+}
+
+// snapshotEntities returns copies of the entities that match filterFunc, keyed by upper case name
+func (jks *JournaledKeyStore) snapshotEntities(filterFunc keystore.KeyStoreWalkFilterFunc) map[string]*security.Entity {
+	snapshot := make(map[string]*security.Entity)
+	_ = jks.KeyStore.Walk(keystore.NewWalkInfo("", false, filterFunc, func(entity *security.Entity) {
+		snapshot[strings.ToUpper(entity.Name)] = entity.Clone()
+	}))
+
+	return snapshot
+}
+
+// snapshotGroupsWithMember returns copies of the groups the entity is a member of
+func (jks *JournaledKeyStore) snapshotGroupsWithMember(name string) []*keystore.Group {
+	groups := []*keystore.Group{}
+	for _, group := range jks.KeyStore.ListGroups() {
+		if group.HasMember(name) {
+			groups = append(groups, group)
+		}
+	}
+
+	return groups
+}
+
+// updateUser is used for the changes that update a single user that is known by name
+func (jks *JournaledKeyStore) updateUser(operation, name string, changeFunc func() (bool, error)) (found bool, err error) {
+	previous := jks.KeyStore.GetKey(name)
+	found, err = changeFunc()
+	if found && err == nil && previous != nil {
+		jks.record(newEntry(operation, &Change{ItemKind: ItemKindUser, Action: ActionUpdate, Name: previous.Name, PreviousEntity: previous}))
+	}
+
+	return found, err
+}
+
+func (jks *JournaledKeyStore) AddCertification(certification *security.Certification) (name string, err error) {
+	var snapshot map[string]*security.Entity
+	if certification != nil {
+		snapshot = jks.snapshotEntities(func(entity *security.Entity) bool {
+			return certification.Certifies(entity.PublicKeys)
+		})
+	}
+
+	name, err = jks.KeyStore.AddCertification(certification)
+	if err == nil && snapshot[strings.ToUpper(name)] != nil {
+		previous := snapshot[strings.ToUpper(name)]
+		jks.record(newEntry("certify user", &Change{ItemKind: ItemKindUser, Action: ActionUpdate, Name: previous.Name, PreviousEntity: previous}))
+	}
+
+	return name, err
+}
+
+func (jks *JournaledKeyStore) AddGroup(name string, memberNames []string) error {
+	err := jks.KeyStore.AddGroup(name, memberNames)
+	if err == nil {
+		jks.record(newEntry("add group", &Change{ItemKind: ItemKindGroup, Action: ActionAdd, Name: name}))
+	}
+
+	return err
+}
+
+func (jks *JournaledKeyStore) AddKey(name, cipherPubKey, signingPubKey string) error {
+	err := jks.KeyStore.AddKey(name, cipherPubKey, signingPubKey)
+	if err == nil {
+		jks.record(newEntry("add user", &Change{ItemKind: ItemKindUser, Action: ActionAdd, Name: name}))
+	}
+
+	return err
+}
+
+func (jks *JournaledKeyStore) AddKeyWithDetails(entity *security.Entity) error {
+	err := jks.KeyStore.AddKeyWithDetails(entity)
+	if err == nil {
+		jks.record(newEntry("add user", &Change{ItemKind: ItemKindUser, Action: ActionAdd, Name: entity.Name}))
+	}
+
+	return err
+}
+
+func (jks *JournaledKeyStore) ApplyKeySuccession(statement *security.SuccessionStatement) (name string, err error) {
+	var snapshot map[string]*security.Entity
+	if statement != nil {
+		snapshot = jks.snapshotEntities(func(entity *security.Entity) bool {
+			return entity.PublicKeys != nil && entity.PublicKeys.SigningPubKey == statement.OldSigningPubKey
+		})
+	}
+
+	name, err = jks.KeyStore.ApplyKeySuccession(statement)
+	if err == nil && snapshot[strings.ToUpper(name)] != nil {
+		previous := snapshot[strings.ToUpper(name)]
+		jks.record(newEntry("apply key succession", &Change{ItemKind: ItemKindUser, Action: ActionUpdate, Name: previous.Name, PreviousEntity: previous}))
+	}
+
+	return name, err
+}
+
+// ApplyRevocation records the revocation, but it is not undoable.  A revocation is meant to be permanent.
+func (jks *JournaledKeyStore) ApplyRevocation(certificate *security.RevocationCertificate) (entry *keystore.RevocationEntry, name string, err error) {
+	entry, name, err = jks.KeyStore.ApplyRevocation(certificate)
+	if err == nil {
+		revocationEntry := newEntry("revoke user")
+		if name != "" {
+			revocationEntry.Changes = []*Change{{ItemKind: ItemKindUser, Action: ActionUpdate, Name: name}}
+		}
+
+		revocationEntry.NotUndoable = true
+		jks.record(revocationEntry)
+	}
+
+	return entry, name, err
+}
+
+// RenameEntity does not write the store, so the rename is recorded when WriteToFile is called
+func (jks *JournaledKeyStore) RenameEntity(oldName, newName string) (bool, error) {
+	found, err := jks.KeyStore.RenameEntity(oldName, newName)
+	if found && err == nil {
+		jks.pending = append(jks.pending, newEntry("rename user", &Change{ItemKind: ItemKindUser, Action: ActionRename, Name: oldName, NewName: newName}))
+	}
+
+	return found, err
+}
+
+// RemoveEntity also records the groups the user is removed from, so that undo can restore the memberships
+func (jks *JournaledKeyStore) RemoveEntity(name string) (found bool, err error) {
+	previous := jks.KeyStore.GetKey(name)
+	groups := jks.snapshotGroupsWithMember(name)
+
+	found, err = jks.KeyStore.RemoveEntity(name)
+	if found && err == nil && previous != nil {
+		changes := []*Change{}
+		for _, group := range groups {
+			changes = append(changes, &Change{ItemKind: ItemKindGroup, Action: ActionUpdate, Name: group.Name, PreviousGroup: group})
+		}
+
+		changes = append(changes, &Change{ItemKind: ItemKindUser, Action: ActionRemove, Name: previous.Name, PreviousEntity: previous})
+		jks.record(newEntry("remove user", changes...))
+	}
+
+	return found, err
+}
+
+func (jks *JournaledKeyStore) RemoveGroup(name string) (found bool, err error) {
+	previous := jks.KeyStore.GetGroup(name)
+	found, err = jks.KeyStore.RemoveGroup(name)
+	if found && err == nil && previous != nil {
+		jks.record(newEntry("remove group", &Change{ItemKind: ItemKindGroup, Action: ActionRemove, Name: previous.Name, PreviousGroup: previous}))
+	}
+
+	return found, err
+}
+
+func (jks *JournaledKeyStore) SetEntityTrust(name string, trust security.TrustLevel, verificationMethod string) (found bool, err error) {
+	return jks.updateUser("set user trust", name, func() (bool, error) {
+		return jks.KeyStore.SetEntityTrust(name, trust, verificationMethod)
+	})
+}
+
+func (jks *JournaledKeyStore) UpdateContactInfo(name string, contact *security.ContactInfo) (found bool, err error) {
+	return jks.updateUser("update user contact", name, func() (bool, error) {
+		return jks.KeyStore.UpdateContactInfo(name, contact)
+	})
+}
+
+func (jks *JournaledKeyStore) UpdateGroupMembers(name string, addNames, removeNames []string) (found bool, err error) {
+	previous := jks.KeyStore.GetGroup(name)
+	found, err = jks.KeyStore.UpdateGroupMembers(name, addNames, removeNames)
+	if found && err == nil && previous != nil {
+		jks.record(newEntry("update group", &Change{ItemKind: ItemKindGroup, Action: ActionUpdate, Name: previous.Name, PreviousGroup: previous}))
+	}
+
+	return found, err
+}
+
+func (jks *JournaledKeyStore) UpdateCipherPublicKey(name, cipherPublicKey string) (found bool, err error) {
+	return jks.updateUser("update user keys", name, func() (bool, error) {
+		return jks.KeyStore.UpdateCipherPublicKey(name, cipherPublicKey)
+	})
+}
+
+func (jks *JournaledKeyStore) UpdatePublicKeys(name, cipherPublicKey, signingPublicKey string) (found bool, err error) {
+	return jks.updateUser("update user keys", name, func() (bool, error) {
+		return jks.KeyStore.UpdatePublicKeys(name, cipherPublicKey, signingPublicKey)
+	})
+}
+
+func (jks *JournaledKeyStore) UpdatePublicKeysWithSource(
+	name, cipherPublicKey, signingPublicKey string,
+	source security.KeySource,
+	sourceDetails string) (found bool, err error) {
+
+	return jks.updateUser("update user keys", name, func() (bool, error) {
+		return jks.KeyStore.UpdatePublicKeysWithSource(name, cipherPublicKey, signingPublicKey, source, sourceDetails)
+	})
+}
+
+func (jks *JournaledKeyStore) UpdateSigningPublicKey(name, signingPublicKey string) (found bool, err error) {
+	return jks.updateUser("update user keys", name, func() (bool, error) {
+		return jks.KeyStore.UpdateSigningPublicKey(name, signingPublicKey)
+	})
+}
+
+func (jks *JournaledKeyStore) UpdateSubkeys(name string, certificates []*security.SubkeyCertificate) (found bool, added int, err error) {
+	previous := jks.KeyStore.GetKey(name)
+	found, added, err = jks.KeyStore.UpdateSubkeys(name, certificates)
+	if found && added > 0 && err == nil && previous != nil {
+		jks.record(newEntry("update user subkeys", &Change{ItemKind: ItemKindUser, Action: ActionUpdate, Name: previous.Name, PreviousEntity: previous}))
+	}
+
+	return found, added, err
+}
+
+// WriteToFile records the pending changes once they are written to the store's own file
+func (jks *JournaledKeyStore) WriteToFile(filePath string) error {
+	err := jks.KeyStore.WriteToFile(filePath)
+	if err == nil && filePath == "" {
+		for _, entry := range jks.pending {
+			jks.record(entry)
+		}
+
+		jks.pending = nil
+	}
+
+	return err
+}
+
+// JournaledKeyPairStore is a keypair store that records its changes in a journal.  Keypair changes are not
+// written until the store is saved, so they are recorded when the store is saved.
+type JournaledKeyPairStore struct {
+	keypairs.KeyPairStore
+
+	journal *Journal
+	pending []*Entry
+}
+
+// NewJournaledKeyPairStore returns store wrapped so that its changes are recorded in journal
+func NewJournaledKeyPairStore(store keypairs.KeyPairStore, journal *Journal) *JournaledKeyPairStore {
+	return &JournaledKeyPairStore{
+		KeyPairStore: store,
+		journal:      journal,
+	}
+}
+
+// Unwrap returns the wrapped store, whose changes are not recorded
+func (jkps *JournaledKeyPairStore) Unwrap() keypairs.KeyPairStore {
+	return jkps.KeyPairStore
+}
+
+func (jkps *JournaledKeyPairStore) addPending(operation string, changes ...*Change) *Entry {
+	entry := newEntry(operation, changes...)
+	jkps.pending = append(jkps.pending, entry)
+	return entry
+}
+
+// addPendingNotUndoable adds an entry for a change that can not be reverted without the private keys it replaced
+func (jkps *JournaledKeyPairStore) addPendingNotUndoable(operation string, previous *security.KeyPairInfo, action string) {
+	entry := jkps.addPending(operation, &Change{ItemKind: ItemKindKeyPair, Action: action, Name: previous.Name, PreviousKeyPair: newKeyPairSummary(previous)})
+	entry.NotUndoable = true
+}
+
+// addPendingKeyPairAdd adds an entry for a new keypair.  Undoing it would destroy the keypair's private keys, which
+// are not recorded, so it is not undoable.
+func (jkps *JournaledKeyPairStore) addPendingKeyPairAdd(operation, name string) {
+	entry := jkps.addPending(operation, &Change{ItemKind: ItemKindKeyPair, Action: ActionAdd, Name: name})
+	entry.NotUndoable = true
+}
+
+// recordPending appends the pending changes to the journal, after the store has been saved
+func (jkps *JournaledKeyPairStore) recordPending() {
+	for _, entry := range jkps.pending {
+		err := jkps.journal.Append(entry)
+		if err != nil {
+			logger.Errorfln("WARNING: Unable to record \"%s\" in the keystore journal: %s", entry.Operation, err)
+		}
+	}
+
+	jkps.pending = nil
+}
+
+func (jkps *JournaledKeyPairStore) AddSubkey(name string, validFor time.Duration) (*security.Subkey, error) {
+	previous := jkps.KeyPairStore.GetKeyPairInfo(name)
+	subkey, err := jkps.KeyPairStore.AddSubkey(name, validFor)
+	if err == nil && previous != nil {
+		jkps.addPendingNotUndoable("add subkey", previous, ActionUpdate)
+	}
+
+	return subkey, err
+}
+
+func (jkps *JournaledKeyPairStore) CreateGroupKeyPair(name string, members []string) (*security.KeyPairInfo, error) {
+	kpi, err := jkps.KeyPairStore.CreateGroupKeyPair(name, members)
+	if err == nil {
+		jkps.addPendingKeyPairAdd("create group keypair", kpi.Name)
+	}
+
+	return kpi, err
+}
+
+func (jkps *JournaledKeyPairStore) CreateNewKeyPair(name string) (*security.KeyPairInfo, error) {
+	kpi, err := jkps.KeyPairStore.CreateNewKeyPair(name)
+	if err == nil {
+		jkps.addPendingKeyPairAdd("add keypair", kpi.Name)
+	}
+
+	return kpi, err
+}
+
+func (jkps *JournaledKeyPairStore) ImportKeyPair(kpi *security.KeyPairInfo) error {
+	err := jkps.KeyPairStore.ImportKeyPair(kpi)
+	if err == nil {
+		jkps.addPendingKeyPairAdd("import keypair", kpi.Name)
+	}
+
+	return err
+}
+
+// LoadKeyPairStoreFromFile replaces the store's keypairs, so any pending changes are discarded
+func (jkps *JournaledKeyPairStore) LoadKeyPairStoreFromFile(key []byte, filePath string) error {
+	jkps.pending = nil
+	return jkps.KeyPairStore.LoadKeyPairStoreFromFile(key, filePath)
+}
+
+func (jkps *JournaledKeyPairStore) MergeGroupKey(groupKPI *security.KeyPairInfo, senderSigningPubKey string) (kpi *security.KeyPairInfo, added bool, err error) {
+	var previous *security.KeyPairInfo
+	if groupKPI != nil {
+		previous = jkps.KeyPairStore.GetKeyPairInfo(groupKPI.Name)
+	}
+
+	kpi, added, err = jkps.KeyPairStore.MergeGroupKey(groupKPI, senderSigningPubKey)
+	if err == nil {
+		if added {
+			jkps.addPendingKeyPairAdd("merge group keypair", kpi.Name)
+		} else if previous != nil {
+			jkps.addPendingNotUndoable("merge group keypair", previous, ActionUpdate)
+		}
+	}
+
+	return kpi, added, err
+}
+
+func (jkps *JournaledKeyPairStore) RekeyGroup(name string, members []string) (oldKPI, newKPI *security.KeyPairInfo, err error) {
+	oldKPI, newKPI, err = jkps.KeyPairStore.RekeyGroup(name, members)
+	if err == nil {
+		jkps.addPendingNotUndoable("rekey group keypair", oldKPI, ActionUpdate)
+	}
+
+	return oldKPI, newKPI, err
+}
+
+// RemoveKeyPair saves the store itself, so the removal and any other pending changes are recorded here
+func (jkps *JournaledKeyPairStore) RemoveKeyPair(name string) (bool, error) {
+	previous := jkps.KeyPairStore.GetKeyPairInfo(name)
+	found, err := jkps.KeyPairStore.RemoveKeyPair(name)
+	if found && err == nil && previous != nil {
+		jkps.addPendingNotUndoable("remove keypair", previous, ActionRemove)
+		jkps.recordPending()
+	}
+
+	return found, err
+}
+
+func (jkps *JournaledKeyPairStore) RenameKeyPair(currentName, newName string) (found bool, err error) {
+	found, err = jkps.KeyPairStore.RenameKeyPair(currentName, newName)
+	if found && err == nil {
+		jkps.addPending("rename keypair", &Change{ItemKind: ItemKindKeyPair, Action: ActionRename, Name: currentName, NewName: newName})
+	}
+
+	return found, err
+}
+
+// RotateKeyPair is recorded as a retire of the keypair if the old keys are kept, or as an update if they are
+// discarded.  Neither can be undone, since undoing a retire would destroy the new keypair's private keys.
+func (jkps *JournaledKeyPairStore) RotateKeyPair(name, retiredName string) (oldKPI, newKPI *security.KeyPairInfo, err error) {
+	oldKPI, newKPI, err = jkps.KeyPairStore.RotateKeyPair(name, retiredName)
+	if err != nil {
+		return oldKPI, newKPI, err
+	}
+
+	if retiredName == "" {
+		jkps.addPendingNotUndoable("rotate keypair", oldKPI, ActionUpdate)
+		return oldKPI, newKPI, err
+	}
+
+	entry := jkps.addPending("rotate keypair", &Change{
+		ItemKind:        ItemKindKeyPair,
+		Action:          ActionRetire,
+		Name:            oldKPI.Name,
+		NewName:         retiredName,
+		PreviousKeyPair: newKeyPairSummary(oldKPI),
+	})
+	entry.NotUndoable = true
+
+	return oldKPI, newKPI, err
+}
+
+func (jkps *JournaledKeyPairStore) SaveKeyPairStore(key []byte, storeFilePath string) error {
+	err := jkps.KeyPairStore.SaveKeyPairStore(key, storeFilePath)
+	if err == nil {
+		jkps.recordPending()
+	}
+
+	return err
+}
+
+func (jkps *JournaledKeyPairStore) SaveKeyPairStoreToOrigin(key []byte) error {
+	err := jkps.KeyPairStore.SaveKeyPairStoreToOrigin(key)
+	if err == nil {
+		jkps.recordPending()
+	}
+
+	return err
+}
@@ -0,0 +1,258 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/keystore"
+	"github.com/thoughtrealm/bumblebee/security"
+)
+
+// Undo reverts the entries, which should be from UndoCandidates, in order.  Each reverted entry is followed by
+// an undo entry in the journal.  If an entry cannot be reverted, Undo stops and returns the error, along with the
+// number of entries that were reverted before it.
+//
+// The changes are made through the keyStore and keyPairStore interfaces.  If they are journaled stores, the
+// stores they wrap are used, so that the undo itself is only recorded as an undo entry.
+func (j *Journal) Undo(entries []*Entry, keyStore keystore.KeyStore, keyPairStore keypairs.KeyPairStore) (count int, err error) {
+	if jks, ok := keyStore.(*JournaledKeyStore); ok {
+		keyStore = jks.Unwrap()
+	}
+
+	if jkps, ok := keyPairStore.(*JournaledKeyPairStore); ok {
+		keyPairStore = jkps.Unwrap()
+	}
+
+	for _, entry := range entries {
+		if entry.NotUndoable {
+			return count, fmt.Errorf("entry %d, \"%s\", cannot be undone", entry.Sequence, entry.Operation)
+		}
+	}
+
+	for _, entry := range entries {
+		err = undoEntry(entry, keyStore, keyPairStore)
+		if err != nil {
+			return count, fmt.Errorf("unable to undo entry %d, \"%s\": %w", entry.Sequence, entry.Operation, err)
+		}
+
+		undoRecord := newEntry(OperationUndo)
+		undoRecord.UndoOf = entry.Sequence
+		err = j.Append(undoRecord)
+		if err != nil {
+			return count, fmt.Errorf("entry %d was undone, but the undo could not be recorded: %w", entry.Sequence, err)
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+// undoEntry reverts the entry's changes in reverse order, then writes the stores that were changed
+func undoEntry(entry *Entry, keyStore keystore.KeyStore, keyPairStore keypairs.KeyPairStore) error {
+	keyStoreChanged := false
+	keyPairStoreChanged := false
+	for index := len(entry.Changes) - 1; index >= 0; index-- {
+		change := entry.Changes[index]
+
+		var err error
+		switch change.ItemKind {
+		case ItemKindUser:
+			err = undoUserChange(change, keyStore)
+			keyStoreChanged = true
+		case ItemKindGroup:
+			err = undoGroupChange(change, keyStore)
+			keyStoreChanged = true
+		case ItemKindKeyPair:
+			err = undoKeyPairChange(change, keyPairStore)
+			keyPairStoreChanged = true
+		default:
+			err = fmt.Errorf("unknown item kind \"%s\"", change.ItemKind)
+		}
+
+		if err != nil {
+			return fmt.Errorf("unable to undo %s: %w", change.Describe(), err)
+		}
+	}
+
+	if keyStoreChanged {
+		err := keyStore.WriteToFile("")
+		if err != nil {
+			return fmt.Errorf("unable to write keystore: %w", err)
+		}
+	}
+
+	if keyPairStoreChanged {
+		err := keyPairStore.SaveKeyPairStoreToOrigin(nil)
+		if err != nil {
+			return fmt.Errorf("unable to save keypair store: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func undoUserChange(change *Change, keyStore keystore.KeyStore) error {
+	if keyStore == nil {
+		return errors.New("keystore not loaded")
+	}
+
+	switch change.Action {
+	case ActionAdd:
+		found, err := keyStore.RemoveEntity(change.Name)
+		if err == nil && !found {
+			err = fmt.Errorf("user \"%s\" no longer exists", change.Name)
+		}
+
+		return err
+	case ActionRename:
+		found, err := keyStore.RenameEntity(change.NewName, change.Name)
+		if err == nil && !found {
+			err = fmt.Errorf("user \"%s\" no longer exists", change.NewName)
+		}
+
+		return err
+	case ActionUpdate:
+		return restoreEntity(change.PreviousEntity, keyStore)
+	case ActionRemove:
+		if change.PreviousEntity == nil {
+			return errors.New("no prior value was recorded")
+		}
+
+		return keyStore.AddKeyWithDetails(change.PreviousEntity)
+	}
+
+	return fmt.Errorf("unknown action \"%s\"", change.Action)
+}
+
+// restoreEntity replaces the current entity with the previous one.  Removing the current entity also removes it
+// from its groups, so the memberships are added back afterwards.
+func restoreEntity(previous *security.Entity, keyStore keystore.KeyStore) error {
+	if previous == nil {
+		return errors.New("no prior value was recorded")
+	}
+
+	groupNames := []string{}
+	for _, group := range keyStore.ListGroups() {
+		if group.HasMember(previous.Name) {
+			groupNames = append(groupNames, group.Name)
+		}
+	}
+
+	found, err := keyStore.RemoveEntity(previous.Name)
+	if err == nil && !found {
+		err = fmt.Errorf("user \"%s\" no longer exists", previous.Name)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = keyStore.AddKeyWithDetails(previous)
+	if err != nil {
+		return err
+	}
+
+	for _, groupName := range groupNames {
+		_, err = keyStore.UpdateGroupMembers(groupName, []string{previous.Name}, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func undoGroupChange(change *Change, keyStore keystore.KeyStore) error {
+	if keyStore == nil {
+		return errors.New("keystore not loaded")
+	}
+
+	switch change.Action {
+	case ActionAdd:
+		found, err := keyStore.RemoveGroup(change.Name)
+		if err == nil && !found {
+			err = fmt.Errorf("group \"%s\" no longer exists", change.Name)
+		}
+
+		return err
+	case ActionUpdate:
+		return restoreGroupMembers(change.PreviousGroup, keyStore)
+	case ActionRemove:
+		if change.PreviousGroup == nil {
+			return errors.New("no prior value was recorded")
+		}
+
+		return keyStore.AddGroup(change.PreviousGroup.Name, change.PreviousGroup.Members)
+	}
+
+	return fmt.Errorf("unknown action \"%s\"", change.Action)
+}
+
+// restoreGroupMembers sets the group's members back to the previous members
+func restoreGroupMembers(previous *keystore.Group, keyStore keystore.KeyStore) error {
+	if previous == nil {
+		return errors.New("no prior value was recorded")
+	}
+
+	current := keyStore.GetGroup(previous.Name)
+	if current == nil {
+		return fmt.Errorf("group \"%s\" no longer exists", previous.Name)
+	}
+
+	addNames := []string{}
+	for _, member := range previous.Members {
+		if !current.HasMember(member) {
+			addNames = append(addNames, member)
+		}
+	}
+
+	removeNames := []string{}
+	for _, member := range current.Members {
+		if !previous.HasMember(member) {
+			removeNames = append(removeNames, member)
+		}
+	}
+
+	if len(addNames) == 0 && len(removeNames) == 0 {
+		return nil
+	}
+
+	_, err := keyStore.UpdateGroupMembers(previous.Name, addNames, removeNames)
+	return err
+}
+
+func undoKeyPairChange(change *Change, keyPairStore keypairs.KeyPairStore) error {
+	if keyPairStore == nil {
+		return errors.New("keypair store not loaded")
+	}
+
+	switch change.Action {
+	case ActionRename:
+		found, err := keyPairStore.RenameKeyPair(change.NewName, change.Name)
+		if err == nil && !found {
+			err = fmt.Errorf("keypair \"%s\" no longer exists", change.NewName)
+		}
+
+		return err
+	case ActionAdd, ActionRetire:
+		return fmt.Errorf("undoing would destroy the private keys of keypair \"%s\"", change.Name)
+	case ActionUpdate, ActionRemove:
+		return errors.New("private keys are not recorded in the journal")
+	}
+
+	return fmt.Errorf("unknown action \"%s\"", change.Action)
+}
@@ -14,8 +14,11 @@
 package keypairs
 
 import (
+	"errors"
 	"fmt"
+	"github.com/thoughtrealm/bumblebee/helpers"
 	"github.com/thoughtrealm/bumblebee/security"
+	"time"
 )
 
 var GlobalKeyPairStore KeyPairStore
@@ -23,12 +26,18 @@ var GlobalKeyPairStore KeyPairStore
 type KeyPairStoreWalkFunc func(kpi *security.KeyPairInfo)
 
 type KeyPairStore interface {
+	AddSubkey(name string, validFor time.Duration) (*security.Subkey, error)
 	Count() int
+	CreateGroupKeyPair(name string, members []string) (*security.KeyPairInfo, error)
 	CreateNewKeyPair(name string) (*security.KeyPairInfo, error)
 	GetKeyPairInfo(name string) *security.KeyPairInfo
+	ImportKeyPair(kpi *security.KeyPairInfo) error
 	LoadKeyPairStoreFromFile(key []byte, filePath string) error
+	MergeGroupKey(groupKPI *security.KeyPairInfo, senderSigningPubKey string) (kpi *security.KeyPairInfo, added bool, err error)
+	RekeyGroup(name string, members []string) (oldKPI, newKPI *security.KeyPairInfo, err error)
 	RemoveKeyPair(name string) (bool, error)
 	RenameKeyPair(currentName, newName string) (found bool, err error)
+	RotateKeyPair(name, retiredName string) (oldKPI, newKPI *security.KeyPairInfo, err error)
 	SaveKeyPairStore(key []byte, storeFilePath string) error
 	SaveKeyPairStoreToOrigin(key []byte) error
 	SetPassword(newPassword []byte)
@@ -72,3 +81,27 @@ func WipeGlobalKeyPairsIfValid() {
 		GlobalKeyPairStore = nil
 	}
 }
+
+// KeyStoreSeedSecret returns the secret that keys for local keystore files are derived from, which is the
+// keystore_read cipher seed followed by the keystore_write signing seed.  Callers should wipe it when done.
+func KeyStoreSeedSecret() ([]byte, error) {
+	if GlobalKeyPairStore == nil {
+		return nil, errors.New("global keypair store is not loaded")
+	}
+
+	kpiRead := GlobalKeyPairStore.GetKeyPairInfo(helpers.KeyPairNameForKeyStoreReads)
+	if kpiRead == nil {
+		return nil, errors.New("keypair info for keystore reads was not found in the global keypair store")
+	}
+	defer kpiRead.Wipe()
+
+	kpiWrite := GlobalKeyPairStore.GetKeyPairInfo(helpers.KeyPairNameForKeyStoreWrites)
+	if kpiWrite == nil {
+		return nil, errors.New("keypair info for keystore writes was not found in the global keypair store")
+	}
+	defer kpiWrite.Wipe()
+
+	secret := make([]byte, 0, len(kpiRead.CipherSeed)+len(kpiWrite.SigningSeed))
+	secret = append(secret, kpiRead.CipherSeed...)
+	return append(secret, kpiWrite.SigningSeed...), nil
+}
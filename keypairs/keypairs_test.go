@@ -14,8 +14,10 @@
 package keypairs
 
 import (
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/security"
 	"os"
 	"path/filepath"
 	"testing"
@@ -227,3 +229,119 @@ func TestNewKeypairStoreFromFile_RequiresPassword_PassesWithCorrectPassword(t *t
 	_, err = NewKeypairStoreFromFile([]byte("password"), storePath)
 	assert.Nil(t, err)
 }
+
+func TestKeypairStore_RotateKeyPair(t *testing.T) {
+	newKPStore, kpi, err := NewKeypairStoreWithKeypair("test")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	oldKPI, newKPI, err := newKPStore.RotateKeyPair("test", "test-retired")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, kpi.SigningSeed, oldKPI.SigningSeed)
+	assert.NotEqual(t, kpi.SigningSeed, newKPI.SigningSeed)
+	assert.Equal(t, newKPI.SigningSeed, newKPStore.GetKeyPairInfo("test").SigningSeed)
+
+	retiredKPI := newKPStore.GetKeyPairInfo("test-retired")
+	if !assert.NotNil(t, retiredKPI) {
+		return
+	}
+	assert.Equal(t, kpi.SigningSeed, retiredKPI.SigningSeed)
+
+	_, _, err = newKPStore.RotateKeyPair("test", "test-retired")
+	assert.NotNil(t, err)
+
+	_, _, err = newKPStore.RotateKeyPair("missing", "")
+	assert.NotNil(t, err)
+}
+
+func TestKeypairStore_GroupKeyPairs(t *testing.T) {
+	managerStore, _, err := NewKeypairStoreWithKeypair("default")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	groupKPI, err := managerStore.CreateGroupKeyPair("team", []string{"bob"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 1, groupKPI.GroupEpoch)
+
+	_, err = managerStore.CreateGroupKeyPair("default", nil)
+	assert.NotNil(t, err)
+
+	_, _, err = managerStore.RekeyGroup("default", nil)
+	assert.NotNil(t, err)
+
+	// A member stores the epoch 1 keys
+	memberStore, _, err := NewKeypairStoreWithKeypair("default")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	_, managerSigningPubKey, _ := managerStore.GetKeyPairInfo("default").PublicKeys()
+	memberKPI, added, err := memberStore.MergeGroupKey(groupKPI, managerSigningPubKey)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, added)
+	assert.False(t, memberKPI.GroupManaged)
+	assert.Equal(t, managerSigningPubKey, memberKPI.GroupManagerSigningPubKey)
+
+	// Only the managing store can rekey
+	_, _, err = memberStore.RekeyGroup("team", nil)
+	assert.NotNil(t, err)
+
+	oldKPI, newKPI, err := managerStore.RekeyGroup("team", []string{"bob", "carol"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, groupKPI.CipherSeed, oldKPI.CipherSeed)
+	assert.Equal(t, 2, newKPI.GroupEpoch)
+	assert.Equal(t, []string{"bob", "carol"}, managerStore.GetKeyPairInfo("team").GroupMembers)
+
+	// A newer epoch sent by a different contact is refused
+	otherKPI, _ := security.NewKeyPairInfoWithSeeds("other")
+	_, otherSigningPubKey, _ := otherKPI.PublicKeys()
+	_, _, err = memberStore.MergeGroupKey(newKPI, otherSigningPubKey)
+	assert.True(t, errors.Is(err, security.ErrGroupManagerMismatch))
+	assert.Equal(t, 1, memberStore.GetKeyPairInfo("team").GroupEpoch)
+
+	memberKPI, added, err = memberStore.MergeGroupKey(newKPI, managerSigningPubKey)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.False(t, added)
+	assert.Equal(t, 2, memberStore.GetKeyPairInfo("team").GroupEpoch)
+	assert.Equal(t, groupKPI.CipherSeed, memberStore.GetKeyPairInfo("team").GroupEpochKeys[0].CipherSeed)
+
+	// A group key can not replace a keypair that is not a group
+	_, _, err = memberStore.MergeGroupKey(security.NewKeyPairInfoFromSeeds("default", newKPI.CipherSeed, newKPI.SigningSeed), managerSigningPubKey)
+	assert.NotNil(t, err)
+
+	groupDefaultKPI := newKPI.Clone()
+	groupDefaultKPI.Name = "default"
+	_, _, err = memberStore.MergeGroupKey(groupDefaultKPI, managerSigningPubKey)
+	assert.NotNil(t, err)
+}
+
+func TestKeyStoreSeedSecret(t *testing.T) {
+	savedStore := GlobalKeyPairStore
+	defer func() {
+		GlobalKeyPairStore = savedStore
+	}()
+
+	GlobalKeyPairStore = NewKeypairStore()
+	_, err := KeyStoreSeedSecret()
+	assert.NotNil(t, err)
+
+	readKPI, _ := GlobalKeyPairStore.CreateNewKeyPair(helpers.KeyPairNameForKeyStoreReads)
+	writeKPI, _ := GlobalKeyPairStore.CreateNewKeyPair(helpers.KeyPairNameForKeyStoreWrites)
+	secret, err := KeyStoreSeedSecret()
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, append(append([]byte{}, readKPI.CipherSeed...), writeKPI.SigningSeed...), secret)
+}
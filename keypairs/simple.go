@@ -27,6 +27,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 // SimpleKeyPairStore will be the storage file for the local account key pairs created in this profile
@@ -255,6 +256,154 @@ func (kps *SimpleKeyPairStore) CreateNewKeyPair(name string) (*security.KeyPairI
 	return kpi, nil
 }
 
+// ImportKeyPair adds a keypair whose keys were created elsewhere, such as from an SSH private key.
+// The store is not saved, so callers must save it afterwards.
+func (kps *SimpleKeyPairStore) ImportKeyPair(kpi *security.KeyPairInfo) error {
+	kps.syncKeyPairs.Lock()
+	defer kps.syncKeyPairs.Unlock()
+
+	if kps.getKeyPairInfo(kpi.Name) != nil {
+		return fmt.Errorf("a keypair already exists by the name \"%s\"", kpi.Name)
+	}
+
+	kps.addKeyPairInfo(kpi)
+	return nil
+}
+
+// RotateKeyPair replaces the keys for the named keypair with new keys.  If retiredName is provided,
+// the old keys are kept under that name, so that older bundles can still be opened.
+func (kps *SimpleKeyPairStore) RotateKeyPair(name, retiredName string) (oldKPI, newKPI *security.KeyPairInfo, err error) {
+	kps.syncKeyPairs.Lock()
+	defer kps.syncKeyPairs.Unlock()
+
+	oldKPI = kps.getKeyPairInfo(name)
+	if oldKPI == nil {
+		return nil, nil, fmt.Errorf("no keypair exists by the name \"%s\"", name)
+	}
+
+	if retiredName != "" && kps.getKeyPairInfo(retiredName) != nil {
+		return nil, nil, fmt.Errorf("a keypair already exists by the name \"%s\"", retiredName)
+	}
+
+	newKPI, err = security.NewKeyPairInfoWithSeeds(oldKPI.Name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable create keypair with new seeds: %w", err)
+	}
+
+	if retiredName != "" {
+		retiredKPI := oldKPI.Clone()
+		retiredKPI.Name = retiredName
+		kps.addKeyPairInfo(retiredKPI)
+	}
+
+	kps.addKeyPairInfo(newKPI)
+	return oldKPI, newKPI, nil
+}
+
+// CreateGroupKeyPair creates a shared group identity at epoch 1, managed by this profile.
+// The store is not saved, so callers must save it afterwards.
+func (kps *SimpleKeyPairStore) CreateGroupKeyPair(name string, members []string) (*security.KeyPairInfo, error) {
+	kps.syncKeyPairs.Lock()
+	defer kps.syncKeyPairs.Unlock()
+
+	if kps.getKeyPairInfo(name) != nil {
+		return nil, fmt.Errorf("a keypair already exists by the name \"%s\"", name)
+	}
+
+	kpi, err := security.NewGroupKeyPairInfo(name, members)
+	if err != nil {
+		return nil, fmt.Errorf("unable create group keypair with new seeds: %w", err)
+	}
+
+	kps.addKeyPairInfo(kpi)
+	return kpi, nil
+}
+
+// RekeyGroup sets the members of a managed group identity and moves it to a new epoch with new keys.  The
+// prior keys are kept as epoch keys, so older bundles sent to the group can still be opened.
+// The store is not saved, so callers must save it afterwards.
+func (kps *SimpleKeyPairStore) RekeyGroup(name string, members []string) (oldKPI, newKPI *security.KeyPairInfo, err error) {
+	kps.syncKeyPairs.Lock()
+	defer kps.syncKeyPairs.Unlock()
+
+	oldKPI = kps.getKeyPairInfo(name)
+	if oldKPI == nil {
+		return nil, nil, fmt.Errorf("no keypair exists by the name \"%s\"", name)
+	}
+
+	if !oldKPI.IsGroup() {
+		return nil, nil, fmt.Errorf("keypair \"%s\" is not a group identity", name)
+	}
+
+	if !oldKPI.GroupManaged {
+		return nil, nil, fmt.Errorf("group \"%s\" is not managed by this profile", name)
+	}
+
+	newKPI = oldKPI.Clone()
+	err = newKPI.Rekey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	newKPI.SetGroupMembers(members)
+	kps.addKeyPairInfo(newKPI)
+	return oldKPI, newKPI, nil
+}
+
+// MergeGroupKey stores group keys received from the group's manager.  If the group is not known yet, it is
+// added, and senderSigningPubKey is recorded as the group's manager.  Otherwise, the keys must be for a newer
+// epoch and sent by the recorded manager, and the current keys are kept as epoch keys.
+// The store is not saved, so callers must save it afterwards.
+func (kps *SimpleKeyPairStore) MergeGroupKey(groupKPI *security.KeyPairInfo, senderSigningPubKey string) (kpi *security.KeyPairInfo, added bool, err error) {
+	kps.syncKeyPairs.Lock()
+	defer kps.syncKeyPairs.Unlock()
+
+	if groupKPI == nil || !groupKPI.IsGroup() {
+		return nil, false, errors.New("keys provided are not a group identity")
+	}
+
+	kpi = kps.getKeyPairInfo(groupKPI.Name)
+	if kpi == nil {
+		kpi = groupKPI.Clone()
+		kpi.GroupManaged = false
+		kpi.GroupMembers = nil
+		kpi.GroupManagerSigningPubKey = senderSigningPubKey
+		kps.addKeyPairInfo(kpi)
+		return kpi, true, nil
+	}
+
+	if !kpi.IsGroup() {
+		return nil, false, fmt.Errorf("a keypair that is not a group identity already exists by the name \"%s\"", groupKPI.Name)
+	}
+
+	err = kpi.MergeGroupEpoch(groupKPI, senderSigningPubKey)
+	if err != nil {
+		return nil, false, err
+	}
+
+	kps.addKeyPairInfo(kpi)
+	return kpi, false, nil
+}
+
+// AddSubkey creates a new encryption subkey for the named keypair, certified by its signing key.
+// The store is not saved, so callers must save it afterwards.
+func (kps *SimpleKeyPairStore) AddSubkey(name string, validFor time.Duration) (*security.Subkey, error) {
+	kps.syncKeyPairs.Lock()
+	defer kps.syncKeyPairs.Unlock()
+
+	kpi, found := kps.KeyPairs[strings.ToUpper(name)]
+	if !found {
+		return nil, fmt.Errorf("no keypair exists by the name \"%s\"", name)
+	}
+
+	subkey, err := kpi.AddSubkey(validFor)
+	if err != nil {
+		return nil, fmt.Errorf("unable to add subkey: %w", err)
+	}
+
+	return subkey, nil
+}
+
 func (kps *SimpleKeyPairStore) ListKeyPairs() []*security.KeyPairInfo {
 	kps.syncKeyPairs.Lock()
 	defer kps.syncKeyPairs.Unlock()
@@ -0,0 +1,91 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystore
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/thoughtrealm/bumblebee/security"
+	"strings"
+)
+
+// decodeUserRecordsAuthorizedKeys reads one record for each key in an authorized_keys file.  Blank lines and
+// comments are ignored.  Keys that are not ssh-ed25519 keys are returned as invalid records, so they are reported.
+func decodeUserRecordsAuthorizedKeys(data []byte) []*UserRecord {
+	var records []*UserRecord
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		records = append(records, NewUserRecordFromSSHLine(text, line))
+	}
+
+	return records
+}
+
+// NewUserRecordFromSSHLine returns a record for an authorized_keys line.  The name is taken from the key comment.
+// If the line can not be converted, the record is returned with the error, which Validate reports.
+func NewUserRecordFromSSHLine(text string, line int) *UserRecord {
+	publicKey, err := security.ParseSSHPublicKey(text)
+	if err != nil {
+		return &UserRecord{Line: line, parseErr: err}
+	}
+
+	record := &UserRecord{
+		Name:             SSHCommentToUserName(publicKey.Comment, line),
+		KeySource:        security.KeySourceSSH,
+		KeySourceDetails: publicKey.SourceDetails(),
+		Line:             line,
+	}
+
+	record.CipherPubKey, record.SigningPubKey, err = publicKey.PublicKeys()
+	if err != nil {
+		record.parseErr = err
+	}
+
+	return record
+}
+
+// SSHCommentToUserName returns a user name from an SSH key comment, which is usually user@host.  The part of the
+// first word before the @ is used, limited to letters, digits, periods, underscores and dashes.  If nothing is left, a name is
+// built from the line number.
+func SSHCommentToUserName(comment string, line int) string {
+	name := ""
+	if fields := strings.Fields(comment); len(fields) > 0 {
+		name, _, _ = strings.Cut(fields[0], "@")
+	}
+
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		default:
+			return -1
+		}
+	}, name)
+
+	if name == "" {
+		return fmt.Sprintf("ssh-user-%d", line)
+	}
+
+	return name
+}
@@ -0,0 +1,766 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystore
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	beecipher "github.com/thoughtrealm/bumblebee/cipher"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/security"
+	"github.com/vmihailenco/msgpack/v5"
+	"golang.org/x/crypto/chacha20poly1305"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	btreeRecordKindEntity     byte = 'e'
+	btreeRecordKindGroup      byte = 'g'
+	btreeRecordKindMeta       byte = 'm'
+	btreeRecordKindRevocation byte = 'r'
+	btreeRecordKindSigningKey byte = 's'
+)
+
+// btreeStoreBatchSize is the number of records written per commit when a B-tree file is created
+const btreeStoreBatchSize = 5000
+
+// btreeStoreKeyLen is the size of the record encryption key and the record key HMAC key
+const btreeStoreKeyLen = 32
+
+// BTreeKeyStore is a keystore in a B-tree file.  Each record is encrypted on its own, so lookups only read
+// the records they need, and changes only write the records they affect.
+//
+// The record logic is shared with SimpleKeyStore.  A change loads the records it reads into a staged
+// SimpleKeyStore and applies the SimpleKeyStore method, then writes only the records that are different.
+type BTreeKeyStore struct {
+	Details        *StoreDetails
+	Server         *ServerInfo
+	SyncStore      sync.RWMutex
+	SourceFilePath string
+
+	entityCount int
+	kvFile      *kvFile
+	aead        cipher.AEAD
+
+	// indexKey is the HMAC key for record keys, so entity names and public keys are not visible in the file
+	indexKey []byte
+}
+
+// btreeStoreMeta is the store record, which has the values that are not kept in other records
+type btreeStoreMeta struct {
+	Details     *StoreDetails
+	Server      *ServerInfo
+	EntityCount int
+}
+
+// newBTreeKeyStoreFromFile opens a B-tree keystore file.  The records are decrypted with keys derived from the
+// keystore_read and keystore_write keypairs in the global keypair store.
+func newBTreeKeyStoreFromFile(filePath string) (*BTreeKeyStore, error) {
+	kf, err := openKVFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open keystore file: %w", err)
+	}
+
+	bks, err := newBTreeKeyStoreForFile(kf, filePath)
+	if err != nil {
+		_ = kf.close()
+		return nil, err
+	}
+
+	meta := &btreeStoreMeta{}
+	found, err := bks.getRecord(kf.view(), btreeRecordKindMeta, "", meta)
+	if err == nil && !found {
+		err = errors.New("the keystore file has no store record")
+	}
+
+	if err != nil {
+		_ = bks.Close()
+		return nil, err
+	}
+
+	bks.Details = meta.Details
+	bks.Server = meta.Server
+	bks.entityCount = meta.EntityCount
+	return bks, nil
+}
+
+func newBTreeKeyStoreForFile(kf *kvFile, filePath string) (*BTreeKeyStore, error) {
+	recordKey, indexKey, err := deriveBTreeStoreKeys(kf.salt())
+	if err != nil {
+		return nil, err
+	}
+	defer security.Wipe(recordKey)
+
+	aead, err := chacha20poly1305.NewX(recordKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize the record cipher: %w", err)
+	}
+
+	return &BTreeKeyStore{
+		SourceFilePath: filePath,
+		kvFile:         kf,
+		aead:           aead,
+		indexKey:       indexKey,
+	}, nil
+}
+
+// deriveBTreeStoreKeys derives the record encryption key and the record key HMAC key from the keystore
+// keypair seeds and the file's salt
+func deriveBTreeStoreKeys(salt []byte) (recordKey, indexKey []byte, err error) {
+	secret, err := keypairs.KeyStoreSeedSecret()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer security.Wipe(secret)
+
+	recordKey, err = beecipher.HKDFSHA256(secret, salt, []byte("bumblebee keystore record key"), btreeStoreKeyLen)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	indexKey, err = beecipher.HKDFSHA256(secret, salt, []byte("bumblebee keystore record index key"), btreeStoreKeyLen)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return recordKey, indexKey, nil
+}
+
+// createBTreeKeyStoreFile writes the records of the source store to a new B-tree keystore file
+func createBTreeKeyStoreFile(source *SimpleKeyStore, filePath string) error {
+	salt, err := helpers.GetRandomBytes(kvSaltLen)
+	if err != nil {
+		return fmt.Errorf("unable to generate keystore salt: %w", err)
+	}
+
+	kf, err := createKVFile(filePath, salt)
+	if err != nil {
+		return fmt.Errorf("unable to create keystore file: %w", err)
+	}
+
+	bks, err := newBTreeKeyStoreForFile(kf, filePath)
+	if err != nil {
+		_ = kf.close()
+		return err
+	}
+	defer func() {
+		_ = bks.Close()
+	}()
+
+	tx := kf.begin()
+	recordCount := 0
+	putRecord := func(kind byte, name string, record interface{}) error {
+		err := bks.putRecord(tx, kind, name, record)
+		if err != nil {
+			return err
+		}
+
+		recordCount++
+		if recordCount%btreeStoreBatchSize != 0 {
+			return nil
+		}
+
+		err = tx.commit()
+		tx = kf.begin()
+		return err
+	}
+
+	signingKeyIndex := map[string][]string{}
+	for entityKey, entity := range source.Entities {
+		err = putRecord(btreeRecordKindEntity, entityKey, entity)
+		if err != nil {
+			return fmt.Errorf("unable to write entity \"%s\": %w", entity.Name, err)
+		}
+
+		if entity.PublicKeys != nil && entity.PublicKeys.SigningPubKey != "" {
+			signingKeyIndex[entity.PublicKeys.SigningPubKey] = append(signingKeyIndex[entity.PublicKeys.SigningPubKey], entityKey)
+		}
+	}
+
+	for signingPubKey, entityKeys := range signingKeyIndex {
+		sort.Strings(entityKeys)
+		err = putRecord(btreeRecordKindSigningKey, signingPubKey, entityKeys)
+		if err != nil {
+			return fmt.Errorf("unable to write signing key index: %w", err)
+		}
+	}
+
+	for groupKey, group := range source.Groups {
+		err = putRecord(btreeRecordKindGroup, groupKey, group)
+		if err != nil {
+			return fmt.Errorf("unable to write group \"%s\": %w", group.Name, err)
+		}
+	}
+
+	for signingPubKey, entry := range source.Revocations {
+		err = putRecord(btreeRecordKindRevocation, signingPubKey, entry)
+		if err != nil {
+			return fmt.Errorf("unable to write revocation: %w", err)
+		}
+	}
+
+	// The store record is written last, so a file without it was not completely written
+	err = bks.putRecord(tx, btreeRecordKindMeta, "", &btreeStoreMeta{
+		Details:     source.Details,
+		Server:      source.Server,
+		EntityCount: len(source.Entities),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to write store record: %w", err)
+	}
+
+	err = tx.commit()
+	if err != nil {
+		return fmt.Errorf("unable to write keystore file: %w", err)
+	}
+
+	return nil
+}
+
+// recordID returns the B-tree key for the record.  The first byte is the record kind, followed by
+// an HMAC of the name.
+func (bks *BTreeKeyStore) recordID(kind byte, name string) kvKey {
+	mac := hmac.New(sha256.New, bks.indexKey)
+	mac.Write([]byte{kind})
+	mac.Write([]byte(name))
+
+	id := kvKey{kind}
+	copy(id[1:], mac.Sum(nil))
+	return id
+}
+
+// sealRecord encrypts the record.  The record ID is the associated data, so a record cannot be moved to another key.
+func (bks *BTreeKeyStore) sealRecord(id kvKey, plainRecord []byte) ([]byte, error) {
+	nonce, err := helpers.GetRandomBytes(bks.aead.NonceSize())
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate record nonce: %w", err)
+	}
+
+	return bks.aead.Seal(nonce, nonce, plainRecord, id[:]), nil
+}
+
+func (bks *BTreeKeyStore) openRecord(id kvKey, sealedRecord []byte) ([]byte, error) {
+	nonceSize := bks.aead.NonceSize()
+	if len(sealedRecord) < nonceSize {
+		return nil, errors.New("keystore record is truncated")
+	}
+
+	plainRecord, err := bks.aead.Open(nil, sealedRecord[:nonceSize], sealedRecord[nonceSize:], id[:])
+	if err != nil {
+		return nil, errors.New("unable to decrypt keystore record.  The keystore keypairs may not be the ones for this keystore")
+	}
+
+	return plainRecord, nil
+}
+
+func (bks *BTreeKeyStore) putRecord(tx *kvTx, kind byte, name string, record interface{}) error {
+	plainRecord, err := msgpack.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("unable to serialize keystore record: %w", err)
+	}
+	defer security.Wipe(plainRecord)
+
+	return bks.putRecordBytes(tx, kind, name, plainRecord)
+}
+
+func (bks *BTreeKeyStore) putRecordBytes(tx *kvTx, kind byte, name string, plainRecord []byte) error {
+	id := bks.recordID(kind, name)
+	sealedRecord, err := bks.sealRecord(id, plainRecord)
+	if err != nil {
+		return err
+	}
+
+	return tx.put(id, sealedRecord)
+}
+
+// getRecordBytes returns the decrypted record, or false if it is not found
+func (bks *BTreeKeyStore) getRecordBytes(tx *kvTx, kind byte, name string) ([]byte, bool, error) {
+	id := bks.recordID(kind, name)
+	sealedRecord, found, err := tx.get(id)
+	if err != nil || !found {
+		return nil, false, err
+	}
+
+	plainRecord, err := bks.openRecord(id, sealedRecord)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return plainRecord, true, nil
+}
+
+func (bks *BTreeKeyStore) getRecord(tx *kvTx, kind byte, name string, record interface{}) (bool, error) {
+	plainRecord, found, err := bks.getRecordBytes(tx, kind, name)
+	if err != nil || !found {
+		return false, err
+	}
+	defer security.Wipe(plainRecord)
+
+	err = msgpack.Unmarshal(plainRecord, record)
+	if err != nil {
+		return false, fmt.Errorf("failed interpreting keystore record: %w", err)
+	}
+
+	return true, nil
+}
+
+func (bks *BTreeKeyStore) deleteRecord(tx *kvTx, kind byte, name string) error {
+	_, err := tx.delete(bks.recordID(kind, name))
+	return err
+}
+
+// scanRecords calls scanFunc with each decrypted record of the kind, until scanFunc returns false
+func (bks *BTreeKeyStore) scanRecords(tx *kvTx, kind byte, scanFunc func(plainRecord []byte) (bool, error)) error {
+	return tx.scan(kvKey{kind}, func(id kvKey, sealedRecord []byte) (bool, error) {
+		if id[0] != kind {
+			return false, nil
+		}
+
+		plainRecord, err := bks.openRecord(id, sealedRecord)
+		if err != nil {
+			return false, err
+		}
+
+		return scanFunc(plainRecord)
+	})
+}
+
+func (bks *BTreeKeyStore) getEntity(tx *kvTx, name string) (*security.Entity, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	entity := &security.Entity{}
+	found, err := bks.getRecord(tx, btreeRecordKindEntity, strings.ToUpper(name), entity)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return entity, nil
+}
+
+// getSigningKeyIndex returns the entity keys of the entities with the signing public key
+func (bks *BTreeKeyStore) getSigningKeyIndex(tx *kvTx, signingPubKey string) ([]string, error) {
+	if signingPubKey == "" {
+		return nil, nil
+	}
+
+	var entityKeys []string
+	_, err := bks.getRecord(tx, btreeRecordKindSigningKey, signingPubKey, &entityKeys)
+	return entityKeys, err
+}
+
+// change applies changeFunc to a staged copy of the records it loads, and commits the records that it changes.
+// Nothing is written if changeFunc returns an error.
+func (bks *BTreeKeyStore) change(changeFunc func(bc *btreeChange) error) error {
+	bks.SyncStore.Lock()
+	defer bks.SyncStore.Unlock()
+
+	if bks.kvFile == nil {
+		return errors.New("the keystore is closed")
+	}
+
+	bc := &btreeChange{
+		bks: bks,
+		tx:  bks.kvFile.begin(),
+		staged: &SimpleKeyStore{
+			Details:     bks.Details.Clone(),
+			Server:      bks.Server,
+			Entities:    NewEntities(),
+			Revocations: RevocationCollection{},
+			Groups:      GroupCollection{},
+			staged:      true,
+		},
+		entities:    map[string][]byte{},
+		revocations: map[string][]byte{},
+		groups:      map[string][]byte{},
+	}
+
+	err := changeFunc(bc)
+	if err != nil {
+		return err
+	}
+
+	return bc.commit()
+}
+
+// btreeChange has a staged SimpleKeyStore with the records that a change reads.  The loaded record bytes are kept,
+// so the records that the change updates can be found when it is committed.  A nil value in the loaded
+// records means the record did not exist.
+type btreeChange struct {
+	bks         *BTreeKeyStore
+	tx          *kvTx
+	staged      *SimpleKeyStore
+	entities    map[string][]byte
+	revocations map[string][]byte
+	groups      map[string][]byte
+}
+
+// loadEntity stages the entity and returns the staged copy, or nil if it does not exist
+func (bc *btreeChange) loadEntity(name string) (*security.Entity, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	entityKey := strings.ToUpper(name)
+	if _, loaded := bc.entities[entityKey]; loaded {
+		return bc.staged.Entities[entityKey], nil
+	}
+
+	plainRecord, found, err := bc.bks.getRecordBytes(bc.tx, btreeRecordKindEntity, entityKey)
+	if err != nil {
+		return nil, err
+	}
+
+	bc.entities[entityKey] = plainRecord
+	if !found {
+		return nil, nil
+	}
+
+	entity := &security.Entity{}
+	err = msgpack.Unmarshal(plainRecord, entity)
+	if err != nil {
+		return nil, fmt.Errorf("failed interpreting keystore record: %w", err)
+	}
+
+	bc.staged.Entities[entityKey] = entity
+	return entity, nil
+}
+
+func (bc *btreeChange) loadEntities(names []string) error {
+	for _, name := range names {
+		_, err := bc.loadEntity(name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadEntitiesWithSigningKey stages the entities that have the signing public key
+func (bc *btreeChange) loadEntitiesWithSigningKey(signingPubKey string) error {
+	entityKeys, err := bc.bks.getSigningKeyIndex(bc.tx, signingPubKey)
+	if err != nil {
+		return err
+	}
+
+	return bc.loadEntities(entityKeys)
+}
+
+func (bc *btreeChange) loadRevocation(signingPubKey string) error {
+	if signingPubKey == "" {
+		return nil
+	}
+
+	if _, loaded := bc.revocations[signingPubKey]; loaded {
+		return nil
+	}
+
+	plainRecord, found, err := bc.bks.getRecordBytes(bc.tx, btreeRecordKindRevocation, signingPubKey)
+	if err != nil {
+		return err
+	}
+
+	bc.revocations[signingPubKey] = plainRecord
+	if !found {
+		return nil
+	}
+
+	entry := &RevocationEntry{}
+	err = msgpack.Unmarshal(plainRecord, entry)
+	if err != nil {
+		return fmt.Errorf("failed interpreting keystore record: %w", err)
+	}
+
+	bc.staged.Revocations[signingPubKey] = entry
+	return nil
+}
+
+func (bc *btreeChange) loadGroup(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	groupKey := strings.ToUpper(name)
+	if _, loaded := bc.groups[groupKey]; loaded {
+		return nil
+	}
+
+	plainRecord, found, err := bc.bks.getRecordBytes(bc.tx, btreeRecordKindGroup, groupKey)
+	if err != nil {
+		return err
+	}
+
+	bc.groups[groupKey] = plainRecord
+	if !found {
+		return nil
+	}
+
+	group := &Group{}
+	err = msgpack.Unmarshal(plainRecord, group)
+	if err != nil {
+		return fmt.Errorf("failed interpreting keystore record: %w", err)
+	}
+
+	bc.staged.Groups[groupKey] = group
+	return nil
+}
+
+// loadGroups stages every group, for changes that update group members
+func (bc *btreeChange) loadGroups() error {
+	return bc.bks.scanRecords(bc.tx, btreeRecordKindGroup, func(plainRecord []byte) (bool, error) {
+		group := &Group{}
+		err := msgpack.Unmarshal(plainRecord, group)
+		if err != nil {
+			return false, fmt.Errorf("failed interpreting keystore record: %w", err)
+		}
+
+		groupKey := strings.ToUpper(group.Name)
+		if _, loaded := bc.groups[groupKey]; !loaded {
+			bc.groups[groupKey] = plainRecord
+			bc.staged.Groups[groupKey] = group
+		}
+
+		return true, nil
+	})
+}
+
+// commit writes the staged records that are different from the loaded records, and updates the
+// signing key index and entity count to match
+func (bc *btreeChange) commit() error {
+	entityCount := bc.bks.entityCount
+	removedSigningKeys := map[string][]string{}
+	addedSigningKeys := map[string][]string{}
+
+	entityKeys := map[string]bool{}
+	for entityKey := range bc.entities {
+		entityKeys[entityKey] = true
+	}
+
+	for entityKey := range bc.staged.Entities {
+		entityKeys[entityKey] = true
+	}
+
+	for entityKey := range entityKeys {
+		loadedRecord := bc.entities[entityKey]
+		entity := bc.staged.Entities[entityKey]
+
+		var stagedRecord []byte
+		if entity != nil {
+			var err error
+			stagedRecord, err = msgpack.Marshal(entity)
+			if err != nil {
+				return fmt.Errorf("unable to serialize keystore record: %w", err)
+			}
+		}
+
+		if bytes.Equal(loadedRecord, stagedRecord) {
+			continue
+		}
+
+		loadedSigningPubKey := ""
+		if loadedRecord != nil {
+			loadedEntity := &security.Entity{}
+			err := msgpack.Unmarshal(loadedRecord, loadedEntity)
+			if err != nil {
+				return fmt.Errorf("failed interpreting keystore record: %w", err)
+			}
+
+			loadedSigningPubKey = entitySigningPubKey(loadedEntity)
+		}
+
+		var err error
+		if entity == nil {
+			err = bc.bks.deleteRecord(bc.tx, btreeRecordKindEntity, entityKey)
+			entityCount--
+		} else {
+			err = bc.bks.putRecordBytes(bc.tx, btreeRecordKindEntity, entityKey, stagedRecord)
+			if loadedRecord == nil {
+				entityCount++
+			}
+		}
+
+		if err != nil {
+			return fmt.Errorf("unable to write keystore record: %w", err)
+		}
+
+		stagedSigningPubKey := entitySigningPubKey(entity)
+		if loadedSigningPubKey != stagedSigningPubKey {
+			if loadedSigningPubKey != "" {
+				removedSigningKeys[loadedSigningPubKey] = append(removedSigningKeys[loadedSigningPubKey], entityKey)
+			}
+
+			if stagedSigningPubKey != "" {
+				addedSigningKeys[stagedSigningPubKey] = append(addedSigningKeys[stagedSigningPubKey], entityKey)
+			}
+		}
+	}
+
+	err := bc.updateSigningKeyIndex(removedSigningKeys, addedSigningKeys)
+	if err != nil {
+		return err
+	}
+
+	stagedGroups := map[string]interface{}{}
+	for groupKey, group := range bc.staged.Groups {
+		stagedGroups[groupKey] = group
+	}
+
+	err = bc.commitRecords(btreeRecordKindGroup, bc.groups, stagedGroups)
+	if err != nil {
+		return err
+	}
+
+	stagedRevocations := map[string]interface{}{}
+	for signingPubKey, entry := range bc.staged.Revocations {
+		stagedRevocations[signingPubKey] = entry
+	}
+
+	err = bc.commitRecords(btreeRecordKindRevocation, bc.revocations, stagedRevocations)
+	if err != nil {
+		return err
+	}
+
+	if entityCount != bc.bks.entityCount {
+		err = bc.bks.putRecord(bc.tx, btreeRecordKindMeta, "", &btreeStoreMeta{
+			Details:     bc.bks.Details,
+			Server:      bc.bks.Server,
+			EntityCount: entityCount,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to write store record: %w", err)
+		}
+	}
+
+	err = bc.tx.commit()
+	if err != nil {
+		return fmt.Errorf("unable to save store data: %w", err)
+	}
+
+	bc.bks.entityCount = entityCount
+	return nil
+}
+
+// commitRecords writes the staged records of the kind that are different from the loaded records
+func (bc *btreeChange) commitRecords(kind byte, loadedRecords map[string][]byte, stagedRecords map[string]interface{}) error {
+	names := map[string]bool{}
+	for name := range loadedRecords {
+		names[name] = true
+	}
+
+	for name := range stagedRecords {
+		names[name] = true
+	}
+
+	for name := range names {
+		loadedRecord := loadedRecords[name]
+		stagedRecord, isStaged := stagedRecords[name]
+
+		var stagedRecordBytes []byte
+		if isStaged {
+			var err error
+			stagedRecordBytes, err = msgpack.Marshal(stagedRecord)
+			if err != nil {
+				return fmt.Errorf("unable to serialize keystore record: %w", err)
+			}
+		}
+
+		if bytes.Equal(loadedRecord, stagedRecordBytes) {
+			continue
+		}
+
+		var err error
+		if !isStaged {
+			err = bc.bks.deleteRecord(bc.tx, kind, name)
+		} else {
+			err = bc.bks.putRecordBytes(bc.tx, kind, name, stagedRecordBytes)
+		}
+
+		if err != nil {
+			return fmt.Errorf("unable to write keystore record: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (bc *btreeChange) updateSigningKeyIndex(removedSigningKeys, addedSigningKeys map[string][]string) error {
+	signingPubKeys := map[string]bool{}
+	for signingPubKey := range removedSigningKeys {
+		signingPubKeys[signingPubKey] = true
+	}
+
+	for signingPubKey := range addedSigningKeys {
+		signingPubKeys[signingPubKey] = true
+	}
+
+	for signingPubKey := range signingPubKeys {
+		entityKeys, err := bc.bks.getSigningKeyIndex(bc.tx, signingPubKey)
+		if err != nil {
+			return err
+		}
+
+		updatedKeys := []string{}
+		for _, entityKey := range entityKeys {
+			if !containsString(entityKey, removedSigningKeys[signingPubKey]) {
+				updatedKeys = append(updatedKeys, entityKey)
+			}
+		}
+
+		for _, entityKey := range addedSigningKeys[signingPubKey] {
+			if !containsString(entityKey, updatedKeys) {
+				updatedKeys = append(updatedKeys, entityKey)
+			}
+		}
+
+		if len(updatedKeys) == 0 {
+			err = bc.bks.deleteRecord(bc.tx, btreeRecordKindSigningKey, signingPubKey)
+		} else {
+			sort.Strings(updatedKeys)
+			err = bc.bks.putRecord(bc.tx, btreeRecordKindSigningKey, signingPubKey, updatedKeys)
+		}
+
+		if err != nil {
+			return fmt.Errorf("unable to write signing key index: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func entitySigningPubKey(entity *security.Entity) string {
+	if entity == nil || entity.PublicKeys == nil {
+		return ""
+	}
+
+	return entity.PublicKeys.SigningPubKey
+}
+
+func containsString(value string, values []string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystore
+
+import (
+	"fmt"
+	"github.com/thoughtrealm/bumblebee/security"
+	"log"
+	"path/filepath"
+	"testing"
+)
+
+// These compare the simple and B-tree formats with the keystore in a file, as it is used by the bee app.
+// The simple benchmarks in simple_benchmarks_test.go are for the in-memory operations of the simple format.
+
+var fileStoreEntityCounts = []int{100, 1000, 10000}
+
+type benchmarkStoreFile struct {
+	formatName string
+	filePath   string
+}
+
+// buildBenchmarkStoreFiles writes a store with the entities to a file in each format
+func buildBenchmarkStoreFiles(b *testing.B, countOfEntities int) []benchmarkStoreFile {
+	log.Printf("Building store files with %d entities", countOfEntities)
+	useTestKeyStoreKeyPairs()
+	testStore := buildTestStoreMultiEntity(countOfEntities)
+
+	storeFiles := []benchmarkStoreFile{
+		{formatName: "Simple", filePath: filepath.Join(b.TempDir(), "simple.keystore")},
+		{formatName: "BTree", filePath: filepath.Join(b.TempDir(), "btree.keystore")},
+	}
+
+	err := testStore.WriteToFile(storeFiles[0].filePath)
+	if err != nil {
+		b.Fatalf("failed writing simple store file: %s", err)
+	}
+
+	err = createBTreeKeyStoreFile(testStore, storeFiles[1].filePath)
+	if err != nil {
+		b.Fatalf("failed writing btree store file: %s", err)
+	}
+
+	return storeFiles
+}
+
+func openBenchmarkStoreFile(b *testing.B, storeFile benchmarkStoreFile) KeyStore {
+	store, err := NewFromFile(nil, storeFile.filePath)
+	if err != nil {
+		b.Fatalf("failed opening %s store file: %s", storeFile.formatName, err)
+	}
+
+	return store
+}
+
+func BenchmarkOpenFromFile(b *testing.B) {
+	for _, entityCount := range fileStoreEntityCounts {
+		for _, storeFile := range buildBenchmarkStoreFiles(b, entityCount) {
+			b.Run(fmt.Sprintf("%s_OpenFromFile_StoreOf_%d_Entities", storeFile.formatName, entityCount), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					store := openBenchmarkStoreFile(b, storeFile)
+					_ = store.Close()
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkGetKeyFromFile(b *testing.B) {
+	for _, entityCount := range fileStoreEntityCounts {
+		for _, storeFile := range buildBenchmarkStoreFiles(b, entityCount) {
+			store := openBenchmarkStoreFile(b, storeFile)
+
+			b.Run(fmt.Sprintf("%s_GetKey_StoreOf_%d_Entities", storeFile.formatName, entityCount), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					entity := store.GetKey(fmt.Sprintf("BOB-%d", i%entityCount))
+					if entity == nil {
+						b.Fatalf("No entity found")
+					}
+				}
+			})
+
+			_ = store.Close()
+		}
+	}
+}
+
+func BenchmarkUpdateKeyInFile(b *testing.B) {
+	for _, entityCount := range fileStoreEntityCounts {
+		for _, storeFile := range buildBenchmarkStoreFiles(b, entityCount) {
+			store := openBenchmarkStoreFile(b, storeFile)
+
+			b.Run(fmt.Sprintf("%s_SetEntityTrust_StoreOf_%d_Entities", storeFile.formatName, entityCount), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					// Alternate the trust level, so every call changes the entity
+					trust := security.TrustLevelVerified
+					if (i/entityCount)%2 == 1 {
+						trust = security.TrustLevelTOFU
+					}
+
+					_, err := store.SetEntityTrust(fmt.Sprintf("bob-%d", i%entityCount), trust, "")
+					if err != nil {
+						b.Fatalf("failed updating entity: %s", err)
+					}
+				}
+			})
+
+			_ = store.Close()
+		}
+	}
+}
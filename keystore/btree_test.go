@@ -0,0 +1,296 @@
+package keystore
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/suite"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/security"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type BTreeKeyStoreTestSuite struct {
+	suite.Suite
+	priorKeyPairStore keypairs.KeyPairStore
+	tempPath          string
+}
+
+func TestBTreeKeyStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(BTreeKeyStoreTestSuite))
+}
+
+func (s *BTreeKeyStoreTestSuite) SetupTest() {
+	s.priorKeyPairStore = keypairs.GlobalKeyPairStore
+	useTestKeyStoreKeyPairs()
+	s.tempPath = s.T().TempDir()
+}
+
+func (s *BTreeKeyStoreTestSuite) TearDownTest() {
+	keypairs.GlobalKeyPairStore = s.priorKeyPairStore
+}
+
+// useTestKeyStoreKeyPairs sets the global keypair store to a new store with the keystore keypairs
+func useTestKeyStoreKeyPairs() {
+	kps := keypairs.NewKeypairStore()
+	_, _ = kps.CreateNewKeyPair(helpers.KeyPairNameForKeyStoreReads)
+	_, _ = kps.CreateNewKeyPair(helpers.KeyPairNameForKeyStoreWrites)
+	keypairs.GlobalKeyPairStore = kps
+}
+
+// writeTestStoreFiles writes the store to a file in each format
+func (s *BTreeKeyStoreTestSuite) writeTestStoreFiles(store *SimpleKeyStore) (simplePath, btreePath string) {
+	simplePath = filepath.Join(s.tempPath, "simple.keystore")
+	btreePath = filepath.Join(s.tempPath, "btree.keystore")
+
+	s.Require().Nil(store.WriteToFile(simplePath))
+	s.Require().Nil(store.WriteToFile(btreePath))
+
+	count, err := ConvertStoreFile(btreePath, StoreFormatBTree)
+	s.Require().Nil(err)
+	s.Require().Equal(len(store.Entities), count)
+	return simplePath, btreePath
+}
+
+func (s *BTreeKeyStoreTestSuite) openStore(filePath string) KeyStore {
+	store, err := NewFromFile(nil, filePath)
+	s.Require().Nil(err)
+	return store
+}
+
+// assertSameStores compares every record of the stores
+func (s *BTreeKeyStoreTestSuite) assertSameStores(expected, actual KeyStore) {
+	s.Assert().Equal(expected.Count(), actual.Count())
+
+	entityNames := func(store KeyStore) []string {
+		var names []string
+		_ = store.Walk(NewWalkInfo("", true, nil, func(entity *security.Entity) {
+			names = append(names, entity.Name)
+		}))
+		return names
+	}
+
+	names := entityNames(expected)
+	s.Assert().Equal(names, entityNames(actual))
+	for _, name := range names {
+		expectedEntity := expected.GetKey(name)
+		actualEntity := actual.GetKey(name)
+		if !s.Assert().NotNil(actualEntity, name) {
+			continue
+		}
+
+		s.Assert().Equal(expectedEntity.Trust, actualEntity.Trust, name)
+		s.Assert().Equal(expectedEntity.RevokedDate, actualEntity.RevokedDate, name)
+		s.Assert().True(expectedEntity.PublicKeys.IsSameData(actualEntity.PublicKeys), name)
+		s.Assert().Equal(len(expectedEntity.KeyHistory), len(actualEntity.KeyHistory), name)
+
+		bySigningKey := actual.GetKeyBySigningPubKey(actualEntity.PublicKeys.SigningPubKey)
+		if s.Assert().NotNil(bySigningKey, name) {
+			s.Assert().Equal(actualEntity.PublicKeys.SigningPubKey, bySigningKey.PublicKeys.SigningPubKey)
+		}
+	}
+
+	expectedGroups := expected.ListGroups()
+	actualGroups := actual.ListGroups()
+	if s.Assert().Equal(len(expectedGroups), len(actualGroups)) {
+		for i := range expectedGroups {
+			s.Assert().Equal(expectedGroups[i].Name, actualGroups[i].Name)
+			s.Assert().Equal(expectedGroups[i].Members, actualGroups[i].Members)
+		}
+	}
+
+	s.Assert().Equal(len(expected.ListRevocations()), len(actual.ListRevocations()))
+}
+
+func newTestPublicKeys(seedName string) (cipherPubKey, signingPubKey string, kpi *security.KeyPairInfo) {
+	kpi, _ = security.NewKeyPairInfoWithSeeds(seedName)
+	cipherPubKey, signingPubKey, _ = kpi.PublicKeys()
+	return cipherPubKey, signingPubKey, kpi
+}
+
+func (s *BTreeKeyStoreTestSuite) TestBTreeKeyStore_ReadConvertedStore() {
+	source := buildTestStoreMultiEntity(50)
+	source.Groups = GroupCollection{"TEAM": &Group{Name: "team", Members: []string{"bob-1", "bob-2"}}}
+	_, btreePath := s.writeTestStoreFiles(source)
+
+	store := s.openStore(btreePath)
+	defer func() {
+		_ = store.Close()
+	}()
+
+	_, isBTree := store.(*BTreeKeyStore)
+	s.Assert().True(isBTree)
+	s.Assert().Equal(50, store.Count())
+	s.Assert().Equal("local", store.GetDetails().Name)
+	s.Assert().Equal("localhost", store.GetServerInfo().Address)
+	s.assertSameStores(source, store)
+
+	s.Assert().Nil(store.GetKey("nobody"))
+	s.Assert().Nil(store.GetKeyBySigningPubKey("nokey"))
+
+	count, err := store.WalkCount("bob-1*", nil)
+	s.Assert().Nil(err)
+	s.Assert().Equal(11, count)
+}
+
+func (s *BTreeKeyStoreTestSuite) TestBTreeKeyStore_ChangesMatchSimpleStore() {
+	simplePath, btreePath := s.writeTestStoreFiles(buildTestStoreMultiEntity(20))
+	simpleStore := s.openStore(simplePath)
+	btreeStore := s.openStore(btreePath)
+
+	newCipherPubKey, newSigningPubKey, _ := newTestPublicKeys("new-keys")
+	rotatedCipherPubKey, rotatedSigningPubKey, _ := newTestPublicKeys("rotated-keys")
+	revokedCipherPubKey, revokedSigningPubKey, revokedKPI := newTestPublicKeys("revoked-keys")
+	// A fixed effective date, so that both stores record the same revocation date
+	revocation, err := security.NewRevocationCertificate("carol", revokedKPI, "compromised", "2024-02-03T04:05:06Z")
+	s.Require().Nil(err)
+
+	changes := []func(store KeyStore) error{
+		func(store KeyStore) error { return store.AddKey("alice", newCipherPubKey, newSigningPubKey) },
+		func(store KeyStore) error { return store.AddKey("alice", newCipherPubKey, newSigningPubKey) },
+		func(store KeyStore) error { return store.AddKey("carol", revokedCipherPubKey, revokedSigningPubKey) },
+		func(store KeyStore) error { return store.AddGroup("team", []string{"alice", "bob-1", "bob-3"}) },
+		func(store KeyStore) error { return store.AddGroup("ops", []string{"bob-3", "nobody"}) },
+		func(store KeyStore) error {
+			_, err := store.SetEntityTrust("bob-1", security.TrustLevelVerified, "in-person")
+			return err
+		},
+		func(store KeyStore) error {
+			_, err := store.RenameEntity("bob-1", "robert")
+			return err
+		},
+		func(store KeyStore) error {
+			_, err := store.UpdatePublicKeys("alice", rotatedCipherPubKey, rotatedSigningPubKey)
+			return err
+		},
+		func(store KeyStore) error {
+			_, _, err := store.ApplyRevocation(revocation)
+			return err
+		},
+		func(store KeyStore) error {
+			_, err := store.SetEntityTrust("carol", security.TrustLevelVerified, "")
+			return err
+		},
+		func(store KeyStore) error {
+			_, err := store.UpdateGroupMembers("team", []string{"bob-7"}, []string{"alice"})
+			return err
+		},
+		func(store KeyStore) error {
+			_, err := store.RemoveEntity("bob-3")
+			return err
+		},
+		func(store KeyStore) error {
+			_, err := store.UpdateContactInfo("bob-7", &security.ContactInfo{Emails: []string{"bob7@example.com"}})
+			return err
+		},
+		func(store KeyStore) error {
+			_, err := store.RemoveEntity("nobody")
+			return err
+		},
+	}
+
+	for i, change := range changes {
+		simpleErr := change(simpleStore)
+		btreeErr := change(btreeStore)
+		s.Assert().Equal(simpleErr == nil, btreeErr == nil, fmt.Sprintf("change %d: %v, %v", i, simpleErr, btreeErr))
+	}
+
+	s.assertSameStores(simpleStore, btreeStore)
+
+	group := btreeStore.GetGroup("team")
+	if s.Assert().NotNil(group) {
+		s.Assert().Equal([]string{"bob-7", "robert"}, group.Members)
+	}
+
+	s.Assert().Equal(security.TrustLevelRevoked, btreeStore.GetKey("carol").Trust)
+	s.Assert().Equal("2024-02-03T04:05:06Z", btreeStore.GetKey("carol").RevokedDate)
+	s.Assert().Equal("bob7@example.com", btreeStore.GetKey("bob-7").Contact.Emails[0])
+	s.Assert().Nil(btreeStore.GetKeyBySigningPubKey(newSigningPubKey))
+
+	// The changes are in the file, so a reopened store has them
+	s.Require().Nil(btreeStore.Close())
+	reopenedStore := s.openStore(btreePath)
+	defer func() {
+		_ = reopenedStore.Close()
+	}()
+
+	s.assertSameStores(simpleStore, reopenedStore)
+}
+
+func (s *BTreeKeyStoreTestSuite) TestBTreeKeyStore_SharedSigningKey() {
+	_, btreePath := s.writeTestStoreFiles(buildTestStore())
+	store := s.openStore(btreePath)
+	defer func() {
+		_ = store.Close()
+	}()
+
+	cipherPubKey, signingPubKey, _ := newTestPublicKeys("shared")
+	s.Require().Nil(store.AddKey("first", cipherPubKey, signingPubKey))
+	s.Require().Nil(store.AddKey("second", cipherPubKey, signingPubKey))
+
+	_, err := store.RemoveEntity("first")
+	s.Require().Nil(err)
+
+	entity := store.GetKeyBySigningPubKey(signingPubKey)
+	if s.Assert().NotNil(entity) {
+		s.Assert().Equal("second", entity.Name)
+	}
+}
+
+func (s *BTreeKeyStoreTestSuite) TestBTreeKeyStore_ConvertBackToSimple() {
+	source := buildTestStoreMultiEntity(10)
+	_, btreePath := s.writeTestStoreFiles(source)
+
+	_, err := ConvertStoreFile(btreePath, StoreFormatBTree)
+	s.Assert().NotNil(err)
+
+	count, err := ConvertStoreFile(btreePath, StoreFormatSimple)
+	s.Require().Nil(err)
+	s.Assert().Equal(10, count)
+
+	format, err := DetectStoreFormat(btreePath)
+	s.Assert().Nil(err)
+	s.Assert().Equal(StoreFormatSimple, format)
+
+	store := s.openStore(btreePath)
+	_, isSimple := store.(*SimpleKeyStore)
+	s.Assert().True(isSimple)
+	s.assertSameStores(source, store)
+}
+
+func (s *BTreeKeyStoreTestSuite) TestBTreeKeyStore_WriteToFileCopy() {
+	_, btreePath := s.writeTestStoreFiles(buildTestStoreMultiEntity(5))
+	store := s.openStore(btreePath)
+	defer func() {
+		_ = store.Close()
+	}()
+
+	copyPath := filepath.Join(s.tempPath, "copy.keystore")
+	s.Require().Nil(store.WriteToFile(copyPath))
+
+	copiedStore := s.openStore(copyPath)
+	defer func() {
+		_ = copiedStore.Close()
+	}()
+
+	s.assertSameStores(store, copiedStore)
+}
+
+func (s *BTreeKeyStoreTestSuite) TestBTreeKeyStore_WrongKeyPairs() {
+	_, btreePath := s.writeTestStoreFiles(buildTestStore())
+
+	useTestKeyStoreKeyPairs()
+	_, err := NewFromFile(nil, btreePath)
+	s.Assert().NotNil(err)
+}
+
+func (s *BTreeKeyStoreTestSuite) TestBTreeKeyStore_RecordsAreEncrypted() {
+	source := buildTestStore()
+	_, btreePath := s.writeTestStoreFiles(source)
+
+	fileBytes, err := os.ReadFile(btreePath)
+	s.Require().Nil(err)
+	s.Assert().NotContains(string(fileBytes), "BOB")
+	s.Assert().NotContains(string(fileBytes), source.Entities["BOB"].PublicKeys.SigningPubKey)
+}
@@ -0,0 +1,679 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystore
+
+import (
+	"errors"
+	"fmt"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/security"
+	"github.com/vmihailenco/msgpack/v5"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The KeyStore methods of BTreeKeyStore.  Changes stage the records that the SimpleKeyStore method reads,
+// then call it on the staged store.  See BTreeKeyStore.change.
+
+func (bks *BTreeKeyStore) AddCertification(certification *security.Certification) (name string, err error) {
+	err = bks.change(func(bc *btreeChange) error {
+		if certification != nil {
+			err := bc.loadEntitiesWithSigningKey(certification.SubjectSigningPubKey)
+			if err != nil {
+				return err
+			}
+		}
+
+		var changeErr error
+		name, changeErr = bc.staged.AddCertification(certification)
+		return changeErr
+	})
+
+	return name, err
+}
+
+func (bks *BTreeKeyStore) AddGroup(name string, memberNames []string) error {
+	return bks.change(func(bc *btreeChange) error {
+		err := bc.loadGroup(name)
+		if err != nil {
+			return err
+		}
+
+		err = bc.loadEntities(memberNames)
+		if err != nil {
+			return err
+		}
+
+		return bc.staged.AddGroup(name, memberNames)
+	})
+}
+
+func (bks *BTreeKeyStore) AddKey(name, cipherPubKey, signingPubKey string) error {
+	return bks.change(func(bc *btreeChange) error {
+		_, err := bc.loadEntity(name)
+		if err != nil {
+			return err
+		}
+
+		err = bc.loadRevocation(signingPubKey)
+		if err != nil {
+			return err
+		}
+
+		return bc.staged.AddKey(name, cipherPubKey, signingPubKey)
+	})
+}
+
+func (bks *BTreeKeyStore) AddKeyWithDetails(entity *security.Entity) error {
+	return bks.change(func(bc *btreeChange) error {
+		_, err := bc.loadEntity(entity.Name)
+		if err != nil {
+			return err
+		}
+
+		err = bc.loadRevocation(entitySigningPubKey(entity))
+		if err != nil {
+			return err
+		}
+
+		return bc.staged.AddKeyWithDetails(entity)
+	})
+}
+
+func (bks *BTreeKeyStore) ApplyKeySuccession(statement *security.SuccessionStatement) (name string, err error) {
+	err = bks.change(func(bc *btreeChange) error {
+		if statement != nil {
+			err := bc.loadEntitiesWithSigningKey(statement.OldSigningPubKey)
+			if err != nil {
+				return err
+			}
+
+			err = bc.loadRevocation(statement.OldSigningPubKey)
+			if err != nil {
+				return err
+			}
+
+			err = bc.loadRevocation(statement.NewSigningPubKey)
+			if err != nil {
+				return err
+			}
+		}
+
+		var changeErr error
+		name, changeErr = bc.staged.ApplyKeySuccession(statement)
+		return changeErr
+	})
+
+	return name, err
+}
+
+// ApplyRevocation only stages the entities with the revoked keys, so the returned name is one of those entities
+func (bks *BTreeKeyStore) ApplyRevocation(certificate *security.RevocationCertificate) (entry *RevocationEntry, name string, err error) {
+	err = bks.change(func(bc *btreeChange) error {
+		if certificate != nil {
+			err := bc.loadRevocation(certificate.SigningPubKey)
+			if err != nil {
+				return err
+			}
+
+			err = bc.loadEntitiesWithSigningKey(certificate.SigningPubKey)
+			if err != nil {
+				return err
+			}
+		}
+
+		var changeErr error
+		entry, name, changeErr = bc.staged.ApplyRevocation(certificate)
+		return changeErr
+	})
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	return entry, name, nil
+}
+
+func (bks *BTreeKeyStore) GetRevocation(signingPubKey string) *RevocationEntry {
+	bks.SyncStore.RLock()
+	defer bks.SyncStore.RUnlock()
+
+	if bks.kvFile == nil || signingPubKey == "" {
+		return nil
+	}
+
+	entry := &RevocationEntry{}
+	found, err := bks.getRecord(bks.kvFile.view(), btreeRecordKindRevocation, signingPubKey, entry)
+	if err != nil || !found {
+		return nil
+	}
+
+	return entry
+}
+
+// ListRevocations returns the local revocation list, sorted by revoked date
+func (bks *BTreeKeyStore) ListRevocations() []*RevocationEntry {
+	bks.SyncStore.RLock()
+	defer bks.SyncStore.RUnlock()
+
+	entries := []*RevocationEntry{}
+	if bks.kvFile == nil {
+		return entries
+	}
+
+	_ = bks.scanRecords(bks.kvFile.view(), btreeRecordKindRevocation, func(plainRecord []byte) (bool, error) {
+		entry := &RevocationEntry{}
+		err := msgpack.Unmarshal(plainRecord, entry)
+		if err != nil {
+			return false, err
+		}
+
+		entries = append(entries, entry)
+		return true, nil
+	})
+
+	sort.Slice(entries, func(i, j int) bool {
+		return helpers.DateTextBefore(entries[i].RevokedDate, entries[j].RevokedDate)
+	})
+
+	return entries
+}
+
+func (bks *BTreeKeyStore) Count() int {
+	bks.SyncStore.RLock()
+	defer bks.SyncStore.RUnlock()
+
+	return bks.entityCount
+}
+
+func (bks *BTreeKeyStore) GetDetails() *StoreDetails {
+	if bks.Details == nil {
+		return nil
+	}
+
+	return bks.Details.Clone()
+}
+
+func (bks *BTreeKeyStore) GetKey(name string) *security.Entity {
+	bks.SyncStore.RLock()
+	defer bks.SyncStore.RUnlock()
+
+	if bks.kvFile == nil {
+		return nil
+	}
+
+	entity, err := bks.getEntity(bks.kvFile.view(), name)
+	if err != nil {
+		return nil
+	}
+
+	return entity
+}
+
+// GetKeyBySigningPubKey returns the entity with the signing public key, or nil if there is none
+func (bks *BTreeKeyStore) GetKeyBySigningPubKey(signingPubKey string) *security.Entity {
+	bks.SyncStore.RLock()
+	defer bks.SyncStore.RUnlock()
+
+	if bks.kvFile == nil {
+		return nil
+	}
+
+	tx := bks.kvFile.view()
+	entityKeys, err := bks.getSigningKeyIndex(tx, signingPubKey)
+	if err != nil || len(entityKeys) == 0 {
+		return nil
+	}
+
+	entity, err := bks.getEntity(tx, entityKeys[0])
+	if err != nil {
+		return nil
+	}
+
+	return entity
+}
+
+// GetGroup returns the group, or nil if it is not found
+func (bks *BTreeKeyStore) GetGroup(name string) *Group {
+	bks.SyncStore.RLock()
+	defer bks.SyncStore.RUnlock()
+
+	if bks.kvFile == nil || name == "" {
+		return nil
+	}
+
+	group := &Group{}
+	found, err := bks.getRecord(bks.kvFile.view(), btreeRecordKindGroup, strings.ToUpper(name), group)
+	if err != nil || !found {
+		return nil
+	}
+
+	return group
+}
+
+// ListGroups returns the groups, sorted by name
+func (bks *BTreeKeyStore) ListGroups() []*Group {
+	bks.SyncStore.RLock()
+	defer bks.SyncStore.RUnlock()
+
+	groups := []*Group{}
+	if bks.kvFile == nil {
+		return groups
+	}
+
+	_ = bks.scanRecords(bks.kvFile.view(), btreeRecordKindGroup, func(plainRecord []byte) (bool, error) {
+		group := &Group{}
+		err := msgpack.Unmarshal(plainRecord, group)
+		if err != nil {
+			return false, err
+		}
+
+		groups = append(groups, group)
+		return true, nil
+	})
+
+	sort.Slice(groups, func(i, j int) bool {
+		return strings.ToUpper(groups[i].Name) < strings.ToUpper(groups[j].Name)
+	})
+
+	return groups
+}
+
+func (bks *BTreeKeyStore) RenameEntity(oldName, newName string) (found bool, err error) {
+	err = bks.change(func(bc *btreeChange) error {
+		err := bc.loadEntities([]string{oldName, newName})
+		if err != nil {
+			return err
+		}
+
+		err = bc.loadGroups()
+		if err != nil {
+			return err
+		}
+
+		var changeErr error
+		found, changeErr = bc.staged.RenameEntity(oldName, newName)
+		return changeErr
+	})
+
+	return found, err
+}
+
+func (bks *BTreeKeyStore) RemoveEntity(name string) (found bool, err error) {
+	err = bks.change(func(bc *btreeChange) error {
+		_, err := bc.loadEntity(name)
+		if err != nil {
+			return err
+		}
+
+		err = bc.loadGroups()
+		if err != nil {
+			return err
+		}
+
+		var changeErr error
+		found, changeErr = bc.staged.RemoveEntity(name)
+		return changeErr
+	})
+
+	return found, err
+}
+
+func (bks *BTreeKeyStore) RemoveGroup(name string) (found bool, err error) {
+	err = bks.change(func(bc *btreeChange) error {
+		err := bc.loadGroup(name)
+		if err != nil {
+			return err
+		}
+
+		var changeErr error
+		found, changeErr = bc.staged.RemoveGroup(name)
+		return changeErr
+	})
+
+	return found, err
+}
+
+func (bks *BTreeKeyStore) SetEntityTrust(name string, trust security.TrustLevel, verificationMethod string) (found bool, err error) {
+	err = bks.change(func(bc *btreeChange) error {
+		entity, err := bc.loadEntity(name)
+		if err != nil {
+			return err
+		}
+
+		err = bc.loadRevocation(entitySigningPubKey(entity))
+		if err != nil {
+			return err
+		}
+
+		var changeErr error
+		found, changeErr = bc.staged.SetEntityTrust(name, trust, verificationMethod)
+		return changeErr
+	})
+
+	return found, err
+}
+
+func (bks *BTreeKeyStore) UpdateContactInfo(name string, contact *security.ContactInfo) (found bool, err error) {
+	err = bks.change(func(bc *btreeChange) error {
+		_, err := bc.loadEntity(name)
+		if err != nil {
+			return err
+		}
+
+		var changeErr error
+		found, changeErr = bc.staged.UpdateContactInfo(name, contact)
+		return changeErr
+	})
+
+	return found, err
+}
+
+func (bks *BTreeKeyStore) UpdateGroupMembers(name string, addNames, removeNames []string) (found bool, err error) {
+	err = bks.change(func(bc *btreeChange) error {
+		err := bc.loadGroup(name)
+		if err != nil {
+			return err
+		}
+
+		err = bc.loadEntities(addNames)
+		if err != nil {
+			return err
+		}
+
+		var changeErr error
+		found, changeErr = bc.staged.UpdateGroupMembers(name, addNames, removeNames)
+		return changeErr
+	})
+
+	return found, err
+}
+
+func (bks *BTreeKeyStore) GetServerInfo() *ServerInfo {
+	bks.SyncStore.RLock()
+	defer bks.SyncStore.RUnlock()
+
+	return bks.Server.Clone()
+}
+
+func (bks *BTreeKeyStore) UpdateCipherPublicKey(name, cipherPublicKey string) (found bool, err error) {
+	err = bks.change(func(bc *btreeChange) error {
+		entity, err := bc.loadEntity(name)
+		if err != nil {
+			return err
+		}
+
+		err = bc.loadRevocation(entitySigningPubKey(entity))
+		if err != nil {
+			return err
+		}
+
+		var changeErr error
+		found, changeErr = bc.staged.UpdateCipherPublicKey(name, cipherPublicKey)
+		return changeErr
+	})
+
+	return found, err
+}
+
+func (bks *BTreeKeyStore) UpdatePublicKeys(name, cipherPublicKey, signingPublicKey string) (found bool, err error) {
+	return bks.UpdatePublicKeysWithSource(name, cipherPublicKey, signingPublicKey, security.KeySourceManual, "")
+}
+
+func (bks *BTreeKeyStore) UpdatePublicKeysWithSource(
+	name, cipherPublicKey, signingPublicKey string,
+	source security.KeySource,
+	sourceDetails string) (found bool, err error) {
+
+	err = bks.change(func(bc *btreeChange) error {
+		_, err := bc.loadEntity(name)
+		if err != nil {
+			return err
+		}
+
+		err = bc.loadRevocation(signingPublicKey)
+		if err != nil {
+			return err
+		}
+
+		var changeErr error
+		found, changeErr = bc.staged.UpdatePublicKeysWithSource(name, cipherPublicKey, signingPublicKey, source, sourceDetails)
+		return changeErr
+	})
+
+	return found, err
+}
+
+func (bks *BTreeKeyStore) UpdateSigningPublicKey(name, signingPublicKey string) (found bool, err error) {
+	err = bks.change(func(bc *btreeChange) error {
+		_, err := bc.loadEntity(name)
+		if err != nil {
+			return err
+		}
+
+		err = bc.loadRevocation(signingPublicKey)
+		if err != nil {
+			return err
+		}
+
+		var changeErr error
+		found, changeErr = bc.staged.UpdateSigningPublicKey(name, signingPublicKey)
+		return changeErr
+	})
+
+	return found, err
+}
+
+func (bks *BTreeKeyStore) UpdateSubkeys(name string, certificates []*security.SubkeyCertificate) (found bool, added int, err error) {
+	err = bks.change(func(bc *btreeChange) error {
+		_, err := bc.loadEntity(name)
+		if err != nil {
+			return err
+		}
+
+		var changeErr error
+		found, added, changeErr = bc.staged.UpdateSubkeys(name, certificates)
+		return changeErr
+	})
+
+	return found, added, err
+}
+
+// scanEntities calls scanFunc with each entity that matches the name filter and filter func
+func (bks *BTreeKeyStore) scanEntities(
+	nameMatchFilter string,
+	walkFilterFunc KeyStoreWalkFilterFunc,
+	scanFunc func(entity *security.Entity)) error {
+
+	bks.SyncStore.RLock()
+	defer bks.SyncStore.RUnlock()
+
+	if bks.kvFile == nil {
+		return errors.New("the keystore is closed")
+	}
+
+	return bks.scanRecords(bks.kvFile.view(), btreeRecordKindEntity, func(plainRecord []byte) (bool, error) {
+		entity := &security.Entity{}
+		err := msgpack.Unmarshal(plainRecord, entity)
+		if err != nil {
+			return false, fmt.Errorf("failed interpreting keystore record: %w", err)
+		}
+
+		if nameMatchFilter != "" && !helpers.MatchesFilter(entity.Name, nameMatchFilter) {
+			// The name filter takes priority over the filter func
+			return true, nil
+		}
+
+		if walkFilterFunc != nil && !walkFilterFunc(entity) {
+			return true, nil
+		}
+
+		scanFunc(entity)
+		return true, nil
+	})
+}
+
+func (bks *BTreeKeyStore) WalkCount(nameMatchFilter string, walkFilterFunc KeyStoreWalkFilterFunc) (count int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Panic in KeyStore.Walk: %s", r)
+		}
+	}()
+
+	err = bks.scanEntities(nameMatchFilter, walkFilterFunc, func(entity *security.Entity) {
+		count += 1
+	})
+
+	return count, err
+}
+
+// Walk reads the matching entities before calling WalkFunc, so WalkFunc may make changes to the store
+func (bks *BTreeKeyStore) Walk(walkInfo *WalkInfo) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Panic in KeyStore.Walk: %s", r)
+		}
+	}()
+
+	// If no entities, then just return
+	if bks.Count() == 0 {
+		return nil
+	}
+
+	// At a bare minimum, the caller must provide a WalkFunc
+	if walkInfo.WalkFunc == nil {
+		return errors.New("no WalkFunc provided")
+	}
+
+	var entities []*security.Entity
+	err = bks.scanEntities(walkInfo.NameMatchFilter, walkInfo.WalkFilterFunc, func(entity *security.Entity) {
+		entities = append(entities, entity)
+	})
+	if err != nil {
+		return err
+	}
+
+	if walkInfo.SortResults == true {
+		sort.Slice(entities, func(i, j int) bool {
+			if helpers.CompareStrings(entities[i].Name, entities[j].Name) == -1 {
+				return true
+			}
+
+			return false
+		})
+	}
+
+	for _, entity := range entities {
+		walkInfo.WalkFunc(entity)
+	}
+
+	return nil
+}
+
+// WriteToFile writes a copy of the keystore to filePath.  Changes are written to the store's own file as they
+// are made, so there is nothing to write if filePath is empty or is the store's own file.
+func (bks *BTreeKeyStore) WriteToFile(filePath string) error {
+	if filePath == "" || filepath.Clean(filePath) == filepath.Clean(bks.SourceFilePath) {
+		bks.Details.IsDirty = false
+		return nil
+	}
+
+	source, err := bks.toSimpleKeyStore()
+	if err != nil {
+		return err
+	}
+
+	return createBTreeKeyStoreFile(source, filePath)
+}
+
+// toSimpleKeyStore reads every record into a SimpleKeyStore
+func (bks *BTreeKeyStore) toSimpleKeyStore() (*SimpleKeyStore, error) {
+	bks.SyncStore.RLock()
+	defer bks.SyncStore.RUnlock()
+
+	if bks.kvFile == nil {
+		return nil, errors.New("the keystore is closed")
+	}
+
+	sks := &SimpleKeyStore{
+		Details:  bks.Details.Clone(),
+		Server:   bks.Server.Clone(),
+		Entities: NewEntities(),
+	}
+
+	tx := bks.kvFile.view()
+	err := bks.scanRecords(tx, btreeRecordKindEntity, func(plainRecord []byte) (bool, error) {
+		entity := &security.Entity{}
+		err := msgpack.Unmarshal(plainRecord, entity)
+		sks.Entities[strings.ToUpper(entity.Name)] = entity
+		return err == nil, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read entities: %w", err)
+	}
+
+	err = bks.scanRecords(tx, btreeRecordKindGroup, func(plainRecord []byte) (bool, error) {
+		group := &Group{}
+		err := msgpack.Unmarshal(plainRecord, group)
+		if sks.Groups == nil {
+			sks.Groups = GroupCollection{}
+		}
+
+		sks.Groups[strings.ToUpper(group.Name)] = group
+		return err == nil, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read groups: %w", err)
+	}
+
+	err = bks.scanRecords(tx, btreeRecordKindRevocation, func(plainRecord []byte) (bool, error) {
+		entry := &RevocationEntry{}
+		err := msgpack.Unmarshal(plainRecord, entry)
+		if err != nil {
+			return false, err
+		}
+
+		if entry.Certificate == nil {
+			return false, errors.New("revocation record has no certificate")
+		}
+
+		if sks.Revocations == nil {
+			sks.Revocations = RevocationCollection{}
+		}
+
+		sks.Revocations[entry.Certificate.SigningPubKey] = entry
+		return true, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read revocations: %w", err)
+	}
+
+	return sks, nil
+}
+
+// Close closes the B-tree file.  The store cannot be used after it is closed.
+func (bks *BTreeKeyStore) Close() error {
+	bks.SyncStore.Lock()
+	defer bks.SyncStore.Unlock()
+
+	if bks.kvFile == nil {
+		return nil
+	}
+
+	security.Wipe(bks.indexKey)
+	err := bks.kvFile.close()
+	bks.kvFile = nil
+	return err
+}
@@ -0,0 +1,98 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystore
+
+import (
+	"errors"
+	"github.com/thoughtrealm/bumblebee/security"
+)
+
+// AddCertification verifies the certification and stores it with the entity that has the certified keys.
+// A newer certification from the same certifier replaces the earlier one.  Returns ErrNoCertificationMatch
+// if no entity has the certified keys.
+func (sks *SimpleKeyStore) AddCertification(certification *security.Certification) (name string, err error) {
+	sks.SyncStore.Lock()
+	defer sks.SyncStore.Unlock()
+
+	if certification == nil {
+		return "", errors.New("no certification provided")
+	}
+
+	err = certification.Verify()
+	if err != nil {
+		return "", err
+	}
+
+	var entity *security.Entity
+	for _, storeEntity := range sks.Entities {
+		if certification.Certifies(storeEntity.PublicKeys) {
+			entity = storeEntity
+			break
+		}
+	}
+
+	if entity == nil {
+		return "", ErrNoCertificationMatch
+	}
+
+	certifications := []*security.Certification{}
+	for _, existing := range entity.Certifications {
+		if existing.CertifierSigningPubKey != certification.CertifierSigningPubKey {
+			certifications = append(certifications, existing)
+		}
+	}
+
+	entity.Certifications = append(certifications, certification.Clone())
+	touchEntity(entity)
+	sks.Details.IsDirty = true
+	return entity.Name, sks.updateStoreFile()
+}
+
+// GetKeyBySigningPubKey returns the entity with the signing public key, or nil if there is none
+func (sks *SimpleKeyStore) GetKeyBySigningPubKey(signingPubKey string) *security.Entity {
+	sks.SyncStore.Lock()
+	defer sks.SyncStore.Unlock()
+
+	if signingPubKey == "" {
+		return nil
+	}
+
+	for _, entity := range sks.Entities {
+		if entity.PublicKeys != nil && entity.PublicKeys.SigningPubKey == signingPubKey {
+			return entity.Clone()
+		}
+	}
+
+	return nil
+}
+
+// pruneCertifications removes certifications that are not for the entity's current keys
+func pruneCertifications(entity *security.Entity) {
+	if len(entity.Certifications) == 0 {
+		return
+	}
+
+	certifications := []*security.Certification{}
+	for _, certification := range entity.Certifications {
+		if certification.Certifies(entity.PublicKeys) {
+			certifications = append(certifications, certification)
+		}
+	}
+
+	if len(certifications) == 0 {
+		certifications = nil
+	}
+
+	entity.Certifications = certifications
+}
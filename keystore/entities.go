@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"github.com/thoughtrealm/bumblebee/security"
 	"strings"
+	"time"
 )
 
 type EntityCollection map[string]*security.Entity
@@ -38,22 +39,86 @@ func (ec EntityCollection) Clone() EntityCollection {
 }
 
 func (sks *SimpleKeyStore) AddEntity(name string, key *security.KeyInfo) error {
+	return sks.AddEntityWithDetails(&security.Entity{
+		Name:       name,
+		PublicKeys: key,
+	})
+}
+
+// AddEntityWithDetails adds a copy of the entity, including any trust and contact values.  New entities are
+// accepted on first use until they are verified, so an unknown trust level is stored as TOFU.
+func (sks *SimpleKeyStore) AddEntityWithDetails(entity *security.Entity) error {
 	sks.SyncStore.Lock()
 	defer sks.SyncStore.Unlock()
 
-	if sks.getEntity(name) != nil {
-		return fmt.Errorf("an entity already exists with name %s", name)
+	if entity.PublicKeys == nil {
+		return fmt.Errorf("no public keys provided for entity %s", entity.Name)
 	}
 
-	sks.Entities[strings.ToUpper(name)] = &security.Entity{
-		Name:       name,
-		PublicKeys: key.Clone(),
+	if sks.getEntity(entity.Name) != nil {
+		return fmt.Errorf("an entity already exists with name %s", entity.Name)
 	}
 
+	newEntity := entity.Clone()
+	if newEntity.Trust == security.TrustLevelUnknown {
+		newEntity.Trust = security.TrustLevelTOFU
+	}
+
+	newEntity.CreatedDate = time.Now().Format(time.RFC3339)
+	newEntity.UpdatedDate = newEntity.CreatedDate
+	sks.applyRevocationList(newEntity)
+
+	sks.Entities[strings.ToUpper(entity.Name)] = newEntity
 	sks.Details.IsDirty = true
 	return nil
 }
 
+// UpdateContactInfo replaces the contact metadata for the entity
+func (sks *SimpleKeyStore) UpdateContactInfo(name string, contact *security.ContactInfo) (found bool, err error) {
+	sks.SyncStore.Lock()
+	defer sks.SyncStore.Unlock()
+
+	entity := sks.getEntity(name)
+	if entity == nil {
+		return false, fmt.Errorf("entity not found with name \"%s\"", name)
+	}
+
+	if contact.IsEmpty() {
+		entity.Contact = nil
+	} else {
+		entity.Contact = contact.Clone()
+	}
+
+	touchEntity(entity)
+	sks.Details.IsDirty = true
+	return true, sks.updateStoreFile()
+}
+
+// UpdateSubkeys adds the subkey certificates that are new for the entity.  Each certificate must be signed by the
+// entity's current signing key.  Since the identity key is unchanged, the entity's trust and verification are kept.
+func (sks *SimpleKeyStore) UpdateSubkeys(name string, certificates []*security.SubkeyCertificate) (found bool, added int, err error) {
+	sks.SyncStore.Lock()
+	defer sks.SyncStore.Unlock()
+
+	entity := sks.getEntity(name)
+	if entity == nil {
+		return false, 0, fmt.Errorf("entity not found with name \"%s\"", name)
+	}
+
+	added, err = entity.PublicKeys.MergeSubkeys(certificates)
+	if err != nil {
+		return true, 0, err
+	}
+
+	if added == 0 {
+		return true, 0, nil
+	}
+
+	touchEntity(entity)
+	sks.Details.IsDirty = true
+	return true, added, sks.updateStoreFile()
+}
+
 func (sks *SimpleKeyStore) RenameEntity(oldName, newName string) (bool, error) {
 	sks.SyncStore.Lock()
 	defer sks.SyncStore.Unlock()
@@ -65,18 +130,30 @@ func (sks *SimpleKeyStore) RenameEntity(oldName, newName string) (bool, error) {
 
 	entity.Name = newName
 	entity.PublicKeys.Name = newName
+	touchEntity(entity)
 
 	// Update map key entry with new name
 	sks.Entities[strings.ToUpper(newName)] = entity
 
 	// Remove prior entity with old name
 	delete(sks.Entities, strings.ToUpper(oldName))
+	sks.renameGroupMember(oldName, newName)
 
 	sks.Details.IsDirty = true
 	return true, nil
 }
 
 func (sks *SimpleKeyStore) UpdatePublicKeys(name, cipherPublicKey, signingPublicKey string) (found bool, err error) {
+	return sks.UpdatePublicKeysWithSource(name, cipherPublicKey, signingPublicKey, security.KeySourceManual, "")
+}
+
+// UpdatePublicKeysWithSource replaces the entity's public keys.  The prior keys are kept in the entity's key history,
+// along with the source of the change.  If the keys are the same as the current keys, nothing is changed.
+func (sks *SimpleKeyStore) UpdatePublicKeysWithSource(
+	name, cipherPublicKey, signingPublicKey string,
+	source security.KeySource,
+	sourceDetails string) (found bool, err error) {
+
 	sks.SyncStore.Lock()
 	defer sks.SyncStore.Unlock()
 
@@ -93,10 +170,7 @@ func (sks *SimpleKeyStore) UpdatePublicKeys(name, cipherPublicKey, signingPublic
 		return true, errors.New("provided signingPublicKey is empty")
 	}
 
-	entity.PublicKeys.CipherPubKey = cipherPublicKey
-	entity.PublicKeys.SigningPubKey = signingPublicKey
-	sks.Details.IsDirty = true
-	return true, sks.updateStoreFile()
+	return true, sks.updateEntityKeys(entity, cipherPublicKey, signingPublicKey, source, sourceDetails)
 }
 
 func (sks *SimpleKeyStore) UpdateCipherPublicKey(name string, cipherPublicKey string) (found bool, err error) {
@@ -112,9 +186,7 @@ func (sks *SimpleKeyStore) UpdateCipherPublicKey(name string, cipherPublicKey st
 		return true, errors.New("provided cipherPublicKey is empty")
 	}
 
-	entity.PublicKeys.CipherPubKey = cipherPublicKey
-	sks.Details.IsDirty = true
-	return true, sks.updateStoreFile()
+	return true, sks.updateEntityKeys(entity, cipherPublicKey, entity.PublicKeys.SigningPubKey, security.KeySourceManual, "")
 }
 
 func (sks *SimpleKeyStore) UpdateSigningPublicKey(name string, signingPublicKey string) (found bool, err error) {
@@ -130,11 +202,149 @@ func (sks *SimpleKeyStore) UpdateSigningPublicKey(name string, signingPublicKey
 		return true, errors.New("provided signingPublicKey is empty")
 	}
 
-	entity.PublicKeys.SigningPubKey = signingPublicKey
+	return true, sks.updateEntityKeys(entity, entity.PublicKeys.CipherPubKey, signingPublicKey, security.KeySourceManual, "")
+}
+
+// updateEntityKeys pins the key change and resets the entity's verification, then saves the store.
+// Nothing is changed if the keys are the same.
+func (sks *SimpleKeyStore) updateEntityKeys(
+	entity *security.Entity,
+	cipherPublicKey, signingPublicKey string,
+	source security.KeySource,
+	sourceDetails string) error {
+
+	if !sks.replaceEntityKeys(entity, cipherPublicKey, signingPublicKey, source, sourceDetails) {
+		return nil
+	}
+
+	resetEntityVerification(entity)
+	sks.applyRevocationList(entity)
+	sks.Details.IsDirty = true
+	return sks.updateStoreFile()
+}
+
+// replaceEntityKeys replaces the keys, keeping the prior keys in the entity's key history.  Subkeys and
+// certifications for the prior keys are removed.  Returns false if the keys are the same as the current keys.
+func (sks *SimpleKeyStore) replaceEntityKeys(
+	entity *security.Entity,
+	cipherPublicKey, signingPublicKey string,
+	source security.KeySource,
+	sourceDetails string) bool {
+
+	signingKeyChanged := entity.PublicKeys.SigningPubKey != signingPublicKey
+	if !entity.PinKeyChange(cipherPublicKey, signingPublicKey, source, sourceDetails) {
+		return false
+	}
+
+	if signingKeyChanged {
+		// subkeys were certified by the prior signing key
+		entity.PublicKeys.Subkeys = nil
+	}
+
+	pruneCertifications(entity)
+	touchEntity(entity)
+	return true
+}
+
+// ApplyKeySuccession locates the entity whose signing key is the statement's old signing key and moves it to the
+// statement's new keys.  The statement is signed by the stored key, so the entity keeps its trust level.
+func (sks *SimpleKeyStore) ApplyKeySuccession(statement *security.SuccessionStatement) (name string, err error) {
+	sks.SyncStore.Lock()
+	defer sks.SyncStore.Unlock()
+
+	if statement == nil {
+		return "", errors.New("no succession statement provided")
+	}
+
+	var entity *security.Entity
+	for _, storeEntity := range sks.Entities {
+		if storeEntity.PublicKeys != nil && storeEntity.PublicKeys.SigningPubKey == statement.OldSigningPubKey {
+			entity = storeEntity
+			break
+		}
+	}
+
+	if entity == nil {
+		return "", ErrNoSuccessionMatch
+	}
+
+	if entity.Trust == security.TrustLevelRevoked || sks.getRevocation(statement.OldSigningPubKey) != nil {
+		return entity.Name, fmt.Errorf("entity \"%s\" is revoked and cannot accept a succession", entity.Name)
+	}
+
+	if sks.getRevocation(statement.NewSigningPubKey) != nil {
+		return entity.Name, fmt.Errorf("the new keys for entity \"%s\" are revoked", entity.Name)
+	}
+
+	err = statement.VerifyFrom(entity.PublicKeys)
+	if err != nil {
+		return entity.Name, err
+	}
+
+	sks.replaceEntityKeys(
+		entity,
+		statement.NewCipherPubKey,
+		statement.NewSigningPubKey,
+		security.KeySourceSuccession,
+		"rotated "+statement.RotatedDate)
+	sks.Details.IsDirty = true
+	return entity.Name, sks.updateStoreFile()
+}
+
+// SetEntityTrust updates the trust level for the entity.  The verification date is set when the level is verified.
+func (sks *SimpleKeyStore) SetEntityTrust(name string, trust security.TrustLevel, verificationMethod string) (found bool, err error) {
+	sks.SyncStore.Lock()
+	defer sks.SyncStore.Unlock()
+
+	entity := sks.getEntity(name)
+	if entity == nil {
+		return false, fmt.Errorf("entity not found with name \"%s\"", name)
+	}
+
+	if trust != security.TrustLevelRevoked && sks.getRevocation(entity.PublicKeys.SigningPubKey) != nil {
+		return true, fmt.Errorf("the keys for entity \"%s\" have a revocation certificate in the keystore", name)
+	}
+
+	if trust == security.TrustLevelRevoked {
+		if entity.Trust != security.TrustLevelRevoked {
+			entity.RevokedDate = time.Now().UTC().Format(time.RFC3339)
+			entity.RevocationReason = ""
+		}
+	} else {
+		entity.RevokedDate = ""
+		entity.RevocationReason = ""
+	}
+
+	entity.Trust = trust
+	if trust == security.TrustLevelVerified {
+		entity.VerifiedDate = time.Now().Format(time.RFC3339)
+		entity.VerificationMethod = verificationMethod
+	} else {
+		entity.VerifiedDate = ""
+		entity.VerificationMethod = ""
+	}
+
+	touchEntity(entity)
 	sks.Details.IsDirty = true
 	return true, sks.updateStoreFile()
 }
 
+// resetEntityVerification drops a verified entity back to TOFU, since a verification only applies to the verified keys
+func resetEntityVerification(entity *security.Entity) {
+	if entity.Trust != security.TrustLevelVerified {
+		return
+	}
+
+	entity.Trust = security.TrustLevelTOFU
+	entity.VerifiedDate = ""
+	entity.VerificationMethod = ""
+}
+
+// touchEntity sets the entity's updated date to now
+func touchEntity(entity *security.Entity) {
+	entity.UpdatedDate = time.Now().Format(time.RFC3339)
+}
+
 func (sks *SimpleKeyStore) GetEntity(name string) (outEntity *security.Entity) {
 	sks.SyncStore.Lock()
 	defer sks.SyncStore.Unlock()
@@ -144,12 +354,7 @@ func (sks *SimpleKeyStore) GetEntity(name string) (outEntity *security.Entity) {
 		return actualEntity
 	}
 
-	outEntity = &security.Entity{
-		Name:       actualEntity.Name,
-		PublicKeys: actualEntity.PublicKeys.Clone(),
-	}
-
-	return outEntity
+	return actualEntity.Clone()
 }
 
 func (sks *SimpleKeyStore) getEntity(name string) (outEntity *security.Entity) {
@@ -176,6 +381,7 @@ func (sks *SimpleKeyStore) removeEntity(name string) (found bool, err error) {
 	}
 
 	delete(sks.Entities, strings.ToUpper(name))
+	sks.removeGroupMemberFromAll(name)
 	sks.Details.IsDirty = true
 	return true, sks.updateStoreFile()
 }
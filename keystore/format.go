@@ -0,0 +1,171 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystore
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// StoreFormat is the file format of a keystore, which determines the KeyStore backend that reads it
+type StoreFormat int
+
+const (
+	// StoreFormatSimple is a single encrypted file that is read and written as a whole
+	StoreFormatSimple StoreFormat = iota
+
+	// StoreFormatBTree is a B-tree file of encrypted records, which are read and written as they are needed
+	StoreFormatBTree
+	StoreFormatUnknown
+)
+
+func TextToStoreFormat(textName string) StoreFormat {
+	switch strings.ToUpper(strings.Trim(textName, " \t\n\r")) {
+	case "SIMPLE":
+		return StoreFormatSimple
+	case "BTREE":
+		return StoreFormatBTree
+	default:
+		return StoreFormatUnknown
+	}
+}
+
+func StoreFormatToText(format StoreFormat) string {
+	switch format {
+	case StoreFormatSimple:
+		return "simple"
+	case StoreFormatBTree:
+		return "btree"
+	default:
+		return "unknown"
+	}
+}
+
+// DetectStoreFormat returns the format of the keystore file
+func DetectStoreFormat(filePath string) (StoreFormat, error) {
+	isBTree, err := isKVFile(filePath)
+	if err != nil {
+		return StoreFormatUnknown, fmt.Errorf("unable to read keystore file: %w", err)
+	}
+
+	if isBTree {
+		return StoreFormatBTree, nil
+	}
+
+	return StoreFormatSimple, nil
+}
+
+// ConvertStoreFile rewrites the keystore file in the format and returns the number of entities.  The converted
+// store is written to a temporary file and read back before it replaces the original, so the original is not
+// changed if the conversion fails.  The keystore must not be open while it is converted.
+func ConvertStoreFile(filePath string, format StoreFormat) (count int, err error) {
+	if format != StoreFormatSimple && format != StoreFormatBTree {
+		return 0, fmt.Errorf("unknown keystore format: %d", format)
+	}
+
+	currentFormat, err := DetectStoreFormat(filePath)
+	if err != nil {
+		return 0, err
+	}
+
+	if currentFormat == format {
+		return 0, fmt.Errorf("the keystore is already in the %s format", StoreFormatToText(format))
+	}
+
+	source, err := readStoreFileToMemory(filePath)
+	if err != nil {
+		return 0, err
+	}
+
+	convertedFilePath := filePath + ".converting"
+	if format == StoreFormatBTree {
+		err = createBTreeKeyStoreFile(source, convertedFilePath)
+	} else {
+		err = source.WriteToFile(convertedFilePath)
+	}
+
+	if err == nil {
+		err = verifyConvertedStoreFile(source, convertedFilePath)
+	}
+
+	if err != nil {
+		_ = os.Remove(convertedFilePath)
+		return 0, fmt.Errorf("unable to convert keystore: %w", err)
+	}
+
+	err = os.Rename(convertedFilePath, filePath)
+	if err != nil {
+		_ = os.Remove(convertedFilePath)
+		return 0, fmt.Errorf("unable to replace keystore file with the converted file: %w", err)
+	}
+
+	return len(source.Entities), nil
+}
+
+// readStoreFileToMemory reads every record of the keystore file into a SimpleKeyStore, whatever its format
+func readStoreFileToMemory(filePath string) (*SimpleKeyStore, error) {
+	store, err := NewFromFile(nil, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load keystore file: %w", err)
+	}
+	defer func() {
+		_ = store.Close()
+	}()
+
+	switch typedStore := store.(type) {
+	case *SimpleKeyStore:
+		return typedStore, nil
+	case *BTreeKeyStore:
+		return typedStore.toSimpleKeyStore()
+	default:
+		return nil, fmt.Errorf("unsupported keystore type %T", store)
+	}
+}
+
+// verifyConvertedStoreFile reads the converted file and checks that it has the same number of records as the source
+func verifyConvertedStoreFile(source *SimpleKeyStore, convertedFilePath string) error {
+	converted, err := NewFromFile(nil, convertedFilePath)
+	if err != nil {
+		return fmt.Errorf("unable to read converted keystore: %w", err)
+	}
+	defer func() {
+		_ = converted.Close()
+	}()
+
+	if converted.Count() != len(source.Entities) {
+		return fmt.Errorf("converted keystore has %d users, but the source has %d", converted.Count(), len(source.Entities))
+	}
+
+	if len(converted.ListGroups()) != len(source.Groups) {
+		return fmt.Errorf("converted keystore has %d groups, but the source has %d", len(converted.ListGroups()), len(source.Groups))
+	}
+
+	if len(converted.ListRevocations()) != len(source.Revocations) {
+		return fmt.Errorf(
+			"converted keystore has %d revocations, but the source has %d",
+			len(converted.ListRevocations()), len(source.Revocations))
+	}
+
+	walkCount, err := converted.WalkCount("", nil)
+	if err != nil {
+		return fmt.Errorf("unable to read converted keystore users: %w", err)
+	}
+
+	if walkCount != len(source.Entities) {
+		return fmt.Errorf("converted keystore has %d readable users, but the source has %d", walkCount, len(source.Entities))
+	}
+
+	return nil
+}
@@ -0,0 +1,256 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystore
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GroupPrefix marks a recipient name as a group, such as "@support"
+const GroupPrefix = "@"
+
+// Group is a named list of entities in the keystore
+type Group struct {
+	Name string
+	// Members are the names of the member entities, sorted
+	Members     []string
+	CreatedDate string
+	UpdatedDate string
+}
+
+func (g *Group) Clone() *Group {
+	gOut := *g
+	gOut.Members = append([]string(nil), g.Members...)
+	return &gOut
+}
+
+// HasMember returns true if the entity name is a member of the group.  Names are not case-sensitive.
+func (g *Group) HasMember(name string) bool {
+	return g.memberIndex(name) >= 0
+}
+
+func (g *Group) memberIndex(name string) int {
+	for i, memberName := range g.Members {
+		if strings.EqualFold(memberName, name) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// GroupCollection is keyed by the upper case group name
+type GroupCollection map[string]*Group
+
+func (gc GroupCollection) Clone() GroupCollection {
+	if gc == nil {
+		return nil
+	}
+
+	gcOutput := GroupCollection{}
+	for key, group := range gc {
+		gcOutput[key] = group.Clone()
+	}
+
+	return gcOutput
+}
+
+// IsGroupReference returns true if the recipient name refers to a group, such as "@support"
+func IsGroupReference(name string) bool {
+	return strings.HasPrefix(name, GroupPrefix)
+}
+
+// GroupNameFromReference removes the group prefix from the name, if it has one
+func GroupNameFromReference(name string) string {
+	return strings.TrimPrefix(name, GroupPrefix)
+}
+
+// AddGroup adds a new group with the member entities, which must exist in the keystore
+func (sks *SimpleKeyStore) AddGroup(name string, memberNames []string) error {
+	sks.SyncStore.Lock()
+	defer sks.SyncStore.Unlock()
+
+	if name == "" {
+		return errors.New("provided group name is empty")
+	}
+
+	if IsGroupReference(name) {
+		return fmt.Errorf("group names may not start with \"%s\"", GroupPrefix)
+	}
+
+	if sks.getGroup(name) != nil {
+		return fmt.Errorf("a group already exists with name \"%s\"", name)
+	}
+
+	group := &Group{Name: name}
+	err := sks.addGroupMembers(group, memberNames)
+	if err != nil {
+		return err
+	}
+
+	group.CreatedDate = time.Now().Format(time.RFC3339)
+	group.UpdatedDate = group.CreatedDate
+
+	if sks.Groups == nil {
+		sks.Groups = GroupCollection{}
+	}
+
+	sks.Groups[strings.ToUpper(name)] = group
+	sks.Details.IsDirty = true
+	return sks.updateStoreFile()
+}
+
+// GetGroup returns a copy of the group, or nil if it is not found
+func (sks *SimpleKeyStore) GetGroup(name string) *Group {
+	sks.SyncStore.Lock()
+	defer sks.SyncStore.Unlock()
+
+	group := sks.getGroup(name)
+	if group == nil {
+		return nil
+	}
+
+	return group.Clone()
+}
+
+// ListGroups returns copies of the groups, sorted by name
+func (sks *SimpleKeyStore) ListGroups() []*Group {
+	sks.SyncStore.Lock()
+	defer sks.SyncStore.Unlock()
+
+	groups := make([]*Group, 0, len(sks.Groups))
+	for _, group := range sks.Groups {
+		groups = append(groups, group.Clone())
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return strings.ToUpper(groups[i].Name) < strings.ToUpper(groups[j].Name)
+	})
+
+	return groups
+}
+
+// RemoveGroup removes the group.  The member entities are not changed.
+func (sks *SimpleKeyStore) RemoveGroup(name string) (found bool, err error) {
+	sks.SyncStore.Lock()
+	defer sks.SyncStore.Unlock()
+
+	if sks.getGroup(name) == nil {
+		return false, nil
+	}
+
+	delete(sks.Groups, strings.ToUpper(name))
+	sks.Details.IsDirty = true
+	return true, sks.updateStoreFile()
+}
+
+// UpdateGroupMembers adds and removes group members.  Added members must exist in the keystore.
+// Removed members that are not in the group are ignored.
+func (sks *SimpleKeyStore) UpdateGroupMembers(name string, addNames, removeNames []string) (found bool, err error) {
+	sks.SyncStore.Lock()
+	defer sks.SyncStore.Unlock()
+
+	group := sks.getGroup(name)
+	if group == nil {
+		return false, fmt.Errorf("group not found with name \"%s\"", name)
+	}
+
+	updatedGroup := group.Clone()
+	for _, removeName := range removeNames {
+		removeGroupMember(updatedGroup, removeName)
+	}
+
+	err = sks.addGroupMembers(updatedGroup, addNames)
+	if err != nil {
+		return true, err
+	}
+
+	updatedGroup.UpdatedDate = time.Now().Format(time.RFC3339)
+	sks.Groups[strings.ToUpper(name)] = updatedGroup
+	sks.Details.IsDirty = true
+	return true, sks.updateStoreFile()
+}
+
+func (sks *SimpleKeyStore) getGroup(name string) *Group {
+	if sks.Groups == nil || name == "" {
+		return nil
+	}
+
+	return sks.Groups[strings.ToUpper(name)]
+}
+
+// addGroupMembers adds the entities to the group by their stored names.  Every entity must exist.
+func (sks *SimpleKeyStore) addGroupMembers(group *Group, memberNames []string) error {
+	for _, memberName := range memberNames {
+		if memberName == "" {
+			continue
+		}
+
+		entity := sks.getEntity(memberName)
+		if entity == nil {
+			return fmt.Errorf("entity not found with name \"%s\"", memberName)
+		}
+
+		if !group.HasMember(entity.Name) {
+			group.Members = append(group.Members, entity.Name)
+		}
+	}
+
+	sortGroupMembers(group)
+	return nil
+}
+
+// renameGroupMember updates every group that has the entity as a member
+func (sks *SimpleKeyStore) renameGroupMember(oldName, newName string) {
+	for _, group := range sks.Groups {
+		index := group.memberIndex(oldName)
+		if index < 0 {
+			continue
+		}
+
+		group.Members[index] = newName
+		sortGroupMembers(group)
+		group.UpdatedDate = time.Now().Format(time.RFC3339)
+	}
+}
+
+// removeGroupMemberFromAll removes the entity from every group it is a member of
+func (sks *SimpleKeyStore) removeGroupMemberFromAll(name string) {
+	for _, group := range sks.Groups {
+		if removeGroupMember(group, name) {
+			group.UpdatedDate = time.Now().Format(time.RFC3339)
+		}
+	}
+}
+
+// removeGroupMember returns true if the name was a member of the group
+func removeGroupMember(group *Group, name string) bool {
+	index := group.memberIndex(name)
+	if index < 0 {
+		return false
+	}
+
+	group.Members = append(group.Members[:index], group.Members[index+1:]...)
+	return true
+}
+
+func sortGroupMembers(group *Group) {
+	sort.Slice(group.Members, func(i, j int) bool {
+		return strings.ToUpper(group.Members[i]) < strings.ToUpper(group.Members[j])
+	})
+}
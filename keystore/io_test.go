@@ -14,8 +14,13 @@
 package keystore
 
 import (
+	"errors"
 	"fmt"
 	"github.com/stretchr/testify/suite"
+	"github.com/thoughtrealm/bumblebee/security"
+	"github.com/vmihailenco/msgpack/v5"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -30,6 +35,10 @@ func TestKeyStoreIOTestSuite(t *testing.T) {
 
 func (s *KeyStoreIOTestSuite) SetupTest() {
 	s.testStore = buildTestStore()
+
+	// Changes to the store are saved to its source file, which is encrypted with the keystore keypairs
+	useTestKeyStoreKeyPairs()
+	s.testStore.SourceFilePath = filepath.Join(s.T().TempDir(), "test.keystore")
 }
 
 func (s *KeyStoreIOTestSuite) Test64BitLen() {
@@ -72,6 +81,390 @@ func (s *KeyStoreIOTestSuite) TestSimpleKeyStore_ReadWriteMemoryCycle_OneEntity(
 	}
 }
 
+func (s *KeyStoreIOTestSuite) TestSimpleKeyStore_ReadWriteMemoryCycle_EntityTrust() {
+	entity := s.testStore.getEntity("bob")
+	if !s.Assert().NotNil(entity) {
+		return
+	}
+	s.Assert().Equal(security.TrustLevelTOFU, entity.Trust)
+
+	entity.Trust = security.TrustLevelVerified
+	entity.VerifiedDate = "2024-01-02T03:04:05Z"
+	entity.VerificationMethod = "in-person"
+
+	bytesStore, err := s.testStore.WriteToMemory()
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	newStore, err := NewFromMemory(bytesStore)
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	newEntity := newStore.GetKey("bob")
+	if !s.Assert().NotNil(newEntity) {
+		return
+	}
+	s.Assert().Equal(security.TrustLevelVerified, newEntity.Trust)
+	s.Assert().Equal("2024-01-02T03:04:05Z", newEntity.VerifiedDate)
+	s.Assert().Equal("in-person", newEntity.VerificationMethod)
+
+	// A verification only applies to the keys that were verified
+	resetEntityVerification(entity)
+	s.Assert().Equal(security.TrustLevelTOFU, entity.Trust)
+	s.Assert().Equal("", entity.VerifiedDate)
+}
+
+func (s *KeyStoreIOTestSuite) TestSimpleKeyStore_ReadLegacyEntityWithoutTrust() {
+	// Entities from prior versions only have a name and public keys
+	type legacyEntity struct {
+		Name       string
+		PublicKeys *security.KeyInfo
+	}
+
+	entityBytes, err := msgpack.Marshal(&legacyEntity{Name: "legacy", PublicKeys: &security.KeyInfo{Name: "legacy"}})
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	entity := &security.Entity{}
+	err = msgpack.Unmarshal(entityBytes, entity)
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	s.Assert().Equal("legacy", entity.Name)
+	s.Assert().Equal(security.TrustLevelUnknown, entity.Trust)
+	s.Assert().Nil(entity.Contact)
+	s.Assert().Equal(security.KeySourceUnknown, entity.KeySource)
+}
+
+func (s *KeyStoreIOTestSuite) TestSimpleKeyStore_ReadWriteMemoryCycle_EntityContact() {
+	err := s.testStore.AddEntityWithDetails(&security.Entity{
+		Name:       "carol",
+		PublicKeys: &security.KeyInfo{Name: "carol"},
+		Contact: &security.ContactInfo{
+			DisplayName:  "Carol Smith",
+			Emails:       []string{"carol@example.com", "csmith@example.org"},
+			Organization: "Example Corp",
+			Notes:        "met at conference",
+			Tags:         []string{"work", "security"},
+		},
+		KeySource:        security.KeySourceImport,
+		KeySourceDetails: "file: /tmp/carol.export",
+	})
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	bytesStore, err := s.testStore.WriteToMemory()
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	newStore, err := NewFromMemory(bytesStore)
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	newEntity := newStore.GetKey("carol")
+	if !s.Assert().NotNil(newEntity) || !s.Assert().NotNil(newEntity.Contact) {
+		return
+	}
+	s.Assert().Equal("Carol Smith", newEntity.Contact.DisplayName)
+	s.Assert().Equal([]string{"carol@example.com", "csmith@example.org"}, newEntity.Contact.Emails)
+	s.Assert().Equal("Example Corp", newEntity.Contact.Organization)
+	s.Assert().Equal("met at conference", newEntity.Contact.Notes)
+	s.Assert().True(newEntity.Contact.HasTag("WORK"))
+	s.Assert().Equal(security.KeySourceImport, newEntity.KeySource)
+	s.Assert().Equal("file: /tmp/carol.export", newEntity.KeySourceDetails)
+	s.Assert().NotEqual("", newEntity.CreatedDate)
+	s.Assert().Equal(newEntity.CreatedDate, newEntity.UpdatedDate)
+
+	// Entities without contact info should not pick up an empty contact on decode
+	s.Assert().Nil(newStore.GetKey("bob").Contact)
+}
+
+func (s *KeyStoreIOTestSuite) TestSimpleKeyStore_ReadWriteMemoryCycle_Revocations() {
+	carolKPI, err := security.NewKeyPairInfoWithSeeds("carol")
+	if !s.Assert().Nil(err) {
+		return
+	}
+	cipherPubKey, signingPubKey, _ := carolKPI.PublicKeys()
+
+	err = s.testStore.AddEntityWithDetails(&security.Entity{
+		Name:       "carol",
+		PublicKeys: &security.KeyInfo{Name: "carol", CipherPubKey: cipherPubKey, SigningPubKey: signingPubKey},
+	})
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	certificate, err := security.NewRevocationCertificate("carol", carolKPI, security.RevocationReasonCompromised, "2024-02-03T04:05:06Z")
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	entry, name, err := s.testStore.ApplyRevocation(certificate)
+	if !s.Assert().Nil(err) || !s.Assert().NotNil(entry) {
+		return
+	}
+	s.Assert().Equal("carol", name)
+	s.Assert().Equal("2024-02-03T04:05:06Z", entry.RevokedDate)
+
+	entity := s.testStore.GetKey("carol")
+	s.Assert().Equal(security.TrustLevelRevoked, entity.Trust)
+	s.Assert().Equal(security.RevocationReasonCompromised, entity.RevocationReason)
+
+	_, err = s.testStore.SetEntityTrust("carol", security.TrustLevelVerified, "manual")
+	s.Assert().NotNil(err)
+	s.Assert().Equal(security.TrustLevelRevoked, s.testStore.GetKey("carol").Trust)
+
+	bytesStore, err := s.testStore.WriteToMemory()
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	newStore, err := NewFromMemory(bytesStore)
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	newEntry := newStore.GetRevocation(signingPubKey)
+	if !s.Assert().NotNil(newEntry) {
+		return
+	}
+	s.Assert().Nil(newEntry.Certificate.Verify())
+	s.Assert().Equal(security.TrustLevelRevoked, newStore.GetKey("carol").Trust)
+	s.Assert().Equal(1, len(newStore.ListRevocations()))
+
+	// Revoked keys that are added again are revoked immediately
+	err = s.testStore.AddEntityWithDetails(&security.Entity{
+		Name:       "carol2",
+		PublicKeys: &security.KeyInfo{Name: "carol2", CipherPubKey: cipherPubKey, SigningPubKey: signingPubKey},
+	})
+	if !s.Assert().Nil(err) {
+		return
+	}
+	s.Assert().Equal(security.TrustLevelRevoked, s.testStore.GetKey("carol2").Trust)
+}
+
+func (s *KeyStoreIOTestSuite) TestSimpleKeyStore_ListRevocationsByTime() {
+	// As text, these dates would sort by hour rather than by time
+	effectiveDates := map[string]string{
+		"later":   "2024-03-10T05:30:00-05:00",
+		"earlier": "2024-03-10T09:00:00+01:00",
+	}
+
+	for name, effectiveDate := range effectiveDates {
+		kpi, _ := security.NewKeyPairInfoWithSeeds(name)
+		certificate, err := security.NewRevocationCertificate(name, kpi, security.RevocationReasonRetired, effectiveDate)
+		if !s.Assert().Nil(err) {
+			return
+		}
+
+		_, _, err = s.testStore.ApplyRevocation(certificate)
+		s.Assert().Nil(err)
+	}
+
+	kpi, _ := security.NewKeyPairInfoWithSeeds("undated")
+	certificate, _ := security.NewRevocationCertificate("undated", kpi, security.RevocationReasonRetired, "")
+	entry, _, err := s.testStore.ApplyRevocation(certificate)
+	if !s.Assert().Nil(err) {
+		return
+	}
+	s.Assert().True(strings.HasSuffix(entry.ImportedDate, "Z"))
+
+	names := []string{}
+	for _, entry := range s.testStore.ListRevocations() {
+		names = append(names, entry.Certificate.Name)
+	}
+	s.Assert().Equal([]string{"earlier", "later", "undated"}, names)
+}
+
+func (s *KeyStoreIOTestSuite) TestSimpleKeyStore_ReadWriteMemoryCycle_Certifications() {
+	daveKPI, err := security.NewKeyPairInfoWithSeeds("dave")
+	if !s.Assert().Nil(err) {
+		return
+	}
+	cipherPubKey, signingPubKey, _ := daveKPI.PublicKeys()
+	daveKI := &security.KeyInfo{Name: "dave", CipherPubKey: cipherPubKey, SigningPubKey: signingPubKey}
+
+	err = s.testStore.AddEntityWithDetails(&security.Entity{Name: "dave", PublicKeys: daveKI.Clone()})
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	aliceKPI, _ := security.NewKeyPairInfoWithSeeds("alice")
+	certification, err := security.NewCertification(daveKI, "alice", aliceKPI)
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	name, err := s.testStore.AddCertification(certification)
+	s.Assert().Nil(err)
+	s.Assert().Equal("dave", name)
+
+	// A newer certification from the same certifier replaces the earlier one
+	_, err = s.testStore.AddCertification(certification)
+	s.Assert().Nil(err)
+	s.Assert().Equal(1, len(s.testStore.GetKey("dave").Certifications))
+
+	bytesStore, err := s.testStore.WriteToMemory()
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	newStore, err := NewFromMemory(bytesStore)
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	newEntity := newStore.GetKey("dave")
+	if !s.Assert().Equal(1, len(newEntity.Certifications)) {
+		return
+	}
+	s.Assert().Nil(newEntity.Certifications[0].Verify())
+
+	// Certifications of other keys are refused
+	otherKPI, _ := security.NewKeyPairInfoWithSeeds("other")
+	otherCipherPubKey, otherSigningPubKey, _ := otherKPI.PublicKeys()
+	otherCertification, _ := security.NewCertification(
+		&security.KeyInfo{Name: "dave", CipherPubKey: otherCipherPubKey, SigningPubKey: otherSigningPubKey}, "alice", aliceKPI)
+	_, err = s.testStore.AddCertification(otherCertification)
+	s.Assert().True(errors.Is(err, ErrNoCertificationMatch))
+
+	// Certifications no longer apply once the keys change
+	_, err = s.testStore.UpdatePublicKeys("dave", otherCipherPubKey, otherSigningPubKey)
+	s.Assert().Nil(err)
+	s.Assert().Nil(s.testStore.GetKey("dave").Certifications)
+}
+
+func (s *KeyStoreIOTestSuite) TestSimpleKeyStore_ReadWriteMemoryCycle_KeyHistory() {
+	oldKPI, _ := security.NewKeyPairInfoWithSeeds("erin")
+	manualKPI, _ := security.NewKeyPairInfoWithSeeds("erin-manual")
+	rotatedKPI, _ := security.NewKeyPairInfoWithSeeds("erin-rotated")
+
+	oldCipherPubKey, oldSigningPubKey, _ := oldKPI.PublicKeys()
+	err := s.testStore.AddEntityWithDetails(&security.Entity{
+		Name:       "erin",
+		PublicKeys: &security.KeyInfo{Name: "erin", CipherPubKey: oldCipherPubKey, SigningPubKey: oldSigningPubKey},
+		Trust:      security.TrustLevelVerified,
+	})
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	// A key change drops a verified user back to TOFU
+	manualCipherPubKey, manualSigningPubKey, _ := manualKPI.PublicKeys()
+	_, err = s.testStore.UpdatePublicKeysWithSource("erin", manualCipherPubKey, manualSigningPubKey, security.KeySourceImport, "test")
+	s.Assert().Nil(err)
+	s.Assert().Equal(security.TrustLevelTOFU, s.testStore.GetKey("erin").Trust)
+
+	// A succession keeps the trust level
+	_, err = s.testStore.SetEntityTrust("erin", security.TrustLevelVerified, "test")
+	s.Assert().Nil(err)
+	statement, err := security.NewSuccessionStatement("erin", manualKPI, rotatedKPI)
+	if !s.Assert().Nil(err) {
+		return
+	}
+	_, err = s.testStore.ApplyKeySuccession(statement)
+	s.Assert().Nil(err)
+	s.Assert().Equal(security.TrustLevelVerified, s.testStore.GetKey("erin").Trust)
+
+	bytesStore, err := s.testStore.WriteToMemory()
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	newStore, err := NewFromMemory(bytesStore)
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	newEntity := newStore.GetKey("erin")
+	s.Assert().Equal(statement.NewSigningPubKey, newEntity.PublicKeys.SigningPubKey)
+	s.Assert().Equal(security.KeySourceSuccession, newEntity.KeySource)
+	if !s.Assert().Equal(2, len(newEntity.KeyHistory)) {
+		return
+	}
+
+	s.Assert().Equal(oldSigningPubKey, newEntity.KeyHistory[0].SigningPubKey)
+	s.Assert().Equal(security.KeySourceImport, newEntity.KeyHistory[0].ChangeSource)
+	s.Assert().Equal("test", newEntity.KeyHistory[0].ChangeSourceDetails)
+	s.Assert().Equal(manualSigningPubKey, newEntity.KeyHistory[1].SigningPubKey)
+	s.Assert().Equal(security.KeySourceSuccession, newEntity.KeyHistory[1].ChangeSource)
+
+	priorKeys := newEntity.PriorKeyInfos()
+	if s.Assert().Equal(2, len(priorKeys)) {
+		s.Assert().Equal(manualSigningPubKey, priorKeys[0].SigningPubKey)
+	}
+}
+
+func (s *KeyStoreIOTestSuite) TestSimpleKeyStore_ReadWriteMemoryCycle_Groups() {
+	for _, name := range []string{"frank", "grace", "heidi"} {
+		kpi, _ := security.NewKeyPairInfoWithSeeds(name)
+		cipherPubKey, signingPubKey, _ := kpi.PublicKeys()
+		err := s.testStore.AddEntityWithDetails(&security.Entity{
+			Name:       name,
+			PublicKeys: &security.KeyInfo{Name: name, CipherPubKey: cipherPubKey, SigningPubKey: signingPubKey},
+		})
+		if !s.Assert().Nil(err) {
+			return
+		}
+	}
+
+	s.Assert().Nil(s.testStore.AddGroup("support", []string{"GRACE", "frank"}))
+	s.Assert().NotNil(s.testStore.AddGroup("support", nil))
+	s.Assert().NotNil(s.testStore.AddGroup("@other", nil))
+	s.Assert().NotNil(s.testStore.AddGroup("other", []string{"nobody"}))
+	s.Assert().Nil(s.testStore.GetGroup("other"))
+
+	group := s.testStore.GetGroup("Support")
+	if !s.Assert().NotNil(group) {
+		return
+	}
+	s.Assert().Equal([]string{"frank", "grace"}, group.Members)
+
+	_, err := s.testStore.UpdateGroupMembers("support", []string{"nobody"}, nil)
+	s.Assert().NotNil(err)
+	s.Assert().Equal([]string{"frank", "grace"}, s.testStore.GetGroup("support").Members)
+
+	_, err = s.testStore.UpdateGroupMembers("support", []string{"heidi"}, []string{"frank"})
+	s.Assert().Nil(err)
+	s.Assert().Equal([]string{"grace", "heidi"}, s.testStore.GetGroup("support").Members)
+
+	// Renaming and removing users keeps the membership consistent
+	_, err = s.testStore.RenameEntity("grace", "alice")
+	s.Assert().Nil(err)
+	_, err = s.testStore.RemoveEntity("heidi")
+	s.Assert().Nil(err)
+	s.Assert().Equal([]string{"alice"}, s.testStore.GetGroup("support").Members)
+
+	bytesStore, err := s.testStore.WriteToMemory()
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	newStore, err := NewFromMemory(bytesStore)
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	groups := newStore.ListGroups()
+	if !s.Assert().Equal(1, len(groups)) {
+		return
+	}
+	s.Assert().Equal("support", groups[0].Name)
+	s.Assert().Equal([]string{"alice"}, groups[0].Members)
+
+	found, err := s.testStore.RemoveGroup("support")
+	s.Assert().Nil(err)
+	s.Assert().True(found)
+	s.Assert().Nil(s.testStore.GetGroup("support"))
+}
+
 func (s *KeyStoreIOTestSuite) TestSimpleKeyStore_ReadWriteMemoryCycle_1000Entities() {
 	testStore := buildTestStoreMultiEntity(1000)
 
@@ -38,24 +38,50 @@ The Bumblebee keystore has the following goals...
 */
 package keystore
 
-import "github.com/thoughtrealm/bumblebee/security"
+import (
+	"errors"
+	"github.com/thoughtrealm/bumblebee/security"
+)
 
 var GlobalKeyStore KeyStore
 
+// ErrNoSuccessionMatch is returned when no entity has the old keys of a succession statement
+var ErrNoSuccessionMatch = errors.New("no user matches the old keys of the succession statement")
+
+// ErrNoCertificationMatch is returned when no entity has the keys of a certification
+var ErrNoCertificationMatch = errors.New("no user matches the certified keys")
+
 type KeyStore interface {
+	AddCertification(certification *security.Certification) (name string, err error)
+	AddGroup(name string, memberNames []string) error
 	AddKey(name, cipherPubKey, signingPubKey string) error
+	AddKeyWithDetails(entity *security.Entity) error
+	ApplyKeySuccession(statement *security.SuccessionStatement) (name string, err error)
+	ApplyRevocation(certificate *security.RevocationCertificate) (entry *RevocationEntry, name string, err error)
+	GetRevocation(signingPubKey string) *RevocationEntry
+	ListRevocations() []*RevocationEntry
 	Count() int
 	GetDetails() *StoreDetails
 	GetKey(name string) *security.Entity
+	GetKeyBySigningPubKey(signingPubKey string) *security.Entity
+	GetGroup(name string) *Group
+	ListGroups() []*Group
 	RenameEntity(oldName, newName string) (bool, error)
 	RemoveEntity(name string) (found bool, err error)
+	RemoveGroup(name string) (found bool, err error)
+	SetEntityTrust(name string, trust security.TrustLevel, verificationMethod string) (found bool, err error)
+	UpdateContactInfo(name string, contact *security.ContactInfo) (found bool, err error)
+	UpdateGroupMembers(name string, addNames, removeNames []string) (found bool, err error)
 	GetServerInfo() *ServerInfo
 	UpdateCipherPublicKey(name, cipherPublicKey string) (found bool, err error)
 	UpdatePublicKeys(name, cipherPpublicKey, signingPublicKey string) (found bool, err error)
+	UpdatePublicKeysWithSource(name, cipherPublicKey, signingPublicKey string, source security.KeySource, sourceDetails string) (found bool, err error)
 	UpdateSigningPublicKey(name, signingPublicKey string) (found bool, err error)
+	UpdateSubkeys(name string, certificates []*security.SubkeyCertificate) (found bool, added int, err error)
 	Walk(info *WalkInfo) error
 	WalkCount(nameMatchFilter string, walkFilterFunc KeyStoreWalkFilterFunc) (count int, err error)
 	WriteToFile(filePath string) error
+	Close() error
 }
 
 type KeyStoreWalkFunc func(entity *security.Entity)
@@ -92,8 +118,17 @@ func NewFromMemory(bytesStore []byte) (newKeyStore KeyStore, err error) {
 	return newSimpleKeyStoreFromMemory(bytesStore)
 }
 
-// NewFromFile returns a new keystore read from a file.
+// NewFromFile returns a new keystore read from a file, using the backend for the file's format.
 // The key sequence is used if the file is encrypted.  If it is not encrypted, the key is ignored.
 func NewFromFile(storeKey []byte, filePath string) (newKeyStore KeyStore, err error) {
+	format, err := DetectStoreFormat(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == StoreFormatBTree {
+		return newBTreeKeyStoreFromFile(filePath)
+	}
+
 	return newSimpleKeyStoreFromFile(filePath)
 }
@@ -0,0 +1,478 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+/*
+	The B-tree keystore file is a sequence of fixed size pages.
+
+	Pages 0 and 1 are header slots.  Each commit writes the header to the slot that the prior commit did not use,
+	so the file always has one complete header, which is the slot with the highest transaction ID and a valid checksum.
+
+	Pages that are referenced by the committed header are never written.  A transaction writes new copies of
+	the pages it changes, then commits them by writing the header.  If a write fails before the header is written,
+	the file still holds the prior state.  The pages that a commit replaces are added to the free list, which is
+	stored in a chain of pages referenced by the header.  Free pages are reused before the file is grown.
+*/
+
+const (
+	kvPageSize      = 4096
+	kvMagic         = "BEEKSDB1"
+	kvFormatVersion = uint16(1)
+	kvSaltLen       = 32
+
+	// kvHeaderSlotCount is the number of header slots at the start of the file
+	kvHeaderSlotCount = 2
+
+	// kvHeaderLen is the length of the header fields, not including the checksum
+	kvHeaderLen = 8 + 2 + 4 + 8 + 4 + 4 + 4 + kvSaltLen
+)
+
+const (
+	kvPageTypeBranch   byte = 1
+	kvPageTypeLeaf     byte = 2
+	kvPageTypeOverflow byte = 3
+	kvPageTypeFreeList byte = 4
+)
+
+// kvChainHeaderLen is the page type, next page and item count of overflow and free list pages
+const kvChainHeaderLen = 1 + 4 + 2
+
+// kvFreeListPageCapacity is the number of page IDs stored in a free list page
+const kvFreeListPageCapacity = (kvPageSize - kvChainHeaderLen) / 4
+
+var ErrNotKVFile = errors.New("file is not a B-tree keystore file")
+
+type kvHeader struct {
+	txID         uint64
+	rootPage     uint32
+	freeListPage uint32
+	pageCount    uint32
+	salt         [kvSaltLen]byte
+}
+
+func (h *kvHeader) encode() []byte {
+	page := make([]byte, kvPageSize)
+	copy(page, kvMagic)
+	binary.BigEndian.PutUint16(page[8:], kvFormatVersion)
+	binary.BigEndian.PutUint32(page[10:], kvPageSize)
+	binary.BigEndian.PutUint64(page[14:], h.txID)
+	binary.BigEndian.PutUint32(page[22:], h.rootPage)
+	binary.BigEndian.PutUint32(page[26:], h.freeListPage)
+	binary.BigEndian.PutUint32(page[30:], h.pageCount)
+	copy(page[34:], h.salt[:])
+
+	checksum := sha256.Sum256(page[:kvHeaderLen])
+	copy(page[kvHeaderLen:], checksum[:])
+	return page
+}
+
+func decodeKVHeader(page []byte) (*kvHeader, error) {
+	if len(page) < kvHeaderLen+sha256.Size || string(page[:8]) != kvMagic {
+		return nil, ErrNotKVFile
+	}
+
+	checksum := sha256.Sum256(page[:kvHeaderLen])
+	if !bytes.Equal(checksum[:], page[kvHeaderLen:kvHeaderLen+sha256.Size]) {
+		return nil, errors.New("header checksum does not match")
+	}
+
+	version := binary.BigEndian.Uint16(page[8:])
+	if version != kvFormatVersion {
+		return nil, fmt.Errorf("unsupported B-tree keystore file version %d", version)
+	}
+
+	pageSize := binary.BigEndian.Uint32(page[10:])
+	if pageSize != kvPageSize {
+		return nil, fmt.Errorf("unsupported B-tree keystore page size %d", pageSize)
+	}
+
+	h := &kvHeader{
+		txID:         binary.BigEndian.Uint64(page[14:]),
+		rootPage:     binary.BigEndian.Uint32(page[22:]),
+		freeListPage: binary.BigEndian.Uint32(page[26:]),
+		pageCount:    binary.BigEndian.Uint32(page[30:]),
+	}
+	copy(h.salt[:], page[34:])
+
+	return h, nil
+}
+
+// kvFile is an open B-tree keystore file
+type kvFile struct {
+	file   *os.File
+	header *kvHeader
+
+	// freePages are the pages that are not referenced by the committed header
+	freePages []uint32
+
+	// freeListChain are the pages that hold the committed free list
+	freeListChain []uint32
+}
+
+// isKVFile returns true if the file starts with the B-tree keystore file marker
+func isKVFile(filePath string) (bool, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	magic := make([]byte, len(kvMagic))
+	_, err = io.ReadFull(file, magic)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return string(magic) == kvMagic, nil
+}
+
+// createKVFile creates an empty B-tree file.  An existing file is replaced.
+func createKVFile(filePath string, salt []byte) (*kvFile, error) {
+	file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	kf := &kvFile{
+		file:   file,
+		header: &kvHeader{pageCount: kvHeaderSlotCount},
+	}
+	copy(kf.header.salt[:], salt)
+
+	// Both slots are written, so the file is valid even if the first commit is interrupted
+	for slot := 0; slot < kvHeaderSlotCount; slot++ {
+		err = kf.writePage(uint32(slot), kf.header.encode())
+		if err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("unable to write B-tree file header: %w", err)
+		}
+	}
+
+	err = file.Sync()
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("unable to sync B-tree file: %w", err)
+	}
+
+	return kf, nil
+}
+
+// openKVFile opens the B-tree file using the most recent valid header slot
+func openKVFile(filePath string) (*kvFile, error) {
+	file, err := os.OpenFile(filePath, os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	kf := &kvFile{file: file}
+	var headerErr error
+	for slot := 0; slot < kvHeaderSlotCount; slot++ {
+		page, err := kf.readPage(uint32(slot))
+		if err != nil {
+			headerErr = err
+			continue
+		}
+
+		header, err := decodeKVHeader(page)
+		if err != nil {
+			headerErr = err
+			continue
+		}
+
+		if kf.header == nil || header.txID > kf.header.txID {
+			kf.header = header
+		}
+	}
+
+	if kf.header == nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("no valid header found: %w", headerErr)
+	}
+
+	err = kf.readFreeList()
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("unable to read the free page list: %w", err)
+	}
+
+	return kf, nil
+}
+
+func (kf *kvFile) close() error {
+	if kf.file == nil {
+		return nil
+	}
+
+	err := kf.file.Close()
+	kf.file = nil
+	return err
+}
+
+func (kf *kvFile) salt() []byte {
+	return kf.header.salt[:]
+}
+
+func (kf *kvFile) readPage(pageID uint32) ([]byte, error) {
+	if kf.header != nil && pageID >= kf.header.pageCount {
+		return nil, fmt.Errorf("page %d is beyond the end of the file", pageID)
+	}
+
+	page := make([]byte, kvPageSize)
+	_, err := kf.file.ReadAt(page, int64(pageID)*kvPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read page %d: %w", pageID, err)
+	}
+
+	return page, nil
+}
+
+func (kf *kvFile) writePage(pageID uint32, page []byte) error {
+	_, err := kf.file.WriteAt(page, int64(pageID)*kvPageSize)
+	if err != nil {
+		return fmt.Errorf("unable to write page %d: %w", pageID, err)
+	}
+
+	return nil
+}
+
+func (kf *kvFile) readFreeList() error {
+	kf.freePages = nil
+	kf.freeListChain = nil
+
+	pageID := kf.header.freeListPage
+	for pageID != 0 {
+		if len(kf.freeListChain) > int(kf.header.pageCount) {
+			return errors.New("the free page list has a cycle")
+		}
+
+		page, err := kf.readPage(pageID)
+		if err != nil {
+			return err
+		}
+
+		if page[0] != kvPageTypeFreeList {
+			return fmt.Errorf("page %d is not a free list page", pageID)
+		}
+
+		kf.freeListChain = append(kf.freeListChain, pageID)
+		count := int(binary.BigEndian.Uint16(page[5:]))
+		if count > kvFreeListPageCapacity {
+			return fmt.Errorf("free list page %d has an invalid count", pageID)
+		}
+
+		for i := 0; i < count; i++ {
+			kf.freePages = append(kf.freePages, binary.BigEndian.Uint32(page[kvChainHeaderLen+i*4:]))
+		}
+
+		pageID = binary.BigEndian.Uint32(page[1:])
+	}
+
+	return nil
+}
+
+// view returns a transaction for reading the committed state.  It must not be used to make changes.
+func (kf *kvFile) view() *kvTx {
+	return &kvTx{
+		kf:        kf,
+		rootPage:  kf.header.rootPage,
+		pageCount: kf.header.pageCount,
+	}
+}
+
+// begin starts a transaction on the committed state.  Only one transaction should be active at a time.
+func (kf *kvFile) begin() *kvTx {
+	return &kvTx{
+		kf:         kf,
+		rootPage:   kf.header.rootPage,
+		pageCount:  kf.header.pageCount,
+		freePages:  append([]uint32(nil), kf.freePages...),
+		allocated:  map[uint32]bool{},
+		nodes:      map[uint32]*kvNode{},
+		dirtyPages: map[uint32][]byte{},
+	}
+}
+
+// kvTx is a set of changes to the B-tree file.  The changes are not visible in the file until commit is called.
+// A transaction that is not committed can just be dropped.
+type kvTx struct {
+	kf        *kvFile
+	rootPage  uint32
+	pageCount uint32
+
+	// freePages are the committed free pages that have not been used by the transaction
+	freePages []uint32
+
+	// pendingFree are the committed pages that the transaction no longer references.  They cannot be
+	// reused until the transaction is committed.
+	pendingFree []uint32
+
+	// allocated are the pages that were allocated by the transaction
+	allocated map[uint32]bool
+
+	// nodes are the B-tree nodes that were written by the transaction, keyed by their new page
+	nodes map[uint32]*kvNode
+
+	// dirtyPages are the overflow pages that were written by the transaction
+	dirtyPages map[uint32][]byte
+}
+
+// allocPage returns a page that is safe to write in this transaction
+func (tx *kvTx) allocPage() uint32 {
+	var pageID uint32
+	if len(tx.freePages) > 0 {
+		pageID = tx.freePages[len(tx.freePages)-1]
+		tx.freePages = tx.freePages[:len(tx.freePages)-1]
+	} else {
+		pageID = tx.pageCount
+		tx.pageCount++
+	}
+
+	tx.allocated[pageID] = true
+	return pageID
+}
+
+// freePage releases a page.  Pages allocated by this transaction can be reused right away.
+func (tx *kvTx) freePage(pageID uint32) {
+	if tx.allocated[pageID] {
+		delete(tx.allocated, pageID)
+		delete(tx.nodes, pageID)
+		delete(tx.dirtyPages, pageID)
+		tx.freePages = append(tx.freePages, pageID)
+		return
+	}
+
+	tx.pendingFree = append(tx.pendingFree, pageID)
+}
+
+func (tx *kvTx) readPage(pageID uint32) ([]byte, error) {
+	if page, found := tx.dirtyPages[pageID]; found {
+		return page, nil
+	}
+
+	if pageID >= tx.kf.header.pageCount {
+		return nil, fmt.Errorf("page %d is beyond the end of the file", pageID)
+	}
+
+	return tx.kf.readPage(pageID)
+}
+
+// isDirty returns true if the transaction has changes to commit
+func (tx *kvTx) isDirty() bool {
+	return tx.rootPage != tx.kf.header.rootPage || len(tx.allocated) > 0 || len(tx.pendingFree) > 0
+}
+
+// commit writes the changed pages and then the header.  The transaction should not be used after commit.
+func (tx *kvTx) commit() error {
+	if !tx.isDirty() {
+		return nil
+	}
+
+	// The pages holding the committed free list are replaced by the new list
+	pendingFree := append(tx.pendingFree, tx.kf.freeListChain...)
+
+	// The free list pages must come from pages that are safe to write now.  Allocating them reduces the pages
+	// left to list, so allocate until the list fits.
+	var listChain []uint32
+	for {
+		listCount := len(tx.freePages) + len(pendingFree)
+		if len(listChain)*kvFreeListPageCapacity >= listCount {
+			break
+		}
+
+		listChain = append(listChain, tx.allocPage())
+	}
+
+	freePages := append(append([]uint32(nil), tx.freePages...), pendingFree...)
+	for chainIndex, pageID := range listChain {
+		page := make([]byte, kvPageSize)
+		page[0] = kvPageTypeFreeList
+		if chainIndex+1 < len(listChain) {
+			binary.BigEndian.PutUint32(page[1:], listChain[chainIndex+1])
+		}
+
+		start := chainIndex * kvFreeListPageCapacity
+		end := start + kvFreeListPageCapacity
+		if end > len(freePages) {
+			end = len(freePages)
+		}
+
+		binary.BigEndian.PutUint16(page[5:], uint16(end-start))
+		for i, freePageID := range freePages[start:end] {
+			binary.BigEndian.PutUint32(page[kvChainHeaderLen+i*4:], freePageID)
+		}
+
+		tx.dirtyPages[pageID] = page
+	}
+
+	for pageID, node := range tx.nodes {
+		page, err := node.encode()
+		if err != nil {
+			return err
+		}
+
+		tx.dirtyPages[pageID] = page
+	}
+
+	for pageID, page := range tx.dirtyPages {
+		err := tx.kf.writePage(pageID, page)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := tx.kf.file.Sync()
+	if err != nil {
+		return fmt.Errorf("unable to sync B-tree pages: %w", err)
+	}
+
+	header := *tx.kf.header
+	header.txID++
+	header.rootPage = tx.rootPage
+	header.pageCount = tx.pageCount
+	header.freeListPage = 0
+	if len(listChain) > 0 {
+		header.freeListPage = listChain[0]
+	}
+
+	err = tx.kf.writePage(uint32(header.txID%kvHeaderSlotCount), header.encode())
+	if err != nil {
+		return fmt.Errorf("unable to write B-tree file header: %w", err)
+	}
+
+	err = tx.kf.file.Sync()
+	if err != nil {
+		return fmt.Errorf("unable to sync B-tree file header: %w", err)
+	}
+
+	tx.kf.header = &header
+	tx.kf.freePages = freePages
+	tx.kf.freeListChain = listChain
+	return nil
+}
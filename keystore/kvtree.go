@@ -0,0 +1,624 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+/*
+	The B-tree maps fixed length keys to values.  Leaf pages hold the keys and values, and branch pages hold
+	the keys that separate their child pages.  A value that is larger than kvMaxInlineValueLen is stored in
+	a chain of overflow pages, and the leaf holds a reference to the first page.
+
+	Leaf page   : type(1) count(2) [key(32) kind(1) length(4) value(length) | firstOverflowPage(4)]...
+	Branch page : type(1) count(2) child(4) [key(32) child(4)]...
+	Overflow    : type(1) nextPage(4) length(2) data(length)
+
+	Deletes remove empty pages, but do not merge partly filled pages.
+*/
+
+const (
+	kvKeyLen            = 32
+	kvMaxInlineValueLen = 1024
+
+	kvLeafEntryHeaderLen = kvKeyLen + 1 + 4
+	kvBranchEntryLen     = kvKeyLen + 4
+	kvNodeHeaderLen      = 1 + 2
+
+	kvValueKindInline   byte = 0
+	kvValueKindOverflow byte = 1
+
+	// kvOverflowDataLen is the number of value bytes held by an overflow page
+	kvOverflowDataLen = kvPageSize - kvChainHeaderLen
+)
+
+// kvKey is a B-tree key.  The first byte is the record kind, so records of a kind are stored together.
+type kvKey [kvKeyLen]byte
+
+func compareKVKeys(a, b kvKey) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+// kvValue is a leaf value, which is either held in the leaf or in a chain of overflow pages
+type kvValue struct {
+	inline       []byte
+	overflowPage uint32
+	length       uint32
+}
+
+func (v kvValue) encodedLen() int {
+	if v.overflowPage != 0 {
+		return kvLeafEntryHeaderLen + 4
+	}
+
+	return kvLeafEntryHeaderLen + len(v.inline)
+}
+
+type kvNode struct {
+	isLeaf   bool
+	keys     []kvKey
+	values   []kvValue
+	children []uint32
+}
+
+func (n *kvNode) encodedLen() int {
+	if !n.isLeaf {
+		return kvNodeHeaderLen + 4 + len(n.keys)*kvBranchEntryLen
+	}
+
+	length := kvNodeHeaderLen
+	for _, value := range n.values {
+		length += value.encodedLen()
+	}
+
+	return length
+}
+
+func (n *kvNode) encode() ([]byte, error) {
+	if n.encodedLen() > kvPageSize {
+		return nil, errors.New("B-tree node is larger than a page")
+	}
+
+	page := make([]byte, kvPageSize)
+	binary.BigEndian.PutUint16(page[1:], uint16(len(n.keys)))
+	offset := kvNodeHeaderLen
+
+	if !n.isLeaf {
+		page[0] = kvPageTypeBranch
+		binary.BigEndian.PutUint32(page[offset:], n.children[0])
+		offset += 4
+		for i, key := range n.keys {
+			offset += copy(page[offset:], key[:])
+			binary.BigEndian.PutUint32(page[offset:], n.children[i+1])
+			offset += 4
+		}
+
+		return page, nil
+	}
+
+	page[0] = kvPageTypeLeaf
+	for i, key := range n.keys {
+		value := n.values[i]
+		offset += copy(page[offset:], key[:])
+		if value.overflowPage != 0 {
+			page[offset] = kvValueKindOverflow
+			binary.BigEndian.PutUint32(page[offset+1:], value.length)
+			binary.BigEndian.PutUint32(page[offset+5:], value.overflowPage)
+			offset += 9
+			continue
+		}
+
+		page[offset] = kvValueKindInline
+		binary.BigEndian.PutUint32(page[offset+1:], uint32(len(value.inline)))
+		offset += 5
+		offset += copy(page[offset:], value.inline)
+	}
+
+	return page, nil
+}
+
+func decodeKVNode(pageID uint32, page []byte) (*kvNode, error) {
+	count := int(binary.BigEndian.Uint16(page[1:]))
+	offset := kvNodeHeaderLen
+
+	switch page[0] {
+	case kvPageTypeBranch:
+		if kvNodeHeaderLen+4+count*kvBranchEntryLen > kvPageSize {
+			return nil, fmt.Errorf("branch page %d has an invalid count", pageID)
+		}
+
+		n := &kvNode{keys: make([]kvKey, count), children: make([]uint32, count+1)}
+		n.children[0] = binary.BigEndian.Uint32(page[offset:])
+		offset += 4
+		for i := 0; i < count; i++ {
+			offset += copy(n.keys[i][:], page[offset:])
+			n.children[i+1] = binary.BigEndian.Uint32(page[offset:])
+			offset += 4
+		}
+
+		return n, nil
+	case kvPageTypeLeaf:
+		n := &kvNode{isLeaf: true, keys: make([]kvKey, count), values: make([]kvValue, count)}
+		for i := 0; i < count; i++ {
+			if offset+kvLeafEntryHeaderLen > kvPageSize {
+				return nil, fmt.Errorf("leaf page %d is truncated", pageID)
+			}
+
+			offset += copy(n.keys[i][:], page[offset:])
+			kind := page[offset]
+			length := binary.BigEndian.Uint32(page[offset+1:])
+			offset += 5
+
+			if kind == kvValueKindOverflow {
+				if offset+4 > kvPageSize {
+					return nil, fmt.Errorf("leaf page %d is truncated", pageID)
+				}
+
+				n.values[i] = kvValue{length: length, overflowPage: binary.BigEndian.Uint32(page[offset:])}
+				offset += 4
+				continue
+			}
+
+			if offset+int(length) > kvPageSize {
+				return nil, fmt.Errorf("leaf page %d is truncated", pageID)
+			}
+
+			n.values[i] = kvValue{inline: append([]byte(nil), page[offset:offset+int(length)]...)}
+			offset += int(length)
+		}
+
+		return n, nil
+	default:
+		return nil, fmt.Errorf("page %d is not a B-tree node", pageID)
+	}
+}
+
+// search returns the index of the first key that is not less than the key, and whether it is the key
+func (n *kvNode) search(key kvKey) (int, bool) {
+	index := sort.Search(len(n.keys), func(i int) bool {
+		return compareKVKeys(n.keys[i], key) >= 0
+	})
+
+	return index, index < len(n.keys) && n.keys[index] == key
+}
+
+// childIndex returns the index of the child of a branch that holds the key
+func (n *kvNode) childIndex(key kvKey) int {
+	return sort.Search(len(n.keys), func(i int) bool {
+		return compareKVKeys(n.keys[i], key) > 0
+	})
+}
+
+func (tx *kvTx) getNode(pageID uint32) (*kvNode, error) {
+	if node, found := tx.nodes[pageID]; found {
+		return node, nil
+	}
+
+	page, err := tx.readPage(pageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeKVNode(pageID, page)
+}
+
+// writableNode returns the node at a page that this transaction has allocated.  A committed node is copied
+// to a new page, and the caller must update the reference to it with the returned page.
+func (tx *kvTx) writableNode(pageID uint32) (uint32, *kvNode, error) {
+	if node, found := tx.nodes[pageID]; found {
+		return pageID, node, nil
+	}
+
+	node, err := tx.getNode(pageID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	tx.freePage(pageID)
+	newPageID := tx.allocPage()
+	tx.nodes[newPageID] = node
+	return newPageID, node, nil
+}
+
+func (tx *kvTx) newNode(isLeaf bool) (uint32, *kvNode) {
+	pageID := tx.allocPage()
+	node := &kvNode{isLeaf: isLeaf}
+	tx.nodes[pageID] = node
+	return pageID, node
+}
+
+// get returns the value for the key, or nil and false if the key is not found
+func (tx *kvTx) get(key kvKey) ([]byte, bool, error) {
+	pageID := tx.rootPage
+	for pageID != 0 {
+		node, err := tx.getNode(pageID)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if !node.isLeaf {
+			pageID = node.children[node.childIndex(key)]
+			continue
+		}
+
+		index, found := node.search(key)
+		if !found {
+			return nil, false, nil
+		}
+
+		value, err := tx.loadValue(node.values[index])
+		return value, err == nil, err
+	}
+
+	return nil, false, nil
+}
+
+// put adds the key or replaces its value
+func (tx *kvTx) put(key kvKey, value []byte) error {
+	storedValue, err := tx.storeValue(value)
+	if err != nil {
+		return err
+	}
+
+	if tx.rootPage == 0 {
+		pageID, root := tx.newNode(true)
+		root.keys = []kvKey{key}
+		root.values = []kvValue{storedValue}
+		tx.rootPage = pageID
+		return nil
+	}
+
+	pageID, split, err := tx.insert(tx.rootPage, key, storedValue)
+	if err != nil {
+		return err
+	}
+
+	if split != nil {
+		leftPageID := pageID
+		var root *kvNode
+		pageID, root = tx.newNode(false)
+		root.keys = []kvKey{split.key}
+		root.children = []uint32{leftPageID, split.pageID}
+	}
+
+	tx.rootPage = pageID
+	return nil
+}
+
+// kvSplit is the new right node of a split, and the key that separates it from the left node
+type kvSplit struct {
+	key    kvKey
+	pageID uint32
+}
+
+func (tx *kvTx) insert(pageID uint32, key kvKey, value kvValue) (uint32, *kvSplit, error) {
+	pageID, node, err := tx.writableNode(pageID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if node.isLeaf {
+		index, found := node.search(key)
+		if found {
+			err = tx.freeValue(node.values[index])
+			if err != nil {
+				return 0, nil, err
+			}
+
+			node.values[index] = value
+		} else {
+			node.keys = append(node.keys, kvKey{})
+			copy(node.keys[index+1:], node.keys[index:])
+			node.keys[index] = key
+
+			node.values = append(node.values, kvValue{})
+			copy(node.values[index+1:], node.values[index:])
+			node.values[index] = value
+		}
+	} else {
+		index := node.childIndex(key)
+		childPageID, childSplit, err := tx.insert(node.children[index], key, value)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		node.children[index] = childPageID
+		if childSplit != nil {
+			node.keys = append(node.keys, kvKey{})
+			copy(node.keys[index+1:], node.keys[index:])
+			node.keys[index] = childSplit.key
+
+			node.children = append(node.children, 0)
+			copy(node.children[index+2:], node.children[index+1:])
+			node.children[index+1] = childSplit.pageID
+		}
+	}
+
+	if node.encodedLen() <= kvPageSize {
+		return pageID, nil, nil
+	}
+
+	return pageID, tx.splitNode(node), nil
+}
+
+// splitNode moves the upper half of the node to a new node
+func (tx *kvTx) splitNode(node *kvNode) *kvSplit {
+	rightPageID, right := tx.newNode(node.isLeaf)
+
+	if !node.isLeaf {
+		middle := len(node.keys) / 2
+		split := &kvSplit{key: node.keys[middle], pageID: rightPageID}
+
+		right.keys = append([]kvKey(nil), node.keys[middle+1:]...)
+		right.children = append([]uint32(nil), node.children[middle+1:]...)
+		node.keys = node.keys[:middle]
+		node.children = node.children[:middle+1]
+		return split
+	}
+
+	// Leaf values have different lengths, so split at the middle of the encoded length
+	half := node.encodedLen() / 2
+	length := kvNodeHeaderLen
+	middle := 0
+	for middle < len(node.values)-1 {
+		length += node.values[middle].encodedLen()
+		if length > half && middle > 0 {
+			break
+		}
+
+		middle++
+	}
+
+	right.keys = append([]kvKey(nil), node.keys[middle:]...)
+	right.values = append([]kvValue(nil), node.values[middle:]...)
+	node.keys = node.keys[:middle]
+	node.values = node.values[:middle]
+	return &kvSplit{key: right.keys[0], pageID: rightPageID}
+}
+
+// delete removes the key.  Returns false if the key was not found.
+func (tx *kvTx) delete(key kvKey) (bool, error) {
+	_, found, err := tx.get(key)
+	if err != nil || !found {
+		return false, err
+	}
+
+	pageID, isEmpty, err := tx.remove(tx.rootPage, key)
+	if err != nil {
+		return false, err
+	}
+
+	if isEmpty {
+		tx.freePage(pageID)
+		tx.rootPage = 0
+		return true, nil
+	}
+
+	// A root branch with one child is replaced by the child
+	for {
+		root, err := tx.getNode(pageID)
+		if err != nil {
+			return false, err
+		}
+
+		if root.isLeaf || len(root.children) > 1 {
+			break
+		}
+
+		tx.freePage(pageID)
+		pageID = root.children[0]
+	}
+
+	tx.rootPage = pageID
+	return true, nil
+}
+
+// remove removes the key, which must exist.  Returns true if the node is empty after the key is removed.
+func (tx *kvTx) remove(pageID uint32, key kvKey) (uint32, bool, error) {
+	pageID, node, err := tx.writableNode(pageID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if node.isLeaf {
+		index, found := node.search(key)
+		if !found {
+			return 0, false, errors.New("B-tree key to remove was not found")
+		}
+
+		err = tx.freeValue(node.values[index])
+		if err != nil {
+			return 0, false, err
+		}
+
+		node.keys = append(node.keys[:index], node.keys[index+1:]...)
+		node.values = append(node.values[:index], node.values[index+1:]...)
+		return pageID, len(node.keys) == 0, nil
+	}
+
+	index := node.childIndex(key)
+	childPageID, childIsEmpty, err := tx.remove(node.children[index], key)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if !childIsEmpty {
+		node.children[index] = childPageID
+		return pageID, false, nil
+	}
+
+	tx.freePage(childPageID)
+	node.children = append(node.children[:index], node.children[index+1:]...)
+	if len(node.keys) > 0 {
+		keyIndex := index
+		if keyIndex > 0 {
+			keyIndex--
+		}
+
+		node.keys = append(node.keys[:keyIndex], node.keys[keyIndex+1:]...)
+	}
+
+	return pageID, len(node.children) == 0, nil
+}
+
+// scan calls scanFunc for each key from the start key, in key order, until scanFunc returns false
+func (tx *kvTx) scan(start kvKey, scanFunc func(key kvKey, value []byte) (bool, error)) error {
+	if tx.rootPage == 0 {
+		return nil
+	}
+
+	_, err := tx.scanNode(tx.rootPage, start, scanFunc)
+	return err
+}
+
+func (tx *kvTx) scanNode(pageID uint32, start kvKey, scanFunc func(key kvKey, value []byte) (bool, error)) (bool, error) {
+	node, err := tx.getNode(pageID)
+	if err != nil {
+		return false, err
+	}
+
+	if !node.isLeaf {
+		for index := node.childIndex(start); index < len(node.children); index++ {
+			shouldContinue, err := tx.scanNode(node.children[index], start, scanFunc)
+			if err != nil || !shouldContinue {
+				return false, err
+			}
+		}
+
+		return true, nil
+	}
+
+	index, _ := node.search(start)
+	for ; index < len(node.keys); index++ {
+		value, err := tx.loadValue(node.values[index])
+		if err != nil {
+			return false, err
+		}
+
+		shouldContinue, err := scanFunc(node.keys[index], value)
+		if err != nil || !shouldContinue {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// storeValue returns the leaf value for the bytes, writing them to overflow pages if needed
+func (tx *kvTx) storeValue(value []byte) (kvValue, error) {
+	if len(value) <= kvMaxInlineValueLen {
+		return kvValue{inline: append([]byte(nil), value...)}, nil
+	}
+
+	if uint64(len(value)) > uint64(^uint32(0)) {
+		return kvValue{}, errors.New("value is too large for a B-tree record")
+	}
+
+	pageCount := (len(value) + kvOverflowDataLen - 1) / kvOverflowDataLen
+	pageIDs := make([]uint32, pageCount)
+	for i := range pageIDs {
+		pageIDs[i] = tx.allocPage()
+	}
+
+	for i, pageID := range pageIDs {
+		start := i * kvOverflowDataLen
+		end := start + kvOverflowDataLen
+		if end > len(value) {
+			end = len(value)
+		}
+
+		page := make([]byte, kvPageSize)
+		page[0] = kvPageTypeOverflow
+		if i+1 < len(pageIDs) {
+			binary.BigEndian.PutUint32(page[1:], pageIDs[i+1])
+		}
+
+		binary.BigEndian.PutUint16(page[5:], uint16(end-start))
+		copy(page[kvChainHeaderLen:], value[start:end])
+		tx.dirtyPages[pageID] = page
+	}
+
+	return kvValue{overflowPage: pageIDs[0], length: uint32(len(value))}, nil
+}
+
+func (tx *kvTx) loadValue(value kvValue) ([]byte, error) {
+	if value.overflowPage == 0 {
+		return append([]byte(nil), value.inline...), nil
+	}
+
+	output := make([]byte, 0, value.length)
+	err := tx.walkOverflowChain(value, func(pageID uint32, page []byte) {
+		length := int(binary.BigEndian.Uint16(page[5:]))
+		output = append(output, page[kvChainHeaderLen:kvChainHeaderLen+length]...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(output) != int(value.length) {
+		return nil, errors.New("overflow value length does not match")
+	}
+
+	return output, nil
+}
+
+// freeValue releases the overflow pages of the value, if it has any
+func (tx *kvTx) freeValue(value kvValue) error {
+	if value.overflowPage == 0 {
+		return nil
+	}
+
+	var pageIDs []uint32
+	err := tx.walkOverflowChain(value, func(pageID uint32, page []byte) {
+		pageIDs = append(pageIDs, pageID)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, pageID := range pageIDs {
+		tx.freePage(pageID)
+	}
+
+	return nil
+}
+
+func (tx *kvTx) walkOverflowChain(value kvValue, walkFunc func(pageID uint32, page []byte)) error {
+	maxPages := (int(value.length) + kvOverflowDataLen - 1) / kvOverflowDataLen
+	pageID := value.overflowPage
+	for pages := 0; pageID != 0; pages++ {
+		if pages >= maxPages {
+			return errors.New("overflow chain is longer than its value")
+		}
+
+		page, err := tx.readPage(pageID)
+		if err != nil {
+			return err
+		}
+
+		if page[0] != kvPageTypeOverflow || int(binary.BigEndian.Uint16(page[5:])) > kvOverflowDataLen {
+			return fmt.Errorf("page %d is not a valid overflow page", pageID)
+		}
+
+		walkFunc(pageID, page)
+		pageID = binary.BigEndian.Uint32(page[1:])
+	}
+
+	return nil
+}
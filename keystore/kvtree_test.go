@@ -0,0 +1,192 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"github.com/stretchr/testify/suite"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type KVTreeTestSuite struct {
+	suite.Suite
+	filePath string
+}
+
+func TestKVTreeTestSuite(t *testing.T) {
+	suite.Run(t, new(KVTreeTestSuite))
+}
+
+func (s *KVTreeTestSuite) SetupTest() {
+	s.filePath = filepath.Join(s.T().TempDir(), "test.kv")
+}
+
+func testKVKey(kind byte, id int) kvKey {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d", id)))
+	key := kvKey{kind}
+	copy(key[1:], sum[:])
+	return key
+}
+
+func testKVValue(rnd *rand.Rand, id int) []byte {
+	// Most values are inline, and some use one or more overflow pages
+	length := 16 + rnd.Intn(600)
+	if rnd.Intn(10) == 0 {
+		length = kvMaxInlineValueLen + rnd.Intn(3*kvPageSize)
+	}
+
+	value := make([]byte, length)
+	rnd.Read(value)
+	copy(value, fmt.Sprintf("value-%d", id))
+	return value
+}
+
+func (s *KVTreeTestSuite) assertContents(kf *kvFile, expected map[kvKey][]byte) {
+	tx := kf.begin()
+	for key, value := range expected {
+		storedValue, found, err := tx.get(key)
+		if !s.Assert().Nil(err) || !s.Assert().True(found) {
+			return
+		}
+
+		s.Assert().True(bytes.Equal(value, storedValue))
+	}
+
+	var lastKey *kvKey
+	count := 0
+	err := tx.scan(kvKey{}, func(key kvKey, value []byte) (bool, error) {
+		if lastKey != nil {
+			s.Assert().Equal(-1, compareKVKeys(*lastKey, key))
+		}
+
+		lastKey = &key
+		count++
+		return true, nil
+	})
+	s.Assert().Nil(err)
+	s.Assert().Equal(len(expected), count)
+}
+
+func (s *KVTreeTestSuite) TestKVTree_PutGetDelete() {
+	kf, err := createKVFile(s.filePath, make([]byte, kvSaltLen))
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	expected := map[kvKey][]byte{}
+	for round := 0; round < 20; round++ {
+		tx := kf.begin()
+		for i := 0; i < 200; i++ {
+			id := rnd.Intn(2000)
+			key := testKVKey('e', id)
+			if rnd.Intn(3) == 0 {
+				found, err := tx.delete(key)
+				s.Require().Nil(err)
+				_, expectedFound := expected[key]
+				s.Assert().Equal(expectedFound, found)
+				delete(expected, key)
+				continue
+			}
+
+			value := testKVValue(rnd, id)
+			s.Require().Nil(tx.put(key, value))
+			expected[key] = value
+		}
+
+		s.Require().Nil(tx.commit())
+		s.assertContents(kf, expected)
+	}
+
+	s.Assert().Nil(kf.close())
+
+	kf, err = openKVFile(s.filePath)
+	if !s.Assert().Nil(err) {
+		return
+	}
+	defer func() {
+		_ = kf.close()
+	}()
+
+	s.assertContents(kf, expected)
+
+	// Remove everything, which leaves an empty tree
+	tx := kf.begin()
+	for key := range expected {
+		found, err := tx.delete(key)
+		s.Require().Nil(err)
+		s.Assert().True(found)
+	}
+
+	s.Require().Nil(tx.commit())
+	s.assertContents(kf, map[kvKey][]byte{})
+	s.Assert().Equal(uint32(0), kf.header.rootPage)
+}
+
+func (s *KVTreeTestSuite) TestKVTree_ReusesFreePages() {
+	kf, err := createKVFile(s.filePath, make([]byte, kvSaltLen))
+	if !s.Assert().Nil(err) {
+		return
+	}
+	defer func() {
+		_ = kf.close()
+	}()
+
+	rnd := rand.New(rand.NewSource(2))
+	for i := 0; i < 500; i++ {
+		tx := kf.begin()
+		s.Require().Nil(tx.put(testKVKey('e', i), testKVValue(rnd, i)))
+		s.Require().Nil(tx.commit())
+	}
+
+	pageCount := kf.header.pageCount
+
+	// Rewriting the same records reuses the pages freed by earlier commits
+	for i := 0; i < 500; i++ {
+		tx := kf.begin()
+		s.Require().Nil(tx.put(testKVKey('e', i), testKVValue(rnd, i)))
+		s.Require().Nil(tx.commit())
+	}
+
+	s.Assert().Less(kf.header.pageCount, pageCount*2)
+}
+
+func (s *KVTreeTestSuite) TestKVTree_UncommittedChangesAreDropped() {
+	kf, err := createKVFile(s.filePath, make([]byte, kvSaltLen))
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	tx := kf.begin()
+	s.Require().Nil(tx.put(testKVKey('e', 1), []byte("committed")))
+	s.Require().Nil(tx.commit())
+
+	tx = kf.begin()
+	s.Require().Nil(tx.put(testKVKey('e', 1), []byte("changed")))
+	s.Require().Nil(tx.put(testKVKey('e', 2), []byte("added")))
+	s.Assert().Nil(kf.close())
+
+	kf, err = openKVFile(s.filePath)
+	if !s.Assert().Nil(err) {
+		return
+	}
+	defer func() {
+		_ = kf.close()
+	}()
+
+	s.assertContents(kf, map[kvKey][]byte{testKVKey('e', 1): []byte("committed")})
+}
+
+func (s *KVTreeTestSuite) TestKVTree_NotKVFile() {
+	s.Require().Nil(os.WriteFile(s.filePath, []byte("not a keystore"), 0600))
+
+	isKV, err := isKVFile(s.filePath)
+	s.Assert().Nil(err)
+	s.Assert().False(isKV)
+
+	_, err = openKVFile(s.filePath)
+	s.Assert().NotNil(err)
+}
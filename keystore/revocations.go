@@ -0,0 +1,162 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystore
+
+import (
+	"errors"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/security"
+	"sort"
+	"time"
+)
+
+// RevocationEntry is a revocation certificate in the local revocation list, with the date it takes effect
+type RevocationEntry struct {
+	Certificate *security.RevocationCertificate
+	// RevokedDate is the certificate's effective date, or the import date if it has none
+	RevokedDate  string
+	ImportedDate string
+}
+
+func (re *RevocationEntry) Clone() *RevocationEntry {
+	return &RevocationEntry{
+		Certificate:  re.Certificate.Clone(),
+		RevokedDate:  re.RevokedDate,
+		ImportedDate: re.ImportedDate,
+	}
+}
+
+// RevocationCollection is keyed by the revoked signing public key
+type RevocationCollection map[string]*RevocationEntry
+
+func (rc RevocationCollection) Clone() RevocationCollection {
+	if rc == nil {
+		return nil
+	}
+
+	rcOutput := RevocationCollection{}
+	for signingPubKey, entry := range rc {
+		rcOutput[signingPubKey] = entry.Clone()
+	}
+
+	return rcOutput
+}
+
+// ApplyRevocation verifies the certificate, adds it to the local revocation list and marks any entity with the
+// revoked keys as revoked.  The certificate is kept even if no entity matches, so the keys are refused if they
+// are added later.  If the keys are already revoked, the earlier revocation is kept.
+func (sks *SimpleKeyStore) ApplyRevocation(certificate *security.RevocationCertificate) (entry *RevocationEntry, name string, err error) {
+	sks.SyncStore.Lock()
+	defer sks.SyncStore.Unlock()
+
+	if certificate == nil {
+		return nil, "", errors.New("no revocation certificate provided")
+	}
+
+	err = certificate.Verify()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if sks.Revocations == nil {
+		sks.Revocations = RevocationCollection{}
+	}
+
+	entry = sks.Revocations[certificate.SigningPubKey]
+	if entry == nil {
+		entry = &RevocationEntry{
+			Certificate:  certificate.Clone(),
+			RevokedDate:  certificate.EffectiveDate,
+			ImportedDate: time.Now().UTC().Format(time.RFC3339),
+		}
+
+		if entry.RevokedDate == "" {
+			entry.RevokedDate = entry.ImportedDate
+		}
+
+		sks.Revocations[certificate.SigningPubKey] = entry
+	}
+
+	for _, entity := range sks.Entities {
+		if sks.applyRevocationList(entity) {
+			name = entity.Name
+		}
+	}
+
+	sks.Details.IsDirty = true
+	return entry.Clone(), name, sks.updateStoreFile()
+}
+
+// GetRevocation returns the revocation list entry for the signing key, or nil if it is not revoked
+func (sks *SimpleKeyStore) GetRevocation(signingPubKey string) *RevocationEntry {
+	sks.SyncStore.Lock()
+	defer sks.SyncStore.Unlock()
+
+	entry := sks.getRevocation(signingPubKey)
+	if entry == nil {
+		return nil
+	}
+
+	return entry.Clone()
+}
+
+func (sks *SimpleKeyStore) getRevocation(signingPubKey string) *RevocationEntry {
+	if sks.Revocations == nil || signingPubKey == "" {
+		return nil
+	}
+
+	return sks.Revocations[signingPubKey]
+}
+
+// ListRevocations returns the local revocation list, sorted by revoked date.  Effective dates come from the
+// certificates, which may be in any zone, so the dates are compared as times.
+func (sks *SimpleKeyStore) ListRevocations() []*RevocationEntry {
+	sks.SyncStore.Lock()
+	defer sks.SyncStore.Unlock()
+
+	entries := make([]*RevocationEntry, 0, len(sks.Revocations))
+	for _, entry := range sks.Revocations {
+		entries = append(entries, entry.Clone())
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return helpers.DateTextBefore(entries[i].RevokedDate, entries[j].RevokedDate)
+	})
+
+	return entries
+}
+
+// applyRevocationList marks the entity as revoked if its signing key is in the revocation list.
+// Returns true if the entity's keys are revoked.
+func (sks *SimpleKeyStore) applyRevocationList(entity *security.Entity) bool {
+	if entity.PublicKeys == nil {
+		return false
+	}
+
+	entry := sks.getRevocation(entity.PublicKeys.SigningPubKey)
+	if entry == nil {
+		return false
+	}
+
+	if entity.Trust != security.TrustLevelRevoked || entity.RevokedDate != entry.RevokedDate {
+		entity.Trust = security.TrustLevelRevoked
+		entity.VerifiedDate = ""
+		entity.VerificationMethod = ""
+		entity.RevokedDate = entry.RevokedDate
+		entity.RevocationReason = entry.Certificate.Reason
+		touchEntity(entity)
+	}
+
+	return true
+}
@@ -29,8 +29,14 @@ type SimpleKeyStore struct {
 	Details        *StoreDetails
 	Server         *ServerInfo
 	Entities       EntityCollection
-	SyncStore      sync.RWMutex `msgpack:"-"`
-	SourceFilePath string       `msgpack:"-"`
+	Revocations    RevocationCollection `msgpack:",omitempty"`
+	Groups         GroupCollection      `msgpack:",omitempty"`
+	SyncStore      sync.RWMutex         `msgpack:"-"`
+	SourceFilePath string               `msgpack:"-"`
+
+	// staged is set for the partial stores that BTreeKeyStore uses to apply a change.  A staged store is never
+	// written to a file.
+	staged bool
 }
 
 // New returns a new SimpleKeyStore with no entities and name, owner and isLocal set accordingly
@@ -75,7 +81,21 @@ func (sks *SimpleKeyStore) AddKey(name, cipherPubKey, signingPubKey string) erro
 		return fmt.Errorf("unable to make new keyInfo: %w", err)
 	}
 
-	err = sks.AddEntity(name, newKeyInfo)
+	err = sks.AddEntityWithDetails(&security.Entity{
+		Name:       name,
+		PublicKeys: newKeyInfo,
+		KeySource:  security.KeySourceManual,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to add new Entity: %w", err)
+	}
+
+	return sks.updateStoreFile()
+}
+
+// AddKeyWithDetails adds the entity, including any contact and key source values, and saves the store
+func (sks *SimpleKeyStore) AddKeyWithDetails(entity *security.Entity) error {
+	err := sks.AddEntityWithDetails(entity)
 	if err != nil {
 		return fmt.Errorf("unable to add new Entity: %w", err)
 	}
@@ -108,6 +128,8 @@ func (sks *SimpleKeyStore) Load(sourceKeyStore *SimpleKeyStore) {
 	sks.Details = sourceKeyStore.Details.Clone()
 	sks.Server = sourceKeyStore.Server.Clone()
 	sks.Entities = sourceKeyStore.Entities.Clone()
+	sks.Revocations = sourceKeyStore.Revocations.Clone()
+	sks.Groups = sourceKeyStore.Groups.Clone()
 	sks.SourceFilePath = sourceKeyStore.SourceFilePath
 }
 
@@ -233,7 +255,16 @@ func (sks *SimpleKeyStore) Walk(walkInfo *WalkInfo) (err error) {
 	return nil
 }
 
+// Close releases the store.  The simple store has no open resources, so there is nothing to release.
+func (sks *SimpleKeyStore) Close() error {
+	return nil
+}
+
 func (sks *SimpleKeyStore) updateStoreFile() error {
+	if sks.staged {
+		return nil
+	}
+
 	// Lower-cased funcs assume the caller has locked the store if needed.
 	// Providing empty values tells WriteToFile to use the corresponding fields of sks.
 	err := sks.WriteToFile("")
@@ -0,0 +1,196 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystore
+
+import (
+	"fmt"
+	"github.com/thoughtrealm/bumblebee/security"
+	"strings"
+)
+
+// UserImportPolicy is how a bulk import handles users that already exist in the keystore
+type UserImportPolicy int
+
+const (
+	// UserImportPolicySkip leaves existing users unchanged
+	UserImportPolicySkip UserImportPolicy = iota
+	// UserImportPolicyMerge adds contact details to existing users with the same keys.  Key changes are conflicts.
+	UserImportPolicyMerge
+	// UserImportPolicyOverwrite replaces the keys and contact details of existing users
+	UserImportPolicyOverwrite
+	UserImportPolicyUnknown
+)
+
+func TextToUserImportPolicy(textName string) UserImportPolicy {
+	switch strings.ToUpper(strings.Trim(textName, " \t\n\r")) {
+	case "SKIP":
+		return UserImportPolicySkip
+	case "MERGE":
+		return UserImportPolicyMerge
+	case "OVERWRITE":
+		return UserImportPolicyOverwrite
+	default:
+		return UserImportPolicyUnknown
+	}
+}
+
+// UserImportAction is the planned outcome for one record of a bulk import
+type UserImportAction int
+
+const (
+	UserImportActionAdd UserImportAction = iota
+	UserImportActionMerge
+	UserImportActionOverwrite
+	UserImportActionUnchanged
+	UserImportActionSkip
+	UserImportActionInvalid
+	UserImportActionDuplicate
+	UserImportActionConflict
+)
+
+func UserImportActionToText(action UserImportAction) string {
+	switch action {
+	case UserImportActionAdd:
+		return "ADD"
+	case UserImportActionMerge:
+		return "MERGE"
+	case UserImportActionOverwrite:
+		return "OVERWRITE"
+	case UserImportActionUnchanged:
+		return "UNCHANGED"
+	case UserImportActionSkip:
+		return "SKIP"
+	case UserImportActionInvalid:
+		return "INVALID"
+	case UserImportActionDuplicate:
+		return "DUPLICATE"
+	case UserImportActionConflict:
+		return "CONFLICT"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// IsError returns true for actions that are caused by problems in the import data
+func (action UserImportAction) IsError() bool {
+	return action == UserImportActionInvalid ||
+		action == UserImportActionDuplicate ||
+		action == UserImportActionConflict
+}
+
+// UserImportItem is the planned outcome for one record of a bulk import
+type UserImportItem struct {
+	Record *UserRecord
+	Action UserImportAction
+	Reason string
+
+	// Existing is the stored user with the same name, if there is one
+	Existing *security.Entity
+
+	// KeysChanged is set for overwrites that replace the stored public keys
+	KeysChanged bool
+
+	// Contact is the contact details to store for merges and overwrites
+	Contact *security.ContactInfo
+}
+
+// PlanUserImport validates each record and decides how it is imported, without changing the keystore.
+// Records with the same name or signing key as an earlier record are duplicates.  Records whose keys are
+// stored under another user's name are conflicts, so that one identity is not stored under two names.
+func PlanUserImport(ks KeyStore, records []*UserRecord, policy UserImportPolicy) []*UserImportItem {
+	items := make([]*UserImportItem, 0, len(records))
+	namesSeen := map[string]*UserRecord{}
+	keysSeen := map[string]*UserRecord{}
+
+	for _, record := range records {
+		item := &UserImportItem{Record: record}
+		items = append(items, item)
+
+		err := record.Validate()
+		if err != nil {
+			item.Action = UserImportActionInvalid
+			item.Reason = err.Error()
+			continue
+		}
+
+		if prior, found := namesSeen[strings.ToUpper(record.Name)]; found {
+			item.Action = UserImportActionDuplicate
+			item.Reason = fmt.Sprintf("same name as line %d", prior.Line)
+			continue
+		}
+
+		if prior, found := keysSeen[record.SigningPubKey]; found {
+			item.Action = UserImportActionDuplicate
+			item.Reason = fmt.Sprintf("same keys as \"%s\" on line %d", prior.Name, prior.Line)
+			continue
+		}
+
+		namesSeen[strings.ToUpper(record.Name)] = record
+		keysSeen[record.SigningPubKey] = record
+		planUserImportItem(ks, item, policy)
+	}
+
+	return items
+}
+
+func planUserImportItem(ks KeyStore, item *UserImportItem, policy UserImportPolicy) {
+	record := item.Record
+	item.Existing = ks.GetKey(record.Name)
+
+	keyOwner := ks.GetKeyBySigningPubKey(record.SigningPubKey)
+	if keyOwner != nil && (item.Existing == nil || !strings.EqualFold(keyOwner.Name, item.Existing.Name)) {
+		item.Action = UserImportActionConflict
+		item.Reason = fmt.Sprintf("keys already belong to user \"%s\"", keyOwner.Name)
+		return
+	}
+
+	if item.Existing == nil {
+		item.Action = UserImportActionAdd
+		return
+	}
+
+	item.KeysChanged = item.Existing.PublicKeys.CipherPubKey != record.CipherPubKey ||
+		item.Existing.PublicKeys.SigningPubKey != record.SigningPubKey
+
+	switch policy {
+	case UserImportPolicyMerge:
+		if item.KeysChanged {
+			item.Action = UserImportActionConflict
+			item.Reason = "public keys differ from the stored keys, use the overwrite policy to replace them"
+			return
+		}
+
+		item.Contact = item.Existing.Contact.Merge(record.Contact())
+		if item.Contact.Equal(item.Existing.Contact) {
+			item.Action = UserImportActionUnchanged
+			return
+		}
+
+		item.Action = UserImportActionMerge
+	case UserImportPolicyOverwrite:
+		item.Contact = record.Contact()
+		if !item.KeysChanged && item.Contact.Equal(item.Existing.Contact) {
+			item.Action = UserImportActionUnchanged
+			return
+		}
+
+		item.Action = UserImportActionOverwrite
+		if item.KeysChanged {
+			item.Reason = "public keys change"
+		}
+	default:
+		item.Action = UserImportActionSkip
+		item.Reason = "user already exists"
+	}
+}
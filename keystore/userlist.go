@@ -0,0 +1,437 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystore
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/thoughtrealm/bumblebee/security"
+	"gopkg.in/yaml.v3"
+	"io"
+	"strings"
+)
+
+// UserListFormat is the file format for bulk user imports and exports
+type UserListFormat int
+
+const (
+	UserListFormatUnknown UserListFormat = iota
+	UserListFormatCSV
+	UserListFormatJSON
+	UserListFormatYAML
+
+	// UserListFormatAuthorizedKeys is an OpenSSH authorized_keys file.  It is only supported for imports.
+	UserListFormatAuthorizedKeys
+
+	// UserListFormatJWKS is a JSON Web Key Set, with an Ed25519 and an X25519 key for each user.  Contact
+	// details are not included.
+	UserListFormatJWKS
+
+	// UserListFormatVCard is a vCard file, with the keys in X-BUMBLEBEE-* properties
+	UserListFormatVCard
+)
+
+func TextToUserListFormat(textName string) UserListFormat {
+	switch strings.ToUpper(strings.Trim(textName, " \t\n\r")) {
+	case "CSV":
+		return UserListFormatCSV
+	case "JSON":
+		return UserListFormatJSON
+	case "YAML", "YML":
+		return UserListFormatYAML
+	case "AUTHORIZED_KEYS", "AUTHORIZED-KEYS", "SSH":
+		return UserListFormatAuthorizedKeys
+	case "JWKS", "JWK":
+		return UserListFormatJWKS
+	case "VCARD", "VCF":
+		return UserListFormatVCard
+	default:
+		return UserListFormatUnknown
+	}
+}
+
+// UserListFormatExt returns the default file extension for the format, including the period
+func UserListFormatExt(format UserListFormat) string {
+	switch format {
+	case UserListFormatCSV:
+		return ".csv"
+	case UserListFormatYAML:
+		return ".yaml"
+	case UserListFormatJWKS:
+		return ".jwks.json"
+	case UserListFormatVCard:
+		return ".vcf"
+	default:
+		return ".json"
+	}
+}
+
+// userListCSVColumns are the CSV header names.  Emails and tags are separated by semicolons within their column.
+var userListCSVColumns = []string{
+	"name", "cipher_pub_key", "signing_pub_key", "fingerprint",
+	"display_name", "emails", "organization", "tags", "notes",
+}
+
+// UserRecord is one user in a bulk import or export file.  The fingerprint is optional on import.  If it is
+// provided, it must match the public keys, which catches keys that were mangled while editing the file.
+type UserRecord struct {
+	Name          string   `json:"name" yaml:"name"`
+	CipherPubKey  string   `json:"cipher_pub_key" yaml:"cipher_pub_key"`
+	SigningPubKey string   `json:"signing_pub_key" yaml:"signing_pub_key"`
+	Fingerprint   string   `json:"fingerprint,omitempty" yaml:"fingerprint,omitempty"`
+	DisplayName   string   `json:"display_name,omitempty" yaml:"display_name,omitempty"`
+	Emails        []string `json:"emails,omitempty" yaml:"emails,omitempty"`
+	Organization  string   `json:"organization,omitempty" yaml:"organization,omitempty"`
+	Tags          []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Notes         string   `json:"notes,omitempty" yaml:"notes,omitempty"`
+
+	// Line is the line or item number in the import file, for reports.  It is not exported.
+	Line int `json:"-" yaml:"-"`
+
+	// KeySource and KeySourceDetails are set for records whose keys were derived from another key format, such
+	// as SSH keys.  They are not exported.
+	KeySource        security.KeySource `json:"-" yaml:"-"`
+	KeySourceDetails string             `json:"-" yaml:"-"`
+
+	// parseErr is set for lines that could not be read, so they are reported with the other invalid records
+	parseErr error
+}
+
+// userListDocument is the JSON and YAML document layout
+type userListDocument struct {
+	Users []*UserRecord `json:"users" yaml:"users"`
+}
+
+func NewUserRecordFromEntity(entity *security.Entity) *UserRecord {
+	record := &UserRecord{
+		Name:          entity.Name,
+		CipherPubKey:  entity.PublicKeys.CipherPubKey,
+		SigningPubKey: entity.PublicKeys.SigningPubKey,
+		Fingerprint:   entity.PublicKeys.Fingerprint().Hex(),
+	}
+
+	if entity.Contact != nil {
+		record.DisplayName = entity.Contact.DisplayName
+		record.Emails = append(record.Emails, entity.Contact.Emails...)
+		record.Organization = entity.Contact.Organization
+		record.Tags = append(record.Tags, entity.Contact.Tags...)
+		record.Notes = entity.Contact.Notes
+	}
+
+	return record
+}
+
+// Validate checks the name, the public keys and the fingerprint, if one is provided
+func (ur *UserRecord) Validate() error {
+	if ur.parseErr != nil {
+		return ur.parseErr
+	}
+
+	if strings.TrimSpace(ur.Name) == "" {
+		return errors.New("name is empty")
+	}
+
+	if IsGroupReference(ur.Name) {
+		return fmt.Errorf("name can not start with \"%s\"", GroupPrefix)
+	}
+
+	err := security.ValidatePublicKeys(ur.CipherPubKey, ur.SigningPubKey)
+	if err != nil {
+		return err
+	}
+
+	if ur.Fingerprint != "" {
+		fp, err := security.ParseFingerprint(ur.Fingerprint)
+		if err != nil {
+			return err
+		}
+
+		if !fp.Equal(security.NewFingerprint(ur.CipherPubKey, ur.SigningPubKey)) {
+			return errors.New("fingerprint does not match the public keys")
+		}
+	}
+
+	return nil
+}
+
+// Contact returns the record's contact details, or nil if there are none
+func (ur *UserRecord) Contact() *security.ContactInfo {
+	contact := &security.ContactInfo{
+		DisplayName:  ur.DisplayName,
+		Emails:       append([]string{}, ur.Emails...),
+		Organization: ur.Organization,
+		Notes:        ur.Notes,
+	}
+	contact.AddTags(ur.Tags)
+
+	if contact.IsEmpty() {
+		return nil
+	}
+
+	if len(contact.Emails) == 0 {
+		contact.Emails = nil
+	}
+
+	return contact
+}
+
+// Entity returns a new entity for the record
+func (ur *UserRecord) Entity() *security.Entity {
+	return &security.Entity{
+		Name: ur.Name,
+		PublicKeys: &security.KeyInfo{
+			Name:          ur.Name,
+			CipherPubKey:  ur.CipherPubKey,
+			SigningPubKey: ur.SigningPubKey,
+		},
+		Contact: ur.Contact(),
+	}
+}
+
+// EncodeUserRecords writes the records in the requested format
+func EncodeUserRecords(records []*UserRecord, format UserListFormat) ([]byte, error) {
+	switch format {
+	case UserListFormatCSV:
+		return encodeUserRecordsCSV(records)
+	case UserListFormatJSON:
+		data, err := json.MarshalIndent(&userListDocument{Users: records}, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("unable to encode users as JSON: %w", err)
+		}
+
+		return append(data, '\n'), nil
+	case UserListFormatYAML:
+		data, err := yaml.Marshal(&userListDocument{Users: records})
+		if err != nil {
+			return nil, fmt.Errorf("unable to encode users as YAML: %w", err)
+		}
+
+		return data, nil
+	case UserListFormatJWKS:
+		kis := make([]*security.KeyInfo, 0, len(records))
+		for _, record := range records {
+			kis = append(kis, record.Entity().PublicKeys)
+		}
+
+		set, err := security.NewJWKSetFromKeyInfos(kis)
+		if err != nil {
+			return nil, err
+		}
+
+		return set.Marshal()
+	case UserListFormatVCard:
+		return encodeUserRecordsVCard(records), nil
+	case UserListFormatAuthorizedKeys:
+		return nil, errors.New("user lists can not be exported as authorized_keys files")
+	default:
+		return nil, errors.New("unknown user list format")
+	}
+}
+
+func encodeUserRecordsCSV(records []*UserRecord) ([]byte, error) {
+	buff := bytes.NewBuffer(nil)
+	writer := csv.NewWriter(buff)
+
+	err := writer.Write(userListCSVColumns)
+	if err != nil {
+		return nil, fmt.Errorf("unable to write CSV header: %w", err)
+	}
+
+	for _, record := range records {
+		err = writer.Write([]string{
+			record.Name,
+			record.CipherPubKey,
+			record.SigningPubKey,
+			record.Fingerprint,
+			record.DisplayName,
+			strings.Join(record.Emails, ";"),
+			record.Organization,
+			strings.Join(record.Tags, ";"),
+			record.Notes,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to write CSV row for user \"%s\": %w", record.Name, err)
+		}
+	}
+
+	writer.Flush()
+	if err = writer.Error(); err != nil {
+		return nil, fmt.Errorf("unable to write CSV data: %w", err)
+	}
+
+	return buff.Bytes(), nil
+}
+
+// DecodeUserRecords reads the records from data in the requested format.  Each record's Line is set to its CSV
+// line number, or its position in the JSON or YAML user list.  Records are not validated.
+func DecodeUserRecords(data []byte, format UserListFormat) ([]*UserRecord, error) {
+	var records []*UserRecord
+	switch format {
+	case UserListFormatCSV:
+		return decodeUserRecordsCSV(data)
+	case UserListFormatAuthorizedKeys:
+		return decodeUserRecordsAuthorizedKeys(data), nil
+	case UserListFormatJWKS:
+		return decodeUserRecordsJWKS(data)
+	case UserListFormatVCard:
+		return decodeUserRecordsVCard(data)
+	case UserListFormatJSON:
+		if security.IsJWKData(data) {
+			return decodeUserRecordsJWKS(data)
+		}
+
+		document := &userListDocument{}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err := decoder.Decode(document)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode JSON user list: %w", err)
+		}
+
+		records = document.Users
+	case UserListFormatYAML:
+		document := &userListDocument{}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err := decoder.Decode(document)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("unable to decode YAML user list: %w", err)
+		}
+
+		records = document.Users
+	default:
+		return nil, errors.New("unknown user list format")
+	}
+
+	for i, record := range records {
+		if record == nil {
+			return nil, fmt.Errorf("user %d is empty", i+1)
+		}
+
+		record.Line = i + 1
+	}
+
+	return records, nil
+}
+
+func decodeUserRecordsJWKS(data []byte) ([]*UserRecord, error) {
+	kis, err := security.ParseJWKData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*UserRecord, 0, len(kis))
+	for i, ki := range kis {
+		records = append(records, &UserRecord{
+			Name:          ki.Name,
+			CipherPubKey:  ki.CipherPubKey,
+			SigningPubKey: ki.SigningPubKey,
+			Line:          i + 1,
+		})
+	}
+
+	return records, nil
+}
+
+func decodeUserRecordsCSV(data []byte) ([]*UserRecord, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CSV header: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, columnName := range header {
+		columnName = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(columnName, "\ufeff")))
+		if !isUserListCSVColumn(columnName) {
+			return nil, fmt.Errorf("unknown CSV column \"%s\"", columnName)
+		}
+
+		columns[columnName] = i
+	}
+
+	for _, requiredColumn := range []string{"name", "cipher_pub_key", "signing_pub_key"} {
+		if _, found := columns[requiredColumn]; !found {
+			return nil, fmt.Errorf("CSV header is missing the \"%s\" column", requiredColumn)
+		}
+	}
+
+	var records []*UserRecord
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CSV data: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		value := func(columnName string) string {
+			i, found := columns[columnName]
+			if !found || i >= len(row) {
+				return ""
+			}
+
+			return strings.TrimSpace(row[i])
+		}
+
+		records = append(records, &UserRecord{
+			Name:          value("name"),
+			CipherPubKey:  value("cipher_pub_key"),
+			SigningPubKey: value("signing_pub_key"),
+			Fingerprint:   value("fingerprint"),
+			DisplayName:   value("display_name"),
+			Emails:        splitUserListValues(value("emails")),
+			Organization:  value("organization"),
+			Tags:          splitUserListValues(value("tags")),
+			Notes:         value("notes"),
+			Line:          line,
+		})
+	}
+
+	return records, nil
+}
+
+func isUserListCSVColumn(columnName string) bool {
+	for _, knownColumn := range userListCSVColumns {
+		if columnName == knownColumn {
+			return true
+		}
+	}
+
+	return false
+}
+
+func splitUserListValues(text string) []string {
+	var values []string
+	for _, value := range strings.Split(text, ";") {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
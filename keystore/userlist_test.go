@@ -0,0 +1,289 @@
+package keystore
+
+import (
+	"github.com/stretchr/testify/suite"
+	"github.com/thoughtrealm/bumblebee/security"
+	"strings"
+	"testing"
+)
+
+type UserListTestSuite struct {
+	suite.Suite
+	testStore *SimpleKeyStore
+}
+
+func TestUserListTestSuite(t *testing.T) {
+	suite.Run(t, new(UserListTestSuite))
+}
+
+func (s *UserListTestSuite) SetupTest() {
+	s.testStore = buildTestStore()
+	s.testStore.Entities["BOB"].Contact = &security.ContactInfo{
+		DisplayName: "Bob",
+		Emails:      []string{"bob@example.com"},
+	}
+}
+
+func newTestUserRecord(name string) *UserRecord {
+	kpi, _ := security.NewKeyPairInfoWithSeeds(name)
+	cipherPubKey, signingPubKey, _ := kpi.PublicKeys()
+	return &UserRecord{
+		Name:          name,
+		CipherPubKey:  cipherPubKey,
+		SigningPubKey: signingPubKey,
+	}
+}
+
+func (s *UserListTestSuite) TestEncodeDecode_AllFormats() {
+	records := []*UserRecord{
+		NewUserRecordFromEntity(s.testStore.GetKey("bob")),
+		newTestUserRecord("alice"),
+	}
+	records[1].Emails = []string{"alice@example.com", "a@example.com"}
+	records[1].Tags = []string{"team"}
+	records[1].Notes = "notes, with a comma"
+
+	for _, format := range []UserListFormat{UserListFormatCSV, UserListFormatJSON, UserListFormatYAML} {
+		data, err := EncodeUserRecords(records, format)
+		if !s.Assert().Nil(err) {
+			return
+		}
+
+		decoded, err := DecodeUserRecords(data, format)
+		if !s.Assert().Nil(err) || !s.Assert().Len(decoded, 2) {
+			return
+		}
+
+		for i := range records {
+			s.Assert().Equal(records[i].Name, decoded[i].Name)
+			s.Assert().Equal(records[i].CipherPubKey, decoded[i].CipherPubKey)
+			s.Assert().Equal(records[i].SigningPubKey, decoded[i].SigningPubKey)
+			s.Assert().Equal(records[i].Fingerprint, decoded[i].Fingerprint)
+			s.Assert().Equal(records[i].Emails, decoded[i].Emails)
+			s.Assert().Equal(records[i].Tags, decoded[i].Tags)
+			s.Assert().Equal(records[i].Notes, decoded[i].Notes)
+			s.Assert().Nil(decoded[i].Validate())
+		}
+	}
+}
+
+func (s *UserListTestSuite) TestDecodeCSV_Errors() {
+	_, err := DecodeUserRecords([]byte("name,cipher_pub_key\nbob,abc\n"), UserListFormatCSV)
+	s.Assert().NotNil(err)
+
+	_, err = DecodeUserRecords([]byte("name,cipher_pub_key,signing_pub_key,color\n"), UserListFormatCSV)
+	s.Assert().NotNil(err)
+
+	records, err := DecodeUserRecords([]byte("\ufeffsigning_pub_key,name,cipher_pub_key\nS,bob,C\n"), UserListFormatCSV)
+	if s.Assert().Nil(err) && s.Assert().Len(records, 1) {
+		s.Assert().Equal("bob", records[0].Name)
+		s.Assert().Equal("S", records[0].SigningPubKey)
+		s.Assert().Equal(2, records[0].Line)
+	}
+}
+
+func (s *UserListTestSuite) TestValidate() {
+	record := newTestUserRecord("alice")
+	s.Assert().Nil(record.Validate())
+
+	swapped := *record
+	swapped.CipherPubKey, swapped.SigningPubKey = record.SigningPubKey, record.CipherPubKey
+	s.Assert().NotNil(swapped.Validate())
+
+	badFingerprint := *record
+	badFingerprint.Fingerprint = NewUserRecordFromEntity(s.testStore.GetKey("bob")).Fingerprint
+	s.Assert().NotNil(badFingerprint.Validate())
+
+	groupName := *record
+	groupName.Name = GroupPrefix + "team"
+	s.Assert().NotNil(groupName.Validate())
+}
+
+func (s *UserListTestSuite) TestPlanUserImport_DuplicatesAndConflicts() {
+	bob := NewUserRecordFromEntity(s.testStore.GetKey("bob"))
+	alice := newTestUserRecord("alice")
+	aliceAgain := newTestUserRecord("ALICE")
+	bobKeysAsCarol := *bob
+	bobKeysAsCarol.Name = "carol"
+	dave := newTestUserRecord("dave")
+	daveKeysAsEve := *dave
+	daveKeysAsEve.Name = "eve"
+	invalid := &UserRecord{Name: "frank", CipherPubKey: "bad", SigningPubKey: "bad"}
+
+	records := []*UserRecord{alice, aliceAgain, &bobKeysAsCarol, dave, &daveKeysAsEve, invalid}
+	for i, record := range records {
+		record.Line = i + 2
+	}
+
+	items := PlanUserImport(s.testStore, records, UserImportPolicySkip)
+	if !s.Assert().Len(items, len(records)) {
+		return
+	}
+
+	s.Assert().Equal(UserImportActionAdd, items[0].Action)
+	s.Assert().Equal(UserImportActionDuplicate, items[1].Action)
+	s.Assert().Equal(UserImportActionConflict, items[2].Action)
+	s.Assert().Equal(UserImportActionAdd, items[3].Action)
+	s.Assert().Equal(UserImportActionDuplicate, items[4].Action)
+	s.Assert().Equal(UserImportActionInvalid, items[5].Action)
+
+	// Planning must not change the store
+	s.Assert().Nil(s.testStore.GetKey("alice"))
+
+	items = PlanUserImport(s.testStore, []*UserRecord{bob}, UserImportPolicySkip)
+	s.Assert().Equal(UserImportActionSkip, items[0].Action)
+}
+
+func (s *UserListTestSuite) TestPlanUserImport_Policies() {
+	bob := NewUserRecordFromEntity(s.testStore.GetKey("bob"))
+	bob.Emails = []string{"BOB@example.com", "bob@work.example.com"}
+
+	items := PlanUserImport(s.testStore, []*UserRecord{bob}, UserImportPolicyMerge)
+	s.Assert().Equal(UserImportActionMerge, items[0].Action)
+	s.Assert().Equal([]string{"bob@example.com", "bob@work.example.com"}, items[0].Contact.Emails)
+	s.Assert().Equal("Bob", items[0].Contact.DisplayName)
+
+	unchanged := NewUserRecordFromEntity(s.testStore.GetKey("bob"))
+	items = PlanUserImport(s.testStore, []*UserRecord{unchanged}, UserImportPolicyMerge)
+	s.Assert().Equal(UserImportActionUnchanged, items[0].Action)
+
+	rekeyed := newTestUserRecord("bob")
+	items = PlanUserImport(s.testStore, []*UserRecord{rekeyed}, UserImportPolicyMerge)
+	s.Assert().Equal(UserImportActionConflict, items[0].Action)
+	s.Assert().True(strings.Contains(items[0].Reason, "overwrite"))
+
+	items = PlanUserImport(s.testStore, []*UserRecord{rekeyed}, UserImportPolicyOverwrite)
+	s.Assert().Equal(UserImportActionOverwrite, items[0].Action)
+	s.Assert().True(items[0].KeysChanged)
+	s.Assert().Nil(items[0].Contact)
+
+	items = PlanUserImport(s.testStore, []*UserRecord{unchanged}, UserImportPolicyOverwrite)
+	s.Assert().Equal(UserImportActionUnchanged, items[0].Action)
+}
+
+func (s *UserListTestSuite) TestDecodeAuthorizedKeys() {
+	data := []byte(`# team keys
+ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIDLjjoOuSPUdkOGzi9wWZnI4slrNJUlCXOjsGHSqoZNe alice@laptop
+
+ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQ bob@host
+no-pty ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIDLjjoOuSPUdkOGzi9wWZnI4slrNJUlCXOjsGHSqoZNe
+`)
+
+	records, err := DecodeUserRecords(data, UserListFormatAuthorizedKeys)
+	if !s.Assert().Nil(err) || !s.Assert().Len(records, 3) {
+		return
+	}
+
+	s.Assert().Equal("alice", records[0].Name)
+	s.Assert().Equal(2, records[0].Line)
+	s.Assert().Equal(security.KeySourceSSH, records[0].KeySource)
+	s.Assert().True(strings.Contains(records[0].KeySourceDetails, "SHA256:aXqnW/WU+og3sE1kkcmvMBElKPEyGRXd6rGNazkc+EE"))
+	s.Assert().Nil(records[0].Validate())
+
+	s.Assert().NotNil(records[1].Validate())
+	s.Assert().Equal("ssh-user-5", records[2].Name)
+
+	items := PlanUserImport(s.testStore, records, UserImportPolicySkip)
+	s.Assert().Equal(UserImportActionAdd, items[0].Action)
+	s.Assert().Equal(UserImportActionInvalid, items[1].Action)
+	s.Assert().Equal(UserImportActionDuplicate, items[2].Action)
+
+	_, err = EncodeUserRecords(records, UserListFormatAuthorizedKeys)
+	s.Assert().NotNil(err)
+}
+
+func (s *UserListTestSuite) TestSSHCommentToUserName() {
+	s.Assert().Equal("alice", SSHCommentToUserName("alice@laptop", 1))
+	s.Assert().Equal("bob.smith", SSHCommentToUserName(" bob.smith (work) ", 1))
+	s.Assert().Equal("ssh-user-3", SSHCommentToUserName("", 3))
+}
+
+func (s *UserListTestSuite) TestEncodeDecode_JWKS() {
+	records := []*UserRecord{NewUserRecordFromEntity(s.testStore.GetKey("bob")), newTestUserRecord("alice")}
+
+	data, err := EncodeUserRecords(records, UserListFormatJWKS)
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	// JWK sets are also detected when read as JSON, since they share the file extension
+	for _, format := range []UserListFormat{UserListFormatJWKS, UserListFormatJSON} {
+		decoded, err := DecodeUserRecords(data, format)
+		if !s.Assert().Nil(err) || !s.Assert().Len(decoded, 2) {
+			return
+		}
+
+		for i := range records {
+			s.Assert().Equal(records[i].Name, decoded[i].Name)
+			s.Assert().Equal(records[i].CipherPubKey, decoded[i].CipherPubKey)
+			s.Assert().Equal(records[i].SigningPubKey, decoded[i].SigningPubKey)
+			s.Assert().Nil(decoded[i].Validate())
+		}
+	}
+}
+
+func (s *UserListTestSuite) TestEncodeDecode_VCard() {
+	records := []*UserRecord{NewUserRecordFromEntity(s.testStore.GetKey("bob")), newTestUserRecord("alice")}
+	records[1].DisplayName = "Alice Smith, Ph.D."
+	records[1].Emails = []string{"alice@example.com"}
+	records[1].Organization = "Example; Inc"
+	records[1].Tags = []string{"team", "a,b"}
+	records[1].Notes = "line one\nline two"
+
+	data, err := EncodeUserRecords(records, UserListFormatVCard)
+	if !s.Assert().Nil(err) {
+		return
+	}
+
+	for _, line := range strings.Split(string(data), "\r\n") {
+		s.Assert().LessOrEqual(len(line), vCardMaxLineOctets)
+	}
+
+	decoded, err := DecodeUserRecords(data, UserListFormatVCard)
+	if !s.Assert().Nil(err) || !s.Assert().Len(decoded, 2) {
+		return
+	}
+
+	for i := range records {
+		s.Assert().Equal(records[i].Name, decoded[i].Name)
+		s.Assert().Equal(records[i].DisplayName, decoded[i].DisplayName)
+		s.Assert().Equal(records[i].CipherPubKey, decoded[i].CipherPubKey)
+		s.Assert().Equal(records[i].SigningPubKey, decoded[i].SigningPubKey)
+		s.Assert().Equal(records[i].Emails, decoded[i].Emails)
+		s.Assert().Equal(records[i].Organization, decoded[i].Organization)
+		s.Assert().Equal(records[i].Tags, decoded[i].Tags)
+		s.Assert().Equal(records[i].Notes, decoded[i].Notes)
+		s.Assert().Nil(decoded[i].Validate())
+	}
+}
+
+func (s *UserListTestSuite) TestDecodeVCard_AddressBookCards() {
+	record := newTestUserRecord("carol")
+	data := "BEGIN:VCARD\nVERSION:3.0\nFN:Not A User\nEND:VCARD\n" +
+		"BEGIN:VCARD\nVERSION:3.0\nitem1.FN;CHARSET=UTF-8:Carol\n" +
+		"EMAIL;TYPE=\"work,internet\":mailto:carol@example.com\nORG:Example;Sales\n" +
+		"X-BUMBLEBEE-CIPHER-KEY:" + record.CipherPubKey[:20] + "\n " + record.CipherPubKey[20:] + "\n" +
+		"x-bumblebee-signing-key:" + record.SigningPubKey + "\nEND:VCARD\n" +
+		"BEGIN:VCARD\nFN:dave\nX-BUMBLEBEE-NAME:dave\nEND:VCARD\n"
+
+	decoded, err := DecodeUserRecords([]byte(data), UserListFormatVCard)
+	if !s.Assert().Nil(err) || !s.Assert().Len(decoded, 2) {
+		return
+	}
+
+	// Without X-BUMBLEBEE-NAME, the FN is the user name
+	s.Assert().Equal("Carol", decoded[0].Name)
+	s.Assert().Equal("", decoded[0].DisplayName)
+	s.Assert().Equal(5, decoded[0].Line)
+	s.Assert().Equal(record.CipherPubKey, decoded[0].CipherPubKey)
+	s.Assert().Equal(record.SigningPubKey, decoded[0].SigningPubKey)
+	s.Assert().Equal([]string{"carol@example.com"}, decoded[0].Emails)
+	s.Assert().Equal("Example", decoded[0].Organization)
+	s.Assert().Nil(decoded[0].Validate())
+
+	s.Assert().Equal("dave", decoded[1].Name)
+	s.Assert().NotNil(decoded[1].Validate())
+
+	_, err = DecodeUserRecords([]byte("BEGIN:VCARD\nFN:x\n"), UserListFormatVCard)
+	s.Assert().NotNil(err)
+}
@@ -0,0 +1,292 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystore
+
+// vCard files follow RFC 6350.  The user name, keys and fingerprint are written in X-BUMBLEBEE-* properties, which
+// address books keep as they are.  The display name, emails, organization, notes and tags use the standard FN,
+// EMAIL, ORG, NOTE and CATEGORIES properties.
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	vCardPropertyName          = "X-BUMBLEBEE-NAME"
+	vCardPropertyCipherKey     = "X-BUMBLEBEE-CIPHER-KEY"
+	vCardPropertySigningKey    = "X-BUMBLEBEE-SIGNING-KEY"
+	vCardPropertyFingerprint   = "X-BUMBLEBEE-FINGERPRINT"
+	vCardPropertyPrefix        = "X-BUMBLEBEE-"
+	vCardMaxLineOctets         = 75
+	vCardLineBreak             = "\r\n"
+	vCardContinuationIndicator = " "
+)
+
+func encodeUserRecordsVCard(records []*UserRecord) []byte {
+	buff := bytes.NewBuffer(nil)
+	for _, record := range records {
+		writeVCardLine(buff, "BEGIN:VCARD")
+		writeVCardLine(buff, "VERSION:4.0")
+
+		displayName := record.DisplayName
+		if displayName == "" {
+			displayName = record.Name
+		}
+		writeVCardLine(buff, "FN:"+escapeVCardText(displayName))
+
+		for _, email := range record.Emails {
+			writeVCardLine(buff, "EMAIL:"+escapeVCardText(email))
+		}
+
+		if record.Organization != "" {
+			writeVCardLine(buff, "ORG:"+escapeVCardText(record.Organization))
+		}
+
+		if len(record.Tags) > 0 {
+			tags := make([]string, 0, len(record.Tags))
+			for _, tag := range record.Tags {
+				tags = append(tags, escapeVCardText(tag))
+			}
+			writeVCardLine(buff, "CATEGORIES:"+strings.Join(tags, ","))
+		}
+
+		if record.Notes != "" {
+			writeVCardLine(buff, "NOTE:"+escapeVCardText(record.Notes))
+		}
+
+		writeVCardLine(buff, vCardPropertyName+":"+escapeVCardText(record.Name))
+		writeVCardLine(buff, vCardPropertyCipherKey+":"+record.CipherPubKey)
+		writeVCardLine(buff, vCardPropertySigningKey+":"+record.SigningPubKey)
+		if record.Fingerprint != "" {
+			writeVCardLine(buff, vCardPropertyFingerprint+":"+record.Fingerprint)
+		}
+
+		writeVCardLine(buff, "END:VCARD")
+	}
+
+	return buff.Bytes()
+}
+
+// writeVCardLine folds lines longer than 75 octets, without splitting UTF-8 characters
+func writeVCardLine(buff *bytes.Buffer, line string) {
+	limit := vCardMaxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+
+		buff.WriteString(line[:cut])
+		buff.WriteString(vCardLineBreak)
+		buff.WriteString(vCardContinuationIndicator)
+		line = line[cut:]
+
+		// continuation lines include the leading space in the limit
+		limit = vCardMaxLineOctets - len(vCardContinuationIndicator)
+	}
+
+	buff.WriteString(line)
+	buff.WriteString(vCardLineBreak)
+}
+
+func escapeVCardText(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`)
+	return replacer.Replace(text)
+}
+
+// splitVCardValue splits the value on unescaped separators and unescapes each part
+func splitVCardValue(value string, separator rune) []string {
+	var parts []string
+	part := strings.Builder{}
+	escaped := false
+	for _, r := range value {
+		switch {
+		case escaped:
+			if r == 'n' || r == 'N' {
+				part.WriteRune('\n')
+			} else {
+				part.WriteRune(r)
+			}
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == separator:
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteRune(r)
+		}
+	}
+
+	return append(parts, part.String())
+}
+
+// unescapeVCardText unescapes a value that is not split.  The zero separator never appears in content lines.
+func unescapeVCardText(value string) string {
+	return splitVCardValue(value, 0)[0]
+}
+
+type vCardProperty struct {
+	name  string
+	value string
+}
+
+// unfoldVCardLines joins folded lines, and returns each content line with the line number it started on
+func unfoldVCardLines(data []byte) (lines []string, lineNumbers []int) {
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		lines = append(lines, line)
+		lineNumbers = append(lineNumbers, i+1)
+	}
+
+	return lines, lineNumbers
+}
+
+// parseVCardLine returns the upper case property name, without any group or parameters, and the raw value
+func parseVCardLine(line string) (*vCardProperty, error) {
+	inQuotes := false
+	for i, r := range line {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == ':' && !inQuotes:
+			name, _, _ := strings.Cut(line[:i], ";")
+			if dot := strings.LastIndex(name, "."); dot >= 0 {
+				name = name[dot+1:]
+			}
+
+			return &vCardProperty{name: strings.ToUpper(strings.TrimSpace(name)), value: line[i+1:]}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("content line has no value: %s", line)
+}
+
+// decodeUserRecordsVCard reads one record for each card with X-BUMBLEBEE-* properties.  Cards without them are
+// regular contacts, so they are ignored.  Each record's Line is the line of its BEGIN:VCARD.
+func decodeUserRecordsVCard(data []byte) ([]*UserRecord, error) {
+	lines, lineNumbers := unfoldVCardLines(data)
+
+	var records []*UserRecord
+	var cardProperties []*vCardProperty
+	cardLine := 0
+	for i, line := range lines {
+		property, err := parseVCardLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumbers[i], err)
+		}
+
+		switch {
+		case property.name == "BEGIN" && strings.EqualFold(property.value, "VCARD"):
+			if cardLine != 0 {
+				return nil, fmt.Errorf("line %d: card started before the card on line %d ended", lineNumbers[i], cardLine)
+			}
+
+			cardLine = lineNumbers[i]
+			cardProperties = nil
+		case property.name == "END" && strings.EqualFold(property.value, "VCARD"):
+			if cardLine == 0 {
+				return nil, fmt.Errorf("line %d: card ended before it started", lineNumbers[i])
+			}
+
+			if record := newUserRecordFromVCard(cardProperties, cardLine); record != nil {
+				records = append(records, record)
+			}
+			cardLine = 0
+		case cardLine == 0:
+			return nil, fmt.Errorf("line %d: content outside of a card", lineNumbers[i])
+		default:
+			cardProperties = append(cardProperties, property)
+		}
+	}
+
+	if cardLine != 0 {
+		return nil, fmt.Errorf("the card on line %d has no END:VCARD", cardLine)
+	}
+
+	return records, nil
+}
+
+func newUserRecordFromVCard(properties []*vCardProperty, line int) *UserRecord {
+	record := &UserRecord{Line: line}
+	hasBumblebeeProperties := false
+	fullName := ""
+
+	for _, property := range properties {
+		if strings.HasPrefix(property.name, vCardPropertyPrefix) {
+			hasBumblebeeProperties = true
+		}
+
+		switch property.name {
+		case vCardPropertyName:
+			record.Name = strings.TrimSpace(unescapeVCardText(property.value))
+		case vCardPropertyCipherKey:
+			record.CipherPubKey = strings.TrimSpace(property.value)
+		case vCardPropertySigningKey:
+			record.SigningPubKey = strings.TrimSpace(property.value)
+		case vCardPropertyFingerprint:
+			record.Fingerprint = strings.TrimSpace(property.value)
+		case "FN":
+			fullName = strings.TrimSpace(unescapeVCardText(property.value))
+		case "EMAIL":
+			email := strings.TrimSpace(unescapeVCardText(property.value))
+			email = strings.TrimPrefix(email, "mailto:")
+			if email != "" {
+				record.Emails = append(record.Emails, email)
+			}
+		case "ORG":
+			// Only the organization name is kept, not the units that follow it
+			record.Organization = strings.TrimSpace(splitVCardValue(property.value, ';')[0])
+		case "NOTE":
+			record.Notes = strings.TrimSpace(unescapeVCardText(property.value))
+		case "CATEGORIES":
+			for _, tag := range splitVCardValue(property.value, ',') {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					record.Tags = append(record.Tags, tag)
+				}
+			}
+		}
+	}
+
+	if !hasBumblebeeProperties {
+		return nil
+	}
+
+	if record.Name == "" {
+		record.Name = fullName
+	}
+
+	if fullName != record.Name {
+		record.DisplayName = fullName
+	}
+
+	if record.CipherPubKey == "" || record.SigningPubKey == "" {
+		record.parseErr = errors.New("card is missing the X-BUMBLEBEE-CIPHER-KEY or X-BUMBLEBEE-SIGNING-KEY property")
+	}
+
+	return record
+}
@@ -0,0 +1,127 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ledger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	cipherio "github.com/thoughtrealm/bumblebee/cipher/io"
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/thoughtrealm/bumblebee/keypairs"
+	"github.com/thoughtrealm/bumblebee/security"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ReadFromFile loads the ledger from filePath, which is decrypted with the keystore system keys.
+// If the file does not exist yet, an empty ledger is returned that will be written to filePath.
+func ReadFromFile(filePath string) (*Ledger, error) {
+	if !helpers.FileExists(filePath) {
+		newLedger := NewLedger()
+		newLedger.SourceFilePath = filePath
+		return newLedger, nil
+	}
+
+	// The read kpi is owned by the cipher reader, which wipes it
+	kpiRead := keypairs.GlobalKeyPairStore.GetKeyPairInfo(helpers.KeyPairNameForKeyStoreReads)
+	if kpiRead == nil {
+		return nil, errors.New("keypair info for keystore reads was not found in the global keypair store")
+	}
+
+	kiSender, err := getSystemKeyInfo(helpers.KeyPairNameForKeyStoreWrites)
+	if err != nil {
+		return nil, err
+	}
+
+	cfr, err := cipherio.NewCipherFileReader(kpiRead, kiSender)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create instance of cipher reader: %w", err)
+	}
+	defer cfr.Wipe()
+
+	ledgerBytes, err := cfr.ReadCombinedFileToBytes(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read ledger data from file: %w", err)
+	}
+	defer security.Wipe(ledgerBytes)
+
+	newLedger := NewLedger()
+	err = msgpack.Unmarshal(ledgerBytes, newLedger)
+	if err != nil {
+		return nil, fmt.Errorf("failed interpreting ledger byte sequence: %w", err)
+	}
+
+	newLedger.SourceFilePath = filePath
+	return newLedger, nil
+}
+
+// WriteToFile writes the ledger to filePath, encrypted with the keystore system keys.
+// If filePath is empty, the ledger's SourceFilePath is used.
+func (l *Ledger) WriteToFile(filePath string) error {
+	useFilePath := filePath
+	if useFilePath == "" {
+		useFilePath = l.SourceFilePath
+	}
+
+	if useFilePath == "" {
+		return errors.New("no target file path provided and no prior filepath available")
+	}
+
+	kiReceiver, err := getSystemKeyInfo(helpers.KeyPairNameForKeyStoreReads)
+	if err != nil {
+		return err
+	}
+
+	kpiWrite := keypairs.GlobalKeyPairStore.GetKeyPairInfo(helpers.KeyPairNameForKeyStoreWrites)
+	if kpiWrite == nil {
+		return errors.New("keypair for keystore writes not found in the global keypair store")
+	}
+	defer kpiWrite.Wipe()
+
+	cfw, err := cipherio.NewCipherWriter(kiReceiver, kpiWrite)
+	if err != nil {
+		return fmt.Errorf("unable to create instance of cipher writer: %w", err)
+	}
+	defer cfw.Wipe()
+
+	ledgerBytes, err := l.WriteToMemory()
+	if err != nil {
+		return fmt.Errorf("unable to serialize ledger data: %w", err)
+	}
+
+	_, err = cfw.WriteToCombinedFileFromReader(useFilePath, bytes.NewReader(ledgerBytes))
+	if err != nil {
+		return fmt.Errorf("unable to write ledger to file: %w", err)
+	}
+
+	l.SourceFilePath = useFilePath
+	l.IsDirty = false
+	return nil
+}
+
+// getSystemKeyInfo returns the public keys of one of the keystore system keypairs
+func getSystemKeyInfo(keypairName string) (*security.KeyInfo, error) {
+	kpi := keypairs.GlobalKeyPairStore.GetKeyPairInfo(keypairName)
+	if kpi == nil {
+		return nil, fmt.Errorf("keypair info for \"%s\" was not found in the global keypair store", keypairName)
+	}
+	defer kpi.Wipe()
+
+	cipherPubKey, signingPubKey, err := kpi.PublicKeys()
+	if err != nil {
+		return nil, fmt.Errorf("unable to extract pub keys from %s kpi: %w", keypairName, err)
+	}
+
+	return security.NewKeyInfo(keypairName, cipherPubKey, signingPubKey)
+}
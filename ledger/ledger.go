@@ -0,0 +1,143 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ledger
+
+import (
+	"github.com/thoughtrealm/bumblebee/helpers"
+	"github.com/vmihailenco/msgpack/v5"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OpenedBundle records a bundle that has been opened by the profile
+type OpenedBundle struct {
+	// BundleID is the bundle's ID in UUID text form
+	BundleID string
+	// SenderName is the name used for the sender when the bundle was opened
+	SenderName string
+	// SenderSigningPubKey is the public signing key that the bundle signature was validated against
+	SenderSigningPubKey string
+	// ToName is the receiver name recorded in the bundle
+	ToName string
+	// CreateDate is the date the bundle was created, in RFC3339
+	CreateDate string
+	// FirstOpenedDate is the date the bundle was first opened, in RFC3339
+	FirstOpenedDate string
+	// LastOpenedDate is the date the bundle was most recently opened, in RFC3339
+	LastOpenedDate string
+	// OpenCount is the number of times the bundle has been opened
+	OpenCount int
+}
+
+func (ob *OpenedBundle) Clone() *OpenedBundle {
+	newOB := *ob
+	return &newOB
+}
+
+// Ledger is the list of bundles opened by a profile, used for detecting replayed bundles
+type Ledger struct {
+	Entries []*OpenedBundle
+
+	// SourceFilePath is the file the ledger was loaded from, if any
+	SourceFilePath string `msgpack:"-"`
+	// IsDirty indicates the ledger has changes that have not been written
+	IsDirty bool `msgpack:"-"`
+}
+
+type LedgerWalkFunc func(entry *OpenedBundle)
+
+func NewLedger() *Ledger {
+	return &Ledger{}
+}
+
+// NewFromMemory returns a ledger from its serialized form, as returned by WriteToMemory
+func NewFromMemory(bytesLedger []byte) (*Ledger, error) {
+	newLedger := NewLedger()
+	err := msgpack.Unmarshal(bytesLedger, newLedger)
+	if err != nil {
+		return nil, err
+	}
+
+	return newLedger, nil
+}
+
+func (l *Ledger) WriteToMemory() ([]byte, error) {
+	return msgpack.Marshal(l)
+}
+
+func (l *Ledger) Count() int {
+	return len(l.Entries)
+}
+
+// Find returns a clone of the entry for the bundle ID and sender, or nil if the bundle has not been opened before
+func (l *Ledger) Find(bundleID, senderSigningPubKey string) *OpenedBundle {
+	entry := l.find(bundleID, senderSigningPubKey)
+	if entry == nil {
+		return nil
+	}
+
+	return entry.Clone()
+}
+
+func (l *Ledger) find(bundleID, senderSigningPubKey string) *OpenedBundle {
+	for _, entry := range l.Entries {
+		if strings.EqualFold(entry.BundleID, bundleID) && entry.SenderSigningPubKey == senderSigningPubKey {
+			return entry
+		}
+	}
+
+	return nil
+}
+
+// RecordOpen adds the bundle to the ledger, or increments its open count if it was opened before.
+// Returns a clone of the updated entry.
+func (l *Ledger) RecordOpen(openedBundle *OpenedBundle) *OpenedBundle {
+	openDate := time.Now().UTC().Format(time.RFC3339)
+	l.IsDirty = true
+
+	entry := l.find(openedBundle.BundleID, openedBundle.SenderSigningPubKey)
+	if entry != nil {
+		entry.LastOpenedDate = openDate
+		entry.OpenCount++
+		return entry.Clone()
+	}
+
+	entry = openedBundle.Clone()
+	entry.FirstOpenedDate = openDate
+	entry.LastOpenedDate = openDate
+	entry.OpenCount = 1
+	l.Entries = append(l.Entries, entry)
+
+	return entry.Clone()
+}
+
+// Walk calls walkFunc for each entry, in order of when each bundle was last opened
+func (l *Ledger) Walk(walkFunc LedgerWalkFunc) {
+	if walkFunc == nil {
+		panic("walkFunc is nil")
+	}
+
+	entries := make([]*OpenedBundle, len(l.Entries))
+	copy(entries, l.Entries)
+
+	// Entries recorded by older versions have local dates, so the dates are compared as times
+	sort.SliceStable(entries, func(i, j int) bool {
+		return helpers.DateTextBefore(entries[i].LastOpenedDate, entries[j].LastOpenedDate)
+	})
+
+	for _, entry := range entries {
+		walkFunc(entry.Clone())
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ledger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestLedger_RecordOpen(t *testing.T) {
+	testLedger := NewLedger()
+
+	openedBundle := &OpenedBundle{
+		BundleID:            "30abd89e-d0c2-434e-8e73-4ea6c1879aac",
+		SenderName:          "bob",
+		SenderSigningPubKey: "UBOBSIGNINGKEY",
+		ToName:              "alice",
+	}
+
+	assert.Nil(t, testLedger.Find(openedBundle.BundleID, openedBundle.SenderSigningPubKey))
+
+	entry := testLedger.RecordOpen(openedBundle)
+	assert.Equal(t, 1, entry.OpenCount)
+	assert.NotEmpty(t, entry.FirstOpenedDate)
+	assert.True(t, testLedger.IsDirty)
+
+	entry = testLedger.RecordOpen(openedBundle)
+	assert.Equal(t, 2, entry.OpenCount)
+	assert.Equal(t, 1, testLedger.Count())
+
+	found := testLedger.Find(openedBundle.BundleID, openedBundle.SenderSigningPubKey)
+	if !assert.NotNil(t, found) {
+		return
+	}
+	assert.Equal(t, 2, found.OpenCount)
+
+	// The same bundle ID from a different sender is a different bundle
+	assert.Nil(t, testLedger.Find(openedBundle.BundleID, "UMALLORYSIGNINGKEY"))
+}
+
+func TestLedger_ReadWriteMemoryCycle(t *testing.T) {
+	testLedger := NewLedger()
+	testLedger.RecordOpen(&OpenedBundle{BundleID: "id-1", SenderSigningPubKey: "key-1"})
+	testLedger.RecordOpen(&OpenedBundle{BundleID: "id-2", SenderSigningPubKey: "key-2"})
+
+	ledgerBytes, err := testLedger.WriteToMemory()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	newLedger, err := NewFromMemory(ledgerBytes)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, 2, newLedger.Count())
+	assert.NotNil(t, newLedger.Find("id-1", "key-1"))
+	assert.NotNil(t, newLedger.Find("id-2", "key-2"))
+	assert.False(t, newLedger.IsDirty)
+}
+
+func TestLedger_WalkOrdersByLastOpenedTime(t *testing.T) {
+	testLedger := NewLedger()
+	entry := testLedger.RecordOpen(&OpenedBundle{BundleID: "id-new", SenderSigningPubKey: "key-1"})
+	assert.True(t, strings.HasSuffix(entry.LastOpenedDate, "Z"))
+
+	// Entries recorded by older versions have local dates in other zones.  As text, these would sort by hour.
+	testLedger.Entries = append(testLedger.Entries,
+		&OpenedBundle{BundleID: "id-later", LastOpenedDate: "2024-03-10T05:30:00-05:00"},
+		&OpenedBundle{BundleID: "id-earlier", LastOpenedDate: "2024-03-10T09:00:00+01:00"},
+	)
+
+	bundleIDs := []string{}
+	testLedger.Walk(func(entry *OpenedBundle) {
+		bundleIDs = append(bundleIDs, entry.BundleID)
+	})
+	assert.Equal(t, []string{"id-earlier", "id-later", "id-new"}, bundleIDs)
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"errors"
+	"fmt"
+	"github.com/vmihailenco/msgpack/v5"
+	"time"
+)
+
+const certificationDomain = "bumblebee certification v1"
+
+var ErrInvalidCertification = errors.New("invalid certification")
+
+// Certification states that the certifier has verified the subject's public keys.  It is signed by one of the
+// certifier's keypairs, so anyone who trusts the certifier's signing key can accept the subject's keys as well.
+type Certification struct {
+	SubjectName            string
+	SubjectCipherPubKey    string
+	SubjectSigningPubKey   string
+	CertifierName          string
+	CertifierSigningPubKey string
+	CertifiedDate          string
+	Signature              []byte
+}
+
+// certificationSignedData is the portion of a Certification covered by the signature
+type certificationSignedData struct {
+	Domain                 string
+	SubjectName            string
+	SubjectCipherPubKey    string
+	SubjectSigningPubKey   string
+	CertifierName          string
+	CertifierSigningPubKey string
+	CertifiedDate          string
+}
+
+// NewCertification builds a certification of the subject's public keys and signs it with the certifier's keypair
+func NewCertification(subject *KeyInfo, certifierName string, certifierKPI *KeyPairInfo) (*Certification, error) {
+	if subject == nil {
+		return nil, errors.New("subject key input is nil")
+	}
+
+	if certifierKPI == nil {
+		return nil, errors.New("certifier keypair input is nil")
+	}
+
+	_, certifierSigningPubKey, err := certifierKPI.PublicKeys()
+	if err != nil {
+		return nil, fmt.Errorf("unable to extract certifier public keys: %w", err)
+	}
+
+	if certifierSigningPubKey == subject.SigningPubKey {
+		return nil, errors.New("a keypair can not certify its own keys")
+	}
+
+	certification := &Certification{
+		SubjectName:            subject.Name,
+		SubjectCipherPubKey:    subject.CipherPubKey,
+		SubjectSigningPubKey:   subject.SigningPubKey,
+		CertifierName:          certifierName,
+		CertifierSigningPubKey: certifierSigningPubKey,
+		CertifiedDate:          time.Now().UTC().Format(time.RFC3339),
+	}
+
+	signedData, err := certification.signedData()
+	if err != nil {
+		return nil, err
+	}
+
+	certification.Signature, err = certifierKPI.Sign(signedData)
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign certification: %w", err)
+	}
+
+	return certification, nil
+}
+
+func (c *Certification) signedData() ([]byte, error) {
+	signedData, err := msgpack.Marshal(&certificationSignedData{
+		Domain:                 certificationDomain,
+		SubjectName:            c.SubjectName,
+		SubjectCipherPubKey:    c.SubjectCipherPubKey,
+		SubjectSigningPubKey:   c.SubjectSigningPubKey,
+		CertifierName:          c.CertifierName,
+		CertifierSigningPubKey: c.CertifierSigningPubKey,
+		CertifiedDate:          c.CertifiedDate,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode certification data: %w", err)
+	}
+
+	return signedData, nil
+}
+
+// Verify checks that the certification is signed by the certifier's signing key
+func (c *Certification) Verify() error {
+	signedData, err := c.signedData()
+	if err != nil {
+		return err
+	}
+
+	certifierKI := &KeyInfo{Name: c.CertifierName, SigningPubKey: c.CertifierSigningPubKey}
+	if _, err = certifierKI.Verify(signedData, c.Signature); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidCertification, err)
+	}
+
+	return nil
+}
+
+// Certifies returns true if the certification is for the provided public keys
+func (c *Certification) Certifies(ki *KeyInfo) bool {
+	return ki != nil && c.SubjectSigningPubKey == ki.SigningPubKey && c.SubjectCipherPubKey == ki.CipherPubKey
+}
+
+// SubjectKeyInfo returns the certified public keys
+func (c *Certification) SubjectKeyInfo() *KeyInfo {
+	return &KeyInfo{Name: c.SubjectName, CipherPubKey: c.SubjectCipherPubKey, SigningPubKey: c.SubjectSigningPubKey}
+}
+
+func (c *Certification) Clone() *Certification {
+	cOut := *c
+	cOut.Signature = append([]byte(nil), c.Signature...)
+	return &cOut
+}
+
+// CloneCertifications returns a deep copy of the certifications
+func CloneCertifications(certifications []*Certification) []*Certification {
+	if certifications == nil {
+		return nil
+	}
+
+	certificationsOut := make([]*Certification, 0, len(certifications))
+	for _, certification := range certifications {
+		certificationsOut = append(certificationsOut, certification.Clone())
+	}
+
+	return certificationsOut
+}
@@ -0,0 +1,49 @@
+package security
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCertification_SignAndVerify(t *testing.T) {
+	subjectKPI, err := NewKeyPairInfoWithSeeds("bob")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	cipherPubKey, signingPubKey, _ := subjectKPI.PublicKeys()
+	subjectKI, _ := NewKeyInfo("bob", cipherPubKey, signingPubKey)
+
+	certifierKPI, err := NewKeyPairInfoWithSeeds("alice")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	certification, err := NewCertification(subjectKI, "alice", certifierKPI)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, certification.Verify())
+	assert.True(t, certification.Certifies(subjectKI))
+
+	_, certifierSigningPubKey, _ := certifierKPI.PublicKeys()
+	assert.Equal(t, certifierSigningPubKey, certification.CertifierSigningPubKey)
+
+	// Any change to the signed values invalidates the certification
+	tampered := certification.Clone()
+	tampered.SubjectCipherPubKey = certifierSigningPubKey
+	assert.True(t, errors.Is(tampered.Verify(), ErrInvalidCertification))
+	assert.False(t, tampered.Certifies(subjectKI))
+
+	// Another keypair can not claim the certification
+	otherKPI, _ := NewKeyPairInfoWithSeeds("mallory")
+	_, otherSigningPubKey, _ := otherKPI.PublicKeys()
+	tampered = certification.Clone()
+	tampered.CertifierSigningPubKey = otherSigningPubKey
+	assert.True(t, errors.Is(tampered.Verify(), ErrInvalidCertification))
+
+	// A keypair can not certify itself
+	_, err = NewCertification(subjectKI, "bob", subjectKPI)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,206 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"strings"
+)
+
+// KeySource records where an entity's public keys came from
+type KeySource int
+
+const (
+	// KeySourceUnknown is the source for entities from versions prior to key sources
+	KeySourceUnknown KeySource = 0
+	// KeySourceManual means the keys were entered directly, such as with "add user"
+	KeySourceManual KeySource = 1
+	// KeySourceImport means the keys were imported from an export file, clipboard or pipe
+	KeySourceImport KeySource = 2
+	// KeySourceServer means the keys were retrieved from a keystore server
+	KeySourceServer KeySource = 3
+	// KeySourceSuccession means the keys came from a verified succession statement
+	KeySourceSuccession KeySource = 4
+	// KeySourceSSH means the keys were derived from an ssh-ed25519 public key
+	KeySourceSSH KeySource = 5
+)
+
+func KeySourceToText(source KeySource) string {
+	switch source {
+	case KeySourceManual:
+		return "Manual"
+	case KeySourceImport:
+		return "Import"
+	case KeySourceServer:
+		return "Server"
+	case KeySourceSuccession:
+		return "Succession"
+	case KeySourceSSH:
+		return "SSH"
+	default:
+		return "Unknown"
+	}
+}
+
+// ContactInfo is optional descriptive metadata for an entity
+type ContactInfo struct {
+	DisplayName  string   `msgpack:",omitempty"`
+	Emails       []string `msgpack:",omitempty"`
+	Organization string   `msgpack:",omitempty"`
+	Notes        string   `msgpack:",omitempty"`
+	Tags         []string `msgpack:",omitempty"`
+}
+
+func (ci *ContactInfo) Clone() *ContactInfo {
+	if ci == nil {
+		return nil
+	}
+
+	return &ContactInfo{
+		DisplayName:  ci.DisplayName,
+		Emails:       cloneStrings(ci.Emails),
+		Organization: ci.Organization,
+		Notes:        ci.Notes,
+		Tags:         cloneStrings(ci.Tags),
+	}
+}
+
+// IsEmpty returns true if no contact values are set
+func (ci *ContactInfo) IsEmpty() bool {
+	return ci == nil ||
+		(ci.DisplayName == "" && len(ci.Emails) == 0 && ci.Organization == "" && ci.Notes == "" && len(ci.Tags) == 0)
+}
+
+// Equal returns true if both have the same values.  A nil contact is equal to an empty contact.
+func (ci *ContactInfo) Equal(other *ContactInfo) bool {
+	if ci.IsEmpty() || other.IsEmpty() {
+		return ci.IsEmpty() && other.IsEmpty()
+	}
+
+	return ci.DisplayName == other.DisplayName &&
+		ci.Organization == other.Organization &&
+		ci.Notes == other.Notes &&
+		stringsEqual(ci.Emails, other.Emails) &&
+		stringsEqual(ci.Tags, other.Tags)
+}
+
+// Merge returns a copy of the contact with values from other added.  Empty values are filled in from other,
+// and emails and tags that are not already present are added.  Values that are already set are kept.
+func (ci *ContactInfo) Merge(other *ContactInfo) *ContactInfo {
+	merged := ci.Clone()
+	if merged == nil {
+		merged = &ContactInfo{}
+	}
+
+	if other == nil {
+		return merged
+	}
+
+	if merged.DisplayName == "" {
+		merged.DisplayName = other.DisplayName
+	}
+
+	if merged.Organization == "" {
+		merged.Organization = other.Organization
+	}
+
+	if merged.Notes == "" {
+		merged.Notes = other.Notes
+	}
+
+	for _, email := range other.Emails {
+		found := false
+		for _, existing := range merged.Emails {
+			if strings.EqualFold(existing, email) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			merged.Emails = append(merged.Emails, email)
+		}
+	}
+
+	merged.AddTags(other.Tags)
+	return merged
+}
+
+// HasTag returns true if the contact has the tag, ignoring case
+func (ci *ContactInfo) HasTag(tag string) bool {
+	if ci == nil {
+		return false
+	}
+
+	for _, contactTag := range ci.Tags {
+		if strings.EqualFold(contactTag, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddTags adds each tag that is not already present
+func (ci *ContactInfo) AddTags(tags []string) {
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !ci.HasTag(tag) {
+			ci.Tags = append(ci.Tags, tag)
+		}
+	}
+}
+
+// RemoveTags removes each tag, ignoring case
+func (ci *ContactInfo) RemoveTags(tags []string) {
+	keptTags := make([]string, 0, len(ci.Tags))
+	for _, contactTag := range ci.Tags {
+		remove := false
+		for _, tag := range tags {
+			if strings.EqualFold(contactTag, strings.TrimSpace(tag)) {
+				remove = true
+				break
+			}
+		}
+
+		if !remove {
+			keptTags = append(keptTags, contactTag)
+		}
+	}
+
+	ci.Tags = keptTags
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func cloneStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+
+	valuesOut := make([]string, len(values))
+	copy(valuesOut, values)
+	return valuesOut
+}
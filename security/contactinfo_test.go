@@ -0,0 +1,31 @@
+package security
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestContactInfo_Tags(t *testing.T) {
+	contact := &ContactInfo{}
+	contact.AddTags([]string{"work", " Family ", "WORK", ""})
+	assert.Equal(t, []string{"work", "Family"}, contact.Tags)
+	assert.True(t, contact.HasTag("family"))
+
+	contact.RemoveTags([]string{"Work"})
+	assert.Equal(t, []string{"Family"}, contact.Tags)
+	assert.False(t, contact.HasTag("work"))
+}
+
+func TestContactInfo_CloneAndIsEmpty(t *testing.T) {
+	var nilContact *ContactInfo
+	assert.True(t, nilContact.IsEmpty())
+	assert.Nil(t, nilContact.Clone())
+	assert.True(t, (&ContactInfo{}).IsEmpty())
+
+	contact := &ContactInfo{DisplayName: "Bob", Emails: []string{"bob@example.com"}}
+	assert.False(t, contact.IsEmpty())
+
+	clone := contact.Clone()
+	clone.Emails[0] = "changed@example.com"
+	assert.Equal(t, "bob@example.com", contact.Emails[0])
+}
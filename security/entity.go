@@ -13,23 +13,220 @@
 
 package security
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TrustLevel records how far an entity's public keys have been verified
+type TrustLevel int
+
+const (
+	// TrustLevelUnknown is the level for entities from versions prior to trust levels
+	TrustLevelUnknown TrustLevel = 0
+	// TrustLevelTOFU means the keys were accepted on first use, without verification
+	TrustLevelTOFU TrustLevel = 1
+	// TrustLevelVerified means the keys were verified out-of-band
+	TrustLevelVerified TrustLevel = 2
+	// TrustLevelRevoked means the keys should no longer be trusted
+	TrustLevelRevoked TrustLevel = 3
+)
+
+func TrustLevelToText(trust TrustLevel) string {
+	switch trust {
+	case TrustLevelUnknown:
+		return "Unknown"
+	case TrustLevelTOFU:
+		return "TOFU"
+	case TrustLevelVerified:
+		return "Verified"
+	case TrustLevelRevoked:
+		return "Revoked"
+	default:
+		return "Unknown"
+	}
+}
+
+// TextToTrustLevel returns the trust level for the text, and false if the text is not a known level
+func TextToTrustLevel(textName string) (TrustLevel, bool) {
+	switch strings.ToUpper(strings.TrimSpace(textName)) {
+	case "UNKNOWN":
+		return TrustLevelUnknown, true
+	case "TOFU":
+		return TrustLevelTOFU, true
+	case "VERIFIED":
+		return TrustLevelVerified, true
+	case "REVOKED":
+		return TrustLevelRevoked, true
+	default:
+		return TrustLevelUnknown, false
+	}
+}
 
 type Entity struct {
 	Name       string
 	PublicKeys *KeyInfo
+
+	// Trust is how far the public keys have been verified
+	Trust TrustLevel `msgpack:",omitempty"`
+	// VerifiedDate is the date the keys were verified, in RFC3339.  Only set for TrustLevelVerified.
+	VerifiedDate string `msgpack:",omitempty"`
+	// VerificationMethod is a description of how the keys were verified, such as "in-person" or "safety-number"
+	VerificationMethod string `msgpack:",omitempty"`
+
+	// Contact is optional descriptive metadata, such as emails and tags
+	Contact *ContactInfo `msgpack:",omitempty"`
+	// CreatedDate is the date the entity was added to the keystore, in RFC3339
+	CreatedDate string `msgpack:",omitempty"`
+	// UpdatedDate is the date the entity was last changed, in RFC3339
+	UpdatedDate string `msgpack:",omitempty"`
+	// KeySource records where the public keys came from
+	KeySource KeySource `msgpack:",omitempty"`
+	// KeySourceDetails is optional, such as the name of the imported file
+	KeySourceDetails string `msgpack:",omitempty"`
+
+	// RevokedDate is the RFC3339 date the keys stopped being valid.  Only set for TrustLevelRevoked.
+	RevokedDate string `msgpack:",omitempty"`
+	// RevocationReason is the reason from the revocation certificate
+	RevocationReason string `msgpack:",omitempty"`
+
+	// Certifications are signed statements from other users that they have verified the public keys
+	Certifications []*Certification `msgpack:",omitempty"`
+
+	// KeysDate is the RFC3339 date the current public keys replaced prior keys.  Empty if the keys never changed.
+	KeysDate string `msgpack:",omitempty"`
+	// KeyHistory holds the entity's prior public keys, oldest first
+	KeyHistory []*KeyHistoryEntry `msgpack:",omitempty"`
+}
+
+// IsVerified returns true if the entity's keys were verified out-of-band
+func (e *Entity) IsVerified() bool {
+	return e.Trust == TrustLevelVerified
+}
+
+// IsRevokedAt returns true if the entity is revoked and the date is not before the revocation date.
+// Dates that cannot be parsed are treated as revoked.
+func (e *Entity) IsRevokedAt(date string) bool {
+	if e.Trust != TrustLevelRevoked {
+		return false
+	}
+
+	revokedTime, err := time.Parse(time.RFC3339, e.RevokedDate)
+	if err != nil {
+		return true
+	}
+
+	dateTime, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return true
+	}
+
+	return !dateTime.Before(revokedTime)
+}
+
+func (e *Entity) revokedText() string {
+	details := []string{}
+	if e.RevokedDate != "" {
+		details = append(details, e.RevokedDate)
+	}
+
+	if e.RevocationReason != "" {
+		details = append(details, e.RevocationReason)
+	}
+
+	if len(details) == 0 {
+		return TrustLevelToText(e.Trust)
+	}
+
+	return fmt.Sprintf("%s (%s)", TrustLevelToText(e.Trust), strings.Join(details, ", "))
+}
+
+// TrustText returns the trust level, with the verification details if verified
+func (e *Entity) TrustText() string {
+	if e.Trust == TrustLevelRevoked {
+		return e.revokedText()
+	}
+
+	if e.Trust != TrustLevelVerified {
+		return TrustLevelToText(e.Trust)
+	}
+
+	details := []string{}
+	if e.VerifiedDate != "" {
+		details = append(details, e.VerifiedDate)
+	}
+
+	if e.VerificationMethod != "" {
+		details = append(details, e.VerificationMethod)
+	}
+
+	if len(details) == 0 {
+		return TrustLevelToText(e.Trust)
+	}
+
+	return fmt.Sprintf("%s (%s)", TrustLevelToText(e.Trust), strings.Join(details, ", "))
 }
 
 func (e *Entity) Print() {
 	fmt.Printf("Name               : %s\n", e.PublicKeys.Name)
 	fmt.Printf("Cipher Public Key  : %s\n", e.PublicKeys.CipherPubKey)
 	fmt.Printf("Signing Public Key : %s\n", e.PublicKeys.SigningPubKey)
+	fp := e.PublicKeys.Fingerprint()
+	fmt.Printf("Fingerprint        : %s\n", fp.Hex())
+	fmt.Printf("Fingerprint Words  : %s\n", fp.Words())
+	fmt.Printf("Trust Level        : %s\n", e.TrustText())
+	for _, certificate := range e.PublicKeys.Subkeys {
+		fmt.Printf("Subkey             : %s\n", certificate.SummaryText(time.Now()))
+	}
+
+	if !e.Contact.IsEmpty() {
+		printEntityValue("Display Name", e.Contact.DisplayName)
+		printEntityValue("Emails", strings.Join(e.Contact.Emails, ", "))
+		printEntityValue("Organization", e.Contact.Organization)
+		printEntityValue("Tags", strings.Join(e.Contact.Tags, ", "))
+		printEntityValue("Notes", e.Contact.Notes)
+	}
+
+	keySourceText := KeySourceToText(e.KeySource)
+	if e.KeySourceDetails != "" {
+		keySourceText += " (" + e.KeySourceDetails + ")"
+	}
+	fmt.Printf("Key Source         : %s\n", keySourceText)
+	printEntityValue("Created", e.CreatedDate)
+	printEntityValue("Updated", e.UpdatedDate)
+	printEntityValue("Keys Changed", e.KeysDate)
+	if len(e.KeyHistory) > 0 {
+		fmt.Printf("Prior Keys         : %d\n", len(e.KeyHistory))
+	}
 	fmt.Println()
 }
 
+// printEntityValue prints the labeled value if it is not empty
+func printEntityValue(label, value string) {
+	if value == "" {
+		return
+	}
+
+	fmt.Printf("%-19s: %s\n", label, value)
+}
+
 func (e *Entity) Clone() *Entity {
 	return &Entity{
-		Name:       e.Name,
-		PublicKeys: e.PublicKeys.Clone(),
+		Name:               e.Name,
+		PublicKeys:         e.PublicKeys.Clone(),
+		Trust:              e.Trust,
+		VerifiedDate:       e.VerifiedDate,
+		VerificationMethod: e.VerificationMethod,
+		Contact:            e.Contact.Clone(),
+		CreatedDate:        e.CreatedDate,
+		UpdatedDate:        e.UpdatedDate,
+		KeySource:          e.KeySource,
+		KeySourceDetails:   e.KeySourceDetails,
+		RevokedDate:        e.RevokedDate,
+		RevocationReason:   e.RevocationReason,
+		Certifications:     CloneCertifications(e.Certifications),
+		KeysDate:           e.KeysDate,
+		KeyHistory:         CloneKeyHistory(e.KeyHistory),
 	}
 }
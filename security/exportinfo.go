@@ -12,9 +12,19 @@ type ExportDataType int
 // If we add types in the future or change things around, we don't want to deprecate or
 // invalidate exported files.
 const (
-	ExportDataTypeUnknown     ExportDataType = 0
-	ExportDataTypeKeyInfo     ExportDataType = 1
-	ExportDataTypeKeyPairInfo ExportDataType = 2
+	ExportDataTypeUnknown       ExportDataType = 0
+	ExportDataTypeKeyInfo       ExportDataType = 1
+	ExportDataTypeKeyPairInfo   ExportDataType = 2
+	ExportDataTypeSuccession    ExportDataType = 3
+	ExportDataTypeRevocation    ExportDataType = 4
+	ExportDataTypeCertification ExportDataType = 5
+	ExportDataTypeGroupKey      ExportDataType = 6
+
+	// ExportDataTypeKeyInfoList is a list of users read from a JWK set with more than one user.
+	// It is only returned by the import processor, and is never written in export data.
+	ExportDataTypeKeyInfoList ExportDataType = 7
+
+	ExportDataTypeRecoveryShare ExportDataType = 8
 )
 
 type ExportKeyInfo struct {
@@ -24,6 +34,28 @@ type ExportKeyInfo struct {
 	SigningSeed   []byte
 	CipherPubKey  string
 	SigningPubKey string
+
+	// Succession is only provided for ExportDataTypeSuccession. The pub key fields hold the new keys.
+	Succession *SuccessionStatement `msgpack:",omitempty"`
+
+	// Revocation is only provided for ExportDataTypeRevocation. The pub key fields hold the revoked keys.
+	Revocation *RevocationCertificate `msgpack:",omitempty"`
+
+	// Certification is only provided for ExportDataTypeCertification. The pub key fields hold the certified keys.
+	Certification *Certification `msgpack:",omitempty"`
+
+	// Subkeys holds the subkey certificates for ExportDataTypeKeyInfo
+	Subkeys []*SubkeyCertificate `msgpack:",omitempty"`
+
+	// KeyPairSubkeys holds the private subkeys for ExportDataTypeKeyPairInfo
+	KeyPairSubkeys []*Subkey `msgpack:",omitempty"`
+
+	// GroupEpoch and GroupEpochDate are provided for ExportDataTypeGroupKey.  The seeds hold the keys for that epoch.
+	GroupEpoch     int    `msgpack:",omitempty"`
+	GroupEpochDate string `msgpack:",omitempty"`
+
+	// RecoveryShare is only provided for ExportDataTypeRecoveryShare.  The pub key fields hold the keys that were split.
+	RecoveryShare *RecoveryShare `msgpack:",omitempty"`
 }
 
 func NewExportKeyInfo() *ExportKeyInfo {
@@ -41,12 +73,13 @@ func NewExportKeyInfoFromKeyPairInfo(kpi *KeyPairInfo) (*ExportKeyInfo, error) {
 	}
 
 	return &ExportKeyInfo{
-		Name:          kpi.Name,
-		DataType:      ExportDataTypeKeyPairInfo,
-		CipherSeed:    kpi.CipherSeed,
-		SigningSeed:   kpi.SigningSeed,
-		CipherPubKey:  cipherPubKey,
-		SigningPubKey: signingPubKey,
+		Name:           kpi.Name,
+		DataType:       ExportDataTypeKeyPairInfo,
+		CipherSeed:     kpi.CipherSeed,
+		SigningSeed:    kpi.SigningSeed,
+		CipherPubKey:   cipherPubKey,
+		SigningPubKey:  signingPubKey,
+		KeyPairSubkeys: kpi.Subkeys,
 	}, nil
 }
 
@@ -62,9 +95,106 @@ func NewExportKeyInfoFromKeyInfo(ki *KeyInfo) (*ExportKeyInfo, error) {
 		SigningSeed:   nil,
 		CipherPubKey:  ki.CipherPubKey,
 		SigningPubKey: ki.SigningPubKey,
+		Subkeys:       ki.Subkeys,
+	}, nil
+}
+
+func NewExportKeyInfoFromSuccession(ss *SuccessionStatement) (*ExportKeyInfo, error) {
+	if ss == nil {
+		return nil, errors.New("succession statement input is nil")
+	}
+
+	return &ExportKeyInfo{
+		Name:          ss.Name,
+		DataType:      ExportDataTypeSuccession,
+		CipherPubKey:  ss.NewCipherPubKey,
+		SigningPubKey: ss.NewSigningPubKey,
+		Succession:    ss,
 	}, nil
 }
 
+func NewExportKeyInfoFromRevocation(rc *RevocationCertificate) (*ExportKeyInfo, error) {
+	if rc == nil {
+		return nil, errors.New("revocation certificate input is nil")
+	}
+
+	return &ExportKeyInfo{
+		Name:          rc.Name,
+		DataType:      ExportDataTypeRevocation,
+		CipherPubKey:  rc.CipherPubKey,
+		SigningPubKey: rc.SigningPubKey,
+		Revocation:    rc,
+	}, nil
+}
+
+func NewExportKeyInfoFromCertification(c *Certification) (*ExportKeyInfo, error) {
+	if c == nil {
+		return nil, errors.New("certification input is nil")
+	}
+
+	return &ExportKeyInfo{
+		Name:          c.SubjectName,
+		DataType:      ExportDataTypeCertification,
+		CipherPubKey:  c.SubjectCipherPubKey,
+		SigningPubKey: c.SubjectSigningPubKey,
+		Certification: c,
+	}, nil
+}
+
+// NewExportKeyInfoFromGroupKey exports the current epoch keys of a group identity.  Prior epoch keys and the
+// member list are not included, so a new member can not open bundles sent to the group before they joined.
+func NewExportKeyInfoFromGroupKey(kpi *KeyPairInfo) (*ExportKeyInfo, error) {
+	if kpi == nil {
+		return nil, errors.New("keypair info input is nil")
+	}
+
+	if !kpi.IsGroup() {
+		return nil, fmt.Errorf("keypair \"%s\" is not a group identity", kpi.Name)
+	}
+
+	cipherPubKey, signingPubKey, err := kpi.PublicKeys()
+	if err != nil {
+		return nil, fmt.Errorf("unable to extract public keys from keypair data: %w", err)
+	}
+
+	return &ExportKeyInfo{
+		Name:           kpi.Name,
+		DataType:       ExportDataTypeGroupKey,
+		CipherSeed:     kpi.CipherSeed,
+		SigningSeed:    kpi.SigningSeed,
+		CipherPubKey:   cipherPubKey,
+		SigningPubKey:  signingPubKey,
+		GroupEpoch:     kpi.GroupEpoch,
+		GroupEpochDate: kpi.GroupEpochDate,
+	}, nil
+}
+
+// GroupKeyPairInfo returns the group identity keys from an ExportDataTypeGroupKey export
+func (eki *ExportKeyInfo) GroupKeyPairInfo() (*KeyPairInfo, error) {
+	if eki.DataType != ExportDataTypeGroupKey {
+		return nil, fmt.Errorf("export data type %d is not a group key", int(eki.DataType))
+	}
+
+	if eki.GroupEpoch <= 0 {
+		return nil, fmt.Errorf("group key has an invalid epoch: %d", eki.GroupEpoch)
+	}
+
+	kpi := NewKeyPairInfoFromSeeds(eki.Name, eki.CipherSeed, eki.SigningSeed)
+	kpi.GroupEpoch = eki.GroupEpoch
+	kpi.GroupEpochDate = eki.GroupEpochDate
+
+	cipherPubKey, signingPubKey, err := kpi.PublicKeys()
+	if err != nil {
+		return nil, fmt.Errorf("unable to extract public keys from group key: %w", err)
+	}
+
+	if cipherPubKey != eki.CipherPubKey || signingPubKey != eki.SigningPubKey {
+		return nil, errors.New("group key seeds do not match the exported public keys")
+	}
+
+	return kpi, nil
+}
+
 func NewExportKeyInfoFromBytes(ekiBytes []byte) (*ExportKeyInfo, error) {
 	var eki = &ExportKeyInfo{}
 	err := msgpack.Unmarshal(ekiBytes, eki)
@@ -75,6 +205,11 @@ func NewExportKeyInfoFromBytes(ekiBytes []byte) (*ExportKeyInfo, error) {
 	return eki, nil
 }
 
+// HasPrivateKeys returns true for exports that hold private keys, which must be password protected
+func (eki *ExportKeyInfo) HasPrivateKeys() bool {
+	return eki.DataType == ExportDataTypeKeyPairInfo || eki.DataType == ExportDataTypeGroupKey
+}
+
 func (eki *ExportKeyInfo) ToBytes() (exportBytes []byte, err error) {
 	return msgpack.Marshal(eki)
 }
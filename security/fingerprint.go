@@ -0,0 +1,172 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// FingerprintSize is the number of hash bytes used for a fingerprint
+	FingerprintSize = 16
+
+	// SafetyNumberGroups is the number of 5-digit groups in a safety number
+	SafetyNumberGroups = 12
+
+	fingerprintDomain  = "bumblebee fingerprint v1"
+	safetyNumberDomain = "bumblebee safety number v1"
+)
+
+var ErrInvalidFingerprint = errors.New("invalid fingerprint")
+
+// Fingerprint is a short hash over an identity's cipher and signing public keys.  It is intended to be
+// compared out-of-band, either as grouped hex or as a word list.
+type Fingerprint []byte
+
+// NewFingerprint returns the fingerprint for the provided public keys
+func NewFingerprint(cipherPubKey, signingPubKey string) Fingerprint {
+	hash := sha512.New()
+	hash.Write([]byte(fingerprintDomain))
+	hash.Write([]byte{0})
+	hash.Write([]byte(cipherPubKey))
+	hash.Write([]byte{0})
+	hash.Write([]byte(signingPubKey))
+
+	return Fingerprint(hash.Sum(nil)[:FingerprintSize])
+}
+
+// Fingerprint returns the fingerprint of the KeyInfo's public keys
+func (ki *KeyInfo) Fingerprint() Fingerprint {
+	return NewFingerprint(ki.CipherPubKey, ki.SigningPubKey)
+}
+
+// Fingerprint returns the fingerprint of the keypair's public keys
+func (kpi *KeyPairInfo) Fingerprint() (Fingerprint, error) {
+	cipherPubKey, signingPubKey, err := kpi.PublicKeys()
+	if err != nil {
+		return nil, fmt.Errorf("unable to extract public keys from keypair: %w", err)
+	}
+
+	return NewFingerprint(cipherPubKey, signingPubKey), nil
+}
+
+// ParseFingerprint reads a fingerprint in either the grouped hex or the word list form.
+// Case, spaces and dashes are ignored.
+func ParseFingerprint(text string) (Fingerprint, error) {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return unicode.IsSpace(r) || r == '-' || r == ','
+	})
+
+	if len(fields) == FingerprintSize && isFingerprintWordList(fields) {
+		fp := make(Fingerprint, FingerprintSize)
+		for idx, field := range fields {
+			fp[idx] = byte(fingerprintWordIndex(field))
+		}
+
+		return fp, nil
+	}
+
+	fp, err := hex.DecodeString(strings.Join(fields, ""))
+	if err != nil || len(fp) != FingerprintSize {
+		return nil, fmt.Errorf("%w: expected %d hex characters or %d words", ErrInvalidFingerprint, FingerprintSize*2, FingerprintSize)
+	}
+
+	return fp, nil
+}
+
+// Hex returns the fingerprint as upper-cased hex, in groups of 4 characters
+func (fp Fingerprint) Hex() string {
+	hexText := strings.ToUpper(hex.EncodeToString(fp))
+
+	groups := make([]string, 0, len(hexText)/4+1)
+	for idx := 0; idx < len(hexText); idx += 4 {
+		end := idx + 4
+		if end > len(hexText) {
+			end = len(hexText)
+		}
+
+		groups = append(groups, hexText[idx:end])
+	}
+
+	return strings.Join(groups, " ")
+}
+
+// Words returns the fingerprint as a list of words, one per byte
+func (fp Fingerprint) Words() string {
+	words := make([]string, len(fp))
+	for idx, b := range fp {
+		words[idx] = fingerprintWords[b]
+	}
+
+	return strings.Join(words, " ")
+}
+
+// Equal compares fingerprints in constant time
+func (fp Fingerprint) Equal(other Fingerprint) bool {
+	return subtle.ConstantTimeCompare(fp, other) == 1
+}
+
+// SafetyNumber returns a number shared by two identities, which both parties can compare to confirm their key exchange.
+// The result is the same regardless of the order of the fingerprints.
+func SafetyNumber(fpA, fpB Fingerprint) string {
+	fingerprints := []Fingerprint{fpA, fpB}
+	sort.Slice(fingerprints, func(i, j int) bool {
+		return string(fingerprints[i]) < string(fingerprints[j])
+	})
+
+	hash := sha512.New()
+	hash.Write([]byte(safetyNumberDomain))
+	hash.Write([]byte{0})
+	hash.Write(fingerprints[0])
+	hash.Write(fingerprints[1])
+	digest := hash.Sum(nil)
+
+	// Each group is 5 bytes of the digest, reduced to 5 digits
+	groups := make([]string, SafetyNumberGroups)
+	chunk := make([]byte, 8)
+	for idx := 0; idx < SafetyNumberGroups; idx++ {
+		copy(chunk[3:], digest[idx*5:idx*5+5])
+		groups[idx] = fmt.Sprintf("%05d", binary.BigEndian.Uint64(chunk)%100000)
+	}
+
+	return strings.Join(groups, " ")
+}
+
+func isFingerprintWordList(fields []string) bool {
+	for _, field := range fields {
+		if fingerprintWordIndex(field) < 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func fingerprintWordIndex(word string) int {
+	for idx, fingerprintWord := range fingerprintWords {
+		if fingerprintWord == word {
+			return idx
+		}
+	}
+
+	return -1
+}
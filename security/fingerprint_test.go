@@ -0,0 +1,72 @@
+package security
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFingerprint_HexAndWordsRoundTrip(t *testing.T) {
+	kpi, err := NewKeyPairInfoWithSeeds("fingerprint")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	fp, err := kpi.Fingerprint()
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, FingerprintSize, len(fp))
+
+	fromHex, err := ParseFingerprint(fp.Hex())
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, fp.Equal(fromHex))
+
+	fromWords, err := ParseFingerprint(fp.Words())
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, fp.Equal(fromWords))
+
+	_, err = ParseFingerprint("not a fingerprint")
+	assert.True(t, errors.Is(err, ErrInvalidFingerprint))
+}
+
+func TestFingerprint_DiffersByKey(t *testing.T) {
+	kpiA, _ := NewKeyPairInfoWithSeeds("a")
+	kpiB, _ := NewKeyPairInfoWithSeeds("b")
+
+	fpA, _ := kpiA.Fingerprint()
+	fpB, _ := kpiB.Fingerprint()
+	assert.False(t, fpA.Equal(fpB))
+
+	// Swapping the cipher and signing keys must produce a different fingerprint
+	cipherPubKey, signingPubKey, _ := kpiA.PublicKeys()
+	assert.False(t, NewFingerprint(signingPubKey, cipherPubKey).Equal(fpA))
+}
+
+func TestSafetyNumber_IsSymmetric(t *testing.T) {
+	kpiA, _ := NewKeyPairInfoWithSeeds("a")
+	kpiB, _ := NewKeyPairInfoWithSeeds("b")
+	kpiC, _ := NewKeyPairInfoWithSeeds("c")
+
+	fpA, _ := kpiA.Fingerprint()
+	fpB, _ := kpiB.Fingerprint()
+	fpC, _ := kpiC.Fingerprint()
+
+	safetyNumberAB := SafetyNumber(fpA, fpB)
+	assert.Equal(t, safetyNumberAB, SafetyNumber(fpB, fpA))
+	assert.NotEqual(t, safetyNumberAB, SafetyNumber(fpA, fpC))
+	assert.Regexp(t, "^[0-9]{5}( [0-9]{5}){11}$", safetyNumberAB)
+}
+
+func TestFingerprintWords_AreUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, word := range fingerprintWords {
+		assert.NotEmpty(t, word)
+		assert.False(t, seen[word], "duplicate word %s", word)
+		seen[word] = true
+	}
+}
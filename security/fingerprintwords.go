@@ -0,0 +1,50 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+// fingerprintWords maps each byte value of a fingerprint to a word, for reading fingerprints aloud
+var fingerprintWords = [256]string{
+	"acid", "acorn", "actor", "adobe", "agent", "alarm", "album", "alley",
+	"amber", "angle", "ankle", "apple", "apron", "arena", "arrow", "atlas",
+	"audio", "award", "bacon", "badge", "bagel", "baker", "bamboo", "banjo",
+	"barrel", "basin", "beach", "beard", "berry", "bison", "blade", "blimp",
+	"bloom", "board", "bonus", "boxer", "brave", "bread", "brick", "bridge",
+	"brook", "brush", "bucket", "buffalo", "bugle", "cabin", "cable", "cactus",
+	"camel", "canal", "candle", "canoe", "canyon", "carbon", "cargo", "carpet",
+	"castle", "cedar", "chalk", "cherry", "chess", "chief", "chimney", "cider",
+	"cinema", "circus", "citrus", "clover", "cobalt", "cocoa", "comet", "coral",
+	"cotton", "cowboy", "crane", "crater", "crayon", "cricket", "crystal", "cupcake",
+	"dagger", "daisy", "dancer", "delta", "denim", "desert", "diesel", "dingo",
+	"dolphin", "donkey", "dragon", "eagle", "easel", "echo", "eclipse", "elbow",
+	"ember", "emerald", "engine", "falcon", "feather", "fern", "ferry", "fiddle",
+	"flame", "flute", "forest", "fossil", "galaxy", "garlic", "gecko", "geyser",
+	"ginger", "glacier", "globe", "goblin", "gopher", "granite", "grape", "gravel",
+	"guitar", "hammer", "harbor", "hazel", "helmet", "heron", "hippo", "honey",
+	"hornet", "husky", "igloo", "iguana", "indigo", "island", "ivory", "jacket",
+	"jaguar", "jasmine", "jelly", "jester", "jigsaw", "jungle", "kayak", "kernel",
+	"kettle", "koala", "ladder", "lagoon", "lantern", "laser", "lemon", "lily",
+	"lizard", "llama", "lobster", "locket", "lotus", "magnet", "mango", "maple",
+	"marble", "meadow", "melon", "meteor", "mitten", "monkey", "mosaic", "muffin",
+	"nectar", "needle", "nickel", "noodle", "nutmeg", "oasis", "ocean", "olive",
+	"onion", "opal", "orbit", "orchid", "otter", "oyster", "paddle", "panda",
+	"panther", "parrot", "peanut", "pebble", "pelican", "pepper", "piano", "pickle",
+	"pilot", "pirate", "planet", "pony", "poppy", "prism", "pumpkin", "puzzle",
+	"quartz", "quill", "rabbit", "radar", "radish", "raven", "ribbon", "rocket",
+	"rodeo", "saddle", "salmon", "sandal", "saturn", "scarf", "shadow", "shark",
+	"shovel", "silver", "sketch", "sloth", "spider", "sponge", "squid", "summit",
+	"sunset", "tablet", "tango", "teapot", "thistle", "thunder", "tiger", "toast",
+	"tomato", "topaz", "tractor", "trumpet", "tulip", "tunnel", "turtle", "umbrella",
+	"unicorn", "urchin", "valley", "velvet", "violin", "volcano", "waffle", "walnut",
+	"walrus", "whistle", "willow", "wizard", "yacht", "yogurt", "zebra", "zipper",
+}
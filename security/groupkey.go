@@ -0,0 +1,192 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrStaleGroupEpoch is returned when a received group key is not newer than the epoch already held
+var ErrStaleGroupEpoch = errors.New("group key epoch is not newer than the current epoch")
+
+// ErrGroupManagerMismatch is returned when a received group key was not sent by the user that manages the group
+var ErrGroupManagerMismatch = errors.New("group key was not sent by the group's manager")
+
+// GroupEpochKey holds the keys of a prior epoch of a shared group identity.  They are kept so that bundles
+// sent to the group during that epoch can still be opened.
+type GroupEpochKey struct {
+	Epoch       int
+	CipherSeed  []byte
+	SigningSeed []byte
+	CreatedDate string
+}
+
+func (gek *GroupEpochKey) Clone() *GroupEpochKey {
+	return &GroupEpochKey{
+		Epoch:       gek.Epoch,
+		CipherSeed:  bytes.Clone(gek.CipherSeed),
+		SigningSeed: bytes.Clone(gek.SigningSeed),
+		CreatedDate: gek.CreatedDate,
+	}
+}
+
+func (gek *GroupEpochKey) Wipe() {
+	if len(gek.CipherSeed) != 0 {
+		_, _ = io.ReadFull(rand.Reader, gek.CipherSeed[:])
+	}
+
+	if len(gek.SigningSeed) != 0 {
+		_, _ = io.ReadFull(rand.Reader, gek.SigningSeed[:])
+	}
+}
+
+// NewGroupKeyPairInfo creates the keys for a new shared group identity at epoch 1.  The group is managed
+// by this profile, which tracks the members the keys are distributed to.
+func NewGroupKeyPairInfo(name string, members []string) (*KeyPairInfo, error) {
+	kpi, err := NewKeyPairInfoWithSeeds(name)
+	if err != nil {
+		return nil, err
+	}
+
+	kpi.GroupEpoch = 1
+	kpi.GroupEpochDate = time.Now().UTC().Format(time.RFC3339)
+	kpi.GroupManaged = true
+	kpi.SetGroupMembers(members)
+	return kpi, nil
+}
+
+// IsGroup returns true if the keypair is a shared group identity
+func (kpi *KeyPairInfo) IsGroup() bool {
+	return kpi.GroupEpoch > 0
+}
+
+// SetGroupMembers replaces the member list, sorted and without duplicates
+func (kpi *KeyPairInfo) SetGroupMembers(members []string) {
+	kpi.GroupMembers = []string{}
+	for _, member := range members {
+		if member == "" || kpi.HasGroupMember(member) {
+			continue
+		}
+
+		kpi.GroupMembers = append(kpi.GroupMembers, member)
+	}
+
+	sort.Slice(kpi.GroupMembers, func(i, j int) bool {
+		return strings.ToUpper(kpi.GroupMembers[i]) < strings.ToUpper(kpi.GroupMembers[j])
+	})
+}
+
+// HasGroupMember returns true if the member name is in the member list.  Names are not case-sensitive.
+func (kpi *KeyPairInfo) HasGroupMember(member string) bool {
+	for _, existing := range kpi.GroupMembers {
+		if strings.EqualFold(existing, member) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (kpi *KeyPairInfo) groupMembersText() string {
+	if len(kpi.GroupMembers) == 0 {
+		return "(none)"
+	}
+
+	return strings.Join(kpi.GroupMembers, ", ")
+}
+
+// Rekey moves the current keys to the prior epoch keys and replaces them with new keys for the next epoch
+func (kpi *KeyPairInfo) Rekey() error {
+	if !kpi.IsGroup() {
+		return fmt.Errorf("keypair \"%s\" is not a group identity", kpi.Name)
+	}
+
+	nextKPI, err := NewKeyPairInfoWithSeeds(kpi.Name)
+	if err != nil {
+		return fmt.Errorf("unable to create keys for the next epoch: %w", err)
+	}
+
+	kpi.pushGroupEpoch()
+	kpi.CipherSeed = nextKPI.CipherSeed
+	kpi.SigningSeed = nextKPI.SigningSeed
+	kpi.GroupEpoch++
+	kpi.GroupEpochDate = time.Now().UTC().Format(time.RFC3339)
+	return nil
+}
+
+// MergeGroupEpoch adopts the keys of a newer epoch received from the group's manager.  The current keys are
+// kept as prior epoch keys.  senderSigningPubKey is the signing key of the user that sent the keys, which must be
+// the manager recorded when the group was joined, or ErrGroupManagerMismatch is returned.  ErrStaleGroupEpoch is
+// returned if the received epoch is not newer.
+func (kpi *KeyPairInfo) MergeGroupEpoch(received *KeyPairInfo, senderSigningPubKey string) error {
+	if received == nil || !received.IsGroup() {
+		return errors.New("received keys are not a group identity")
+	}
+
+	if !kpi.IsGroup() {
+		return fmt.Errorf("keypair \"%s\" is not a group identity", kpi.Name)
+	}
+
+	if kpi.GroupManagerSigningPubKey == "" {
+		return fmt.Errorf("%w: no manager was recorded for group \"%s\"", ErrGroupManagerMismatch, kpi.Name)
+	}
+
+	if senderSigningPubKey != kpi.GroupManagerSigningPubKey {
+		return fmt.Errorf("%w: group \"%s\" was joined from signing key %s", ErrGroupManagerMismatch, kpi.Name, kpi.GroupManagerSigningPubKey)
+	}
+
+	if received.GroupEpoch <= kpi.GroupEpoch {
+		return fmt.Errorf("%w: received epoch %d, current epoch %d", ErrStaleGroupEpoch, received.GroupEpoch, kpi.GroupEpoch)
+	}
+
+	kpi.pushGroupEpoch()
+	kpi.CipherSeed = bytes.Clone(received.CipherSeed)
+	kpi.SigningSeed = bytes.Clone(received.SigningSeed)
+	kpi.GroupEpoch = received.GroupEpoch
+	kpi.GroupEpochDate = received.GroupEpochDate
+	return nil
+}
+
+// pushGroupEpoch adds the current keys to the front of the prior epoch keys
+func (kpi *KeyPairInfo) pushGroupEpoch() {
+	epochKey := &GroupEpochKey{
+		Epoch:       kpi.GroupEpoch,
+		CipherSeed:  kpi.CipherSeed,
+		SigningSeed: kpi.SigningSeed,
+		CreatedDate: kpi.GroupEpochDate,
+	}
+
+	kpi.GroupEpochKeys = append([]*GroupEpochKey{epochKey}, kpi.GroupEpochKeys...)
+}
+
+// PriorGroupEpochKeys returns copies of the prior epoch keys, newest first
+func (kpi *KeyPairInfo) PriorGroupEpochKeys() []*GroupEpochKey {
+	epochKeys := make([]*GroupEpochKey, 0, len(kpi.GroupEpochKeys))
+	for _, epochKey := range kpi.GroupEpochKeys {
+		epochKeys = append(epochKeys, epochKey.Clone())
+	}
+
+	sort.SliceStable(epochKeys, func(i, j int) bool {
+		return epochKeys[i].Epoch > epochKeys[j].Epoch
+	})
+
+	return epochKeys
+}
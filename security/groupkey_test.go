@@ -0,0 +1,133 @@
+package security
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewGroupKeyPairInfo(t *testing.T) {
+	kpi, err := NewGroupKeyPairInfo("team", []string{"carol", "Bob", "bob", "alice"})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.True(t, kpi.IsGroup())
+	assert.True(t, kpi.GroupManaged)
+	assert.Equal(t, 1, kpi.GroupEpoch)
+	assert.NotEqual(t, "", kpi.GroupEpochDate)
+	assert.Equal(t, []string{"alice", "Bob", "carol"}, kpi.GroupMembers)
+	assert.True(t, kpi.HasGroupMember("BOB"))
+	assert.False(t, kpi.HasGroupMember("dave"))
+
+	plainKPI, err := NewKeyPairInfoWithSeeds("plain")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.False(t, plainKPI.IsGroup())
+	assert.NotNil(t, plainKPI.Rekey())
+}
+
+func TestKeyPairInfo_Rekey(t *testing.T) {
+	kpi, err := NewGroupKeyPairInfo("team", nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	epoch1CipherSeed := string(kpi.CipherSeed)
+	epoch1SigningSeed := string(kpi.SigningSeed)
+	if !assert.Nil(t, kpi.Rekey()) {
+		return
+	}
+
+	epoch2CipherSeed := string(kpi.CipherSeed)
+	if !assert.Nil(t, kpi.Rekey()) {
+		return
+	}
+
+	assert.Equal(t, 3, kpi.GroupEpoch)
+	assert.NotEqual(t, epoch1CipherSeed, string(kpi.CipherSeed))
+	assert.NotEqual(t, epoch1SigningSeed, string(kpi.SigningSeed))
+
+	epochKeys := kpi.PriorGroupEpochKeys()
+	if !assert.Equal(t, 2, len(epochKeys)) {
+		return
+	}
+	assert.Equal(t, 2, epochKeys[0].Epoch)
+	assert.Equal(t, epoch2CipherSeed, string(epochKeys[0].CipherSeed))
+	assert.Equal(t, 1, epochKeys[1].Epoch)
+	assert.Equal(t, epoch1CipherSeed, string(epochKeys[1].CipherSeed))
+	assert.Equal(t, epoch1SigningSeed, string(epochKeys[1].SigningSeed))
+
+	// clones hold their own copies of the epoch keys
+	kpiClone := kpi.Clone()
+	kpiClone.Wipe()
+	assert.Equal(t, epoch1CipherSeed, string(kpi.PriorGroupEpochKeys()[1].CipherSeed))
+}
+
+func TestKeyPairInfo_MergeGroupEpoch(t *testing.T) {
+	managerKPI, err := NewGroupKeyPairInfo("team", []string{"bob"})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	eki, err := NewExportKeyInfoFromGroupKey(managerKPI)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	memberKPI, err := eki.GroupKeyPairInfo()
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 1, memberKPI.GroupEpoch)
+	assert.False(t, memberKPI.GroupManaged)
+	assert.Nil(t, memberKPI.GroupMembers)
+
+	// keys are only merged when sent by the manager recorded when the group was joined
+	managerUserKPI, _ := NewKeyPairInfoWithSeeds("manager")
+	_, managerSigningPubKey, _ := managerUserKPI.PublicKeys()
+	err = memberKPI.MergeGroupEpoch(managerKPI, managerSigningPubKey)
+	assert.True(t, errors.Is(err, ErrGroupManagerMismatch))
+	memberKPI.GroupManagerSigningPubKey = managerSigningPubKey
+
+	// the same epoch is not merged again
+	err = memberKPI.MergeGroupEpoch(managerKPI, managerSigningPubKey)
+	assert.True(t, errors.Is(err, ErrStaleGroupEpoch))
+
+	if !assert.Nil(t, managerKPI.Rekey()) {
+		return
+	}
+
+	eki, err = NewExportKeyInfoFromGroupKey(managerKPI)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	receivedKPI, err := eki.GroupKeyPairInfo()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	otherUserKPI, _ := NewKeyPairInfoWithSeeds("other")
+	_, otherSigningPubKey, _ := otherUserKPI.PublicKeys()
+	err = memberKPI.MergeGroupEpoch(receivedKPI, otherSigningPubKey)
+	assert.True(t, errors.Is(err, ErrGroupManagerMismatch))
+	assert.Equal(t, 1, memberKPI.GroupEpoch)
+
+	err = memberKPI.MergeGroupEpoch(receivedKPI, managerSigningPubKey)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 2, memberKPI.GroupEpoch)
+	assert.Equal(t, managerKPI.CipherSeed, memberKPI.CipherSeed)
+	assert.Equal(t, managerKPI.GroupEpochKeys[0].CipherSeed, memberKPI.GroupEpochKeys[0].CipherSeed)
+
+	// the export does not include the prior epoch keys
+	assert.Equal(t, 0, len(receivedKPI.GroupEpochKeys))
+
+	// tampered public keys are rejected
+	eki.CipherPubKey = eki.SigningPubKey
+	_, err = eki.GroupKeyPairInfo()
+	assert.NotNil(t, err)
+}
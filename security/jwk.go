@@ -0,0 +1,236 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+// Public keys are written as RFC 8037 OKP keys, so services that use JOSE libraries can verify signatures and
+// encrypt to bumblebee users.  Each user is two keys with the user name as the kid: an Ed25519 key for the
+// signing key and an X25519 key for the cipher key.  Private keys are never written or read.
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nkeys"
+)
+
+const (
+	JWKKeyTypeOKP     = "OKP"
+	JWKCurveEd25519   = "Ed25519"
+	JWKCurveX25519    = "X25519"
+	JWKUseSignature   = "sig"
+	JWKUseEncryption  = "enc"
+	JWKAlgorithmEdDSA = "EdDSA"
+	JWKAlgorithmECDH  = "ECDH-ES"
+)
+
+// ErrInvalidJWK is returned when a JWK or JWK set can not be converted to bumblebee public keys
+var ErrInvalidJWK = errors.New("invalid JWK")
+
+// JWK is an OKP JSON Web Key.  D is only read, so that private keys can be refused.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	D         string `json:"d,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+}
+
+// JWKSet is a JSON Web Key Set
+type JWKSet struct {
+	Keys []*JWK `json:"keys"`
+}
+
+// NewJWKsFromKeyInfo returns the signing and cipher keys as JWKs, with the key info name as the kid
+func NewJWKsFromKeyInfo(ki *KeyInfo) ([]*JWK, error) {
+	signingKey, err := nkeys.Decode(nkeys.PrefixByteUser, []byte(ki.SigningPubKey))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode signing public key for \"%s\": %w", ki.Name, err)
+	}
+
+	cipherKey, err := nkeys.Decode(nkeys.PrefixByteCurve, []byte(ki.CipherPubKey))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode cipher public key for \"%s\": %w", ki.Name, err)
+	}
+
+	return []*JWK{
+		{
+			KeyType:   JWKKeyTypeOKP,
+			Curve:     JWKCurveEd25519,
+			X:         base64.RawURLEncoding.EncodeToString(signingKey),
+			KeyID:     ki.Name,
+			Use:       JWKUseSignature,
+			Algorithm: JWKAlgorithmEdDSA,
+		},
+		{
+			KeyType:   JWKKeyTypeOKP,
+			Curve:     JWKCurveX25519,
+			X:         base64.RawURLEncoding.EncodeToString(cipherKey),
+			KeyID:     ki.Name,
+			Use:       JWKUseEncryption,
+			Algorithm: JWKAlgorithmECDH,
+		},
+	}, nil
+}
+
+// NewJWKSetFromKeyInfos returns a JWK set with two keys for each key info
+func NewJWKSetFromKeyInfos(kis []*KeyInfo) (*JWKSet, error) {
+	set := &JWKSet{Keys: []*JWK{}}
+	for _, ki := range kis {
+		keys, err := NewJWKsFromKeyInfo(ki)
+		if err != nil {
+			return nil, err
+		}
+
+		set.Keys = append(set.Keys, keys...)
+	}
+
+	return set, nil
+}
+
+// Marshal returns the indented JSON for the set
+func (set *JWKSet) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode JWK set: %w", err)
+	}
+
+	return append(data, '\n'), nil
+}
+
+// KeyInfos converts the set back to key infos, in the order each kid first appears.  Each kid must have one
+// Ed25519 key and one X25519 key.
+func (set *JWKSet) KeyInfos() ([]*KeyInfo, error) {
+	var kis []*KeyInfo
+	kisByID := map[string]*KeyInfo{}
+
+	for i, key := range set.Keys {
+		if key == nil {
+			return nil, fmt.Errorf("%w: key %d is empty", ErrInvalidJWK, i+1)
+		}
+
+		if key.KeyID == "" {
+			return nil, fmt.Errorf("%w: key %d has no kid", ErrInvalidJWK, i+1)
+		}
+
+		ki, found := kisByID[key.KeyID]
+		if !found {
+			ki = &KeyInfo{Name: key.KeyID}
+			kisByID[key.KeyID] = ki
+			kis = append(kis, ki)
+		}
+
+		err := ki.setJWKPublicKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %d (kid \"%s\"): %w", i+1, key.KeyID, err)
+		}
+	}
+
+	for _, ki := range kis {
+		if ki.SigningPubKey == "" || ki.CipherPubKey == "" {
+			return nil, fmt.Errorf("%w: kid \"%s\" needs both an Ed25519 and an X25519 key", ErrInvalidJWK, ki.Name)
+		}
+	}
+
+	return kis, nil
+}
+
+func (ki *KeyInfo) setJWKPublicKey(key *JWK) error {
+	if key.D != "" {
+		return fmt.Errorf("%w: private keys are not imported", ErrInvalidJWK)
+	}
+
+	if key.KeyType != JWKKeyTypeOKP {
+		return fmt.Errorf("%w: unsupported key type \"%s\", only OKP keys are supported", ErrInvalidJWK, key.KeyType)
+	}
+
+	rawKey, err := base64.RawURLEncoding.DecodeString(key.X)
+	if err != nil || len(rawKey) != 32 {
+		return fmt.Errorf("%w: x is not a base64url encoded 32 byte key", ErrInvalidJWK)
+	}
+
+	var prefix nkeys.PrefixByte
+	var target *string
+	switch key.Curve {
+	case JWKCurveEd25519:
+		if key.Use != "" && key.Use != JWKUseSignature {
+			return fmt.Errorf("%w: Ed25519 keys must have use \"%s\"", ErrInvalidJWK, JWKUseSignature)
+		}
+
+		prefix, target = nkeys.PrefixByteUser, &ki.SigningPubKey
+	case JWKCurveX25519:
+		if key.Use != "" && key.Use != JWKUseEncryption {
+			return fmt.Errorf("%w: X25519 keys must have use \"%s\"", ErrInvalidJWK, JWKUseEncryption)
+		}
+
+		prefix, target = nkeys.PrefixByteCurve, &ki.CipherPubKey
+	default:
+		return fmt.Errorf("%w: unsupported curve \"%s\", only Ed25519 and X25519 are supported", ErrInvalidJWK, key.Curve)
+	}
+
+	if *target != "" {
+		return fmt.Errorf("%w: more than one %s key", ErrInvalidJWK, key.Curve)
+	}
+
+	encodedKey, err := nkeys.Encode(prefix, rawKey)
+	if err != nil {
+		return fmt.Errorf("unable to encode public key: %w", err)
+	}
+
+	*target = string(encodedKey)
+	return nil
+}
+
+// IsJWKData returns true if the data is a JSON object with a "keys" or "kty" member, which is a JWK set or a JWK
+func IsJWKData(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return false
+	}
+
+	members := map[string]json.RawMessage{}
+	if json.Unmarshal(trimmed, &members) != nil {
+		return false
+	}
+
+	_, hasKeys := members["keys"]
+	_, hasKeyType := members["kty"]
+	return hasKeys || hasKeyType
+}
+
+// ParseJWKData reads a JWK set, or a single JWK, and converts it to key infos.  A single JWK only holds one key,
+// so it can only be converted if it is part of a set with the other key.
+func ParseJWKData(data []byte) ([]*KeyInfo, error) {
+	set := &JWKSet{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	err := decoder.Decode(set)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidJWK, err)
+	}
+
+	if set.Keys == nil {
+		key := &JWK{}
+		err = json.Unmarshal(data, key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidJWK, err)
+		}
+
+		set.Keys = []*JWK{key}
+	}
+
+	return set.KeyInfos()
+}
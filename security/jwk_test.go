@@ -0,0 +1,103 @@
+package security
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func newTestJWKKeyInfo(t *testing.T, name string) *KeyInfo {
+	kpi, err := NewKeyPairInfoWithSeeds(name)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	cipherPubKey, signingPubKey, err := kpi.PublicKeys()
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	ki, _ := NewKeyInfo(name, cipherPubKey, signingPubKey)
+	return ki
+}
+
+func TestJWKSet_RoundTrip(t *testing.T) {
+	alice := newTestJWKKeyInfo(t, "alice")
+	bob := newTestJWKKeyInfo(t, "bob")
+
+	set, err := NewJWKSetFromKeyInfos([]*KeyInfo{alice, bob})
+	if !assert.Nil(t, err) || !assert.Len(t, set.Keys, 4) {
+		return
+	}
+
+	assert.Equal(t, JWKKeyTypeOKP, set.Keys[0].KeyType)
+	assert.Equal(t, JWKCurveEd25519, set.Keys[0].Curve)
+	assert.Equal(t, JWKUseSignature, set.Keys[0].Use)
+	assert.Equal(t, "alice", set.Keys[0].KeyID)
+	assert.Equal(t, JWKCurveX25519, set.Keys[1].Curve)
+	assert.Equal(t, JWKUseEncryption, set.Keys[1].Use)
+	assert.Equal(t, 43, len(set.Keys[0].X))
+
+	data, err := set.Marshal()
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, IsJWKData(data))
+	assert.False(t, strings.Contains(string(data), `"d"`))
+
+	kis, err := ParseJWKData(data)
+	if !assert.Nil(t, err) || !assert.Len(t, kis, 2) {
+		return
+	}
+
+	for i, expected := range []*KeyInfo{alice, bob} {
+		assert.Equal(t, expected.Name, kis[i].Name)
+		assert.Equal(t, expected.CipherPubKey, kis[i].CipherPubKey)
+		assert.Equal(t, expected.SigningPubKey, kis[i].SigningPubKey)
+	}
+}
+
+func TestParseJWKData_Errors(t *testing.T) {
+	alice := newTestJWKKeyInfo(t, "alice")
+	keys, err := NewJWKsFromKeyInfo(alice)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	parse := func(keys ...*JWK) error {
+		data, _ := json.Marshal(&JWKSet{Keys: keys})
+		_, err := ParseJWKData(data)
+		return err
+	}
+
+	// A single key can not be converted on its own
+	singleKey, _ := json.Marshal(keys[0])
+	_, err = ParseJWKData(singleKey)
+	assert.True(t, errors.Is(err, ErrInvalidJWK))
+
+	privateKey := *keys[0]
+	privateKey.D = privateKey.X
+	assert.True(t, errors.Is(parse(&privateKey, keys[1]), ErrInvalidJWK))
+
+	rsaKey := &JWK{KeyType: "RSA", KeyID: "alice"}
+	assert.True(t, errors.Is(parse(keys[0], keys[1], rsaKey), ErrInvalidJWK))
+
+	wrongUse := *keys[1]
+	wrongUse.Use = JWKUseSignature
+	assert.True(t, errors.Is(parse(keys[0], &wrongUse), ErrInvalidJWK))
+
+	assert.True(t, errors.Is(parse(keys[0], keys[0], keys[1]), ErrInvalidJWK))
+
+	noKeyID := *keys[0]
+	noKeyID.KeyID = ""
+	assert.True(t, errors.Is(parse(&noKeyID, keys[1]), ErrInvalidJWK))
+
+	shortKey := *keys[1]
+	shortKey.X = "AAAA"
+	assert.True(t, errors.Is(parse(keys[0], &shortKey), ErrInvalidJWK))
+
+	assert.False(t, IsJWKData([]byte(`{"users": []}`)))
+	assert.False(t, IsJWKData([]byte(":start  :export-user  :hex")))
+}
@@ -0,0 +1,137 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"time"
+)
+
+// KeyHistoryEntry is a set of public keys an entity had before they were replaced
+type KeyHistoryEntry struct {
+	CipherPubKey  string
+	SigningPubKey string
+	// FirstSeenDate is the RFC3339 date the keys were stored for the entity, if known
+	FirstSeenDate string `msgpack:",omitempty"`
+	// ReplacedDate is the RFC3339 date the keys were replaced
+	ReplacedDate string
+	// ChangeSource is where the replacing keys came from
+	ChangeSource KeySource
+	// ChangeSourceDetails is optional, such as the name of the imported file
+	ChangeSourceDetails string `msgpack:",omitempty"`
+}
+
+func (khe *KeyHistoryEntry) KeyInfo(name string) *KeyInfo {
+	return &KeyInfo{Name: name, CipherPubKey: khe.CipherPubKey, SigningPubKey: khe.SigningPubKey}
+}
+
+// ChangeSourceText returns the change source, with the details if there are any
+func (khe *KeyHistoryEntry) ChangeSourceText() string {
+	if khe.ChangeSourceDetails == "" {
+		return KeySourceToText(khe.ChangeSource)
+	}
+
+	return KeySourceToText(khe.ChangeSource) + " (" + khe.ChangeSourceDetails + ")"
+}
+
+// IsReplacedAt returns true if the date is not before the date the keys were replaced.
+// Dates that cannot be parsed are treated as replaced.
+func (khe *KeyHistoryEntry) IsReplacedAt(date string) bool {
+	replacedTime, err := time.Parse(time.RFC3339, khe.ReplacedDate)
+	if err != nil {
+		return true
+	}
+
+	dateTime, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return true
+	}
+
+	return !dateTime.Before(replacedTime)
+}
+
+func (khe *KeyHistoryEntry) Clone() *KeyHistoryEntry {
+	kheOut := *khe
+	return &kheOut
+}
+
+// CloneKeyHistory returns a deep copy of the key history
+func CloneKeyHistory(history []*KeyHistoryEntry) []*KeyHistoryEntry {
+	if history == nil {
+		return nil
+	}
+
+	historyOut := make([]*KeyHistoryEntry, 0, len(history))
+	for _, entry := range history {
+		historyOut = append(historyOut, entry.Clone())
+	}
+
+	return historyOut
+}
+
+// PinKeyChange replaces the entity's public keys, keeping the prior keys in the key history along with the source
+// of the change.  Returns false, and makes no changes, if the keys are the same as the current keys.
+func (e *Entity) PinKeyChange(cipherPubKey, signingPubKey string, source KeySource, sourceDetails string) bool {
+	if e.PublicKeys.CipherPubKey == cipherPubKey && e.PublicKeys.SigningPubKey == signingPubKey {
+		return false
+	}
+
+	firstSeenDate := e.KeysDate
+	if firstSeenDate == "" {
+		firstSeenDate = e.CreatedDate
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	e.KeyHistory = append(e.KeyHistory, &KeyHistoryEntry{
+		CipherPubKey:        e.PublicKeys.CipherPubKey,
+		SigningPubKey:       e.PublicKeys.SigningPubKey,
+		FirstSeenDate:       firstSeenDate,
+		ReplacedDate:        now,
+		ChangeSource:        source,
+		ChangeSourceDetails: sourceDetails,
+	})
+
+	e.PublicKeys.CipherPubKey = cipherPubKey
+	e.PublicKeys.SigningPubKey = signingPubKey
+	e.KeysDate = now
+	e.KeySource = source
+	e.KeySourceDetails = sourceDetails
+	return true
+}
+
+// FindKeyHistory returns the most recent history entry with the signing key, or nil if there is none
+func (e *Entity) FindKeyHistory(signingPubKey string) *KeyHistoryEntry {
+	for i := len(e.KeyHistory) - 1; i >= 0; i-- {
+		if e.KeyHistory[i].SigningPubKey == signingPubKey {
+			return e.KeyHistory[i]
+		}
+	}
+
+	return nil
+}
+
+// PriorKeyInfos returns the entity's prior public keys, most recent first.  Keys that match the current keys
+// are not included.
+func (e *Entity) PriorKeyInfos() []*KeyInfo {
+	priorKeys := []*KeyInfo{}
+	for i := len(e.KeyHistory) - 1; i >= 0; i-- {
+		entry := e.KeyHistory[i]
+		if entry.CipherPubKey == e.PublicKeys.CipherPubKey && entry.SigningPubKey == e.PublicKeys.SigningPubKey {
+			continue
+		}
+
+		priorKeys = append(priorKeys, entry.KeyInfo(e.Name))
+	}
+
+	return priorKeys
+}
@@ -0,0 +1,79 @@
+package security
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEntity_PinKeyChange(t *testing.T) {
+	entity := &Entity{
+		Name:        "bob",
+		PublicKeys:  &KeyInfo{Name: "bob", CipherPubKey: "cipher-1", SigningPubKey: "signing-1"},
+		CreatedDate: "2024-01-01T00:00:00Z",
+	}
+
+	// The same keys are not a change
+	assert.False(t, entity.PinKeyChange("cipher-1", "signing-1", KeySourceManual, ""))
+	assert.Len(t, entity.KeyHistory, 0)
+
+	assert.True(t, entity.PinKeyChange("cipher-2", "signing-2", KeySourceImport, "file: bob.bee"))
+	assert.True(t, entity.PinKeyChange("cipher-3", "signing-3", KeySourceSuccession, ""))
+
+	assert.Equal(t, "cipher-3", entity.PublicKeys.CipherPubKey)
+	assert.Equal(t, "signing-3", entity.PublicKeys.SigningPubKey)
+	assert.Equal(t, KeySourceSuccession, entity.KeySource)
+	assert.NotEmpty(t, entity.KeysDate)
+
+	if !assert.Len(t, entity.KeyHistory, 2) {
+		return
+	}
+
+	first := entity.KeyHistory[0]
+	assert.Equal(t, "signing-1", first.SigningPubKey)
+	assert.Equal(t, "2024-01-01T00:00:00Z", first.FirstSeenDate)
+	assert.Equal(t, "Import (file: bob.bee)", first.ChangeSourceText())
+
+	second := entity.KeyHistory[1]
+	assert.Equal(t, "signing-2", second.SigningPubKey)
+	assert.Equal(t, first.ReplacedDate, second.FirstSeenDate)
+	assert.Equal(t, "Succession", second.ChangeSourceText())
+
+	// Clones do not share the history
+	clone := entity.Clone()
+	clone.KeyHistory[0].SigningPubKey = "changed"
+	assert.Equal(t, "signing-1", entity.KeyHistory[0].SigningPubKey)
+}
+
+func TestEntity_PriorKeyInfos(t *testing.T) {
+	entity := &Entity{
+		Name:       "bob",
+		PublicKeys: &KeyInfo{Name: "bob", CipherPubKey: "cipher-1", SigningPubKey: "signing-1"},
+	}
+
+	entity.PinKeyChange("cipher-2", "signing-2", KeySourceManual, "")
+	entity.PinKeyChange("cipher-3", "signing-3", KeySourceManual, "")
+
+	// Reverting to earlier keys does not list them as prior keys
+	entity.PinKeyChange("cipher-1", "signing-1", KeySourceManual, "")
+
+	priorKeys := entity.PriorKeyInfos()
+	if !assert.Len(t, priorKeys, 2) {
+		return
+	}
+
+	assert.Equal(t, "signing-3", priorKeys[0].SigningPubKey)
+	assert.Equal(t, "signing-2", priorKeys[1].SigningPubKey)
+	assert.Equal(t, "bob", priorKeys[0].Name)
+
+	assert.Equal(t, "signing-3", entity.FindKeyHistory("signing-3").SigningPubKey)
+	assert.Equal(t, "signing-1", entity.FindKeyHistory("signing-1").SigningPubKey)
+	assert.Nil(t, entity.FindKeyHistory("signing-4"))
+}
+
+func TestKeyHistoryEntry_IsReplacedAt(t *testing.T) {
+	entry := &KeyHistoryEntry{ReplacedDate: "2024-02-03T04:05:06Z"}
+	assert.False(t, entry.IsReplacedAt("2024-02-03T04:05:05Z"))
+	assert.True(t, entry.IsReplacedAt("2024-02-03T04:05:06Z"))
+	assert.True(t, entry.IsReplacedAt("2024-03-01T00:00:00Z"))
+	assert.True(t, entry.IsReplacedAt("not a date"))
+}
@@ -33,6 +33,26 @@ type KeyInfo struct {
 	Name          string
 	CipherPubKey  string
 	SigningPubKey string
+
+	// Subkeys are encryption subkeys certified by the signing key.  See CurrentCipherPubKey().
+	Subkeys []*SubkeyCertificate `msgpack:",omitempty"`
+}
+
+// ErrInvalidPublicKey is returned when a public key is not a valid nkeys public key of the expected type
+var ErrInvalidPublicKey = errors.New("invalid public key")
+
+// ValidatePublicKeys checks that the cipher key is an nkeys curve public key and the signing key is an
+// nkeys user public key
+func ValidatePublicKeys(cipherPubKey, signingPubKey string) error {
+	if !nkeys.IsValidPublicCurveKey(cipherPubKey) {
+		return fmt.Errorf("%w: cipher key is not a curve public key", ErrInvalidPublicKey)
+	}
+
+	if !nkeys.IsValidPublicUserKey(signingPubKey) {
+		return fmt.Errorf("%w: signing key is not a user public key", ErrInvalidPublicKey)
+	}
+
+	return nil
 }
 
 func NewKey() *KeyInfo {
@@ -60,6 +80,7 @@ func (ki *KeyInfo) Clone() (keyOut *KeyInfo) {
 		Name:          ki.Name,
 		CipherPubKey:  ki.CipherPubKey,
 		SigningPubKey: ki.SigningPubKey,
+		Subkeys:       CloneSubkeyCertificates(ki.Subkeys),
 	}
 
 	return keyOut
@@ -71,6 +92,7 @@ func (ki *KeyInfo) CopyFrom(sourceKeyInfo *KeyInfo) *KeyInfo {
 	ki.Name = sourceKeyInfo.Name
 	ki.CipherPubKey = sourceKeyInfo.CipherPubKey
 	ki.SigningPubKey = sourceKeyInfo.SigningPubKey
+	ki.Subkeys = CloneSubkeyCertificates(sourceKeyInfo.Subkeys)
 	return ki
 }
 
@@ -22,6 +22,7 @@ import (
 	"github.com/thoughtrealm/bumblebee/helpers"
 	"github.com/vmihailenco/msgpack/v5"
 	"io"
+	"time"
 )
 
 // KeyPairInfo defines the local environment's cipher keypairs created for sending/receiving and signing/verifying bundles
@@ -33,6 +34,31 @@ type KeyPairInfo struct {
 	// Seed is stored as NATS base32 string
 	CipherSeed  []byte
 	SigningSeed []byte
+
+	// Subkeys are encryption subkeys certified by the signing key.  The cipher seed above is the primary
+	// cipher key, which is still used when sending and for peers that do not know of any subkeys.
+	Subkeys []*Subkey `msgpack:",omitempty"`
+
+	// GroupEpoch is set for shared group identities, starting at 1.  It is incremented each time the group is
+	// rekeyed.  The cipher and signing seeds above are the keys for the current epoch.
+	GroupEpoch     int    `msgpack:",omitempty"`
+	GroupEpochDate string `msgpack:",omitempty"`
+
+	// GroupEpochKeys are the keys of the prior epochs held by this profile, newest first
+	GroupEpochKeys []*GroupEpochKey `msgpack:",omitempty"`
+
+	// GroupMembers are the user names the group keys are distributed to.  They are only tracked by the
+	// profile that manages the group.
+	GroupMembers []string `msgpack:",omitempty"`
+	GroupManaged bool     `msgpack:",omitempty"`
+
+	// GroupManagerSigningPubKey is the signing public key of the user that sent the group keys when the group
+	// was joined.  Keys for later epochs are only accepted from the same user.
+	GroupManagerSigningPubKey string `msgpack:",omitempty"`
+
+	// SSHFingerprint is set for keypairs imported from an OpenSSH ed25519 private key, in the form
+	// displayed by "ssh-keygen -l".  Bundles sent to the SSH identity's public key are opened with this keypair.
+	SSHFingerprint string `msgpack:",omitempty"`
 }
 
 func NewKeyPairInfoWithSeeds(name string) (*KeyPairInfo, error) {
@@ -74,11 +100,31 @@ func NewKeyPairInfoFromSeeds(name string, cipherSeed, signingSeed []byte) *KeyPa
 }
 
 func (kpi *KeyPairInfo) Clone() *KeyPairInfo {
-	return &KeyPairInfo{
-		Name:        kpi.Name,
-		CipherSeed:  bytes.Clone(kpi.CipherSeed),
-		SigningSeed: bytes.Clone(kpi.SigningSeed),
+	kpiOut := &KeyPairInfo{
+		Name:           kpi.Name,
+		CipherSeed:     bytes.Clone(kpi.CipherSeed),
+		SigningSeed:    bytes.Clone(kpi.SigningSeed),
+		GroupEpoch:     kpi.GroupEpoch,
+		GroupEpochDate: kpi.GroupEpochDate,
+		GroupManaged:   kpi.GroupManaged,
+		SSHFingerprint: kpi.SSHFingerprint,
+
+		GroupManagerSigningPubKey: kpi.GroupManagerSigningPubKey,
+	}
+
+	for _, subkey := range kpi.Subkeys {
+		kpiOut.Subkeys = append(kpiOut.Subkeys, subkey.Clone())
+	}
+
+	for _, epochKey := range kpi.GroupEpochKeys {
+		kpiOut.GroupEpochKeys = append(kpiOut.GroupEpochKeys, epochKey.Clone())
+	}
+
+	if kpi.GroupMembers != nil {
+		kpiOut.GroupMembers = append([]string{}, kpi.GroupMembers...)
 	}
+
+	return kpiOut
 }
 
 func (kpi *KeyPairInfo) PrivateKeys() (cipher, signing []byte, err error) {
@@ -205,6 +251,7 @@ func (kpi *KeyPairInfo) Print(headerText string, showAll bool) error {
 
 	fmt.Printf("Name: %s\n", kpi.Name)
 	fmt.Println("========================================================")
+	fp := NewFingerprint(cipherPublicKey, signingPublicKey)
 
 	if showAll {
 		fmt.Println("    Cipher Key")
@@ -218,6 +265,37 @@ func (kpi *KeyPairInfo) Print(headerText string, showAll bool) error {
 		fmt.Printf("    KP Seed     : %s\n", string(kpi.SigningSeed))
 		fmt.Printf("    Private Key : %s\n", string(signingPrivateKey))
 		fmt.Printf("    Public Key  : %s\n", signingPublicKey)
+		fmt.Println("")
+		fmt.Printf("    Fingerprint : %s\n", fp.Hex())
+		fmt.Printf("    Words       : %s\n", fp.Words())
+		for _, certificate := range kpi.SubkeyCertificates() {
+			fmt.Println("")
+			fmt.Println("    Subkey")
+			fmt.Println("    ---------------------------------------------------------")
+			fmt.Printf("    Public Key  : %s\n", certificate.CipherPubKey)
+			fmt.Printf("    Created     : %s\n", certificate.CreatedDate)
+			fmt.Printf("    Expires     : %s\n", certificate.ExpiryDate)
+		}
+		if kpi.SSHFingerprint != "" {
+			fmt.Println("")
+			fmt.Printf("    SSH Key     : %s %s\n", SSHKeyTypeEd25519, kpi.SSHFingerprint)
+		}
+		if kpi.IsGroup() {
+			fmt.Println("")
+			fmt.Println("    Group")
+			fmt.Println("    ---------------------------------------------------------")
+			fmt.Printf("    Epoch       : %d (since %s)\n", kpi.GroupEpoch, kpi.GroupEpochDate)
+			for _, epochKey := range kpi.PriorGroupEpochKeys() {
+				fmt.Printf("    Prior Epoch : %d (since %s) %s\n", epochKey.Epoch, epochKey.CreatedDate, string(epochKey.CipherSeed))
+				epochKey.Wipe()
+			}
+			if kpi.GroupManaged {
+				fmt.Printf("    Members     : %s\n", kpi.groupMembersText())
+			}
+			if kpi.GroupManagerSigningPubKey != "" {
+				fmt.Printf("    Manager Key : %s\n", kpi.GroupManagerSigningPubKey)
+			}
+		}
 
 		return nil
 
@@ -225,6 +303,24 @@ func (kpi *KeyPairInfo) Print(headerText string, showAll bool) error {
 
 	fmt.Printf("Cipher Public Key   : %s\n", cipherPublicKey)
 	fmt.Printf("Signing Public Key  : %s\n", signingPublicKey)
+	fmt.Printf("Fingerprint         : %s\n", fp.Hex())
+	fmt.Printf("Fingerprint Words   : %s\n", fp.Words())
+	for _, certificate := range kpi.SubkeyCertificates() {
+		fmt.Printf("Subkey              : %s\n", certificate.SummaryText(time.Now()))
+	}
+	if kpi.SSHFingerprint != "" {
+		fmt.Printf("SSH Key             : %s %s\n", SSHKeyTypeEd25519, kpi.SSHFingerprint)
+	}
+	if kpi.IsGroup() {
+		fmt.Printf("Group Epoch         : %d (since %s)\n", kpi.GroupEpoch, kpi.GroupEpochDate)
+		fmt.Printf("Prior Epoch Keys    : %d\n", len(kpi.GroupEpochKeys))
+		if kpi.GroupManaged {
+			fmt.Printf("Group Members       : %s\n", kpi.groupMembersText())
+		}
+		if kpi.GroupManagerSigningPubKey != "" {
+			fmt.Printf("Group Manager Key   : %s\n", kpi.GroupManagerSigningPubKey)
+		}
+	}
 
 	return nil
 }
@@ -237,6 +333,16 @@ func (kpi *KeyPairInfo) Wipe() {
 	if len(kpi.SigningSeed) != 0 {
 		_, _ = io.ReadFull(rand.Reader, kpi.SigningSeed[:])
 	}
+
+	for _, subkey := range kpi.Subkeys {
+		if len(subkey.CipherSeed) != 0 {
+			_, _ = io.ReadFull(rand.Reader, subkey.CipherSeed[:])
+		}
+	}
+
+	for _, epochKey := range kpi.GroupEpochKeys {
+		epochKey.Wipe()
+	}
 }
 
 func (kpi *KeyPairInfo) SignRandom() ([]byte, error) {
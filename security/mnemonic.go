@@ -0,0 +1,206 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+// Mnemonic recovery phrases follow BIP-39.  The phrase encodes random entropy with a checksum, and is stretched
+// with an optional passphrase into a 64 byte seed using PBKDF2-HMAC-SHA512.  The keypair seeds are derived
+// from that seed with HMAC-SHA512, so the same phrase and passphrase always recreate the same keypair.
+//
+// Phrases are compatible with other BIP-39 tools, but the keypair derivation is specific to bumblebee.
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nkeys"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+)
+
+var ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+const (
+	// MnemonicEntropySize is the entropy size for new phrases, which are 24 words
+	MnemonicEntropySize = 32
+
+	mnemonicBitsPerWord      = 11
+	mnemonicSeedIterations   = 2048
+	mnemonicSeedSize         = 64
+	mnemonicKeyPairDomain    = "bumblebee mnemonic keypair v1"
+	mnemonicSaltPrefix       = "mnemonic"
+	mnemonicMinEntropySize   = 16
+	mnemonicMaxEntropySize   = 32
+	mnemonicEntropySizeScale = 4
+)
+
+var mnemonicWordIndexes map[string]int
+
+func init() {
+	mnemonicWordIndexes = make(map[string]int, len(mnemonicWords))
+	for idx, word := range mnemonicWords {
+		mnemonicWordIndexes[word] = idx
+	}
+}
+
+// NewMnemonic returns a new 24 word recovery phrase from random entropy
+func NewMnemonic() (string, error) {
+	entropy := make([]byte, MnemonicEntropySize)
+	defer Wipe(entropy)
+
+	_, err := rand.Read(entropy)
+	if err != nil {
+		return "", fmt.Errorf("unable to read random entropy: %w", err)
+	}
+
+	return NewMnemonicFromEntropy(entropy)
+}
+
+// NewMnemonicFromEntropy returns the phrase for the entropy, which must be 16 to 32 bytes in steps of 4 bytes
+func NewMnemonicFromEntropy(entropy []byte) (string, error) {
+	if len(entropy) < mnemonicMinEntropySize || len(entropy) > mnemonicMaxEntropySize || len(entropy)%mnemonicEntropySizeScale != 0 {
+		return "", fmt.Errorf("%w: entropy must be 16 to 32 bytes in steps of 4, but is %d bytes", ErrInvalidMnemonic, len(entropy))
+	}
+
+	// The checksum is the first bit of the entropy hash for each 32 bits of entropy
+	checksum := sha256.Sum256(entropy)
+	checksumBits := len(entropy) / 4
+	bits := append(bytesToBits(entropy), bytesToBits(checksum[:1])[:checksumBits]...)
+
+	words := make([]string, len(bits)/mnemonicBitsPerWord)
+	for idx := range words {
+		wordIndex := 0
+		for _, bit := range bits[idx*mnemonicBitsPerWord : (idx+1)*mnemonicBitsPerWord] {
+			wordIndex = wordIndex<<1 | int(bit)
+		}
+
+		words[idx] = mnemonicWords[wordIndex]
+	}
+
+	return strings.Join(words, " "), nil
+}
+
+// NormalizeMnemonic lower cases the phrase and removes extra whitespace
+func NormalizeMnemonic(mnemonic string) string {
+	return strings.Join(strings.Fields(strings.ToLower(norm.NFKD.String(mnemonic))), " ")
+}
+
+// MnemonicEntropy validates the words and checksum of the phrase, and returns its entropy
+func MnemonicEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(NormalizeMnemonic(mnemonic))
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, fmt.Errorf("%w: phrase must be 12, 15, 18, 21 or 24 words, but has %d words", ErrInvalidMnemonic, len(words))
+	}
+
+	bits := make([]byte, 0, len(words)*mnemonicBitsPerWord)
+	for idx, word := range words {
+		wordIndex, found := mnemonicWordIndexes[word]
+		if !found {
+			return nil, fmt.Errorf("%w: word %d \"%s\" is not in the word list", ErrInvalidMnemonic, idx+1, word)
+		}
+
+		for shift := mnemonicBitsPerWord - 1; shift >= 0; shift-- {
+			bits = append(bits, byte(wordIndex>>shift&1))
+		}
+	}
+
+	checksumBits := len(bits) / 33
+	entropy := bitsToBytes(bits[:len(bits)-checksumBits])
+	checksum := sha256.Sum256(entropy)
+	for idx, bit := range bytesToBits(checksum[:1])[:checksumBits] {
+		if bits[len(bits)-checksumBits+idx] != bit {
+			Wipe(entropy)
+			return nil, fmt.Errorf("%w: checksum does not match, so a word may be misspelled or out of order", ErrInvalidMnemonic)
+		}
+	}
+
+	return entropy, nil
+}
+
+// MnemonicSeed validates the phrase and returns the 64 byte BIP-39 seed for the phrase and passphrase
+func MnemonicSeed(mnemonic, passphrase string) ([]byte, error) {
+	entropy, err := MnemonicEntropy(mnemonic)
+	if err != nil {
+		return nil, err
+	}
+	Wipe(entropy)
+
+	password := []byte(NormalizeMnemonic(mnemonic))
+	defer Wipe(password)
+
+	salt := []byte(mnemonicSaltPrefix + norm.NFKD.String(passphrase))
+	defer Wipe(salt)
+
+	return pbkdf2.Key(password, salt, mnemonicSeedIterations, mnemonicSeedSize, sha512.New), nil
+}
+
+// NewKeyPairInfoFromMnemonic derives the keypair for the phrase and passphrase.  The signing seed is an Ed25519
+// seed and the cipher seed is a clamped X25519 key, both derived from the BIP-39 seed with HMAC-SHA512.
+func NewKeyPairInfoFromMnemonic(name, mnemonic, passphrase string) (*KeyPairInfo, error) {
+	seed, err := MnemonicSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer Wipe(seed)
+
+	mac := hmac.New(sha512.New, []byte(mnemonicKeyPairDomain))
+	mac.Write(seed)
+	keyMaterial := mac.Sum(nil)
+	defer Wipe(keyMaterial)
+
+	signingKey := keyMaterial[:32]
+	curveKey := keyMaterial[32:]
+	curveKey[0] &= 248
+	curveKey[31] &= 127
+	curveKey[31] |= 64
+
+	cipherSeed, err := nkeys.EncodeSeed(nkeys.PrefixByteCurve, curveKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode the cipher seed: %w", err)
+	}
+	defer Wipe(cipherSeed)
+
+	signingSeed, err := nkeys.EncodeSeed(nkeys.PrefixByteUser, signingKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode the signing seed: %w", err)
+	}
+	defer Wipe(signingSeed)
+
+	return NewKeyPairInfoFromSeeds(name, cipherSeed, signingSeed), nil
+}
+
+// bytesToBits returns one byte per bit, with the most significant bit first
+func bytesToBits(data []byte) []byte {
+	bits := make([]byte, 0, len(data)*8)
+	for _, b := range data {
+		for shift := 7; shift >= 0; shift-- {
+			bits = append(bits, b>>shift&1)
+		}
+	}
+
+	return bits
+}
+
+// bitsToBytes is the reverse of bytesToBits.  The bit count must be a multiple of 8.
+func bitsToBytes(bits []byte) []byte {
+	data := make([]byte, len(bits)/8)
+	for idx, bit := range bits {
+		data[idx/8] |= bit << (7 - idx%8)
+	}
+
+	return data
+}
@@ -0,0 +1,142 @@
+package security
+
+import (
+	"encoding/hex"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// mnemonicTestVectors are from the BIP-39 reference test vectors, which all use the passphrase "TREZOR"
+var mnemonicTestVectors = []struct {
+	entropy  string
+	mnemonic string
+	seed     string
+}{
+	{
+		entropy:  "00000000000000000000000000000000",
+		mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		seed:     "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+	},
+	{
+		entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+		mnemonic: "legal winner thank year wave sausage worth useful legal winner thank yellow",
+		seed:     "2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+	},
+	{
+		entropy:  "ffffffffffffffffffffffffffffffff",
+		mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+		seed:     "ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+	},
+	{
+		entropy:  "808080808080808080808080808080808080808080808080",
+		mnemonic: "letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic avoid letter always",
+		seed:     "107d7c02a5aa6f38c58083ff74f04c607c2d2c0ecc55501dadd72d025b751bc27fe913ffb796f841c49b1d33b610cf0e91d3aa239027f5e99fe4ce9e5088cd65",
+	},
+	{
+		entropy:  "0000000000000000000000000000000000000000000000000000000000000000",
+		mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art",
+		seed:     "bda85446c68413707090a52022edd26a1c9462295029f2e60cd7c4f2bbd3097170af7a4d73245cafa9c3cca8d561a7c3de6f5d4a10be8ed2a5e608d68f92fcc8",
+	},
+	{
+		entropy:  "b63a9c59a6e641f288ebc103017f1da9f8290b3da6bdef7b",
+		mnemonic: "renew stay biology evidence goat welcome casual join adapt armor shuffle fault little machine walk stumble urge swap",
+		seed:     "9248d83e06f4cd98debf5b6f010542760df925ce46cf38a1bdb4e4de7d21f5c39366941c69e1bdbf2966e0f6e6dbece898a0e2f0a4c2b3e640953dfe8b7bbdc5",
+	},
+	{
+		entropy:  "3e141609b97933b66a060dcddc71fad1d91677db872031e85f4c015c5e7e8982",
+		mnemonic: "dignity pass list indicate nasty swamp pool script soccer toe leaf photo multiply desk host tomato cradle drill spread actor shine dismiss champion exotic",
+		seed:     "ff7f3184df8696d8bef94b6c03114dbee0ef89ff938712301d27ed8336ca89ef9635da20af07d4175f2bf5f3de130f39c9d9e8dd0472489c19b1a020a940da67",
+	},
+}
+
+func TestMnemonic_TestVectors(t *testing.T) {
+	for _, vector := range mnemonicTestVectors {
+		entropy, _ := hex.DecodeString(vector.entropy)
+
+		mnemonic, err := NewMnemonicFromEntropy(entropy)
+		if assert.Nil(t, err) {
+			assert.Equal(t, vector.mnemonic, mnemonic)
+		}
+
+		decodedEntropy, err := MnemonicEntropy(vector.mnemonic)
+		if assert.Nil(t, err) {
+			assert.Equal(t, vector.entropy, hex.EncodeToString(decodedEntropy))
+		}
+
+		seed, err := MnemonicSeed(vector.mnemonic, "TREZOR")
+		if assert.Nil(t, err) {
+			assert.Equal(t, vector.seed, hex.EncodeToString(seed))
+		}
+	}
+}
+
+func TestMnemonic_Invalid(t *testing.T) {
+	// "abandon" as the last word has the wrong checksum
+	_, err := MnemonicEntropy(strings.Repeat("abandon ", 12))
+	assert.True(t, errors.Is(err, ErrInvalidMnemonic))
+
+	_, err = MnemonicEntropy("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abuot")
+	assert.True(t, errors.Is(err, ErrInvalidMnemonic))
+
+	_, err = MnemonicEntropy("abandon abandon about")
+	assert.True(t, errors.Is(err, ErrInvalidMnemonic))
+
+	_, err = NewMnemonicFromEntropy(make([]byte, 15))
+	assert.True(t, errors.Is(err, ErrInvalidMnemonic))
+
+	_, err = NewKeyPairInfoFromMnemonic("test", strings.Repeat("zoo ", 12), "")
+	assert.True(t, errors.Is(err, ErrInvalidMnemonic))
+}
+
+func TestMnemonic_Normalize(t *testing.T) {
+	seed, err := MnemonicSeed("  Legal WINNER thank year\twave sausage worth useful\nlegal winner thank yellow ", "TREZOR")
+	if assert.Nil(t, err) {
+		assert.Equal(t, mnemonicTestVectors[1].seed, hex.EncodeToString(seed))
+	}
+}
+
+func TestNewMnemonic(t *testing.T) {
+	mnemonic, err := NewMnemonic()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Len(t, strings.Fields(mnemonic), 24)
+
+	entropy, err := MnemonicEntropy(mnemonic)
+	if assert.Nil(t, err) {
+		assert.Len(t, entropy, MnemonicEntropySize)
+	}
+}
+
+func TestNewKeyPairInfoFromMnemonic(t *testing.T) {
+	mnemonic := mnemonicTestVectors[4].mnemonic
+
+	kpi, err := NewKeyPairInfoFromMnemonic("test", mnemonic, "")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	cipherPubKey, signingPubKey, err := kpi.PublicKeys()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	// The derivation must never change, or existing phrases would no longer recover their keypairs
+	assert.Equal(t, "XCAEDMEYJMK4DKMILEVWDNLJHRQER5GH7WF3WF44R7GRYAIGJXAF53RK", cipherPubKey)
+	assert.Equal(t, "UDZOFVXDN7QHZPZR3BAGCZOYVZM7RP2JKQUEJH5ZUAYTFPIACZPDTDXU", signingPubKey)
+
+	recoveredKPI, err := NewKeyPairInfoFromMnemonic("test", strings.ToUpper(mnemonic), "")
+	if assert.Nil(t, err) {
+		assert.Equal(t, kpi.CipherSeed, recoveredKPI.CipherSeed)
+		assert.Equal(t, kpi.SigningSeed, recoveredKPI.SigningSeed)
+	}
+
+	passphraseKPI, err := NewKeyPairInfoFromMnemonic("test", mnemonic, "passphrase")
+	if assert.Nil(t, err) {
+		assert.NotEqual(t, kpi.CipherSeed, passphraseKPI.CipherSeed)
+		assert.NotEqual(t, kpi.SigningSeed, passphraseKPI.SigningSeed)
+	}
+}
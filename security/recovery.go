@@ -0,0 +1,181 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// recoverySetIDSize is the number of random bytes that identify the shares split from the same secret
+const recoverySetIDSize = 8
+
+// RecoveryShare is one share of a keypair that was split for social recovery.  The public keys are included so
+// that the recovered keypair can be verified, and so a contact can see whose share they are holding.
+type RecoveryShare struct {
+	SetID         string
+	Name          string
+	CipherPubKey  string
+	SigningPubKey string
+	Threshold     int
+	ShareCount    int
+	Index         int
+	Data          []byte
+	CreatedDate   string
+}
+
+// NewRecoveryShares splits the keypair into shareCount shares, any threshold of which can recover it.  The
+// secret that is split is the keypair export data, so subkeys are recovered along with the keypair.
+func NewRecoveryShares(kpi *KeyPairInfo, threshold, shareCount int) ([]*RecoveryShare, error) {
+	if kpi == nil {
+		return nil, errors.New("keypair info input is nil")
+	}
+
+	if kpi.IsGroup() {
+		return nil, fmt.Errorf("keypair \"%s\" is a group identity and can not be split for recovery", kpi.Name)
+	}
+
+	eki, err := NewExportKeyInfoFromKeyPairInfo(kpi)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := eki.ToBytes()
+	if err != nil {
+		return nil, fmt.Errorf("unable to serialize keypair: %w", err)
+	}
+	defer Wipe(secret)
+
+	secretShares, err := SplitSecret(secret, shareCount, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	setID := make([]byte, recoverySetIDSize)
+	_, err = io.ReadFull(rand.Reader, setID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read random set id: %w", err)
+	}
+
+	createdDate := time.Now().UTC().Format(time.RFC3339)
+	shares := make([]*RecoveryShare, 0, len(secretShares))
+	for _, secretShare := range secretShares {
+		shares = append(shares, &RecoveryShare{
+			SetID:         hex.EncodeToString(setID),
+			Name:          kpi.Name,
+			CipherPubKey:  eki.CipherPubKey,
+			SigningPubKey: eki.SigningPubKey,
+			Threshold:     threshold,
+			ShareCount:    shareCount,
+			Index:         int(secretShare.Index),
+			Data:          secretShare.Data,
+			CreatedDate:   createdDate,
+		})
+	}
+
+	return shares, nil
+}
+
+// CombineRecoveryShares recovers the keypair from its shares.  The shares must be from the same split and there
+// must be at least the threshold number of them.  The recovered keys are verified against the share's public keys.
+func CombineRecoveryShares(shares []*RecoveryShare) (*KeyPairInfo, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("no recovery shares provided")
+	}
+
+	first := shares[0]
+	secretShares := make([]*SecretShare, 0, len(shares))
+	for _, share := range shares {
+		if share.SetID != first.SetID {
+			return nil, fmt.Errorf("share %d for \"%s\" is from a different recovery set than share %d for \"%s\"",
+				share.Index, share.Name, first.Index, first.Name)
+		}
+
+		if share.Index < 1 || share.Index > MaxSecretShares {
+			return nil, fmt.Errorf("share has an invalid index of %d", share.Index)
+		}
+
+		secretShares = append(secretShares, &SecretShare{Index: byte(share.Index), Data: share.Data})
+	}
+
+	if len(shares) < first.Threshold {
+		return nil, fmt.Errorf("%d share(s) provided, but %d are required", len(shares), first.Threshold)
+	}
+
+	secret, err := CombineSecretShares(secretShares)
+	if err != nil {
+		return nil, err
+	}
+	defer Wipe(secret)
+
+	eki, err := NewExportKeyInfoFromBytes(secret)
+	if err != nil || eki.DataType != ExportDataTypeKeyPairInfo {
+		return nil, errors.New("recovered data is not a valid keypair, the shares may be damaged")
+	}
+	defer Wipe(eki.CipherSeed)
+	defer Wipe(eki.SigningSeed)
+
+	kpi := NewKeyPairInfoFromSeeds(eki.Name, eki.CipherSeed, eki.SigningSeed)
+	kpi.Subkeys = eki.KeyPairSubkeys
+
+	cipherPubKey, signingPubKey, err := kpi.PublicKeys()
+	if err != nil || cipherPubKey != first.CipherPubKey || signingPubKey != first.SigningPubKey {
+		kpi.Wipe()
+		return nil, errors.New("recovered keys do not match the public keys of the shares")
+	}
+
+	return kpi, nil
+}
+
+// Fingerprint returns the fingerprint of the keypair the share was split from
+func (rs *RecoveryShare) Fingerprint() Fingerprint {
+	return NewFingerprint(rs.CipherPubKey, rs.SigningPubKey)
+}
+
+// Wipe overwrites the share data
+func (rs *RecoveryShare) Wipe() {
+	Wipe(rs.Data)
+}
+
+// NewExportKeyInfoFromRecoveryShare exports a recovery share, so it can be bundled to a contact
+func NewExportKeyInfoFromRecoveryShare(rs *RecoveryShare) (*ExportKeyInfo, error) {
+	if rs == nil {
+		return nil, errors.New("recovery share input is nil")
+	}
+
+	return &ExportKeyInfo{
+		Name:          rs.Name,
+		DataType:      ExportDataTypeRecoveryShare,
+		CipherPubKey:  rs.CipherPubKey,
+		SigningPubKey: rs.SigningPubKey,
+		RecoveryShare: rs,
+	}, nil
+}
+
+// GetRecoveryShare returns the share from an ExportDataTypeRecoveryShare export
+func (eki *ExportKeyInfo) GetRecoveryShare() (*RecoveryShare, error) {
+	if eki.DataType != ExportDataTypeRecoveryShare {
+		return nil, fmt.Errorf("export data type %d is not a recovery share", int(eki.DataType))
+	}
+
+	if eki.RecoveryShare == nil || len(eki.RecoveryShare.Data) == 0 {
+		return nil, errors.New("export data has no recovery share")
+	}
+
+	return eki.RecoveryShare, nil
+}
@@ -0,0 +1,100 @@
+package security
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewRecoveryShares_Combine(t *testing.T) {
+	kpi, err := NewKeyPairInfoWithSeeds("me")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	subkey, err := kpi.AddSubkey(time.Hour)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	shares, err := NewRecoveryShares(kpi, 2, 3)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	if !assert.Len(t, shares, 3) {
+		return
+	}
+
+	fingerprint, err := kpi.Fingerprint()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	for _, share := range shares {
+		assert.Equal(t, shares[0].SetID, share.SetID)
+		assert.Equal(t, "me", share.Name)
+		assert.Equal(t, 2, share.Threshold)
+		assert.Equal(t, 3, share.ShareCount)
+		assert.True(t, fingerprint.Equal(share.Fingerprint()))
+	}
+
+	// shares survive the export round trip they are bundled with
+	eki, err := NewExportKeyInfoFromRecoveryShare(shares[2])
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	ekiBytes, err := eki.ToBytes()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	eki, err = NewExportKeyInfoFromBytes(ekiBytes)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	thirdShare, err := eki.GetRecoveryShare()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	recovered, err := CombineRecoveryShares([]*RecoveryShare{thirdShare, shares[0]})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, "me", recovered.Name)
+	assert.Equal(t, kpi.CipherSeed, recovered.CipherSeed)
+	assert.Equal(t, kpi.SigningSeed, recovered.SigningSeed)
+	if assert.Len(t, recovered.Subkeys, 1) {
+		assert.Equal(t, subkey.CipherSeed, recovered.Subkeys[0].CipherSeed)
+	}
+
+	_, err = CombineRecoveryShares(shares[:1])
+	assert.NotNil(t, err)
+
+	otherShares, err := NewRecoveryShares(kpi, 2, 3)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	_, err = CombineRecoveryShares([]*RecoveryShare{shares[0], otherShares[1]})
+	assert.NotNil(t, err)
+
+	// a damaged share is detected when the recovered keys are verified
+	shares[1].Data[0] ^= 0xff
+	_, err = CombineRecoveryShares(shares[:2])
+	assert.NotNil(t, err)
+}
+
+func TestNewRecoveryShares_Group(t *testing.T) {
+	kpi, err := NewGroupKeyPairInfo("team", nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	_, err = NewRecoveryShares(kpi, 2, 3)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,159 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"errors"
+	"fmt"
+	"github.com/vmihailenco/msgpack/v5"
+	"strings"
+	"time"
+)
+
+const revocationDomain = "bumblebee revocation v1"
+
+var ErrInvalidRevocation = errors.New("invalid revocation certificate")
+
+// Revocation reasons are stored as text in the certificate
+const (
+	RevocationReasonUnspecified = "unspecified"
+	RevocationReasonCompromised = "compromised"
+	RevocationReasonSuperseded  = "superseded"
+	RevocationReasonRetired     = "retired"
+)
+
+// IsValidRevocationReason returns true for one of the RevocationReason values
+func IsValidRevocationReason(reason string) bool {
+	switch strings.ToLower(reason) {
+	case RevocationReasonUnspecified, RevocationReasonCompromised, RevocationReasonSuperseded, RevocationReasonRetired:
+		return true
+	}
+
+	return false
+}
+
+// RevocationCertificate states that a keypair must no longer be used.  It is signed by the revoked signing key,
+// so it can be created ahead of time and stored offline, then published if the keypair is lost or stolen.
+type RevocationCertificate struct {
+	Name          string
+	CipherPubKey  string
+	SigningPubKey string
+	Reason        string
+	CreatedDate   string
+
+	// EffectiveDate is the RFC3339 date the keys stop being valid.  If empty, the revocation is
+	// effective from the date it is imported, which suits certificates created ahead of time.
+	EffectiveDate string `msgpack:",omitempty"`
+	Signature     []byte
+}
+
+// revocationSignedData is the portion of a RevocationCertificate covered by the signature
+type revocationSignedData struct {
+	Domain        string
+	Name          string
+	CipherPubKey  string
+	SigningPubKey string
+	Reason        string
+	CreatedDate   string
+	EffectiveDate string
+}
+
+// NewRevocationCertificate builds a certificate for the keypair's public keys and signs it with the keypair
+func NewRevocationCertificate(name string, kpi *KeyPairInfo, reason, effectiveDate string) (*RevocationCertificate, error) {
+	if kpi == nil {
+		return nil, errors.New("keypair input is nil")
+	}
+
+	if reason == "" {
+		reason = RevocationReasonUnspecified
+	}
+
+	if !IsValidRevocationReason(reason) {
+		return nil, fmt.Errorf("unknown revocation reason: %s", reason)
+	}
+
+	if effectiveDate != "" {
+		if _, err := time.Parse(time.RFC3339, effectiveDate); err != nil {
+			return nil, fmt.Errorf("effective date is not in RFC3339 format: %w", err)
+		}
+	}
+
+	cipherPubKey, signingPubKey, err := kpi.PublicKeys()
+	if err != nil {
+		return nil, fmt.Errorf("unable to extract public keys: %w", err)
+	}
+
+	rc := &RevocationCertificate{
+		Name:          name,
+		CipherPubKey:  cipherPubKey,
+		SigningPubKey: signingPubKey,
+		Reason:        strings.ToLower(reason),
+		CreatedDate:   time.Now().UTC().Format(time.RFC3339),
+		EffectiveDate: effectiveDate,
+	}
+
+	signedData, err := rc.signedData()
+	if err != nil {
+		return nil, err
+	}
+
+	rc.Signature, err = kpi.Sign(signedData)
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign revocation certificate: %w", err)
+	}
+
+	return rc, nil
+}
+
+func (rc *RevocationCertificate) signedData() ([]byte, error) {
+	signedData, err := msgpack.Marshal(&revocationSignedData{
+		Domain:        revocationDomain,
+		Name:          rc.Name,
+		CipherPubKey:  rc.CipherPubKey,
+		SigningPubKey: rc.SigningPubKey,
+		Reason:        rc.Reason,
+		CreatedDate:   rc.CreatedDate,
+		EffectiveDate: rc.EffectiveDate,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode revocation data: %w", err)
+	}
+
+	return signedData, nil
+}
+
+// Verify checks that the certificate is signed by the signing key it revokes
+func (rc *RevocationCertificate) Verify() error {
+	signedData, err := rc.signedData()
+	if err != nil {
+		return err
+	}
+
+	keyInfo := rc.KeyInfo()
+	if _, err = keyInfo.Verify(signedData, rc.Signature); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidRevocation, err)
+	}
+
+	return nil
+}
+
+func (rc *RevocationCertificate) KeyInfo() *KeyInfo {
+	return &KeyInfo{Name: rc.Name, CipherPubKey: rc.CipherPubKey, SigningPubKey: rc.SigningPubKey}
+}
+
+func (rc *RevocationCertificate) Clone() *RevocationCertificate {
+	rcOut := *rc
+	rcOut.Signature = append([]byte(nil), rc.Signature...)
+	return &rcOut
+}
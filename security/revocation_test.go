@@ -0,0 +1,46 @@
+package security
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRevocationCertificate_SignAndVerify(t *testing.T) {
+	kpi, err := NewKeyPairInfoWithSeeds("revoked")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	rc, err := NewRevocationCertificate("bob", kpi, RevocationReasonCompromised, "2024-02-03T04:05:06Z")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, rc.Verify())
+
+	cipherPubKey, signingPubKey, _ := kpi.PublicKeys()
+	assert.Equal(t, cipherPubKey, rc.CipherPubKey)
+	assert.Equal(t, signingPubKey, rc.SigningPubKey)
+
+	// Any change to the signed values invalidates the certificate
+	tampered := rc.Clone()
+	tampered.EffectiveDate = "2030-01-01T00:00:00Z"
+	assert.True(t, errors.Is(tampered.Verify(), ErrInvalidRevocation))
+
+	_, err = NewRevocationCertificate("bob", kpi, "lost-it", "")
+	assert.NotNil(t, err)
+
+	_, err = NewRevocationCertificate("bob", kpi, "", "yesterday")
+	assert.NotNil(t, err)
+}
+
+func TestEntity_IsRevokedAt(t *testing.T) {
+	entity := &Entity{Name: "bob", Trust: TrustLevelRevoked, RevokedDate: "2024-02-03T04:05:06Z"}
+	assert.False(t, entity.IsRevokedAt("2024-02-03T04:05:05Z"))
+	assert.True(t, entity.IsRevokedAt("2024-02-03T04:05:06Z"))
+	assert.True(t, entity.IsRevokedAt("2024-03-01T00:00:00Z"))
+	assert.True(t, entity.IsRevokedAt("not a date"))
+
+	entity.Trust = TrustLevelTOFU
+	assert.False(t, entity.IsRevokedAt("2024-03-01T00:00:00Z"))
+}
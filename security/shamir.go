@@ -0,0 +1,187 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+// Secrets are split with Shamir's secret sharing over GF(2^8), one byte at a time.  Each byte of the secret is the
+// constant term of a random polynomial of degree threshold-1, and each share holds that polynomial evaluated at the
+// share's index.  Any threshold shares recover the secret by Lagrange interpolation at zero.  Fewer shares reveal
+// nothing about it.
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// MaxSecretShares is the largest number of shares a secret can be split into, since share indexes are a
+	// single non-zero byte
+	MaxSecretShares = 255
+
+	// gf256Polynomial is the AES reduction polynomial, x^8 + x^4 + x^3 + x + 1
+	gf256Polynomial = 0x11b
+)
+
+var (
+	gf256Exp [510]byte
+	gf256Log [256]byte
+)
+
+func init() {
+	// 3 is a generator of the multiplicative group, so its powers fill the tables
+	value := 1
+	for idx := 0; idx < 255; idx++ {
+		gf256Exp[idx] = byte(value)
+		gf256Exp[idx+255] = byte(value)
+		gf256Log[value] = byte(idx)
+
+		value ^= value << 1
+		if value&0x100 != 0 {
+			value ^= gf256Polynomial
+		}
+	}
+}
+
+// SecretShare is one share of a split secret
+type SecretShare struct {
+	Index byte
+	Data  []byte
+}
+
+// SplitSecret splits the secret into shareCount shares, any threshold of which can recover it
+func SplitSecret(secret []byte, shareCount, threshold int) ([]*SecretShare, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("secret is empty")
+	}
+
+	if threshold < 2 {
+		return nil, fmt.Errorf("threshold must be at least 2, but is %d", threshold)
+	}
+
+	if shareCount < threshold {
+		return nil, fmt.Errorf("share count %d is less than the threshold %d", shareCount, threshold)
+	}
+
+	if shareCount > MaxSecretShares {
+		return nil, fmt.Errorf("share count %d is more than the maximum of %d", shareCount, MaxSecretShares)
+	}
+
+	shares := make([]*SecretShare, shareCount)
+	for idx := range shares {
+		shares[idx] = &SecretShare{Index: byte(idx + 1), Data: make([]byte, len(secret))}
+	}
+
+	coefficients := make([]byte, threshold)
+	defer Wipe(coefficients)
+
+	for byteIdx, secretByte := range secret {
+		coefficients[0] = secretByte
+		_, err := io.ReadFull(rand.Reader, coefficients[1:])
+		if err != nil {
+			return nil, fmt.Errorf("unable to read random coefficients: %w", err)
+		}
+
+		for _, share := range shares {
+			share.Data[byteIdx] = gf256EvalPolynomial(coefficients, share.Index)
+		}
+	}
+
+	return shares, nil
+}
+
+// CombineSecretShares recovers a secret from its shares.  At least the threshold number of shares must be provided,
+// which is not known here, so fewer shares silently return the wrong secret.  Callers should verify the result.
+func CombineSecretShares(shares []*SecretShare) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, fmt.Errorf("at least 2 shares are required, but %d were provided", len(shares))
+	}
+
+	secretLen := len(shares[0].Data)
+	seen := make(map[byte]bool, len(shares))
+	for _, share := range shares {
+		if share.Index == 0 {
+			return nil, errors.New("share has an invalid index of 0")
+		}
+
+		if seen[share.Index] {
+			return nil, fmt.Errorf("share index %d was provided more than once", share.Index)
+		}
+		seen[share.Index] = true
+
+		if len(share.Data) != secretLen || secretLen == 0 {
+			return nil, errors.New("shares are not the same length")
+		}
+	}
+
+	// The Lagrange basis at zero for each share depends only on the indexes, so it is the same for every byte
+	basis := make([]byte, len(shares))
+	for i, share := range shares {
+		basis[i] = 1
+		for j, other := range shares {
+			if i == j {
+				continue
+			}
+
+			basis[i] = gf256Mul(basis[i], gf256Div(other.Index, other.Index^share.Index))
+		}
+	}
+
+	secret := make([]byte, secretLen)
+	for byteIdx := range secret {
+		var value byte
+		for i, share := range shares {
+			value ^= gf256Mul(share.Data[byteIdx], basis[i])
+		}
+
+		secret[byteIdx] = value
+	}
+
+	return secret, nil
+}
+
+// Wipe overwrites the share data
+func (ss *SecretShare) Wipe() {
+	Wipe(ss.Data)
+}
+
+// gf256EvalPolynomial evaluates the polynomial at x using Horner's method.  Coefficients are lowest degree first.
+func gf256EvalPolynomial(coefficients []byte, x byte) byte {
+	var value byte
+	for idx := len(coefficients) - 1; idx >= 0; idx-- {
+		value = gf256Mul(value, x) ^ coefficients[idx]
+	}
+
+	return value
+}
+
+func gf256Mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+
+	return gf256Exp[int(gf256Log[a])+int(gf256Log[b])]
+}
+
+func gf256Div(a, b byte) byte {
+	if b == 0 {
+		panic("gf256: division by zero")
+	}
+
+	if a == 0 {
+		return 0
+	}
+
+	return gf256Exp[int(gf256Log[a])+255-int(gf256Log[b])]
+}
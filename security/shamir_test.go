@@ -0,0 +1,93 @@
+package security
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGF256_MulDiv(t *testing.T) {
+	// 0x53 and 0xca are inverses in the AES field
+	assert.Equal(t, byte(0x01), gf256Mul(0x53, 0xca))
+	assert.Equal(t, byte(0xc1), gf256Mul(0x57, 0x83))
+
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			product := gf256Mul(byte(a), byte(b))
+			if !assert.Equal(t, byte(a), gf256Div(product, byte(b))) {
+				return
+			}
+		}
+	}
+}
+
+func TestSplitSecret_Combine(t *testing.T) {
+	secret := []byte("the keys to the kingdom")
+	shares, err := SplitSecret(secret, 5, 3)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	if !assert.Len(t, shares, 5) {
+		return
+	}
+
+	for idx, share := range shares {
+		assert.Equal(t, byte(idx+1), share.Index)
+		assert.Len(t, share.Data, len(secret))
+		assert.False(t, bytes.Equal(secret, share.Data))
+	}
+
+	// every combination of 3 or more shares recovers the secret
+	for mask := 0; mask < 1<<len(shares); mask++ {
+		var subset []*SecretShare
+		for idx, share := range shares {
+			if mask&(1<<idx) != 0 {
+				subset = append(subset, share)
+			}
+		}
+
+		if len(subset) < 3 {
+			continue
+		}
+
+		recovered, err := CombineSecretShares(subset)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, secret, recovered)
+	}
+
+	// 2 shares are below the threshold and do not recover the secret
+	recovered, err := CombineSecretShares(shares[:2])
+	assert.Nil(t, err)
+	assert.NotEqual(t, secret, recovered)
+}
+
+func TestSplitSecret_InvalidInput(t *testing.T) {
+	_, err := SplitSecret(nil, 3, 2)
+	assert.NotNil(t, err)
+
+	_, err = SplitSecret([]byte("secret"), 3, 1)
+	assert.NotNil(t, err)
+
+	_, err = SplitSecret([]byte("secret"), 2, 3)
+	assert.NotNil(t, err)
+
+	_, err = SplitSecret([]byte("secret"), 256, 2)
+	assert.NotNil(t, err)
+
+	shares, err := SplitSecret([]byte("secret"), 3, 2)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	_, err = CombineSecretShares(shares[:1])
+	assert.NotNil(t, err)
+
+	_, err = CombineSecretShares([]*SecretShare{shares[0], shares[0]})
+	assert.NotNil(t, err)
+
+	_, err = CombineSecretShares([]*SecretShare{shares[0], {Index: 2, Data: []byte("short")}})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,379 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+// SSH ed25519 keys are parsed by hand, since only the ed25519 formats are needed.  The signing key is the ed25519
+// key itself.  The cipher key is the X25519 form of the same key, which is the birational map from the Edwards
+// curve to the Montgomery curve for public keys, and the clamped SHA-512 hash of the seed for private keys.
+// This is the same conversion libsodium and age use, so a sender only needs the recipient's SSH public key.
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nkeys"
+	"math/big"
+	"strings"
+)
+
+// SSHKeyTypeEd25519 is the only SSH key type that can be converted to bumblebee keys
+const SSHKeyTypeEd25519 = "ssh-ed25519"
+
+const sshPrivateKeyMagic = "openssh-key-v1\x00"
+
+var (
+	// ErrUnsupportedSSHKey is returned for SSH keys that are not ed25519 keys
+	ErrUnsupportedSSHKey = errors.New("unsupported ssh key type, only ssh-ed25519 keys are supported")
+	// ErrEncryptedSSHKey is returned for passphrase protected OpenSSH private keys
+	ErrEncryptedSSHKey = errors.New("the ssh private key is passphrase protected, remove the passphrase with \"ssh-keygen -p\" on a copy of the key first")
+	// ErrInvalidSSHKey is returned when an SSH key can not be parsed
+	ErrInvalidSSHKey = errors.New("invalid ssh key")
+)
+
+// SSHPublicKey is an ssh-ed25519 public key, such as one line of an authorized_keys file
+type SSHPublicKey struct {
+	Key     ed25519.PublicKey
+	Comment string
+}
+
+// SSHPrivateKey is an unencrypted OpenSSH ed25519 private key
+type SSHPrivateKey struct {
+	Seed      []byte
+	PublicKey *SSHPublicKey
+}
+
+// IsSSHKeyType returns true if the text is an SSH public key algorithm name, such as "ssh-rsa" or "ssh-ed25519"
+func IsSSHKeyType(text string) bool {
+	return strings.HasPrefix(text, "ssh-") ||
+		strings.HasPrefix(text, "ecdsa-sha2-") ||
+		strings.HasPrefix(text, "sk-")
+}
+
+// ParseSSHPublicKey parses a public key in the authorized_keys format.  Options before the key type are ignored.
+func ParseSSHPublicKey(line string) (*SSHPublicKey, error) {
+	fields := strings.Fields(line)
+	for i, field := range fields {
+		if !IsSSHKeyType(field) {
+			continue
+		}
+
+		if field != SSHKeyTypeEd25519 {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedSSHKey, field)
+		}
+
+		if i+1 >= len(fields) {
+			return nil, fmt.Errorf("%w: no key data after the key type", ErrInvalidSSHKey)
+		}
+
+		blob, err := base64.StdEncoding.DecodeString(fields[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to decode key data: %s", ErrInvalidSSHKey, err)
+		}
+
+		publicKey, err := parseSSHPublicKeyBlob(blob)
+		if err != nil {
+			return nil, err
+		}
+
+		publicKey.Comment = strings.Join(fields[i+2:], " ")
+		return publicKey, nil
+	}
+
+	return nil, fmt.Errorf("%w: no ssh key type found", ErrInvalidSSHKey)
+}
+
+func parseSSHPublicKeyBlob(blob []byte) (*SSHPublicKey, error) {
+	keyType, rest, ok := readSSHString(blob)
+	if !ok {
+		return nil, fmt.Errorf("%w: unable to read the key type", ErrInvalidSSHKey)
+	}
+
+	if string(keyType) != SSHKeyTypeEd25519 {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedSSHKey, string(keyType))
+	}
+
+	key, rest, ok := readSSHString(rest)
+	if !ok || len(key) != ed25519.PublicKeySize || len(rest) != 0 {
+		return nil, fmt.Errorf("%w: the ed25519 key data is malformed", ErrInvalidSSHKey)
+	}
+
+	return &SSHPublicKey{Key: ed25519.PublicKey(bytes.Clone(key))}, nil
+}
+
+// Blob returns the key in the SSH wire format
+func (spk *SSHPublicKey) Blob() []byte {
+	blob := appendSSHString(nil, []byte(SSHKeyTypeEd25519))
+	return appendSSHString(blob, spk.Key)
+}
+
+// Fingerprint returns the SHA256 fingerprint in the form displayed by "ssh-keygen -l"
+func (spk *SSHPublicKey) Fingerprint() string {
+	digest := sha256.Sum256(spk.Blob())
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(digest[:])
+}
+
+// AuthorizedKey returns the key as an authorized_keys line
+func (spk *SSHPublicKey) AuthorizedKey() string {
+	line := SSHKeyTypeEd25519 + " " + base64.StdEncoding.EncodeToString(spk.Blob())
+	if spk.Comment != "" {
+		line += " " + spk.Comment
+	}
+
+	return line
+}
+
+// SourceDetails describes the key for an entity's key source details
+func (spk *SSHPublicKey) SourceDetails() string {
+	details := SSHKeyTypeEd25519 + " " + spk.Fingerprint()
+	if spk.Comment != "" {
+		details += " " + spk.Comment
+	}
+
+	return details
+}
+
+// PublicKeys returns the nkeys cipher and signing public keys for the SSH key
+func (spk *SSHPublicKey) PublicKeys() (cipherPubKey, signingPubKey string, err error) {
+	curveKey, err := ed25519PublicKeyToX25519(spk.Key)
+	if err != nil {
+		return "", "", err
+	}
+
+	cipherBytes, err := nkeys.Encode(nkeys.PrefixByteCurve, curveKey)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to encode the cipher public key: %w", err)
+	}
+
+	signingBytes, err := nkeys.Encode(nkeys.PrefixByteUser, spk.Key)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to encode the signing public key: %w", err)
+	}
+
+	return string(cipherBytes), string(signingBytes), nil
+}
+
+// KeyInfo returns a KeyInfo with the nkeys public keys for the SSH key
+func (spk *SSHPublicKey) KeyInfo(name string) (*KeyInfo, error) {
+	cipherPubKey, signingPubKey, err := spk.PublicKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKeyInfo(name, cipherPubKey, signingPubKey)
+}
+
+// ed25519PublicKeyToX25519 maps an Edwards point to the Montgomery u coordinate, u = (1 + y) / (1 - y).
+// Points that are not on the curve are refused, so a mangled key is not silently converted.
+func ed25519PublicKeyToX25519(publicKey ed25519.PublicKey) ([]byte, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: ed25519 public keys are %d bytes", ErrInvalidSSHKey, ed25519.PublicKeySize)
+	}
+
+	p := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+
+	// The key is the little endian y coordinate, with the sign of x in the top bit
+	yBytes := make([]byte, ed25519.PublicKeySize)
+	for i := range publicKey {
+		yBytes[ed25519.PublicKeySize-1-i] = publicKey[i]
+	}
+	yBytes[0] &= 0x7f
+	y := new(big.Int).SetBytes(yBytes)
+
+	one := big.NewInt(1)
+	if y.Cmp(p) >= 0 || y.Cmp(one) == 0 {
+		return nil, fmt.Errorf("%w: the ed25519 key is not a valid curve point", ErrInvalidSSHKey)
+	}
+
+	// The point is on the curve if x^2 = (y^2 - 1) / (d*y^2 + 1) has a solution, where d = -121665/121666
+	d := new(big.Int).Neg(big.NewInt(121665))
+	d.Mul(d, new(big.Int).ModInverse(big.NewInt(121666), p))
+	d.Mod(d, p)
+
+	ySquared := new(big.Int).Mul(y, y)
+	ySquared.Mod(ySquared, p)
+	numerator := new(big.Int).Sub(ySquared, one)
+	numerator.Mod(numerator, p)
+	denominator := new(big.Int).Mul(d, ySquared)
+	denominator.Add(denominator, one)
+	denominator.Mod(denominator, p)
+	xSquared := new(big.Int).Mul(numerator, new(big.Int).ModInverse(denominator, p))
+	xSquared.Mod(xSquared, p)
+	if xSquared.Sign() != 0 && big.Jacobi(xSquared, p) != 1 {
+		return nil, fmt.Errorf("%w: the ed25519 key is not a valid curve point", ErrInvalidSSHKey)
+	}
+
+	u := new(big.Int).Add(one, y)
+	oneMinusY := new(big.Int).Sub(one, y)
+	oneMinusY.Mod(oneMinusY, p)
+	u.Mul(u, new(big.Int).ModInverse(oneMinusY, p))
+	u.Mod(u, p)
+
+	uBytes := u.FillBytes(make([]byte, 32))
+	curveKey := make([]byte, 32)
+	for i := range uBytes {
+		curveKey[31-i] = uBytes[i]
+	}
+
+	return curveKey, nil
+}
+
+// ParseSSHPrivateKey parses an unencrypted OpenSSH ed25519 private key file
+func ParseSSHPrivateKey(data []byte) (*SSHPrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "OPENSSH PRIVATE KEY" {
+		return nil, fmt.Errorf("%w: not an OpenSSH private key file", ErrInvalidSSHKey)
+	}
+	defer Wipe(block.Bytes)
+
+	if !bytes.HasPrefix(block.Bytes, []byte(sshPrivateKeyMagic)) {
+		return nil, fmt.Errorf("%w: not an openssh-key-v1 private key", ErrInvalidSSHKey)
+	}
+
+	rest := block.Bytes[len(sshPrivateKeyMagic):]
+	cipherName, rest, ok := readSSHString(rest)
+	if !ok {
+		return nil, fmt.Errorf("%w: unable to read the cipher name", ErrInvalidSSHKey)
+	}
+
+	kdfName, rest, ok := readSSHString(rest)
+	if !ok {
+		return nil, fmt.Errorf("%w: unable to read the kdf name", ErrInvalidSSHKey)
+	}
+
+	if string(cipherName) != "none" || string(kdfName) != "none" {
+		return nil, ErrEncryptedSSHKey
+	}
+
+	_, rest, ok = readSSHString(rest) // kdf options, empty for unencrypted keys
+	if !ok || len(rest) < 4 {
+		return nil, fmt.Errorf("%w: unable to read the key count", ErrInvalidSSHKey)
+	}
+
+	if binary.BigEndian.Uint32(rest) != 1 {
+		return nil, fmt.Errorf("%w: files with more than one key are not supported", ErrInvalidSSHKey)
+	}
+	rest = rest[4:]
+
+	publicBlob, rest, ok := readSSHString(rest)
+	if !ok {
+		return nil, fmt.Errorf("%w: unable to read the public key", ErrInvalidSSHKey)
+	}
+
+	publicKey, err := parseSSHPublicKeyBlob(publicBlob)
+	if err != nil {
+		return nil, err
+	}
+
+	privateBlock, _, ok := readSSHString(rest)
+	if !ok || len(privateBlock) < 8 {
+		return nil, fmt.Errorf("%w: unable to read the private key", ErrInvalidSSHKey)
+	}
+
+	// The two check values are the same random value, which detects a wrong passphrase for encrypted keys
+	if binary.BigEndian.Uint32(privateBlock) != binary.BigEndian.Uint32(privateBlock[4:]) {
+		return nil, fmt.Errorf("%w: the private key check values do not match", ErrInvalidSSHKey)
+	}
+
+	rest = privateBlock[8:]
+	keyType, rest, ok := readSSHString(rest)
+	if !ok || string(keyType) != SSHKeyTypeEd25519 {
+		return nil, ErrUnsupportedSSHKey
+	}
+
+	privatePublicKey, rest, ok := readSSHString(rest)
+	if !ok || !bytes.Equal(privatePublicKey, publicKey.Key) {
+		return nil, fmt.Errorf("%w: the private key does not match the public key", ErrInvalidSSHKey)
+	}
+
+	// The private key is the 32 byte seed followed by the 32 byte public key
+	privateKey, rest, ok := readSSHString(rest)
+	if !ok || len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%w: the ed25519 private key data is malformed", ErrInvalidSSHKey)
+	}
+
+	comment, _, ok := readSSHString(rest)
+	if !ok {
+		return nil, fmt.Errorf("%w: unable to read the key comment", ErrInvalidSSHKey)
+	}
+	publicKey.Comment = string(comment)
+
+	sshPrivateKey := &SSHPrivateKey{
+		Seed:      bytes.Clone(privateKey[:ed25519.SeedSize]),
+		PublicKey: publicKey,
+	}
+
+	derivedPublicKey := ed25519.NewKeyFromSeed(sshPrivateKey.Seed).Public().(ed25519.PublicKey)
+	if !bytes.Equal(derivedPublicKey, publicKey.Key) {
+		sshPrivateKey.Wipe()
+		return nil, fmt.Errorf("%w: the private key does not match the public key", ErrInvalidSSHKey)
+	}
+
+	return sshPrivateKey, nil
+}
+
+// KeyPairInfo returns a keypair for the SSH key.  The signing seed is the ed25519 seed, and the cipher seed is
+// the X25519 private key derived from it.
+func (spk *SSHPrivateKey) KeyPairInfo(name string) (*KeyPairInfo, error) {
+	digest := sha512.Sum512(spk.Seed)
+	defer Wipe(digest[:])
+
+	curveKey := digest[:32]
+	curveKey[0] &= 248
+	curveKey[31] &= 127
+	curveKey[31] |= 64
+
+	cipherSeed, err := nkeys.EncodeSeed(nkeys.PrefixByteCurve, curveKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode the cipher seed: %w", err)
+	}
+	defer Wipe(cipherSeed)
+
+	signingSeed, err := nkeys.EncodeSeed(nkeys.PrefixByteUser, spk.Seed)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode the signing seed: %w", err)
+	}
+	defer Wipe(signingSeed)
+
+	kpi := NewKeyPairInfoFromSeeds(name, cipherSeed, signingSeed)
+	kpi.SSHFingerprint = spk.PublicKey.Fingerprint()
+	return kpi, nil
+}
+
+func (spk *SSHPrivateKey) Wipe() {
+	Wipe(spk.Seed)
+}
+
+func readSSHString(data []byte) (value, rest []byte, ok bool) {
+	if len(data) < 4 {
+		return nil, nil, false
+	}
+
+	length := binary.BigEndian.Uint32(data)
+	if uint64(length) > uint64(len(data)-4) {
+		return nil, nil, false
+	}
+
+	return data[4 : 4+length], data[4+length:], true
+}
+
+func appendSSHString(data, value []byte) []byte {
+	data = binary.BigEndian.AppendUint32(data, uint32(len(value)))
+	return append(data, value...)
+}
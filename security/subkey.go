@@ -0,0 +1,347 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nkeys"
+	"github.com/vmihailenco/msgpack/v5"
+	"sort"
+	"time"
+)
+
+const (
+	subkeyDomain = "bumblebee subkey v1"
+
+	// SubkeyDefaultValidity is how long a new encryption subkey is valid for, if not provided
+	SubkeyDefaultValidity = 90 * 24 * time.Hour
+
+	// SubkeyOpenGracePeriod is how long after expiry a subkey is still tried when opening bundles,
+	// since bundles may be opened some time after they were sent
+	SubkeyOpenGracePeriod = 90 * 24 * time.Hour
+)
+
+var ErrInvalidSubkey = errors.New("invalid subkey certificate")
+
+// SubkeyCertificate is the public part of an encryption subkey.  It is signed by the identity signing key,
+// so peers that hold the identity key can accept new subkeys without verifying them out-of-band.
+type SubkeyCertificate struct {
+	CipherPubKey string
+	CreatedDate  string
+	ExpiryDate   string
+	Signature    []byte
+}
+
+// subkeySignedData is the portion of a SubkeyCertificate covered by the signature
+type subkeySignedData struct {
+	Domain                string
+	IdentitySigningPubKey string
+	CipherPubKey          string
+	CreatedDate           string
+	ExpiryDate            string
+}
+
+// Subkey is an encryption subkey with its private seed, stored with the identity's KeyPairInfo
+type Subkey struct {
+	CipherSeed  []byte
+	Certificate *SubkeyCertificate
+}
+
+func (sc *SubkeyCertificate) signedData(identitySigningPubKey string) ([]byte, error) {
+	signedData, err := msgpack.Marshal(&subkeySignedData{
+		Domain:                subkeyDomain,
+		IdentitySigningPubKey: identitySigningPubKey,
+		CipherPubKey:          sc.CipherPubKey,
+		CreatedDate:           sc.CreatedDate,
+		ExpiryDate:            sc.ExpiryDate,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode subkey data: %w", err)
+	}
+
+	return signedData, nil
+}
+
+// Verify checks that the certificate is signed by the identity signing key and has valid dates
+func (sc *SubkeyCertificate) Verify(identitySigningPubKey string) error {
+	createdTime, expiryTime, err := sc.dates()
+	if err != nil {
+		return err
+	}
+
+	if !expiryTime.After(createdTime) {
+		return fmt.Errorf("%w: expiry date is not after the created date", ErrInvalidSubkey)
+	}
+
+	signedData, err := sc.signedData(identitySigningPubKey)
+	if err != nil {
+		return err
+	}
+
+	verifyKP, err := nkeys.FromPublicKey(identitySigningPubKey)
+	if err != nil {
+		return fmt.Errorf("%w: unable to read identity signing key: %s", ErrInvalidSubkey, err)
+	}
+
+	err = verifyKP.Verify(signedData, sc.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidSubkey, err)
+	}
+
+	return nil
+}
+
+func (sc *SubkeyCertificate) dates() (createdTime, expiryTime time.Time, err error) {
+	createdTime, err = time.Parse(time.RFC3339, sc.CreatedDate)
+	if err != nil {
+		return createdTime, expiryTime, fmt.Errorf("%w: invalid created date: %s", ErrInvalidSubkey, err)
+	}
+
+	expiryTime, err = time.Parse(time.RFC3339, sc.ExpiryDate)
+	if err != nil {
+		return createdTime, expiryTime, fmt.Errorf("%w: invalid expiry date: %s", ErrInvalidSubkey, err)
+	}
+
+	return createdTime, expiryTime, nil
+}
+
+// IsValidAt returns true if the subkey was created on or before t and expires after t
+func (sc *SubkeyCertificate) IsValidAt(t time.Time) bool {
+	createdTime, expiryTime, err := sc.dates()
+	if err != nil {
+		return false
+	}
+
+	return !t.Before(createdTime) && t.Before(expiryTime)
+}
+
+// IsUsableForOpenAt returns true if the subkey is valid at t, or expired within the open grace period
+func (sc *SubkeyCertificate) IsUsableForOpenAt(t time.Time) bool {
+	_, expiryTime, err := sc.dates()
+	if err != nil {
+		return false
+	}
+
+	return t.Before(expiryTime.Add(SubkeyOpenGracePeriod))
+}
+
+// SummaryText returns the subkey's public key and expiry, with its status at t
+func (sc *SubkeyCertificate) SummaryText(t time.Time) string {
+	var status string
+	createdTime, expiryTime, err := sc.dates()
+	switch {
+	case err != nil:
+		status = "invalid dates"
+	case t.Before(createdTime):
+		status = "not yet valid"
+	case t.Before(expiryTime):
+		status = "valid"
+	case sc.IsUsableForOpenAt(t):
+		status = "expired"
+	default:
+		status = "expired, no longer used"
+	}
+
+	return fmt.Sprintf("%s (expires %s, %s)", sc.CipherPubKey, sc.ExpiryDate, status)
+}
+
+func (sc *SubkeyCertificate) Clone() *SubkeyCertificate {
+	if sc == nil {
+		return nil
+	}
+
+	return &SubkeyCertificate{
+		CipherPubKey: sc.CipherPubKey,
+		CreatedDate:  sc.CreatedDate,
+		ExpiryDate:   sc.ExpiryDate,
+		Signature:    bytes.Clone(sc.Signature),
+	}
+}
+
+func (sk *Subkey) Clone() *Subkey {
+	return &Subkey{
+		CipherSeed:  bytes.Clone(sk.CipherSeed),
+		Certificate: sk.Certificate.Clone(),
+	}
+}
+
+// CloneSubkeyCertificates returns a deep copy of the certificates
+func CloneSubkeyCertificates(certificates []*SubkeyCertificate) []*SubkeyCertificate {
+	if certificates == nil {
+		return nil
+	}
+
+	certificatesOut := make([]*SubkeyCertificate, 0, len(certificates))
+	for _, certificate := range certificates {
+		certificatesOut = append(certificatesOut, certificate.Clone())
+	}
+
+	return certificatesOut
+}
+
+// isNewerThan compares created dates.  Certificates with unreadable dates are considered oldest.
+func (sc *SubkeyCertificate) isNewerThan(other *SubkeyCertificate) bool {
+	createdTime, _, err := sc.dates()
+	if err != nil {
+		return false
+	}
+
+	otherCreatedTime, _, err := other.dates()
+	if err != nil {
+		return true
+	}
+
+	return createdTime.After(otherCreatedTime)
+}
+
+// sortSubkeyCertificates sorts newest first, by created date
+func sortSubkeyCertificates(certificates []*SubkeyCertificate) {
+	sort.SliceStable(certificates, func(i, j int) bool {
+		return certificates[i].isNewerThan(certificates[j])
+	})
+}
+
+// AddSubkey creates a new encryption subkey that is valid from now for the provided duration,
+// and certifies it with the keypair's signing key
+func (kpi *KeyPairInfo) AddSubkey(validFor time.Duration) (*Subkey, error) {
+	if validFor <= 0 {
+		validFor = SubkeyDefaultValidity
+	}
+
+	_, signingPubKey, err := kpi.PublicKeys()
+	if err != nil {
+		return nil, fmt.Errorf("unable to extract identity signing key: %w", err)
+	}
+
+	cipherKP, err := nkeys.CreateCurveKeys()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create subkey: %w", err)
+	}
+	defer cipherKP.Wipe()
+
+	cipherSeed, err := cipherKP.Seed()
+	if err != nil {
+		return nil, fmt.Errorf("unable to extract subkey seed: %w", err)
+	}
+	defer Wipe(cipherSeed)
+
+	cipherPubKey, err := cipherKP.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("unable to extract subkey public key: %w", err)
+	}
+
+	now := time.Now().UTC()
+	certificate := &SubkeyCertificate{
+		CipherPubKey: cipherPubKey,
+		CreatedDate:  now.Format(time.RFC3339),
+		ExpiryDate:   now.Add(validFor).Format(time.RFC3339),
+	}
+
+	signedData, err := certificate.signedData(signingPubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	certificate.Signature, err = kpi.Sign(signedData)
+	if err != nil {
+		return nil, fmt.Errorf("unable to certify subkey: %w", err)
+	}
+
+	subkey := &Subkey{CipherSeed: bytes.Clone(cipherSeed), Certificate: certificate}
+	kpi.Subkeys = append(kpi.Subkeys, subkey)
+	return subkey.Clone(), nil
+}
+
+// SubkeyCertificates returns the public certificates for the keypair's subkeys, newest first
+func (kpi *KeyPairInfo) SubkeyCertificates() []*SubkeyCertificate {
+	if len(kpi.Subkeys) == 0 {
+		return nil
+	}
+
+	certificates := make([]*SubkeyCertificate, 0, len(kpi.Subkeys))
+	for _, subkey := range kpi.Subkeys {
+		certificates = append(certificates, subkey.Certificate.Clone())
+	}
+
+	sortSubkeyCertificates(certificates)
+	return certificates
+}
+
+// OpenCipherSeeds returns the subkey seeds to try when opening a bundle at t, newest first.  These are the subkeys
+// that are valid, or expired within the open grace period.  The primary cipher seed is not included.
+func (kpi *KeyPairInfo) OpenCipherSeeds(t time.Time) [][]byte {
+	subkeys := make([]*Subkey, 0, len(kpi.Subkeys))
+	for _, subkey := range kpi.Subkeys {
+		if subkey.Certificate != nil && subkey.Certificate.IsUsableForOpenAt(t) {
+			subkeys = append(subkeys, subkey)
+		}
+	}
+
+	sort.SliceStable(subkeys, func(i, j int) bool {
+		return subkeys[i].Certificate.isNewerThan(subkeys[j].Certificate)
+	})
+
+	seeds := make([][]byte, 0, len(subkeys))
+	for _, subkey := range subkeys {
+		seeds = append(seeds, bytes.Clone(subkey.CipherSeed))
+	}
+
+	return seeds
+}
+
+// CurrentCipherPubKey returns the cipher key to encrypt to at t.  This is the newest subkey that is valid at t
+// and certified by the identity signing key, or the primary cipher key if there is none.
+func (ki *KeyInfo) CurrentCipherPubKey(t time.Time) string {
+	certificates := CloneSubkeyCertificates(ki.Subkeys)
+	sortSubkeyCertificates(certificates)
+
+	for _, certificate := range certificates {
+		if certificate.IsValidAt(t) && certificate.Verify(ki.SigningPubKey) == nil {
+			return certificate.CipherPubKey
+		}
+	}
+
+	return ki.CipherPubKey
+}
+
+// MergeSubkeys verifies each certificate against the identity signing key and adds those that are new.
+// An error is returned if any certificate fails verification, and no certificates are added.
+func (ki *KeyInfo) MergeSubkeys(certificates []*SubkeyCertificate) (added int, err error) {
+	for _, certificate := range certificates {
+		err = certificate.Verify(ki.SigningPubKey)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for _, certificate := range certificates {
+		found := false
+		for _, existing := range ki.Subkeys {
+			if existing.CipherPubKey == certificate.CipherPubKey {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			ki.Subkeys = append(ki.Subkeys, certificate.Clone())
+			added++
+		}
+	}
+
+	sortSubkeyCertificates(ki.Subkeys)
+	return added, nil
+}
@@ -0,0 +1,146 @@
+package security
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// newTestSubkeyCertificate certifies a new cipher key for kpi with the provided dates
+func newTestSubkeyCertificate(t *testing.T, kpi *KeyPairInfo, created, expiry time.Time) *SubkeyCertificate {
+	otherKPI, err := NewKeyPairInfoWithSeeds("other")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	cipherPubKey, _, err := otherKPI.PublicKeys()
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	_, signingPubKey, err := kpi.PublicKeys()
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	certificate := &SubkeyCertificate{
+		CipherPubKey: cipherPubKey,
+		CreatedDate:  created.UTC().Format(time.RFC3339),
+		ExpiryDate:   expiry.UTC().Format(time.RFC3339),
+	}
+
+	signedData, err := certificate.signedData(signingPubKey)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	certificate.Signature, err = kpi.Sign(signedData)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	return certificate
+}
+
+func TestSubkeyCertificate_Verify(t *testing.T) {
+	kpi, err := NewKeyPairInfoWithSeeds("identity")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	subkey, err := kpi.AddSubkey(time.Hour)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	_, signingPubKey, err := kpi.PublicKeys()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Nil(t, subkey.Certificate.Verify(signingPubKey))
+	assert.True(t, subkey.Certificate.IsValidAt(time.Now()))
+	assert.False(t, subkey.Certificate.IsValidAt(time.Now().Add(2*time.Hour)))
+
+	// a different identity did not certify the subkey
+	otherKPI, _ := NewKeyPairInfoWithSeeds("other")
+	_, otherSigningPubKey, _ := otherKPI.PublicKeys()
+	assert.True(t, errors.Is(subkey.Certificate.Verify(otherSigningPubKey), ErrInvalidSubkey))
+
+	// tampered dates fail verification
+	tampered := subkey.Certificate.Clone()
+	tampered.ExpiryDate = time.Now().Add(1000 * time.Hour).UTC().Format(time.RFC3339)
+	assert.True(t, errors.Is(tampered.Verify(signingPubKey), ErrInvalidSubkey))
+
+	// tampered cipher key fails verification
+	tampered = subkey.Certificate.Clone()
+	tampered.CipherPubKey = otherKPI.Name
+	assert.True(t, errors.Is(tampered.Verify(signingPubKey), ErrInvalidSubkey))
+}
+
+func TestKeyInfo_CurrentCipherPubKey(t *testing.T) {
+	kpi, err := NewKeyPairInfoWithSeeds("identity")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	cipherPubKey, signingPubKey, err := kpi.PublicKeys()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	ki, _ := NewKeyInfo("identity", cipherPubKey, signingPubKey)
+	now := time.Now()
+
+	// no subkeys uses the primary key
+	assert.Equal(t, cipherPubKey, ki.CurrentCipherPubKey(now))
+
+	expired := newTestSubkeyCertificate(t, kpi, now.Add(-48*time.Hour), now.Add(-24*time.Hour))
+	older := newTestSubkeyCertificate(t, kpi, now.Add(-2*time.Hour), now.Add(24*time.Hour))
+	newer := newTestSubkeyCertificate(t, kpi, now.Add(-1*time.Hour), now.Add(24*time.Hour))
+	future := newTestSubkeyCertificate(t, kpi, now.Add(1*time.Hour), now.Add(48*time.Hour))
+
+	added, err := ki.MergeSubkeys([]*SubkeyCertificate{expired})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, added)
+	assert.Equal(t, cipherPubKey, ki.CurrentCipherPubKey(now))
+
+	added, err = ki.MergeSubkeys([]*SubkeyCertificate{older, newer, future, expired})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, added)
+	assert.Equal(t, newer.CipherPubKey, ki.CurrentCipherPubKey(now))
+	assert.Equal(t, future.CipherPubKey, ki.CurrentCipherPubKey(now.Add(2*time.Hour)))
+
+	// certificates from another identity are refused
+	otherKPI, _ := NewKeyPairInfoWithSeeds("other")
+	foreign := newTestSubkeyCertificate(t, otherKPI, now.Add(-1*time.Minute), now.Add(24*time.Hour))
+	added, err = ki.MergeSubkeys([]*SubkeyCertificate{foreign})
+	assert.True(t, errors.Is(err, ErrInvalidSubkey))
+	assert.Equal(t, 0, added)
+	assert.Equal(t, newer.CipherPubKey, ki.CurrentCipherPubKey(now))
+}
+
+func TestKeyPairInfo_OpenCipherSeeds(t *testing.T) {
+	kpi, err := NewKeyPairInfoWithSeeds("identity")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	_, err = kpi.AddSubkey(time.Hour)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, 1, len(kpi.OpenCipherSeeds(time.Now())))
+
+	// recently expired subkeys are still tried
+	assert.Equal(t, 1, len(kpi.OpenCipherSeeds(time.Now().Add(2*time.Hour))))
+
+	// but not once the grace period has passed
+	assert.Equal(t, 0, len(kpi.OpenCipherSeeds(time.Now().Add(2*time.Hour+SubkeyOpenGracePeriod))))
+
+	clone := kpi.Clone()
+	assert.Equal(t, kpi.Subkeys[0].CipherSeed, clone.Subkeys[0].CipherSeed)
+	assert.Equal(t, kpi.Subkeys[0].Certificate.CipherPubKey, clone.SubkeyCertificates()[0].CipherPubKey)
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 The Bumblebee Authors
+//
+// Use of this source code is governed by an MIT license that is located
+// in this project's root folder, and can also be found online at:
+//
+// https://github.com/thoughtrealm/bumblebee/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"errors"
+	"fmt"
+	"github.com/vmihailenco/msgpack/v5"
+	"time"
+)
+
+const successionDomain = "bumblebee succession v1"
+
+var ErrInvalidSuccession = errors.New("invalid succession statement")
+
+// SuccessionStatement announces that a keypair's public keys were replaced with new keys.
+// It is signed by both the old and the new signing keys, so a peer holding the old keys
+// can accept the new keys without trusting the channel the statement arrived on.
+type SuccessionStatement struct {
+	Name             string
+	OldCipherPubKey  string
+	OldSigningPubKey string
+	NewCipherPubKey  string
+	NewSigningPubKey string
+	RotatedDate      string
+	OldSignature     []byte
+	NewSignature     []byte
+}
+
+// successionSignedData is the portion of a SuccessionStatement covered by both signatures
+type successionSignedData struct {
+	Domain           string
+	Name             string
+	OldCipherPubKey  string
+	OldSigningPubKey string
+	NewCipherPubKey  string
+	NewSigningPubKey string
+	RotatedDate      string
+}
+
+// NewSuccessionStatement builds a statement for the rotation from oldKPI to newKPI and signs it with both
+func NewSuccessionStatement(name string, oldKPI, newKPI *KeyPairInfo) (*SuccessionStatement, error) {
+	if oldKPI == nil || newKPI == nil {
+		return nil, errors.New("both the old and new keypairs are required")
+	}
+
+	oldCipherPubKey, oldSigningPubKey, err := oldKPI.PublicKeys()
+	if err != nil {
+		return nil, fmt.Errorf("unable to extract old public keys: %w", err)
+	}
+
+	newCipherPubKey, newSigningPubKey, err := newKPI.PublicKeys()
+	if err != nil {
+		return nil, fmt.Errorf("unable to extract new public keys: %w", err)
+	}
+
+	ss := &SuccessionStatement{
+		Name:             name,
+		OldCipherPubKey:  oldCipherPubKey,
+		OldSigningPubKey: oldSigningPubKey,
+		NewCipherPubKey:  newCipherPubKey,
+		NewSigningPubKey: newSigningPubKey,
+		RotatedDate:      time.Now().UTC().Format(time.RFC3339),
+	}
+
+	signedData, err := ss.signedData()
+	if err != nil {
+		return nil, err
+	}
+
+	ss.OldSignature, err = oldKPI.Sign(signedData)
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign with old signing key: %w", err)
+	}
+
+	ss.NewSignature, err = newKPI.Sign(signedData)
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign with new signing key: %w", err)
+	}
+
+	return ss, nil
+}
+
+func (ss *SuccessionStatement) signedData() ([]byte, error) {
+	signedData, err := msgpack.Marshal(&successionSignedData{
+		Domain:           successionDomain,
+		Name:             ss.Name,
+		OldCipherPubKey:  ss.OldCipherPubKey,
+		OldSigningPubKey: ss.OldSigningPubKey,
+		NewCipherPubKey:  ss.NewCipherPubKey,
+		NewSigningPubKey: ss.NewSigningPubKey,
+		RotatedDate:      ss.RotatedDate,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode succession data: %w", err)
+	}
+
+	return signedData, nil
+}
+
+// Verify checks that the statement is signed by both its old and new signing keys
+func (ss *SuccessionStatement) Verify() error {
+	if ss.OldSigningPubKey == ss.NewSigningPubKey {
+		return fmt.Errorf("%w: old and new signing keys are the same", ErrInvalidSuccession)
+	}
+
+	signedData, err := ss.signedData()
+	if err != nil {
+		return err
+	}
+
+	oldKeyInfo := ss.OldKeyInfo()
+	if _, err = oldKeyInfo.Verify(signedData, ss.OldSignature); err != nil {
+		return fmt.Errorf("%w: old key signature: %s", ErrInvalidSuccession, err)
+	}
+
+	newKeyInfo := ss.NewKeyInfo()
+	if _, err = newKeyInfo.Verify(signedData, ss.NewSignature); err != nil {
+		return fmt.Errorf("%w: new key signature: %s", ErrInvalidSuccession, err)
+	}
+
+	return nil
+}
+
+// VerifyFrom checks the statement signatures and that the old keys match the provided current keys
+func (ss *SuccessionStatement) VerifyFrom(currentKeys *KeyInfo) error {
+	if currentKeys == nil || currentKeys.SigningPubKey != ss.OldSigningPubKey {
+		return fmt.Errorf("%w: old signing key does not match the stored signing key", ErrInvalidSuccession)
+	}
+
+	return ss.Verify()
+}
+
+func (ss *SuccessionStatement) OldKeyInfo() *KeyInfo {
+	return &KeyInfo{Name: ss.Name, CipherPubKey: ss.OldCipherPubKey, SigningPubKey: ss.OldSigningPubKey}
+}
+
+func (ss *SuccessionStatement) NewKeyInfo() *KeyInfo {
+	return &KeyInfo{Name: ss.Name, CipherPubKey: ss.NewCipherPubKey, SigningPubKey: ss.NewSigningPubKey}
+}
@@ -0,0 +1,51 @@
+package security
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSuccessionStatement_SignAndVerify(t *testing.T) {
+	oldKPI, err := NewKeyPairInfoWithSeeds("old")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	newKPI, err := NewKeyPairInfoWithSeeds("new")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	ss, err := NewSuccessionStatement("bob", oldKPI, newKPI)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, ss.Verify())
+
+	_, oldSigningPubKey, _ := oldKPI.PublicKeys()
+	assert.Nil(t, ss.VerifyFrom(&KeyInfo{SigningPubKey: oldSigningPubKey}))
+
+	// The stored key must be the old signing key
+	err = ss.VerifyFrom(&KeyInfo{SigningPubKey: ss.NewSigningPubKey})
+	assert.True(t, errors.Is(err, ErrInvalidSuccession))
+
+	// Any change to the signed values invalidates the statement
+	tampered := *ss
+	tampered.NewCipherPubKey = ss.OldCipherPubKey
+	assert.True(t, errors.Is(tampered.Verify(), ErrInvalidSuccession))
+
+	// A statement signed only by the new key is rejected
+	otherKPI, err := NewKeyPairInfoWithSeeds("other")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	forged, err := NewSuccessionStatement("bob", otherKPI, newKPI)
+	if !assert.Nil(t, err) {
+		return
+	}
+	forged.OldCipherPubKey = ss.OldCipherPubKey
+	forged.OldSigningPubKey = ss.OldSigningPubKey
+	assert.True(t, errors.Is(forged.Verify(), ErrInvalidSuccession))
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	beecipher "github.com/thoughtrealm/bumblebee/cipher"
 	"github.com/vmihailenco/msgpack/v5"
 	"io"
 )
@@ -11,7 +12,91 @@ import (
 const DEFAULT_CHUNK_SIZE = 64000
 const DEFAULT_SALT_SIZE = 32
 const SymFileHeader_SIZE = 35
-const HeaderVersion = 1
+const HeaderVersion = 2
+
+// SymFileMarker begins the preamble of sym files that carry a key commitment.  Sym files created before
+// key commitment support begin directly with the salt.  A random salt colliding with the marker is possible,
+// but the odds are 1 in 2^32, and such a file will fail the commitment check rather than decrypt wrongly.
+var SymFileMarker = []byte{'B', 'S', 'Y', '2'}
+
+// symFilePreamble holds the unencrypted values that precede the encrypted sym file stream
+type symFilePreamble struct {
+	salt          []byte
+	keyCommitment []byte
+}
+
+// newCipherFromPreamble creates the decrypting cipher.  When the preamble has a key commitment, the cipher
+// verifies it before decrypting, and the commitment is also bound into each chunk's AD, so it cannot be
+// stripped to downgrade the file to the older format.
+func newCipherFromPreamble(key []byte, preamble *symFilePreamble) (beecipher.Cipher, error) {
+	sc, err := beecipher.NewSymmetricCipherFromSalt(key, preamble.salt, DEFAULT_CHUNK_SIZE)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(preamble.keyCommitment) != 0 {
+		sc.SetKeyCommitment(preamble.keyCommitment)
+		sc.SetAssociatedData(preamble.keyCommitment)
+	}
+
+	return sc, nil
+}
+
+// getPreambleFromReader reads the salt and key commitment from the start of a sym file stream.
+// Sym files without the marker are read as the older format, which only has a salt.
+func getPreambleFromReader(r io.Reader) (*symFilePreamble, error) {
+	marker := make([]byte, len(SymFileMarker))
+	_, err := io.ReadFull(r, marker)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading preamble from input sym file: %w", err)
+	}
+
+	preamble := &symFilePreamble{
+		salt: make([]byte, DEFAULT_SALT_SIZE),
+	}
+
+	if !bytes.Equal(marker, SymFileMarker) {
+		// Older format, so the marker bytes are actually the start of the salt
+		copy(preamble.salt, marker)
+		_, err = io.ReadFull(r, preamble.salt[len(marker):])
+		if err != nil {
+			return nil, fmt.Errorf("failed reading salt from input sym file: %w", err)
+		}
+
+		return preamble, nil
+	}
+
+	_, err = io.ReadFull(r, preamble.salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading salt from input sym file: %w", err)
+	}
+
+	preamble.keyCommitment = make([]byte, beecipher.KeyCommitmentLen)
+	_, err = io.ReadFull(r, preamble.keyCommitment)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading key commitment from input sym file: %w", err)
+	}
+
+	return preamble, nil
+}
+
+// writePreamble writes the marker, salt and key commitment to w.  It also binds the commitment into the
+// cipher's chunk AD, matching newCipherFromPreamble.
+func writePreamble(sc beecipher.Cipher, w io.Writer) (int, error) {
+	keyCommitment, err := sc.GetKeyCommitment()
+	if err != nil {
+		return 0, fmt.Errorf("failed generating key commitment: %w", err)
+	}
+
+	sc.SetAssociatedData(keyCommitment)
+
+	preambleBytes := make([]byte, 0, len(SymFileMarker)+DEFAULT_SALT_SIZE+len(keyCommitment))
+	preambleBytes = append(preambleBytes, SymFileMarker...)
+	preambleBytes = append(preambleBytes, sc.GetSalt()...)
+	preambleBytes = append(preambleBytes, keyCommitment...)
+
+	return w.Write(preambleBytes)
+}
 
 type SymFilePayload uint8
 
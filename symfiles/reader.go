@@ -3,7 +3,6 @@ package symfiles
 import (
 	"errors"
 	"fmt"
-	beecipher "github.com/thoughtrealm/bumblebee/cipher"
 	"github.com/thoughtrealm/bumblebee/helpers"
 	"github.com/thoughtrealm/bumblebee/security"
 	"github.com/thoughtrealm/bumblebee/streams"
@@ -35,16 +34,6 @@ func NewSymFileReader(key []byte, useDerivedFilename bool, includePaths []string
 	}, nil
 }
 
-func (ssfr *SimpleSymFileReader) getSaltFromReader(r io.Reader) (salt []byte, err error) {
-	salt = make([]byte, DEFAULT_SALT_SIZE)
-	b, err := r.Read(salt)
-	if err != nil || b > 3333 {
-		return nil, fmt.Errorf("failed reading salt from input sym file: %w", err)
-	}
-
-	return salt, nil
-}
-
 // ReadSymFile reads a .bsym file.  If the sym file is of type file stream, then outputPath must be a file
 // name.  If the sym file is of type multi-dir stream, then outputPath must be a path name.
 func (ssfr *SimpleSymFileReader) ReadSymFile(inputSymFilename, outputPath string) (bytesWritten int, err error) {
@@ -58,9 +47,9 @@ func (ssfr *SimpleSymFileReader) ReadSymFile(inputSymFilename, outputPath string
 	}
 	defer inputFile.Close()
 
-	salt, err := ssfr.getSaltFromReader(inputFile)
+	preamble, err := getPreambleFromReader(inputFile)
 	if err != nil {
-		return 0, fmt.Errorf("failed reading salt from input sym file: %w", err)
+		return 0, fmt.Errorf("failed reading preamble from input sym file: %w", err)
 	}
 
 	var outputFile *os.File
@@ -155,7 +144,7 @@ func (ssfr *SimpleSymFileReader) ReadSymFile(inputSymFilename, outputPath string
 		return outputWriter, nil
 	})
 
-	chacha, err := beecipher.NewSymmetricCipherFromSalt(ssfr.key, salt, DEFAULT_CHUNK_SIZE)
+	chacha, err := newCipherFromPreamble(ssfr.key, preamble)
 	if err != nil {
 		return DEFAULT_SALT_SIZE, fmt.Errorf("failed creating symmetric cipher: %w", err)
 	}
@@ -181,9 +170,9 @@ func (ssfr *SimpleSymFileReader) ReadSymFileMetadata(inputSymFilename string) (m
 	}
 	defer inputFile.Close()
 
-	salt, err := ssfr.getSaltFromReader(inputFile)
+	preamble, err := getPreambleFromReader(inputFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed reading salt from input sym file: %w", err)
+		return nil, fmt.Errorf("failed reading preamble from input sym file: %w", err)
 	}
 
 	var mdsw streams.StreamWriter
@@ -205,7 +194,7 @@ func (ssfr *SimpleSymFileReader) ReadSymFileMetadata(inputSymFilename string) (m
 		return outputWriter, nil
 	})
 
-	chacha, err := beecipher.NewSymmetricCipherFromSalt(ssfr.key, salt, DEFAULT_CHUNK_SIZE)
+	chacha, err := newCipherFromPreamble(ssfr.key, preamble)
 	if err != nil {
 		return nil, fmt.Errorf("failed creating symmetric cipher: %w", err)
 	}
@@ -277,20 +266,20 @@ func (ssfr *SimpleSymFileReader) getFilenameFromReaderHeader(defaultOutputFilena
 }
 
 // ReadSymReaderToFile reads a .bsym stream from symReader and writes it to the outputFile.  It reads the
-// stream header, then passes the salt info to the readSymReaderToFile completion func.
+// stream header, then passes the preamble to the readSymReaderToFile completion func.
 // It returns the number of bytes written, and any error encountered.
 func (ssfr *SimpleSymFileReader) ReadSymReaderToFile(symReader io.Reader, outputFilename string) (bytesWritten int, err error) {
-	salt, err := ssfr.getSaltFromReader(symReader)
+	preamble, err := getPreambleFromReader(symReader)
 	if err != nil {
-		return 0, fmt.Errorf("failed reading salt from input sym file: %w", err)
+		return 0, fmt.Errorf("failed reading preamble from input sym file: %w", err)
 	}
 
-	return ssfr.readSymReaderToFile(salt, symReader, outputFilename)
+	return ssfr.readSymReaderToFile(preamble, symReader, outputFilename)
 }
 
 // readSymReaderToFile reads a .bsym stream from symReader and writes it to the outputFile.
 // It returns the number of bytes written, and any error encountered.
-func (ssfr *SimpleSymFileReader) readSymReaderToFile(salt []byte, symReader io.Reader, outputFilename string) (bytesWritten int, err error) {
+func (ssfr *SimpleSymFileReader) readSymReaderToFile(preamble *symFilePreamble, symReader io.Reader, outputFilename string) (bytesWritten int, err error) {
 	var outputFile *os.File
 
 	defer func() {
@@ -299,7 +288,7 @@ func (ssfr *SimpleSymFileReader) readSymReaderToFile(salt []byte, symReader io.R
 		}
 	}()
 
-	chacha, err := beecipher.NewSymmetricCipherFromSalt(ssfr.key, salt, DEFAULT_CHUNK_SIZE)
+	chacha, err := newCipherFromPreamble(ssfr.key, preamble)
 	if err != nil {
 		return SymFileHeader_SIZE, fmt.Errorf("failed creating symmetric cipher: %w", err)
 	}
@@ -330,20 +319,20 @@ func (ssfr *SimpleSymFileReader) readSymReaderToFile(salt []byte, symReader io.R
 }
 
 // ReadSymReaderToPath reads a .bsym file with multi-dir data from the inputSymFilePath and writes it to the
-// outputPath. It reads the header from the input stream then passes the salt to the completion function
+// outputPath. It reads the header from the input stream then passes the preamble to the completion function
 // readSymReaderToPath. It returns the number of bytes written, and any error encountered.
 func (ssfr *SimpleSymFileReader) ReadSymReaderToPath(symReader io.Reader, outputPath string) (bytesWritten int, err error) {
-	salt, err := ssfr.getSaltFromReader(symReader)
+	preamble, err := getPreambleFromReader(symReader)
 	if err != nil {
-		return 0, fmt.Errorf("failed reading salt from input sym file: %w", err)
+		return 0, fmt.Errorf("failed reading preamble from input sym file: %w", err)
 	}
 
-	return ssfr.readSymReaderToPath(salt, symReader, outputPath)
+	return ssfr.readSymReaderToPath(preamble, symReader, outputPath)
 }
 
-// readSymReaderToPath is the completion function that receives the salt and streams multi-dir to the output path.
-func (ssfr *SimpleSymFileReader) readSymReaderToPath(salt []byte, symReader io.Reader, outputPath string) (bytesWritten int, err error) {
-	chacha, err := beecipher.NewSymmetricCipherFromSalt(ssfr.key, salt, DEFAULT_CHUNK_SIZE)
+// readSymReaderToPath is the completion function that receives the preamble and streams multi-dir to the output path.
+func (ssfr *SimpleSymFileReader) readSymReaderToPath(preamble *symFilePreamble, symReader io.Reader, outputPath string) (bytesWritten int, err error) {
+	chacha, err := newCipherFromPreamble(ssfr.key, preamble)
 	if err != nil {
 		return SymFileHeader_SIZE, fmt.Errorf("failed creating symmetric cipher: %w", err)
 	}
@@ -383,9 +372,9 @@ func (ssfr *SimpleSymFileReader) Wipe() {
 // ReadSymReaderToWriter reads a reader stream from symReader and writes it to the provider writer.
 // It returns the number of bytes written, and any error encountered.
 func (ssfr *SimpleSymFileReader) ReadSymReaderToWriter(symReader io.Reader, w io.Writer) (bytesWritten int, err error) {
-	salt, err := ssfr.getSaltFromReader(symReader)
+	preamble, err := getPreambleFromReader(symReader)
 	if err != nil {
-		return 0, fmt.Errorf("failed reading salt from input sym file: %w", err)
+		return 0, fmt.Errorf("failed reading preamble from input sym file: %w", err)
 	}
 
 	// We do not pass a writer to the processor creator so that we can validate the payload type after
@@ -398,7 +387,7 @@ func (ssfr *SimpleSymFileReader) ReadSymReaderToWriter(symReader io.Reader, w io
 		return w, nil
 	})
 
-	chacha, err := beecipher.NewSymmetricCipherFromSalt(ssfr.key, salt, DEFAULT_CHUNK_SIZE)
+	chacha, err := newCipherFromPreamble(ssfr.key, preamble)
 	if err != nil {
 		return 0, fmt.Errorf("failed creating symmetric cipher: %w", err)
 	}
@@ -1,7 +1,10 @@
 package symfiles
 
 import (
+	"bytes"
+	"errors"
 	"github.com/stretchr/testify/assert"
+	beecipher "github.com/thoughtrealm/bumblebee/cipher"
 	"github.com/thoughtrealm/bumblebee/streams"
 	"os"
 	"testing"
@@ -117,3 +120,49 @@ func TestSimpleSymFile_ReadSymFile(t *testing.T) {
 		})
 	}
 }
+
+func TestSimpleSymFile_ReadSymReaderKeyCommitment(t *testing.T) {
+	secretBytes := []byte("My name is Werner Brandon.  My voice is my passport.  Verify me.")
+
+	symFileWriter, err := NewSymFileWriter(readerTestKey)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	symBuff := bytes.NewBuffer(nil)
+	_, err = symFileWriter.WriteSymFileToWriterFromReader(bytes.NewReader(secretBytes), symBuff, SymFilePayloadDataStream)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	symBytes := symBuff.Bytes()
+	assert.Equal(t, SymFileMarker, symBytes[:len(SymFileMarker)])
+
+	symFileReader, _ := NewSymFileReader(bytes.Clone(readerTestKey), true, nil)
+	outputBuff := bytes.NewBuffer(nil)
+	_, err = symFileReader.ReadSymReaderToWriter(bytes.NewReader(symBytes), outputBuff)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, secretBytes, outputBuff.Bytes())
+
+	// A tampered commitment must be rejected before any data is written
+	tamperedBytes := bytes.Clone(symBytes)
+	tamperedBytes[len(SymFileMarker)+DEFAULT_SALT_SIZE] ^= 0xFF
+	outputBuff.Reset()
+	_, err = symFileReader.ReadSymReaderToWriter(bytes.NewReader(tamperedBytes), outputBuff)
+	assert.True(t, errors.Is(err, beecipher.ErrKeyCommitmentMismatch))
+	assert.Equal(t, 0, outputBuff.Len())
+
+	// The wrong key must also fail the commitment check
+	wrongKeyReader, _ := NewSymFileReader([]byte("wrongkey"), true, nil)
+	_, err = wrongKeyReader.ReadSymReaderToWriter(bytes.NewReader(symBytes), outputBuff)
+	assert.True(t, errors.Is(err, beecipher.ErrKeyCommitmentMismatch))
+
+	// Stripping the marker and commitment to downgrade to the older format must not decrypt
+	strippedBytes := bytes.Clone(symBytes[len(SymFileMarker) : len(SymFileMarker)+DEFAULT_SALT_SIZE])
+	strippedBytes = append(strippedBytes, symBytes[len(SymFileMarker)+DEFAULT_SALT_SIZE+beecipher.KeyCommitmentLen:]...)
+	_, err = symFileReader.ReadSymReaderToWriter(bytes.NewReader(strippedBytes), outputBuff)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, outputBuff.Len())
+}
@@ -138,8 +138,8 @@ func (ssfw *SimpleSymFileWriter) WriteSymFileFromReader(r io.Reader, outputSymFi
 
 	psw := newPreStreamEncoderReader(headerBytes, r)
 
-	// we first write the salt/IV directly to the output stream unencrypted/unencoded
-	saltBytesWritten, err := outputSymFile.Write(ssfw.sc.GetSalt())
+	// we first write the salt/IV and key commitment directly to the output stream unencrypted/unencoded
+	saltBytesWritten, err := writePreamble(ssfw.sc, outputSymFile)
 	if err != nil {
 		return 0, fmt.Errorf("error writing salt/IV: %w", err)
 	}
@@ -166,8 +166,8 @@ func (ssfw *SimpleSymFileWriter) WriteSymFileToWriterFromReader(r io.Reader, w i
 
 	pser := newPreStreamEncoderReader(headerBytes, r)
 
-	// we first write the salt/IV to the output stream
-	saltBytesWritten, err := w.Write(ssfw.sc.GetSalt())
+	// we first write the salt/IV and key commitment to the output stream
+	saltBytesWritten, err := writePreamble(ssfw.sc, w)
 	if err != nil {
 		return 0, fmt.Errorf("error writing salt/IV: %w", err)
 	}